@@ -5,15 +5,17 @@ import (
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/provider"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type App struct {
-	db         *gorm.DB
-	middleware *middleware.Middleware
-	provider   *provider.Provider
-	gin        *gin.Engine
+	db             *gorm.DB
+	middleware     *middleware.Middleware
+	provider       *provider.Provider
+	gin            *gin.Engine
+	trustedProxies service.TrustedProxies
 }
 
 func NewApp(db *gorm.DB, sqlDB *sql.DB) (*App, func(), error) {
@@ -27,6 +29,7 @@ func NewApp(db *gorm.DB, sqlDB *sql.DB) (*App, func(), error) {
 
 func (a *App) Init(g *gin.Engine) {
 	a.gin = g
+	a.initTrustedProxies()
 	a.initProviders()
 	a.initMiddlewares()
 	a.entryBeforeGlobalMiddleware()
@@ -3,9 +3,12 @@ package app_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/app"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -47,3 +50,110 @@ func TestNewAppAndInitRoutes(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "ok")
 }
+
+func TestInitWithFirewallRules(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "firewall.json")
+	os.WriteFile(rulesFile, []byte(`{"default": {"deny": ["203.0.113.0/24"]}}`), 0o600)
+
+	funcs.WithEnv("FIREWALL_RULES_FILE", rulesFile, t, func() {
+		funcs.WithEnvMap(funcs.Envs{"TRUSTED_PROXIES": "192.0.2.0/24", "TRUST_FORWARDED_HEADER": "true"}, t, func() {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+
+			db, cleanup := newTestDB(t)
+			defer cleanup()
+			sqlDB, _ := db.DB()
+			a, cleanup, err := app.NewApp(db, sqlDB)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+			a.Init(r)
+
+			tests := map[string]struct {
+				remoteAddr   string
+				forwardedFor string
+				forwarded    string
+				expectedCode int
+			}{
+				"allowed client":                  {"198.51.100.1:1234", "", "", http.StatusOK},
+				"denied client":                   {"203.0.113.5:1234", "", "", http.StatusForbidden},
+				"denied client via trusted proxy": {"192.0.2.10:1234", "203.0.113.5", "", http.StatusForbidden},
+				"spoofed forwarded for":           {"198.51.100.1:1234", "203.0.113.5", "", http.StatusOK},
+				"denied client via forwarded":     {"192.0.2.10:1234", "", "for=203.0.113.5;proto=https", http.StatusForbidden},
+				"spoofed forwarded":               {"198.51.100.1:1234", "", "for=203.0.113.5", http.StatusOK},
+				// 信頼するプロキシが X-Forwarded-For に実際の接続元を追記し、クライアントの Forwarded を通した場合
+				"forged forwarded via trusted proxy": {"192.0.2.10:1234", "203.0.113.5", "for=198.51.100.1", http.StatusForbidden},
+			}
+
+			for title, tt := range tests {
+				t.Run(title, func(t *testing.T) {
+					req := httptest.NewRequest("GET", "/healthcheck", nil)
+					req.RemoteAddr = tt.remoteAddr
+					if tt.forwardedFor != "" {
+						req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+					}
+					if tt.forwarded != "" {
+						req.Header.Set("Forwarded", tt.forwarded)
+					}
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, req)
+
+					assert.Equal(t, tt.expectedCode, w.Code)
+				})
+			}
+		})
+	})
+}
+
+func TestInitAnswersCorsPreflight(t *testing.T) {
+	funcs.WithEnv("CORS_CREDENTIAL_ORIGINS", "https://app.example.com", t, func() {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+
+		db, cleanup := newTestDB(t)
+		defer cleanup()
+		sqlDB, _ := db.DB()
+		a, cleanup, err := app.NewApp(db, sqlDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+		a.Init(r)
+
+		// OPTIONS のルートは定義していないが、グローバルミドルウェアで応答する
+		req := httptest.NewRequest(http.MethodOptions, "/auth/login", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
+
+func TestInitSetsSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	db, cleanup := newTestDB(t)
+	defer cleanup()
+	sqlDB, _ := db.DB()
+	a, cleanup, err := app.NewApp(db, sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	a.Init(r)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.NotEmpty(t, w.Header().Get("Strict-Transport-Security"))
+}
@@ -4,8 +4,13 @@ import "github.com/AtsuyaOotsuka/portfolio-go-auth/internal/routing"
 
 func (a *App) entryBeforeGlobalMiddleware() {
 	// 前処理系ミドルウェアをここに追加
-	// a.gin.Use(a.middleware.Firewall)
-	a.gin.Use(a.middleware.Csrf)
+	// gin の Use は登録済みのルートに適用されないため、レスポンスヘッダーもここで設定する
+	a.gin.Use(a.middleware.SecurityHeaders)
+	a.gin.Use(a.middleware.Forwarded)
+	a.gin.Use(a.middleware.Firewall)
+	// プリフライトはルートのミドルウェアより前に応答する
+	// CSRF の検証は Cookie で認証するルートにのみ、ルーティングで付ける
+	a.gin.Use(a.middleware.Cors)
 }
 
 func (a *App) entryAfterGlobalMiddleware() {
@@ -28,4 +33,34 @@ func (a *App) entryRoutes() {
 	routing.AuthRouting(
 		a.provider.BindAuthHandler(),
 	)
+	routing.MfaRouting(
+		a.provider.BindMfaHandler(),
+	)
+	routing.WebauthnRouting(
+		a.provider.BindWebauthnHandler(),
+	)
+	routing.PasswordlessRouting(
+		a.provider.BindPasswordlessHandler(),
+	)
+	routing.OauthRouting(
+		a.provider.BindOauthHandler(),
+	)
+	routing.OidcRouting(
+		a.provider.BindOidcHandler(),
+	)
+	routing.AccountRouting(
+		a.provider.BindAccountHandler(),
+	)
+	routing.UserRouting(
+		a.provider.BindRoleHandler(),
+	)
+	routing.AuthzRouting(
+		a.provider.BindAuthzHandler(),
+	)
+	routing.AdminOauthClientRouting(
+		a.provider.BindOauthClientHandler(),
+	)
+	routing.AdminRoleRouting(
+		a.provider.BindRoleHandler(),
+	)
 }
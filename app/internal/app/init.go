@@ -1,8 +1,11 @@
 package app
 
 import (
+	"log"
+
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/provider"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
 )
 
 func (a *App) initProviders() {
@@ -11,5 +14,16 @@ func (a *App) initProviders() {
 
 func (a *App) initMiddlewares() {
 	// ミドルウェアの初期化
-	a.middleware = middleware.NewMiddleware(a.gin)
+	a.middleware = middleware.NewMiddleware(a.gin, a.db, a.trustedProxies)
+}
+
+// X-Forwarded-For は TRUSTED_PROXIES からの接続の場合のみ信頼する
+// gin は既定ですべての接続元を信頼するため、未設定の場合は接続元をそのままクライアント IP とする
+// 読み込んだ値は Forwarded ミドルウェアにも渡し、ファイアウォールはこの設定で解決したクライアント IP を使う
+func (a *App) initTrustedProxies() {
+	a.trustedProxies = service.NewTrustedProxiesFromEnv()
+	if err := a.gin.SetTrustedProxies(a.trustedProxies.Strings()); err != nil {
+		log.Printf("[app] failed to set trusted proxies, no proxies are trusted: %v", err)
+		a.gin.SetTrustedProxies(nil)
+	}
 }
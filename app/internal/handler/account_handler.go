@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandlerInterface interface {
+	ChangePassword(c *gin.Context)
+	ChangeEmail(c *gin.Context)
+	ChangeLocale(c *gin.Context)
+	DeleteAccount(c *gin.Context)
+}
+
+type AccountHandlerStruct struct {
+	BaseHandler
+	service service.AccountSvcInterface
+}
+
+func NewAccountHandler(
+	service service.AccountSvcInterface,
+) *AccountHandlerStruct {
+	return &AccountHandlerStruct{
+		service: service,
+	}
+}
+
+type changePasswordRequest struct {
+	NewPassword string `form:"new_password" json:"new_password" binding:"required"`
+}
+
+type changeEmailRequest struct {
+	Email string `form:"email" json:"email" binding:"required,email"`
+}
+
+// 空の場合は設定を消す
+type changeLocaleRequest struct {
+	Locale string `form:"locale" json:"locale" binding:"omitempty,oneof=en ja"`
+}
+
+func (h *AccountHandlerStruct) ChangePassword(c *gin.Context) {
+	var req changePasswordRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	err := h.service.ChangePassword(service.ChangePasswordInput{
+		UserUUID:    c.GetString(middleware.AuthUserUUIDKey),
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AccountHandlerStruct) ChangeEmail(c *gin.Context) {
+	var req changeEmailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	user, err := h.service.ChangeEmail(service.ChangeEmailInput{
+		UserUUID: c.GetString(middleware.AuthUserUUIDKey),
+		Email:    req.Email,
+	})
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"uuid":  user.UUID,
+		"email": user.Email,
+	})
+}
+
+func (h *AccountHandlerStruct) ChangeLocale(c *gin.Context) {
+	var req changeLocaleRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	user, err := h.service.ChangeLocale(service.ChangeLocaleInput{
+		UserUUID: c.GetString(middleware.AuthUserUUIDKey),
+		Locale:   req.Locale,
+	})
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"uuid":   user.UUID,
+		"locale": user.Locale,
+	})
+}
+
+func (h *AccountHandlerStruct) DeleteAccount(c *gin.Context) {
+	if err := h.service.DeleteAccount(c.GetString(middleware.AuthUserUUIDKey)); err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
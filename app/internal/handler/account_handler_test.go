@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangePasswordSuccess(t *testing.T) {
+	c, w := newMfaTestContext(map[string]string{"new_password": "correct horse battery"})
+
+	accountSvcMock := new(svc_mock.AccountSvcMock)
+	accountSvcMock.On("ChangePassword", service.ChangePasswordInput{
+		UserUUID:    "test-uuid",
+		NewPassword: "correct horse battery",
+	}).Return(nil)
+
+	handler := NewAccountHandler(accountSvcMock)
+	handler.ChangePassword(c)
+
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+	assert.Empty(t, w.Body.String())
+	accountSvcMock.AssertExpectations(t)
+}
+
+func TestChangePasswordFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		body     map[string]string
+		err      error
+		expected int
+	}{
+		{"missing password", map[string]string{}, nil, http.StatusBadRequest},
+		{"policy violation", map[string]string{"new_password": "password"}, &service.PasswordPolicyError{Violations: []service.PasswordPolicyViolation{{Code: service.PasswordViolationTooWeak}}}, http.StatusBadRequest},
+		{"internal error", map[string]string{"new_password": "correct horse battery"}, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newMfaTestContext(tt.body)
+
+			accountSvcMock := new(svc_mock.AccountSvcMock)
+			accountSvcMock.On("ChangePassword", service.ChangePasswordInput{
+				UserUUID:    "test-uuid",
+				NewPassword: tt.body["new_password"],
+			}).Return(tt.err)
+
+			handler := NewAccountHandler(accountSvcMock)
+			handler.ChangePassword(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestChangeEmailSuccess(t *testing.T) {
+	c, w := newMfaTestContext(map[string]string{"email": "new@example.com"})
+
+	accountSvcMock := new(svc_mock.AccountSvcMock)
+	accountSvcMock.On("ChangeEmail", service.ChangeEmailInput{
+		UserUUID: "test-uuid",
+		Email:    "new@example.com",
+	}).Return(&models.User{UUID: "test-uuid", Email: "new@example.com"}, nil)
+
+	handler := NewAccountHandler(accountSvcMock)
+	handler.ChangeEmail(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "new@example.com", result["email"])
+}
+
+func TestChangeEmailFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		body     map[string]string
+		err      error
+		expected int
+	}{
+		{"invalid email", map[string]string{"email": "invalid"}, nil, http.StatusBadRequest},
+		{"already in use", map[string]string{"email": "new@example.com"}, service.ErrEmailAlreadyInUse, http.StatusConflict},
+		{"internal error", map[string]string{"email": "new@example.com"}, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newMfaTestContext(tt.body)
+
+			accountSvcMock := new(svc_mock.AccountSvcMock)
+			accountSvcMock.On("ChangeEmail", service.ChangeEmailInput{
+				UserUUID: "test-uuid",
+				Email:    tt.body["email"],
+			}).Return((*models.User)(nil), tt.err)
+
+			handler := NewAccountHandler(accountSvcMock)
+			handler.ChangeEmail(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestChangeLocaleSuccess(t *testing.T) {
+	c, w := newMfaTestContext(map[string]string{"locale": "ja"})
+
+	accountSvcMock := new(svc_mock.AccountSvcMock)
+	accountSvcMock.On("ChangeLocale", service.ChangeLocaleInput{
+		UserUUID: "test-uuid",
+		Locale:   "ja",
+	}).Return(&models.User{UUID: "test-uuid", Locale: "ja"}, nil)
+
+	handler := NewAccountHandler(accountSvcMock)
+	handler.ChangeLocale(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+	assert.Equal(t, "ja", result["locale"])
+}
+
+func TestChangeLocaleFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		body     map[string]string
+		err      error
+		expected int
+	}{
+		{"unsupported locale", map[string]string{"locale": "fr"}, nil, http.StatusBadRequest},
+		{"internal error", map[string]string{"locale": "ja"}, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newMfaTestContext(tt.body)
+
+			accountSvcMock := new(svc_mock.AccountSvcMock)
+			accountSvcMock.On("ChangeLocale", service.ChangeLocaleInput{
+				UserUUID: "test-uuid",
+				Locale:   tt.body["locale"],
+			}).Return((*models.User)(nil), tt.err)
+
+			handler := NewAccountHandler(accountSvcMock)
+			handler.ChangeLocale(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestDeleteAccountSuccess(t *testing.T) {
+	c, _ := newMfaTestContext(nil)
+
+	accountSvcMock := new(svc_mock.AccountSvcMock)
+	accountSvcMock.On("DeleteAccount", "test-uuid").Return(nil)
+
+	handler := NewAccountHandler(accountSvcMock)
+	handler.DeleteAccount(c)
+
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+}
+
+func TestDeleteAccountFail(t *testing.T) {
+	c, w := newMfaTestContext(nil)
+
+	accountSvcMock := new(svc_mock.AccountSvcMock)
+	accountSvcMock.On("DeleteAccount", "test-uuid").Return(fmt.Errorf("db error"))
+
+	handler := NewAccountHandler(accountSvcMock)
+	handler.DeleteAccount(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -10,79 +10,202 @@ import (
 type AuthHandlerInterface interface {
 	Login(c *gin.Context)
 	Refresh(c *gin.Context)
+	VerifyMfa(c *gin.Context)
+	LoginWithPasskey(c *gin.Context)
+	CompletePasswordless(c *gin.Context)
 }
 
 type AuthHandlerStruct struct {
 	BaseHandler
-	service service.AuthSvcInterface
+	service    service.AuthSvcInterface
+	csrf       service.CsrfSvcInterface
+	csrfConfig service.CsrfConfig
 }
 
 func NewAuthHandler(
 	service service.AuthSvcInterface,
+	csrf service.CsrfSvcInterface,
+	csrfConfig service.CsrfConfig,
 ) *AuthHandlerStruct {
 	return &AuthHandlerStruct{
-		service: service,
+		service:    service,
+		csrf:       csrf,
+		csrfConfig: csrfConfig,
 	}
 }
 
 type loginRequest struct {
 	Email    string `form:"email" json:"email" binding:"required,email"`
-	Password string `form:"password" json:"password" binding:"required,min=8"`
+	Password string `form:"password" json:"password" binding:"required"`
+	// スペース区切り。許可されたスコープを絞り込む場合のみ指定する
+	Scope string `form:"scope" json:"scope"`
 }
 
 func (h *AuthHandlerStruct) Login(c *gin.Context) {
 	var req loginRequest
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		h.invalidRequest(c, err)
 		return
 	}
 
 	response, err := h.service.Login(service.LoginInput{
 		Email:    req.Email,
 		Password: req.Password,
+		Scope:    req.Scope,
 	})
 
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		h.errorResponse(c, err)
 		return
 	}
 
-	resp := map[string]interface{}{
-		"access_token":  response.AccessToken,
-		"refresh_token": response.RefreshToken,
-		"token_type":    "Bearer",
-		"expires_in":    3600,
+	// 2 要素目の検証が必要な場合はチャレンジトークンのみ返す
+	if response.MfaRequired {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    response.MfaToken,
+			"token_type":   service.MfaTokenType,
+			"expires_in":   service.MfaTokenExpiresIn,
+		})
+		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	h.tokenResponse(c, response)
 }
 
 type refreshRequest struct {
 	RefreshToken string `form:"refresh_token" json:"refresh_token" binding:"required"`
+	Scope        string `form:"scope" json:"scope"`
 }
 
 func (h *AuthHandlerStruct) Refresh(c *gin.Context) {
 	var req refreshRequest
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		h.invalidRequest(c, err)
 		return
 	}
 
 	response, err := h.service.Refresh(service.RefreshInput{
 		RefreshToken: req.RefreshToken,
 		IpAddress:    c.ClientIP(),
+		Scope:        req.Scope,
 	})
 
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		h.errorResponse(c, err)
+		return
+	}
+
+	h.tokenResponse(c, response)
+}
+
+type verifyMfaRequest struct {
+	MfaToken     string `form:"mfa_token" json:"mfa_token" binding:"required"`
+	Code         string `form:"code" json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `form:"recovery_code" json:"recovery_code" binding:"required_without=Code"`
+}
+
+func (h *AuthHandlerStruct) VerifyMfa(c *gin.Context) {
+	var req verifyMfaRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
 		return
 	}
 
+	response, err := h.service.VerifyMfa(service.VerifyMfaInput{
+		MfaToken:     req.MfaToken,
+		Code:         req.Code,
+		RecoveryCode: req.RecoveryCode,
+	})
+
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	h.tokenResponse(c, response)
+}
+
+func (h *AuthHandlerStruct) LoginWithPasskey(c *gin.Context) {
+	var req webauthnLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	response, err := h.service.LoginWithPasskey(service.WebauthnLoginInput{
+		CredentialID:      req.ID,
+		ClientDataJSON:    req.Response.ClientDataJSON,
+		AuthenticatorData: req.Response.AuthenticatorData,
+		Signature:         req.Response.Signature,
+		UserHandle:        req.Response.UserHandle,
+	})
+
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	h.tokenResponse(c, response)
+}
+
+type completePasswordlessRequest struct {
+	Token string `form:"token" json:"token" binding:"required_without=Code"`
+	Email string `form:"email" json:"email" binding:"required_with=Code,omitempty,email"`
+	Code  string `form:"code" json:"code" binding:"required_without=Token,omitempty,len=6,numeric"`
+}
+
+func (h *AuthHandlerStruct) CompletePasswordless(c *gin.Context) {
+	var req completePasswordlessRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	response, err := h.service.CompletePasswordless(service.PasswordlessCompleteInput{
+		Token: req.Token,
+		Email: req.Email,
+		Code:  req.Code,
+	})
+
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	if response.MfaRequired {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    response.MfaToken,
+			"token_type":   service.MfaTokenType,
+			"expires_in":   service.MfaTokenExpiresIn,
+		})
+		return
+	}
+
+	h.tokenResponse(c, response)
+}
+
+func (h *AuthHandlerStruct) tokenResponse(c *gin.Context, response *service.AuthOutput) {
 	resp := map[string]interface{}{
 		"access_token":  response.AccessToken,
 		"refresh_token": response.RefreshToken,
 		"token_type":    "Bearer",
-		"expires_in":    3600,
+		"expires_in":    response.ExpiresIn,
+	}
+	if response.Scope != "" {
+		resp["scope"] = response.Scope
+	}
+
+	// ログイン前の CSRF トークンは使えなくなるため、新しいセッションに紐付けたトークンを発行し直す
+	if h.csrfConfig.BindSession {
+		csrfToken, err := issueCsrfToken(c, h.csrf, h.csrfConfig, response.SessionID)
+		if err != nil {
+			h.errorResponse(c, err)
+			return
+		}
+		issueCsrfSessionCookie(c, h.csrf, h.csrfConfig, response.SessionID)
+		resp["csrf_token"] = csrfToken
 	}
 
 	c.JSON(http.StatusOK, resp)
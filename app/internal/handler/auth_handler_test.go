@@ -13,6 +13,7 @@ import (
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestLoginSuccess(t *testing.T) {
@@ -38,12 +39,13 @@ func TestLoginSuccess(t *testing.T) {
 	response := &service.AuthOutput{
 		AccessToken:  "access_token_value",
 		RefreshToken: "refresh_token_value",
+		ExpiresIn:    900,
 	}
 
 	authSvcMock := new(svc_mock.AuthSvcMock)
 	authSvcMock.On("Login", input).Return(response, nil)
 
-	handler := NewAuthHandler(authSvcMock)
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
 	handler.Login(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -55,7 +57,105 @@ func TestLoginSuccess(t *testing.T) {
 	assert.Equal(t, "access_token_value", result["access_token"])
 	assert.Equal(t, "refresh_token_value", result["refresh_token"])
 	assert.Equal(t, "Bearer", result["token_type"])
-	assert.Equal(t, float64(3600), result["expires_in"])
+	assert.Equal(t, float64(900), result["expires_in"])
+}
+
+func TestLoginWithScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := map[string]string{
+		"email":    "user@example.com",
+		"password": "securepassword",
+		"scope":    "account",
+	}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	input := service.LoginInput{
+		Email:    "user@example.com",
+		Password: "securepassword",
+		Scope:    "account",
+	}
+
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("Login", input).Return(&service.AuthOutput{
+		AccessToken:  "access_token_value",
+		RefreshToken: "refresh_token_value",
+		Scope:        "account",
+	}, nil)
+
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "account", result["scope"])
+	authSvcMock.AssertExpectations(t)
+}
+
+func TestLoginRotatesCsrfToken(t *testing.T) {
+	funcs.WithEnv("CSRF_TOKEN", "test_secret", t, func() {
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		req := httptest.NewRequest("POST", "/", strings.NewReader(`{"email":"user@example.com","password":"securepassword"}`))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		authSvcMock := new(svc_mock.AuthSvcMock)
+		authSvcMock.On("Login", mock.Anything).Return(&service.AuthOutput{
+			AccessToken:  "access_token_value",
+			RefreshToken: "refresh_token_value",
+			SessionID:    "session-id",
+		}, nil)
+
+		csrfSvcMock := new(svc_mock.CsrfSvcMockStruct)
+		csrfSvcMock.On("CreateCSRFToken", mock.AnythingOfType("int64"), "test_secret", "session-id").Return("bound_csrf_token", nil)
+		csrfSvcMock.On("SessionCookie", "session-id").Return("session-id.signature")
+
+		handler := NewAuthHandler(authSvcMock, csrfSvcMock, service.CsrfConfig{CookieName: "csrf_token", SessionCookieName: "csrf_session", BindSession: true})
+		handler.Login(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		result := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "bound_csrf_token", result["csrf_token"])
+		cookies := strings.Join(w.Header().Values("Set-Cookie"), "\n")
+		assert.Contains(t, cookies, "csrf_token=bound_csrf_token; Path=/; Max-Age=600")
+		assert.Contains(t, cookies, "csrf_session=session-id.signature")
+	})
+}
+
+func TestLoginRotateCsrfTokenFail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"email":"user@example.com","password":"securepassword"}`))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("Login", mock.Anything).Return(&service.AuthOutput{SessionID: "session-id"}, nil)
+
+	csrfSvcMock := new(svc_mock.CsrfSvcMockStruct)
+	csrfSvcMock.On("CreateCSRFToken", mock.Anything, mock.Anything, "session-id").Return("", fmt.Errorf("db error"))
+
+	handler := NewAuthHandler(authSvcMock, csrfSvcMock, service.CsrfConfig{BindSession: true})
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "access_token")
 }
 
 func TestLoginFail(t *testing.T) {
@@ -79,18 +179,19 @@ func TestLoginFail(t *testing.T) {
 	}
 
 	authSvcMock := new(svc_mock.AuthSvcMock)
-	authSvcMock.On("Login", input).Return(&service.AuthOutput{}, fmt.Errorf("Invalid email or password"))
+	authSvcMock.On("Login", input).Return(&service.AuthOutput{}, fmt.Errorf("%w: password mismatch", service.ErrInvalidCredentials))
 
-	handler := NewAuthHandler(authSvcMock)
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
 	handler.Login(c)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
 
 	result := map[string]interface{}{}
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "Invalid email or password", result["error"])
+	assert.Equal(t, ErrorCodeInvalidCredentials, result["code"])
+	assert.Equal(t, "invalid email or password", result["detail"])
 }
 
 func TestLoginFailedValidation(t *testing.T) {
@@ -110,11 +211,6 @@ func TestLoginFailedValidation(t *testing.T) {
 			Key:       "password",
 			ErrorType: "required",
 		},
-		{
-			Title:     "Password is too short",
-			Key:       "password",
-			ErrorType: "min",
-		},
 	}
 	for _, exp := range expected {
 		t.Run(exp.Title, func(t *testing.T) {
@@ -136,10 +232,11 @@ func TestLoginFailedValidation(t *testing.T) {
 			c.Request = req
 
 			authSvcMock := new(svc_mock.AuthSvcMock)
-			handler := NewAuthHandler(authSvcMock)
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
 			handler.Login(c)
 
 			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assertFieldError(t, w, exp.Key, exp.ErrorType)
 		})
 	}
 }
@@ -166,12 +263,13 @@ func TestRefreshSuccess(t *testing.T) {
 	response := &service.AuthOutput{
 		AccessToken:  "new_access_token",
 		RefreshToken: "new_refresh_token",
+		ExpiresIn:    900,
 	}
 
 	authSvcMock := new(svc_mock.AuthSvcMock)
 	authSvcMock.On("Refresh", input).Return(response, nil)
 
-	handler := NewAuthHandler(authSvcMock)
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
 	handler.Refresh(c)
 
 	assert.Equal(t, http.StatusOK, w.Code)
@@ -183,7 +281,7 @@ func TestRefreshSuccess(t *testing.T) {
 	assert.Equal(t, "new_access_token", result["access_token"])
 	assert.Equal(t, "new_refresh_token", result["refresh_token"])
 	assert.Equal(t, "Bearer", result["token_type"])
-	assert.Equal(t, float64(3600), result["expires_in"])
+	assert.Equal(t, float64(900), result["expires_in"])
 }
 
 func TestRefreshFail(t *testing.T) {
@@ -206,18 +304,53 @@ func TestRefreshFail(t *testing.T) {
 	}
 
 	authSvcMock := new(svc_mock.AuthSvcMock)
-	authSvcMock.On("Refresh", input).Return(&service.AuthOutput{}, fmt.Errorf("Invalid refresh token"))
+	authSvcMock.On("Refresh", input).Return(&service.AuthOutput{}, fmt.Errorf("%w: refresh token expired", service.ErrInvalidRefreshToken))
 
-	handler := NewAuthHandler(authSvcMock)
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
 	handler.Refresh(c)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
 
 	result := map[string]interface{}{}
 	err := json.Unmarshal(w.Body.Bytes(), &result)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "Invalid refresh token", result["error"])
+	assert.Equal(t, ErrorCodeInvalidRefreshToken, result["code"])
+}
+
+func TestRefreshFailInvalidScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := map[string]string{
+		"refresh_token": "valid_refresh_token",
+		"scope":         "account admin",
+	}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	input := service.RefreshInput{
+		RefreshToken: "valid_refresh_token",
+		IpAddress:    c.ClientIP(),
+		Scope:        "account admin",
+	}
+
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("Refresh", input).Return(&service.AuthOutput{}, fmt.Errorf("%w: admin", service.ErrInvalidScope))
+
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+	handler.Refresh(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrorCodeInvalidScope, result["code"])
 }
 
 func TestRefreshFailedValidation(t *testing.T) {
@@ -235,8 +368,386 @@ func TestRefreshFailedValidation(t *testing.T) {
 	c.Request = req
 
 	authSvcMock := new(svc_mock.AuthSvcMock)
-	handler := NewAuthHandler(authSvcMock)
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
 	handler.Refresh(c)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestLoginMfaRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := map[string]string{
+		"email":    "user@example.com",
+		"password": "securepassword",
+	}
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("Login", service.LoginInput{
+		Email:    "user@example.com",
+		Password: "securepassword",
+	}).Return(&service.AuthOutput{
+		MfaRequired: true,
+		MfaToken:    "mfa_token_value",
+	}, nil)
+
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, result["mfa_required"])
+	assert.Equal(t, "mfa_token_value", result["mfa_token"])
+	assert.Equal(t, "mfa", result["token_type"])
+	assert.Equal(t, float64(300), result["expires_in"])
+	assert.NotContains(t, result, "access_token")
+}
+
+func TestVerifyMfaSuccess(t *testing.T) {
+	tests := []struct {
+		title string
+		body  map[string]string
+		input service.VerifyMfaInput
+	}{
+		{
+			title: "totp code",
+			body:  map[string]string{"mfa_token": "mfa_token_value", "code": "123456"},
+			input: service.VerifyMfaInput{MfaToken: "mfa_token_value", Code: "123456"},
+		},
+		{
+			title: "recovery code",
+			body:  map[string]string{"mfa_token": "mfa_token_value", "recovery_code": "abcde-fghij"},
+			input: service.VerifyMfaInput{MfaToken: "mfa_token_value", RecoveryCode: "abcde-fghij"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			jsonBody, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			authSvcMock.On("VerifyMfa", tt.input).Return(&service.AuthOutput{
+				AccessToken:  "access_token_value",
+				RefreshToken: "refresh_token_value",
+			}, nil)
+
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.VerifyMfa(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			result := map[string]interface{}{}
+			err := json.Unmarshal(w.Body.Bytes(), &result)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "access_token_value", result["access_token"])
+			assert.Equal(t, "refresh_token_value", result["refresh_token"])
+			assert.Equal(t, "Bearer", result["token_type"])
+		})
+	}
+}
+
+func TestVerifyMfaFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		err      error
+		expected int
+	}{
+		{"invalid token", service.ErrInvalidMfaToken, http.StatusUnauthorized},
+		{"invalid code", service.ErrInvalidMfaCode, http.StatusUnauthorized},
+		{"internal error", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			jsonBody, _ := json.Marshal(map[string]string{"mfa_token": "mfa_token_value", "code": "123456"})
+			req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			authSvcMock.On("VerifyMfa", mock.Anything).Return(&service.AuthOutput{}, tt.err)
+
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.VerifyMfa(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestVerifyMfaFailedValidation(t *testing.T) {
+	tests := map[string]map[string]string{
+		"mfa token is required": {"code": "123456"},
+		"code or recovery code": {"mfa_token": "mfa_token_value"},
+		"code is not numeric":   {"mfa_token": "mfa_token_value", "code": "abcdef"},
+		"code is too short":     {"mfa_token": "mfa_token_value", "code": "12345"},
+	}
+
+	for title, body := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			jsonBody, _ := json.Marshal(body)
+			req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.VerifyMfa(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func newPasskeyLoginBody() map[string]any {
+	return map[string]any{
+		"id":   "credential-id",
+		"type": "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    "Y2xpZW50RGF0YQ",
+			"authenticatorData": "YXV0aERhdGE",
+			"signature":         "c2lnbmF0dXJl",
+			"userHandle":        "dGVzdC11dWlk",
+		},
+	}
+}
+
+func TestLoginWithPasskeySuccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(newPasskeyLoginBody())
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("LoginWithPasskey", service.WebauthnLoginInput{
+		CredentialID:      "credential-id",
+		ClientDataJSON:    []byte("clientData"),
+		AuthenticatorData: []byte("authData"),
+		Signature:         []byte("signature"),
+		UserHandle:        []byte("test-uuid"),
+	}).Return(&service.AuthOutput{
+		AccessToken:  "access_token_value",
+		RefreshToken: "refresh_token_value",
+	}, nil)
+
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+	handler.LoginWithPasskey(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "access_token_value", result["access_token"])
+	assert.Equal(t, "refresh_token_value", result["refresh_token"])
+	assert.Equal(t, "Bearer", result["token_type"])
+}
+
+func TestLoginWithPasskeyFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		err      error
+		expected int
+	}{
+		{"invalid response", service.ErrInvalidWebauthnResponse, http.StatusUnauthorized},
+		{"internal error", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			jsonBody, _ := json.Marshal(newPasskeyLoginBody())
+			req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			authSvcMock.On("LoginWithPasskey", mock.Anything).Return(&service.AuthOutput{}, tt.err)
+
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.LoginWithPasskey(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestLoginWithPasskeyFailedValidation(t *testing.T) {
+	tests := map[string]func(body map[string]any){
+		"id is required":       func(body map[string]any) { delete(body, "id") },
+		"type must be public":  func(body map[string]any) { body["type"] = "password" },
+		"invalid base64url":    func(body map[string]any) { body["response"].(map[string]string)["signature"] = "!!" },
+		"signature required":   func(body map[string]any) { delete(body["response"].(map[string]string), "signature") },
+		"user handle required": func(body map[string]any) { delete(body["response"].(map[string]string), "userHandle") },
+	}
+
+	for title, modify := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			body := newPasskeyLoginBody()
+			modify(body)
+			jsonBody, _ := json.Marshal(body)
+			req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.LoginWithPasskey(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestCompletePasswordlessSuccess(t *testing.T) {
+	tests := []struct {
+		title string
+		body  map[string]string
+		input service.PasswordlessCompleteInput
+	}{
+		{
+			title: "magic link",
+			body:  map[string]string{"token": "link_token_value"},
+			input: service.PasswordlessCompleteInput{Token: "link_token_value"},
+		},
+		{
+			title: "email code",
+			body:  map[string]string{"email": "user@example.com", "code": "123456"},
+			input: service.PasswordlessCompleteInput{Email: "user@example.com", Code: "123456"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newPasswordlessTestContext(tt.body)
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			authSvcMock.On("CompletePasswordless", tt.input).Return(&service.AuthOutput{
+				AccessToken:  "access_token_value",
+				RefreshToken: "refresh_token_value",
+			}, nil)
+
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.CompletePasswordless(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			result := map[string]interface{}{}
+			err := json.Unmarshal(w.Body.Bytes(), &result)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "access_token_value", result["access_token"])
+			assert.Equal(t, "refresh_token_value", result["refresh_token"])
+			assert.Equal(t, "Bearer", result["token_type"])
+		})
+	}
+}
+
+func TestCompletePasswordlessMfaRequired(t *testing.T) {
+	c, w := newPasswordlessTestContext(map[string]string{"token": "link_token_value"})
+
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("CompletePasswordless", mock.Anything).Return(&service.AuthOutput{
+		MfaRequired: true,
+		MfaToken:    "mfa_token_value",
+	}, nil)
+
+	handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+	handler.CompletePasswordless(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, result["mfa_required"])
+	assert.Equal(t, "mfa_token_value", result["mfa_token"])
+	assert.Nil(t, result["access_token"])
+}
+
+func TestCompletePasswordlessFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		err      error
+		expected int
+	}{
+		{"invalid token", service.ErrInvalidPasswordlessToken, http.StatusUnauthorized},
+		{"invalid code", service.ErrInvalidPasswordlessCode, http.StatusUnauthorized},
+		{"internal error", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newPasswordlessTestContext(map[string]string{"email": "user@example.com", "code": "123456"})
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			authSvcMock.On("CompletePasswordless", mock.Anything).Return(&service.AuthOutput{}, tt.err)
+
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.CompletePasswordless(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestCompletePasswordlessFailedValidation(t *testing.T) {
+	tests := map[string]map[string]string{
+		"token or code":      {},
+		"email is required":  {"code": "123456"},
+		"email is invalid":   {"email": "invalid", "code": "123456"},
+		"code is not digits": {"email": "user@example.com", "code": "abcdef"},
+		"code is too long":   {"email": "user@example.com", "code": "1234567"},
+	}
+
+	for title, body := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newPasswordlessTestContext(body)
+
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			handler := NewAuthHandler(authSvcMock, nil, service.CsrfConfig{})
+			handler.CompletePasswordless(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
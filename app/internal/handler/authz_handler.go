@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthzHandlerInterface interface {
+	Check(c *gin.Context)
+	CheckBatch(c *gin.Context)
+}
+
+type AuthzHandlerStruct struct {
+	BaseHandler
+	service service.AuthzSvcInterface
+}
+
+func NewAuthzHandler(
+	service service.AuthzSvcInterface,
+) *AuthzHandlerStruct {
+	return &AuthzHandlerStruct{
+		service: service,
+	}
+}
+
+// subject はユーザーの UUID、resource は条件式の resource から参照する任意の属性
+type authzCheckRequest struct {
+	Subject  string         `json:"subject" binding:"required,max=36"`
+	Action   string         `json:"action" binding:"required,max=100"`
+	Resource map[string]any `json:"resource"`
+}
+
+type authzCheckBatchRequest struct {
+	Checks []authzCheckRequest `json:"checks" binding:"required,min=1,max=100,dive"`
+}
+
+type authzDecisionResponse struct {
+	Allowed bool   `json:"allowed"`
+	Policy  string `json:"policy,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+func newAuthzRequest(req authzCheckRequest) service.AuthzRequest {
+	return service.AuthzRequest{
+		Subject:  req.Subject,
+		Action:   req.Action,
+		Resource: req.Resource,
+	}
+}
+
+func newAuthzDecisionResponse(decision *service.AuthzDecision) authzDecisionResponse {
+	return authzDecisionResponse{
+		Allowed: decision.Allowed,
+		Policy:  decision.Policy,
+		Reason:  decision.Reason,
+	}
+}
+
+// 拒否した場合も判定結果として 200 を返す
+func (h *AuthzHandlerStruct) Check(c *gin.Context) {
+	var req authzCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	decision, err := h.service.Check(newAuthzRequest(req))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthzDecisionResponse(decision))
+}
+
+// results はリクエストの checks と同じ順に並べる
+func (h *AuthzHandlerStruct) CheckBatch(c *gin.Context) {
+	var req authzCheckBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	requests := make([]service.AuthzRequest, 0, len(req.Checks))
+	for _, check := range req.Checks {
+		requests = append(requests, newAuthzRequest(check))
+	}
+	decisions, err := h.service.CheckBatch(requests)
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	results := make([]authzDecisionResponse, 0, len(decisions))
+	for i := range decisions {
+		results = append(results, newAuthzDecisionResponse(&decisions[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newAuthzTestContext(body any) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/authz/check", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	return c, w
+}
+
+func decodeAuthz(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	result := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return result
+}
+
+func TestAuthzCheck(t *testing.T) {
+	c, w := newAuthzTestContext(map[string]any{
+		"subject":  "user-uuid",
+		"action":   "documents:update",
+		"resource": map[string]any{"owner": "user-uuid", "size": 10},
+	})
+
+	authzSvcMock := new(svc_mock.AuthzSvcMock)
+	authzSvcMock.On("Check", service.AuthzRequest{
+		Subject:  "user-uuid",
+		Action:   "documents:update",
+		Resource: map[string]any{"owner": "user-uuid", "size": float64(10)},
+	}).Return(&service.AuthzDecision{Allowed: true, Policy: "owner-edit", Reason: service.AuthzReasonAllowed}, nil)
+
+	handler := NewAuthzHandler(authzSvcMock)
+	handler.Check(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]any{"allowed": true, "policy": "owner-edit", "reason": "allowed"}, decodeAuthz(t, w))
+}
+
+func TestAuthzCheckDenied(t *testing.T) {
+	c, w := newAuthzTestContext(map[string]any{"subject": "user-uuid", "action": "documents:update"})
+
+	authzSvcMock := new(svc_mock.AuthzSvcMock)
+	authzSvcMock.On("Check", mock.Anything).Return(&service.AuthzDecision{Reason: service.AuthzReasonNoMatch}, nil)
+
+	handler := NewAuthzHandler(authzSvcMock)
+	handler.Check(c)
+
+	// 拒否も判定結果として 200 で返す
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]any{"allowed": false, "reason": "no_matching_policy"}, decodeAuthz(t, w))
+}
+
+func TestAuthzCheckFail(t *testing.T) {
+	tests := map[string]struct {
+		body   map[string]any
+		err    error
+		status int
+	}{
+		"missing subject": {map[string]any{"action": "documents:read"}, nil, http.StatusBadRequest},
+		"missing action":  {map[string]any{"subject": "user-uuid"}, nil, http.StatusBadRequest},
+		"internal error":  {map[string]any{"subject": "user-uuid", "action": "documents:read"}, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newAuthzTestContext(tt.body)
+
+			authzSvcMock := new(svc_mock.AuthzSvcMock)
+			authzSvcMock.On("Check", mock.Anything).Return((*service.AuthzDecision)(nil), tt.err)
+
+			handler := NewAuthzHandler(authzSvcMock)
+			handler.Check(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestAuthzCheckBatch(t *testing.T) {
+	c, w := newAuthzTestContext(map[string]any{
+		"checks": []map[string]any{
+			{"subject": "user-uuid", "action": "documents:read"},
+			{"subject": "user-uuid", "action": "documents:delete", "resource": map[string]any{"id": "doc-1"}},
+		},
+	})
+
+	authzSvcMock := new(svc_mock.AuthzSvcMock)
+	authzSvcMock.On("CheckBatch", []service.AuthzRequest{
+		{Subject: "user-uuid", Action: "documents:read"},
+		{Subject: "user-uuid", Action: "documents:delete", Resource: map[string]any{"id": "doc-1"}},
+	}).Return([]service.AuthzDecision{
+		{Allowed: true, Policy: "read-documents", Reason: service.AuthzReasonAllowed},
+		{Policy: "archived", Reason: service.AuthzReasonDenied},
+	}, nil)
+
+	handler := NewAuthzHandler(authzSvcMock)
+	handler.CheckBatch(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []any{
+		map[string]any{"allowed": true, "policy": "read-documents", "reason": "allowed"},
+		map[string]any{"allowed": false, "policy": "archived", "reason": "denied"},
+	}, decodeAuthz(t, w)["results"])
+}
+
+func TestAuthzCheckBatchFail(t *testing.T) {
+	tooMany := make([]map[string]any, 101)
+	for i := range tooMany {
+		tooMany[i] = map[string]any{"subject": "user-uuid", "action": "documents:read"}
+	}
+
+	tests := map[string]struct {
+		body   map[string]any
+		err    error
+		status int
+	}{
+		"missing checks": {map[string]any{}, nil, http.StatusBadRequest},
+		"empty checks":   {map[string]any{"checks": []any{}}, nil, http.StatusBadRequest},
+		"too many":       {map[string]any{"checks": tooMany}, nil, http.StatusBadRequest},
+		"invalid check":  {map[string]any{"checks": []map[string]any{{"subject": "user-uuid"}}}, nil, http.StatusBadRequest},
+		"internal error": {map[string]any{"checks": tooMany[:1]}, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newAuthzTestContext(tt.body)
+
+			authzSvcMock := new(svc_mock.AuthzSvcMock)
+			authzSvcMock.On("CheckBatch", mock.Anything).Return([]service.AuthzDecision(nil), tt.err)
+
+			handler := NewAuthzHandler(authzSvcMock)
+			handler.CheckBatch(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -1,3 +1,13 @@
 package handler
 
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/gin-gonic/gin"
+)
+
 type BaseHandler struct{}
+
+// Accept-Language（なければ認証済みのユーザーが設定した言語）からエラーメッセージ等の言語を決める
+func (h *BaseHandler) locale(c *gin.Context) string {
+	return problemjson.Locale(c)
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
@@ -15,21 +16,68 @@ type CSRFHandlerInterface interface {
 type CSRFHandlerStruct struct {
 	BaseHandler
 	service service.CsrfSvcInterface
+	config  service.CsrfConfig
 }
 
 func NewCSRFHandler(
 	service service.CsrfSvcInterface,
+	config service.CsrfConfig,
 ) *CSRFHandlerStruct {
 	return &CSRFHandlerStruct{
 		service: service,
+		config:  config,
 	}
 }
 
 func (h *CSRFHandlerStruct) CsrfGet(c *gin.Context) {
-	secret := os.Getenv("CSRF_TOKEN")
-	token := h.service.CreateCSRFToken(time.Now().Unix(), secret)
-	c.SetCookie("csrf_token", token, 3600, "/", "", false, true)
+	// ログイン済みの場合はアクセストークンまたはセッション Cookie のセッションに紐付ける
+	token, err := issueCsrfToken(c, h.service, h.config, csrfSessionID(c, h.service, h.config))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
 	c.JSON(200, gin.H{
 		"csrf_token": token,
 	})
 }
+
+// トークンを発行し、double-submit 用の Cookie に載せる
+func issueCsrfToken(
+	c *gin.Context,
+	svc service.CsrfSvcInterface,
+	config service.CsrfConfig,
+	sessionID string,
+) (string, error) {
+	token, err := svc.CreateCSRFToken(time.Now().Unix(), os.Getenv("CSRF_TOKEN"), sessionID)
+	if err != nil {
+		return "", err
+	}
+	c.SetSameSite(config.CookieSameSite)
+	c.SetCookie(config.CookieName, token, service.CsrfTokenExpiresIn, "/", config.CookieDomain, config.CookieSecure, true)
+	return token, nil
+}
+
+// ログイン時にセッション ID を署名付きの Cookie に載せ、Bearer のない Cookie だけのリクエストでもセッションを特定できるようにする
+// 有効期限は付けず、ブラウザを閉じるまでとする
+func issueCsrfSessionCookie(
+	c *gin.Context,
+	svc service.CsrfSvcInterface,
+	config service.CsrfConfig,
+	sessionID string,
+) {
+	c.SetSameSite(config.CookieSameSite)
+	c.SetCookie(config.SessionCookieName, svc.SessionCookie(sessionID), 0, "/", config.CookieDomain, config.CookieSecure, true)
+}
+
+func csrfSessionID(
+	c *gin.Context,
+	svc service.CsrfSvcInterface,
+	config service.CsrfConfig,
+) string {
+	accessToken := ""
+	if scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " "); strings.EqualFold(scheme, "Bearer") {
+		accessToken = token
+	}
+	sessionCookie, _ := c.Cookie(config.SessionCookieName)
+	return svc.SessionID(accessToken, sessionCookie)
+}
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
 	"github.com/gin-gonic/gin"
@@ -24,18 +26,102 @@ func TestCSRFHandler(t *testing.T) {
 		c.Request = req
 
 		csrfSvcMock := new(svc_mock.CsrfSvcMockStruct)
+		csrfSvcMock.On("SessionID", "", "").Return("")
 		csrfSvcMock.On(
 			"CreateCSRFToken",
 			mock.AnythingOfType("int64"),
 			"test_secret",
-		).Return("mocked_csrf_token")
+			"",
+		).Return("mocked_csrf_token", nil)
 
 		handler := NewCSRFHandler(
 			csrfSvcMock,
+			service.CsrfConfig{
+				CookieName:     "csrf_token",
+				CookieDomain:   "example.com",
+				CookieSecure:   true,
+				CookieSameSite: http.SameSiteStrictMode,
+			},
 		)
 		handler.CsrfGet(c)
 
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.Contains(t, w.Body.String(), "mocked_csrf_token")
+
+		cookie := w.Header().Get("Set-Cookie")
+		assert.Contains(t, cookie, "csrf_token=mocked_csrf_token")
+		assert.Contains(t, cookie, "Domain=example.com")
+		assert.Contains(t, cookie, "Secure")
+		assert.Contains(t, cookie, "HttpOnly")
+		assert.Contains(t, cookie, "SameSite=Strict")
+	})
+}
+
+func TestCSRFHandlerBindSession(t *testing.T) {
+	funcs.WithEnv("CSRF_TOKEN", "test_secret", t, func() {
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer access-token")
+		c.Request = req
+
+		csrfSvcMock := new(svc_mock.CsrfSvcMockStruct)
+		csrfSvcMock.On("SessionID", "access-token", "").Return("session-id")
+		csrfSvcMock.On(
+			"CreateCSRFToken",
+			mock.AnythingOfType("int64"),
+			"test_secret",
+			"session-id",
+		).Return("bound_csrf_token", nil)
+
+		handler := NewCSRFHandler(csrfSvcMock, service.CsrfConfig{CookieName: "csrf_token", BindSession: true})
+		handler.CsrfGet(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "bound_csrf_token")
+		assert.Contains(t, w.Header().Get("Set-Cookie"), "csrf_token=bound_csrf_token")
+		csrfSvcMock.AssertExpectations(t)
 	})
 }
+
+func TestCSRFHandlerBindCookieSession(t *testing.T) {
+	funcs.WithEnv("CSRF_TOKEN", "test_secret", t, func() {
+		gin.SetMode(gin.TestMode)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: "csrf_session", Value: "session-id.signature"})
+		c.Request = req
+
+		csrfSvcMock := new(svc_mock.CsrfSvcMockStruct)
+		csrfSvcMock.On("SessionID", "", "session-id.signature").Return("session-id")
+		csrfSvcMock.On("CreateCSRFToken", mock.AnythingOfType("int64"), "test_secret", "session-id").Return("bound_csrf_token", nil)
+
+		handler := NewCSRFHandler(csrfSvcMock, service.CsrfConfig{CookieName: "csrf_token", SessionCookieName: "csrf_session", BindSession: true})
+		handler.CsrfGet(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "bound_csrf_token")
+		csrfSvcMock.AssertExpectations(t)
+	})
+}
+
+func TestCSRFHandlerCreateFail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+
+	csrfSvcMock := new(svc_mock.CsrfSvcMockStruct)
+	csrfSvcMock.On("SessionID", "", "").Return("")
+	csrfSvcMock.On("CreateCSRFToken", mock.Anything, mock.Anything, "").Return("", fmt.Errorf("db error"))
+
+	handler := NewCSRFHandler(csrfSvcMock, service.CsrfConfig{CookieName: "csrf_token"})
+	handler.CsrfGet(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Set-Cookie"))
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// クライアントが分岐に使う機械判読用のエラーコード。値は変更しない
+const (
+	ErrorCodeInvalidRequest           = "invalid_request"
+	ErrorCodePasswordPolicy           = "password_policy_violation"
+	ErrorCodeInvalidCredentials       = "invalid_credentials"
+	ErrorCodeInvalidRefreshToken      = "invalid_refresh_token"
+	ErrorCodeInvalidMfaToken          = "invalid_mfa_token"
+	ErrorCodeInvalidMfaCode           = "invalid_mfa_code"
+	ErrorCodeTotpNotEnabled           = "totp_not_enabled"
+	ErrorCodeTotpAlreadyEnabled       = "totp_already_enabled"
+	ErrorCodeInvalidWebauthnResponse  = "invalid_webauthn_response"
+	ErrorCodeWebauthnCredentialExists = "webauthn_credential_exists"
+	ErrorCodeInvalidPasswordless      = "invalid_passwordless_token"
+	ErrorCodeEmailAlreadyInUse        = "email_already_in_use"
+	ErrorCodeOauthClientNotFound      = "oauth_client_not_found"
+	ErrorCodeInvalidClientMetadata    = "invalid_client_metadata"
+	ErrorCodeInvalidUserCode          = "invalid_user_code"
+	ErrorCodeInvalidConsentChallenge  = "invalid_consent_challenge"
+	ErrorCodeConsentNotFound          = "consent_not_found"
+	ErrorCodeInvalidScope             = "invalid_scope"
+	ErrorCodeRoleNotFound             = "role_not_found"
+	ErrorCodeRoleAlreadyExists        = "role_already_exists"
+	ErrorCodeInvalidRole              = "invalid_role"
+	ErrorCodeUserNotFound             = "user_not_found"
+	ErrorCodeUserRoleNotFound         = "user_role_not_found"
+	ErrorCodeInternal                 = "internal_error"
+)
+
+// ミドルウェアのエラーと同じ形式で返す（problemjson）
+const (
+	ProblemContentType = problemjson.ContentType
+	ProblemTypePrefix  = problemjson.TypePrefix
+)
+
+// RFC 7807 の problem details。code と errors は拡張メンバー
+type problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Code   string       `json:"code"`
+	Errors []fieldError `json:"errors,omitempty"`
+}
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+	title  string
+}
+
+// service / repositories のエラーと HTTP ステータスの対応。ここにないエラーは 500 として扱う
+var errorMappings = []errorMapping{
+	{service.ErrInvalidCredentials, http.StatusUnauthorized, ErrorCodeInvalidCredentials, "Invalid credentials"},
+	{service.ErrInvalidRefreshToken, http.StatusUnauthorized, ErrorCodeInvalidRefreshToken, "Invalid refresh token"},
+	{service.ErrInvalidMfaToken, http.StatusUnauthorized, ErrorCodeInvalidMfaToken, "Invalid MFA token"},
+	{service.ErrInvalidMfaCode, http.StatusUnauthorized, ErrorCodeInvalidMfaCode, "Invalid MFA code"},
+	{service.ErrInvalidWebauthnResponse, http.StatusUnauthorized, ErrorCodeInvalidWebauthnResponse, "Invalid WebAuthn response"},
+	{service.ErrInvalidPasswordlessToken, http.StatusUnauthorized, ErrorCodeInvalidPasswordless, "Invalid passwordless token"},
+	{service.ErrInvalidPasswordlessCode, http.StatusUnauthorized, ErrorCodeInvalidPasswordless, "Invalid passwordless token"},
+	{service.ErrTotpNotEnabled, http.StatusBadRequest, ErrorCodeTotpNotEnabled, "TOTP not enabled"},
+	{service.ErrTotpAlreadyEnabled, http.StatusConflict, ErrorCodeTotpAlreadyEnabled, "TOTP already enabled"},
+	{service.ErrWebauthnCredentialExists, http.StatusConflict, ErrorCodeWebauthnCredentialExists, "WebAuthn credential already registered"},
+	{service.ErrEmailAlreadyInUse, http.StatusConflict, ErrorCodeEmailAlreadyInUse, "Email already in use"},
+	{service.ErrOauthClientNotFound, http.StatusNotFound, ErrorCodeOauthClientNotFound, "OAuth client not found"},
+	{service.ErrInvalidOauthClientMetadata, http.StatusBadRequest, ErrorCodeInvalidClientMetadata, "Invalid client metadata"},
+	{service.ErrInvalidOauthUserCode, http.StatusBadRequest, ErrorCodeInvalidUserCode, "Invalid user code"},
+	{service.ErrInvalidOauthConsentChallenge, http.StatusBadRequest, ErrorCodeInvalidConsentChallenge, "Invalid consent challenge"},
+	{service.ErrOauthConsentNotFound, http.StatusNotFound, ErrorCodeConsentNotFound, "Consent not found"},
+	{service.ErrInvalidScope, http.StatusBadRequest, ErrorCodeInvalidScope, "Invalid scope"},
+	{service.ErrRoleNotFound, http.StatusNotFound, ErrorCodeRoleNotFound, "Role not found"},
+	{service.ErrRoleAlreadyExists, http.StatusConflict, ErrorCodeRoleAlreadyExists, "Role already exists"},
+	{service.ErrInvalidRole, http.StatusBadRequest, ErrorCodeInvalidRole, "Invalid role"},
+	{service.ErrUserNotFound, http.StatusNotFound, ErrorCodeUserNotFound, "User not found"},
+	{service.ErrUserRoleNotFound, http.StatusNotFound, ErrorCodeUserRoleNotFound, "Role not assigned"},
+}
+
+func writeProblem(c *gin.Context, locale string, p problem) {
+	p.Type = ProblemTypePrefix + p.Code
+	problemjson.Write(c, locale, p.Status, p)
+}
+
+// リクエストのバインド・バリデーションエラー。項目ごとのエラーを errors で返す
+func (h *BaseHandler) invalidRequest(c *gin.Context, err error) {
+	locale := h.locale(c)
+	fields := fieldErrors(err, locale)
+	detail := i18n.T(locale, "error.invalid_request")
+	if len(fields) == 0 {
+		detail = i18n.T(locale, "error.malformed_request")
+	}
+	writeProblem(c, locale, problem{
+		Title:  "Invalid request",
+		Status: http.StatusBadRequest,
+		Detail: detail,
+		Code:   ErrorCodeInvalidRequest,
+		Errors: fields,
+	})
+}
+
+// エラーを対応するステータスとコードで返す
+// 想定外のエラーは内部の詳細（SQL やライブラリのメッセージ）を返さず、ログにのみ残す
+func (h *BaseHandler) errorResponse(c *gin.Context, err error) {
+	h.writeError(c, err, false)
+}
+
+// ログイン済みのユーザーが送ったコードや応答の誤りは、再ログインしても解決しないため 401 ではなく 400 を返す
+func (h *BaseHandler) authenticatedErrorResponse(c *gin.Context, err error) {
+	h.writeError(c, err, true)
+}
+
+func (h *BaseHandler) writeError(c *gin.Context, err error, authenticated bool) {
+	locale := h.locale(c)
+
+	var policyErr *service.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		fields := make([]fieldError, 0, len(policyErr.Violations))
+		for _, v := range policyErr.Violations {
+			args := []any{}
+			if v.Param != "" {
+				args = append(args, v.Param)
+			}
+			fields = append(fields, fieldError{
+				Field:   "password",
+				Code:    v.Code,
+				Param:   v.Param,
+				Message: i18n.T(locale, "password_policy."+v.Code, args...),
+			})
+		}
+		writeProblem(c, locale, problem{
+			Title:  "Password policy violation",
+			Status: http.StatusBadRequest,
+			Detail: i18n.T(locale, "error."+ErrorCodePasswordPolicy),
+			Code:   ErrorCodePasswordPolicy,
+			Errors: fields,
+		})
+		return
+	}
+
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			status := m.status
+			if authenticated && status == http.StatusUnauthorized {
+				status = http.StatusBadRequest
+			}
+			// ラップされた詳細（未登録かパスワード誤りか等）は返さず、コードに対応するメッセージのみ返す
+			writeProblem(c, locale, problem{
+				Title:  m.title,
+				Status: status,
+				Detail: i18n.T(locale, "error."+m.code),
+				Code:   m.code,
+			})
+			return
+		}
+	}
+
+	log.Printf("[handler] %s %s: %v", c.Request.Method, c.FullPath(), err)
+	writeProblem(c, locale, problem{
+		Title:  "Internal server error",
+		Status: http.StatusInternalServerError,
+		Code:   ErrorCodeInternal,
+	})
+}
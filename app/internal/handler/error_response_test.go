@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := map[string]struct {
+		err           error
+		authenticated bool
+		status        int
+		code          string
+	}{
+		"invalid credentials":       {fmt.Errorf("%w: email not found", service.ErrInvalidCredentials), false, http.StatusUnauthorized, ErrorCodeInvalidCredentials},
+		"invalid refresh token":     {fmt.Errorf("%w: refresh token already used", service.ErrInvalidRefreshToken), false, http.StatusUnauthorized, ErrorCodeInvalidRefreshToken},
+		"invalid mfa code":          {service.ErrInvalidMfaCode, false, http.StatusUnauthorized, ErrorCodeInvalidMfaCode},
+		"authenticated mfa code":    {service.ErrInvalidMfaCode, true, http.StatusBadRequest, ErrorCodeInvalidMfaCode},
+		"authenticated webauthn":    {service.ErrInvalidWebauthnResponse, true, http.StatusBadRequest, ErrorCodeInvalidWebauthnResponse},
+		"totp not enabled":          {service.ErrTotpNotEnabled, true, http.StatusBadRequest, ErrorCodeTotpNotEnabled},
+		"email already in use":      {service.ErrEmailAlreadyInUse, false, http.StatusConflict, ErrorCodeEmailAlreadyInUse},
+		"credential already exists": {service.ErrWebauthnCredentialExists, true, http.StatusConflict, ErrorCodeWebauthnCredentialExists},
+		"invalid client metadata":   {fmt.Errorf("%w: invalid scope", service.ErrInvalidOauthClientMetadata), false, http.StatusBadRequest, ErrorCodeInvalidClientMetadata},
+		"invalid user code":         {service.ErrInvalidOauthUserCode, true, http.StatusBadRequest, ErrorCodeInvalidUserCode},
+		"password policy":           {&service.PasswordPolicyError{}, false, http.StatusBadRequest, ErrorCodePasswordPolicy},
+		"internal":                  {errors.New("Error 1045: Access denied for user 'auth'@'10.0.0.1'"), false, http.StatusInternalServerError, ErrorCodeInternal},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/", nil)
+
+			h := &BaseHandler{}
+			if tt.authenticated {
+				h.authenticatedErrorResponse(c, tt.err)
+			} else {
+				h.errorResponse(c, tt.err)
+			}
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+			var result problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, tt.code, result.Code)
+			assert.Equal(t, ProblemTypePrefix+tt.code, result.Type)
+			assert.Equal(t, tt.status, result.Status)
+			assert.NotEmpty(t, result.Title)
+			// ラップした内部の詳細はクライアントに返さない
+			assert.NotContains(t, w.Body.String(), "not found")
+			assert.NotContains(t, w.Body.String(), "already used")
+			assert.NotContains(t, w.Body.String(), "Access denied")
+		})
+	}
+}
+
+func TestInvalidRequest(t *testing.T) {
+	type nestedRequest struct {
+		Response struct {
+			ClientDataJSON string `json:"clientDataJSON" binding:"required"`
+		} `json:"response"`
+	}
+	type request struct {
+		Email    string        `form:"email" json:"email" binding:"required,email"`
+		Password string        `json:"password" binding:"required,min=8"`
+		Code     string        `json:"code" binding:"required_without=Token"`
+		Token    string        `json:"token"`
+		Count    int           `json:"count"`
+		Nested   nestedRequest `json:"nested"`
+	}
+
+	tests := map[string]struct {
+		body     string
+		expected []fieldError
+	}{
+		"validation": {
+			`{"email": "invalid", "password": "short", "nested": {"response": {}}}`,
+			[]fieldError{
+				{Field: "email", Code: "email", Message: "email must be a valid email address"},
+				{Field: "password", Code: "min", Param: "8", Message: "password must be at least 8 characters"},
+				{Field: "code", Code: "required_without", Message: "code is required"},
+				{Field: "nested.response.clientDataJSON", Code: "required", Message: "nested.response.clientDataJSON is required"},
+			},
+		},
+		"type mismatch": {
+			`{"count": "one"}`,
+			[]fieldError{{Field: "count", Code: "type", Param: "int", Message: "count has an invalid type"}},
+		},
+		"malformed": {
+			`{"email":`,
+			nil,
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			var req request
+			err := c.ShouldBind(&req)
+			assert.Error(t, err)
+
+			h := &BaseHandler{}
+			h.invalidRequest(c, err)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+
+			var result problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, ErrorCodeInvalidRequest, result.Code)
+			assert.Equal(t, http.StatusBadRequest, result.Status)
+			assert.NotEmpty(t, result.Detail)
+			assert.Equal(t, tt.expected, result.Errors)
+			// 構造体名などの内部の文字列は返さない
+			assert.NotContains(t, w.Body.String(), "Key: ")
+		})
+	}
+}
+
+func TestInvalidRequestLocalized(t *testing.T) {
+	type request struct {
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password" binding:"required,min=8"`
+		Unknown  string `json:"unknown" binding:"required,hostname"`
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/", strings.NewReader(`{"email": "invalid", "password": "short", "unknown": "-"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("Accept-Language", "ja-JP,ja;q=0.9")
+
+	var req request
+	h := &BaseHandler{}
+	h.invalidRequest(c, c.ShouldBind(&req))
+
+	assert.Equal(t, "ja", w.Header().Get("Content-Language"))
+
+	var result problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "入力内容に誤りがあります", result.Detail)
+	assert.Equal(t, []fieldError{
+		{Field: "email", Code: "email", Message: "メールアドレスの形式が正しくありません"},
+		{Field: "password", Code: "min", Param: "8", Message: "パスワードは8文字以上で入力してください"},
+		// カタログにないルールは汎用のメッセージにする
+		{Field: "unknown", Code: "hostname", Message: "unknownが正しくありません"},
+	}, result.Errors)
+}
+
+func TestErrorResponseLocalized(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		detail   string
+		messages []string
+	}{
+		"mapped error": {
+			fmt.Errorf("%w: email not found", service.ErrInvalidCredentials),
+			"メールアドレスまたはパスワードが正しくありません",
+			nil,
+		},
+		"password policy": {
+			&service.PasswordPolicyError{Violations: []service.PasswordPolicyViolation{
+				{Code: service.PasswordViolationTooShort, Message: "password must be at least 12 characters", Param: "12"},
+				{Code: service.PasswordViolationBreached, Message: "password has appeared in a data breach"},
+			}},
+			"パスワードがパスワードポリシーを満たしていません",
+			[]string{"パスワードは12文字以上で入力してください", "このパスワードは過去の漏洩で流出しています"},
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/", nil)
+			c.Request.Header.Set("Accept-Language", "ja")
+
+			h := &BaseHandler{}
+			h.errorResponse(c, tt.err)
+
+			var result problem
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, tt.detail, result.Detail)
+			assert.Len(t, result.Errors, len(tt.messages))
+			for i, message := range tt.messages {
+				assert.Equal(t, message, result.Errors[i].Message)
+			}
+		})
+	}
+}
+
+// バリデーションエラーのレスポンスに、指定した項目とルールのエラーが含まれることを確認する
+func assertFieldError(t *testing.T, w *httptest.ResponseRecorder, field string, code string) {
+	t.Helper()
+	var result problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	for _, f := range result.Errors {
+		if f.Field == field && f.Code == code {
+			return
+		}
+	}
+	t.Errorf("expected field error %s/%s, got %+v", field, code, result.Errors)
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type MfaHandlerInterface interface {
+	EnrollTotp(c *gin.Context)
+	ConfirmTotp(c *gin.Context)
+	DisableTotp(c *gin.Context)
+}
+
+type MfaHandlerStruct struct {
+	BaseHandler
+	service service.MfaSvcInterface
+}
+
+func NewMfaHandler(
+	service service.MfaSvcInterface,
+) *MfaHandlerStruct {
+	return &MfaHandlerStruct{
+		service: service,
+	}
+}
+
+type totpCodeRequest struct {
+	Code string `form:"code" json:"code" binding:"required,len=6,numeric"`
+}
+
+func (h *MfaHandlerStruct) EnrollTotp(c *gin.Context) {
+	output, err := h.service.EnrollTotp(c.GetString(middleware.AuthUserUUIDKey))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      output.Secret,
+		"otpauth_uri": output.URI,
+		"qr_code":     output.QRCode,
+	})
+}
+
+func (h *MfaHandlerStruct) ConfirmTotp(c *gin.Context) {
+	var req totpCodeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	codes, err := h.service.ConfirmTotp(service.TotpCodeInput{
+		UserUUID: c.GetString(middleware.AuthUserUUIDKey),
+		Code:     req.Code,
+	})
+	if err != nil {
+		h.authenticatedErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":        true,
+		"recovery_codes": codes,
+	})
+}
+
+func (h *MfaHandlerStruct) DisableTotp(c *gin.Context) {
+	var req totpCodeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	err := h.service.DisableTotp(service.TotpCodeInput{
+		UserUUID: c.GetString(middleware.AuthUserUUIDKey),
+		Code:     req.Code,
+	})
+	if err != nil {
+		h.authenticatedErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
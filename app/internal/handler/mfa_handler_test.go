@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newMfaTestContext(body map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+
+	return c, w
+}
+
+func TestEnrollTotpSuccess(t *testing.T) {
+	c, w := newMfaTestContext(nil)
+
+	mfaSvcMock := new(svc_mock.MfaSvcMock)
+	mfaSvcMock.On("EnrollTotp", "test-uuid").Return(&service.TotpEnrollOutput{
+		Secret: "SECRET",
+		URI:    "otpauth://totp/issuer:user@example.com?secret=SECRET",
+		QRCode: "data:image/png;base64,xxxx",
+	}, nil)
+
+	handler := NewMfaHandler(mfaSvcMock)
+	handler.EnrollTotp(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "SECRET", result["secret"])
+	assert.Equal(t, "otpauth://totp/issuer:user@example.com?secret=SECRET", result["otpauth_uri"])
+	assert.Equal(t, "data:image/png;base64,xxxx", result["qr_code"])
+}
+
+func TestEnrollTotpFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		err      error
+		expected int
+	}{
+		{"already enabled", service.ErrTotpAlreadyEnabled, http.StatusConflict},
+		{"internal error", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newMfaTestContext(nil)
+
+			mfaSvcMock := new(svc_mock.MfaSvcMock)
+			mfaSvcMock.On("EnrollTotp", "test-uuid").Return(&service.TotpEnrollOutput{}, tt.err)
+
+			handler := NewMfaHandler(mfaSvcMock)
+			handler.EnrollTotp(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestConfirmTotpSuccess(t *testing.T) {
+	c, w := newMfaTestContext(map[string]string{"code": "123456"})
+
+	mfaSvcMock := new(svc_mock.MfaSvcMock)
+	mfaSvcMock.On("ConfirmTotp", service.TotpCodeInput{
+		UserUUID: "test-uuid",
+		Code:     "123456",
+	}).Return([]string{"aaaaa-bbbbb", "ccccc-ddddd"}, nil)
+
+	handler := NewMfaHandler(mfaSvcMock)
+	handler.ConfirmTotp(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, true, result["enabled"])
+	assert.Equal(t, []interface{}{"aaaaa-bbbbb", "ccccc-ddddd"}, result["recovery_codes"])
+}
+
+func TestConfirmTotpFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		err      error
+		expected int
+	}{
+		{"invalid code", service.ErrInvalidMfaCode, http.StatusBadRequest},
+		{"not enrolled", service.ErrTotpNotEnabled, http.StatusBadRequest},
+		{"already enabled", service.ErrTotpAlreadyEnabled, http.StatusConflict},
+		{"internal error", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newMfaTestContext(map[string]string{"code": "123456"})
+
+			mfaSvcMock := new(svc_mock.MfaSvcMock)
+			mfaSvcMock.On("ConfirmTotp", mock.Anything).Return(nil, tt.err)
+
+			handler := NewMfaHandler(mfaSvcMock)
+			handler.ConfirmTotp(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestConfirmTotpFailedValidation(t *testing.T) {
+	for _, code := range []string{"", "12345", "abcdef"} {
+		c, w := newMfaTestContext(map[string]string{"code": code})
+
+		mfaSvcMock := new(svc_mock.MfaSvcMock)
+		handler := NewMfaHandler(mfaSvcMock)
+		handler.ConfirmTotp(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestDisableTotpSuccess(t *testing.T) {
+	c, w := newMfaTestContext(map[string]string{"code": "123456"})
+
+	mfaSvcMock := new(svc_mock.MfaSvcMock)
+	mfaSvcMock.On("DisableTotp", service.TotpCodeInput{
+		UserUUID: "test-uuid",
+		Code:     "123456",
+	}).Return(nil)
+
+	handler := NewMfaHandler(mfaSvcMock)
+	handler.DisableTotp(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"enabled":false`)
+}
+
+func TestDisableTotpFail(t *testing.T) {
+	c, w := newMfaTestContext(map[string]string{"code": "123456"})
+
+	mfaSvcMock := new(svc_mock.MfaSvcMock)
+	mfaSvcMock.On("DisableTotp", mock.Anything).Return(service.ErrInvalidMfaCode)
+
+	handler := NewMfaHandler(mfaSvcMock)
+	handler.DisableTotp(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDisableTotpFailedValidation(t *testing.T) {
+	c, w := newMfaTestContext(map[string]string{})
+
+	mfaSvcMock := new(svc_mock.MfaSvcMock)
+	handler := NewMfaHandler(mfaSvcMock)
+	handler.DisableTotp(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
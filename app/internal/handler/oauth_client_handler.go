@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type OauthClientHandlerInterface interface {
+	List(c *gin.Context)
+	Create(c *gin.Context)
+	Get(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+	RotateSecret(c *gin.Context)
+}
+
+type OauthClientHandlerStruct struct {
+	BaseHandler
+	service service.OauthClientSvcInterface
+}
+
+func NewOauthClientHandler(
+	service service.OauthClientSvcInterface,
+) *OauthClientHandlerStruct {
+	return &OauthClientHandlerStruct{
+		service: service,
+	}
+}
+
+// 項目名は RFC 7591 のクライアントメタデータに合わせる
+type oauthClientRequest struct {
+	Name                    string   `json:"client_name" binding:"required,max=255"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method" binding:"required,oneof=none client_secret_basic client_secret_post private_key_jwt"`
+	PublicKey               string   `json:"public_key"`
+	RedirectURIs            []string `json:"redirect_uris" binding:"max=20"`
+	GrantTypes              []string `json:"grant_types" binding:"required,min=1"`
+	Scopes                  []string `json:"scopes" binding:"max=50"`
+	Audiences               []string `json:"audiences" binding:"max=20"`
+	// トークン交換（RFC 8693）で受け付ける subject_token の aud と、要求できる audience
+	TokenExchangeSubjectAudiences []string `json:"token_exchange_subject_audiences" binding:"max=20"`
+	TokenExchangeAudiences        []string `json:"token_exchange_audiences" binding:"max=20"`
+	AccessTokenLifetime           int      `json:"access_token_lifetime" binding:"min=0,max=86400"`
+	RefreshTokenLifetime          int      `json:"refresh_token_lifetime" binding:"min=0,max=31536000"`
+	FirstParty                    bool     `json:"first_party"`
+}
+
+func (r oauthClientRequest) input() service.OauthClientInput {
+	return service.OauthClientInput{
+		Name:                          r.Name,
+		TokenEndpointAuthMethod:       r.TokenEndpointAuthMethod,
+		PublicKey:                     r.PublicKey,
+		RedirectURIs:                  r.RedirectURIs,
+		GrantTypes:                    r.GrantTypes,
+		Scopes:                        r.Scopes,
+		Audiences:                     r.Audiences,
+		TokenExchangeSubjectAudiences: r.TokenExchangeSubjectAudiences,
+		TokenExchangeAudiences:        r.TokenExchangeAudiences,
+		AccessTokenLifetime:           r.AccessTokenLifetime,
+		RefreshTokenLifetime:          r.RefreshTokenLifetime,
+		FirstParty:                    r.FirstParty,
+	}
+}
+
+// client_secret は発行した直後のレスポンスにのみ含める
+type oauthClientResponse struct {
+	ClientID                      string    `json:"client_id"`
+	ClientSecret                  string    `json:"client_secret,omitempty"`
+	Name                          string    `json:"client_name"`
+	TokenEndpointAuthMethod       string    `json:"token_endpoint_auth_method"`
+	PublicKey                     string    `json:"public_key,omitempty"`
+	RedirectURIs                  []string  `json:"redirect_uris"`
+	GrantTypes                    []string  `json:"grant_types"`
+	Scopes                        []string  `json:"scopes"`
+	Audiences                     []string  `json:"audiences"`
+	TokenExchangeSubjectAudiences []string  `json:"token_exchange_subject_audiences"`
+	TokenExchangeAudiences        []string  `json:"token_exchange_audiences"`
+	AccessTokenLifetime           int       `json:"access_token_lifetime"`
+	RefreshTokenLifetime          int       `json:"refresh_token_lifetime"`
+	FirstParty                    bool      `json:"first_party"`
+	CreatedAt                     time.Time `json:"created_at"`
+	UpdatedAt                     time.Time `json:"updated_at"`
+}
+
+func newOauthClientResponse(client *models.OauthClient, secret string) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:                      client.ClientID,
+		ClientSecret:                  secret,
+		Name:                          client.Name,
+		TokenEndpointAuthMethod:       client.TokenEndpointAuthMethod,
+		PublicKey:                     client.PublicKey,
+		RedirectURIs:                  client.RedirectURIList(),
+		GrantTypes:                    client.GrantTypeList(),
+		Scopes:                        client.ScopeList(),
+		Audiences:                     client.AudienceList(),
+		TokenExchangeSubjectAudiences: client.TokenExchangeSubjectAudienceList(),
+		TokenExchangeAudiences:        client.TokenExchangeAudienceList(),
+		AccessTokenLifetime:           client.AccessTokenLifetime,
+		RefreshTokenLifetime:          client.RefreshTokenLifetime,
+		FirstParty:                    client.FirstParty,
+		CreatedAt:                     client.CreatedAt,
+		UpdatedAt:                     client.UpdatedAt,
+	}
+}
+
+func (h *OauthClientHandlerStruct) List(c *gin.Context) {
+	clients, err := h.service.List()
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	response := make([]oauthClientResponse, 0, len(clients))
+	for i := range clients {
+		response = append(response, newOauthClientResponse(&clients[i], ""))
+	}
+	c.JSON(http.StatusOK, gin.H{"clients": response})
+}
+
+func (h *OauthClientHandlerStruct) Create(c *gin.Context) {
+	var req oauthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	output, err := h.service.Create(req.input())
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newOauthClientResponse(output.Client, output.ClientSecret))
+}
+
+func (h *OauthClientHandlerStruct) Get(c *gin.Context) {
+	client, err := h.service.Get(c.Param("client_id"))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newOauthClientResponse(client, ""))
+}
+
+func (h *OauthClientHandlerStruct) Update(c *gin.Context) {
+	var req oauthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	output, err := h.service.Update(c.Param("client_id"), req.input())
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newOauthClientResponse(output.Client, output.ClientSecret))
+}
+
+func (h *OauthClientHandlerStruct) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("client_id")); err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *OauthClientHandlerStruct) RotateSecret(c *gin.Context) {
+	output, err := h.service.RotateSecret(c.Param("client_id"))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newOauthClientResponse(output.Client, output.ClientSecret))
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newOauthClientTestContext(method string, clientID string, body any) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, "/admin/oauth/clients", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	if clientID != "" {
+		c.Params = gin.Params{{Key: "client_id", Value: clientID}}
+	}
+
+	return c, w
+}
+
+func oauthClientRequestBody() map[string]any {
+	return map[string]any{
+		"client_name":                "Client",
+		"token_endpoint_auth_method": "client_secret_basic",
+		"redirect_uris":              []string{"https://client.example.com/cb"},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"scopes":                     []string{"openid"},
+		"audiences":                  []string{"https://api.example.com"},
+		"token_exchange_audiences":   []string{"orders-client"},
+		"access_token_lifetime":      600,
+	}
+}
+
+var expectedOauthClientInput = service.OauthClientInput{
+	Name:                    "Client",
+	TokenEndpointAuthMethod: "client_secret_basic",
+	RedirectURIs:            []string{"https://client.example.com/cb"},
+	GrantTypes:              []string{"authorization_code", "refresh_token"},
+	Scopes:                  []string{"openid"},
+	Audiences:               []string{"https://api.example.com"},
+	TokenExchangeAudiences:  []string{"orders-client"},
+	AccessTokenLifetime:     600,
+}
+
+var testAdminOauthClient = &models.OauthClient{
+	ClientID:                "client",
+	Name:                    "Client",
+	TokenEndpointAuthMethod: "client_secret_basic",
+	RedirectURIs:            "https://client.example.com/cb",
+	GrantTypes:              "authorization_code refresh_token",
+	Scopes:                  "openid",
+	Audiences:               "https://api.example.com",
+	TokenExchangeAudiences:  "orders-client",
+	ClientSecretHash:        "hash",
+}
+
+func decodeOauthClient(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	result := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return result
+}
+
+func TestOauthClientCreate(t *testing.T) {
+	c, w := newOauthClientTestContext("POST", "", oauthClientRequestBody())
+
+	oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+	oauthClientSvcMock.On("Create", expectedOauthClientInput).Return(&service.OauthClientOutput{
+		Client:       testAdminOauthClient,
+		ClientSecret: "secret",
+	}, nil)
+
+	handler := NewOauthClientHandler(oauthClientSvcMock)
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	result := decodeOauthClient(t, w)
+	assert.Equal(t, "client", result["client_id"])
+	assert.Equal(t, "secret", result["client_secret"])
+	assert.Equal(t, []any{"authorization_code", "refresh_token"}, result["grant_types"])
+	assert.Equal(t, []any{"https://api.example.com"}, result["audiences"])
+	assert.Equal(t, []any{"orders-client"}, result["token_exchange_audiences"])
+	assert.Equal(t, []any{}, result["token_exchange_subject_audiences"])
+	assert.NotContains(t, result, "client_secret_hash")
+}
+
+func TestOauthClientCreateFail(t *testing.T) {
+	tests := map[string]struct {
+		modify func(body map[string]any)
+		err    error
+		status int
+	}{
+		"missing name":      {func(body map[string]any) { delete(body, "client_name") }, nil, http.StatusBadRequest},
+		"unknown method":    {func(body map[string]any) { body["token_endpoint_auth_method"] = "tls_client_auth" }, nil, http.StatusBadRequest},
+		"no grant types":    {func(body map[string]any) { body["grant_types"] = []string{} }, nil, http.StatusBadRequest},
+		"negative lifetime": {func(body map[string]any) { body["access_token_lifetime"] = -1 }, nil, http.StatusBadRequest},
+		"invalid metadata":  {func(body map[string]any) {}, service.ErrInvalidOauthClientMetadata, http.StatusBadRequest},
+		"internal error":    {func(body map[string]any) {}, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			body := oauthClientRequestBody()
+			tt.modify(body)
+			c, w := newOauthClientTestContext("POST", "", body)
+
+			oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+			oauthClientSvcMock.On("Create", mock.Anything).Return((*service.OauthClientOutput)(nil), tt.err)
+
+			handler := NewOauthClientHandler(oauthClientSvcMock)
+			handler.Create(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestOauthClientList(t *testing.T) {
+	c, w := newOauthClientTestContext("GET", "", nil)
+
+	oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+	oauthClientSvcMock.On("List").Return([]models.OauthClient{*testAdminOauthClient}, nil)
+
+	handler := NewOauthClientHandler(oauthClientSvcMock)
+	handler.List(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	clients := decodeOauthClient(t, w)["clients"].([]any)
+	assert.Len(t, clients, 1)
+	assert.NotContains(t, clients[0], "client_secret")
+}
+
+func TestOauthClientListFail(t *testing.T) {
+	c, w := newOauthClientTestContext("GET", "", nil)
+
+	oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+	oauthClientSvcMock.On("List").Return([]models.OauthClient(nil), fmt.Errorf("db error"))
+
+	handler := NewOauthClientHandler(oauthClientSvcMock)
+	handler.List(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestOauthClientGet(t *testing.T) {
+	tests := map[string]struct {
+		client *models.OauthClient
+		err    error
+		status int
+	}{
+		"found":     {testAdminOauthClient, nil, http.StatusOK},
+		"not found": {nil, service.ErrOauthClientNotFound, http.StatusNotFound},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthClientTestContext("GET", "client", nil)
+
+			oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+			oauthClientSvcMock.On("Get", "client").Return(tt.client, tt.err)
+
+			handler := NewOauthClientHandler(oauthClientSvcMock)
+			handler.Get(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.client != nil {
+				result := decodeOauthClient(t, w)
+				assert.Equal(t, "client", result["client_id"])
+				assert.NotContains(t, result, "client_secret")
+			}
+		})
+	}
+}
+
+func TestOauthClientUpdate(t *testing.T) {
+	c, w := newOauthClientTestContext("PUT", "client", oauthClientRequestBody())
+
+	oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+	oauthClientSvcMock.On("Update", "client", expectedOauthClientInput).Return(&service.OauthClientOutput{
+		Client: testAdminOauthClient,
+	}, nil)
+
+	handler := NewOauthClientHandler(oauthClientSvcMock)
+	handler.Update(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, decodeOauthClient(t, w), "client_secret")
+}
+
+func TestOauthClientUpdateFail(t *testing.T) {
+	t.Run("invalid request", func(t *testing.T) {
+		c, w := newOauthClientTestContext("PUT", "client", map[string]any{})
+
+		handler := NewOauthClientHandler(new(svc_mock.OauthClientSvcMock))
+		handler.Update(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		c, w := newOauthClientTestContext("PUT", "client", oauthClientRequestBody())
+
+		oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+		oauthClientSvcMock.On("Update", "client", mock.Anything).Return((*service.OauthClientOutput)(nil), service.ErrOauthClientNotFound)
+
+		handler := NewOauthClientHandler(oauthClientSvcMock)
+		handler.Update(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestOauthClientDelete(t *testing.T) {
+	tests := map[string]struct {
+		err    error
+		status int
+	}{
+		"deleted":   {nil, http.StatusNoContent},
+		"not found": {service.ErrOauthClientNotFound, http.StatusNotFound},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthClientTestContext("DELETE", "client", nil)
+
+			oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+			oauthClientSvcMock.On("Delete", "client").Return(tt.err)
+
+			handler := NewOauthClientHandler(oauthClientSvcMock)
+			handler.Delete(c)
+
+			assert.Equal(t, tt.status, c.Writer.Status())
+			if tt.err == nil {
+				assert.Empty(t, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOauthClientRotateSecret(t *testing.T) {
+	c, w := newOauthClientTestContext("POST", "client", nil)
+
+	oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+	oauthClientSvcMock.On("RotateSecret", "client").Return(&service.OauthClientOutput{
+		Client:       testAdminOauthClient,
+		ClientSecret: "new-secret",
+	}, nil)
+
+	handler := NewOauthClientHandler(oauthClientSvcMock)
+	handler.RotateSecret(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "new-secret", decodeOauthClient(t, w)["client_secret"])
+}
+
+func TestOauthClientRotateSecretFail(t *testing.T) {
+	c, w := newOauthClientTestContext("POST", "client", nil)
+
+	oauthClientSvcMock := new(svc_mock.OauthClientSvcMock)
+	oauthClientSvcMock.On("RotateSecret", "client").Return((*service.OauthClientOutput)(nil), fmt.Errorf("%w: client does not use client_secret", service.ErrInvalidOauthClientMetadata))
+
+	handler := NewOauthClientHandler(oauthClientSvcMock)
+	handler.RotateSecret(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ErrorCodeInvalidClientMetadata, decodeOauthClient(t, w)["code"])
+}
@@ -0,0 +1,460 @@
+package handler
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type OauthHandlerInterface interface {
+	BeginAuthorize(c *gin.Context)
+	Authorize(c *gin.Context)
+	Token(c *gin.Context)
+	DeviceAuthorization(c *gin.Context)
+	GetDeviceVerification(c *gin.Context)
+	DecideDeviceVerification(c *gin.Context)
+	ConsentPage(c *gin.Context)
+	DecideConsent(c *gin.Context)
+	ListConsents(c *gin.Context)
+	RevokeConsent(c *gin.Context)
+}
+
+//go:embed templates/oauth_consent.html
+var templateFS embed.FS
+
+var oauthConsentTemplate = template.Must(template.ParseFS(templateFS, "templates/oauth_consent.html"))
+
+type OauthHandlerStruct struct {
+	BaseHandler
+	service    service.OauthSvcInterface
+	auth       service.AuthSvcInterface
+	csrf       service.CsrfSvcInterface
+	csrfConfig service.CsrfConfig
+}
+
+func NewOauthHandler(
+	service service.OauthSvcInterface,
+	auth service.AuthSvcInterface,
+	csrf service.CsrfSvcInterface,
+	csrfConfig service.CsrfConfig,
+) *OauthHandlerStruct {
+	return &OauthHandlerStruct{
+		service:    service,
+		auth:       auth,
+		csrf:       csrf,
+		csrfConfig: csrfConfig,
+	}
+}
+
+// 値の検証はリダイレクトの可否を判断する service で行うため、ここでは必須にしない
+type oauthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Nonce               string `form:"nonce" json:"nonce"`
+}
+
+func (r oauthAuthorizeRequest) input() service.OauthAuthorizeInput {
+	return service.OauthAuthorizeInput{
+		ResponseType:        r.ResponseType,
+		ClientID:            r.ClientID,
+		RedirectURI:         r.RedirectURI,
+		Scope:               r.Scope,
+		State:               r.State,
+		CodeChallenge:       r.CodeChallenge,
+		CodeChallengeMethod: r.CodeChallengeMethod,
+		Nonce:               r.Nonce,
+	}
+}
+
+// クライアントがユーザーを送る認可エンドポイント。リクエストを検証してログイン画面へリダイレクトする
+func (h *OauthHandlerStruct) BeginAuthorize(c *gin.Context) {
+	var req oauthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.oauthErrorResponse(c, &service.OauthError{Code: service.OauthErrorInvalidRequest, Description: "malformed request"})
+		return
+	}
+
+	redirectTo, err := h.service.BeginAuthorize(req.input())
+	if err != nil {
+		h.oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectTo)
+}
+
+// ログイン画面がユーザーのアクセストークンで呼び、認可コードを付けたリダイレクト先を受け取る
+// 同意が必要な場合は同意画面の URL を返す
+func (h *OauthHandlerStruct) Authorize(c *gin.Context) {
+	var req oauthAuthorizeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.oauthErrorResponse(c, &service.OauthError{Code: service.OauthErrorInvalidRequest, Description: "malformed request"})
+		return
+	}
+
+	claims, _ := c.Value(middleware.AuthClaimsKey).(jwt.MapClaims)
+	redirectTo, err := h.service.Authorize(c.GetString(middleware.AuthUserUUIDKey), service.AuthContextFromClaims(claims), req.input())
+	if err != nil {
+		h.oauthErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"redirect_to": redirectTo})
+}
+
+// トークンエンドポイント・デバイス認可エンドポイントで送られるクライアントの認証情報
+type oauthClientAuthRequest struct {
+	ClientID            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertionType string `form:"client_assertion_type"`
+	ClientAssertion     string `form:"client_assertion"`
+}
+
+// RFC 6749 4.1.3 / 4.4.2 / 6、RFC 8628 3.4、RFC 8693 2.1 のトークンリクエスト（application/x-www-form-urlencoded）
+type oauthTokenRequest struct {
+	oauthClientAuthRequest
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	DeviceCode   string `form:"device_code"`
+	// トークン交換。audience は複数指定できる
+	SubjectToken       string   `form:"subject_token"`
+	SubjectTokenType   string   `form:"subject_token_type"`
+	ActorToken         string   `form:"actor_token"`
+	ActorTokenType     string   `form:"actor_token_type"`
+	RequestedTokenType string   `form:"requested_token_type"`
+	RequestedSubject   string   `form:"requested_subject"`
+	Audience           []string `form:"audience"`
+}
+
+func (h *OauthHandlerStruct) Token(c *gin.Context) {
+	var req oauthTokenRequest
+	if err := c.ShouldBind(&req); err != nil || req.GrantType == "" {
+		h.oauthErrorResponse(c, &service.OauthError{Code: service.OauthErrorInvalidRequest, Description: "grant_type is required"})
+		return
+	}
+
+	client, err := h.authenticateClient(c, req.oauthClientAuthRequest)
+	if err != nil {
+		h.oauthErrorResponse(c, err)
+		return
+	}
+
+	var response *service.AuthOutput
+	switch req.GrantType {
+	case service.OauthGrantTypeAuthorizationCode:
+		response, err = h.auth.ExchangeAuthorizationCode(client, service.OauthTokenInput{
+			Code:         req.Code,
+			RedirectURI:  req.RedirectURI,
+			CodeVerifier: req.CodeVerifier,
+		})
+	case service.OauthGrantTypeRefreshToken:
+		response, err = h.auth.RefreshForClient(client, service.RefreshInput{
+			RefreshToken: req.RefreshToken,
+			IpAddress:    c.ClientIP(),
+			Scope:        req.Scope,
+		})
+	case service.OauthGrantTypeDeviceCode:
+		response, err = h.auth.ExchangeDeviceCode(client, req.DeviceCode)
+	case service.OauthGrantTypeClientCredentials:
+		response, err = h.auth.IssueClientCredentials(client, service.OauthClientCredentialsInput{
+			Scope: req.Scope,
+		})
+	case service.OauthGrantTypeTokenExchange:
+		response, err = h.auth.ExchangeToken(client, service.OauthTokenExchangeInput{
+			SubjectToken:       req.SubjectToken,
+			SubjectTokenType:   req.SubjectTokenType,
+			ActorToken:         req.ActorToken,
+			ActorTokenType:     req.ActorTokenType,
+			RequestedTokenType: req.RequestedTokenType,
+			RequestedSubject:   req.RequestedSubject,
+			Audience:           req.Audience,
+			Scope:              req.Scope,
+			IpAddress:          c.ClientIP(),
+		})
+	default:
+		err = &service.OauthError{Code: service.OauthErrorUnsupportedGrantType, Description: "grant_type is not supported"}
+	}
+	if err != nil {
+		h.oauthErrorResponse(c, err)
+		return
+	}
+
+	resp := gin.H{
+		"access_token": response.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   response.ExpiresIn,
+	}
+	if response.RefreshToken != "" {
+		resp["refresh_token"] = response.RefreshToken
+	}
+	if response.Scope != "" {
+		resp["scope"] = response.Scope
+	}
+	if response.IDToken != "" {
+		resp["id_token"] = response.IDToken
+	}
+	if response.IssuedTokenType != "" {
+		resp["issued_token_type"] = response.IssuedTokenType
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type oauthDeviceAuthorizationRequest struct {
+	oauthClientAuthRequest
+	Scope string `form:"scope"`
+}
+
+// ブラウザを使えないデバイスがユーザーコードと検証画面の URL を受け取る（RFC 8628 3.1 / 3.2）
+func (h *OauthHandlerStruct) DeviceAuthorization(c *gin.Context) {
+	var req oauthDeviceAuthorizationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.oauthErrorResponse(c, &service.OauthError{Code: service.OauthErrorInvalidRequest, Description: "malformed request"})
+		return
+	}
+
+	client, err := h.authenticateClient(c, req.oauthClientAuthRequest)
+	if err != nil {
+		h.oauthErrorResponse(c, err)
+		return
+	}
+	response, err := h.service.AuthorizeDevice(client, service.OauthDeviceAuthorizationInput{
+		Scope: req.Scope,
+	})
+	if err != nil {
+		h.oauthErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"device_code":               response.DeviceCode,
+		"user_code":                 response.UserCode,
+		"verification_uri":          response.VerificationURI,
+		"verification_uri_complete": response.VerificationURIComplete,
+		"expires_in":                response.ExpiresIn,
+		"interval":                  response.Interval,
+	})
+}
+
+type oauthDeviceVerificationQuery struct {
+	UserCode string `form:"user_code" binding:"required"`
+}
+
+// 検証画面がログイン済みユーザーのアクセストークンで呼び、入力されたコードで承認するクライアントとスコープを表示する
+func (h *OauthHandlerStruct) GetDeviceVerification(c *gin.Context) {
+	var req oauthDeviceVerificationQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	verification, err := h.service.GetDeviceAuthorization(req.UserCode)
+	if err != nil {
+		h.authenticatedErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, verification)
+}
+
+type oauthDeviceDecisionRequest struct {
+	UserCode string `form:"user_code" json:"user_code" binding:"required"`
+	Action   string `form:"action" json:"action" binding:"required,oneof=approve deny"`
+}
+
+// 検証画面でユーザーがデバイスを承認・拒否する。結果はデバイスのポーリングで返す
+func (h *OauthHandlerStruct) DecideDeviceVerification(c *gin.Context) {
+	var req oauthDeviceDecisionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	claims, _ := c.Value(middleware.AuthClaimsKey).(jwt.MapClaims)
+	if err := h.service.DecideDeviceAuthorization(c.GetString(middleware.AuthUserUUIDKey), service.AuthContextFromClaims(claims), service.OauthDeviceDecisionInput{
+		UserCode: req.UserCode,
+		Approve:  req.Action == "approve",
+	}); err != nil {
+		h.authenticatedErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type oauthConsentQuery struct {
+	ConsentChallenge string `form:"consent_challenge" binding:"required"`
+}
+
+// 同意画面はブラウザで直接開くため HTML を返す。フォームの送信は CSRF ミドルウェアで検証する
+func (h *OauthHandlerStruct) ConsentPage(c *gin.Context) {
+	var req oauthConsentQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	consent, err := h.service.GetConsent(req.ConsentChallenge)
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+	token, err := issueCsrfToken(c, h.csrf, h.csrfConfig, csrfSessionID(c, h.csrf, h.csrfConfig))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	locale := h.locale(c)
+	scopes := []string{}
+	for _, scope := range consent.Scopes {
+		// 説明のないスコープは名前をそのまま表示する
+		if description, ok := i18n.Lookup(locale, "scope."+scope); ok {
+			scope = description
+		}
+		scopes = append(scopes, scope)
+	}
+	clientName := consent.ClientName
+	if clientName == "" {
+		clientName = consent.ClientID
+	}
+
+	var body bytes.Buffer
+	if err := oauthConsentTemplate.Execute(&body, map[string]any{
+		"Locale":      locale,
+		"Title":       i18n.T(locale, "consent.title"),
+		"Description": i18n.T(locale, "consent.description", clientName),
+		"Scopes":      scopes,
+		"Action":      c.Request.URL.Path,
+		"CsrfToken":   token,
+		"Challenge":   req.ConsentChallenge,
+		"Approve":     i18n.T(locale, "consent.approve"),
+		"Deny":        i18n.T(locale, "consent.deny"),
+	}); err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+	c.Header("Content-Language", locale)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+type oauthConsentDecisionRequest struct {
+	ConsentChallenge string `form:"consent_challenge" binding:"required"`
+	Action           string `form:"action" binding:"required,oneof=approve deny"`
+}
+
+// 同意画面のフォームの送信先。許可・拒否の結果を付けてクライアントへリダイレクトする
+func (h *OauthHandlerStruct) DecideConsent(c *gin.Context) {
+	var req oauthConsentDecisionRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	redirectTo, err := h.service.DecideConsent(req.ConsentChallenge, req.Action == "approve")
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	// フォームの POST から GET で遷移させる
+	c.Redirect(http.StatusSeeOther, redirectTo)
+}
+
+func (h *OauthHandlerStruct) ListConsents(c *gin.Context) {
+	consents, err := h.service.ListConsents(c.GetString(middleware.AuthUserUUIDKey))
+	if err != nil {
+		h.authenticatedErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"consents": consents})
+}
+
+// 同意を取り消し、そのクライアントに発行したリフレッシュトークンを失効させる
+func (h *OauthHandlerStruct) RevokeConsent(c *gin.Context) {
+	if err := h.service.RevokeConsent(c.GetString(middleware.AuthUserUUIDKey), c.Param("client_id")); err != nil {
+		h.authenticatedErrorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *OauthHandlerStruct) authenticateClient(c *gin.Context, req oauthClientAuthRequest) (*models.OauthClient, error) {
+	clientAuth, err := clientAuthInput(c, req)
+	if err != nil {
+		return nil, err
+	}
+	return h.service.AuthenticateClient(clientAuth)
+}
+
+// client_secret_basic の ID とシークレットは application/x-www-form-urlencoded でエンコードされている（RFC 6749 2.3.1）
+func clientAuthInput(c *gin.Context, req oauthClientAuthRequest) (service.OauthClientAuthInput, error) {
+	input := service.OauthClientAuthInput{
+		ClientID:            req.ClientID,
+		ClientSecret:        req.ClientSecret,
+		ClientAssertionType: req.ClientAssertionType,
+		ClientAssertion:     req.ClientAssertion,
+	}
+	if c.GetHeader("Authorization") == "" {
+		return input, nil
+	}
+
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return input, &service.OauthError{Code: service.OauthErrorInvalidClient, Description: "authorization header is invalid"}
+	}
+	var err error
+	if input.BasicClientID, err = url.QueryUnescape(id); err != nil {
+		return input, &service.OauthError{Code: service.OauthErrorInvalidClient, Description: "authorization header is invalid"}
+	}
+	if input.BasicClientSecret, err = url.QueryUnescape(secret); err != nil {
+		return input, &service.OauthError{Code: service.OauthErrorInvalidClient, Description: "authorization header is invalid"}
+	}
+	return input, nil
+}
+
+// OAuth のクライアントは RFC 6749 5.2 の形式でエラーを解釈するため、problem+json ではなく error / error_description で返す
+func (h *OauthHandlerStruct) oauthErrorResponse(c *gin.Context, err error) {
+	var oauthErr *service.OauthError
+	if !errors.As(err, &oauthErr) {
+		log.Printf("[handler] %s %s: %v", c.Request.Method, c.FullPath(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "server_error",
+		})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == service.OauthErrorInvalidClient {
+		status = http.StatusUnauthorized
+		// Authorization ヘッダーで認証を試みた場合は、使うべき認証方式を返す（RFC 6749 5.2）
+		if c.GetHeader("Authorization") != "" {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	}
+	c.JSON(status, gin.H{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
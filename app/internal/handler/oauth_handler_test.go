@@ -0,0 +1,740 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newOauthTestContext(method string, target string, form url.Values) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c.Request = req
+
+	return c, w
+}
+
+func newTestOauthHandler(oauthSvc service.OauthSvcInterface, authSvc service.AuthSvcInterface) *OauthHandlerStruct {
+	return NewOauthHandler(oauthSvc, authSvc, new(svc_mock.CsrfSvcMockStruct), service.CsrfConfig{CookieName: "csrf_token"})
+}
+
+func oauthAuthorizeQuery() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {"test-client"},
+		"redirect_uri":          {"https://client.example.com/callback"},
+		"scope":                 {"openid"},
+		"state":                 {"xyz"},
+		"code_challenge":        {"challenge"},
+		"code_challenge_method": {"S256"},
+		"nonce":                 {"n-0S6_WzA2Mj"},
+	}
+}
+
+var expectedOauthAuthorizeInput = service.OauthAuthorizeInput{
+	ResponseType:        "code",
+	ClientID:            "test-client",
+	RedirectURI:         "https://client.example.com/callback",
+	Scope:               "openid",
+	State:               "xyz",
+	CodeChallenge:       "challenge",
+	CodeChallengeMethod: "S256",
+	Nonce:               "n-0S6_WzA2Mj",
+}
+
+func decodeOauthError(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	result := map[string]string{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return result
+}
+
+func TestOauthBeginAuthorize(t *testing.T) {
+	c, w := newOauthTestContext("GET", "/oauth/authorize?"+oauthAuthorizeQuery().Encode(), nil)
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("BeginAuthorize", expectedOauthAuthorizeInput).Return("https://auth.example.com/login?client_id=test-client", nil)
+
+	handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+	handler.BeginAuthorize(c)
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://auth.example.com/login?client_id=test-client", w.Header().Get("Location"))
+}
+
+func TestOauthBeginAuthorizeFail(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		status   int
+		expected string
+	}{
+		"invalid client": {&service.OauthError{Code: service.OauthErrorInvalidRequest, Description: "unknown client_id"}, http.StatusBadRequest, "invalid_request"},
+		"internal error": {fmt.Errorf("db error"), http.StatusInternalServerError, "server_error"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthTestContext("GET", "/oauth/authorize?client_id=unknown", nil)
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("BeginAuthorize", mock.Anything).Return("", tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.BeginAuthorize(c)
+
+			// 確認できないリダイレクト先には送らない
+			assert.Equal(t, tt.status, w.Code)
+			assert.Empty(t, w.Header().Get("Location"))
+			assert.Equal(t, tt.expected, decodeOauthError(t, w)["error"])
+		})
+	}
+}
+
+func TestOauthAuthorize(t *testing.T) {
+	c, w := newOauthTestContext("POST", "/oauth/authorize", oauthAuthorizeQuery())
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+	c.Set(middleware.AuthClaimsKey, jwt.MapClaims{
+		"auth_time": float64(authTime.Unix()),
+		"amr":       []any{"pwd", "otp", "mfa"},
+	})
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("Authorize", "test-uuid", service.AuthContext{
+		AuthTime: authTime,
+		Amr:      []string{"pwd", "otp", "mfa"},
+	}, expectedOauthAuthorizeInput).Return("https://client.example.com/callback?code=abc&state=xyz", nil)
+
+	handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+	handler.Authorize(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://client.example.com/callback?code=abc&state=xyz", decodeOauthError(t, w)["redirect_to"])
+}
+
+func TestOauthAuthorizeFail(t *testing.T) {
+	c, w := newOauthTestContext("POST", "/oauth/authorize", oauthAuthorizeQuery())
+	c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("Authorize", "test-uuid", service.AuthContext{}, mock.Anything).
+		Return("", &service.OauthError{Code: service.OauthErrorInvalidRequest, Description: "redirect_uri is not registered"})
+
+	handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+	handler.Authorize(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	result := decodeOauthError(t, w)
+	assert.Equal(t, "invalid_request", result["error"])
+	assert.Equal(t, "redirect_uri is not registered", result["error_description"])
+}
+
+func oauthTokenForm() url.Values {
+	return url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"test-code"},
+		"redirect_uri":  {"https://client.example.com/callback"},
+		"client_id":     {"test-client"},
+		"code_verifier": {"verifier"},
+	}
+}
+
+var testOauthTokenClient = &models.OauthClient{ClientID: "test-client"}
+
+func TestOauthToken(t *testing.T) {
+	c, w := newOauthTestContext("POST", "/oauth/token", oauthTokenForm())
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("AuthenticateClient", service.OauthClientAuthInput{ClientID: "test-client"}).Return(testOauthTokenClient, nil)
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("ExchangeAuthorizationCode", testOauthTokenClient, service.OauthTokenInput{
+		Code:         "test-code",
+		RedirectURI:  "https://client.example.com/callback",
+		CodeVerifier: "verifier",
+	}).Return(&service.AuthOutput{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+		ExpiresIn:    3600,
+		Scope:        "openid",
+		IDToken:      "id-token",
+	}, nil)
+
+	handler := newTestOauthHandler(oauthSvcMock, authSvcMock)
+	handler.Token(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "access-token", result["access_token"])
+	assert.Equal(t, "refresh-token", result["refresh_token"])
+	assert.Equal(t, "Bearer", result["token_type"])
+	assert.Equal(t, float64(3600), result["expires_in"])
+	assert.Equal(t, "openid", result["scope"])
+	assert.Equal(t, "id-token", result["id_token"])
+}
+
+func TestOauthTokenRefreshGrant(t *testing.T) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {"refresh-token"},
+	}
+	c, w := newOauthTestContext("POST", "/oauth/token", form)
+	// client_secret_basic の値は URL エンコードされている
+	c.Request.SetBasicAuth("test-client", url.QueryEscape("se:cr et"))
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("AuthenticateClient", service.OauthClientAuthInput{
+		BasicClientID:     "test-client",
+		BasicClientSecret: "se:cr et",
+	}).Return(testOauthTokenClient, nil)
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("RefreshForClient", testOauthTokenClient, mock.MatchedBy(func(input service.RefreshInput) bool {
+		return input.RefreshToken == "refresh-token"
+	})).Return(&service.AuthOutput{AccessToken: "access-token", ExpiresIn: 600}, nil)
+
+	handler := newTestOauthHandler(oauthSvcMock, authSvcMock)
+	handler.Token(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "access-token", result["access_token"])
+	assert.Equal(t, float64(600), result["expires_in"])
+	// リフレッシュトークン・ID トークンを発行しない場合は返さない
+	assert.NotContains(t, result, "refresh_token")
+	assert.NotContains(t, result, "id_token")
+}
+
+func TestOauthTokenClientCredentialsGrant(t *testing.T) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"scope":         {"jobs:read"},
+		"client_id":     {"test-client"},
+		"client_secret": {"secret"},
+	}
+	c, w := newOauthTestContext("POST", "/oauth/token", form)
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("AuthenticateClient", service.OauthClientAuthInput{
+		ClientID:     "test-client",
+		ClientSecret: "secret",
+	}).Return(testOauthTokenClient, nil)
+	authSvcMock := new(svc_mock.AuthSvcMock)
+	authSvcMock.On("IssueClientCredentials", testOauthTokenClient, service.OauthClientCredentialsInput{
+		Scope: "jobs:read",
+	}).Return(&service.AuthOutput{AccessToken: "access-token", ExpiresIn: 3600, Scope: "jobs:read"}, nil)
+
+	handler := newTestOauthHandler(oauthSvcMock, authSvcMock)
+	handler.Token(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "access-token", result["access_token"])
+	assert.Equal(t, "jobs:read", result["scope"])
+	assert.NotContains(t, result, "refresh_token")
+}
+
+func TestOauthTokenFail(t *testing.T) {
+	tests := map[string]struct {
+		form     func(form url.Values)
+		err      error
+		status   int
+		expected string
+	}{
+		"missing grant_type":     {func(form url.Values) { form.Del("grant_type") }, nil, http.StatusBadRequest, "invalid_request"},
+		"unsupported grant_type": {func(form url.Values) { form.Set("grant_type", "password") }, nil, http.StatusBadRequest, "unsupported_grant_type"},
+		"invalid grant":          {func(form url.Values) {}, &service.OauthError{Code: service.OauthErrorInvalidGrant}, http.StatusBadRequest, "invalid_grant"},
+		"internal error":         {func(form url.Values) {}, fmt.Errorf("db error"), http.StatusInternalServerError, "server_error"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			form := oauthTokenForm()
+			tt.form(form)
+			c, w := newOauthTestContext("POST", "/oauth/token", form)
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("AuthenticateClient", mock.Anything).Return(testOauthTokenClient, nil)
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			authSvcMock.On("ExchangeAuthorizationCode", mock.Anything, mock.Anything).Return((*service.AuthOutput)(nil), tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, authSvcMock)
+			handler.Token(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.expected, decodeOauthError(t, w)["error"])
+		})
+	}
+}
+
+func TestOauthTokenFailClientAuthentication(t *testing.T) {
+	invalidClient := &service.OauthError{Code: service.OauthErrorInvalidClient, Description: "client authentication failed"}
+
+	t.Run("form credentials", func(t *testing.T) {
+		c, w := newOauthTestContext("POST", "/oauth/token", oauthTokenForm())
+
+		oauthSvcMock := new(svc_mock.OauthSvcMock)
+		oauthSvcMock.On("AuthenticateClient", mock.Anything).Return((*models.OauthClient)(nil), invalidClient)
+
+		handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+		handler.Token(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "invalid_client", decodeOauthError(t, w)["error"])
+		assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+	})
+
+	// Authorization ヘッダーで認証に失敗した場合は WWW-Authenticate を返す
+	t.Run("basic credentials", func(t *testing.T) {
+		c, w := newOauthTestContext("POST", "/oauth/token", oauthTokenForm())
+		c.Request.SetBasicAuth("test-client", "wrong")
+
+		oauthSvcMock := new(svc_mock.OauthSvcMock)
+		oauthSvcMock.On("AuthenticateClient", mock.Anything).Return((*models.OauthClient)(nil), invalidClient)
+
+		handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+		handler.Token(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, `Basic realm="oauth"`, w.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("malformed authorization header", func(t *testing.T) {
+		c, w := newOauthTestContext("POST", "/oauth/token", oauthTokenForm())
+		c.Request.Header.Set("Authorization", "Bearer token")
+
+		handler := newTestOauthHandler(new(svc_mock.OauthSvcMock), new(svc_mock.AuthSvcMock))
+		handler.Token(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "invalid_client", decodeOauthError(t, w)["error"])
+	})
+}
+
+func TestOauthTokenDeviceCodeGrant(t *testing.T) {
+	form := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {"device-code"},
+		"client_id":   {"test-client"},
+	}
+
+	tests := map[string]struct {
+		output   *service.AuthOutput
+		err      error
+		status   int
+		expected string
+	}{
+		"approved": {&service.AuthOutput{AccessToken: "access-token", ExpiresIn: 3600}, nil, http.StatusOK, ""},
+		// ポーリング中のエラーは RFC 8628 3.5 の error で返す
+		"pending":   {nil, &service.OauthError{Code: service.OauthErrorAuthorizationPending}, http.StatusBadRequest, "authorization_pending"},
+		"slow down": {nil, &service.OauthError{Code: service.OauthErrorSlowDown}, http.StatusBadRequest, "slow_down"},
+		"expired":   {nil, &service.OauthError{Code: service.OauthErrorExpiredToken}, http.StatusBadRequest, "expired_token"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthTestContext("POST", "/oauth/token", form)
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("AuthenticateClient", service.OauthClientAuthInput{ClientID: "test-client"}).Return(testOauthTokenClient, nil)
+			authSvcMock := new(svc_mock.AuthSvcMock)
+			authSvcMock.On("ExchangeDeviceCode", testOauthTokenClient, "device-code").Return(tt.output, tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, authSvcMock)
+			handler.Token(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.err == nil {
+				assert.Contains(t, w.Body.String(), `"access_token":"access-token"`)
+			} else {
+				assert.Equal(t, tt.expected, decodeOauthError(t, w)["error"])
+			}
+		})
+	}
+}
+
+func TestOauthTokenExchangeGrant(t *testing.T) {
+	form := url.Values{
+		"grant_type":         {"urn:ietf:params:oauth:grant-type:token-exchange"},
+		"subject_token":      {"subject-token"},
+		"subject_token_type": {"urn:ietf:params:oauth:token-type:access_token"},
+		"audience":           {"orders-api", "billing-api"},
+		"scope":              {"orders"},
+		"client_id":          {"test-client"},
+	}
+	input := service.OauthTokenExchangeInput{
+		SubjectToken:     "subject-token",
+		SubjectTokenType: service.OauthTokenTypeAccessToken,
+		Audience:         []string{"orders-api", "billing-api"},
+		Scope:            "orders",
+		IpAddress:        "192.0.2.1",
+	}
+
+	t.Run("success", func(t *testing.T) {
+		c, w := newOauthTestContext("POST", "/oauth/token", form)
+
+		oauthSvcMock := new(svc_mock.OauthSvcMock)
+		oauthSvcMock.On("AuthenticateClient", service.OauthClientAuthInput{ClientID: "test-client"}).Return(testOauthTokenClient, nil)
+		authSvcMock := new(svc_mock.AuthSvcMock)
+		authSvcMock.On("ExchangeToken", testOauthTokenClient, input).Return(&service.AuthOutput{
+			AccessToken:     "access-token",
+			ExpiresIn:       600,
+			Scope:           "orders",
+			IssuedTokenType: service.OauthTokenTypeAccessToken,
+		}, nil)
+
+		handler := newTestOauthHandler(oauthSvcMock, authSvcMock)
+		handler.Token(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		result := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, map[string]interface{}{
+			"access_token":      "access-token",
+			"token_type":        "Bearer",
+			"expires_in":        float64(600),
+			"scope":             "orders",
+			"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+		}, result)
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		c, w := newOauthTestContext("POST", "/oauth/token", form)
+
+		oauthSvcMock := new(svc_mock.OauthSvcMock)
+		oauthSvcMock.On("AuthenticateClient", mock.Anything).Return(testOauthTokenClient, nil)
+		authSvcMock := new(svc_mock.AuthSvcMock)
+		authSvcMock.On("ExchangeToken", testOauthTokenClient, input).
+			Return((*service.AuthOutput)(nil), &service.OauthError{Code: service.OauthErrorInvalidTarget})
+
+		handler := newTestOauthHandler(oauthSvcMock, authSvcMock)
+		handler.Token(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "invalid_target", decodeOauthError(t, w)["error"])
+	})
+}
+
+func TestOauthDeviceAuthorization(t *testing.T) {
+	form := url.Values{
+		"client_id": {"test-client"},
+		"scope":     {"openid profile"},
+	}
+	c, w := newOauthTestContext("POST", "/oauth/device_authorization", form)
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("AuthenticateClient", service.OauthClientAuthInput{ClientID: "test-client"}).Return(testOauthTokenClient, nil)
+	oauthSvcMock.On("AuthorizeDevice", testOauthTokenClient, service.OauthDeviceAuthorizationInput{Scope: "openid profile"}).
+		Return(&service.OauthDeviceAuthorizationOutput{
+			DeviceCode:              "device-code",
+			UserCode:                "WDJB-MJHT",
+			VerificationURI:         "https://auth.example.com/device",
+			VerificationURIComplete: "https://auth.example.com/device?user_code=WDJB-MJHT",
+			ExpiresIn:               600,
+			Interval:                5,
+		}, nil)
+
+	handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+	handler.DeviceAuthorization(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, map[string]interface{}{
+		"device_code":               "device-code",
+		"user_code":                 "WDJB-MJHT",
+		"verification_uri":          "https://auth.example.com/device",
+		"verification_uri_complete": "https://auth.example.com/device?user_code=WDJB-MJHT",
+		"expires_in":                float64(600),
+		"interval":                  float64(5),
+	}, result)
+}
+
+func TestOauthDeviceAuthorizationFail(t *testing.T) {
+	t.Run("client authentication", func(t *testing.T) {
+		c, w := newOauthTestContext("POST", "/oauth/device_authorization", url.Values{"client_id": {"unknown"}})
+
+		oauthSvcMock := new(svc_mock.OauthSvcMock)
+		oauthSvcMock.On("AuthenticateClient", mock.Anything).
+			Return((*models.OauthClient)(nil), &service.OauthError{Code: service.OauthErrorInvalidClient})
+
+		handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+		handler.DeviceAuthorization(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "invalid_client", decodeOauthError(t, w)["error"])
+	})
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		c, w := newOauthTestContext("POST", "/oauth/device_authorization", url.Values{"client_id": {"test-client"}})
+
+		oauthSvcMock := new(svc_mock.OauthSvcMock)
+		oauthSvcMock.On("AuthenticateClient", mock.Anything).Return(testOauthTokenClient, nil)
+		oauthSvcMock.On("AuthorizeDevice", mock.Anything, mock.Anything).
+			Return((*service.OauthDeviceAuthorizationOutput)(nil), &service.OauthError{Code: service.OauthErrorUnauthorizedClient})
+
+		handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+		handler.DeviceAuthorization(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "unauthorized_client", decodeOauthError(t, w)["error"])
+	})
+}
+
+func TestOauthGetDeviceVerification(t *testing.T) {
+	tests := map[string]struct {
+		target string
+		err    error
+		status int
+	}{
+		"found":          {"/oauth/device?user_code=WDJB-MJHT", nil, http.StatusOK},
+		"missing code":   {"/oauth/device", nil, http.StatusBadRequest},
+		"invalid code":   {"/oauth/device?user_code=WDJB-MJHT", service.ErrInvalidOauthUserCode, http.StatusBadRequest},
+		"internal error": {"/oauth/device?user_code=WDJB-MJHT", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthTestContext("GET", tt.target, nil)
+
+			verification := &service.OauthDeviceVerification{ClientID: "device-client", ClientName: "TV App", Scopes: []string{"openid"}}
+			if tt.err != nil {
+				verification = nil
+			}
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("GetDeviceAuthorization", "WDJB-MJHT").Return(verification, tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.GetDeviceVerification(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.JSONEq(t, `{"client_id":"device-client","client_name":"TV App","scopes":["openid"]}`, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOauthDecideDeviceVerification(t *testing.T) {
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	for action, approve := range map[string]bool{"approve": true, "deny": false} {
+		t.Run(action, func(t *testing.T) {
+			c, w := newOauthTestContext("POST", "/oauth/device", url.Values{"user_code": {"WDJB-MJHT"}, "action": {action}})
+			c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+			c.Set(middleware.AuthClaimsKey, jwt.MapClaims{
+				"auth_time": float64(authTime.Unix()),
+				"amr":       []any{"pwd"},
+			})
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("DecideDeviceAuthorization", "test-uuid", service.AuthContext{
+				AuthTime: authTime,
+				Amr:      []string{"pwd"},
+			}, service.OauthDeviceDecisionInput{UserCode: "WDJB-MJHT", Approve: approve}).Return(nil)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.DecideDeviceVerification(c)
+
+			assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+			assert.Empty(t, w.Body.String())
+		})
+	}
+}
+
+func TestOauthDecideDeviceVerificationFail(t *testing.T) {
+	tests := map[string]struct {
+		form   url.Values
+		err    error
+		status int
+		code   string
+	}{
+		"missing code":   {url.Values{"action": {"approve"}}, nil, http.StatusBadRequest, ErrorCodeInvalidRequest},
+		"unknown action": {url.Values{"user_code": {"WDJB-MJHT"}, "action": {"allow"}}, nil, http.StatusBadRequest, ErrorCodeInvalidRequest},
+		"invalid code":   {url.Values{"user_code": {"WDJB-MJHT"}, "action": {"approve"}}, service.ErrInvalidOauthUserCode, http.StatusBadRequest, ErrorCodeInvalidUserCode},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthTestContext("POST", "/oauth/device", tt.form)
+			c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("DecideDeviceAuthorization", mock.Anything, mock.Anything, mock.Anything).Return(tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.DecideDeviceVerification(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestOauthConsentPage(t *testing.T) {
+	c, w := newOauthTestContext("GET", "/oauth/consent?consent_challenge=test-challenge", nil)
+	c.Request.Header.Set("Accept-Language", "ja")
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("GetConsent", "test-challenge").Return(&service.OauthConsentRequest{
+		ClientID:   "third-party-client",
+		ClientName: "<Third Party>",
+		Scopes:     []string{"openid", "custom"},
+	}, nil)
+	csrfSvcMock := new(svc_mock.CsrfSvcMockStruct)
+	csrfSvcMock.On("SessionID", "", "").Return("")
+	csrfSvcMock.On("CreateCSRFToken", mock.Anything, mock.Anything, "").Return("test-csrf-token", nil)
+
+	handler := NewOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock), csrfSvcMock, service.CsrfConfig{CookieName: "csrf_token"})
+	handler.ConsentPage(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "ja", w.Header().Get("Content-Language"))
+	assert.Contains(t, w.Header().Get("Set-Cookie"), "csrf_token=test-csrf-token")
+
+	body := w.Body.String()
+	assert.Contains(t, body, `name="_token" value="test-csrf-token"`)
+	assert.Contains(t, body, `name="consent_challenge" value="test-challenge"`)
+	// クライアント名はエスケープして表示する
+	assert.Contains(t, body, "&lt;Third Party&gt;")
+	assert.NotContains(t, body, "<Third Party>")
+	// 説明のないスコープは名前をそのまま表示する
+	assert.Contains(t, body, "custom")
+}
+
+func TestOauthConsentPageFail(t *testing.T) {
+	tests := map[string]struct {
+		target string
+		err    error
+		status int
+	}{
+		"missing challenge": {"/oauth/consent", nil, http.StatusBadRequest},
+		"invalid challenge": {"/oauth/consent?consent_challenge=test-challenge", service.ErrInvalidOauthConsentChallenge, http.StatusBadRequest},
+		"internal error":    {"/oauth/consent?consent_challenge=test-challenge", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthTestContext("GET", tt.target, nil)
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("GetConsent", "test-challenge").Return((*service.OauthConsentRequest)(nil), tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.ConsentPage(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestOauthDecideConsent(t *testing.T) {
+	for action, approve := range map[string]bool{"approve": true, "deny": false} {
+		t.Run(action, func(t *testing.T) {
+			c, w := newOauthTestContext("POST", "/oauth/consent", url.Values{"consent_challenge": {"test-challenge"}, "action": {action}})
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("DecideConsent", "test-challenge", approve).Return("https://client.example.com/callback?code=test-code", nil)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.DecideConsent(c)
+
+			assert.Equal(t, http.StatusSeeOther, c.Writer.Status())
+			assert.Equal(t, "https://client.example.com/callback?code=test-code", w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestOauthDecideConsentFail(t *testing.T) {
+	tests := map[string]struct {
+		form   url.Values
+		err    error
+		status int
+		code   string
+	}{
+		"missing challenge": {url.Values{"action": {"approve"}}, nil, http.StatusBadRequest, ErrorCodeInvalidRequest},
+		"unknown action":    {url.Values{"consent_challenge": {"test-challenge"}, "action": {"allow"}}, nil, http.StatusBadRequest, ErrorCodeInvalidRequest},
+		"invalid challenge": {url.Values{"consent_challenge": {"test-challenge"}, "action": {"approve"}}, service.ErrInvalidOauthConsentChallenge, http.StatusBadRequest, ErrorCodeInvalidConsentChallenge},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newOauthTestContext("POST", "/oauth/consent", tt.form)
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("DecideConsent", mock.Anything, mock.Anything).Return("", tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.DecideConsent(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Contains(t, w.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestOauthListConsents(t *testing.T) {
+	grantedAt := time.Date(2026, 2, 21, 9, 0, 0, 0, time.UTC)
+	c, w := newOauthTestContext("GET", "/oauth/consents", nil)
+	c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+
+	oauthSvcMock := new(svc_mock.OauthSvcMock)
+	oauthSvcMock.On("ListConsents", "test-uuid").Return([]service.OauthConsent{
+		{ClientID: "third-party-client", ClientName: "Third Party", Scopes: []string{"openid", "profile"}, GrantedAt: grantedAt},
+	}, nil)
+
+	handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+	handler.ListConsents(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"consents":[{"client_id":"third-party-client","client_name":"Third Party","scopes":["openid","profile"],"granted_at":"2026-02-21T09:00:00Z"}]}`, w.Body.String())
+}
+
+func TestOauthRevokeConsent(t *testing.T) {
+	tests := map[string]struct {
+		err    error
+		status int
+	}{
+		"revoked":        {nil, http.StatusNoContent},
+		"not found":      {service.ErrOauthConsentNotFound, http.StatusNotFound},
+		"internal error": {fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, _ := newOauthTestContext("DELETE", "/oauth/consents/third-party-client", nil)
+			c.Params = gin.Params{{Key: "client_id", Value: "third-party-client"}}
+			c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+
+			oauthSvcMock := new(svc_mock.OauthSvcMock)
+			oauthSvcMock.On("RevokeConsent", "test-uuid", "third-party-client").Return(tt.err)
+
+			handler := newTestOauthHandler(oauthSvcMock, new(svc_mock.AuthSvcMock))
+			handler.RevokeConsent(c)
+
+			assert.Equal(t, tt.status, c.Writer.Status())
+		})
+	}
+}
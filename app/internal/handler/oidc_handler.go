@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type OidcHandlerInterface interface {
+	Discovery(c *gin.Context)
+	Jwks(c *gin.Context)
+	UserInfo(c *gin.Context)
+}
+
+type OidcHandlerStruct struct {
+	BaseHandler
+	service service.OidcSvcInterface
+}
+
+func NewOidcHandler(
+	service service.OidcSvcInterface,
+) *OidcHandlerStruct {
+	return &OidcHandlerStruct{
+		service: service,
+	}
+}
+
+func (h *OidcHandlerStruct) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Discovery())
+}
+
+func (h *OidcHandlerStruct) Jwks(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Jwks())
+}
+
+// OAuth クライアントに発行したアクセストークンで呼び、許可されたスコープの項目のみを返す
+// エラーは RFC 6750 3 の WWW-Authenticate で返す
+func (h *OidcHandlerStruct) UserInfo(c *gin.Context) {
+	claims, _ := c.Value(middleware.AuthClaimsKey).(jwt.MapClaims)
+	scope, _ := claims["scope"].(string)
+
+	info, err := h.service.UserInfo(c.GetString(middleware.AuthUserUUIDKey), scope)
+	switch {
+	case errors.Is(err, service.ErrInsufficientScope):
+		c.Header("WWW-Authenticate", `Bearer realm="api", error="insufficient_scope", scope="openid"`)
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+		return
+	case errors.Is(err, service.ErrOidcUserNotFound):
+		c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	case err != nil:
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
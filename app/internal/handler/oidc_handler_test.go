@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOidcTestContext(scope any) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/userinfo", nil)
+	c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+	c.Set(middleware.AuthClaimsKey, jwt.MapClaims{"sub": "usertest-uuid", "scope": scope})
+	return c, w
+}
+
+func TestOidcDiscovery(t *testing.T) {
+	c, w := newOidcTestContext(nil)
+
+	oidcSvcMock := new(svc_mock.OidcSvcMock)
+	oidcSvcMock.On("Discovery").Return(service.OidcDiscovery{
+		Issuer:                 "https://auth.example.com",
+		ResponseTypesSupported: []string{"code"},
+	})
+
+	handler := NewOidcHandler(oidcSvcMock)
+	handler.Discovery(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := map[string]any{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "https://auth.example.com", result["issuer"])
+	assert.Equal(t, []any{"code"}, result["response_types_supported"])
+}
+
+func TestOidcJwks(t *testing.T) {
+	c, w := newOidcTestContext(nil)
+
+	oidcSvcMock := new(svc_mock.OidcSvcMock)
+	oidcSvcMock.On("Jwks").Return(service.OidcJwks{Keys: []jwttoken.JWK{{Kty: "RSA", Kid: "kid", N: "n", E: "AQAB"}}})
+
+	handler := NewOidcHandler(oidcSvcMock)
+	handler.Jwks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := map[string][]map[string]string{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "kid", result["keys"][0]["kid"])
+}
+
+func TestOidcUserInfo(t *testing.T) {
+	c, w := newOidcTestContext("openid email")
+
+	oidcSvcMock := new(svc_mock.OidcSvcMock)
+	oidcSvcMock.On("UserInfo", "test-uuid", "openid email").Return(&service.OidcUserInfo{
+		Sub:   "usertest-uuid",
+		Email: "user@example.com",
+	}, nil)
+
+	handler := NewOidcHandler(oidcSvcMock)
+	handler.UserInfo(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	// スコープで許可されていない項目は返さない
+	assert.JSONEq(t, `{"sub":"usertest-uuid","email":"user@example.com"}`, w.Body.String())
+}
+
+func TestOidcUserInfoFail(t *testing.T) {
+	tests := map[string]struct {
+		err             error
+		status          int
+		wwwAuthenticate string
+	}{
+		"insufficient scope": {service.ErrInsufficientScope, http.StatusForbidden, `Bearer realm="api", error="insufficient_scope", scope="openid"`},
+		"deleted user":       {service.ErrOidcUserNotFound, http.StatusUnauthorized, `Bearer realm="api", error="invalid_token"`},
+		"internal error":     {fmt.Errorf("db error"), http.StatusInternalServerError, ""},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			// 自サービスのログインで発行したトークンには scope クレームがない
+			c, w := newOidcTestContext(nil)
+
+			oidcSvcMock := new(svc_mock.OidcSvcMock)
+			oidcSvcMock.On("UserInfo", "test-uuid", "").Return((*service.OidcUserInfo)(nil), tt.err)
+
+			handler := NewOidcHandler(oidcSvcMock)
+			handler.UserInfo(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.wwwAuthenticate, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordlessHandlerInterface interface {
+	Start(c *gin.Context)
+}
+
+type PasswordlessHandlerStruct struct {
+	BaseHandler
+	service service.PasswordlessSvcInterface
+}
+
+func NewPasswordlessHandler(
+	service service.PasswordlessSvcInterface,
+) *PasswordlessHandlerStruct {
+	return &PasswordlessHandlerStruct{
+		service: service,
+	}
+}
+
+type passwordlessStartRequest struct {
+	Email  string `form:"email" json:"email" binding:"required,email"`
+	Method string `form:"method" json:"method" binding:"required,oneof=link code"`
+}
+
+// 登録有無にかかわらず同じレスポンスを返す
+func (h *PasswordlessHandlerStruct) Start(c *gin.Context) {
+	var req passwordlessStartRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	err := h.service.Start(service.PasswordlessStartInput{
+		Email:  req.Email,
+		Method: req.Method,
+		Locale: h.locale(c),
+	})
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"method":     req.Method,
+		"expires_in": service.PasswordlessExpiresIn,
+	})
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newPasswordlessTestContext(body map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	return c, w
+}
+
+func TestPasswordlessStartSuccess(t *testing.T) {
+	for _, method := range []string{"link", "code"} {
+		t.Run(method, func(t *testing.T) {
+			c, w := newPasswordlessTestContext(map[string]string{"email": "user@example.com", "method": method})
+			c.Request.Header.Set("Accept-Language", "ja-JP,ja;q=0.9,en;q=0.8")
+
+			passwordlessSvcMock := new(svc_mock.PasswordlessSvcMock)
+			passwordlessSvcMock.On("Start", service.PasswordlessStartInput{
+				Email:  "user@example.com",
+				Method: method,
+				Locale: "ja",
+			}).Return(nil)
+
+			handler := NewPasswordlessHandler(passwordlessSvcMock)
+			handler.Start(c)
+
+			assert.Equal(t, http.StatusAccepted, w.Code)
+
+			result := map[string]interface{}{}
+			err := json.Unmarshal(w.Body.Bytes(), &result)
+			assert.NoError(t, err)
+
+			assert.Equal(t, method, result["method"])
+			assert.Equal(t, float64(service.PasswordlessExpiresIn), result["expires_in"])
+		})
+	}
+}
+
+func TestPasswordlessStartFail(t *testing.T) {
+	c, w := newPasswordlessTestContext(map[string]string{"email": "user@example.com", "method": "code"})
+
+	passwordlessSvcMock := new(svc_mock.PasswordlessSvcMock)
+	passwordlessSvcMock.On("Start", mock.Anything).Return(fmt.Errorf("smtp error"))
+
+	handler := NewPasswordlessHandler(passwordlessSvcMock)
+	handler.Start(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestPasswordlessStartFailedValidation(t *testing.T) {
+	tests := map[string]map[string]string{
+		"email is required":  {"method": "code"},
+		"email is invalid":   {"email": "invalid", "method": "code"},
+		"method is required": {"email": "user@example.com"},
+		"method is unknown":  {"email": "user@example.com", "method": "sms"},
+	}
+
+	for title, body := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newPasswordlessTestContext(body)
+
+			passwordlessSvcMock := new(svc_mock.PasswordlessSvcMock)
+			handler := NewPasswordlessHandler(passwordlessSvcMock)
+			handler.Start(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
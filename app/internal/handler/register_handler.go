@@ -25,25 +25,33 @@ func NewRegisterHandler(
 type registerRequest struct {
 	Name     string `form:"name" json:"name" binding:"required"`
 	Email    string `form:"email" json:"email" binding:"required,email"`
-	Password string `form:"password" json:"password" binding:"required,min=8"`
+	Password string `form:"password" json:"password" binding:"required"`
+	// 省略した場合は Accept-Language から決める
+	Locale string `form:"locale" json:"locale" binding:"omitempty,oneof=en ja"`
 }
 
 func (h *RegisterHandlerStruct) Register(c *gin.Context) {
 	var req registerRequest
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		h.invalidRequest(c, err)
 		return
 	}
 
+	locale := req.Locale
+	if locale == "" {
+		locale = h.locale(c)
+	}
+
 	input := service.RegisterUserInput{
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: req.Password,
+		Locale:   locale,
 	}
 
 	user, err := h.service.RegisterUser(input)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		h.errorResponse(c, err)
 		return
 	}
 
@@ -30,12 +30,14 @@ func TestRegisterSuccess(t *testing.T) {
 	reqBody := strings.NewReader(string(jsonBody))
 	req := httptest.NewRequest("POST", "/", reqBody)
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "ja-JP,ja;q=0.9")
 	c.Request = req
 
 	input := service.RegisterUserInput{
 		Name:     "Test User",
 		Email:    "testuser@example.com",
 		Password: "securepassword",
+		Locale:   "ja",
 	}
 
 	user := models.User{
@@ -65,6 +67,52 @@ func TestRegisterSuccess(t *testing.T) {
 	assert.Equal(t, "testuser@example.com", result["email"])
 }
 
+func TestRegisterWithLocale(t *testing.T) {
+	tests := map[string]struct {
+		locale   string
+		expected int
+	}{
+		"explicit locale":    {"en", http.StatusOK},
+		"unsupported locale": {"fr", http.StatusBadRequest},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			jsonBody, _ := json.Marshal(map[string]string{
+				"name":     "Test User",
+				"email":    "testuser@example.com",
+				"password": "securepassword",
+				"locale":   tt.locale,
+			})
+			req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+			req.Header.Set("Content-Type", "application/json")
+			// 明示した言語は Accept-Language より優先する
+			req.Header.Set("Accept-Language", "ja")
+			c.Request = req
+
+			registerUserMock := new(svc_mock.UserRegisterSvcStructMock)
+			registerUserMock.On("RegisterUser", service.RegisterUserInput{
+				Name:     "Test User",
+				Email:    "testuser@example.com",
+				Password: "securepassword",
+				Locale:   "en",
+			}).Return(models.User{UUID: "some-uuid"}, nil)
+
+			handler := NewRegisterHandler(registerUserMock)
+			handler.Register(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusBadRequest {
+				assertFieldError(t, w, "locale", "oneof")
+			}
+		})
+	}
+}
+
 func TestRegisterFailRegisterUser(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	w := httptest.NewRecorder()
@@ -86,6 +134,7 @@ func TestRegisterFailRegisterUser(t *testing.T) {
 		Name:     "Test User",
 		Email:    "testuser@example.com",
 		Password: "securepassword",
+		Locale:   "en",
 	}
 
 	registerUserMock := new(svc_mock.UserRegisterSvcStructMock)
@@ -100,6 +149,97 @@ func TestRegisterFailRegisterUser(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
+func TestRegisterFailPasswordPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	body := map[string]string{
+		"name":     "Test User",
+		"email":    "testuser@example.com",
+		"password": "password",
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	reqBody := strings.NewReader(string(jsonBody))
+	req := httptest.NewRequest("POST", "/", reqBody)
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	input := service.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "testuser@example.com",
+		Password: "password",
+		Locale:   "en",
+	}
+
+	registerUserMock := new(svc_mock.UserRegisterSvcStructMock)
+	registerUserMock.On(
+		"RegisterUser",
+		input,
+	).Return(models.User{}, &service.PasswordPolicyError{
+		Violations: []service.PasswordPolicyViolation{
+			{Code: service.PasswordViolationTooWeak, Message: "password is too weak"},
+			{Code: service.PasswordViolationBreached, Message: "password has appeared in a data breach"},
+		},
+	})
+
+	handler := NewRegisterHandler(registerUserMock)
+	handler.Register(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var result problem
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ErrorCodePasswordPolicy, result.Code)
+	assert.Len(t, result.Errors, 2)
+	assert.Equal(t, "password", result.Errors[0].Field)
+	assert.Equal(t, service.PasswordViolationTooWeak, result.Errors[0].Code)
+	assert.Equal(t, service.PasswordViolationBreached, result.Errors[1].Code)
+}
+
+// 短いパスワードもバインドでは弾かず、PASSWORD_MIN_LENGTH に従ってパスワードポリシーが判定する
+func TestRegisterShortPasswordUsesPasswordPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(map[string]string{
+		"name":     "Test User",
+		"email":    "testuser@example.com",
+		"password": "Ab1!",
+	})
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+
+	registerUserMock := new(svc_mock.UserRegisterSvcStructMock)
+	registerUserMock.On("RegisterUser", service.RegisterUserInput{
+		Name:     "Test User",
+		Email:    "testuser@example.com",
+		Password: "Ab1!",
+		Locale:   "en",
+	}).Return(models.User{}, &service.PasswordPolicyError{
+		Violations: []service.PasswordPolicyViolation{
+			{Code: service.PasswordViolationTooShort, Message: "password must be at least 8 characters", Param: "8"},
+		},
+	})
+
+	handler := NewRegisterHandler(registerUserMock)
+	handler.Register(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var result problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, ErrorCodePasswordPolicy, result.Code)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, service.PasswordViolationTooShort, result.Errors[0].Code)
+	registerUserMock.AssertExpectations(t)
+}
+
 func TestRegisterFailedValidation(t *testing.T) {
 	expected := []*funcs.ValidationSetting{
 		{
@@ -122,11 +262,6 @@ func TestRegisterFailedValidation(t *testing.T) {
 			Key:       "password",
 			ErrorType: "required",
 		},
-		{
-			Title:     "Password is too short",
-			Key:       "password",
-			ErrorType: "min",
-		},
 	}
 
 	for _, exp := range expected {
@@ -159,6 +294,7 @@ func TestRegisterFailedValidation(t *testing.T) {
 			handler.Register(c)
 
 			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assertFieldError(t, w, exp.Key, exp.ErrorType)
 		})
 	}
 }
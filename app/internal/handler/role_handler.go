@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type RoleHandlerInterface interface {
+	List(c *gin.Context)
+	Create(c *gin.Context)
+	Get(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+	ListUserRoles(c *gin.Context)
+	AssignUserRole(c *gin.Context)
+	UnassignUserRole(c *gin.Context)
+}
+
+type RoleHandlerStruct struct {
+	BaseHandler
+	service service.RoleSvcInterface
+}
+
+func NewRoleHandler(
+	service service.RoleSvcInterface,
+) *RoleHandlerStruct {
+	return &RoleHandlerStruct{
+		service: service,
+	}
+}
+
+type createRoleRequest struct {
+	Name        string   `json:"name" binding:"required,max=100"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"max=100"`
+}
+
+// ロール名は変更できない（アクセストークンの roles クレームに含めるため）
+type updateRoleRequest struct {
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"max=100"`
+}
+
+type roleResponse struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func newRoleResponse(role *models.Role) roleResponse {
+	return roleResponse{
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: role.PermissionNames(),
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+func newRoleListResponse(roles []models.Role) gin.H {
+	response := make([]roleResponse, 0, len(roles))
+	for i := range roles {
+		response = append(response, newRoleResponse(&roles[i]))
+	}
+	return gin.H{"roles": response}
+}
+
+func (h *RoleHandlerStruct) List(c *gin.Context) {
+	roles, err := h.service.List()
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleListResponse(roles))
+}
+
+func (h *RoleHandlerStruct) Create(c *gin.Context) {
+	var req createRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	role, err := h.service.Create(req.Name, service.RoleInput{
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newRoleResponse(role))
+}
+
+func (h *RoleHandlerStruct) Get(c *gin.Context) {
+	role, err := h.service.Get(c.Param("name"))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(role))
+}
+
+func (h *RoleHandlerStruct) Update(c *gin.Context) {
+	var req updateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	role, err := h.service.Update(c.Param("name"), service.RoleInput{
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleResponse(role))
+}
+
+func (h *RoleHandlerStruct) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Param("name")); err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandlerStruct) ListUserRoles(c *gin.Context) {
+	roles, err := h.service.ListUserRoles(c.Param("uuid"))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newRoleListResponse(roles))
+}
+
+// 割り当て済みの場合も 204 を返す
+func (h *RoleHandlerStruct) AssignUserRole(c *gin.Context) {
+	if err := h.service.AssignUserRole(c.Param("uuid"), c.Param("name")); err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *RoleHandlerStruct) UnassignUserRole(c *gin.Context) {
+	if err := h.service.UnassignUserRole(c.Param("uuid"), c.Param("name")); err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
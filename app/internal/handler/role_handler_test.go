@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRoleTestContext(method string, params gin.Params, body any) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, "/admin/roles", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Params = params
+	return c, w
+}
+
+func decodeRole(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	result := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return result
+}
+
+var testAdminRole = &models.Role{
+	Name:        "admin",
+	Description: "Administrators",
+	Permissions: []models.Permission{{Name: "users:read"}, {Name: "users:write"}},
+}
+
+func TestRoleCreate(t *testing.T) {
+	c, w := newRoleTestContext("POST", nil, map[string]any{
+		"name":        "admin",
+		"description": "Administrators",
+		"permissions": []string{"users:read", "users:write"},
+	})
+
+	roleSvcMock := new(svc_mock.RoleSvcMock)
+	roleSvcMock.On("Create", "admin", service.RoleInput{
+		Description: "Administrators",
+		Permissions: []string{"users:read", "users:write"},
+	}).Return(testAdminRole, nil)
+
+	handler := NewRoleHandler(roleSvcMock)
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	result := decodeRole(t, w)
+	assert.Equal(t, "admin", result["name"])
+	assert.Equal(t, []any{"users:read", "users:write"}, result["permissions"])
+}
+
+func TestRoleCreateFail(t *testing.T) {
+	tests := map[string]struct {
+		body   map[string]any
+		err    error
+		status int
+	}{
+		"missing name":   {map[string]any{"permissions": []string{"users:read"}}, nil, http.StatusBadRequest},
+		"invalid role":   {map[string]any{"name": "super admin"}, service.ErrInvalidRole, http.StatusBadRequest},
+		"duplicate":      {map[string]any{"name": "admin"}, service.ErrRoleAlreadyExists, http.StatusConflict},
+		"internal error": {map[string]any{"name": "admin"}, fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newRoleTestContext("POST", nil, tt.body)
+
+			roleSvcMock := new(svc_mock.RoleSvcMock)
+			roleSvcMock.On("Create", mock.Anything, mock.Anything).Return((*models.Role)(nil), tt.err)
+
+			handler := NewRoleHandler(roleSvcMock)
+			handler.Create(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestRoleList(t *testing.T) {
+	c, w := newRoleTestContext("GET", nil, nil)
+
+	roleSvcMock := new(svc_mock.RoleSvcMock)
+	roleSvcMock.On("List").Return([]models.Role{*testAdminRole}, nil)
+
+	handler := NewRoleHandler(roleSvcMock)
+	handler.List(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	result := decodeRole(t, w)
+	roles := result["roles"].([]any)
+	assert.Len(t, roles, 1)
+	assert.Equal(t, "admin", roles[0].(map[string]any)["name"])
+}
+
+func TestRoleGet(t *testing.T) {
+	roleSvcMock := new(svc_mock.RoleSvcMock)
+	roleSvcMock.On("Get", "admin").Return(testAdminRole, nil)
+	roleSvcMock.On("Get", "unknown").Return((*models.Role)(nil), service.ErrRoleNotFound)
+	handler := NewRoleHandler(roleSvcMock)
+
+	c, w := newRoleTestContext("GET", gin.Params{{Key: "name", Value: "admin"}}, nil)
+	handler.Get(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Administrators", decodeRole(t, w)["description"])
+
+	c, w = newRoleTestContext("GET", gin.Params{{Key: "name", Value: "unknown"}}, nil)
+	handler.Get(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ErrorCodeRoleNotFound, decodeRole(t, w)["code"])
+}
+
+func TestRoleUpdate(t *testing.T) {
+	c, w := newRoleTestContext("PUT", gin.Params{{Key: "name", Value: "admin"}}, map[string]any{
+		"description": "Read only",
+		"permissions": []string{"users:read"},
+	})
+
+	roleSvcMock := new(svc_mock.RoleSvcMock)
+	roleSvcMock.On("Update", "admin", service.RoleInput{
+		Description: "Read only",
+		Permissions: []string{"users:read"},
+	}).Return(&models.Role{Name: "admin", Description: "Read only", Permissions: []models.Permission{{Name: "users:read"}}}, nil)
+
+	handler := NewRoleHandler(roleSvcMock)
+	handler.Update(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []any{"users:read"}, decodeRole(t, w)["permissions"])
+}
+
+func TestRoleUpdateFail(t *testing.T) {
+	tests := map[string]struct {
+		body   map[string]any
+		err    error
+		status int
+	}{
+		"too long description": {map[string]any{"description": strings.Repeat("a", 256)}, nil, http.StatusBadRequest},
+		"not found":            {map[string]any{}, service.ErrRoleNotFound, http.StatusNotFound},
+		"invalid permission":   {map[string]any{"permissions": []string{"users"}}, service.ErrInvalidRole, http.StatusBadRequest},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, w := newRoleTestContext("PUT", gin.Params{{Key: "name", Value: "admin"}}, tt.body)
+
+			roleSvcMock := new(svc_mock.RoleSvcMock)
+			roleSvcMock.On("Update", "admin", mock.Anything).Return((*models.Role)(nil), tt.err)
+
+			handler := NewRoleHandler(roleSvcMock)
+			handler.Update(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestRoleDelete(t *testing.T) {
+	roleSvcMock := new(svc_mock.RoleSvcMock)
+	roleSvcMock.On("Delete", "admin").Return(nil)
+	roleSvcMock.On("Delete", "unknown").Return(service.ErrRoleNotFound)
+	handler := NewRoleHandler(roleSvcMock)
+
+	c, w := newRoleTestContext("DELETE", gin.Params{{Key: "name", Value: "admin"}}, nil)
+	handler.Delete(c)
+	assert.Equal(t, http.StatusNoContent, c.Writer.Status())
+
+	c, w = newRoleTestContext("DELETE", gin.Params{{Key: "name", Value: "unknown"}}, nil)
+	handler.Delete(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRoleListUserRoles(t *testing.T) {
+	roleSvcMock := new(svc_mock.RoleSvcMock)
+	roleSvcMock.On("ListUserRoles", "user-uuid").Return([]models.Role{*testAdminRole}, nil)
+	roleSvcMock.On("ListUserRoles", "unknown").Return([]models.Role(nil), service.ErrUserNotFound)
+	handler := NewRoleHandler(roleSvcMock)
+
+	c, w := newRoleTestContext("GET", gin.Params{{Key: "uuid", Value: "user-uuid"}}, nil)
+	handler.ListUserRoles(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, decodeRole(t, w)["roles"], 1)
+
+	c, w = newRoleTestContext("GET", gin.Params{{Key: "uuid", Value: "unknown"}}, nil)
+	handler.ListUserRoles(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ErrorCodeUserNotFound, decodeRole(t, w)["code"])
+}
+
+func TestRoleAssignUserRole(t *testing.T) {
+	tests := map[string]struct {
+		err    error
+		status int
+	}{
+		"assigned":       {nil, http.StatusNoContent},
+		"user not found": {service.ErrUserNotFound, http.StatusNotFound},
+		"role not found": {service.ErrRoleNotFound, http.StatusNotFound},
+		"internal error": {fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, _ := newRoleTestContext("PUT", gin.Params{{Key: "uuid", Value: "user-uuid"}, {Key: "name", Value: "admin"}}, nil)
+
+			roleSvcMock := new(svc_mock.RoleSvcMock)
+			roleSvcMock.On("AssignUserRole", "user-uuid", "admin").Return(tt.err)
+
+			handler := NewRoleHandler(roleSvcMock)
+			handler.AssignUserRole(c)
+
+			assert.Equal(t, tt.status, c.Writer.Status())
+		})
+	}
+}
+
+func TestRoleUnassignUserRole(t *testing.T) {
+	tests := map[string]struct {
+		err    error
+		status int
+	}{
+		"unassigned":   {nil, http.StatusNoContent},
+		"not assigned": {service.ErrUserRoleNotFound, http.StatusNotFound},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			c, _ := newRoleTestContext("DELETE", gin.Params{{Key: "uuid", Value: "user-uuid"}, {Key: "name", Value: "admin"}}, nil)
+
+			roleSvcMock := new(svc_mock.RoleSvcMock)
+			roleSvcMock.On("UnassignUserRole", "user-uuid", "admin").Return(tt.err)
+
+			handler := NewRoleHandler(roleSvcMock)
+			handler.UnassignUserRole(c)
+
+			assert.Equal(t, tt.status, c.Writer.Status())
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// バリデーションエラーのフィールド名を構造体のフィールド名ではなく、クライアントが送った JSON / フォームの名前にする
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(requestFieldName)
+	}
+}
+
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// 項目ごとのエラー。code はバリデーションのルール名（required / email / min など）、message は翻訳済みの文言
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// バインドエラーを項目ごとのエラーに変換する。項目を特定できない場合（JSON の構文誤りなど）は空を返す
+func fieldErrors(err error, locale string) []fieldError {
+	var fields []fieldError
+
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		for _, fe := range validationErrs {
+			fields = append(fields, fieldError{
+				Field: fieldPath(fe.Namespace()),
+				Code:  fe.Tag(),
+				Param: ruleParam(fe),
+			})
+		}
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		fields = append(fields, fieldError{Field: typeErr.Field, Code: "type", Param: typeErr.Type.String()})
+	}
+
+	for i := range fields {
+		fields[i].Message = fieldErrorMessage(fields[i], locale)
+	}
+	return fields
+}
+
+// 項目名とルールのメッセージを翻訳する。カタログにない項目名はそのまま使う
+func fieldErrorMessage(field fieldError, locale string) string {
+	name, ok := i18n.Lookup(locale, "field."+field.Field)
+	if !ok {
+		name = field.Field
+	}
+	key := "validation." + field.Code
+	if _, ok := i18n.Lookup(locale, key); !ok {
+		key = "validation.invalid"
+	}
+	return i18n.T(locale, key, name, field.Param)
+}
+
+// "loginRequest.email" や "webauthnLoginRequest.response.clientDataJSON" から先頭の構造体名を除く
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// required_without などの他の項目を参照するルールは構造体のフィールド名になるため返さない
+func ruleParam(fe validator.FieldError) string {
+	if strings.HasPrefix(fe.Tag(), "required_") {
+		return ""
+	}
+	return fe.Param()
+}
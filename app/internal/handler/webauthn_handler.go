@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+type WebauthnHandlerInterface interface {
+	RegisterBegin(c *gin.Context)
+	RegisterFinish(c *gin.Context)
+	LoginBegin(c *gin.Context)
+}
+
+type WebauthnHandlerStruct struct {
+	BaseHandler
+	service service.WebauthnSvcInterface
+}
+
+func NewWebauthnHandler(
+	service service.WebauthnSvcInterface,
+) *WebauthnHandlerStruct {
+	return &WebauthnHandlerStruct{
+		service: service,
+	}
+}
+
+// PublicKeyCredential の JSON 表現では ArrayBuffer を base64url 文字列で送る
+type webauthnRegistrationRequest struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type" binding:"required,eq=public-key"`
+	Name     string `json:"name" binding:"max=255"`
+	Response struct {
+		ClientDataJSON    protocol.URLEncodedBase64 `json:"clientDataJSON" binding:"required"`
+		AttestationObject protocol.URLEncodedBase64 `json:"attestationObject" binding:"required"`
+	} `json:"response" binding:"required"`
+}
+
+type webauthnLoginRequest struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type" binding:"required,eq=public-key"`
+	Response struct {
+		ClientDataJSON    protocol.URLEncodedBase64 `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData protocol.URLEncodedBase64 `json:"authenticatorData" binding:"required"`
+		Signature         protocol.URLEncodedBase64 `json:"signature" binding:"required"`
+		UserHandle        protocol.URLEncodedBase64 `json:"userHandle" binding:"required"`
+	} `json:"response" binding:"required"`
+}
+
+func (h *WebauthnHandlerStruct) RegisterBegin(c *gin.Context) {
+	options, err := h.service.BeginRegistration(c.GetString(middleware.AuthUserUUIDKey))
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+func (h *WebauthnHandlerStruct) RegisterFinish(c *gin.Context) {
+	var req webauthnRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.invalidRequest(c, err)
+		return
+	}
+
+	credential, err := h.service.FinishRegistration(service.WebauthnRegistrationInput{
+		UserUUID:          c.GetString(middleware.AuthUserUUIDKey),
+		CredentialID:      req.ID,
+		ClientDataJSON:    req.Response.ClientDataJSON,
+		AttestationObject: req.Response.AttestationObject,
+		Name:              req.Name,
+	})
+	if err != nil {
+		h.authenticatedErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":   credential.CredentialID,
+		"name": credential.Name,
+	})
+}
+
+func (h *WebauthnHandlerStruct) LoginBegin(c *gin.Context) {
+	options, err := h.service.BeginLogin()
+	if err != nil {
+		h.errorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
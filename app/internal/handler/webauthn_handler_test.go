@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newWebauthnTestContext(body any) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(string(jsonBody)))
+	req.Header.Set("Content-Type", "application/json")
+	c.Request = req
+	c.Set(middleware.AuthUserUUIDKey, "test-uuid")
+
+	return c, w
+}
+
+func newPasskeyRegistrationBody() map[string]any {
+	return map[string]any{
+		"id":   "credential-id",
+		"type": "public-key",
+		"name": "My Key",
+		"response": map[string]string{
+			// パディング付きの base64url も受け付ける
+			"clientDataJSON":    "Y2xpZW50RGF0YQ==",
+			"attestationObject": "YXR0ZXN0YXRpb24",
+		},
+	}
+}
+
+func TestWebauthnRegisterBeginSuccess(t *testing.T) {
+	c, w := newWebauthnTestContext(nil)
+
+	webauthnSvcMock := new(svc_mock.WebauthnSvcMock)
+	webauthnSvcMock.On("BeginRegistration", "test-uuid").Return(&service.WebauthnCreationOptions{
+		Challenge:    []byte("challenge"),
+		RelyingParty: protocol.RelyingPartyEntity{ID: "localhost"},
+	}, nil)
+
+	handler := NewWebauthnHandler(webauthnSvcMock)
+	handler.RegisterBegin(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Y2hhbGxlbmdl", result["publicKey"]["challenge"])
+	assert.Equal(t, "localhost", result["publicKey"]["rp"].(map[string]interface{})["id"])
+}
+
+func TestWebauthnRegisterBeginFail(t *testing.T) {
+	c, w := newWebauthnTestContext(nil)
+
+	webauthnSvcMock := new(svc_mock.WebauthnSvcMock)
+	webauthnSvcMock.On("BeginRegistration", "test-uuid").Return((*service.WebauthnCreationOptions)(nil), fmt.Errorf("db error"))
+
+	handler := NewWebauthnHandler(webauthnSvcMock)
+	handler.RegisterBegin(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestWebauthnRegisterFinishSuccess(t *testing.T) {
+	c, w := newWebauthnTestContext(newPasskeyRegistrationBody())
+
+	webauthnSvcMock := new(svc_mock.WebauthnSvcMock)
+	webauthnSvcMock.On("FinishRegistration", service.WebauthnRegistrationInput{
+		UserUUID:          "test-uuid",
+		CredentialID:      "credential-id",
+		ClientDataJSON:    []byte("clientData"),
+		AttestationObject: []byte("attestation"),
+		Name:              "My Key",
+	}).Return(&models.UserCredential{
+		CredentialID: "credential-id",
+		Name:         "My Key",
+	}, nil)
+
+	handler := NewWebauthnHandler(webauthnSvcMock)
+	handler.RegisterFinish(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	result := map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "credential-id", result["id"])
+	assert.Equal(t, "My Key", result["name"])
+}
+
+func TestWebauthnRegisterFinishFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		err      error
+		expected int
+	}{
+		{"invalid response", service.ErrInvalidWebauthnResponse, http.StatusBadRequest},
+		{"already registered", service.ErrWebauthnCredentialExists, http.StatusConflict},
+		{"internal error", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			c, w := newWebauthnTestContext(newPasskeyRegistrationBody())
+
+			webauthnSvcMock := new(svc_mock.WebauthnSvcMock)
+			webauthnSvcMock.On("FinishRegistration", mock.Anything).Return((*models.UserCredential)(nil), tt.err)
+
+			handler := NewWebauthnHandler(webauthnSvcMock)
+			handler.RegisterFinish(c)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+func TestWebauthnRegisterFinishFailedValidation(t *testing.T) {
+	tests := map[string]func(body map[string]any){
+		"id is required":         func(body map[string]any) { delete(body, "id") },
+		"type must be public":    func(body map[string]any) { body["type"] = "password" },
+		"attestation required":   func(body map[string]any) { delete(body["response"].(map[string]string), "attestationObject") },
+		"invalid base64url":      func(body map[string]any) { body["response"].(map[string]string)["clientDataJSON"] = "!!" },
+		"name is too long":       func(body map[string]any) { body["name"] = strings.Repeat("a", 256) },
+		"response is not object": func(body map[string]any) { body["response"] = "invalid" },
+	}
+
+	for title, modify := range tests {
+		t.Run(title, func(t *testing.T) {
+			body := newPasskeyRegistrationBody()
+			modify(body)
+			c, w := newWebauthnTestContext(body)
+
+			webauthnSvcMock := new(svc_mock.WebauthnSvcMock)
+			handler := NewWebauthnHandler(webauthnSvcMock)
+			handler.RegisterFinish(c)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			webauthnSvcMock.AssertNotCalled(t, "FinishRegistration", mock.Anything)
+		})
+	}
+}
+
+func TestWebauthnLoginBeginSuccess(t *testing.T) {
+	c, w := newWebauthnTestContext(nil)
+
+	webauthnSvcMock := new(svc_mock.WebauthnSvcMock)
+	webauthnSvcMock.On("BeginLogin").Return(&service.WebauthnRequestOptions{
+		Challenge:        []byte("challenge"),
+		RelyingPartyID:   "localhost",
+		UserVerification: protocol.VerificationRequired,
+	}, nil)
+
+	handler := NewWebauthnHandler(webauthnSvcMock)
+	handler.LoginBegin(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	result := map[string]map[string]interface{}{}
+	err := json.Unmarshal(w.Body.Bytes(), &result)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Y2hhbGxlbmdl", result["publicKey"]["challenge"])
+	assert.Equal(t, "localhost", result["publicKey"]["rpId"])
+}
+
+func TestWebauthnLoginBeginFail(t *testing.T) {
+	c, w := newWebauthnTestContext(nil)
+
+	webauthnSvcMock := new(svc_mock.WebauthnSvcMock)
+	webauthnSvcMock.On("BeginLogin").Return((*service.WebauthnRequestOptions)(nil), fmt.Errorf("db error"))
+
+	handler := NewWebauthnHandler(webauthnSvcMock)
+	handler.LoginBegin(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,151 @@
+package i18n
+
+// 言語ごとのメッセージカタログ
+// キーの接頭辞: field.（入力項目名）、validation.（バリデーションのルール）、error.（エラーコード）、password_policy.（パスワードポリシー違反）、
+// consent.（OAuth の同意画面）、scope.（同意画面に表示するスコープの説明）
+var catalogs = map[string]map[string]string{
+	LocaleEn: {
+		"field.name":     "name",
+		"field.email":    "email",
+		"field.password": "password",
+		"field.locale":   "locale",
+
+		"validation.required":         "%[1]s is required",
+		"validation.required_with":    "%[1]s is required",
+		"validation.required_without": "%[1]s is required",
+		"validation.email":            "%[1]s must be a valid email address",
+		"validation.min":              "%[1]s must be at least %[2]s characters",
+		"validation.max":              "%[1]s must be at most %[2]s characters",
+		"validation.len":              "%[1]s must be exactly %[2]s characters",
+		"validation.numeric":          "%[1]s must contain only digits",
+		"validation.oneof":            "%[1]s must be one of: %[2]s",
+		"validation.eq":               "%[1]s must be %[2]s",
+		"validation.type":             "%[1]s has an invalid type",
+		"validation.invalid":          "%[1]s is invalid",
+
+		"error.invalid_request":                  "one or more fields are invalid",
+		"error.malformed_request":                "request body could not be parsed",
+		"error.password_policy_violation":        "password does not satisfy the password policy",
+		"error.invalid_credentials":              "invalid email or password",
+		"error.invalid_refresh_token":            "invalid refresh token",
+		"error.invalid_mfa_token":                "invalid mfa token",
+		"error.invalid_mfa_code":                 "invalid mfa code",
+		"error.totp_not_enabled":                 "totp is not enabled",
+		"error.totp_already_enabled":             "totp is already enabled",
+		"error.invalid_webauthn_response":        "invalid webauthn response",
+		"error.webauthn_credential_exists":       "webauthn credential is already registered",
+		"error.invalid_passwordless_token":       "invalid passwordless token",
+		"error.email_already_in_use":             "email is already in use",
+		"error.too_many_requests":                "too many requests",
+		"error.oauth_client_not_found":           "oauth client not found",
+		"error.invalid_client_metadata":          "client metadata is invalid",
+		"error.invalid_user_code":                "invalid or expired user code",
+		"error.invalid_consent_challenge":        "the consent request is invalid or has expired",
+		"error.consent_not_found":                "consent not found",
+		"error.invalid_scope":                    "the requested scope exceeds the granted scope",
+		"error.role_not_found":                   "role not found",
+		"error.role_already_exists":              "role already exists",
+		"error.invalid_role":                     "role name or permissions are invalid",
+		"error.user_not_found":                   "user not found",
+		"error.user_role_not_found":              "the role is not assigned to the user",
+		"error.access_denied":                    "access from this address is not allowed",
+		"error.origin_not_allowed":               "requests from this origin are not allowed",
+		"error.csrf_token_missing":               "csrf token is not set",
+		"error.invalid_csrf_token":               "invalid csrf token",
+		"error.missing_access_token":             "access token is not set",
+		"error.invalid_access_token":             "invalid access token",
+		"error.invalid_api_key":                  "invalid api key",
+		"error.insufficient_scope":               "the access token does not have the required scope",
+		"error.insufficient_permission":          "you do not have permission to perform this operation",
+		"error.insufficient_user_authentication": "more recent or stronger authentication is required",
+
+		"password_policy.password_too_short":         "password must be at least %[1]s characters",
+		"password_policy.password_too_long":          "password must be at most %[1]s characters",
+		"password_policy.password_too_many_bytes":    "password must be at most %[1]s bytes",
+		"password_policy.password_contains_email":    "password must not contain the email address",
+		"password_policy.password_contains_username": "password must not contain the username",
+		"password_policy.password_too_weak":          "password is too weak",
+		"password_policy.password_breached":          "password has appeared in a data breach",
+
+		"consent.title":       "Authorize application",
+		"consent.description": "%[1]s is requesting access to your account.",
+		"consent.approve":     "Allow",
+		"consent.deny":        "Deny",
+
+		"scope.openid":  "Sign you in with your account",
+		"scope.profile": "View your name",
+		"scope.email":   "View your email address",
+	},
+	LocaleJa: {
+		"field.name":     "名前",
+		"field.email":    "メールアドレス",
+		"field.password": "パスワード",
+		"field.locale":   "言語",
+
+		"validation.required":         "%[1]sを入力してください",
+		"validation.required_with":    "%[1]sを入力してください",
+		"validation.required_without": "%[1]sを入力してください",
+		"validation.email":            "%[1]sの形式が正しくありません",
+		"validation.min":              "%[1]sは%[2]s文字以上で入力してください",
+		"validation.max":              "%[1]sは%[2]s文字以内で入力してください",
+		"validation.len":              "%[1]sは%[2]s文字で入力してください",
+		"validation.numeric":          "%[1]sは数字で入力してください",
+		"validation.oneof":            "%[1]sは次のいずれかを指定してください: %[2]s",
+		"validation.eq":               "%[1]sは%[2]sを指定してください",
+		"validation.type":             "%[1]sの型が正しくありません",
+		"validation.invalid":          "%[1]sが正しくありません",
+
+		"error.invalid_request":                  "入力内容に誤りがあります",
+		"error.malformed_request":                "リクエストの形式が正しくありません",
+		"error.password_policy_violation":        "パスワードがパスワードポリシーを満たしていません",
+		"error.invalid_credentials":              "メールアドレスまたはパスワードが正しくありません",
+		"error.invalid_refresh_token":            "リフレッシュトークンが無効です",
+		"error.invalid_mfa_token":                "2 要素認証のトークンが無効です",
+		"error.invalid_mfa_code":                 "認証コードが正しくありません",
+		"error.totp_not_enabled":                 "2 要素認証が有効になっていません",
+		"error.totp_already_enabled":             "2 要素認証はすでに有効です",
+		"error.invalid_webauthn_response":        "パスキーの応答が正しくありません",
+		"error.webauthn_credential_exists":       "このパスキーはすでに登録されています",
+		"error.invalid_passwordless_token":       "ログインリンクまたはコードが無効です",
+		"error.email_already_in_use":             "このメールアドレスはすでに使用されています",
+		"error.too_many_requests":                "リクエストが多すぎます。しばらくしてから再度お試しください",
+		"error.oauth_client_not_found":           "OAuth クライアントが見つかりません",
+		"error.invalid_client_metadata":          "クライアントの登録内容に誤りがあります",
+		"error.invalid_user_code":                "コードが正しくないか、有効期限が切れています",
+		"error.invalid_consent_challenge":        "同意のリクエストが無効か、有効期限が切れています",
+		"error.consent_not_found":                "許可したアプリケーションが見つかりません",
+		"error.invalid_scope":                    "許可されていないスコープが含まれています",
+		"error.role_not_found":                   "ロールが見つかりません",
+		"error.role_already_exists":              "このロールはすでに存在します",
+		"error.invalid_role":                     "ロール名またはパーミッションの形式が正しくありません",
+		"error.user_not_found":                   "ユーザーが見つかりません",
+		"error.user_role_not_found":              "このロールはユーザーに割り当てられていません",
+		"error.access_denied":                    "このアドレスからのアクセスは許可されていません",
+		"error.origin_not_allowed":               "このオリジンからのリクエストは許可されていません",
+		"error.csrf_token_missing":               "CSRF トークンが設定されていません",
+		"error.invalid_csrf_token":               "CSRF トークンが無効です",
+		"error.missing_access_token":             "アクセストークンが設定されていません",
+		"error.invalid_access_token":             "アクセストークンが無効です",
+		"error.invalid_api_key":                  "API キーが無効です",
+		"error.insufficient_scope":               "アクセストークンに必要なスコープがありません",
+		"error.insufficient_permission":          "この操作を行う権限がありません",
+		"error.insufficient_user_authentication": "この操作には再度の認証、または 2 要素認証が必要です",
+
+		"password_policy.password_too_short":         "パスワードは%[1]s文字以上で入力してください",
+		"password_policy.password_too_long":          "パスワードは%[1]s文字以内で入力してください",
+		"password_policy.password_too_many_bytes":    "パスワードは%[1]sバイト以内で入力してください",
+		"password_policy.password_contains_email":    "パスワードにメールアドレスを含めることはできません",
+		"password_policy.password_contains_username": "パスワードにユーザー名を含めることはできません",
+		"password_policy.password_too_weak":          "パスワードが推測されやすすぎます",
+		"password_policy.password_breached":          "このパスワードは過去の漏洩で流出しています",
+
+		"consent.title":       "アプリケーションの許可",
+		"consent.description": "%[1]s がアカウントへのアクセスを求めています。",
+		"consent.approve":     "許可する",
+		"consent.deny":        "拒否する",
+
+		"scope.openid":  "アカウントでのログイン",
+		"scope.profile": "名前の参照",
+		"scope.email":   "メールアドレスの参照",
+	},
+}
@@ -0,0 +1,65 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	LocaleEn = "en"
+	LocaleJa = "ja"
+	// Accept-Language がない、または対応していない言語のみの場合に使う
+	DefaultLocale = LocaleEn
+)
+
+// 並び順は matcher の優先順（先頭が一致しない場合の既定値）
+var supportedLocales = []string{LocaleEn, LocaleJa}
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Japanese})
+
+// Accept-Language ヘッダーから対応している言語を選ぶ
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	return supportedLocales[index]
+}
+
+// "ja-JP" などを対応している言語に丸める。対応していない場合は空を返す
+func Normalize(locale string) string {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(locale)), "-")
+	base, _, _ = strings.Cut(base, "_")
+	for _, supported := range supportedLocales {
+		if base == supported {
+			return supported
+		}
+	}
+	return ""
+}
+
+// メッセージを返す。指定の言語にない場合は既定の言語、それもない場合はキーをそのまま返す
+func T(locale string, key string, args ...any) string {
+	message, ok := Lookup(locale, key)
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+func Lookup(locale string, key string) (string, bool) {
+	if message, ok := catalogs[locale][key]; ok {
+		return message, true
+	}
+	message, ok := catalogs[DefaultLocale][key]
+	return message, ok
+}
@@ -0,0 +1,75 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := map[string]string{
+		"":                        DefaultLocale,
+		"ja":                      LocaleJa,
+		"ja-JP,ja;q=0.9,en;q=0.8": LocaleJa,
+		"en-US,en;q=0.9,ja;q=0.8": LocaleEn,
+		"fr-FR,fr;q=0.9,ja;q=0.5": LocaleJa,
+		"fr-FR,de;q=0.9":          DefaultLocale,
+		"*":                       DefaultLocale,
+		"invalid;;;q=abc":         DefaultLocale,
+		"en;q=0.1,ja-JP;q=0.9":    LocaleJa,
+		"zh-Hant-TW,en-GB;q=0.8":  LocaleEn,
+		"ja;q=0,en;q=0.5":         LocaleEn,
+	}
+
+	for header, expected := range tests {
+		if got := Negotiate(header); got != expected {
+			t.Errorf("%q: expected %s, got %s", header, expected, got)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"ja":    LocaleJa,
+		"ja-JP": LocaleJa,
+		"JA_jp": LocaleJa,
+		" en ":  LocaleEn,
+		"en-US": LocaleEn,
+		"fr":    "",
+		"":      "",
+	}
+
+	for locale, expected := range tests {
+		if got := Normalize(locale); got != expected {
+			t.Errorf("%q: expected %q, got %q", locale, expected, got)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T(LocaleJa, "validation.min", T(LocaleJa, "field.password"), "8"); got != "パスワードは8文字以上で入力してください" {
+		t.Errorf("unexpected message: %s", got)
+	}
+	if got := T(LocaleEn, "validation.required", "email"); got != "email is required" {
+		t.Errorf("unexpected message: %s", got)
+	}
+	// 対応していない言語は既定の言語、存在しないキーはキーをそのまま返す
+	if got := T("fr", "error.invalid_credentials"); got != "invalid email or password" {
+		t.Errorf("expected fallback to default locale, got %s", got)
+	}
+	if got := T(LocaleJa, "unknown.key"); got != "unknown.key" {
+		t.Errorf("expected key, got %s", got)
+	}
+}
+
+// すべての言語で同じキーが定義されていることを確認する
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	for key := range catalogs[DefaultLocale] {
+		for _, locale := range supportedLocales {
+			if _, ok := catalogs[locale][key]; !ok {
+				t.Errorf("%s: missing key %s", locale, key)
+			}
+		}
+	}
+	for _, locale := range supportedLocales {
+		if len(catalogs[locale]) != len(catalogs[DefaultLocale]) {
+			t.Errorf("%s: expected %d keys, got %d", locale, len(catalogs[DefaultLocale]), len(catalogs[locale]))
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package jwttoken
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKS で公開する RSA 公開鍵（RFC 7517 / RFC 7518 6.3.1）
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// kid には RFC 7638 の JWK Thumbprint を使い、鍵を入れ替えると自動で変わるようにする
+func NewRSAPublicJWK(key *rsa.PublicKey) JWK {
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	// Thumbprint の入力は必須メンバーのみを辞書順に並べた JSON
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: jwt.SigningMethodRS256.Alg(),
+		Kid: base64.RawURLEncoding.EncodeToString(sum[:]),
+		N:   n,
+		E:   e,
+	}
+}
+
+// PEM 形式（PKCS #1 / PKCS #8）の RSA 秘密鍵を読み込む
+func ParseRSAPrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return key, nil
+}
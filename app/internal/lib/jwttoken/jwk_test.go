@@ -0,0 +1,74 @@
+package jwttoken
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestNewRSAPublicJWK(t *testing.T) {
+	// RFC 7638 3.1 の例
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	nBytes, _ := base64.RawURLEncoding.DecodeString(n)
+
+	jwk := NewRSAPublicJWK(&rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: 65537})
+	if jwk.N != n || jwk.E != "AQAB" || jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.Use != "sig" {
+		t.Errorf("unexpected jwk: %+v", jwk)
+	}
+	if jwk.Kid != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("unexpected kid: %s", jwk.Kid)
+	}
+}
+
+func TestSignRS256(t *testing.T) {
+	p := NewJwtTokenPkg()
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	token, err := p.SignRS256(jwt.MapClaims{
+		"sub": "usertest-uuid",
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}, key, "test-kid")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if parsed.Header["kid"] != "test-kid" {
+		t.Errorf("unexpected header: %v", parsed.Header)
+	}
+}
+
+func TestParseRSAPrivateKey(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	for title, keyPEM := range map[string][]byte{"pkcs1": pkcs1, "pkcs8": pkcs8} {
+		t.Run(title, func(t *testing.T) {
+			parsed, err := ParseRSAPrivateKey(string(keyPEM))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !parsed.Equal(key) {
+				t.Error("unexpected key")
+			}
+		})
+	}
+
+	if _, err := ParseRSAPrivateKey("invalid"); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
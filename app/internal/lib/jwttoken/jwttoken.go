@@ -0,0 +1,103 @@
+package jwttoken
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// アクセストークン以外の用途（MFA チャレンジ等）も含め、HS256 の JWT を署名・検証する
+// クライアントが署名した JWT（private_key_jwt）は登録された公開鍵で検証する
+// クライアントが検証する ID トークンは、公開鍵を配布できる RS256 で署名する
+type JwtTokenPkgInterface interface {
+	Sign(claims jwt.MapClaims, key []byte) (string, error)
+	SignRS256(claims jwt.MapClaims, key *rsa.PrivateKey, kid string) (string, error)
+	Parse(token string, key []byte) (jwt.MapClaims, error)
+	ParseWithPublicKey(token string, publicKeyPEM string) (jwt.MapClaims, error)
+}
+
+// クライアントが秘密鍵で署名した JWT（private_key_jwt 等）で受け付ける署名方式
+var PublicKeyMethods = []string{
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodPS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+}
+
+type JwtTokenPkgStruct struct{}
+
+func NewJwtTokenPkg() *JwtTokenPkgStruct {
+	return &JwtTokenPkgStruct{}
+}
+
+func (p *JwtTokenPkgStruct) Sign(claims jwt.MapClaims, key []byte) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %w", err)
+	}
+	return tokenString, nil
+}
+
+// kid ヘッダーで JWKS のどの鍵で検証すればよいかを示す
+func (p *JwtTokenPkgStruct) SignRS256(claims jwt.MapClaims, key *rsa.PrivateKey, kid string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign jwt: %w", err)
+	}
+	return tokenString, nil
+}
+
+func (p *JwtTokenPkgStruct) Parse(token string, key []byte) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(
+		token,
+		claims,
+		func(t *jwt.Token) (interface{}, error) {
+			return key, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwt: %w", err)
+	}
+	return claims, nil
+}
+
+// 公開鍵で署名を検証する。鍵の種類と alg が一致しない場合も検証に失敗する
+func (p *JwtTokenPkgStruct) ParseWithPublicKey(token string, publicKeyPEM string) (jwt.MapClaims, error) {
+	key, err := ParsePublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(
+		token,
+		claims,
+		func(t *jwt.Token) (interface{}, error) {
+			return key, nil
+		},
+		jwt.WithValidMethods(PublicKeyMethods),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwt: %w", err)
+	}
+	return claims, nil
+}
+
+// PEM 形式の RSA・ECDSA 公開鍵を読み込む
+func ParsePublicKey(publicKeyPEM string) (crypto.PublicKey, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicKeyPEM)); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM([]byte(publicKeyPEM)); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("failed to parse public key: unsupported or invalid PEM")
+}
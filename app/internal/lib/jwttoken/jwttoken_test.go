@@ -0,0 +1,169 @@
+package jwttoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSignAndParse(t *testing.T) {
+	p := NewJwtTokenPkg()
+	key := []byte("testsecretkey")
+
+	token, err := p.Sign(jwt.MapClaims{
+		"sub": "usertest-uuid",
+		"typ": "mfa",
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}, key)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	claims, err := p.Parse(token, key)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if claims["sub"] != "usertest-uuid" {
+		t.Errorf("expected sub usertest-uuid, got %v", claims["sub"])
+	}
+	if claims["typ"] != "mfa" {
+		t.Errorf("expected typ mfa, got %v", claims["typ"])
+	}
+}
+
+func TestParseInvalidKey(t *testing.T) {
+	p := NewJwtTokenPkg()
+
+	token, _ := p.Sign(jwt.MapClaims{
+		"sub": "usertest-uuid",
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}, []byte("testsecretkey"))
+
+	if _, err := p.Parse(token, []byte("wrongkey")); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestParseExpired(t *testing.T) {
+	p := NewJwtTokenPkg()
+	key := []byte("testsecretkey")
+
+	token, _ := p.Sign(jwt.MapClaims{
+		"sub": "usertest-uuid",
+		"exp": time.Now().Add(-1 * time.Minute).Unix(),
+	}, key)
+
+	if _, err := p.Parse(token, key); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestParseWithoutExp(t *testing.T) {
+	p := NewJwtTokenPkg()
+	key := []byte("testsecretkey")
+
+	token, _ := p.Sign(jwt.MapClaims{
+		"sub": "usertest-uuid",
+	}, key)
+
+	if _, err := p.Parse(token, key); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestParseRejectsOtherAlgorithms(t *testing.T) {
+	p := NewJwtTokenPkg()
+	key := []byte("testsecretkey")
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS512, jwt.MapClaims{
+		"sub": "usertest-uuid",
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	})
+	tokenString, _ := token.SignedString(key)
+
+	if _, err := p.Parse(tokenString, key); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func encodePublicKey(t *testing.T, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestParseWithPublicKey(t *testing.T) {
+	p := NewJwtTokenPkg()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	claims := jwt.MapClaims{
+		"sub": "client",
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+
+	tests := map[string]struct {
+		method jwt.SigningMethod
+		key    any
+		public crypto.PublicKey
+	}{
+		"RS256": {jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey},
+		"PS256": {jwt.SigningMethodPS256, rsaKey, &rsaKey.PublicKey},
+		"ES256": {jwt.SigningMethodES256, ecKey, &ecKey.PublicKey},
+	}
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			token, _ := jwt.NewWithClaims(tt.method, claims).SignedString(tt.key)
+
+			parsed, err := p.ParseWithPublicKey(token, encodePublicKey(t, tt.public))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if parsed["sub"] != "client" {
+				t.Errorf("unexpected claims: %v", parsed)
+			}
+		})
+	}
+}
+
+func TestParseWithPublicKeyFail(t *testing.T) {
+	p := NewJwtTokenPkg()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	publicKey := encodePublicKey(t, &rsaKey.PublicKey)
+
+	valid, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}).SignedString(rsaKey)
+	withoutExp, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{}).SignedString(rsaKey)
+	// 公開鍵を HMAC の鍵として使わせる攻撃
+	hmac, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}).SignedString([]byte(publicKey))
+
+	tests := map[string]struct {
+		token string
+		key   string
+	}{
+		"other key":   {valid, encodePublicKey(t, &otherKey.PublicKey)},
+		"without exp": {withoutExp, publicKey},
+		"hmac":        {hmac, publicKey},
+		"invalid pem": {valid, "invalid"},
+	}
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			if _, err := p.ParseWithPublicKey(tt.token, tt.key); err == nil {
+				t.Fatal("expected error, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("invalid mail header")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// Content-Language ヘッダーの値（空の場合は付けない）
+	Language string
+}
+
+type MailerPkgInterface interface {
+	Send(message Message) error
+}
+
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP_HOST が未設定の場合は送信せずログに出力する（ローカル開発用）
+func NewConfigFromEnv() Config {
+	config := Config{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+	}
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	if config.From == "" {
+		config.From = "no-reply@localhost"
+	}
+	return config
+}
+
+type MailerPkgStruct struct {
+	config Config
+	send   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewMailerPkg(config Config) *MailerPkgStruct {
+	return &MailerPkgStruct{
+		config: config,
+		send:   smtp.SendMail,
+	}
+}
+
+func (p *MailerPkgStruct) Send(message Message) error {
+	msg, err := p.build(message, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if p.config.Host == "" {
+		log.Printf("[mailer] SMTP_HOST is not set, mail is not sent\n%s", message.Body)
+		return nil
+	}
+
+	var auth smtp.Auth
+	if p.config.Username != "" {
+		auth = smtp.PlainAuth("", p.config.Username, p.config.Password, p.config.Host)
+	}
+	addr := net.JoinHostPort(p.config.Host, p.config.Port)
+	if err := p.send(addr, auth, p.config.From, []string{message.To}, msg); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+func (p *MailerPkgStruct) build(message Message, now time.Time) ([]byte, error) {
+	// ヘッダーインジェクション対策として改行を含む値は拒否する
+	for _, v := range []string{p.config.From, message.To, message.Subject, message.Language} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+	if message.To == "" {
+		return nil, ErrInvalidHeader
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", p.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	if message.Language != "" {
+		fmt.Fprintf(&buf, "Content-Language: %s\r\n", message.Language)
+	}
+	buf.WriteString("\r\n")
+
+	body := base64.StdEncoding.EncodeToString([]byte(message.Body))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,142 @@
+package mailer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+)
+
+func TestNewConfigFromEnv(t *testing.T) {
+	funcs.WithEnvMap(funcs.Envs{
+		"SMTP_HOST": "",
+		"SMTP_PORT": "",
+		"MAIL_FROM": "",
+	}, t, func() {
+		config := NewConfigFromEnv()
+		if config.Host != "" || config.Port != "587" || config.From != "no-reply@localhost" {
+			t.Errorf("unexpected default config: %+v", config)
+		}
+	})
+
+	funcs.WithEnvMap(funcs.Envs{
+		"SMTP_HOST":     "smtp.example.com",
+		"SMTP_PORT":     "2525",
+		"SMTP_USERNAME": "user",
+		"SMTP_PASSWORD": "pass",
+		"MAIL_FROM":     "auth@example.com",
+	}, t, func() {
+		config := NewConfigFromEnv()
+		expected := Config{Host: "smtp.example.com", Port: "2525", Username: "user", Password: "pass", From: "auth@example.com"}
+		if config != expected {
+			t.Errorf("expected %+v, got %+v", expected, config)
+		}
+	})
+}
+
+func TestSend(t *testing.T) {
+	p := NewMailerPkg(Config{Host: "smtp.example.com", Port: "2525", Username: "user", Password: "pass", From: "auth@example.com"})
+
+	var sentAddr, sentFrom string
+	var sentTo []string
+	var sentMsg []byte
+	p.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		sentAddr, sentFrom, sentTo, sentMsg = addr, from, to, msg
+		if a == nil {
+			t.Error("expected smtp auth")
+		}
+		return nil
+	}
+
+	body := strings.Repeat("ログインコード: 123456\n", 10)
+	err := p.Send(Message{To: "user@example.com", Subject: "ログインコード", Body: body, Language: "ja"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if sentAddr != "smtp.example.com:2525" || sentFrom != "auth@example.com" || len(sentTo) != 1 || sentTo[0] != "user@example.com" {
+		t.Errorf("unexpected envelope: %s %s %v", sentAddr, sentFrom, sentTo)
+	}
+
+	header, encoded, found := strings.Cut(string(sentMsg), "\r\n\r\n")
+	if !found {
+		t.Fatal("expected header and body")
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(strings.TrimPrefix(strings.Split(header, "\r\n")[2], "Subject: "))
+	if subject != "ログインコード" {
+		t.Errorf("unexpected subject: %s", subject)
+	}
+	if !strings.Contains(header, "\r\nContent-Language: ja") {
+		t.Errorf("expected Content-Language header: %s", header)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(encoded), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("line too long: %d", len(line))
+		}
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\r\n", ""))
+	if string(decoded) != body {
+		t.Errorf("unexpected body: %s", decoded)
+	}
+}
+
+func TestSendWithoutHost(t *testing.T) {
+	p := NewMailerPkg(Config{From: "auth@example.com"})
+	p.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		t.Error("expected mail not to be sent")
+		return nil
+	}
+
+	if err := p.Send(Message{To: "user@example.com", Subject: "subject", Body: "body"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestSendFail(t *testing.T) {
+	p := NewMailerPkg(Config{Host: "smtp.example.com", Port: "25", From: "auth@example.com"})
+	p.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if a != nil {
+			t.Error("expected no smtp auth without username")
+		}
+		return fmt.Errorf("connection refused")
+	}
+
+	if err := p.Send(Message{To: "user@example.com", Subject: "subject", Body: "body"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestSendInvalidHeader(t *testing.T) {
+	p := NewMailerPkg(Config{From: "auth@example.com"})
+
+	tests := map[string]Message{
+		"empty to":           {Subject: "subject"},
+		"to with newline":    {To: "user@example.com\r\nBcc: evil@example.com", Subject: "subject"},
+		"subject with break": {To: "user@example.com", Subject: "subject\nBcc: evil@example.com"},
+	}
+
+	for title, message := range tests {
+		if err := p.Send(message); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("%s: expected ErrInvalidHeader, got %v", title, err)
+		}
+	}
+}
+
+func TestBuildDate(t *testing.T) {
+	p := NewMailerPkg(Config{From: "auth@example.com"})
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	msg, err := p.build(Message{To: "user@example.com", Subject: "subject", Body: "body"}, now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !strings.Contains(string(msg), "Date: Thu, 02 Jan 2025 03:04:05 +0000\r\n") {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
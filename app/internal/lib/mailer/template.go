@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"text/template"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
+)
+
+// templates/<言語>/<名前>.tmpl に件名（subject）と本文（body）を定義する
+//
+//go:embed templates
+var templateFS embed.FS
+
+// テンプレートから言語に応じたメールを組み立てる。指定の言語のテンプレートがない場合は既定の言語を使う
+func NewTemplateMessage(to string, locale string, name string, data any) (Message, error) {
+	tmpl, err := template.ParseFS(templateFS, "templates/"+locale+"/"+name+".tmpl")
+	if err != nil {
+		locale = i18n.DefaultLocale
+		tmpl, err = template.ParseFS(templateFS, "templates/"+locale+"/"+name+".tmpl")
+		if err != nil {
+			return Message{}, fmt.Errorf("failed to load mail template %s: %w", name, err)
+		}
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render mail subject %s: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render mail body %s: %w", name, err)
+	}
+
+	return Message{
+		To:       to,
+		Subject:  subject.String(),
+		Body:     body.String(),
+		Language: locale,
+	}, nil
+}
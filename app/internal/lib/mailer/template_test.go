@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewTemplateMessage(t *testing.T) {
+	data := map[string]any{"Code": "123456", "Link": "https://example.com/login?token=abc", "ExpiresInMinutes": 10}
+
+	tests := []struct {
+		locale   string
+		name     string
+		language string
+		subject  string
+		body     string
+	}{
+		{"en", "passwordless_code", "en", "Your sign-in code", "Your sign-in code is 123456. The code expires in 10 minutes."},
+		{"ja", "passwordless_code", "ja", "ログイン用コードのお知らせ", "ログイン用のコードは 123456 です。コードの有効期限は 10 分です。"},
+		{"ja", "passwordless_link", "ja", "ログイン用リンクのお知らせ", "https://example.com/login?token=abc"},
+		{"fr", "passwordless_link", "en", "Your sign-in link", "https://example.com/login?token=abc"},
+		{"../en", "passwordless_link", "en", "Your sign-in link", "The link expires in 10 minutes"},
+	}
+
+	for _, tt := range tests {
+		message, err := NewTemplateMessage("user@example.com", tt.locale, tt.name, data)
+		if err != nil {
+			t.Fatalf("%s/%s: expected no error, got %v", tt.locale, tt.name, err)
+		}
+		if message.To != "user@example.com" || message.Language != tt.language || message.Subject != tt.subject {
+			t.Errorf("%s/%s: unexpected message: %+v", tt.locale, tt.name, message)
+		}
+		if !strings.Contains(message.Body, tt.body) || !strings.HasSuffix(message.Body, "\n") {
+			t.Errorf("%s/%s: unexpected body: %q", tt.locale, tt.name, message.Body)
+		}
+	}
+}
+
+func TestNewTemplateMessageNotFound(t *testing.T) {
+	if _, err := NewTemplateMessage("user@example.com", "en", "unknown", nil); err == nil {
+		t.Error("expected error, got none")
+	}
+}
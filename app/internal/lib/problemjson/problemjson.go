@@ -0,0 +1,56 @@
+package problemjson
+
+import (
+	"maps"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// RFC 7807 の Content-Type
+const ContentType = "application/problem+json"
+
+// type はエラーコードから組み立てる URI（解決できる URL ではなく識別子）
+const TypePrefix = "urn:portfolio-go-auth:problem:"
+
+// 認証済みのユーザーが設定した言語を入れるコンテキストのキー（認証のミドルウェアが設定する）
+const UserLocaleKey = "user_locale"
+
+// Accept-Language があればそれに従い、なければ認証済みのユーザーが設定した言語、それもなければ既定の言語を使う
+func Locale(c *gin.Context) string {
+	if acceptLanguage := c.GetHeader("Accept-Language"); acceptLanguage != "" {
+		return i18n.Negotiate(acceptLanguage)
+	}
+	if locale := i18n.Normalize(c.GetString(UserLocaleKey)); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
+
+// problem details を書き込む。body の type 等のメンバーは呼び出し側で設定する
+func Write(c *gin.Context, locale string, status int, body any) {
+	// c.JSON は Content-Type が設定済みの場合は上書きしない
+	c.Header("Content-Type", ContentType)
+	c.Header("Content-Language", locale)
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	c.JSON(status, body)
+}
+
+// ミドルウェアで後続の処理を中断してエラーを返す
+// detail はカタログの error.<code>、extensions は code 以外の拡張メンバー
+func Abort(c *gin.Context, status int, code string, title string, extensions gin.H) {
+	locale := Locale(c)
+	body := gin.H{
+		"type":   TypePrefix + code,
+		"title":  title,
+		"status": status,
+		"code":   code,
+	}
+	if detail, ok := i18n.Lookup(locale, "error."+code); ok {
+		body["detail"] = detail
+	}
+	maps.Copy(body, extensions)
+
+	c.Abort()
+	Write(c, locale, status, body)
+}
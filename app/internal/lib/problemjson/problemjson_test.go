@@ -0,0 +1,97 @@
+package problemjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbort(t *testing.T) {
+	tests := map[string]struct {
+		acceptLanguage string
+		locale         string
+		detail         string
+	}{
+		"default locale": {"", "en", "too many requests"},
+		"japanese":       {"ja-JP,ja;q=0.9", "ja", "リクエストが多すぎます。しばらくしてから再度お試しください"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/", nil)
+			c.Request.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			Abort(c, http.StatusTooManyRequests, "too_many_requests", "Too many requests", gin.H{"retry": 1})
+
+			assert.True(t, c.IsAborted())
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.locale, w.Header().Get("Content-Language"))
+			assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+
+			result := map[string]any{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+			assert.Equal(t, map[string]any{
+				"type":   TypePrefix + "too_many_requests",
+				"title":  "Too many requests",
+				"status": float64(http.StatusTooManyRequests),
+				"detail": tt.detail,
+				"code":   "too_many_requests",
+				"retry":  float64(1),
+			}, result)
+		})
+	}
+}
+
+// カタログにないコードは detail を省略する
+func TestAbortWithoutDetail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+
+	Abort(c, http.StatusForbidden, "unknown_code", "Forbidden", nil)
+
+	result := map[string]any{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.NotContains(t, result, "detail")
+	assert.Equal(t, "unknown_code", result["code"])
+}
+
+// Accept-Language がない場合は認証済みのユーザーが設定した言語を使う
+func TestLocale(t *testing.T) {
+	tests := map[string]struct {
+		acceptLanguage string
+		userLocale     string
+		expected       string
+	}{
+		"accept language":             {"ja", "", "ja"},
+		"accept language before user": {"en-US", "ja", "en"},
+		"user locale":                 {"", "ja", "ja"},
+		"unsupported user locale":     {"", "fr", "en"},
+		"no accept language nor user": {"", "", "en"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/", nil)
+			if tt.acceptLanguage != "" {
+				c.Request.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			if tt.userLocale != "" {
+				c.Set(UserLocaleKey, tt.userLocale)
+			}
+
+			assert.Equal(t, tt.expected, Locale(c))
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("invalid proxy protocol header")
+
+// PROXY protocol v2 のシグネチャ
+var v2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// v1 ヘッダーの最大長（CRLF を含む）
+const v1MaxLength = 107
+
+type Config struct {
+	// ロードバランサーが PROXY protocol（v1/v2）のヘッダーを付けて接続してくる場合に有効にする
+	Enabled bool
+	// ヘッダーを受け付ける接続元（IP / CIDR）。空の場合はすべての接続元にヘッダーを求める
+	TrustedProxies []string
+	// ヘッダーの受信を待つ時間
+	HeaderTimeout time.Duration
+}
+
+func NewConfigFromEnv() Config {
+	config := Config{
+		Enabled:        os.Getenv("PROXY_PROTOCOL") == "true",
+		TrustedProxies: []string{},
+		HeaderTimeout:  5 * time.Second,
+	}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			config.TrustedProxies = append(config.TrustedProxies, proxy)
+		}
+	}
+	return config
+}
+
+// PROXY protocol が有効な場合は、ヘッダーの送信元アドレスを RemoteAddr として返すリスナーを返す
+func Listen(address string, config Config) (net.Listener, error) {
+	inner, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return inner, nil
+	}
+	listener, err := NewListener(inner, config)
+	if err != nil {
+		inner.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+type Listener struct {
+	net.Listener
+	trusted       []netip.Prefix
+	headerTimeout time.Duration
+}
+
+func NewListener(inner net.Listener, config Config) (*Listener, error) {
+	trusted := make([]netip.Prefix, 0, len(config.TrustedProxies))
+	for _, proxy := range config.TrustedProxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		trusted = append(trusted, prefix)
+	}
+	return &Listener{
+		Listener:      inner,
+		trusted:       trusted,
+		headerTimeout: config.HeaderTimeout,
+	}, nil
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		Conn:          c,
+		reader:        bufio.NewReader(c),
+		requireHeader: l.isTrusted(c.RemoteAddr()),
+		headerTimeout: l.headerTimeout,
+	}, nil
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	if len(l.trusted) == 0 {
+		return true
+	}
+	addrPort, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := addrPort.Addr().Unmap()
+	for _, prefix := range l.trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ヘッダーの読み込みは Accept のループを止めないよう、最初の Read / RemoteAddr まで遅延させる
+type Conn struct {
+	net.Conn
+	reader        *bufio.Reader
+	requireHeader bool
+	headerTimeout time.Duration
+	once          sync.Once
+	remoteAddr    net.Addr
+	err           error
+}
+
+func (c *Conn) init() {
+	c.once.Do(func() {
+		// 信頼しない接続元のヘッダーは送信元を偽装できるため読まない
+		if !c.requireHeader {
+			return
+		}
+		if c.headerTimeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout))
+			defer c.Conn.SetReadDeadline(time.Time{})
+		}
+		c.remoteAddr, c.err = ReadHeader(c.reader)
+		if c.err != nil {
+			c.Conn.Close()
+		}
+	})
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// v1 / v2 のヘッダーを読み、送信元アドレスを返す
+// LOCAL コマンドや UNKNOWN の場合は nil を返す（接続元をそのまま使う）
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	signature, err := r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	if bytes.Equal(signature, v2Signature) {
+		return readV2(r)
+	}
+	if bytes.HasPrefix(signature, []byte("PROXY ")) {
+		return readV1(r)
+	}
+	return nil, ErrInvalidHeader
+}
+
+// PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	line := make([]byte, 0, v1MaxLength)
+	for len(line) < v1MaxLength {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidHeader
+	}
+
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidHeader
+	}
+
+	ip, err := netip.ParseAddr(fields[2])
+	if err != nil || ip.Is4() != (fields[1] == "TCP4") {
+		return nil, ErrInvalidHeader
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(port))), nil
+}
+
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+	if header[12]>>4 != 0x2 {
+		return nil, ErrInvalidHeader
+	}
+	command := header[12] & 0x0F
+	family := header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	switch command {
+	case 0x0:
+		// LOCAL（ヘルスチェック等、プロキシ自身からの接続）
+		return nil, nil
+	case 0x1:
+	default:
+		return nil, ErrInvalidHeader
+	}
+
+	// 上位 4 ビットがアドレスファミリー、下位 4 ビットがプロトコル
+	switch family >> 4 {
+	case 0x1:
+		if len(payload) < 12 {
+			return nil, ErrInvalidHeader
+		}
+		ip := netip.AddrFrom4([4]byte(payload[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, binary.BigEndian.Uint16(payload[8:10]))), nil
+	case 0x2:
+		if len(payload) < 36 {
+			return nil, ErrInvalidHeader
+		}
+		ip := netip.AddrFrom16([16]byte(payload[0:16]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, binary.BigEndian.Uint16(payload[32:34]))), nil
+	default:
+		// UNSPEC や UNIX ソケットは送信元を特定できないため接続元をそのまま使う
+		return nil, nil
+	}
+}
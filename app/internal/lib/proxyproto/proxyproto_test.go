@@ -0,0 +1,214 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+)
+
+func v2Header(command byte, family byte, payload []byte) []byte {
+	header := append([]byte{}, v2Signature...)
+	header = append(header, 0x20|command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(payload)))
+	return append(header, payload...)
+}
+
+func TestNewConfigFromEnv(t *testing.T) {
+	config := NewConfigFromEnv()
+	if config.Enabled || len(config.TrustedProxies) != 0 || config.HeaderTimeout != 5*time.Second {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+
+	funcs.WithEnv("PROXY_PROTOCOL", "true", t, func() {
+		funcs.WithEnv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1", t, func() {
+			config := NewConfigFromEnv()
+			if !config.Enabled || len(config.TrustedProxies) != 2 || config.TrustedProxies[1] != "192.0.2.1" {
+				t.Errorf("unexpected config: %+v", config)
+			}
+		})
+	})
+}
+
+func TestReadHeader(t *testing.T) {
+	v4Payload := []byte{192, 0, 2, 1, 198, 51, 100, 1}
+	v4Payload = binary.BigEndian.AppendUint16(v4Payload, 56324)
+	v4Payload = binary.BigEndian.AppendUint16(v4Payload, 443)
+	// 末尾の TLV は読み飛ばす
+	v4Payload = append(v4Payload, 0x04, 0x00, 0x01, 0xFF)
+
+	v6Payload := make([]byte, 32)
+	copy(v6Payload, net.ParseIP("2001:db8::1"))
+	copy(v6Payload[16:], net.ParseIP("2001:db8::2"))
+	v6Payload = binary.BigEndian.AppendUint16(v6Payload, 56324)
+	v6Payload = binary.BigEndian.AppendUint16(v6Payload, 443)
+
+	tests := map[string]struct {
+		header   []byte
+		expected string
+	}{
+		"v1 tcp4":    {[]byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"), "192.0.2.1:56324"},
+		"v1 tcp6":    {[]byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n"), "[2001:db8::1]:56324"},
+		"v1 unknown": {[]byte("PROXY UNKNOWN\r\n"), ""},
+		"v2 tcp4":    {v2Header(0x1, 0x11, v4Payload), "192.0.2.1:56324"},
+		"v2 tcp6":    {v2Header(0x1, 0x21, v6Payload), "[2001:db8::1]:56324"},
+		"v2 local":   {v2Header(0x0, 0x00, nil), ""},
+		"v2 unspec":  {v2Header(0x1, 0x00, nil), ""},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			r := bufio.NewReader(io.MultiReader(bytes.NewReader(tt.header), strings.NewReader("GET / HTTP/1.1\r\n")))
+			addr, err := ReadHeader(r)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if tt.expected == "" {
+				if addr != nil {
+					t.Errorf("expected nil addr, got %v", addr)
+				}
+			} else if addr == nil || addr.String() != tt.expected {
+				t.Errorf("expected %s, got %v", tt.expected, addr)
+			}
+
+			// ヘッダーの後ろはそのまま読める
+			rest, _ := io.ReadAll(r)
+			if string(rest) != "GET / HTTP/1.1\r\n" {
+				t.Errorf("unexpected rest: %q", rest)
+			}
+		})
+	}
+}
+
+func TestReadHeaderInvalid(t *testing.T) {
+	tests := map[string][]byte{
+		"no header":          []byte("GET / HTTP/1.1\r\nHost: example.com\r\n"),
+		"v1 without crlf":    []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\n"),
+		"v1 too long":        []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"),
+		"v1 invalid ip":      []byte("PROXY TCP4 example.com 198.51.100.1 56324 443\r\n"),
+		"v1 family mismatch": []byte("PROXY TCP4 2001:db8::1 2001:db8::2 56324 443\r\n"),
+		"v1 invalid port":    []byte("PROXY TCP4 192.0.2.1 198.51.100.1 99999 443\r\n"),
+		"v2 invalid version": append(append([]byte{}, v2Signature...), 0x11, 0x11, 0x00, 0x00),
+		"v2 short payload":   v2Header(0x1, 0x11, []byte{192, 0, 2, 1}),
+		"v2 truncated":       append(v2Header(0x1, 0x11, nil)[:14], 0x00, 0x0C),
+		"short":              []byte("PROXY"),
+	}
+
+	for title, header := range tests {
+		t.Run(title, func(t *testing.T) {
+			if _, err := ReadHeader(bufio.NewReader(bytes.NewReader(header))); !errors.Is(err, ErrInvalidHeader) {
+				t.Errorf("expected ErrInvalidHeader, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewListenerInvalidTrustedProxy(t *testing.T) {
+	if _, err := NewListener(nil, Config{TrustedProxies: []string{"example.com"}}); err == nil {
+		t.Error("expected error, got none")
+	}
+}
+
+func TestListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer inner.Close()
+
+	tests := map[string]struct {
+		trusted  []string
+		header   string
+		expected string
+	}{
+		"trusted proxy":        {[]string{"127.0.0.0/8"}, "PROXY TCP4 192.0.2.1 127.0.0.1 56324 443\r\n", "192.0.2.1:56324"},
+		"all proxies trusted":  {nil, "PROXY TCP4 192.0.2.1 127.0.0.1 56324 443\r\n", "192.0.2.1:56324"},
+		"untrusted connection": {[]string{"10.0.0.0/8"}, "", "127.0.0.1"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			listener, err := NewListener(inner, Config{TrustedProxies: tt.trusted, HeaderTimeout: time.Second})
+			if err != nil {
+				t.Fatalf("failed to create listener: %v", err)
+			}
+
+			client, err := net.Dial("tcp", inner.Addr().String())
+			if err != nil {
+				t.Fatalf("failed to dial: %v", err)
+			}
+			defer client.Close()
+			client.Write([]byte(tt.header + "hello"))
+
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatalf("failed to accept: %v", err)
+			}
+			defer conn.Close()
+
+			if !strings.HasPrefix(conn.RemoteAddr().String(), tt.expected) {
+				t.Errorf("expected remote addr %s, got %s", tt.expected, conn.RemoteAddr())
+			}
+			body := make([]byte, 5)
+			if _, err := io.ReadFull(conn, body); err != nil || string(body) != "hello" {
+				t.Errorf("unexpected body %q: %v", body, err)
+			}
+		})
+	}
+}
+
+func TestListenerRejectsMissingHeader(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer inner.Close()
+
+	listener, _ := NewListener(inner, Config{TrustedProxies: []string{"127.0.0.1"}, HeaderTimeout: time.Second})
+
+	client, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+	client.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func TestListen(t *testing.T) {
+	listener, err := Listen("127.0.0.1:0", Config{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	listener.Close()
+	if _, ok := listener.(*Listener); ok {
+		t.Error("expected plain listener when disabled")
+	}
+
+	listener, err = Listen("127.0.0.1:0", Config{Enabled: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	listener.Close()
+	if _, ok := listener.(*Listener); !ok {
+		t.Error("expected proxy protocol listener when enabled")
+	}
+
+	if _, err := Listen("127.0.0.1:0", Config{Enabled: true, TrustedProxies: []string{"invalid"}}); err == nil {
+		t.Error("expected error for invalid trusted proxy")
+	}
+}
@@ -0,0 +1,34 @@
+package qrcode
+
+import (
+	"fmt"
+
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+type QrcodePkgInterface interface {
+	EncodePNG(content string, scale int) ([]byte, error)
+}
+
+type QrcodePkgStruct struct{}
+
+func NewQrcodePkg() *QrcodePkgStruct {
+	return &QrcodePkgStruct{}
+}
+
+// 誤り訂正レベル M で符号化し、1 モジュールを scale ピクセルで描画する（静寂領域を含む）
+func (p *QrcodePkgStruct) EncodePNG(content string, scale int) ([]byte, error) {
+	code, err := goqrcode.New(content, goqrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode qr code: %w", err)
+	}
+	if scale < 1 {
+		scale = 1
+	}
+
+	png, err := code.PNG(-scale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return png, nil
+}
@@ -0,0 +1,63 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// 静寂領域（4 モジュール）
+const quietZone = 4
+
+func TestEncodePNG(t *testing.T) {
+	p := NewQrcodePkg()
+
+	b, err := p.EncodePNG("HELLO WORLD", 4)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("expected valid png, got %v", err)
+	}
+
+	// バージョン 1（21 モジュール）
+	expected := (21 + quietZone*2) * 4
+	if img.Bounds().Dx() != expected || img.Bounds().Dy() != expected {
+		t.Errorf("expected %dx%d, got %v", expected, expected, img.Bounds())
+	}
+
+	// 静寂領域は白、左上のファインダパターンは黒
+	if r, _, _, _ := img.At(0, 0).RGBA(); r != 0xffff {
+		t.Error("expected quiet zone to be white")
+	}
+	if r, _, _, _ := img.At(quietZone*4, quietZone*4).RGBA(); r != 0 {
+		t.Error("expected finder pattern to be black")
+	}
+}
+
+func TestEncodePNGMinimumScale(t *testing.T) {
+	p := NewQrcodePkg()
+
+	b, err := p.EncodePNG("HELLO WORLD", 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("expected valid png, got %v", err)
+	}
+	if img.Bounds().Dx() != 21+quietZone*2 {
+		t.Errorf("expected 1 pixel per module, got %v", img.Bounds())
+	}
+}
+
+func TestEncodePNGTooLong(t *testing.T) {
+	p := NewQrcodePkg()
+
+	if _, err := p.EncodePNG(strings.Repeat("a", 3000), 4); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
@@ -0,0 +1,97 @@
+package totp
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	otptotp "github.com/pquerna/otp/totp"
+)
+
+// RFC 6238 の既定値（Google Authenticator 等が前提としている値）
+const (
+	Period = 30
+	Digits = 6
+)
+
+type TotpPkgInterface interface {
+	GenerateSecret() (string, error)
+	Code(secret string, step int64) (string, error)
+	Validate(secret string, code string, now time.Time, skew int64) (int64, bool)
+	Step(now time.Time) int64
+	URI(issuer string, account string, secret string) (string, error)
+}
+
+type TotpPkgStruct struct{}
+
+func NewTotpPkg() *TotpPkgStruct {
+	return &TotpPkgStruct{}
+}
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+var validateOpts = hotp.ValidateOpts{
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+func (p *TotpPkgStruct) GenerateSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+func (p *TotpPkgStruct) Step(now time.Time) int64 {
+	return now.Unix() / Period
+}
+
+// 使用済みの time step を記録するため、time step を直接指定して HOTP を計算する
+func (p *TotpPkgStruct) Code(secret string, step int64) (string, error) {
+	code, err := hotp.GenerateCodeCustom(secret, uint64(step), validateOpts)
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %w", err)
+	}
+	return code, nil
+}
+
+// 前後 skew ステップまでのずれを許容して検証し、一致した time step を返す
+func (p *TotpPkgStruct) Validate(secret string, code string, now time.Time, skew int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	current := p.Step(now)
+	for step := current - skew; step <= current+skew; step++ {
+		ok, err := hotp.ValidateCustom(code, uint64(step), secret, validateOpts)
+		if err != nil {
+			return 0, false
+		}
+		if ok {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// 認証アプリ登録用の otpauth:// URI を組み立てる
+func (p *TotpPkgStruct) URI(issuer string, account string, secret string) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimSpace(secret)))
+	if err != nil {
+		return "", fmt.Errorf("invalid totp secret: %w", err)
+	}
+	generated, err := otptotp.Generate(otptotp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: account,
+		Period:      Period,
+		Secret:      key,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create totp uri: %w", err)
+	}
+	return generated.URL(), nil
+}
@@ -0,0 +1,132 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B の SHA1 テストベクタ（下 6 桁）
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	p := NewTotpPkg()
+
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := p.Code(rfcSecret, p.Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("at %d: expected %s, got %s", tt.unix, tt.expected, code)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	p := NewTotpPkg()
+
+	if _, err := p.Code("not base32!", 1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	p := NewTotpPkg()
+	now := time.Unix(1111111111, 0)
+
+	step, ok := p.Validate(rfcSecret, "050471", now, 1)
+	if !ok {
+		t.Fatal("expected code to be valid")
+	}
+	if step != p.Step(now) {
+		t.Errorf("expected step %d, got %d", p.Step(now), step)
+	}
+
+	// 1 ステップ前のコードも許容する
+	step, ok = p.Validate(rfcSecret, "081804", now, 1)
+	if !ok {
+		t.Fatal("expected previous step code to be valid")
+	}
+	if step != p.Step(now)-1 {
+		t.Errorf("expected step %d, got %d", p.Step(now)-1, step)
+	}
+
+	if _, ok := p.Validate(rfcSecret, "050471", now.Add(3*Period*time.Second), 1); ok {
+		t.Error("expected code outside skew to be invalid")
+	}
+	if _, ok := p.Validate(rfcSecret, "12345", now, 1); ok {
+		t.Error("expected short code to be invalid")
+	}
+	if _, ok := p.Validate("not base32!", "050471", now, 1); ok {
+		t.Error("expected invalid secret to fail")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	p := NewTotpPkg()
+
+	secret, err := p.GenerateSecret()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	secret2, _ := p.GenerateSecret()
+
+	if len(secret) != 32 {
+		t.Errorf("expected secret length 32, got %d", len(secret))
+	}
+	if secret == secret2 {
+		t.Error("expected different secrets")
+	}
+	if _, err := p.Code(secret, 1); err != nil {
+		t.Errorf("expected generated secret to be usable, got %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	p := NewTotpPkg()
+
+	uri, err := p.URI("Example App", "user@example.com", rfcSecret)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("expected valid uri, got %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("unexpected uri: %s", uri)
+	}
+	if u.Path != "/Example App:user@example.com" {
+		t.Errorf("unexpected label: %s", u.Path)
+	}
+	if u.Query().Get("secret") != rfcSecret {
+		t.Errorf("unexpected secret: %s", u.Query().Get("secret"))
+	}
+	if u.Query().Get("issuer") != "Example App" {
+		t.Errorf("unexpected issuer: %s", u.Query().Get("issuer"))
+	}
+	if u.Query().Get("algorithm") != "SHA1" || u.Query().Get("digits") != "6" || u.Query().Get("period") != "30" {
+		t.Errorf("unexpected parameters: %s", u.RawQuery)
+	}
+}
+
+func TestURIInvalidSecret(t *testing.T) {
+	p := NewTotpPkg()
+
+	if _, err := p.URI("Example App", "user@example.com", "not base32!"); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
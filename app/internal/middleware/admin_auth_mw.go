@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminAuthMiddlewareInterface interface {
+	Handler() gin.HandlerFunc
+	AuthzHandler() gin.HandlerFunc
+}
+
+type AdminAuthMiddleware struct{}
+
+func NewAdminAuthMiddleware() AdminAuthMiddlewareInterface {
+	return &AdminAuthMiddleware{}
+}
+
+// 管理 API は ADMIN_API_KEY を Bearer トークンとして送ったリクエストのみ許可する
+// キーが未設定の場合は管理 API を無効にし、すべて拒否する
+func (m *AdminAuthMiddleware) Handler() gin.HandlerFunc {
+	return m.handler("ADMIN_API_KEY", "admin", "Invalid admin API key")
+}
+
+// 認可判定 API は他のサービスから呼ぶため、管理 API とは別の AUTHZ_API_KEY で保護する
+func (m *AdminAuthMiddleware) AuthzHandler() gin.HandlerFunc {
+	return m.handler("AUTHZ_API_KEY", "authz", "Invalid authz API key")
+}
+
+func (m *AdminAuthMiddleware) handler(env string, realm string, title string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := os.Getenv(env)
+		scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if key == "" || !strings.EqualFold(scheme, "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s"`, realm))
+			problemjson.Abort(c, http.StatusUnauthorized, ErrorCodeInvalidApiKey, title, nil)
+			return
+		}
+		c.Next()
+	}
+}
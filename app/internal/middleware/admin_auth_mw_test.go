@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAdminAuthTestRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(NewAdminAuthMiddleware().Handler())
+	r.GET("/test", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	funcs.WithEnv("ADMIN_API_KEY", "admin-key", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer admin-key")
+		w := httptest.NewRecorder()
+		newAdminAuthTestRouter().ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestAdminAuthMiddlewareFail(t *testing.T) {
+	tests := map[string]struct {
+		key    string
+		header string
+	}{
+		"no header":     {"admin-key", ""},
+		"wrong key":     {"admin-key", "Bearer wrong"},
+		"basic scheme":  {"admin-key", "Basic admin-key"},
+		"key not set":   {"", "Bearer "},
+		"empty bearer":  {"admin-key", "Bearer "},
+		"prefix of key": {"admin-key", "Bearer admin"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			funcs.WithEnv("ADMIN_API_KEY", tt.key, t, func() {
+				req := httptest.NewRequest(http.MethodGet, "/test", nil)
+				req.Header.Set("Authorization", tt.header)
+				w := httptest.NewRecorder()
+				newAdminAuthTestRouter().ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				assert.Equal(t, `Bearer realm="admin"`, w.Header().Get("WWW-Authenticate"))
+				assertProblem(t, w, ErrorCodeInvalidApiKey)
+			})
+		})
+	}
+}
+
+func TestAdminAuthMiddlewareAuthz(t *testing.T) {
+	newRouter := func() *gin.Engine {
+		r := gin.New()
+		r.Use(NewAdminAuthMiddleware().AuthzHandler())
+		r.GET("/test", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+
+	funcs.WithEnv("ADMIN_API_KEY", "admin-key", t, func() {
+		funcs.WithEnv("AUTHZ_API_KEY", "authz-key", t, func() {
+			// 管理 API のキーでは呼べない
+			for header, status := range map[string]int{"Bearer authz-key": http.StatusOK, "Bearer admin-key": http.StatusUnauthorized} {
+				req := httptest.NewRequest(http.MethodGet, "/test", nil)
+				req.Header.Set("Authorization", header)
+				w := httptest.NewRecorder()
+				newRouter().ServeHTTP(w, req)
+
+				assert.Equal(t, status, w.Code, header)
+				if status == http.StatusUnauthorized {
+					assert.Equal(t, `Bearer realm="authz"`, w.Header().Get("WWW-Authenticate"))
+					assertProblem(t, w, ErrorCodeInvalidApiKey)
+				}
+			}
+		})
+	})
+}
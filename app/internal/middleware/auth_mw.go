@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// 認証済みユーザーの UUID を gin.Context に格納するキー
+	AuthUserUUIDKey = "auth_user_uuid"
+	// 検証済みのクレームを gin.Context に格納するキー
+	AuthClaimsKey = "auth_claims"
+)
+
+// アクセストークンの sub は "user" + UUID の形式
+const accessTokenSubjectPrefix = "user"
+
+type AuthMiddlewareInterface interface {
+	Handler() gin.HandlerFunc
+	OauthHandler() gin.HandlerFunc
+}
+
+type AuthMiddleware struct {
+	jwttoken jwttoken.JwtTokenPkgInterface
+	config   service.AuthConfig
+}
+
+func NewAuthMiddleware(
+	jwttoken jwttoken.JwtTokenPkgInterface,
+	config service.AuthConfig,
+) AuthMiddlewareInterface {
+	return &AuthMiddleware{
+		jwttoken: jwttoken,
+		config:   config,
+	}
+}
+
+// ファーストパーティの API。OAuth クライアントに発行したトークンは受け付けない
+func (m *AuthMiddleware) Handler() gin.HandlerFunc {
+	return m.handler(false)
+}
+
+// UserInfo 等、OAuth クライアントに発行したトークンでも呼べる API
+// 受け手にこのサーバー（iss）を含むトークンのみ受け付ける
+func (m *AuthMiddleware) OauthHandler() gin.HandlerFunc {
+	return m.handler(true)
+}
+
+func (m *AuthMiddleware) handler(allowClients bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			problemjson.Abort(c, http.StatusUnauthorized, ErrorCodeMissingAccessToken, "Missing access token", nil)
+			return
+		}
+
+		claims, err := m.jwttoken.Parse(token, []byte(os.Getenv("JWT_SECRET_KEY")))
+		if err != nil || !m.accepts(claims, allowClients) {
+			c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			problemjson.Abort(c, http.StatusUnauthorized, ErrorCodeInvalidAccessToken, "Invalid access token", nil)
+			return
+		}
+
+		sub, _ := claims["sub"].(string)
+		c.Set(AuthUserUUIDKey, strings.TrimPrefix(sub, accessTokenSubjectPrefix))
+		c.Set(AuthClaimsKey, claims)
+		if locale, ok := claims["locale"].(string); ok {
+			c.Set(problemjson.UserLocaleKey, locale)
+		}
+		c.Next()
+	}
+}
+
+// MFA チャレンジ等、アクセストークン以外の用途のトークンやクライアント自身のトークンは受け付けない
+func (m *AuthMiddleware) accepts(claims jwt.MapClaims, allowClients bool) bool {
+	sub, _ := claims["sub"].(string)
+	_, hasTyp := claims["typ"]
+	if hasTyp || claims["principal"] == service.PrincipalClient || !strings.HasPrefix(sub, accessTokenSubjectPrefix) {
+		return false
+	}
+	if issuer, _ := claims.GetIssuer(); issuer != m.config.Issuer {
+		return false
+	}
+
+	audience, _ := claims.GetAudience()
+	if _, ok := claims["client_id"]; ok {
+		return allowClients && slices.Contains(audience, m.config.Issuer)
+	}
+	return slices.Contains(audience, m.config.Audience)
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/lib_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAuthConfig = service.AuthConfig{
+	Issuer:   "https://auth.example.com",
+	Audience: "https://api.example.com",
+}
+
+func newAuthTestRouter(jwtTokenMock *lib_mock.JwtTokenPkgMock) *gin.Engine {
+	r := gin.New()
+	r.Use(NewAuthMiddleware(jwtTokenMock, testAuthConfig).Handler())
+	r.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"uuid": c.GetString(AuthUserUUIDKey)})
+	})
+	return r
+}
+
+func TestAuthMiddlewareSuccess(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Parse", "valid_token", []byte("testsecretkey")).Return(jwt.MapClaims{
+			"iss":   "https://auth.example.com",
+			"aud":   "https://api.example.com",
+			"sub":   "usertest-uuid",
+			"email": "user@example.com",
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+		w := httptest.NewRecorder()
+		newAuthTestRouter(jwtTokenMock).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"uuid":"test-uuid"`)
+	})
+}
+
+func TestAuthMiddlewareUserLocale(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Parse", "valid_token", []byte("testsecretkey")).Return(jwt.MapClaims{
+			"iss":    "https://auth.example.com",
+			"aud":    "https://api.example.com",
+			"sub":    "usertest-uuid",
+			"locale": "ja",
+		}, nil)
+
+		r := gin.New()
+		r.Use(NewAuthMiddleware(jwtTokenMock, testAuthConfig).Handler())
+		r.GET("/test", func(c *gin.Context) {
+			c.String(http.StatusOK, problemjson.Locale(c))
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer valid_token")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "ja", w.Body.String())
+	})
+}
+
+func TestAuthMiddlewareNoToken(t *testing.T) {
+	tests := map[string]string{
+		"empty":       "",
+		"basic":       "Basic dXNlcjpwYXNz",
+		"no token":    "Bearer ",
+		"only scheme": "Bearer",
+	}
+
+	for title, header := range tests {
+		t.Run(title, func(t *testing.T) {
+			jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", header)
+			w := httptest.NewRecorder()
+			newAuthTestRouter(jwtTokenMock).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assertProblem(t, w, ErrorCodeMissingAccessToken)
+			assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestAuthMiddlewareInvalidToken(t *testing.T) {
+	jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+	jwtTokenMock.On("Parse", "invalid_token", []byte("")).Return(nil, fmt.Errorf("invalid"))
+
+	funcs.WithEnv("JWT_SECRET_KEY", "", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer invalid_token")
+		w := httptest.NewRecorder()
+		newAuthTestRouter(jwtTokenMock).ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assertProblem(t, w, ErrorCodeInvalidAccessToken)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+	})
+}
+
+func TestAuthMiddlewareRejectsMfaToken(t *testing.T) {
+	tests := map[string]jwt.MapClaims{
+		"mfa token":      {"iss": "https://auth.example.com", "aud": "https://api.example.com", "sub": "test-uuid", "typ": "mfa"},
+		"invalid prefix": {"iss": "https://auth.example.com", "aud": "https://api.example.com", "sub": "test-uuid"},
+		"no subject":     {"iss": "https://auth.example.com", "aud": "https://api.example.com", "email": "user@example.com"},
+		"client token":   {"iss": "https://auth.example.com", "aud": "https://api.example.com", "sub": "usertest-uuid", "principal": "client"},
+		"other issuer":   {"iss": "https://other.example.com", "aud": "https://api.example.com", "sub": "usertest-uuid"},
+		"no issuer":      {"aud": "https://api.example.com", "sub": "usertest-uuid"},
+		"other audience": {"iss": "https://auth.example.com", "aud": "https://other.example.com", "sub": "usertest-uuid"},
+		"no audience":    {"iss": "https://auth.example.com", "sub": "usertest-uuid"},
+		// OAuth クライアントに発行したトークンはファーストパーティの API では使えない
+		"oauth client": {"iss": "https://auth.example.com", "aud": []any{"https://api.example.com"}, "sub": "usertest-uuid", "client_id": "test-client"},
+	}
+
+	for title, claims := range tests {
+		t.Run(title, func(t *testing.T) {
+			jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+			jwtTokenMock.On("Parse", "token", []byte("testsecretkey")).Return(claims, nil)
+
+			funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+				req := httptest.NewRequest(http.MethodGet, "/test", nil)
+				req.Header.Set("Authorization", "Bearer token")
+				w := httptest.NewRecorder()
+				newAuthTestRouter(jwtTokenMock).ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+			})
+		})
+	}
+}
+
+func TestAuthMiddlewareOauthHandler(t *testing.T) {
+	tests := map[string]struct {
+		claims jwt.MapClaims
+		status int
+	}{
+		"oauth client":       {jwt.MapClaims{"iss": "https://auth.example.com", "aud": "https://auth.example.com", "sub": "usertest-uuid", "client_id": "test-client"}, http.StatusOK},
+		"first party":        {jwt.MapClaims{"iss": "https://auth.example.com", "aud": "https://api.example.com", "sub": "usertest-uuid"}, http.StatusOK},
+		"other audience":     {jwt.MapClaims{"iss": "https://auth.example.com", "aud": []any{"https://resource.example.com"}, "sub": "usertest-uuid", "client_id": "test-client"}, http.StatusUnauthorized},
+		"client credentials": {jwt.MapClaims{"iss": "https://auth.example.com", "aud": "https://auth.example.com", "sub": "test-client", "client_id": "test-client", "principal": "client"}, http.StatusUnauthorized},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+			jwtTokenMock.On("Parse", "token", []byte("testsecretkey")).Return(tt.claims, nil)
+
+			r := gin.New()
+			r.Use(NewAuthMiddleware(jwtTokenMock, testAuthConfig).OauthHandler())
+			r.GET("/test", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"uuid": c.GetString(AuthUserUUIDKey)})
+			})
+
+			funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+				req := httptest.NewRequest(http.MethodGet, "/test", nil)
+				req.Header.Set("Authorization", "Bearer token")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				assert.Equal(t, tt.status, w.Code)
+			})
+		})
+	}
+}
@@ -1,26 +1,95 @@
 package middleware
 
 import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabcsrf"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type Middleware struct {
-	g    *gin.Engine
-	Csrf gin.HandlerFunc
+	g                    *gin.Engine
+	SecurityHeaders      gin.HandlerFunc
+	SecurityHeadersGroup func(opts SecurityHeaderOptions) gin.HandlerFunc
+	Forwarded            gin.HandlerFunc
+	Firewall             gin.HandlerFunc
+	FirewallGroup        func(group string) gin.HandlerFunc
+	Cors                 gin.HandlerFunc
+	Csrf                 gin.HandlerFunc
+	Auth                 gin.HandlerFunc
+	OauthAuth            gin.HandlerFunc
+	RequireScope         func(scope string) gin.HandlerFunc
+	RequirePermission    func(permission string) gin.HandlerFunc
+	StepUp               func(opts StepUpOptions) gin.HandlerFunc
+	AdminAuth            gin.HandlerFunc
+	AuthzAuth            gin.HandlerFunc
 }
 
-func NewMiddleware(r *gin.Engine) *Middleware {
+func NewMiddleware(r *gin.Engine, db *gorm.DB, trustedProxies service.TrustedProxies) *Middleware {
 
+	securityHeaders := NewSecurityHeadersMiddleware(
+		service.NewSecurityHeadersConfigFromEnv(),
+	)
+
+	forwarded := NewForwardedMiddleware(
+		service.NewForwardedConfigFromEnv(trustedProxies),
+	)
+
+	firewall := NewFirewallMiddleware(
+		service.NewFirewallSvc(
+			service.NewFirewallConfigFromEnv(),
+			atylabclock.NewClock(),
+		),
+	)
+
+	cors := NewCorsMiddleware(
+		service.NewCorsConfigFromEnv(),
+	)
+
+	csrfConfig := service.NewCsrfConfigFromEnv()
 	csrf := NewCSRFMiddleware(
 		service.NewCsrfSvcStruct(
 			atylabcsrf.NewCsrfPkgStruct(),
+			csrfConfig,
+			repositories.NewCsrfTokenRepo(db),
+			jwttoken.NewJwtTokenPkg(),
 		),
+		csrfConfig,
+	)
+
+	auth := NewAuthMiddleware(
+		jwttoken.NewJwtTokenPkg(),
+		service.NewAuthConfigFromEnv(),
+	)
+
+	scope := NewScopeMiddleware()
+
+	permission := NewPermissionMiddleware()
+
+	stepUp := NewStepUpMiddleware(
+		atylabclock.NewClock(),
 	)
 
+	adminAuth := NewAdminAuthMiddleware()
+
 	return &Middleware{
-		g:    r,
-		Csrf: csrf.Handler(),
+		g:                    r,
+		SecurityHeaders:      securityHeaders.Handler(),
+		SecurityHeadersGroup: securityHeaders.Group,
+		Forwarded:            forwarded.Handler(),
+		Firewall:             firewall.Handler(service.FirewallDefaultGroup),
+		FirewallGroup:        firewall.Handler,
+		Cors:                 cors.Handler(),
+		Csrf:                 csrf.Handler(),
+		Auth:                 auth.Handler(),
+		OauthAuth:            auth.OauthHandler(),
+		RequireScope:         scope.Handler,
+		RequirePermission:    permission.Handler,
+		StepUp:               stepUp.Handler,
+		AdminAuth:            adminAuth.Handler(),
+		AuthzAuth:            adminAuth.AuthzHandler(),
 	}
 }
@@ -3,13 +3,34 @@ package middleware
 import (
 	"testing"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
 )
 
 func TestNewMiddleware(t *testing.T) {
 	g := &gin.Engine{}
-	m := NewMiddleware(g)
+	m := NewMiddleware(g, &gorm.DB{}, service.TrustedProxies{})
 
 	assert.Equal(t, g, m.g)
 }
+
+func TestNewMiddlewareHandlers(t *testing.T) {
+	m := NewMiddleware(&gin.Engine{}, &gorm.DB{}, service.TrustedProxies{})
+
+	assert.NotNil(t, m.SecurityHeaders)
+	assert.NotNil(t, m.SecurityHeadersGroup)
+	assert.NotNil(t, m.Forwarded)
+	assert.NotNil(t, m.Firewall)
+	assert.NotNil(t, m.FirewallGroup)
+	assert.NotNil(t, m.Cors)
+	assert.NotNil(t, m.Csrf)
+	assert.NotNil(t, m.Auth)
+	assert.NotNil(t, m.OauthAuth)
+	assert.NotNil(t, m.RequireScope)
+	assert.NotNil(t, m.RequirePermission)
+	assert.NotNil(t, m.StepUp)
+	assert.NotNil(t, m.AdminAuth)
+	assert.NotNil(t, m.AuthzAuth)
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type CorsMiddlewareInterface interface {
+	Handler() gin.HandlerFunc
+}
+
+type CorsMiddleware struct {
+	config service.CorsConfig
+}
+
+func NewCorsMiddleware(
+	config service.CorsConfig,
+) CorsMiddlewareInterface {
+	return &CorsMiddleware{
+		config: config,
+	}
+}
+
+// CSRF より前に置き、プリフライトはここで応答して後続に渡さない
+func (m *CorsMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		// オリジンによってレスポンスが変わるため、共有キャッシュに別オリジンの結果を返させない
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		allowed, credentials := m.config.Match(origin)
+		if !allowed {
+			if preflight {
+				problemjson.Abort(c, http.StatusForbidden, ErrorCodeOriginNotAllowed, "Origin not allowed", nil)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if credentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", strings.Join(m.config.AllowedMethods, ", "))
+			c.Header("Access-Control-Allow-Headers", strings.Join(m.config.AllowedHeaders, ", "))
+			c.Header("Access-Control-Max-Age", strconv.Itoa(int(m.config.MaxAge.Seconds())))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if len(m.config.ExposedHeaders) > 0 {
+			c.Header("Access-Control-Expose-Headers", strings.Join(m.config.ExposedHeaders, ", "))
+		}
+		c.Next()
+	}
+}
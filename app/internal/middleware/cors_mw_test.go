@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testCorsConfig = service.CorsConfig{
+	AllowedOrigins:    []string{"https://*.example.com"},
+	CredentialOrigins: []string{"https://app.example.com"},
+	AllowedMethods:    []string{"GET", "POST"},
+	AllowedHeaders:    []string{"Authorization", "Content-Type", "X-CSRF-Token"},
+	ExposedHeaders:    []string{"X-CSRF-Token"},
+	MaxAge:            10 * time.Minute,
+}
+
+func newCorsTestRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(NewCorsMiddleware(testCorsConfig).Handler())
+	// プリフライトが後続のミドルウェアに届かないことを確認する
+	r.Use(func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "not set csrf token"})
+			return
+		}
+		c.Next()
+	})
+	r.POST("/auth/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	return r
+}
+
+func TestCorsMiddlewarePreflight(t *testing.T) {
+	tests := map[string]struct {
+		origin      string
+		expected    int
+		credentials string
+	}{
+		"credential origin": {"https://app.example.com", http.StatusNoContent, "true"},
+		"subdomain origin":  {"https://docs.example.com", http.StatusNoContent, ""},
+		"unknown origin":    {"https://evil.test", http.StatusForbidden, ""},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/auth/login", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", "content-type")
+			w := httptest.NewRecorder()
+			newCorsTestRouter().ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			assert.Equal(t, tt.credentials, w.Header().Get("Access-Control-Allow-Credentials"))
+			assert.Contains(t, w.Header().Values("Vary"), "Origin")
+			if tt.expected == http.StatusNoContent {
+				assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+				assert.Equal(t, "Authorization, Content-Type, X-CSRF-Token", w.Header().Get("Access-Control-Allow-Headers"))
+				assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+			} else {
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+				assertProblem(t, w, ErrorCodeOriginNotAllowed)
+			}
+		})
+	}
+}
+
+func TestCorsMiddlewareActualRequest(t *testing.T) {
+	tests := map[string]struct {
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		"credential origin": {"https://app.example.com", "https://app.example.com", "true"},
+		"subdomain origin":  {"https://docs.example.com", "https://docs.example.com", ""},
+		// 許可しないオリジンにもレスポンスは返すが、CORS ヘッダーを付けないためブラウザが読めない
+		"unknown origin": {"https://evil.test", "", ""},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+			req.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			newCorsTestRouter().ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.allowOrigin, w.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.credentials, w.Header().Get("Access-Control-Allow-Credentials"))
+			if tt.allowOrigin != "" {
+				assert.Equal(t, "X-CSRF-Token", w.Header().Get("Access-Control-Expose-Headers"))
+			}
+		})
+	}
+}
+
+func TestCorsMiddlewareWithoutOrigin(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	w := httptest.NewRecorder()
+	newCorsTestRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Values("Vary"))
+}
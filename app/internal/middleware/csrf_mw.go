@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -14,45 +18,113 @@ type CSRFMiddlewareInterface interface {
 }
 
 type CSRFMiddleware struct {
-	csrf service.CsrfSvcInterface
+	csrf         service.CsrfSvcInterface
+	config       service.CsrfConfig
+	exemptRoutes map[string]bool
 }
 
 func NewCSRFMiddleware(
 	v service.CsrfSvcInterface,
+	config service.CsrfConfig,
 ) CSRFMiddlewareInterface {
+	// Bearer のリクエストを検証しないと、セッションへの紐付けはセッション Cookie だけのリクエストにしか効かない
+	if config.BindSession && config.ExemptAPIClients {
+		log.Printf("[csrf] WARNING: CSRF_BIND_SESSION=true only applies to cookie-only requests while CSRF_EXEMPT_API_CLIENTS=true; set CSRF_EXEMPT_API_CLIENTS=false to bind bearer requests as well")
+	}
+
+	// CSRF の検証を付けないルートはルーティングで決める。ここでは CSRF_EXEMPT_ROUTES で追加したルートのみ除外する
+	exemptRoutes := map[string]bool{}
+	for _, route := range config.ExemptRoutes {
+		exemptRoutes[route] = true
+	}
 	return &CSRFMiddleware{
-		csrf: v,
+		csrf:         v,
+		config:       config,
+		exemptRoutes: exemptRoutes,
 	}
 }
 
 func (m *CSRFMiddleware) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		if isSafeMethod(c.Request.Method) || m.isExempt(c) {
 			c.Next()
 			return
 		}
+
+		if !m.config.IsAllowedOrigin(c.GetHeader("Origin"), c.GetHeader("Referer"), c.Request.Host) {
+			problemjson.Abort(c, http.StatusForbidden, ErrorCodeOriginNotAllowed, "Origin not allowed", nil)
+			return
+		}
+
+		// Cookie はクロスサイトでも自動送信されるため、ヘッダーまたはフォームの値と一致することを確認する（double-submit）
 		token := c.GetHeader("X-CSRF-Token")
 		if token == "" {
 			token = c.PostForm("_token")
 		}
-		if token == "" {
-			cookie, err := c.Cookie("csrf_token")
-			if err == nil {
-				token = cookie
-			}
+		cookie, _ := c.Cookie(m.config.CookieName)
+		if token == "" || cookie == "" {
+			problemjson.Abort(c, http.StatusBadRequest, ErrorCodeCsrfTokenMissing, "Missing CSRF token", nil)
+			return
 		}
-		if token == "" {
-			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "not set csrf token"})
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cookie)) != 1 {
+			problemjson.Abort(c, http.StatusForbidden, ErrorCodeInvalidCsrfToken, "Invalid CSRF token", nil)
 			return
 		}
+
+		sessionCookie, _ := c.Cookie(m.config.SessionCookieName)
+		sessionID := m.csrf.SessionID(bearerToken(c), sessionCookie)
 		if err := m.csrf.Verify(
 			token,
 			os.Getenv("CSRF_TOKEN"),
 			time.Now().Unix(),
+			sessionID,
 		); err != nil {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid csrf token"})
+			problemjson.Abort(c, http.StatusForbidden, ErrorCodeInvalidCsrfToken, "Invalid CSRF token", nil)
 			return
 		}
+
+		// 使い捨ての場合は次のリクエスト用のトークンを Cookie とレスポンスヘッダーで渡す
+		if m.config.SingleUse {
+			next, err := m.csrf.CreateCSRFToken(time.Now().Unix(), os.Getenv("CSRF_TOKEN"), sessionID)
+			if err != nil {
+				log.Printf("[csrf] failed to issue csrf token: %v", err)
+				problemjson.Abort(c, http.StatusInternalServerError, ErrorCodeInternal, "Internal server error", nil)
+				return
+			}
+			c.SetSameSite(m.config.CookieSameSite)
+			c.SetCookie(m.config.CookieName, next, service.CsrfTokenExpiresIn, "/", m.config.CookieDomain, m.config.CookieSecure, true)
+			c.Header("X-CSRF-Token", next)
+		}
 		c.Next()
 	}
 }
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func (m *CSRFMiddleware) isExempt(c *gin.Context) bool {
+	if m.exemptRoutes[c.Request.Method+" "+c.FullPath()] {
+		return true
+	}
+	if !m.config.ExemptAPIClients {
+		return false
+	}
+
+	// ブラウザはクロスオリジンで Bearer の Authorization ヘッダーを自動付与しないため、CSRF の対象にならない
+	// Basic はキャッシュした資格情報を自動送信し、フォームの client_id / client_secret はクロスサイトのフォームでも付けられるため除外しない
+	scheme, _, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	return strings.EqualFold(scheme, "Bearer")
+}
+
+func bearerToken(c *gin.Context) string {
+	scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return token
+}
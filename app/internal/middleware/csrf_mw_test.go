@@ -1,105 +1,430 @@
 package middleware
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabcsrf"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-func TestCsrfHandler(t *testing.T) {
-	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
-	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+var testCsrfConfig = service.CsrfConfig{
+	CookieName:        "csrf_token",
+	SessionCookieName: "csrf_session",
+	TrustedOrigins:    []string{"https://app.example.com"},
+}
+
+func newCsrfTestRouter(mockCsrfSvc *svc_mock.CsrfSvcMockStruct) *gin.Engine {
+	mockCsrfSvc.On("SessionID", "", "").Return("").Maybe()
 
 	r := gin.New()
-	r.Use(NewCSRFMiddleware(mockCsrfSvc).Handler())
+	r.Use(NewCSRFMiddleware(mockCsrfSvc, testCsrfConfig).Handler())
+	r.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "GET success"})
+	})
 	r.POST("/test", func(c *gin.Context) {
-		c.JSON(200, gin.H{"message": "success"})
+		c.JSON(http.StatusOK, gin.H{"message": "POST success"})
 	})
+	return r
+}
+
+func TestCsrfHandler(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
 
 	req := httptest.NewRequest(http.MethodPost, "/test", nil)
 	w := httptest.NewRecorder()
-	r.ServeHTTP(w, req)
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	assert.Contains(t, w.Body.String(), "not set csrf token")
+	assertProblem(t, w, ErrorCodeCsrfTokenMissing)
 }
 
 func TestCSRFMiddleware_InvalidToken(t *testing.T) {
 	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
-	mockCsrfSvc.On("Verify", "invalid-token", mock.Anything, mock.AnythingOfType("int64")).Return(fmt.Errorf("invalid"))
-
-	r := gin.New()
-	r.Use(NewCSRFMiddleware(mockCsrfSvc).Handler())
-	r.POST("/test", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "OK"})
-	})
+	mockCsrfSvc.On("Verify", "invalid-token", mock.Anything, mock.AnythingOfType("int64"), "").Return(fmt.Errorf("invalid"))
 
 	req := httptest.NewRequest(http.MethodPost, "/test", nil)
 	req.Header.Set("X-CSRF-Token", "invalid-token")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "invalid-token"})
 	w := httptest.NewRecorder()
-	r.ServeHTTP(w, req)
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusForbidden, w.Code)
-	assert.Contains(t, w.Body.String(), "invalid csrf token")
+	assertProblem(t, w, ErrorCodeInvalidCsrfToken)
 }
 
 func TestCSRFMiddlewareForGET(t *testing.T) {
 	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
-	mockCsrfSvc.On("Verify", mock.Anything, mock.Anything, mock.Anything).Return(nil).Times(1)
-
-	r := gin.New()
-	r.Use(NewCSRFMiddleware(mockCsrfSvc).Handler())
-	r.GET("/test", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"message": "GET success"})
-	})
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
 	w := httptest.NewRecorder()
-	r.ServeHTTP(w, req)
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "GET success")
+	mockCsrfSvc.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCSRFMiddlewareRejectsCookieOnly(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	mockCsrfSvc.On("Verify", "cookie_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+
+	// クロスサイトのフォーム送信でも Cookie は自動で付くため、Cookie だけでは通さない
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "cookie_token"})
+	w := httptest.NewRecorder()
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assertProblem(t, w, ErrorCodeCsrfTokenMissing)
+}
+
+func TestCSRFMiddlewareRejectsHeaderOnly(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req.Header.Set("X-CSRF-Token", "valid_token")
+	w := httptest.NewRecorder()
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
-func TestCSRFMiddlewareForCookie(t *testing.T) {
+func TestCSRFMiddlewareMismatch(t *testing.T) {
 	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
-	mockCsrfSvc.On("Verify", "cookie_token", mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+	mockCsrfSvc.On("Verify", mock.Anything, mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req.Header.Set("X-CSRF-Token", "attacker_token")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "victim_token"})
+	w := httptest.NewRecorder()
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assertProblem(t, w, ErrorCodeInvalidCsrfToken)
+	mockCsrfSvc.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCSRFMiddlewareSuccess(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req.Header.Set("X-CSRF-Token", "valid_token")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "valid_token"})
+	w := httptest.NewRecorder()
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "POST success")
+}
+
+func TestCSRFMiddlewareFormToken(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+
+	form := url.Values{"_token": {"valid_token"}}
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "valid_token"})
+	w := httptest.NewRecorder()
+	newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCSRFMiddlewareOrigin(t *testing.T) {
+	tests := []struct {
+		title    string
+		origin   string
+		referer  string
+		expected int
+	}{
+		{"same origin", "http://example.com", "", http.StatusOK},
+		{"trusted origin", "https://app.example.com", "", http.StatusOK},
+		{"trusted referer", "", "https://app.example.com/login", http.StatusOK},
+		{"no origin", "", "", http.StatusOK},
+		{"untrusted origin", "https://evil.example.com", "", http.StatusForbidden},
+		{"untrusted referer", "", "https://evil.example.com/form", http.StatusForbidden},
+		{"origin takes precedence", "https://evil.example.com", "https://app.example.com/", http.StatusForbidden},
+		{"null origin", "null", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+			mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/test", nil)
+			req.Header.Set("X-CSRF-Token", "valid_token")
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "valid_token"})
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			w := httptest.NewRecorder()
+			newCsrfTestRouter(mockCsrfSvc).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusForbidden {
+				assertProblem(t, w, ErrorCodeOriginNotAllowed)
+			}
+		})
+	}
+}
+
+func TestCSRFMiddlewareExemptAPIClients(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	for _, exempt := range []bool{true, false} {
+		config := testCsrfConfig
+		config.ExemptAPIClients = exempt
+
+		r := gin.New()
+		r.Use(NewCSRFMiddleware(mockCsrfSvc, config).Handler())
+		r.POST("/test", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "POST success"})
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/test", nil)
+		req.Header.Set("Authorization", "Bearer access_token")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if exempt {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// Basic とフォームのクライアント資格情報はクロスサイトのリクエストにも付くため除外しない
+func TestCSRFMiddlewareClientCredentialsNotExempt(t *testing.T) {
+	tests := map[string]func(req *http.Request){
+		"basic client credentials": func(req *http.Request) {
+			req.SetBasicAuth("client", "secret")
+		},
+		"client_secret_post": func(req *http.Request) {
+			req.Body = io.NopCloser(strings.NewReader(url.Values{"client_id": {"client"}, "client_secret": {"secret"}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		},
+	}
+
+	for title, setup := range tests {
+		t.Run(title, func(t *testing.T) {
+			mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+			config := testCsrfConfig
+			config.ExemptAPIClients = true
+
+			r := gin.New()
+			r.Use(NewCSRFMiddleware(mockCsrfSvc, config).Handler())
+			r.POST("/test", func(c *gin.Context) {
+				c.JSON(http.StatusOK, gin.H{"message": "POST success"})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			setup(req)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestCSRFMiddlewareExemptRoutes(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	config := testCsrfConfig
+	config.ExemptRoutes = []string{"POST /webhooks/:id"}
 
 	r := gin.New()
-	r.Use(NewCSRFMiddleware(mockCsrfSvc).Handler())
+	r.Use(NewCSRFMiddleware(mockCsrfSvc, config).Handler())
+	for _, path := range []string{"/webhooks/:id", "/test"} {
+		r.POST(path, func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "POST success"})
+		})
+	}
+	r.PUT("/webhooks/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "PUT success"})
+	})
+
+	tests := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		{http.MethodPost, "/webhooks/123", http.StatusOK},
+		{http.MethodPut, "/webhooks/123", http.StatusBadRequest},
+		{http.MethodPost, "/test", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.expected, w.Code, "%s %s", tt.method, tt.path)
+	}
+}
+
+func TestCSRFMiddlewareWarnsBindSessionWithExemptAPIClients(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	config := testCsrfConfig
+	config.BindSession = true
+	config.ExemptAPIClients = true
+	NewCSRFMiddleware(new(svc_mock.CsrfSvcMockStruct), config)
+	assert.Contains(t, buf.String(), "CSRF_BIND_SESSION=true only applies to cookie-only requests")
+
+	buf.Reset()
+	config.ExemptAPIClients = false
+	NewCSRFMiddleware(new(svc_mock.CsrfSvcMockStruct), config)
+	assert.Empty(t, buf.String())
+}
+
+func TestCSRFMiddlewareBindSession(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	mockCsrfSvc.On("SessionID", "access-token", "").Return("session-id")
+	mockCsrfSvc.On("Verify", "bound_token", mock.Anything, mock.AnythingOfType("int64"), "session-id").Return(nil)
+	mockCsrfSvc.On("Verify", "anonymous_token", mock.Anything, mock.AnythingOfType("int64"), "session-id").Return(fmt.Errorf("invalid token signature"))
+
+	config := testCsrfConfig
+	config.BindSession = true
+	r := gin.New()
+	r.Use(NewCSRFMiddleware(mockCsrfSvc, config).Handler())
+	r.POST("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "POST success"})
+	})
+
+	tests := map[string]struct {
+		token    string
+		expected int
+	}{
+		"token bound to the session": {"bound_token", http.StatusOK},
+		"token issued before login":  {"anonymous_token", http.StatusForbidden},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			req.Header.Set("Authorization", "Bearer access-token")
+			req.Header.Set("X-CSRF-Token", tt.token)
+			req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.token})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
+
+// Bearer のない Cookie だけのリクエストでは、セッション Cookie のセッションに紐付けて検証する
+func TestCSRFMiddlewareBindCookieSession(t *testing.T) {
+	funcs.WithEnv("CSRF_TOKEN", "test_secret", t, func() {
+		config := testCsrfConfig
+		config.BindSession = true
+		csrfSvc := service.NewCsrfSvcStruct(atylabcsrf.NewCsrfPkgStruct(), config, nil, nil)
+
+		r := gin.New()
+		r.Use(NewCSRFMiddleware(csrfSvc, config).Handler())
+		r.POST("/test", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"message": "POST success"})
+		})
+
+		sessionA := csrfSvc.SessionCookie("session-a")
+		tokenA, err := csrfSvc.CreateCSRFToken(time.Now().Unix(), "test_secret", csrfSvc.SessionID("", sessionA))
+		assert.NoError(t, err)
+
+		tests := map[string]struct {
+			sessionCookie string
+			expected      int
+		}{
+			"same session":      {sessionA, http.StatusOK},
+			"another session":   {csrfSvc.SessionCookie("session-b"), http.StatusForbidden},
+			"forged session":    {"session-a.forged", http.StatusForbidden},
+			"no session cookie": {"", http.StatusForbidden},
+		}
+
+		for title, tt := range tests {
+			t.Run(title, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/test", nil)
+				req.Header.Set("X-CSRF-Token", tokenA)
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tokenA})
+				if tt.sessionCookie != "" {
+					req.AddCookie(&http.Cookie{Name: "csrf_session", Value: tt.sessionCookie})
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				assert.Equal(t, tt.expected, w.Code)
+			})
+		}
+	})
+}
+
+func TestCSRFMiddlewareSingleUse(t *testing.T) {
+	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
+	mockCsrfSvc.On("SessionID", "", "").Return("")
+	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+	mockCsrfSvc.On("CreateCSRFToken", mock.AnythingOfType("int64"), mock.Anything, "").Return("next_token", nil)
+
+	config := testCsrfConfig
+	config.SingleUse = true
+	r := gin.New()
+	r.Use(NewCSRFMiddleware(mockCsrfSvc, config).Handler())
 	r.POST("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "POST success"})
 	})
 
 	req := httptest.NewRequest(http.MethodPost, "/test", nil)
-	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "cookie_token"})
+	req.Header.Set("X-CSRF-Token", "valid_token")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "valid_token"})
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Contains(t, w.Body.String(), "POST success")
+	assert.Equal(t, "next_token", w.Header().Get("X-CSRF-Token"))
+	assert.Contains(t, w.Header().Get("Set-Cookie"), "csrf_token=next_token")
 }
 
-func TestCSRFMiddlewareSuccess(t *testing.T) {
+func TestCSRFMiddlewareSingleUseIssueFail(t *testing.T) {
 	mockCsrfSvc := new(svc_mock.CsrfSvcMockStruct)
-	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64")).Return(nil)
+	mockCsrfSvc.On("SessionID", "", "").Return("")
+	mockCsrfSvc.On("Verify", "valid_token", mock.Anything, mock.AnythingOfType("int64"), "").Return(nil)
+	mockCsrfSvc.On("CreateCSRFToken", mock.Anything, mock.Anything, "").Return("", fmt.Errorf("db error"))
 
+	config := testCsrfConfig
+	config.SingleUse = true
 	r := gin.New()
-	r.Use(NewCSRFMiddleware(mockCsrfSvc).Handler())
+	r.Use(NewCSRFMiddleware(mockCsrfSvc, config).Handler())
 	r.POST("/test", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "POST success"})
 	})
 
 	req := httptest.NewRequest(http.MethodPost, "/test", nil)
 	req.Header.Set("X-CSRF-Token", "valid_token")
+	req.AddCookie(&http.Cookie{Name: "csrf_token", Value: "valid_token"})
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Contains(t, w.Body.String(), "POST success")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
@@ -0,0 +1,16 @@
+package middleware
+
+// クライアントが分岐に使う機械判読用のエラーコード。値は変更しない
+// レスポンスはハンドラーと同じ problem+json（problemjson.Abort）で返す
+const (
+	ErrorCodeAccessDenied           = "access_denied"
+	ErrorCodeOriginNotAllowed       = "origin_not_allowed"
+	ErrorCodeCsrfTokenMissing       = "csrf_token_missing"
+	ErrorCodeInvalidCsrfToken       = "invalid_csrf_token"
+	ErrorCodeMissingAccessToken     = "missing_access_token"
+	ErrorCodeInvalidAccessToken     = "invalid_access_token"
+	ErrorCodeInvalidApiKey          = "invalid_api_key"
+	ErrorCodeInsufficientScope      = "insufficient_scope"
+	ErrorCodeInsufficientPermission = "insufficient_permission"
+	ErrorCodeInternal               = "internal_error"
+)
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/stretchr/testify/assert"
+)
+
+// ミドルウェアのエラーがハンドラーと同じ problem+json で返ることを確認し、拡張メンバーを含む本文を返す
+func assertProblem(t *testing.T, w *httptest.ResponseRecorder, code string) map[string]any {
+	t.Helper()
+	assert.Equal(t, problemjson.ContentType, w.Header().Get("Content-Type"))
+
+	result := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	assert.Equal(t, code, result["code"])
+	assert.Equal(t, problemjson.TypePrefix+code, result["type"])
+	assert.Equal(t, float64(w.Code), result["status"])
+	return result
+}
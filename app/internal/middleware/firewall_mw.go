@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type FirewallMiddlewareInterface interface {
+	Handler(group string) gin.HandlerFunc
+}
+
+type FirewallMiddleware struct {
+	firewall service.FirewallSvcInterface
+}
+
+func NewFirewallMiddleware(
+	firewall service.FirewallSvcInterface,
+) FirewallMiddlewareInterface {
+	return &FirewallMiddleware{
+		firewall: firewall,
+	}
+}
+
+// クライアント IP は TRUSTED_PROXIES を渡した gin の信頼するプロキシ設定に従って解決する（App.initTrustedProxies）
+func (m *FirewallMiddleware) Handler(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.firewall.Allowed(group, c.ClientIP()) {
+			problemjson.Abort(c, http.StatusForbidden, ErrorCodeAccessDenied, "Access denied", nil)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/svc_mock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newFirewallTestRouter(firewallSvc *svc_mock.FirewallSvcMock, trustedProxies []string) *gin.Engine {
+	r := gin.New()
+	r.SetTrustedProxies(trustedProxies)
+	r.GET("/admin", NewFirewallMiddleware(firewallSvc).Handler("admin"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	return r
+}
+
+func TestFirewallMiddleware(t *testing.T) {
+	firewallSvc := new(svc_mock.FirewallSvcMock)
+	firewallSvc.On("Allowed", "admin", "10.0.0.1").Return(true)
+	firewallSvc.On("Allowed", "admin", "198.51.100.1").Return(false)
+
+	tests := map[string]struct {
+		remoteAddr string
+		expected   int
+	}{
+		"allowed": {"10.0.0.1:12345", http.StatusOK},
+		"denied":  {"198.51.100.1:12345", http.StatusForbidden},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			newFirewallTestRouter(firewallSvc, nil).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusForbidden {
+				assertProblem(t, w, ErrorCodeAccessDenied)
+			}
+		})
+	}
+}
+
+func TestFirewallMiddlewareClientIPBehindProxy(t *testing.T) {
+	tests := map[string]struct {
+		remoteAddr string
+		expectedIP string
+	}{
+		// 信頼するプロキシ経由の場合は X-Forwarded-For のクライアント IP で判定する
+		"trusted proxy": {"192.0.2.10:443", "10.0.0.1"},
+		// 信頼しない接続元が付けた X-Forwarded-For は偽装できるため使わない
+		"untrusted proxy": {"198.51.100.1:443", "198.51.100.1"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			firewallSvc := new(svc_mock.FirewallSvcMock)
+			firewallSvc.On("Allowed", "admin", tt.expectedIP).Return(true)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "10.0.0.1")
+			w := httptest.NewRecorder()
+			newFirewallTestRouter(firewallSvc, []string{"192.0.2.0/24"}).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			firewallSvc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ForwardedMiddlewareInterface interface {
+	Handler() gin.HandlerFunc
+}
+
+type ForwardedMiddleware struct {
+	config service.ForwardedConfig
+}
+
+func NewForwardedMiddleware(
+	config service.ForwardedConfig,
+) ForwardedMiddlewareInterface {
+	return &ForwardedMiddleware{
+		config: config,
+	}
+}
+
+// RFC 7239 の Forwarded ヘッダーの for= を X-Forwarded-For として渡す
+// クライアントが付けた Forwarded はプロキシがそのまま通すことがあるため、明示的に有効にした場合かつ
+// 直接の接続元が信頼するプロキシの場合のみ使い、プロキシが付けた X-Forwarded-For は上書きしない
+// 以降のクライアント IP の判定は gin の ClientIP に任せるため、ファイアウォールより前に置く
+func (m *ForwardedMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		forwarded := c.Request.Header.Values("Forwarded")
+		if m.config.Enabled && len(forwarded) > 0 && c.Request.Header.Get("X-Forwarded-For") == "" && m.fromTrustedProxy(c) {
+			c.Request.Header.Set("X-Forwarded-For", strings.Join(ParseForwardedFor(strings.Join(forwarded, ",")), ", "))
+		}
+		c.Next()
+	}
+}
+
+func (m *ForwardedMiddleware) fromTrustedProxy(c *gin.Context) bool {
+	addrPort, err := netip.ParseAddrPort(c.Request.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	return m.config.IsTrustedProxy(addrPort.Addr())
+}
+
+// Forwarded: for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"
+// unknown や難読化された識別子（_hidden 等）は IP に変換できないため "unknown" として残し、
+// それより手前のアドレスを信用しないようにする
+func ParseForwardedFor(value string) []string {
+	addrs := []string{}
+	for _, element := range strings.Split(value, ",") {
+		for _, pair := range strings.Split(element, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || !strings.EqualFold(key, "for") {
+				continue
+			}
+			addrs = append(addrs, forwardedNode(strings.Trim(val, `"`)))
+		}
+	}
+	return addrs
+}
+
+func forwardedNode(node string) string {
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().String()
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")); err == nil {
+		return addr.String()
+	}
+	return "unknown"
+}
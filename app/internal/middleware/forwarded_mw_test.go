@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForwardedFor(t *testing.T) {
+	tests := map[string][]string{
+		`for=192.0.2.60;proto=http;by=203.0.113.43`:      {"192.0.2.60"},
+		`for="[2001:db8:cafe::17]:4711"`:                 {"2001:db8:cafe::17"},
+		`For="[2001:db8::1]"`:                            {"2001:db8::1"},
+		`for=192.0.2.43, for=198.51.100.17`:              {"192.0.2.43", "198.51.100.17"},
+		`for="192.0.2.43:8080";proto=https, for=unknown`: {"192.0.2.43", "unknown"},
+		`for=_hidden, for=198.51.100.17`:                 {"unknown", "198.51.100.17"},
+		`proto=https;by=203.0.113.43`:                    {},
+	}
+
+	for header, expected := range tests {
+		assert.Equal(t, expected, ParseForwardedFor(header), header)
+	}
+}
+
+func TestForwardedMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		enabled      bool
+		remoteAddr   string
+		forwardedFor string
+		forwarded    []string
+		expected     string
+	}{
+		"trusted proxy":            {true, "192.0.2.10:443", "", []string{`for=198.51.100.1`}, "198.51.100.1"},
+		"multiple headers":         {true, "192.0.2.10:443", "", []string{`for=203.0.113.5`, `for=192.0.2.11`}, "203.0.113.5"},
+		"ipv6 client":              {true, "192.0.2.10:443", "", []string{`for="[2001:db8::1]:4711"`}, "2001:db8::1"},
+		"obfuscated client":        {true, "192.0.2.10:443", "", []string{`for=_hidden`}, "192.0.2.10"},
+		"spoofed by direct client": {true, "198.51.100.1:443", "", []string{`for=10.0.0.1`}, "198.51.100.1"},
+		"disabled":                 {false, "192.0.2.10:443", "", []string{`for=198.51.100.1`}, "192.0.2.10"},
+		// 信頼するプロキシがクライアントの Forwarded をそのまま通し、X-Forwarded-For に実際の接続元を追記した場合
+		"forged through trusted proxy": {true, "192.0.2.10:443", "203.0.113.5", []string{`for=10.0.0.1`}, "203.0.113.5"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			r := gin.New()
+			r.SetTrustedProxies([]string{"192.0.2.0/24"})
+			r.Use(NewForwardedMiddleware(service.ForwardedConfig{
+				Enabled:        tt.enabled,
+				TrustedProxies: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")},
+			}).Handler())
+			r.GET("/ip", func(c *gin.Context) {
+				c.String(http.StatusOK, c.ClientIP())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			for _, forwarded := range tt.forwarded {
+				req.Header.Add("Forwarded", forwarded)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type PermissionMiddlewareInterface interface {
+	Handler(permission string) gin.HandlerFunc
+}
+
+type PermissionMiddleware struct{}
+
+func NewPermissionMiddleware() PermissionMiddlewareInterface {
+	return &PermissionMiddleware{}
+}
+
+// Auth ミドルウェアの後に置き、検証済みクレームの permissions に必要なパーミッションが含まれるか確認する
+// ロールの変更はトークンの再発行まで反映されないため、クレームのみで判定する
+func (m *PermissionMiddleware) Handler(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := c.Value(AuthClaimsKey).(jwt.MapClaims)
+		if !slices.Contains(stringListClaim(claims["permissions"]), permission) {
+			problemjson.Abort(c, http.StatusForbidden, ErrorCodeInsufficientPermission, "Insufficient permission", gin.H{"permission": permission})
+			return
+		}
+		c.Next()
+	}
+}
+
+// JSON から復元したクレームの配列は []any になる
+func stringListClaim(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
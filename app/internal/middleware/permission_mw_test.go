@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPermissionTestRouter(claims jwt.MapClaims, permission string) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(AuthClaimsKey, claims)
+		}
+		c.Next()
+	})
+	r.Use(NewPermissionMiddleware().Handler(permission))
+	r.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	return r
+}
+
+func TestPermissionMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		claims jwt.MapClaims
+		status int
+	}{
+		"granted":            {jwt.MapClaims{"permissions": []any{"users:write", "users:read"}}, http.StatusOK},
+		"granted string":     {jwt.MapClaims{"permissions": []string{"users:read"}}, http.StatusOK},
+		"other permissions":  {jwt.MapClaims{"permissions": []any{"users:write"}}, http.StatusForbidden},
+		"no permissions":     {jwt.MapClaims{"roles": []any{"users:read"}}, http.StatusForbidden},
+		"no claims":          {nil, http.StatusForbidden},
+		"invalid permission": {jwt.MapClaims{"permissions": "users:read"}, http.StatusForbidden},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			w := httptest.NewRecorder()
+			newPermissionTestRouter(tt.claims, "users:read").ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusForbidden {
+				assert.Equal(t, "users:read", assertProblem(t, w, ErrorCodeInsufficientPermission)["permission"])
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type ScopeMiddlewareInterface interface {
+	Handler(scope string) gin.HandlerFunc
+}
+
+type ScopeMiddleware struct{}
+
+func NewScopeMiddleware() ScopeMiddlewareInterface {
+	return &ScopeMiddleware{}
+}
+
+// Auth ミドルウェアの後に置き、検証済みクレームの scope に必要なスコープが含まれるか確認する
+// 不足している場合は RFC 6750 3.1 の insufficient_scope を返す
+func (m *ScopeMiddleware) Handler(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := c.Value(AuthClaimsKey).(jwt.MapClaims)
+		granted, _ := claims["scope"].(string)
+		if !slices.Contains(strings.Fields(granted), scope) {
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="api", error="insufficient_scope", scope="%s"`, scope))
+			problemjson.Abort(c, http.StatusForbidden, ErrorCodeInsufficientScope, "Insufficient scope", gin.H{"scope": scope})
+			return
+		}
+		c.Next()
+	}
+}
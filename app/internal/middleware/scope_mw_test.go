@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newScopeTestRouter(claims jwt.MapClaims, scope string) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(AuthClaimsKey, claims)
+		}
+		c.Next()
+	})
+	r.Use(NewScopeMiddleware().Handler(scope))
+	r.POST("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	return r
+}
+
+func TestScopeMiddleware(t *testing.T) {
+	tests := map[string]struct {
+		claims jwt.MapClaims
+		status int
+	}{
+		"granted":       {jwt.MapClaims{"scope": "account mfa"}, http.StatusOK},
+		"other scopes":  {jwt.MapClaims{"scope": "mfa accounts"}, http.StatusForbidden},
+		"no scope":      {jwt.MapClaims{}, http.StatusForbidden},
+		"no claims":     {nil, http.StatusForbidden},
+		"invalid scope": {jwt.MapClaims{"scope": []any{"account"}}, http.StatusForbidden},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			w := httptest.NewRecorder()
+			newScopeTestRouter(tt.claims, "account").ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusForbidden {
+				assert.Equal(t, `Bearer realm="api", error="insufficient_scope", scope="account"`, w.Header().Get("WWW-Authenticate"))
+				assert.Equal(t, "account", assertProblem(t, w, ErrorCodeInsufficientScope)["scope"])
+			}
+		})
+	}
+}
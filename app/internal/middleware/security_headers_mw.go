@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ルートグループごとに上書きするヘッダー（空の値は上書きしない）
+type SecurityHeaderOptions struct {
+	// トークンなど、キャッシュさせてはいけないレスポンスを返す場合に true にする（RFC 6749 5.1）
+	NoStore               bool
+	ReferrerPolicy        string
+	ContentSecurityPolicy string
+}
+
+type SecurityHeadersMiddlewareInterface interface {
+	Handler() gin.HandlerFunc
+	Group(opts SecurityHeaderOptions) gin.HandlerFunc
+}
+
+type SecurityHeadersMiddleware struct {
+	config service.SecurityHeadersConfig
+}
+
+func NewSecurityHeadersMiddleware(
+	config service.SecurityHeadersConfig,
+) SecurityHeadersMiddlewareInterface {
+	return &SecurityHeadersMiddleware{
+		config: config,
+	}
+}
+
+// 後続で中断されたレスポンスにも付くよう、グローバルミドルウェアの先頭に置く
+func (m *SecurityHeadersMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hsts := m.config.StrictTransportSecurity(); hsts != "" {
+			c.Header("Strict-Transport-Security", hsts)
+		}
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("X-Frame-Options", "DENY")
+		c.Header("Referrer-Policy", m.config.ReferrerPolicy)
+		c.Header("Content-Security-Policy", m.config.ContentSecurityPolicy)
+		c.Next()
+	}
+}
+
+func (m *SecurityHeadersMiddleware) Group(opts SecurityHeaderOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if opts.NoStore {
+			c.Header("Cache-Control", "no-store")
+			c.Header("Pragma", "no-cache")
+		}
+		if opts.ReferrerPolicy != "" {
+			c.Header("Referrer-Policy", opts.ReferrerPolicy)
+		}
+		if opts.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", opts.ContentSecurityPolicy)
+		}
+		c.Next()
+	}
+}
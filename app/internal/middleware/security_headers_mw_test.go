@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var testSecurityHeadersConfig = service.SecurityHeadersConfig{
+	HstsMaxAge:            31536000,
+	HstsIncludeSubdomains: true,
+	ReferrerPolicy:        "no-referrer",
+	ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+}
+
+func newSecurityHeadersTestRouter() *gin.Engine {
+	securityHeaders := NewSecurityHeadersMiddleware(testSecurityHeadersConfig)
+
+	r := gin.New()
+	r.Use(securityHeaders.Handler())
+	r.GET("/healthcheck", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	authGroup := r.Group("/auth", securityHeaders.Group(SecurityHeaderOptions{NoStore: true}))
+	authGroup.POST("/login", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"access_token": "token"})
+	})
+
+	pageGroup := r.Group("/oauth", securityHeaders.Group(SecurityHeaderOptions{
+		ReferrerPolicy:        "same-origin",
+		ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'",
+	}))
+	pageGroup.GET("/consent", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte("<html></html>"))
+	})
+	return r
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/healthcheck", nil)
+	w := httptest.NewRecorder()
+	newSecurityHeadersTestRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, "max-age=31536000; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+}
+
+func TestSecurityHeadersMiddlewareNoStore(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+	w := httptest.NewRecorder()
+	newSecurityHeadersTestRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "no-cache", w.Header().Get("Pragma"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+}
+
+func TestSecurityHeadersMiddlewareGroupOverride(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/oauth/consent", nil)
+	w := httptest.NewRecorder()
+	newSecurityHeadersTestRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, "same-origin", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "default-src 'self'; frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+}
+
+func TestSecurityHeadersMiddlewareWithoutHsts(t *testing.T) {
+	config := testSecurityHeadersConfig
+	config.HstsMaxAge = 0
+
+	r := gin.New()
+	r.Use(NewSecurityHeadersMiddleware(config).Handler())
+	r.GET("/healthcheck", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/healthcheck", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"))
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/problemjson"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ステップアップ認証を要求した際のエラーコード（RFC 9470）
+const InsufficientUserAuthentication = "insufficient_user_authentication"
+
+// 重要な操作で再認証を求めるまでの既定の猶予
+const DefaultStepUpMaxAge = 5 * time.Minute
+
+type StepUpOptions struct {
+	// ログインからこの時間を過ぎていたら再認証を求める（0 の場合は確認しない）
+	MaxAge time.Duration
+	// true の場合は 2 要素以上での認証（acr=aal2）を求める
+	RequireMfa bool
+}
+
+type StepUpMiddlewareInterface interface {
+	Handler(opts StepUpOptions) gin.HandlerFunc
+}
+
+type StepUpMiddleware struct {
+	clock atylabclock.ClockInterface
+}
+
+func NewStepUpMiddleware(
+	clock atylabclock.ClockInterface,
+) StepUpMiddlewareInterface {
+	return &StepUpMiddleware{
+		clock: clock,
+	}
+}
+
+// Auth ミドルウェアの後に置き、検証済みクレームの auth_time / acr を確認する
+func (m *StepUpMiddleware) Handler(opts StepUpOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := c.Value(AuthClaimsKey).(jwt.MapClaims)
+
+		if opts.RequireMfa {
+			if acr, _ := claims["acr"].(string); acr != service.AcrAal2 {
+				m.challenge(c, opts, "multi-factor authentication is required")
+				return
+			}
+		}
+
+		if opts.MaxAge > 0 {
+			authTime, ok := unixClaim(claims["auth_time"])
+			if !ok || m.clock.Now().Sub(authTime) > opts.MaxAge {
+				m.challenge(c, opts, "more recent authentication is required")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// クライアントが必要な条件を付けて再認証できるよう、満たすべき max_age と acr_values を返す
+func (m *StepUpMiddleware) challenge(c *gin.Context, opts StepUpOptions, description string) {
+	params := []string{
+		fmt.Sprintf(`error="%s"`, InsufficientUserAuthentication),
+		fmt.Sprintf(`error_description="%s"`, description),
+	}
+	extensions := gin.H{}
+	if opts.MaxAge > 0 {
+		maxAge := int64(opts.MaxAge / time.Second)
+		params = append(params, fmt.Sprintf(`max_age="%d"`, maxAge))
+		extensions["max_age"] = maxAge
+	}
+	if opts.RequireMfa {
+		params = append(params, fmt.Sprintf(`acr_values="%s"`, service.AcrAal2))
+		extensions["acr_values"] = service.AcrAal2
+	}
+
+	c.Header("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	problemjson.Abort(c, http.StatusUnauthorized, InsufficientUserAuthentication, "Insufficient user authentication", extensions)
+}
+
+// JSON からパースしたクレームは float64 になる
+func unixClaim(v any) (time.Time, bool) {
+	switch n := v.(type) {
+	case float64:
+		return time.Unix(int64(n), 0), true
+	case int64:
+		return time.Unix(n, 0), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var testStepUpNow = time.Now()
+
+func newStepUpTestRouter(claims jwt.MapClaims, opts StepUpOptions) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if claims != nil {
+			c.Set(AuthClaimsKey, claims)
+		}
+		c.Next()
+	})
+	r.Use(NewStepUpMiddleware(atylabclock.NewClockMock(testStepUpNow)).Handler(opts))
+	r.POST("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+	return r
+}
+
+func TestStepUpMiddlewareSuccess(t *testing.T) {
+	tests := map[string]struct {
+		claims jwt.MapClaims
+		opts   StepUpOptions
+	}{
+		"recent login": {
+			jwt.MapClaims{"auth_time": float64(testStepUpNow.Add(-time.Minute).Unix()), "acr": "aal1"},
+			StepUpOptions{MaxAge: DefaultStepUpMaxAge},
+		},
+		"recent mfa login": {
+			jwt.MapClaims{"auth_time": float64(testStepUpNow.Unix()), "acr": "aal2"},
+			StepUpOptions{MaxAge: DefaultStepUpMaxAge, RequireMfa: true},
+		},
+		"mfa only": {
+			jwt.MapClaims{"acr": "aal2"},
+			StepUpOptions{RequireMfa: true},
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			w := httptest.NewRecorder()
+			newStepUpTestRouter(tt.claims, tt.opts).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
+
+func TestStepUpMiddlewareChallenge(t *testing.T) {
+	tests := map[string]struct {
+		claims     jwt.MapClaims
+		opts       StepUpOptions
+		authHeader string
+	}{
+		"stale login": {
+			jwt.MapClaims{"auth_time": float64(testStepUpNow.Add(-10 * time.Minute).Unix()), "acr": "aal2"},
+			StepUpOptions{MaxAge: DefaultStepUpMaxAge},
+			`max_age="300"`,
+		},
+		"no auth_time": {
+			jwt.MapClaims{"acr": "aal2"},
+			StepUpOptions{MaxAge: DefaultStepUpMaxAge},
+			`max_age="300"`,
+		},
+		"single factor": {
+			jwt.MapClaims{"auth_time": float64(testStepUpNow.Unix()), "acr": "aal1"},
+			StepUpOptions{MaxAge: DefaultStepUpMaxAge, RequireMfa: true},
+			`acr_values="aal2"`,
+		},
+		"no claims": {
+			nil,
+			StepUpOptions{RequireMfa: true},
+			`acr_values="aal2"`,
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			w := httptest.NewRecorder()
+			newStepUpTestRouter(tt.claims, tt.opts).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assertProblem(t, w, InsufficientUserAuthentication)
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), `error="insufficient_user_authentication"`)
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), tt.authHeader)
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// 使い捨てモードで発行した CSRF トークン
+// 一度検証に使ったトークンは再利用できない
+type CsrfToken struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	TokenHash string     `gorm:"type:char(64);uniqueIndex;not null"`
+	SessionID string     `gorm:"type:char(36)"`
+	ExpiresAt time.Time  `gorm:"type:datetime;index;not null"`
+	UsedAt    *time.Time `gorm:"type:datetime"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
+
+func HashCsrfToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,14 @@
+package models
+
+import "testing"
+
+func TestHashCsrfToken(t *testing.T) {
+	hash := HashCsrfToken("token")
+
+	if len(hash) != 64 {
+		t.Errorf("expected 64 chars hash, got %d", len(hash))
+	}
+	if hash != HashCsrfToken("token") || hash == HashCsrfToken("other") {
+		t.Error("expected deterministic hash")
+	}
+}
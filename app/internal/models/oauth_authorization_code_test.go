@@ -0,0 +1,65 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreateOauthAuthorizationCode(t *testing.T) {
+	code := CreateOauthAuthorizationCode()
+
+	if len(code) != 43 {
+		t.Errorf("expected 43 chars code, got %d", len(code))
+	}
+	if code == CreateOauthAuthorizationCode() {
+		t.Error("expected unique codes")
+	}
+	if len(HashOauthAuthorizationCode(code)) != 64 {
+		t.Error("expected sha256 hex hash")
+	}
+}
+
+func TestCreateOauthCodeChallenge(t *testing.T) {
+	// RFC 7636 Appendix B
+	challenge := CreateOauthCodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if challenge != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("unexpected challenge: %s", challenge)
+	}
+}
+
+func TestOauthAuthorizationCodeVerifyCodeVerifier(t *testing.T) {
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	code := &OauthAuthorizationCode{
+		CodeChallenge:       CreateOauthCodeChallenge(verifier),
+		CodeChallengeMethod: OauthCodeChallengeMethodS256,
+	}
+
+	if !code.VerifyCodeVerifier(verifier) {
+		t.Error("expected verifier to match")
+	}
+
+	tests := map[string]string{
+		"mismatch":      strings.Repeat("a", 43),
+		"too short":     verifier[:42],
+		"too long":      strings.Repeat("a", 129),
+		"invalid chars": verifier[:42] + "+",
+		"empty":         "",
+	}
+	for title, v := range tests {
+		if code.VerifyCodeVerifier(v) {
+			t.Errorf("%s: expected verifier not to match", title)
+		}
+	}
+
+	plain := &OauthAuthorizationCode{CodeChallenge: verifier, CodeChallengeMethod: "plain"}
+	if plain.VerifyCodeVerifier(verifier) {
+		t.Error("expected plain method to be rejected")
+	}
+}
+
+func TestOauthAuthorizationCodeAmrList(t *testing.T) {
+	code := &OauthAuthorizationCode{Amr: "pwd otp mfa"}
+	if got := code.AmrList(); len(got) != 3 || got[0] != "pwd" || got[2] != "mfa" {
+		t.Errorf("unexpected amr: %v", got)
+	}
+}
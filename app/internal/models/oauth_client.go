@@ -0,0 +1,139 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// クライアント認証の方式（RFC 7591 token_endpoint_auth_method）
+const (
+	// client_secret を保持できない公開クライアント（SPA・ネイティブアプリ）
+	OauthClientAuthMethodNone              = "none"
+	OauthClientAuthMethodClientSecretBasic = "client_secret_basic"
+	OauthClientAuthMethodClientSecretPost  = "client_secret_post"
+	OauthClientAuthMethodPrivateKeyJwt     = "private_key_jwt"
+)
+
+// 認可サーバーを利用するクライアントアプリケーション
+// リダイレクト URI・グラント・スコープはスペース区切りで保存する
+type OauthClient struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement"`
+	ClientID string `gorm:"type:varchar(64);uniqueIndex;not null"`
+	// client_secret は発行時にのみ返し、ハッシュのみを保持する
+	ClientSecretHash        string `gorm:"type:char(64);not null;default:''"`
+	Name                    string `gorm:"type:varchar(255);not null"`
+	TokenEndpointAuthMethod string `gorm:"type:varchar(32);not null"`
+	// private_key_jwt で署名を検証する公開鍵（PEM）
+	PublicKey    string `gorm:"type:text"`
+	RedirectURIs string `gorm:"type:text"`
+	GrantTypes   string `gorm:"type:varchar(255);not null;default:''"`
+	Scopes       string `gorm:"type:varchar(1024);not null;default:''"`
+	// アクセストークンの aud に入れる受け手（リソースサーバー）の識別子
+	Audiences string `gorm:"type:varchar(1024);not null;default:''"`
+	// トークン交換（RFC 8693）で受け付ける subject_token（成り代わりでは actor_token）の aud
+	// aud にクライアント ID を含むトークンは登録しなくても受け付ける
+	TokenExchangeSubjectAudiences string `gorm:"type:varchar(1024);not null;default:''"`
+	// トークン交換で要求できる audience。クライアント自身は登録しなくても要求できる
+	TokenExchangeAudiences string `gorm:"type:varchar(1024);not null;default:''"`
+	// トークンの有効期間（秒）。0 の場合は既定値を使う
+	AccessTokenLifetime  int `gorm:"not null;default:0"`
+	RefreshTokenLifetime int `gorm:"not null;default:0"`
+	// 自社のアプリケーション。同意画面の省略等に使う
+	FirstParty bool      `gorm:"not null;default:false"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+func (c *OauthClient) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+func (c *OauthClient) GrantTypeList() []string {
+	return strings.Fields(c.GrantTypes)
+}
+
+func (c *OauthClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+func (c *OauthClient) AudienceList() []string {
+	return strings.Fields(c.Audiences)
+}
+
+func (c *OauthClient) TokenExchangeSubjectAudienceList() []string {
+	return strings.Fields(c.TokenExchangeSubjectAudiences)
+}
+
+func (c *OauthClient) TokenExchangeAudienceList() []string {
+	return strings.Fields(c.TokenExchangeAudiences)
+}
+
+func (c *OauthClient) AllowsGrantType(grantType string) bool {
+	return slices.Contains(c.GrantTypeList(), grantType)
+}
+
+// 登録済みの URI と完全一致のみ許可する（RFC 6749 3.1.2.3）
+func (c *OauthClient) AllowsRedirectURI(redirectURI string) bool {
+	return slices.Contains(c.RedirectURIList(), redirectURI)
+}
+
+func (c *OauthClient) AllowsScopes(scopes []string) bool {
+	allowed := c.ScopeList()
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// トークン交換に使うトークンの aud に、クライアント自身か交換を許可した受け手が含まれるか
+func (c *OauthClient) AcceptsTokenExchangeSubject(audience []string) bool {
+	allowed := append(c.TokenExchangeSubjectAudienceList(), c.ClientID)
+	for _, aud := range audience {
+		if slices.Contains(allowed, aud) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *OauthClient) AllowsTokenExchangeAudience(audience string) bool {
+	return audience == c.ClientID || slices.Contains(c.TokenExchangeAudienceList(), audience)
+}
+
+// client_secret を保持できない公開クライアント
+func (c *OauthClient) IsPublic() bool {
+	return c.TokenEndpointAuthMethod == OauthClientAuthMethodNone
+}
+
+// client_secret で認証するクライアント
+func (c *OauthClient) UsesClientSecret() bool {
+	return c.TokenEndpointAuthMethod == OauthClientAuthMethodClientSecretBasic ||
+		c.TokenEndpointAuthMethod == OauthClientAuthMethodClientSecretPost
+}
+
+func (c *OauthClient) VerifyClientSecret(secret string) bool {
+	if c.ClientSecretHash == "" || secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(HashOauthClientSecret(secret)), []byte(c.ClientSecretHash)) == 1
+}
+
+func CreateOauthClientSecret() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// client_secret は十分な長さの乱数のため、パスワードのような低速なハッシュは使わない
+func HashOauthClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// private_key_jwt で受け付けたクライアントアサーションの jti
+// 有効期限までは同じ jti のアサーションを再利用できないようにする（RFC 7523 3）
+type OauthClientAssertion struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	ClientID  string    `gorm:"type:varchar(64);uniqueIndex:idx_oauth_client_assertions_client_id_jti;not null"`
+	Jti       string    `gorm:"type:varchar(255);uniqueIndex:idx_oauth_client_assertions_client_id_jti;not null"`
+	ExpiresAt time.Time `gorm:"type:datetime;index;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
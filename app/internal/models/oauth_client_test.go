@@ -0,0 +1,81 @@
+package models
+
+import "testing"
+
+func TestOauthClientLists(t *testing.T) {
+	client := &OauthClient{
+		RedirectURIs: "https://a.example.com/cb https://b.example.com/cb?tenant=1",
+		GrantTypes:   "authorization_code refresh_token",
+		Scopes:       "openid profile",
+		Audiences:    "https://api.example.com",
+	}
+
+	if !client.AllowsRedirectURI("https://b.example.com/cb?tenant=1") || client.AllowsRedirectURI("https://b.example.com/cb") {
+		t.Error("expected exact redirect uri match")
+	}
+	if !client.AllowsGrantType("refresh_token") || client.AllowsGrantType("client_credentials") {
+		t.Error("unexpected grant type result")
+	}
+	if !client.AllowsScopes([]string{"openid"}) || !client.AllowsScopes(nil) || client.AllowsScopes([]string{"openid", "email"}) {
+		t.Error("unexpected scope result")
+	}
+	if len(client.RedirectURIList()) != 2 || len(client.GrantTypeList()) != 2 || len(client.ScopeList()) != 2 || len(client.AudienceList()) != 1 {
+		t.Error("unexpected list length")
+	}
+}
+
+func TestOauthClientTokenExchangeAudiences(t *testing.T) {
+	client := &OauthClient{
+		ClientID:                      "exchange-client",
+		TokenExchangeSubjectAudiences: "https://api.example.com",
+		TokenExchangeAudiences:        "orders-client",
+	}
+
+	if !client.AcceptsTokenExchangeSubject([]string{"other", "exchange-client"}) || !client.AcceptsTokenExchangeSubject([]string{"https://api.example.com"}) {
+		t.Error("expected subject audience to be accepted")
+	}
+	if client.AcceptsTokenExchangeSubject([]string{"orders-client"}) || client.AcceptsTokenExchangeSubject(nil) {
+		t.Error("expected subject audience to be rejected")
+	}
+	if !client.AllowsTokenExchangeAudience("exchange-client") || !client.AllowsTokenExchangeAudience("orders-client") || client.AllowsTokenExchangeAudience("https://api.example.com") {
+		t.Error("unexpected requested audience result")
+	}
+}
+
+func TestOauthClientUsesClientSecret(t *testing.T) {
+	tests := map[string]bool{
+		OauthClientAuthMethodNone:              false,
+		OauthClientAuthMethodClientSecretBasic: true,
+		OauthClientAuthMethodClientSecretPost:  true,
+		OauthClientAuthMethodPrivateKeyJwt:     false,
+	}
+	for method, expected := range tests {
+		client := &OauthClient{TokenEndpointAuthMethod: method}
+		if client.UsesClientSecret() != expected {
+			t.Errorf("%s: expected %v", method, expected)
+		}
+		if client.IsPublic() != (method == OauthClientAuthMethodNone) {
+			t.Errorf("%s: unexpected IsPublic", method)
+		}
+	}
+}
+
+func TestOauthClientVerifyClientSecret(t *testing.T) {
+	secret := CreateOauthClientSecret()
+	if len(secret) != 43 || secret == CreateOauthClientSecret() {
+		t.Fatalf("unexpected secret: %s", secret)
+	}
+
+	client := &OauthClient{ClientSecretHash: HashOauthClientSecret(secret)}
+	if !client.VerifyClientSecret(secret) {
+		t.Error("expected secret to match")
+	}
+	if client.VerifyClientSecret("wrong") || client.VerifyClientSecret("") {
+		t.Error("expected secret not to match")
+	}
+
+	// secret を持たないクライアントは空文字でも一致しない
+	if (&OauthClient{}).VerifyClientSecret("") {
+		t.Error("expected client without secret to be rejected")
+	}
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// デバイス認可リクエストの状態
+const (
+	OauthDeviceCodeStatusPending  = "pending"
+	OauthDeviceCodeStatusApproved = "approved"
+	OauthDeviceCodeStatusDenied   = "denied"
+)
+
+// ユーザーコードに使う文字。母音を除き、単語や読み間違えやすい文字の組み合わせにならないようにする（RFC 8628 6.1）
+const oauthUserCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+
+// ユーザーコードの文字数。20 文字種 × 8 文字で約 34 ビット
+const oauthUserCodeLength = 8
+
+// デバイス認可エンドポイントで発行したデバイスコードとユーザーコード（RFC 8628 3.2）
+// いずれも平文は保存せず、ハッシュのみを保持する
+type OauthDeviceCode struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement"`
+	DeviceCodeHash string `gorm:"type:char(64);uniqueIndex;not null"`
+	UserCodeHash   string `gorm:"type:char(64);uniqueIndex;not null"`
+	ClientID       string `gorm:"type:varchar(255);not null"`
+	Scope          string `gorm:"type:varchar(1024);not null;default:''"`
+	Status         string `gorm:"type:varchar(16);not null;default:'pending'"`
+	// 承認・拒否したユーザーとそのセッションの認証時刻と方式（トークンに引き継ぐ）
+	UserID   *uint      `gorm:"index"`
+	AuthTime *time.Time `gorm:"type:datetime"`
+	Amr      string     `gorm:"type:varchar(64);not null;default:''"`
+	// トークンエンドポイントへのポーリング間隔（秒）。slow_down を返すたびに延ばす
+	PollInterval int        `gorm:"not null"`
+	LastPolledAt *time.Time `gorm:"type:datetime"`
+	ExpiresAt    time.Time  `gorm:"type:datetime;not null"`
+	UsedAt       *time.Time `gorm:"type:datetime"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime"`
+}
+
+// amr はスペース区切りで保存している
+func (c *OauthDeviceCode) AmrList() []string {
+	return strings.Fields(c.Amr)
+}
+
+func CreateOauthDeviceCode() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// 入力しやすいよう 4 文字ずつハイフンで区切る（例: WDJB-MJHT）
+func CreateOauthUserCode() string {
+	code := make([]byte, oauthUserCodeLength)
+	max := big.NewInt(int64(len(oauthUserCodeCharset)))
+	for i := range code {
+		n, _ := rand.Int(rand.Reader, max)
+		code[i] = oauthUserCodeCharset[n.Int64()]
+	}
+	return string(code[:oauthUserCodeLength/2]) + "-" + string(code[oauthUserCodeLength/2:])
+}
+
+// ユーザーが入力したコードは大文字・小文字とハイフン・空白を区別しない（RFC 8628 6.1）
+func NormalizeOauthUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(userCode))
+}
+
+func HashOauthDeviceCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func HashOauthUserCode(userCode string) string {
+	sum := sha256.Sum256([]byte(NormalizeOauthUserCode(userCode)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCreateOauthDeviceCode(t *testing.T) {
+	code := CreateOauthDeviceCode()
+
+	if len(code) != 43 {
+		t.Errorf("expected 43 chars code, got %d", len(code))
+	}
+	if code == CreateOauthDeviceCode() {
+		t.Error("expected unique codes")
+	}
+	if len(HashOauthDeviceCode(code)) != 64 {
+		t.Error("expected sha256 hex hash")
+	}
+}
+
+func TestCreateOauthUserCode(t *testing.T) {
+	userCode := CreateOauthUserCode()
+
+	if !regexp.MustCompile(`^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$`).MatchString(userCode) {
+		t.Errorf("unexpected user code: %s", userCode)
+	}
+}
+
+func TestHashOauthUserCode(t *testing.T) {
+	expected := HashOauthUserCode("WDJB-MJHT")
+
+	for _, input := range []string{"WDJBMJHT", "wdjb-mjht", " wdjb mjht "} {
+		if HashOauthUserCode(input) != expected {
+			t.Errorf("%q: expected the same hash as WDJB-MJHT", input)
+		}
+	}
+	if HashOauthUserCode("WDJB-MJHV") == expected {
+		t.Error("expected different hash")
+	}
+}
+
+func TestOauthDeviceCodeAmrList(t *testing.T) {
+	code := &OauthDeviceCode{Amr: "pwd otp"}
+
+	if amr := code.AmrList(); len(amr) != 2 || amr[0] != "pwd" || amr[1] != "otp" {
+		t.Errorf("unexpected amr: %v", amr)
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// トークン交換（RFC 8693）で発行したトークンの監査記録
+// 誰が（actor）誰として（subject）どのクライアント経由でトークンを得たかを残す
+// ユーザーの削除後も監査のために残す
+type OauthTokenExchange struct {
+	ID uint `gorm:"primaryKey;autoIncrement"`
+	// 発行したアクセストークンの jti
+	Jti           string `gorm:"type:char(36);uniqueIndex;not null"`
+	ClientID      string `gorm:"type:varchar(64);not null"`
+	SubjectUserID uint   `gorm:"index;not null"`
+	// act クレームの sub（ユーザーは "user" + UUID、クライアントは client_id）
+	Actor string `gorm:"type:varchar(255);index;not null"`
+	// サポート担当者による成り代わりの場合 true
+	Impersonation bool      `gorm:"not null;default:false"`
+	Audience      string    `gorm:"type:varchar(1024);not null;default:''"`
+	Scope         string    `gorm:"type:varchar(1024);not null;default:''"`
+	IpAddress     string    `gorm:"type:varchar(45);not null;default:''"`
+	ExpiresAt     time.Time `gorm:"type:datetime;not null"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
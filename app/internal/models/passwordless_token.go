@@ -0,0 +1,61 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	PasswordlessMethodLink = "link"
+	PasswordlessMethodCode = "code"
+)
+
+// パスワードレスログインで発行したマジックリンク・ワンタイムコード
+// いずれも平文は保存せず、ハッシュのみを保持する
+type PasswordlessToken struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `gorm:"index;not null"`
+	Method    string     `gorm:"type:varchar(16);not null"`
+	TokenHash string     `gorm:"type:char(64);index;not null"`
+	Attempts  int        `gorm:"not null;default:0"`
+	ExpiresAt time.Time  `gorm:"type:datetime;not null"`
+	UsedAt    *time.Time `gorm:"type:datetime"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (t *PasswordlessToken) IsActive(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// 000000〜999999 を一様に発行する
+func CreatePasswordlessCode() string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
+	return fmt.Sprintf("%06d", n.Int64())
+}
+
+// マジックリンクに埋め込むランダム値
+func CreatePasswordlessNonce() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func HashPasswordlessNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// 6 桁のコードは総当たりが容易なため、サーバー側の鍵で HMAC を取って保存する
+// ユーザー ID を含めることで、同じコードでもユーザーごとに異なるハッシュになる
+func HashPasswordlessCode(userId uint, code string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%s", userId, code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
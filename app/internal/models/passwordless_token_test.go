@@ -0,0 +1,69 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCreatePasswordlessCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code := CreatePasswordlessCode()
+		if !regexp.MustCompile(`^[0-9]{6}$`).MatchString(code) {
+			t.Fatalf("expected 6 digits code, got %s", code)
+		}
+	}
+}
+
+func TestCreatePasswordlessNonce(t *testing.T) {
+	nonce := CreatePasswordlessNonce()
+
+	if len(nonce) != 43 {
+		t.Errorf("expected 43 chars nonce, got %d", len(nonce))
+	}
+	if nonce == CreatePasswordlessNonce() {
+		t.Error("expected unique nonces")
+	}
+	if len(HashPasswordlessNonce(nonce)) != 64 {
+		t.Error("expected sha256 hex hash")
+	}
+}
+
+func TestHashPasswordlessCode(t *testing.T) {
+	key := []byte("secret")
+	hash := HashPasswordlessCode(1, "123456", key)
+
+	if len(hash) != 64 {
+		t.Errorf("expected 64 chars hash, got %d", len(hash))
+	}
+	if hash != HashPasswordlessCode(1, "123456", key) {
+		t.Error("expected deterministic hash")
+	}
+	if hash == HashPasswordlessCode(2, "123456", key) {
+		t.Error("expected hash to depend on user")
+	}
+	if hash == HashPasswordlessCode(1, "123456", []byte("other")) {
+		t.Error("expected hash to depend on key")
+	}
+}
+
+func TestPasswordlessTokenIsActive(t *testing.T) {
+	now := time.Now()
+	used := now.Add(-time.Minute)
+
+	tests := []struct {
+		title    string
+		token    PasswordlessToken
+		expected bool
+	}{
+		{"active", PasswordlessToken{ExpiresAt: now.Add(time.Minute)}, true},
+		{"expired", PasswordlessToken{ExpiresAt: now.Add(-time.Second)}, false},
+		{"used", PasswordlessToken{ExpiresAt: now.Add(time.Minute), UsedAt: &used}, false},
+	}
+
+	for _, tt := range tests {
+		if tt.token.IsActive(now) != tt.expected {
+			t.Errorf("%s: expected %v", tt.title, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// パーミッションをまとめたロール。ユーザーに割り当てると、アクセストークンの roles / permissions クレームになる
+type Role struct {
+	ID          uint         `gorm:"primaryKey;autoIncrement"`
+	Name        string       `gorm:"type:varchar(100);uniqueIndex;not null"`
+	Description string       `gorm:"type:varchar(255);not null;default:''"`
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+	CreatedAt   time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time    `gorm:"autoUpdateTime"`
+}
+
+func (r *Role) PermissionNames() []string {
+	names := make([]string, 0, len(r.Permissions))
+	for _, permission := range r.Permissions {
+		names = append(names, permission.Name)
+	}
+	return names
+}
+
+// "users:read" のように、リソースと操作をコロンで区切った名前
+type Permission struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"type:varchar(100);uniqueIndex;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+type RolePermission struct {
+	RoleID       uint `gorm:"primaryKey"`
+	PermissionID uint `gorm:"primaryKey;index"`
+}
+
+type UserRole struct {
+	UserID    uint      `gorm:"primaryKey"`
+	RoleID    uint      `gorm:"primaryKey;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
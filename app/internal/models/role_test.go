@@ -0,0 +1,16 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRolePermissionNames(t *testing.T) {
+	role := &Role{Permissions: []Permission{{Name: "users:read"}, {Name: "users:write"}}}
+	if names := role.PermissionNames(); !reflect.DeepEqual(names, []string{"users:read", "users:write"}) {
+		t.Errorf("unexpected permission names: %v", names)
+	}
+	if names := (&Role{}).PermissionNames(); len(names) != 0 {
+		t.Errorf("expected no permission names, got %v", names)
+	}
+}
@@ -8,10 +8,12 @@ import (
 )
 
 type User struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement"`
-	UUID         string    `gorm:"type:char(36);uniqueIndex;not null"`
-	Username     string    `gorm:"type:varchar(255);uniqueIndex;not null"`
-	Email        string    `gorm:"type:varchar(255);uniqueIndex;not null"`
+	ID       uint   `gorm:"primaryKey;autoIncrement"`
+	UUID     string `gorm:"type:char(36);uniqueIndex;not null"`
+	Username string `gorm:"type:varchar(255);uniqueIndex;not null"`
+	Email    string `gorm:"type:varchar(255);uniqueIndex;not null"`
+	// メールと、Accept-Language のないリクエストのエラーメッセージの言語（i18n.LocaleJa / i18n.LocaleEn）。空の場合は Accept-Language に従う
+	Locale       string    `gorm:"type:varchar(8);not null;default:''"`
 	PasswordHash string    `gorm:"type:varchar(255);not null"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
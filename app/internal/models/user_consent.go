@@ -0,0 +1,44 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ユーザーがサードパーティのクライアントに許可したスコープ
+// 許可済みのスコープの範囲内であれば、次回以降の認可リクエストで同意画面を省略する
+type UserConsent struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement"`
+	UserID   uint   `gorm:"uniqueIndex:idx_user_consents_user_id_client_id;not null"`
+	ClientID string `gorm:"type:varchar(255);uniqueIndex:idx_user_consents_user_id_client_id;index;not null"`
+	// 許可したスコープ（スペース区切り）。追加で許可した場合は和集合で保存する
+	Scope     string    `gorm:"type:varchar(1024);not null;default:''"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (c *UserConsent) ScopeList() []string {
+	return strings.Fields(c.Scope)
+}
+
+// 要求されたスコープをすべて許可済みか
+func (c *UserConsent) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.ScopeList(), scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// 許可済みのスコープに追加で許可したスコープを加える
+func (c *UserConsent) Merge(scopes []string) {
+	merged := c.ScopeList()
+	for _, scope := range scopes {
+		if !slices.Contains(merged, scope) {
+			merged = append(merged, scope)
+		}
+	}
+	c.Scope = strings.Join(merged, " ")
+}
@@ -0,0 +1,36 @@
+package models
+
+import "testing"
+
+func TestUserConsentCovers(t *testing.T) {
+	consent := &UserConsent{Scope: "openid profile"}
+
+	tests := map[string]struct {
+		scopes   []string
+		expected bool
+	}{
+		"empty":   {[]string{}, true},
+		"subset":  {[]string{"profile"}, true},
+		"equal":   {[]string{"openid", "profile"}, true},
+		"missing": {[]string{"openid", "email"}, false},
+	}
+	for title, tt := range tests {
+		if got := consent.Covers(tt.scopes); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", title, tt.expected, got)
+		}
+	}
+}
+
+func TestUserConsentMerge(t *testing.T) {
+	consent := &UserConsent{Scope: "openid profile"}
+	consent.Merge([]string{"profile", "email"})
+	if consent.Scope != "openid profile email" {
+		t.Errorf("unexpected scope: %s", consent.Scope)
+	}
+
+	empty := &UserConsent{}
+	empty.Merge([]string{"openid"})
+	if empty.Scope != "openid" {
+		t.Errorf("unexpected scope: %s", empty.Scope)
+	}
+}
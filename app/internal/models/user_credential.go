@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// WebAuthn で登録されたクレデンシャル（パスキー）
+type UserCredential struct {
+	ID                uint       `gorm:"primaryKey;autoIncrement"`
+	UserID            uint       `gorm:"index;not null"`
+	CredentialID      string     `gorm:"type:varchar(255);uniqueIndex;not null"`
+	PublicKey         []byte     `gorm:"type:blob;not null"`
+	Algorithm         int64      `gorm:"not null"`
+	SignCount         uint32     `gorm:"default:0"`
+	BackupEligible    bool       `gorm:"default:false"`
+	AttestationFormat string     `gorm:"type:varchar(32);not null"`
+	AAGUID            string     `gorm:"column:aaguid;type:char(32);not null"`
+	Name              string     `gorm:"type:varchar(255)"`
+	LastUsedAt        *time.Time `gorm:"type:datetime"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt         time.Time  `gorm:"autoUpdateTime"`
+}
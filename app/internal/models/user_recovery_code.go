@@ -0,0 +1,34 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+type UserRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `gorm:"index;not null"`
+	CodeHash  string     `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time `gorm:"type:datetime"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
+
+// 入力しやすいよう xxxxx-xxxxx 形式の小文字英数字で発行する
+func CreateRecoveryCode() string {
+	bytes := make([]byte, 10)
+	rand.Read(bytes)
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(bytes))[:10]
+	return code[:5] + "-" + code[5:]
+}
+
+// リカバリーコードは十分なエントロピーがあるため SHA-256 で保存する
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
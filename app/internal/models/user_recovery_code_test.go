@@ -0,0 +1,32 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCreateRecoveryCode(t *testing.T) {
+	code := CreateRecoveryCode()
+	code2 := CreateRecoveryCode()
+
+	if code == code2 {
+		t.Error("Expected different codes, got the same")
+	}
+	if !regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`).MatchString(code) {
+		t.Errorf("Unexpected recovery code format: %s", code)
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	hash := HashRecoveryCode("abcde-fghij")
+
+	if len(hash) != 64 {
+		t.Errorf("Expected hash length of 64, got %d", len(hash))
+	}
+	if hash != HashRecoveryCode(" ABCDEFGHIJ ") {
+		t.Error("Expected hash to ignore case, hyphen and spaces")
+	}
+	if hash == HashRecoveryCode("abcde-fghik") {
+		t.Error("Expected different hashes for different codes")
+	}
+}
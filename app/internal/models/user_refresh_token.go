@@ -3,18 +3,36 @@ package models
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"strings"
 	"time"
 )
 
 type UserRefreshToken struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement"`
-	UserID       uint      `gorm:"not null"`
+	ID     uint `gorm:"primaryKey;autoIncrement"`
+	UserID uint `gorm:"not null"`
+	// ログイン時に採番し、リフレッシュ後も引き継ぐセッションの識別子
+	FamilyID string `gorm:"type:char(36);index"`
+	// OAuth クライアントに発行した場合のクライアント ID（自サービスのログインでは空）
+	ClientID string `gorm:"type:varchar(64);not null;default:''"`
+	// クライアントに許可したスコープ（リフレッシュしても引き継ぐ）
+	Scope        string    `gorm:"type:varchar(1024);not null;default:''"`
 	RefreshToken string    `gorm:"type:varchar(512);uniqueIndex;not null"`
 	ExpiresAt    time.Time `gorm:"type:datetime;not null"`
 	IsUsed       bool      `gorm:"default:false"`
 	UseIP        string    `gorm:"type:varchar(45)"`
-	CreatedAt    time.Time `gorm:"autoCreateTime"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime"`
+	// 最初にログインした時刻と認証方式（リフレッシュしても引き継ぐ）
+	AuthTime  *time.Time `gorm:"type:datetime"`
+	Amr       string     `gorm:"type:varchar(64)"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime"`
+}
+
+// クライアントで有効期間を指定しない場合のリフレッシュトークンの有効期間
+const DefaultRefreshTokenLifetime = 24 * time.Hour * 30
+
+// amr はスペース区切りで保存している
+func (t *UserRefreshToken) AmrList() []string {
+	return strings.Fields(t.Amr)
 }
 
 func CreateRefreshToken() string {
@@ -20,3 +20,15 @@ func TestCreateRefreshToken(t *testing.T) {
 		t.Error("Expected a valid token, got an empty string")
 	}
 }
+
+func TestUserRefreshTokenAmrList(t *testing.T) {
+	token := UserRefreshToken{Amr: "pwd otp mfa"}
+	amr := token.AmrList()
+	if len(amr) != 3 || amr[0] != "pwd" || amr[2] != "mfa" {
+		t.Errorf("unexpected amr: %v", amr)
+	}
+
+	if len((&UserRefreshToken{}).AmrList()) != 0 {
+		t.Error("expected empty amr")
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type UserTotp struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement"`
+	UserID       uint       `gorm:"uniqueIndex;not null"`
+	Secret       string     `gorm:"type:varchar(64);not null"`
+	Enabled      bool       `gorm:"default:false"`
+	LastUsedStep int64      `gorm:"default:0"`
+	ConfirmedAt  *time.Time `gorm:"type:datetime"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// 登録・認証セレモニーで発行したチャレンジ
+// 一度検証に使ったチャレンジは再利用できない
+type WebauthnChallenge struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement"`
+	ChallengeHash string     `gorm:"type:char(64);uniqueIndex;not null"`
+	Ceremony      string     `gorm:"type:varchar(32);not null"`
+	UserID        *uint      `gorm:"index"`
+	ExpiresAt     time.Time  `gorm:"type:datetime;not null"`
+	UsedAt        *time.Time `gorm:"type:datetime"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
+}
+
+func HashWebauthnChallenge(challenge string) string {
+	sum := sha256.Sum256([]byte(challenge))
+	return hex.EncodeToString(sum[:])
+}
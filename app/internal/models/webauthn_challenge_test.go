@@ -0,0 +1,14 @@
+package models
+
+import "testing"
+
+func TestHashWebauthnChallenge(t *testing.T) {
+	hash := HashWebauthnChallenge("challenge")
+
+	if len(hash) != 64 {
+		t.Errorf("expected 64 chars hash, got %d", len(hash))
+	}
+	if hash != HashWebauthnChallenge("challenge") || hash == HashWebauthnChallenge("other") {
+		t.Error("expected deterministic hash")
+	}
+}
@@ -1,6 +1,9 @@
 package provider
 
-import "github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+)
 
 func (p *Provider) BindRegisterHandler() *handler.RegisterHandlerStruct {
 	return handler.NewRegisterHandler(
@@ -11,12 +14,72 @@ func (p *Provider) BindRegisterHandler() *handler.RegisterHandlerStruct {
 func (p *Provider) BindAuthHandler() *handler.AuthHandlerStruct {
 	return handler.NewAuthHandler(
 		p.bindAuthSvc(),
+		p.bindCsrfSvc(),
+		service.NewCsrfConfigFromEnv(),
+	)
+}
+
+func (p *Provider) BindMfaHandler() *handler.MfaHandlerStruct {
+	return handler.NewMfaHandler(
+		p.bindMfaSvc(),
+	)
+}
+
+func (p *Provider) BindWebauthnHandler() *handler.WebauthnHandlerStruct {
+	return handler.NewWebauthnHandler(
+		p.bindWebauthnSvc(),
+	)
+}
+
+func (p *Provider) BindPasswordlessHandler() *handler.PasswordlessHandlerStruct {
+	return handler.NewPasswordlessHandler(
+		p.bindPasswordlessSvc(),
+	)
+}
+
+func (p *Provider) BindOauthHandler() *handler.OauthHandlerStruct {
+	return handler.NewOauthHandler(
+		p.bindOauthSvc(),
+		p.bindAuthSvc(),
+		p.bindCsrfSvc(),
+		service.NewCsrfConfigFromEnv(),
+	)
+}
+
+func (p *Provider) BindOidcHandler() *handler.OidcHandlerStruct {
+	return handler.NewOidcHandler(
+		p.bindOidcSvc(),
+	)
+}
+
+func (p *Provider) BindOauthClientHandler() *handler.OauthClientHandlerStruct {
+	return handler.NewOauthClientHandler(
+		p.bindOauthClientSvc(),
+	)
+}
+
+func (p *Provider) BindRoleHandler() *handler.RoleHandlerStruct {
+	return handler.NewRoleHandler(
+		p.bindRoleSvc(),
+	)
+}
+
+func (p *Provider) BindAuthzHandler() *handler.AuthzHandlerStruct {
+	return handler.NewAuthzHandler(
+		p.bindAuthzSvc(),
+	)
+}
+
+func (p *Provider) BindAccountHandler() *handler.AccountHandlerStruct {
+	return handler.NewAccountHandler(
+		p.bindAccountSvc(),
 	)
 }
 
 func (p *Provider) BindCSRFHandler() *handler.CSRFHandlerStruct {
 	return handler.NewCSRFHandler(
 		p.bindCsrfSvc(),
+		service.NewCsrfConfigFromEnv(),
 	)
 }
 
@@ -24,6 +24,105 @@ func TestBindAuthHandler(t *testing.T) {
 	}
 }
 
+func TestBindMfaHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	mfaHandler := provider.BindMfaHandler()
+
+	if mfaHandler == nil {
+		t.Fatal("BindMfaHandler returned nil")
+	}
+}
+
+func TestBindWebauthnHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	webauthnHandler := provider.BindWebauthnHandler()
+
+	if webauthnHandler == nil {
+		t.Fatal("BindWebauthnHandler returned nil")
+	}
+}
+
+func TestBindPasswordlessHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	passwordlessHandler := provider.BindPasswordlessHandler()
+
+	if passwordlessHandler == nil {
+		t.Fatal("BindPasswordlessHandler returned nil")
+	}
+}
+
+func TestBindOauthHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	oauthHandler := provider.BindOauthHandler()
+
+	if oauthHandler == nil {
+		t.Fatal("BindOauthHandler returned nil")
+	}
+}
+
+func TestBindOidcHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	oidcHandler := provider.BindOidcHandler()
+
+	if oidcHandler == nil {
+		t.Fatal("BindOidcHandler returned nil")
+	}
+}
+
+func TestBindOauthClientHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	oauthClientHandler := provider.BindOauthClientHandler()
+
+	if oauthClientHandler == nil {
+		t.Fatal("BindOauthClientHandler returned nil")
+	}
+}
+
+func TestBindRoleHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	roleHandler := provider.BindRoleHandler()
+
+	if roleHandler == nil {
+		t.Fatal("BindRoleHandler returned nil")
+	}
+}
+
+func TestBindAuthzHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	authzHandler := provider.BindAuthzHandler()
+
+	if authzHandler == nil {
+		t.Fatal("BindAuthzHandler returned nil")
+	}
+}
+
+func TestBindAccountHandler(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	accountHandler := provider.BindAccountHandler()
+
+	if accountHandler == nil {
+		t.Fatal("BindAccountHandler returned nil")
+	}
+}
+
 func TestBindCSRFHandler(t *testing.T) {
 	db := setupTestDB()
 
@@ -1,19 +1,112 @@
 package provider
 
 import (
+	"os"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/mailer"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/qrcode"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/totp"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabcsrf"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabencrypt"
-	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabjwt"
 )
 
 func (p *Provider) bindAuthSvc() *service.AuthSvcStruct {
 	return service.NewAuthSvc(
+		service.NewAuthConfigFromEnv(),
 		repositories.NewUserRepo(p.db),
 		repositories.NewUserRefreshTokenRepo(p.db),
-		atylabjwt.NewJwtSvc(),
+		atylabencrypt.NewEncryptPkg(),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClock(),
+		p.bindMfaSvc(),
+		p.bindWebauthnSvc(),
+		p.bindPasswordlessSvc(),
+		p.bindOauthSvc(),
+		p.bindOidcSvc(),
+		p.bindRoleSvc(),
+	)
+}
+
+func (p *Provider) bindMfaSvc() *service.MfaSvcStruct {
+	return service.NewMfaSvc(
+		repositories.NewUserRepo(p.db),
+		repositories.NewUserTotpRepo(p.db),
+		repositories.NewUserRecoveryCodeRepo(p.db),
+		totp.NewTotpPkg(),
+		qrcode.NewQrcodePkg(),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClock(),
+	)
+}
+
+func (p *Provider) bindWebauthnSvc() *service.WebauthnSvcStruct {
+	return service.NewWebauthnSvc(
+		service.NewWebauthnConfigFromEnv(),
+		repositories.NewUserRepo(p.db),
+		repositories.NewUserCredentialRepo(p.db),
+		repositories.NewWebauthnChallengeRepo(p.db),
+		atylabclock.NewClock(),
+	)
+}
+
+func (p *Provider) bindPasswordlessSvc() *service.PasswordlessSvcStruct {
+	return service.NewPasswordlessSvc(
+		repositories.NewUserRepo(p.db),
+		repositories.NewPasswordlessTokenRepo(p.db),
+		mailer.NewMailerPkg(mailer.NewConfigFromEnv()),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClock(),
+	)
+}
+
+func (p *Provider) bindOauthSvc() *service.OauthSvcStruct {
+	return service.NewOauthSvc(
+		service.NewOauthConfigFromEnv(),
+		repositories.NewUserRepo(p.db),
+		repositories.NewOauthClientRepo(p.db),
+		repositories.NewOauthAuthorizationCodeRepo(p.db),
+		repositories.NewOauthDeviceCodeRepo(p.db),
+		repositories.NewOauthClientAssertionRepo(p.db),
+		repositories.NewOauthTokenExchangeRepo(p.db),
+		repositories.NewUserConsentRepo(p.db),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClock(),
+	)
+}
+
+func (p *Provider) bindOidcSvc() *service.OidcSvcStruct {
+	return service.NewOidcSvc(
+		service.NewOauthConfigFromEnv(),
+		repositories.NewUserRepo(p.db),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClock(),
+	)
+}
+
+func (p *Provider) bindOauthClientSvc() *service.OauthClientSvcStruct {
+	return service.NewOauthClientSvc(
+		repositories.NewOauthClientRepo(p.db),
+	)
+}
+
+func (p *Provider) bindRoleSvc() *service.RoleSvcStruct {
+	return service.NewRoleSvc(
+		repositories.NewRoleRepo(p.db),
+		repositories.NewPermissionRepo(p.db),
+		repositories.NewUserRoleRepo(p.db),
+		repositories.NewUserRepo(p.db),
+	)
+}
+
+func (p *Provider) bindAuthzSvc() *service.AuthzSvcStruct {
+	return service.NewAuthzSvc(
+		service.NewAuthzConfigFromEnv(),
+		repositories.NewUserRepo(p.db),
+		p.bindRoleSvc(),
 		atylabclock.NewClock(),
 	)
 }
@@ -22,11 +115,31 @@ func (p *Provider) bindRegisterSvc() *service.UserRegisterSvcStruct {
 	return service.NewUserRegisterSvc(
 		atylabencrypt.NewEncryptPkg(),
 		repositories.NewUserRepo(p.db),
+		p.bindPasswordPolicySvc(),
+	)
+}
+
+func (p *Provider) bindAccountSvc() *service.AccountSvcStruct {
+	return service.NewAccountSvc(
+		atylabencrypt.NewEncryptPkg(),
+		repositories.NewUserRepo(p.db),
+		repositories.NewUserRefreshTokenRepo(p.db),
+		p.bindPasswordPolicySvc(),
+	)
+}
+
+func (p *Provider) bindPasswordPolicySvc() *service.PasswordPolicySvcStruct {
+	return service.NewPasswordPolicySvc(
+		service.NewPasswordPolicyConfigFromEnv(),
+		repositories.NewBreachedPasswordRepo(os.Getenv("BREACHED_PASSWORD_FILE")),
 	)
 }
 
 func (p *Provider) bindCsrfSvc() *service.CsrfSvcStruct {
 	return service.NewCsrfSvcStruct(
 		atylabcsrf.NewCsrfPkgStruct(),
+		service.NewCsrfConfigFromEnv(),
+		repositories.NewCsrfTokenRepo(p.db),
+		jwttoken.NewJwtTokenPkg(),
 	)
 }
@@ -42,3 +42,102 @@ func TestBindCsrfSvc(t *testing.T) {
 		t.Fatal("BindCsrfSvc returned nil")
 	}
 }
+
+func TestBindPasswordPolicySvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	passwordPolicySvc := provider.bindPasswordPolicySvc()
+
+	if passwordPolicySvc == nil {
+		t.Fatal("BindPasswordPolicySvc returned nil")
+	}
+}
+
+func TestBindMfaSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	mfaSvc := provider.bindMfaSvc()
+
+	if mfaSvc == nil {
+		t.Fatal("BindMfaSvc returned nil")
+	}
+}
+
+func TestBindWebauthnSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	webauthnSvc := provider.bindWebauthnSvc()
+
+	if webauthnSvc == nil {
+		t.Fatal("BindWebauthnSvc returned nil")
+	}
+}
+
+func TestBindPasswordlessSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	passwordlessSvc := provider.bindPasswordlessSvc()
+
+	if passwordlessSvc == nil {
+		t.Fatal("BindPasswordlessSvc returned nil")
+	}
+}
+
+func TestBindOauthSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	oauthSvc := provider.bindOauthSvc()
+
+	if oauthSvc == nil {
+		t.Fatal("BindOauthSvc returned nil")
+	}
+}
+
+func TestBindOidcSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	oidcSvc := provider.bindOidcSvc()
+
+	if oidcSvc == nil {
+		t.Fatal("BindOidcSvc returned nil")
+	}
+}
+
+func TestBindOauthClientSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	oauthClientSvc := provider.bindOauthClientSvc()
+
+	if oauthClientSvc == nil {
+		t.Fatal("BindOauthClientSvc returned nil")
+	}
+}
+
+func TestBindAuthzSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	authzSvc := provider.bindAuthzSvc()
+
+	if authzSvc == nil {
+		t.Fatal("BindAuthzSvc returned nil")
+	}
+}
+
+func TestBindAccountSvc(t *testing.T) {
+	db := setupTestDB()
+
+	provider := NewProvider(db)
+	accountSvc := provider.bindAccountSvc()
+
+	if accountSvc == nil {
+		t.Fatal("BindAccountSvc returned nil")
+	}
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type BreachedPasswordRepoInterface interface {
+	IsBreached(password string) (bool, error)
+}
+
+// HIBP の Pwned Passwords（SHA-1 ハッシュ順にソート済み、"HASH:COUNT" 形式）をローカルファイルから検索する
+type BreachedPasswordRepoStruct struct {
+	path string
+}
+
+func NewBreachedPasswordRepo(
+	path string,
+) *BreachedPasswordRepoStruct {
+	return &BreachedPasswordRepoStruct{
+		path: path,
+	}
+}
+
+// 二分探索を打ち切って線形走査に切り替えるバイト幅
+const breachedPasswordScanWindow = 4096
+
+func (r *BreachedPasswordRepoStruct) IsBreached(password string) (bool, error) {
+	// ファイル未設定の場合はチェックしない
+	if r.path == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	key := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	file, err := os.Open(r.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open breached password file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat breached password file: %w", err)
+	}
+
+	lo, hi := int64(0), info.Size()
+	for hi-lo > breachedPasswordScanWindow {
+		mid := (lo + hi) / 2
+		line, err := r.lineAt(file, mid)
+		if err != nil {
+			return false, err
+		}
+		if line == "" || key <= r.hashOf(line) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	reader, err := r.readerAt(file, lo)
+	if err != nil {
+		return false, err
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			hash := r.hashOf(line)
+			if hash == key {
+				return true, nil
+			}
+			if hash > key {
+				return false, nil
+			}
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to read breached password file: %w", err)
+		}
+	}
+}
+
+// offset 以降で最初に始まる行を返す
+func (r *BreachedPasswordRepoStruct) lineAt(file *os.File, offset int64) (string, error) {
+	reader, err := r.readerAt(file, offset)
+	if err != nil {
+		return "", err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read breached password file: %w", err)
+	}
+	return line, nil
+}
+
+// offset 以降で最初に始まる行の先頭に位置づけた reader を返す
+func (r *BreachedPasswordRepoStruct) readerAt(file *os.File, offset int64) (*bufio.Reader, error) {
+	if offset == 0 {
+		return bufio.NewReader(io.NewSectionReader(file, 0, 1<<62)), nil
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(file, offset-1, 1<<62))
+	if _, err := reader.ReadString('\n'); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read breached password file: %w", err)
+	}
+	return reader, nil
+}
+
+func (r *BreachedPasswordRepoStruct) hashOf(line string) string {
+	line = strings.TrimRight(line, "\r\n")
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.ToUpper(line)
+}
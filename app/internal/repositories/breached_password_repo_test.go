@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func writeBreachedPasswordFile(t *testing.T, passwords []string, padding int) string {
+	t.Helper()
+
+	hashes := []string{}
+	for _, p := range passwords {
+		sum := sha1.Sum([]byte(p))
+		hashes = append(hashes, strings.ToUpper(hex.EncodeToString(sum[:])))
+	}
+	// 二分探索を通すためにダミー行を追加する
+	for i := 0; i < padding; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("padding-%d", i)))
+		hashes = append(hashes, strings.ToUpper(hex.EncodeToString(sum[:])))
+	}
+	sort.Strings(hashes)
+
+	lines := []string{}
+	for i, h := range hashes {
+		lines = append(lines, fmt.Sprintf("%s:%d", h, i+1))
+	}
+
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatalf("failed to write breached password file: %v", err)
+	}
+	return path
+}
+
+func TestIsBreached(t *testing.T) {
+	path := writeBreachedPasswordFile(t, []string{"password", "123456", "qwerty"}, 2000)
+	repo := NewBreachedPasswordRepo(path)
+
+	for _, p := range []string{"password", "123456", "qwerty"} {
+		breached, err := repo.IsBreached(p)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !breached {
+			t.Errorf("expected %q to be breached", p)
+		}
+	}
+
+	breached, err := repo.IsBreached("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if breached {
+		t.Error("expected password not to be breached")
+	}
+}
+
+func TestIsBreachedSmallFile(t *testing.T) {
+	path := writeBreachedPasswordFile(t, []string{"password"}, 0)
+	repo := NewBreachedPasswordRepo(path)
+
+	breached, err := repo.IsBreached("password")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !breached {
+		t.Error("expected password to be breached")
+	}
+}
+
+func TestIsBreachedPathNotSet(t *testing.T) {
+	repo := NewBreachedPasswordRepo("")
+
+	breached, err := repo.IsBreached("password")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if breached {
+		t.Error("expected password not to be breached when file is not set")
+	}
+}
+
+func TestIsBreachedFileNotFound(t *testing.T) {
+	repo := NewBreachedPasswordRepo(filepath.Join(t.TempDir(), "not_found.txt"))
+
+	_, err := repo.IsBreached("password")
+	if err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrCsrfTokenNotFound = errors.New("csrf token not found")
+
+type CsrfTokenRepoInterface interface {
+	Create(token *models.CsrfToken) error
+	Consume(tokenHash string) error
+}
+
+type CsrfTokenRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewCsrfTokenRepo(
+	db *gorm.DB,
+) *CsrfTokenRepoStruct {
+	return &CsrfTokenRepoStruct{
+		db: db,
+	}
+}
+
+func (r *CsrfTokenRepoStruct) Create(token *models.CsrfToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create csrf token: %w", err)
+	}
+	return nil
+}
+
+// 有効期限内かつ未使用のトークンのみ使用済みにする
+func (r *CsrfTokenRepoStruct) Consume(tokenHash string) error {
+	now := time.Now()
+	result := r.db.Model(&models.CsrfToken{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return fmt.Errorf("failed to use csrf token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrCsrfTokenNotFound
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCsrfTokenCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `csrf_tokens`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewCsrfTokenRepo(gdb)
+	err := repo.Create(&models.CsrfToken{
+		TokenHash: "hash",
+		SessionID: "session-id",
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestCsrfTokenCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `csrf_tokens`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewCsrfTokenRepo(gdb)
+	if err := repo.Create(&models.CsrfToken{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestCsrfTokenConsume(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `csrf_tokens` SET `used_at`=.*WHERE token_hash = \\? AND used_at IS NULL AND expires_at > \\?").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "hash", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewCsrfTokenRepo(gdb)
+	if err := repo.Consume("hash"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestCsrfTokenConsumeNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `csrf_tokens` SET `used_at`=").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	repo := NewCsrfTokenRepo(gdb)
+	if err := repo.Consume("hash"); !errors.Is(err, ErrCsrfTokenNotFound) {
+		t.Fatalf("expected ErrCsrfTokenNotFound, got %v", err)
+	}
+}
+
+func TestCsrfTokenConsumeFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `csrf_tokens` SET `used_at`=").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewCsrfTokenRepo(gdb)
+	if err := repo.Consume("hash"); err == nil || errors.Is(err, ErrCsrfTokenNotFound) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrOauthAuthorizationCodeNotFound = errors.New("oauth authorization code not found")
+
+type OauthAuthorizationCodeRepoInterface interface {
+	Create(code *models.OauthAuthorizationCode) error
+	ConsumeByCodeHash(codeHash string) (*models.OauthAuthorizationCode, error)
+}
+
+type OauthAuthorizationCodeRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewOauthAuthorizationCodeRepo(
+	db *gorm.DB,
+) *OauthAuthorizationCodeRepoStruct {
+	return &OauthAuthorizationCodeRepoStruct{
+		db: db,
+	}
+}
+
+func (r *OauthAuthorizationCodeRepoStruct) Create(code *models.OauthAuthorizationCode) error {
+	if err := r.db.Create(code).Error; err != nil {
+		return fmt.Errorf("failed to create oauth authorization code: %w", err)
+	}
+	return nil
+}
+
+// 有効期限内かつ未使用のコードのみ使用済みにして返す
+// 条件付きの UPDATE で使用済みにするため、同時に交換されても成功するのは 1 件のみ
+func (r *OauthAuthorizationCodeRepoStruct) ConsumeByCodeHash(codeHash string) (*models.OauthAuthorizationCode, error) {
+	now := time.Now()
+	result := r.db.Model(&models.OauthAuthorizationCode{}).
+		Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", codeHash, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to use oauth authorization code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrOauthAuthorizationCodeNotFound
+	}
+
+	var code models.OauthAuthorizationCode
+	if err := r.db.Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+		return nil, fmt.Errorf("failed to get oauth authorization code: %w", err)
+	}
+	return &code, nil
+}
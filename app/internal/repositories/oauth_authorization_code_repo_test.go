@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOauthAuthorizationCodeCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_authorization_codes`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewOauthAuthorizationCodeRepo(gdb)
+	err := repo.Create(&models.OauthAuthorizationCode{
+		CodeHash:            "hash",
+		ClientID:            "client",
+		UserID:              1,
+		RedirectURI:         "https://client.example.com/callback",
+		CodeChallenge:       "challenge",
+		CodeChallengeMethod: models.OauthCodeChallengeMethodS256,
+		ExpiresAt:           time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthAuthorizationCodeCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_authorization_codes`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewOauthAuthorizationCodeRepo(gdb)
+	if err := repo.Create(&models.OauthAuthorizationCode{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestOauthAuthorizationCodeConsumeByCodeHash(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `oauth_authorization_codes` SET `used_at`=.*WHERE code_hash = \\? AND used_at IS NULL AND expires_at > \\?").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "hash", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT .* FROM `oauth_authorization_codes` WHERE code_hash = \\?").
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code_hash", "client_id", "user_id", "redirect_uri"}).
+			AddRow(5, "hash", "client", 1, "https://client.example.com/callback"))
+
+	repo := NewOauthAuthorizationCodeRepo(gdb)
+	code, err := repo.ConsumeByCodeHash("hash")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if code.ID != 5 || code.UserID != 1 || code.ClientID != "client" {
+		t.Errorf("unexpected code: %+v", code)
+	}
+}
+
+func TestOauthAuthorizationCodeConsumeByCodeHashNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `oauth_authorization_codes` SET `used_at`=").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	repo := NewOauthAuthorizationCodeRepo(gdb)
+	if _, err := repo.ConsumeByCodeHash("hash"); !errors.Is(err, ErrOauthAuthorizationCodeNotFound) {
+		t.Fatalf("expected ErrOauthAuthorizationCodeNotFound, got %v", err)
+	}
+}
+
+func TestOauthAuthorizationCodeConsumeByCodeHashFail(t *testing.T) {
+	t.Run("update error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `oauth_authorization_codes`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewOauthAuthorizationCodeRepo(gdb)
+		_, err := repo.ConsumeByCodeHash("hash")
+		if err == nil || errors.Is(err, ErrOauthAuthorizationCodeNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+
+	t.Run("select error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `oauth_authorization_codes`").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT .* FROM `oauth_authorization_codes`").
+			WillReturnError(sqlmock.ErrCancelled)
+
+		repo := NewOauthAuthorizationCodeRepo(gdb)
+		if _, err := repo.ConsumeByCodeHash("hash"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
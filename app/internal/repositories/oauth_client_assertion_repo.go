@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrOauthClientAssertionReplayed = errors.New("oauth client assertion already used")
+
+type OauthClientAssertionRepoInterface interface {
+	Register(clientId string, jti string, expiresAt time.Time) error
+}
+
+type OauthClientAssertionRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewOauthClientAssertionRepo(
+	db *gorm.DB,
+) *OauthClientAssertionRepoStruct {
+	return &OauthClientAssertionRepoStruct{
+		db: db,
+	}
+}
+
+// 受け付けた jti を記録する。同じクライアントの同じ jti は一意制約で拒否する
+// 期限切れの記録は、同じ jti を再び受け付けられるよう先に削除する
+func (r *OauthClientAssertionRepoStruct) Register(clientId string, jti string, expiresAt time.Time) error {
+	if err := r.db.Where("client_id = ? AND expires_at <= ?", clientId, time.Now()).
+		Delete(&models.OauthClientAssertion{}).Error; err != nil {
+		return fmt.Errorf("failed to delete expired oauth client assertions: %w", err)
+	}
+
+	if err := r.db.Create(&models.OauthClientAssertion{
+		ClientID:  clientId,
+		Jti:       jti,
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		if isDuplicateEntry(err) {
+			return ErrOauthClientAssertionReplayed
+		}
+		return fmt.Errorf("failed to register oauth client assertion: %w", err)
+	}
+	return nil
+}
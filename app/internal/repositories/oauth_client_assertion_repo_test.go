@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestOauthClientAssertionRegister(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `oauth_client_assertions` WHERE client_id = \\? AND expires_at <= \\?").
+		WithArgs("client", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_client_assertions`").
+		WithArgs("client", "jti", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewOauthClientAssertionRepo(gdb)
+	if err := repo.Register("client", "jti", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthClientAssertionRegisterFail(t *testing.T) {
+	t.Run("replayed", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `oauth_client_assertions`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `oauth_client_assertions`").
+			WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+		mock.ExpectRollback()
+
+		repo := NewOauthClientAssertionRepo(gdb)
+		if err := repo.Register("client", "jti", time.Now().Add(time.Minute)); !errors.Is(err, ErrOauthClientAssertionReplayed) {
+			t.Fatalf("expected ErrOauthClientAssertionReplayed, got %v", err)
+		}
+	})
+
+	t.Run("delete error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `oauth_client_assertions`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewOauthClientAssertionRepo(gdb)
+		if err := repo.Register("client", "jti", time.Now()); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+
+	t.Run("insert error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `oauth_client_assertions`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `oauth_client_assertions`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewOauthClientAssertionRepo(gdb)
+		err := repo.Register("client", "jti", time.Now())
+		if err == nil || errors.Is(err, ErrOauthClientAssertionReplayed) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
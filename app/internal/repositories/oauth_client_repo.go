@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrOauthClientNotFound = errors.New("oauth client not found")
+
+type OauthClientRepoInterface interface {
+	Create(client *models.OauthClient) error
+	GetByClientID(clientId string) (*models.OauthClient, error)
+	List() ([]models.OauthClient, error)
+	Update(client *models.OauthClient) error
+	Delete(clientId string) error
+}
+
+type OauthClientRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewOauthClientRepo(
+	db *gorm.DB,
+) *OauthClientRepoStruct {
+	return &OauthClientRepoStruct{
+		db: db,
+	}
+}
+
+func (r *OauthClientRepoStruct) Create(client *models.OauthClient) error {
+	if err := r.db.Create(client).Error; err != nil {
+		return fmt.Errorf("failed to create oauth client: %w", err)
+	}
+	return nil
+}
+
+func (r *OauthClientRepoStruct) GetByClientID(clientId string) (*models.OauthClient, error) {
+	var client models.OauthClient
+	if err := r.db.Where("client_id = ?", clientId).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOauthClientNotFound
+		}
+		return nil, fmt.Errorf("failed to get oauth client: %w", err)
+	}
+	return &client, nil
+}
+
+func (r *OauthClientRepoStruct) List() ([]models.OauthClient, error) {
+	clients := []models.OauthClient{}
+	if err := r.db.Order("id").Find(&clients).Error; err != nil {
+		return nil, fmt.Errorf("failed to list oauth clients: %w", err)
+	}
+	return clients, nil
+}
+
+// client_id 以外の項目をすべて更新する（ゼロ値の項目も更新する）
+func (r *OauthClientRepoStruct) Update(client *models.OauthClient) error {
+	if err := r.db.Model(&models.OauthClient{}).
+		Where("client_id = ?", client.ClientID).
+		Select("client_secret_hash", "name", "token_endpoint_auth_method", "public_key", "redirect_uris",
+			"grant_types", "scopes", "audiences", "access_token_lifetime", "refresh_token_lifetime", "first_party").
+		Updates(client).Error; err != nil {
+		return fmt.Errorf("failed to update oauth client: %w", err)
+	}
+	return nil
+}
+
+// クライアントに発行済みの認可コード・デバイスコードは削除し、リフレッシュトークンは失効させる
+func (r *OauthClientRepoStruct) Delete(clientId string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("client_id = ?", clientId).Delete(&models.OauthClient{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete oauth client: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrOauthClientNotFound
+		}
+
+		for _, model := range []any{&models.OauthAuthorizationCode{}, &models.OauthDeviceCode{}, &models.OauthClientAssertion{}, &models.UserConsent{}} {
+			if err := tx.Where("client_id = ?", clientId).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to delete oauth client data: %w", err)
+			}
+		}
+		if err := tx.Model(&models.UserRefreshToken{}).
+			Where("client_id = ? AND is_used = ?", clientId, false).
+			Update("is_used", true).Error; err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		return nil
+	})
+}
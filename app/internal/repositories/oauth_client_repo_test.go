@@ -0,0 +1,223 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var oauthClientColumns = []string{"id", "client_id", "name", "token_endpoint_auth_method", "redirect_uris", "grant_types", "scopes"}
+
+func TestOauthClientCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_clients`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewOauthClientRepo(gdb)
+	err := repo.Create(&models.OauthClient{
+		ClientID:                "client",
+		Name:                    "Client",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodNone,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthClientCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_clients`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewOauthClientRepo(gdb)
+	if err := repo.Create(&models.OauthClient{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestOauthClientGetByClientID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `oauth_clients` WHERE client_id = \\?").
+		WithArgs("client", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(oauthClientColumns).
+			AddRow(1, "client", "Client", "none", "https://client.example.com/cb", "authorization_code", "openid"))
+
+	repo := NewOauthClientRepo(gdb)
+	client, err := repo.GetByClientID("client")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if client.ClientID != "client" || !client.AllowsGrantType("authorization_code") {
+		t.Errorf("unexpected client: %+v", client)
+	}
+}
+
+func TestOauthClientGetByClientIDFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT .* FROM `oauth_clients`").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		repo := NewOauthClientRepo(gdb)
+		if _, err := repo.GetByClientID("client"); !errors.Is(err, ErrOauthClientNotFound) {
+			t.Fatalf("expected ErrOauthClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT .* FROM `oauth_clients`").
+			WillReturnError(sqlmock.ErrCancelled)
+
+		repo := NewOauthClientRepo(gdb)
+		_, err := repo.GetByClientID("client")
+		if err == nil || errors.Is(err, ErrOauthClientNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
+
+func TestOauthClientList(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `oauth_clients` ORDER BY id").
+		WillReturnRows(sqlmock.NewRows(oauthClientColumns).
+			AddRow(1, "a", "A", "none", "", "", "").
+			AddRow(2, "b", "B", "client_secret_basic", "", "", ""))
+
+	repo := NewOauthClientRepo(gdb)
+	clients, err := repo.List()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(clients) != 2 || clients[1].ClientID != "b" {
+		t.Errorf("unexpected clients: %+v", clients)
+	}
+}
+
+func TestOauthClientListFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `oauth_clients`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewOauthClientRepo(gdb)
+	if _, err := repo.List(); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestOauthClientUpdate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	// ゼロ値（first_party = false 等）も更新対象に含める
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `oauth_clients` SET `client_secret_hash`=\\?,`name`=\\?,.*`first_party`=\\?.*WHERE client_id = \\?").
+		WithArgs("", "Renamed", "none", "", "", "", "", "", 0, 0, false, sqlmock.AnyArg(), "client").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewOauthClientRepo(gdb)
+	err := repo.Update(&models.OauthClient{
+		ClientID:                "client",
+		Name:                    "Renamed",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodNone,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthClientUpdateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `oauth_clients`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewOauthClientRepo(gdb)
+	if err := repo.Update(&models.OauthClient{ClientID: "client"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestOauthClientDelete(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `oauth_clients` WHERE client_id = \\?").
+		WithArgs("client").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, table := range []string{"oauth_authorization_codes", "oauth_device_codes", "oauth_client_assertions", "user_consents"} {
+		mock.ExpectExec("DELETE FROM `" + table + "` WHERE client_id = \\?").
+			WithArgs("client").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("UPDATE `user_refresh_tokens` SET `is_used`=.*WHERE client_id = \\? AND is_used = \\?").
+		WithArgs(true, sqlmock.AnyArg(), "client", false).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewOauthClientRepo(gdb)
+	if err := repo.Delete("client"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthClientDeleteFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `oauth_clients`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		repo := NewOauthClientRepo(gdb)
+		if err := repo.Delete("client"); !errors.Is(err, ErrOauthClientNotFound) {
+			t.Fatalf("expected ErrOauthClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `oauth_clients`").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM `oauth_authorization_codes`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewOauthClientRepo(gdb)
+		err := repo.Delete("client")
+		if err == nil || errors.Is(err, ErrOauthClientNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrOauthDeviceCodeNotFound = errors.New("oauth device code not found")
+
+type OauthDeviceCodeRepoInterface interface {
+	Create(code *models.OauthDeviceCode) error
+	GetPendingByUserCodeHash(userCodeHash string) (*models.OauthDeviceCode, error)
+	GetByDeviceCodeHash(deviceCodeHash string) (*models.OauthDeviceCode, error)
+	Decide(code *models.OauthDeviceCode) error
+	UpdatePolling(id uint, lastPolledAt time.Time, pollInterval int) error
+	Consume(id uint) error
+}
+
+type OauthDeviceCodeRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewOauthDeviceCodeRepo(
+	db *gorm.DB,
+) *OauthDeviceCodeRepoStruct {
+	return &OauthDeviceCodeRepoStruct{
+		db: db,
+	}
+}
+
+func (r *OauthDeviceCodeRepoStruct) Create(code *models.OauthDeviceCode) error {
+	if err := r.db.Create(code).Error; err != nil {
+		return fmt.Errorf("failed to create oauth device code: %w", err)
+	}
+	return nil
+}
+
+// 検証画面で入力されたユーザーコードのうち、有効期限内で承認・拒否されていないもののみ返す
+func (r *OauthDeviceCodeRepoStruct) GetPendingByUserCodeHash(userCodeHash string) (*models.OauthDeviceCode, error) {
+	var code models.OauthDeviceCode
+	if err := r.db.Where("user_code_hash = ? AND status = ? AND expires_at > ?", userCodeHash, models.OauthDeviceCodeStatusPending, time.Now()).
+		First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOauthDeviceCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to get oauth device code: %w", err)
+	}
+	return &code, nil
+}
+
+// ポーリングでは期限切れ・使用済みのコードも返し、状態に応じたエラーを判定させる
+func (r *OauthDeviceCodeRepoStruct) GetByDeviceCodeHash(deviceCodeHash string) (*models.OauthDeviceCode, error) {
+	var code models.OauthDeviceCode
+	if err := r.db.Where("device_code_hash = ?", deviceCodeHash).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOauthDeviceCodeNotFound
+		}
+		return nil, fmt.Errorf("failed to get oauth device code: %w", err)
+	}
+	return &code, nil
+}
+
+// 承認・拒否の結果を保存する
+// 条件付きの UPDATE にするため、同じユーザーコードを同時に承認・拒否しても成功するのは 1 件のみ
+func (r *OauthDeviceCodeRepoStruct) Decide(code *models.OauthDeviceCode) error {
+	result := r.db.Model(&models.OauthDeviceCode{}).
+		Where("id = ? AND status = ? AND expires_at > ?", code.ID, models.OauthDeviceCodeStatusPending, time.Now()).
+		Updates(map[string]any{
+			"status":    code.Status,
+			"user_id":   code.UserID,
+			"auth_time": code.AuthTime,
+			"amr":       code.Amr,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update oauth device code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrOauthDeviceCodeNotFound
+	}
+	return nil
+}
+
+func (r *OauthDeviceCodeRepoStruct) UpdatePolling(id uint, lastPolledAt time.Time, pollInterval int) error {
+	if err := r.db.Model(&models.OauthDeviceCode{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"last_polled_at": lastPolledAt,
+			"poll_interval":  pollInterval,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update oauth device code: %w", err)
+	}
+	return nil
+}
+
+// 承認済みかつ未使用のコードのみ使用済みにする
+// 同時にポーリングされてもトークンを発行するのは 1 回のみ
+func (r *OauthDeviceCodeRepoStruct) Consume(id uint) error {
+	result := r.db.Model(&models.OauthDeviceCode{}).
+		Where("id = ? AND status = ? AND used_at IS NULL", id, models.OauthDeviceCodeStatusApproved).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to use oauth device code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrOauthDeviceCodeNotFound
+	}
+	return nil
+}
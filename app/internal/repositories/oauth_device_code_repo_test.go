@@ -0,0 +1,225 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOauthDeviceCodeCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_device_codes`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewOauthDeviceCodeRepo(gdb)
+	err := repo.Create(&models.OauthDeviceCode{
+		DeviceCodeHash: "device-hash",
+		UserCodeHash:   "user-hash",
+		ClientID:       "client",
+		Status:         models.OauthDeviceCodeStatusPending,
+		PollInterval:   5,
+		ExpiresAt:      time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthDeviceCodeCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_device_codes`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewOauthDeviceCodeRepo(gdb)
+	if err := repo.Create(&models.OauthDeviceCode{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestOauthDeviceCodeGetPendingByUserCodeHash(t *testing.T) {
+	tests := map[string]struct {
+		rows     *sqlmock.Rows
+		err      error
+		expected error
+	}{
+		"found":     {sqlmock.NewRows([]string{"id", "client_id"}).AddRow(3, "client"), nil, nil},
+		"not found": {sqlmock.NewRows([]string{"id"}), nil, ErrOauthDeviceCodeNotFound},
+		"db error":  {nil, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+			defer cleanup()
+
+			query := mock.ExpectQuery("SELECT .* FROM `oauth_device_codes` WHERE user_code_hash = \\? AND status = \\? AND expires_at > \\?").
+				WithArgs("user-hash", models.OauthDeviceCodeStatusPending, sqlmock.AnyArg(), 1)
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(tt.rows)
+			}
+
+			repo := NewOauthDeviceCodeRepo(gdb)
+			code, err := repo.GetPendingByUserCodeHash("user-hash")
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			if tt.expected == nil && (code.ID != 3 || code.ClientID != "client") {
+				t.Errorf("unexpected code: %+v", code)
+			}
+		})
+	}
+}
+
+func TestOauthDeviceCodeGetByDeviceCodeHash(t *testing.T) {
+	tests := map[string]struct {
+		rows     *sqlmock.Rows
+		err      error
+		expected error
+	}{
+		"found":     {sqlmock.NewRows([]string{"id", "status"}).AddRow(3, models.OauthDeviceCodeStatusApproved), nil, nil},
+		"not found": {sqlmock.NewRows([]string{"id"}), nil, ErrOauthDeviceCodeNotFound},
+		"db error":  {nil, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+			defer cleanup()
+
+			query := mock.ExpectQuery("SELECT .* FROM `oauth_device_codes` WHERE device_code_hash = \\?").
+				WithArgs("device-hash", 1)
+			if tt.err != nil {
+				query.WillReturnError(tt.err)
+			} else {
+				query.WillReturnRows(tt.rows)
+			}
+
+			repo := NewOauthDeviceCodeRepo(gdb)
+			code, err := repo.GetByDeviceCodeHash("device-hash")
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			if tt.expected == nil && code.Status != models.OauthDeviceCodeStatusApproved {
+				t.Errorf("unexpected code: %+v", code)
+			}
+		})
+	}
+}
+
+func TestOauthDeviceCodeDecide(t *testing.T) {
+	tests := map[string]struct {
+		rowsAffected int64
+		err          error
+		expected     error
+	}{
+		"decided":         {1, nil, nil},
+		"already decided": {0, nil, ErrOauthDeviceCodeNotFound},
+		"db error":        {0, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+			defer cleanup()
+
+			mock.ExpectBegin()
+			exec := mock.ExpectExec("UPDATE `oauth_device_codes` SET .*`status`=.*WHERE id = \\? AND status = \\? AND expires_at > \\?")
+			if tt.err != nil {
+				exec.WillReturnError(tt.err)
+				mock.ExpectRollback()
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+				mock.ExpectCommit()
+			}
+
+			userID := uint(1)
+			repo := NewOauthDeviceCodeRepo(gdb)
+			err := repo.Decide(&models.OauthDeviceCode{ID: 3, Status: models.OauthDeviceCodeStatusApproved, UserID: &userID})
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestOauthDeviceCodeUpdatePolling(t *testing.T) {
+	tests := map[string]struct {
+		err error
+	}{
+		"updated":  {nil},
+		"db error": {sqlmock.ErrCancelled},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+			defer cleanup()
+
+			now := time.Now()
+			mock.ExpectBegin()
+			exec := mock.ExpectExec("UPDATE `oauth_device_codes` SET `last_polled_at`=\\?,`poll_interval`=\\?,`updated_at`=\\? WHERE id = \\?").
+				WithArgs(now, 10, sqlmock.AnyArg(), 3)
+			if tt.err != nil {
+				exec.WillReturnError(tt.err)
+				mock.ExpectRollback()
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			}
+
+			repo := NewOauthDeviceCodeRepo(gdb)
+			if err := repo.UpdatePolling(3, now, 10); (err != nil) != (tt.err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestOauthDeviceCodeConsume(t *testing.T) {
+	tests := map[string]struct {
+		rowsAffected int64
+		err          error
+		expected     error
+	}{
+		"consumed":     {1, nil, nil},
+		"already used": {0, nil, ErrOauthDeviceCodeNotFound},
+		"db error":     {0, sqlmock.ErrCancelled, sqlmock.ErrCancelled},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+			defer cleanup()
+
+			mock.ExpectBegin()
+			exec := mock.ExpectExec("UPDATE `oauth_device_codes` SET `used_at`=.*WHERE id = \\? AND status = \\? AND used_at IS NULL").
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 3, models.OauthDeviceCodeStatusApproved)
+			if tt.err != nil {
+				exec.WillReturnError(tt.err)
+				mock.ExpectRollback()
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tt.rowsAffected))
+				mock.ExpectCommit()
+			}
+
+			repo := NewOauthDeviceCodeRepo(gdb)
+			if err := repo.Consume(3); !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+type OauthTokenExchangeRepoInterface interface {
+	Create(exchange *models.OauthTokenExchange) error
+}
+
+type OauthTokenExchangeRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewOauthTokenExchangeRepo(
+	db *gorm.DB,
+) *OauthTokenExchangeRepoStruct {
+	return &OauthTokenExchangeRepoStruct{
+		db: db,
+	}
+}
+
+func (r *OauthTokenExchangeRepoStruct) Create(exchange *models.OauthTokenExchange) error {
+	if err := r.db.Create(exchange).Error; err != nil {
+		return fmt.Errorf("failed to create oauth token exchange: %w", err)
+	}
+	return nil
+}
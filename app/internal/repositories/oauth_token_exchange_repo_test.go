@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestOauthTokenExchangeCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_token_exchanges`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewOauthTokenExchangeRepo(gdb)
+	err := repo.Create(&models.OauthTokenExchange{
+		Jti:           "jti",
+		ClientID:      "client",
+		SubjectUserID: 1,
+		Actor:         "client",
+		ExpiresAt:     time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthTokenExchangeCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `oauth_token_exchanges`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewOauthTokenExchangeRepo(gdb)
+	if err := repo.Create(&models.OauthTokenExchange{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
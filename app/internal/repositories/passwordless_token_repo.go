@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrPasswordlessTokenNotFound = errors.New("passwordless token not found")
+
+type PasswordlessTokenRepoInterface interface {
+	Create(token *models.PasswordlessToken) error
+	GetLatestByUserID(userId uint) (*models.PasswordlessToken, error)
+	InvalidateByUserID(userId uint) error
+	RegisterAttempt(id uint, maxAttempts int) error
+	Consume(id uint) error
+	ConsumeByTokenHash(tokenHash string, method string) (*models.PasswordlessToken, error)
+}
+
+type PasswordlessTokenRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewPasswordlessTokenRepo(
+	db *gorm.DB,
+) *PasswordlessTokenRepoStruct {
+	return &PasswordlessTokenRepoStruct{
+		db: db,
+	}
+}
+
+func (r *PasswordlessTokenRepoStruct) Create(token *models.PasswordlessToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		return fmt.Errorf("failed to create passwordless token: %w", err)
+	}
+	return nil
+}
+
+func (r *PasswordlessTokenRepoStruct) GetLatestByUserID(userId uint) (*models.PasswordlessToken, error) {
+	var token models.PasswordlessToken
+	if err := r.db.Where("user_id = ?", userId).Order("id DESC").First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPasswordlessTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get passwordless token: %w", err)
+	}
+	return &token, nil
+}
+
+// 新しいリンク・コードを発行する前に、未使用のものをすべて無効にする
+func (r *PasswordlessTokenRepoStruct) InvalidateByUserID(userId uint) error {
+	if err := r.db.Model(&models.PasswordlessToken{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Update("used_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to invalidate passwordless tokens: %w", err)
+	}
+	return nil
+}
+
+// 照合の前に試行回数を加算する
+// 上限に達している場合は更新されず、コードは二度と使えない
+func (r *PasswordlessTokenRepoStruct) RegisterAttempt(id uint, maxAttempts int) error {
+	result := r.db.Model(&models.PasswordlessToken{}).
+		Where("id = ? AND attempts < ? AND used_at IS NULL", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to update passwordless token attempts: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPasswordlessTokenNotFound
+	}
+	return nil
+}
+
+func (r *PasswordlessTokenRepoStruct) Consume(id uint) error {
+	now := time.Now()
+	result := r.db.Model(&models.PasswordlessToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return fmt.Errorf("failed to use passwordless token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPasswordlessTokenNotFound
+	}
+	return nil
+}
+
+// 有効期限内かつ未使用のトークンのみ使用済みにして返す
+func (r *PasswordlessTokenRepoStruct) ConsumeByTokenHash(tokenHash string, method string) (*models.PasswordlessToken, error) {
+	now := time.Now()
+	result := r.db.Model(&models.PasswordlessToken{}).
+		Where("token_hash = ? AND method = ? AND used_at IS NULL AND expires_at > ?", tokenHash, method, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to use passwordless token: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrPasswordlessTokenNotFound
+	}
+
+	var token models.PasswordlessToken
+	if err := r.db.Where("token_hash = ? AND method = ?", tokenHash, method).Order("id DESC").First(&token).Error; err != nil {
+		return nil, fmt.Errorf("failed to get passwordless token: %w", err)
+	}
+	return &token, nil
+}
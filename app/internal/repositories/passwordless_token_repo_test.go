@@ -0,0 +1,292 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPasswordlessTokenCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `passwordless_tokens`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	err := repo.Create(&models.PasswordlessToken{
+		UserID:    1,
+		Method:    models.PasswordlessMethodCode,
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestPasswordlessTokenCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `passwordless_tokens`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	if err := repo.Create(&models.PasswordlessToken{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestPasswordlessTokenGetLatestByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `passwordless_tokens` WHERE user_id = \\? ORDER BY id DESC").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "method", "token_hash", "attempts"}).
+			AddRow(3, 1, "code", "hash", 2))
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	token, err := repo.GetLatestByUserID(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if token.ID != 3 || token.Method != "code" || token.Attempts != 2 {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
+
+func TestPasswordlessTokenGetLatestByUserIDFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT .* FROM `passwordless_tokens`").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		repo := NewPasswordlessTokenRepo(gdb)
+		if _, err := repo.GetLatestByUserID(1); !errors.Is(err, ErrPasswordlessTokenNotFound) {
+			t.Fatalf("expected ErrPasswordlessTokenNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT .* FROM `passwordless_tokens`").
+			WillReturnError(sqlmock.ErrCancelled)
+
+		repo := NewPasswordlessTokenRepo(gdb)
+		_, err := repo.GetLatestByUserID(1)
+		if err == nil || errors.Is(err, ErrPasswordlessTokenNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
+
+func TestPasswordlessTokenInvalidateByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `passwordless_tokens` SET `used_at`=.*WHERE user_id = \\? AND used_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	if err := repo.InvalidateByUserID(1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestPasswordlessTokenInvalidateByUserIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `passwordless_tokens`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	if err := repo.InvalidateByUserID(1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestPasswordlessTokenRegisterAttempt(t *testing.T) {
+	tests := []struct {
+		title    string
+		affected int64
+		expected error
+	}{
+		{"success", 1, nil},
+		{"limit reached", 0, ErrPasswordlessTokenNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+			defer cleanup()
+
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE `passwordless_tokens` SET `attempts`=attempts \\+ 1.*WHERE id = \\? AND attempts < \\? AND used_at IS NULL").
+				WithArgs(sqlmock.AnyArg(), 3, 5).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			mock.ExpectCommit()
+
+			repo := NewPasswordlessTokenRepo(gdb)
+			if err := repo.RegisterAttempt(3, 5); !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestPasswordlessTokenRegisterAttemptFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `passwordless_tokens`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	err := repo.RegisterAttempt(3, 5)
+	if err == nil || errors.Is(err, ErrPasswordlessTokenNotFound) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestPasswordlessTokenConsume(t *testing.T) {
+	tests := []struct {
+		title    string
+		affected int64
+		expected error
+	}{
+		{"success", 1, nil},
+		{"already used", 0, ErrPasswordlessTokenNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+			defer cleanup()
+
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE `passwordless_tokens` SET `used_at`=.*WHERE id = \\? AND used_at IS NULL AND expires_at > \\?").
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 3, sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			mock.ExpectCommit()
+
+			repo := NewPasswordlessTokenRepo(gdb)
+			if err := repo.Consume(3); !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestPasswordlessTokenConsumeFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `passwordless_tokens`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	err := repo.Consume(3)
+	if err == nil || errors.Is(err, ErrPasswordlessTokenNotFound) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestPasswordlessTokenConsumeByTokenHash(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `passwordless_tokens` SET `used_at`=.*WHERE token_hash = \\? AND method = \\? AND used_at IS NULL AND expires_at > \\?").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "hash", "link", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT .* FROM `passwordless_tokens` WHERE token_hash = \\? AND method = \\?").
+		WithArgs("hash", "link", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "method", "token_hash"}).
+			AddRow(4, 1, "link", "hash"))
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	token, err := repo.ConsumeByTokenHash("hash", "link")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if token.ID != 4 || token.UserID != 1 {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
+
+func TestPasswordlessTokenConsumeByTokenHashNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `passwordless_tokens` SET `used_at`=").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	repo := NewPasswordlessTokenRepo(gdb)
+	if _, err := repo.ConsumeByTokenHash("hash", "link"); !errors.Is(err, ErrPasswordlessTokenNotFound) {
+		t.Fatalf("expected ErrPasswordlessTokenNotFound, got %v", err)
+	}
+}
+
+func TestPasswordlessTokenConsumeByTokenHashFail(t *testing.T) {
+	t.Run("update error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `passwordless_tokens`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewPasswordlessTokenRepo(gdb)
+		_, err := repo.ConsumeByTokenHash("hash", "link")
+		if err == nil || errors.Is(err, ErrPasswordlessTokenNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+
+	t.Run("select error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `passwordless_tokens`").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT .* FROM `passwordless_tokens`").
+			WillReturnError(sqlmock.ErrCancelled)
+
+		repo := NewPasswordlessTokenRepo(gdb)
+		if _, err := repo.ConsumeByTokenHash("hash", "link"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
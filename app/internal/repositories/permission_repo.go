@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"fmt"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PermissionRepoInterface interface {
+	List() ([]models.Permission, error)
+	FindOrCreate(names []string) ([]models.Permission, error)
+}
+
+type PermissionRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepo(
+	db *gorm.DB,
+) *PermissionRepoStruct {
+	return &PermissionRepoStruct{
+		db: db,
+	}
+}
+
+func (r *PermissionRepoStruct) List() ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	if err := r.db.Order("name").Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+// 未登録のパーミッションは登録し、名前に対応するパーミッションをすべて返す
+func (r *PermissionRepoStruct) FindOrCreate(names []string) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	if len(names) == 0 {
+		return permissions, nil
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		rows := make([]models.Permission, 0, len(names))
+		for _, name := range names {
+			rows = append(rows, models.Permission{Name: name})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to create permissions: %w", err)
+		}
+		if err := tx.Where("name IN ?", names).Order("id").Find(&permissions).Error; err != nil {
+			return fmt.Errorf("failed to find permissions: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
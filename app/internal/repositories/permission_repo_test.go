@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPermissionList(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `permissions` ORDER BY name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "users:read"))
+
+	repo := NewPermissionRepo(gdb)
+	permissions, err := repo.List()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(permissions) != 1 || permissions[0].Name != "users:read" {
+		t.Errorf("unexpected permissions: %+v", permissions)
+	}
+}
+
+func TestPermissionListFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `permissions`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewPermissionRepo(gdb)
+	if _, err := repo.List(); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestPermissionFindOrCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	// 登録済みのパーミッションは重複させない
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `permissions` .* ON DUPLICATE KEY UPDATE `id`=`id`").
+		WillReturnResult(sqlmock.NewResult(11, 1))
+	mock.ExpectQuery("SELECT \\* FROM `permissions` WHERE name IN \\(\\?,\\?\\) ORDER BY id").
+		WithArgs("users:read", "users:write").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "users:read").AddRow(11, "users:write"))
+	mock.ExpectCommit()
+
+	repo := NewPermissionRepo(gdb)
+	permissions, err := repo.FindOrCreate([]string{"users:read", "users:write"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(permissions) != 2 || permissions[1].ID != 11 {
+		t.Errorf("unexpected permissions: %+v", permissions)
+	}
+}
+
+func TestPermissionFindOrCreateEmpty(t *testing.T) {
+	gdb, _, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	repo := NewPermissionRepo(gdb)
+	permissions, err := repo.FindOrCreate(nil)
+	if err != nil || len(permissions) != 0 {
+		t.Fatalf("expected no permissions, got %+v, %v", permissions, err)
+	}
+}
+
+func TestPermissionFindOrCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `permissions`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewPermissionRepo(gdb)
+	if _, err := repo.FindOrCreate([]string{"users:read"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRoleNotFound  = errors.New("role not found")
+	ErrDuplicateRole = errors.New("role already exists")
+)
+
+type RoleRepoInterface interface {
+	List() ([]models.Role, error)
+	GetByName(name string) (*models.Role, error)
+	Create(role *models.Role) error
+	Update(role *models.Role) error
+	Delete(name string) error
+}
+
+type RoleRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewRoleRepo(
+	db *gorm.DB,
+) *RoleRepoStruct {
+	return &RoleRepoStruct{
+		db: db,
+	}
+}
+
+func (r *RoleRepoStruct) List() ([]models.Role, error) {
+	roles := []models.Role{}
+	if err := r.db.Preload("Permissions").Order("id").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (r *RoleRepoStruct) GetByName(name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	return &role, nil
+}
+
+// パーミッションは登録済みのもの（ID を持つもの）のみ紐づける
+func (r *RoleRepoStruct) Create(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(role).Error; err != nil {
+			if isDuplicateEntry(err) {
+				return ErrDuplicateRole
+			}
+			return fmt.Errorf("failed to create role: %w", err)
+		}
+		return replaceRolePermissions(tx, role)
+	})
+}
+
+// 説明とパーミッションを更新する。パーミッションは role.Permissions で置き換える
+func (r *RoleRepoStruct) Update(role *models.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Role{}).
+			Where("id = ?", role.ID).
+			Update("description", role.Description).Error; err != nil {
+			return fmt.Errorf("failed to update role: %w", err)
+		}
+		return replaceRolePermissions(tx, role)
+	})
+}
+
+// ロールの削除と同時に、ユーザーへの割り当ても解除する
+func (r *RoleRepoStruct) Delete(name string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var role models.Role
+		if err := tx.Where("name = ?", name).First(&role).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRoleNotFound
+			}
+			return fmt.Errorf("failed to get role: %w", err)
+		}
+
+		for _, model := range []any{&models.UserRole{}, &models.RolePermission{}} {
+			if err := tx.Where("role_id = ?", role.ID).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to delete role data: %w", err)
+			}
+		}
+		if err := tx.Where("id = ?", role.ID).Delete(&models.Role{}).Error; err != nil {
+			return fmt.Errorf("failed to delete role: %w", err)
+		}
+		return nil
+	})
+}
+
+func replaceRolePermissions(tx *gorm.DB, role *models.Role) error {
+	if err := tx.Where("role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+		return fmt.Errorf("failed to delete role permissions: %w", err)
+	}
+	if len(role.Permissions) == 0 {
+		return nil
+	}
+
+	rows := make([]models.RolePermission, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		rows = append(rows, models.RolePermission{RoleID: role.ID, PermissionID: permission.ID})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to create role permissions: %w", err)
+	}
+	return nil
+}
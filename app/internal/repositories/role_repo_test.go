@@ -0,0 +1,252 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+func expectRolePermissionsPreload(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT \\* FROM `role_permissions` WHERE `role_permissions`.`role_id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(1, 10).AddRow(1, 11))
+	mock.ExpectQuery("SELECT \\* FROM `permissions` WHERE `permissions`.`id` IN \\(\\?,\\?\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "users:read").AddRow(11, "users:write"))
+}
+
+func TestRoleList(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `roles` ORDER BY id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin"))
+	expectRolePermissionsPreload(mock)
+
+	repo := NewRoleRepo(gdb)
+	roles, err := repo.List()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(roles) != 1 || len(roles[0].Permissions) != 2 || roles[0].Permissions[0].Name != "users:read" {
+		t.Errorf("unexpected roles: %+v", roles)
+	}
+}
+
+func TestRoleListFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `roles`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewRoleRepo(gdb)
+	if _, err := repo.List(); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestRoleGetByName(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE name = \\?").
+		WithArgs("admin", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin"))
+	expectRolePermissionsPreload(mock)
+
+	repo := NewRoleRepo(gdb)
+	role, err := repo.GetByName("admin")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if role.Name != "admin" || len(role.Permissions) != 2 {
+		t.Errorf("unexpected role: %+v", role)
+	}
+}
+
+func TestRoleGetByNameFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT \\* FROM `roles`").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		repo := NewRoleRepo(gdb)
+		if _, err := repo.GetByName("admin"); !errors.Is(err, ErrRoleNotFound) {
+			t.Fatalf("expected ErrRoleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT \\* FROM `roles`").
+			WillReturnError(sqlmock.ErrCancelled)
+
+		repo := NewRoleRepo(gdb)
+		_, err := repo.GetByName("admin")
+		if err == nil || errors.Is(err, ErrRoleNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
+
+func TestRoleCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `roles`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM `role_permissions` WHERE role_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `role_permissions` \\(`role_id`,`permission_id`\\) VALUES \\(\\?,\\?\\),\\(\\?,\\?\\)").
+		WithArgs(1, 10, 1, 11).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewRoleRepo(gdb)
+	role := &models.Role{Name: "admin", Permissions: []models.Permission{{ID: 10}, {ID: 11}}}
+	if err := repo.Create(role); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if role.ID != 1 {
+		t.Errorf("expected role id to be set, got %d", role.ID)
+	}
+}
+
+func TestRoleCreateFail(t *testing.T) {
+	t.Run("duplicate", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `roles`").
+			WillReturnError(&mysql.MySQLError{Number: mysqlErrDuplicateEntry})
+		mock.ExpectRollback()
+
+		repo := NewRoleRepo(gdb)
+		if err := repo.Create(&models.Role{Name: "admin"}); !errors.Is(err, ErrDuplicateRole) {
+			t.Fatalf("expected ErrDuplicateRole, got %v", err)
+		}
+	})
+
+	t.Run("permission error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `roles`").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("DELETE FROM `role_permissions`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `role_permissions`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewRoleRepo(gdb)
+		if err := repo.Create(&models.Role{Name: "admin", Permissions: []models.Permission{{ID: 10}}}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestRoleUpdate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	// パーミッションを空にした場合は紐づけを削除するのみ
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `roles` SET `description`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs("Administrators", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `role_permissions` WHERE role_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewRoleRepo(gdb)
+	if err := repo.Update(&models.Role{ID: 1, Name: "admin", Description: "Administrators"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRoleUpdateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `roles`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewRoleRepo(gdb)
+	if err := repo.Update(&models.Role{ID: 1}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestRoleDelete(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE name = \\?").
+		WithArgs("admin", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin"))
+	for _, table := range []string{"user_roles", "role_permissions"} {
+		mock.ExpectExec("DELETE FROM `" + table + "` WHERE role_id = \\?").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("DELETE FROM `roles` WHERE id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewRoleRepo(gdb)
+	if err := repo.Delete("admin"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRoleDeleteFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT \\* FROM `roles`").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		repo := NewRoleRepo(gdb)
+		if err := repo.Delete("admin"); !errors.Is(err, ErrRoleNotFound) {
+			t.Fatalf("expected ErrRoleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT \\* FROM `roles`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin"))
+		mock.ExpectExec("DELETE FROM `user_roles`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewRoleRepo(gdb)
+		err := repo.Delete("admin")
+		if err == nil || errors.Is(err, ErrRoleNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrUserConsentNotFound = errors.New("user consent not found")
+
+type UserConsentRepoInterface interface {
+	Get(userID uint, clientID string) (*models.UserConsent, error)
+	ListByUserID(userID uint) ([]models.UserConsent, error)
+	Save(consent *models.UserConsent) error
+	Delete(userID uint, clientID string) error
+}
+
+type UserConsentRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewUserConsentRepo(
+	db *gorm.DB,
+) *UserConsentRepoStruct {
+	return &UserConsentRepoStruct{
+		db: db,
+	}
+}
+
+func (r *UserConsentRepoStruct) Get(userID uint, clientID string) (*models.UserConsent, error) {
+	var consent models.UserConsent
+	if err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserConsentNotFound
+		}
+		return nil, fmt.Errorf("failed to get user consent: %w", err)
+	}
+	return &consent, nil
+}
+
+func (r *UserConsentRepoStruct) ListByUserID(userID uint) ([]models.UserConsent, error) {
+	var consents []models.UserConsent
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&consents).Error; err != nil {
+		return nil, fmt.Errorf("failed to list user consents: %w", err)
+	}
+	return consents, nil
+}
+
+// 同じクライアントへの同意が既にある場合はスコープを上書きする
+func (r *UserConsentRepoStruct) Save(consent *models.UserConsent) error {
+	if err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(consent).Error; err != nil {
+		return fmt.Errorf("failed to save user consent: %w", err)
+	}
+	return nil
+}
+
+// 同意を取り消し、そのクライアントに発行したユーザーのリフレッシュトークンも失効させる
+func (r *UserConsentRepoStruct) Delete(userID uint, clientID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&models.UserConsent{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete user consent: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrUserConsentNotFound
+		}
+
+		if err := tx.Model(&models.UserRefreshToken{}).
+			Where("user_id = ? AND client_id = ? AND is_used = ?", userID, clientID, false).
+			Update("is_used", true).Error; err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		return nil
+	})
+}
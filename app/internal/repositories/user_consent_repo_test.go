@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUserConsentGet(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `user_consents` WHERE user_id = \\? AND client_id = \\?").
+		WithArgs(1, "client", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "client_id", "scope"}).AddRow(1, 1, "client", "openid profile"))
+
+	repo := NewUserConsentRepo(gdb)
+	consent, err := repo.Get(1, "client")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if consent.Scope != "openid profile" {
+		t.Errorf("unexpected consent: %+v", consent)
+	}
+}
+
+func TestUserConsentGetFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT \\* FROM `user_consents`").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		repo := NewUserConsentRepo(gdb)
+		if _, err := repo.Get(1, "client"); !errors.Is(err, ErrUserConsentNotFound) {
+			t.Fatalf("expected ErrUserConsentNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT \\* FROM `user_consents`").
+			WillReturnError(sqlmock.ErrCancelled)
+
+		repo := NewUserConsentRepo(gdb)
+		_, err := repo.Get(1, "client")
+		if err == nil || errors.Is(err, ErrUserConsentNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
+
+func TestUserConsentListByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `user_consents` WHERE user_id = \\? ORDER BY id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "client_id", "scope"}).
+			AddRow(1, 1, "client-a", "openid").
+			AddRow(2, 1, "client-b", "profile"))
+
+	repo := NewUserConsentRepo(gdb)
+	consents, err := repo.ListByUserID(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(consents) != 2 || consents[1].ClientID != "client-b" {
+		t.Errorf("unexpected consents: %+v", consents)
+	}
+}
+
+func TestUserConsentListByUserIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `user_consents`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewUserConsentRepo(gdb)
+	if _, err := repo.ListByUserID(1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserConsentSave(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_consents` .* ON DUPLICATE KEY UPDATE `scope`=VALUES\\(`scope`\\),`updated_at`=VALUES\\(`updated_at`\\)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserConsentRepo(gdb)
+	if err := repo.Save(&models.UserConsent{UserID: 1, ClientID: "client", Scope: "openid"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserConsentSaveFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_consents`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserConsentRepo(gdb)
+	if err := repo.Save(&models.UserConsent{UserID: 1, ClientID: "client"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserConsentDelete(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_consents` WHERE user_id = \\? AND client_id = \\?").
+		WithArgs(1, "client").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `user_refresh_tokens` SET `is_used`=.*WHERE user_id = \\? AND client_id = \\? AND is_used = \\?").
+		WithArgs(true, sqlmock.AnyArg(), 1, "client", false).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewUserConsentRepo(gdb)
+	if err := repo.Delete(1, "client"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserConsentDeleteFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `user_consents`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		repo := NewUserConsentRepo(gdb)
+		if err := repo.Delete(1, "client"); !errors.Is(err, ErrUserConsentNotFound) {
+			t.Fatalf("expected ErrUserConsentNotFound, got %v", err)
+		}
+	})
+
+	t.Run("revoke error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `user_consents`").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `user_refresh_tokens`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewUserConsentRepo(gdb)
+		err := repo.Delete(1, "client")
+		if err == nil || errors.Is(err, ErrUserConsentNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
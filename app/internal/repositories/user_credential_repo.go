@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrCredentialNotFound = errors.New("credential not found")
+
+type UserCredentialRepoInterface interface {
+	Create(credential *models.UserCredential) error
+	GetByCredentialID(credentialId string) (*models.UserCredential, error)
+	ListByUserID(userId uint) ([]models.UserCredential, error)
+	UpdateSignCount(id uint, signCount uint32) error
+}
+
+type UserCredentialRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewUserCredentialRepo(
+	db *gorm.DB,
+) *UserCredentialRepoStruct {
+	return &UserCredentialRepoStruct{
+		db: db,
+	}
+}
+
+func (r *UserCredentialRepoStruct) Create(credential *models.UserCredential) error {
+	if err := r.db.Create(credential).Error; err != nil {
+		return fmt.Errorf("failed to create credential: %w", err)
+	}
+	return nil
+}
+
+func (r *UserCredentialRepoStruct) GetByCredentialID(credentialId string) (*models.UserCredential, error) {
+	var credential models.UserCredential
+	if err := r.db.Where("credential_id = ?", credentialId).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, fmt.Errorf("failed to get credential: %w", err)
+	}
+
+	return &credential, nil
+}
+
+func (r *UserCredentialRepoStruct) ListByUserID(userId uint) ([]models.UserCredential, error) {
+	var credentials []models.UserCredential
+	if err := r.db.Where("user_id = ?", userId).Order("id").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+
+	return credentials, nil
+}
+
+func (r *UserCredentialRepoStruct) UpdateSignCount(id uint, signCount uint32) error {
+	updates := map[string]any{
+		"sign_count":   signCount,
+		"last_used_at": time.Now(),
+	}
+
+	if err := r.db.Model(&models.UserCredential{}).
+		Where("id = ?", id).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update sign count: %w", err)
+	}
+	return nil
+}
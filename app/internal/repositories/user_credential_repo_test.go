@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUserCredentialCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_credentials`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserCredentialRepo(gdb)
+	credential := &models.UserCredential{
+		UserID:       1,
+		CredentialID: "credential-id",
+		PublicKey:    []byte{1, 2, 3},
+		Algorithm:    -7,
+	}
+	if err := repo.Create(credential); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if credential.ID != 1 {
+		t.Errorf("expected id 1, got %d", credential.ID)
+	}
+}
+
+func TestUserCredentialCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_credentials`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserCredentialRepo(gdb)
+	if err := repo.Create(&models.UserCredential{UserID: 1}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserCredentialGetByCredentialID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "credential_id", "public_key", "algorithm", "sign_count"}).
+		AddRow(1, 2, "credential-id", []byte{1, 2, 3}, -7, 10)
+	mock.ExpectQuery("SELECT .* FROM `user_credentials`.*WHERE credential_id = \\?").
+		WithArgs("credential-id", sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	repo := NewUserCredentialRepo(gdb)
+	result, err := repo.GetByCredentialID("credential-id")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.UserID != 2 || result.Algorithm != -7 || result.SignCount != 10 {
+		t.Errorf("unexpected credential: %+v", result)
+	}
+}
+
+func TestUserCredentialGetByCredentialIDNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `user_credentials`.*WHERE credential_id = \\?").
+		WithArgs("credential-id", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	repo := NewUserCredentialRepo(gdb)
+	if _, err := repo.GetByCredentialID("credential-id"); !errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("expected ErrCredentialNotFound, got %v", err)
+	}
+}
+
+func TestUserCredentialGetByCredentialIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `user_credentials`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewUserCredentialRepo(gdb)
+	_, err := repo.GetByCredentialID("credential-id")
+	if err == nil || errors.Is(err, ErrCredentialNotFound) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestUserCredentialListByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "credential_id"}).
+		AddRow(1, 2, "credential-1").
+		AddRow(2, 2, "credential-2")
+	mock.ExpectQuery("SELECT .* FROM `user_credentials` WHERE user_id = \\? ORDER BY id").
+		WithArgs(2).
+		WillReturnRows(rows)
+
+	repo := NewUserCredentialRepo(gdb)
+	result, err := repo.ListByUserID(2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(result) != 2 || result[1].CredentialID != "credential-2" {
+		t.Errorf("unexpected credentials: %+v", result)
+	}
+}
+
+func TestUserCredentialListByUserIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `user_credentials`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewUserCredentialRepo(gdb)
+	if _, err := repo.ListByUserID(2); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserCredentialUpdateSignCount(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_credentials` SET").
+		WithArgs(sqlmock.AnyArg(), uint32(11), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserCredentialRepo(gdb)
+	if err := repo.UpdateSignCount(1, 11); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserCredentialUpdateSignCountFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_credentials` SET").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserCredentialRepo(gdb)
+	if err := repo.UpdateSignCount(1, 11); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
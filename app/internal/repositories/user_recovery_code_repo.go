@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+
+type UserRecoveryCodeRepoInterface interface {
+	ReplaceAll(userId uint, codeHashes []string) error
+	Use(userId uint, codeHash string) error
+	DeleteByUserID(userId uint) error
+}
+
+type UserRecoveryCodeRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewUserRecoveryCodeRepo(
+	db *gorm.DB,
+) *UserRecoveryCodeRepoStruct {
+	return &UserRecoveryCodeRepoStruct{
+		db: db,
+	}
+}
+
+// 既存のコードを破棄して新しいコードに入れ替える
+func (r *UserRecoveryCodeRepoStruct) ReplaceAll(userId uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		codes := make([]models.UserRecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, models.UserRecoveryCode{
+				UserID:   userId,
+				CodeHash: hash,
+			})
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			return fmt.Errorf("failed to create recovery codes: %w", err)
+		}
+		return nil
+	})
+}
+
+// 未使用のコードのみ使用済みにできる
+func (r *UserRecoveryCodeRepoStruct) Use(userId uint, codeHash string) error {
+	result := r.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+func (r *UserRecoveryCodeRepoStruct) DeleteByUserID(userId uint) error {
+	if err := r.db.Where("user_id = ?", userId).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRecoveryCodeReplaceAll(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_recovery_codes` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec("INSERT INTO `user_recovery_codes`").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	if err := repo.ReplaceAll(1, []string{"hash1", "hash2"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRecoveryCodeReplaceAllFailDelete(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_recovery_codes`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	if err := repo.ReplaceAll(1, []string{"hash1"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestRecoveryCodeReplaceAllFailInsert(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_recovery_codes`").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `user_recovery_codes`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	if err := repo.ReplaceAll(1, []string{"hash1"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestRecoveryCodeUse(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_recovery_codes` SET `used_at`=.*WHERE user_id = \\? AND code_hash = \\? AND used_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, "hash1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	if err := repo.Use(1, "hash1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRecoveryCodeUseNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_recovery_codes` SET `used_at`=").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	if err := repo.Use(1, "hash1"); !errors.Is(err, ErrRecoveryCodeNotFound) {
+		t.Fatalf("expected ErrRecoveryCodeNotFound, got %v", err)
+	}
+}
+
+func TestRecoveryCodeUseFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_recovery_codes` SET `used_at`=").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	err := repo.Use(1, "hash1")
+	if err == nil || errors.Is(err, ErrRecoveryCodeNotFound) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestRecoveryCodeDeleteByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_recovery_codes` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectCommit()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	if err := repo.DeleteByUserID(1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRecoveryCodeDeleteByUserIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_recovery_codes`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserRecoveryCodeRepo(gdb)
+	if err := repo.DeleteByUserID(1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
@@ -9,10 +9,17 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	ErrRefreshTokenNotFound    = errors.New("refresh token not found")
+	ErrRefreshTokenAlreadyUsed = errors.New("refresh token already used")
+	ErrRefreshTokenExpired     = errors.New("refresh token expired")
+)
+
 type UserRefreshTokenRepoInterface interface {
-	CreateRefreshToken(userId uint) (*models.UserRefreshToken, error)
-	GetUserByRefreshToken(refreshToken string) (*models.User, error)
+	CreateRefreshToken(token *models.UserRefreshToken) (*models.UserRefreshToken, error)
+	GetUserByRefreshToken(refreshToken string) (*models.User, *models.UserRefreshToken, error)
 	ChangeUsed(refreshToken string, ipAddress string) error
+	RevokeAllByUserID(userId uint) error
 }
 
 type UserRefreshTokenRepoStruct struct {
@@ -27,23 +34,23 @@ func NewUserRefreshTokenRepo(
 	}
 }
 
-func (r *UserRefreshTokenRepoStruct) CreateRefreshToken(userId uint) (*models.UserRefreshToken, error) {
-	model := &models.UserRefreshToken{
-		UserID:       userId,
-		RefreshToken: models.CreateRefreshToken(),
-		ExpiresAt:    time.Now().Add(24 * time.Hour * 30),
+// トークンの値を採番して保存する。有効期限が未指定の場合は既定の有効期間を使う
+func (r *UserRefreshTokenRepoStruct) CreateRefreshToken(token *models.UserRefreshToken) (*models.UserRefreshToken, error) {
+	token.RefreshToken = models.CreateRefreshToken()
+	if token.ExpiresAt.IsZero() {
+		token.ExpiresAt = time.Now().Add(models.DefaultRefreshTokenLifetime)
 	}
-	if err := r.db.Create(model).Error; err != nil {
+	if err := r.db.Create(token).Error; err != nil {
 		return nil, err
 	}
-	return model, nil
+	return token, nil
 }
 
 func (r *UserRefreshTokenRepoStruct) getRefreshTokenl(refreshToken string) (*models.UserRefreshToken, error) {
 	var userRefreshToken models.UserRefreshToken
 	if err := r.db.Where("refresh_token = ?", refreshToken).First(&userRefreshToken).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("refresh token not found")
+			return nil, ErrRefreshTokenNotFound
 		}
 		return nil, fmt.Errorf("failed to get refresh token: %w", err)
 	}
@@ -51,29 +58,29 @@ func (r *UserRefreshTokenRepoStruct) getRefreshTokenl(refreshToken string) (*mod
 	return &userRefreshToken, nil
 }
 
-func (r *UserRefreshTokenRepoStruct) GetUserByRefreshToken(refreshToken string) (*models.User, error) {
+func (r *UserRefreshTokenRepoStruct) GetUserByRefreshToken(refreshToken string) (*models.User, *models.UserRefreshToken, error) {
 	var user models.User
 	userRefreshToken, err := r.getRefreshTokenl(refreshToken)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if userRefreshToken.IsUsed {
-		return nil, errors.New("refresh token already used")
+		return nil, nil, ErrRefreshTokenAlreadyUsed
 	}
 
 	if time.Now().After(userRefreshToken.ExpiresAt) {
-		return nil, errors.New("refresh token expired")
+		return nil, nil, ErrRefreshTokenExpired
 	}
 
 	if err := r.db.Where("id = ?", userRefreshToken.UserID).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, nil, ErrUserNotFound
 		}
-		return nil, fmt.Errorf("failed to get user by refresh token: %w", err)
+		return nil, nil, fmt.Errorf("failed to get user by refresh token: %w", err)
 	}
 
-	return &user, nil
+	return &user, userRefreshToken, nil
 }
 
 func (r *UserRefreshTokenRepoStruct) ChangeUsed(refreshToken string, ipAddress string) error {
@@ -95,3 +102,13 @@ func (r *UserRefreshTokenRepoStruct) ChangeUsed(refreshToken string, ipAddress s
 
 	return nil
 }
+
+// パスワード変更時などにユーザーの未使用リフレッシュトークンをすべて失効させる
+func (r *UserRefreshTokenRepoStruct) RevokeAllByUserID(userId uint) error {
+	if err := r.db.Model(&models.UserRefreshToken{}).
+		Where("user_id = ? AND is_used = ?", userId, false).
+		Update("is_used", true).Error; err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -19,7 +20,13 @@ func TestCreateRefreshToken(t *testing.T) {
 	mock.ExpectCommit()
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	result, err := repo.CreateRefreshToken(1)
+	authTime := time.Now()
+	result, err := repo.CreateRefreshToken(&models.UserRefreshToken{
+		UserID:   1,
+		FamilyID: "family-id",
+		AuthTime: &authTime,
+		Amr:      "pwd",
+	})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -27,6 +34,37 @@ func TestCreateRefreshToken(t *testing.T) {
 	if result.RefreshToken == "" {
 		t.Errorf("expected non-empty refresh token, got %q", result.RefreshToken)
 	}
+	if result.AuthTime == nil || result.Amr != "pwd" {
+		t.Errorf("expected auth context to be stored, got %v %q", result.AuthTime, result.Amr)
+	}
+	if expected := time.Now().Add(models.DefaultRefreshTokenLifetime); result.ExpiresAt.Before(expected.Add(-time.Minute)) || result.ExpiresAt.After(expected) {
+		t.Errorf("expected default lifetime, got %v", result.ExpiresAt)
+	}
+}
+
+func TestCreateRefreshTokenWithExpiresAt(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO .*user_refresh_tokens.*").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// クライアントごとの有効期間を指定した場合はそのまま使う
+	expiresAt := time.Now().Add(time.Hour)
+	repo := NewUserRefreshTokenRepo(gdb)
+	result, err := repo.CreateRefreshToken(&models.UserRefreshToken{
+		UserID:    1,
+		ClientID:  "client",
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !result.ExpiresAt.Equal(expiresAt) || result.ClientID != "client" {
+		t.Errorf("unexpected token: %+v", result)
+	}
 }
 
 func TestCreateRefreshTokenFailDbErr(t *testing.T) {
@@ -39,7 +77,7 @@ func TestCreateRefreshTokenFailDbErr(t *testing.T) {
 	mock.ExpectRollback()
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	_, err := repo.CreateRefreshToken(1)
+	_, err := repo.CreateRefreshToken(&models.UserRefreshToken{UserID: 1})
 	if err == nil {
 		t.Fatalf("expected error, got none")
 	}
@@ -84,8 +122,8 @@ func TestGetRefreshTokenFailNotFound(t *testing.T) {
 
 	repo := NewUserRefreshTokenRepo(gdb)
 	_, err := repo.getRefreshTokenl("non_existent_token")
-	if err == nil {
-		t.Fatalf("expected error, got none")
+	if !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Fatalf("expected ErrRefreshTokenNotFound, got %v", err)
 	}
 }
 
@@ -135,7 +173,7 @@ func TestGetUserByRefreshToken(t *testing.T) {
 	defer cleanup()
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	result, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
+	result, token, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -143,6 +181,9 @@ func TestGetUserByRefreshToken(t *testing.T) {
 	if result.ID != user.ID {
 		t.Errorf("expected user ID %v, got %v", user.ID, result.ID)
 	}
+	if token.RefreshToken != refreshToken.RefreshToken {
+		t.Errorf("expected refresh token %v, got %v", refreshToken.RefreshToken, token.RefreshToken)
+	}
 }
 
 func TestGetUserByRefreshTokenUserNotFound(t *testing.T) {
@@ -166,9 +207,9 @@ func TestGetUserByRefreshTokenUserNotFound(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash"}))
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	_, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
-	if err == nil {
-		t.Fatalf("expected error, got none")
+	_, _, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
 	}
 
 	defer cleanup()
@@ -183,7 +224,7 @@ func TestGetUserByRefreshTokenGetRefreshTokenlFail(t *testing.T) {
 		WillReturnError(sqlmock.ErrCancelled)
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	_, err := repo.GetUserByRefreshToken("invalid_token")
+	_, _, err := repo.GetUserByRefreshToken("invalid_token")
 	if err == nil {
 		t.Fatalf("expected error, got none")
 	}
@@ -208,9 +249,9 @@ func TestGetUserByRefreshTokenIsUsed(t *testing.T) {
 	defer cleanup()
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	_, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
-	if err == nil {
-		t.Fatalf("expected error, got none")
+	_, _, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
+	if !errors.Is(err, ErrRefreshTokenAlreadyUsed) {
+		t.Fatalf("expected ErrRefreshTokenAlreadyUsed, got %v", err)
 	}
 }
 
@@ -233,9 +274,9 @@ func TestGetUserByRefreshTokenExpired(t *testing.T) {
 	defer cleanup()
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	_, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
-	if err == nil {
-		t.Fatalf("expected error, got none")
+	_, _, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
+	if !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Fatalf("expected ErrRefreshTokenExpired, got %v", err)
 	}
 }
 
@@ -262,7 +303,7 @@ func TestGetUserByRefreshTokenGetUserFail(t *testing.T) {
 	defer cleanup()
 
 	repo := NewUserRefreshTokenRepo(gdb)
-	_, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
+	_, _, err := repo.GetUserByRefreshToken(refreshToken.RefreshToken)
 	if err == nil {
 		t.Fatalf("expected error, got none")
 	}
@@ -345,3 +386,34 @@ func TestChangeUsedFailUpdates(t *testing.T) {
 		t.Fatalf("expected error, got none")
 	}
 }
+
+func TestRevokeAllByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_refresh_tokens` SET `is_used`=\\?,`updated_at`=\\? WHERE user_id = \\? AND is_used = \\?").
+		WithArgs(true, sqlmock.AnyArg(), 1, false).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	repo := NewUserRefreshTokenRepo(gdb)
+	if err := repo.RevokeAllByUserID(1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRevokeAllByUserIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_refresh_tokens` SET").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserRefreshTokenRepo(gdb)
+	if err := repo.RevokeAllByUserID(1); err == nil {
+		t.Fatalf("expected error, got none")
+	}
+}
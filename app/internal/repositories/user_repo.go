@@ -5,12 +5,32 @@ import (
 	"fmt"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrDuplicateEmail = errors.New("email already exists")
+)
+
+// MySQL の一意制約違反（ER_DUP_ENTRY）
+const mysqlErrDuplicateEntry = 1062
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
+
 type UserRepoInterface interface {
 	Create(user *models.User) error
 	GetByEmail(email string) (*models.User, error)
+	GetByUUID(uuid string) (*models.User, error)
+	GetByID(id uint) (*models.User, error)
+	UpdatePassword(id uint, passwordHash string) error
+	UpdateEmail(id uint, email string) error
+	UpdateLocale(id uint, locale string) error
+	Delete(id uint) error
 }
 
 type UserRepoStruct struct {
@@ -30,6 +50,9 @@ func (r *UserRepoStruct) Create(user *models.User) error {
 	user.UUID = UUID
 
 	if err := r.db.Create(user).Error; err != nil {
+		if isDuplicateEntry(err) {
+			return ErrDuplicateEmail
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
@@ -39,10 +62,92 @@ func (r *UserRepoStruct) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user by email: %w", err)
 	}
 
 	return &user, nil
 }
+
+func (r *UserRepoStruct) GetByUUID(uuid string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("uuid = ?", uuid).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by uuid: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepoStruct) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("id = ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepoStruct) UpdatePassword(id uint, passwordHash string) error {
+	if err := r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("password_hash", passwordHash).Error; err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepoStruct) UpdateEmail(id uint, email string) error {
+	if err := r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("email", email).Error; err != nil {
+		if isDuplicateEntry(err) {
+			return ErrDuplicateEmail
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepoStruct) UpdateLocale(id uint, locale string) error {
+	if err := r.db.Model(&models.User{}).
+		Where("id = ?", id).
+		Update("locale", locale).Error; err != nil {
+		return fmt.Errorf("failed to update locale: %w", err)
+	}
+	return nil
+}
+
+// ユーザーに紐づくトークン・認証器もまとめて削除する
+func (r *UserRepoStruct) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		dependents := []any{
+			&models.UserRefreshToken{},
+			&models.UserTotp{},
+			&models.UserRecoveryCode{},
+			&models.UserCredential{},
+			&models.WebauthnChallenge{},
+			&models.PasswordlessToken{},
+			&models.OauthAuthorizationCode{},
+			&models.OauthDeviceCode{},
+			&models.UserConsent{},
+			&models.UserRole{},
+		}
+		for _, model := range dependents {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to delete user data: %w", err)
+			}
+		}
+
+		if err := tx.Where("id = ?", id).Delete(&models.User{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+}
@@ -2,11 +2,13 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 )
 
 func TestCreate(t *testing.T) {
@@ -49,6 +51,26 @@ func TestCreateFailDbErr(t *testing.T) {
 	}
 }
 
+func TestCreateFailDuplicateEmail(t *testing.T) {
+	user := &models.User{
+		PasswordHash: "hashed_password",
+		Email:        "example@example.com",
+	}
+
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO .*users.*").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'example@example.com' for key 'users.email'"})
+
+	defer cleanup()
+	mock.ExpectRollback()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.Create(user); !errors.Is(err, ErrDuplicateEmail) {
+		t.Fatalf("expected ErrDuplicateEmail, but got %v", err)
+	}
+}
+
 func TestUserRepoGetByEmail(t *testing.T) {
 	user := &models.User{
 		PasswordHash: "hashed_password",
@@ -97,7 +119,258 @@ func TestUserRepoGetByEmailFailNotFound(t *testing.T) {
 
 	repo := NewUserRepo(gdb)
 	_, err := repo.GetByEmail("notfound@example.com")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, but got %v", err)
+	}
+}
+
+func TestUserRepoGetByUUID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "email"}).
+		AddRow(1, "test-uuid", "example@example.com")
+	mock.ExpectQuery("SELECT .* FROM `users`.*WHERE uuid = \\?").
+		WithArgs("test-uuid", sqlmock.AnyArg()).
+		WillReturnRows(rows)
+	defer cleanup()
+
+	repo := NewUserRepo(gdb)
+	result, err := repo.GetByUUID("test-uuid")
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	if result.UUID != "test-uuid" {
+		t.Errorf("expected uuid %v, but got %v", "test-uuid", result.UUID)
+	}
+}
+
+func TestUserRepoGetByUUIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	mock.ExpectQuery("SELECT .* FROM `users`.*WHERE uuid = \\?").
+		WithArgs("dberror-uuid", sqlmock.AnyArg()).
+		WillReturnError(sql.ErrConnDone)
+	defer cleanup()
+
+	repo := NewUserRepo(gdb)
+	_, err := repo.GetByUUID("dberror-uuid")
+	if err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+}
+
+func TestUserRepoGetByUUIDFailNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	mock.ExpectQuery("SELECT .* FROM `users`.*WHERE uuid = \\?").
+		WithArgs("notfound-uuid", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "email"}))
+	defer cleanup()
+
+	repo := NewUserRepo(gdb)
+	_, err := repo.GetByUUID("notfound-uuid")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, but got %v", err)
+	}
+}
+
+func TestUserRepoGetByID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "email"}).
+		AddRow(1, "test-uuid", "example@example.com")
+	mock.ExpectQuery("SELECT .* FROM `users`.*WHERE id = \\?").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(rows)
+	defer cleanup()
+
+	repo := NewUserRepo(gdb)
+	result, err := repo.GetByID(1)
+	if err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+
+	if result.ID != 1 {
+		t.Errorf("expected id %v, but got %v", 1, result.ID)
+	}
+}
+
+func TestUserRepoGetByIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	mock.ExpectQuery("SELECT .* FROM `users`.*WHERE id = \\?").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnError(sql.ErrConnDone)
+	defer cleanup()
+
+	repo := NewUserRepo(gdb)
+	_, err := repo.GetByID(1)
 	if err == nil {
 		t.Fatalf("expected error, but got none")
 	}
 }
+
+func TestUserRepoGetByIDFailNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	mock.ExpectQuery("SELECT .* FROM `users`.*WHERE id = \\?").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "email"}))
+	defer cleanup()
+
+	repo := NewUserRepo(gdb)
+	_, err := repo.GetByID(1)
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, but got %v", err)
+	}
+}
+
+func TestUserRepoUpdatePassword(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `password_hash`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs("new_hash", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.UpdatePassword(1, "new_hash"); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+}
+
+func TestUserRepoUpdatePasswordFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET").
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.UpdatePassword(1, "new_hash"); err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+}
+
+func TestUserRepoUpdateEmail(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `email`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs("new@example.com", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.UpdateEmail(1, "new@example.com"); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+}
+
+func TestUserRepoUpdateEmailFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET").
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.UpdateEmail(1, "new@example.com"); err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+}
+
+func TestUserRepoUpdateEmailFailDuplicateEmail(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET").
+		WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mock.ExpectRollback()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.UpdateEmail(1, "new@example.com"); !errors.Is(err, ErrDuplicateEmail) {
+		t.Fatalf("expected ErrDuplicateEmail, but got %v", err)
+	}
+}
+
+func TestUserRepoUpdateLocale(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `locale`=\\?,`updated_at`=\\? WHERE id = \\?").
+		WithArgs("ja", sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.UpdateLocale(1, "ja"); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+}
+
+func TestUserRepoUpdateLocaleFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET").
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.UpdateLocale(1, "ja"); err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+}
+
+func TestUserRepoDelete(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	for _, table := range []string{
+		"user_refresh_tokens",
+		"user_totps",
+		"user_recovery_codes",
+		"user_credentials",
+		"webauthn_challenges",
+		"passwordless_tokens",
+		"oauth_authorization_codes",
+		"oauth_device_codes",
+		"user_consents",
+		"user_roles",
+	} {
+		mock.ExpectExec("DELETE FROM `" + table + "` WHERE user_id = \\?").
+			WithArgs(1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec("DELETE FROM `users` WHERE id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.Delete(1); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+}
+
+func TestUserRepoDeleteFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_refresh_tokens`").
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	repo := NewUserRepo(gdb)
+	if err := repo.Delete(1); err == nil {
+		t.Fatalf("expected error, but got none")
+	}
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrUserRoleNotFound = errors.New("user role not found")
+
+type UserRoleRepoInterface interface {
+	ListRoles(userID uint) ([]models.Role, error)
+	Assign(userID uint, roleID uint) error
+	Unassign(userID uint, roleID uint) error
+}
+
+type UserRoleRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewUserRoleRepo(
+	db *gorm.DB,
+) *UserRoleRepoStruct {
+	return &UserRoleRepoStruct{
+		db: db,
+	}
+}
+
+// ユーザーに割り当てたロールを、パーミッションとあわせて返す
+func (r *UserRoleRepoStruct) ListRoles(userID uint) ([]models.Role, error) {
+	roles := []models.Role{}
+	if err := r.db.Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.id").
+		Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list user roles: %w", err)
+	}
+	return roles, nil
+}
+
+// 割り当て済みの場合は何もしない
+func (r *UserRoleRepoStruct) Assign(userID uint, roleID uint) error {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.UserRole{UserID: userID, RoleID: roleID}).Error; err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRoleRepoStruct) Unassign(userID uint, roleID uint) error {
+	result := r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unassign role: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserRoleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUserRoleListRoles(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `roles`.`id`,.* FROM `roles` JOIN user_roles ON user_roles.role_id = roles.id WHERE user_roles.user_id = \\? ORDER BY roles.id").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "admin"))
+	expectRolePermissionsPreload(mock)
+
+	repo := NewUserRoleRepo(gdb)
+	roles, err := repo.ListRoles(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(roles) != 1 || roles[0].Name != "admin" || len(roles[0].PermissionNames()) != 2 {
+		t.Errorf("unexpected roles: %+v", roles)
+	}
+}
+
+func TestUserRoleListRolesFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `roles`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewUserRoleRepo(gdb)
+	if _, err := repo.ListRoles(1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserRoleAssign(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_roles` .* ON DUPLICATE KEY UPDATE").
+		WithArgs(1, 2, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserRoleRepo(gdb)
+	if err := repo.Assign(1, 2); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserRoleAssignFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_roles`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserRoleRepo(gdb)
+	if err := repo.Assign(1, 2); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserRoleUnassign(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_roles` WHERE user_id = \\? AND role_id = \\?").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserRoleRepo(gdb)
+	if err := repo.Unassign(1, 2); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserRoleUnassignFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `user_roles`").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		repo := NewUserRoleRepo(gdb)
+		if err := repo.Unassign(1, 2); !errors.Is(err, ErrUserRoleNotFound) {
+			t.Fatalf("expected ErrUserRoleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `user_roles`").
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		repo := NewUserRoleRepo(gdb)
+		err := repo.Unassign(1, 2)
+		if err == nil || errors.Is(err, ErrUserRoleNotFound) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTotpNotFound        = errors.New("totp not found")
+	ErrTotpStepAlreadyUsed = errors.New("totp code already used")
+)
+
+type UserTotpRepoInterface interface {
+	GetByUserID(userId uint) (*models.UserTotp, error)
+	Save(totp *models.UserTotp) error
+	Enable(id uint, step int64) error
+	MarkStepUsed(id uint, step int64) error
+	DeleteByUserID(userId uint) error
+}
+
+type UserTotpRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewUserTotpRepo(
+	db *gorm.DB,
+) *UserTotpRepoStruct {
+	return &UserTotpRepoStruct{
+		db: db,
+	}
+}
+
+func (r *UserTotpRepoStruct) GetByUserID(userId uint) (*models.UserTotp, error) {
+	var totp models.UserTotp
+	if err := r.db.Where("user_id = ?", userId).First(&totp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTotpNotFound
+		}
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+
+	return &totp, nil
+}
+
+func (r *UserTotpRepoStruct) Save(totp *models.UserTotp) error {
+	if err := r.db.Save(totp).Error; err != nil {
+		return fmt.Errorf("failed to save totp: %w", err)
+	}
+	return nil
+}
+
+func (r *UserTotpRepoStruct) Enable(id uint, step int64) error {
+	updates := map[string]any{
+		"enabled":        true,
+		"last_used_step": step,
+		"confirmed_at":   time.Now(),
+	}
+
+	if err := r.db.Model(&models.UserTotp{}).
+		Where("id = ?", id).
+		Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	return nil
+}
+
+// 同じ time step のコードを二度使えないよう、より新しい step のときだけ更新する
+func (r *UserTotpRepoStruct) MarkStepUsed(id uint, step int64) error {
+	result := r.db.Model(&models.UserTotp{}).
+		Where("id = ? AND last_used_step < ?", id, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update totp step: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTotpStepAlreadyUsed
+	}
+	return nil
+}
+
+func (r *UserTotpRepoStruct) DeleteByUserID(userId uint) error {
+	if err := r.db.Where("user_id = ?", userId).Delete(&models.UserTotp{}).Error; err != nil {
+		return fmt.Errorf("failed to delete totp: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUserTotpGetByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	rows := sqlmock.NewRows([]string{"id", "user_id", "secret", "enabled", "last_used_step"}).
+		AddRow(1, 1, "SECRET", true, 100)
+	mock.ExpectQuery("SELECT .* FROM `user_totps`.*WHERE user_id = \\?").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	repo := NewUserTotpRepo(gdb)
+	result, err := repo.GetByUserID(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Secret != "SECRET" || !result.Enabled || result.LastUsedStep != 100 {
+		t.Errorf("unexpected totp: %+v", result)
+	}
+}
+
+func TestUserTotpGetByUserIDNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `user_totps`.*WHERE user_id = \\?").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	repo := NewUserTotpRepo(gdb)
+	_, err := repo.GetByUserID(1)
+	if !errors.Is(err, ErrTotpNotFound) {
+		t.Fatalf("expected ErrTotpNotFound, got %v", err)
+	}
+}
+
+func TestUserTotpGetByUserIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `user_totps`.*WHERE user_id = \\?").
+		WithArgs(1, sqlmock.AnyArg()).
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewUserTotpRepo(gdb)
+	_, err := repo.GetByUserID(1)
+	if err == nil || errors.Is(err, ErrTotpNotFound) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestUserTotpSave(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_totps`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserTotpRepo(gdb)
+	totp := &models.UserTotp{UserID: 1, Secret: "SECRET"}
+	if err := repo.Save(totp); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if totp.ID != 1 {
+		t.Errorf("expected id 1, got %d", totp.ID)
+	}
+}
+
+func TestUserTotpSaveFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `user_totps`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserTotpRepo(gdb)
+	if err := repo.Save(&models.UserTotp{UserID: 1, Secret: "SECRET"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserTotpEnable(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_totps` SET").
+		WithArgs(sqlmock.AnyArg(), true, int64(100), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserTotpRepo(gdb)
+	if err := repo.Enable(1, 100); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserTotpEnableFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_totps` SET").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserTotpRepo(gdb)
+	if err := repo.Enable(1, 100); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestUserTotpMarkStepUsed(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_totps` SET `last_used_step`=.*WHERE id = \\? AND last_used_step < \\?").
+		WithArgs(int64(101), sqlmock.AnyArg(), 1, int64(101)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserTotpRepo(gdb)
+	if err := repo.MarkStepUsed(1, 101); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserTotpMarkStepUsedAlreadyUsed(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_totps` SET `last_used_step`=").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	repo := NewUserTotpRepo(gdb)
+	if err := repo.MarkStepUsed(1, 100); !errors.Is(err, ErrTotpStepAlreadyUsed) {
+		t.Fatalf("expected ErrTotpStepAlreadyUsed, got %v", err)
+	}
+}
+
+func TestUserTotpMarkStepUsedFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user_totps` SET `last_used_step`=").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserTotpRepo(gdb)
+	err := repo.MarkStepUsed(1, 100)
+	if err == nil || errors.Is(err, ErrTotpStepAlreadyUsed) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestUserTotpDeleteByUserID(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_totps` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewUserTotpRepo(gdb)
+	if err := repo.DeleteByUserID(1); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestUserTotpDeleteByUserIDFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `user_totps` WHERE user_id = \\?").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewUserTotpRepo(gdb)
+	if err := repo.DeleteByUserID(1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrWebauthnChallengeNotFound = errors.New("webauthn challenge not found")
+
+type WebauthnChallengeRepoInterface interface {
+	Create(challenge *models.WebauthnChallenge) error
+	Consume(challengeHash string, ceremony string) (*models.WebauthnChallenge, error)
+}
+
+type WebauthnChallengeRepoStruct struct {
+	db *gorm.DB
+}
+
+func NewWebauthnChallengeRepo(
+	db *gorm.DB,
+) *WebauthnChallengeRepoStruct {
+	return &WebauthnChallengeRepoStruct{
+		db: db,
+	}
+}
+
+func (r *WebauthnChallengeRepoStruct) Create(challenge *models.WebauthnChallenge) error {
+	if err := r.db.Create(challenge).Error; err != nil {
+		return fmt.Errorf("failed to create webauthn challenge: %w", err)
+	}
+	return nil
+}
+
+// 有効期限内かつ未使用のチャレンジのみ使用済みにして返す
+func (r *WebauthnChallengeRepoStruct) Consume(challengeHash string, ceremony string) (*models.WebauthnChallenge, error) {
+	now := time.Now()
+	result := r.db.Model(&models.WebauthnChallenge{}).
+		Where("challenge_hash = ? AND ceremony = ? AND used_at IS NULL AND expires_at > ?", challengeHash, ceremony, now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to use webauthn challenge: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWebauthnChallengeNotFound
+	}
+
+	var challenge models.WebauthnChallenge
+	if err := r.db.Where("challenge_hash = ?", challengeHash).First(&challenge).Error; err != nil {
+		return nil, fmt.Errorf("failed to get webauthn challenge: %w", err)
+	}
+	return &challenge, nil
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/global_mock"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestWebauthnChallengeCreate(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `webauthn_challenges`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	repo := NewWebauthnChallengeRepo(gdb)
+	err := repo.Create(&models.WebauthnChallenge{
+		ChallengeHash: "hash",
+		Ceremony:      "webauthn.get",
+		ExpiresAt:     time.Now().Add(5 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestWebauthnChallengeCreateFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `webauthn_challenges`").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewWebauthnChallengeRepo(gdb)
+	if err := repo.Create(&models.WebauthnChallenge{}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestWebauthnChallengeConsume(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `webauthn_challenges` SET `used_at`=.*WHERE challenge_hash = \\? AND ceremony = \\? AND used_at IS NULL AND expires_at > \\?").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "hash", "webauthn.create", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT .* FROM `webauthn_challenges`.*WHERE challenge_hash = \\?").
+		WithArgs("hash", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "challenge_hash", "ceremony", "user_id"}).
+			AddRow(1, "hash", "webauthn.create", 2))
+
+	repo := NewWebauthnChallengeRepo(gdb)
+	result, err := repo.Consume("hash", "webauthn.create")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.UserID == nil || *result.UserID != 2 {
+		t.Errorf("unexpected challenge: %+v", result)
+	}
+}
+
+func TestWebauthnChallengeConsumeNotFound(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `webauthn_challenges` SET `used_at`=").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	repo := NewWebauthnChallengeRepo(gdb)
+	if _, err := repo.Consume("hash", "webauthn.get"); !errors.Is(err, ErrWebauthnChallengeNotFound) {
+		t.Fatalf("expected ErrWebauthnChallengeNotFound, got %v", err)
+	}
+}
+
+func TestWebauthnChallengeConsumeFailDbErr(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `webauthn_challenges` SET `used_at`=").
+		WillReturnError(sqlmock.ErrCancelled)
+	mock.ExpectRollback()
+
+	repo := NewWebauthnChallengeRepo(gdb)
+	_, err := repo.Consume("hash", "webauthn.get")
+	if err == nil || errors.Is(err, ErrWebauthnChallengeNotFound) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestWebauthnChallengeConsumeFailSelect(t *testing.T) {
+	gdb, mock, cleanup := global_mock.NewGormWithMock(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `webauthn_challenges` SET `used_at`=").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT .* FROM `webauthn_challenges`").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	repo := NewWebauthnChallengeRepo(gdb)
+	if _, err := repo.Consume("hash", "webauthn.get"); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
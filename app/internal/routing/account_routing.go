@@ -0,0 +1,23 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+)
+
+// パスワード・メールアドレスの変更と退会は、直近にログインしたセッションのみ許可する
+func (r *Routing) AccountRouting(
+	accountHandler handler.AccountHandlerInterface,
+) {
+	accountGroup := r.gin.Group("/account", r.middleware.Csrf, r.middleware.Auth, r.middleware.RequireScope(service.ScopeAccount), r.middleware.StepUp(middleware.StepUpOptions{
+		MaxAge: middleware.DefaultStepUpMaxAge,
+	}))
+	accountGroup.POST("/password", accountHandler.ChangePassword)
+	accountGroup.POST("/email", accountHandler.ChangeEmail)
+	accountGroup.DELETE("", accountHandler.DeleteAccount)
+
+	// 言語の設定は再認証を求めない
+	settingsGroup := r.gin.Group("/account", r.middleware.Csrf, r.middleware.Auth, r.middleware.RequireScope(service.ScopeAccount))
+	settingsGroup.POST("/locale", accountHandler.ChangeLocale)
+}
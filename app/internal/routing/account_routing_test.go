@@ -0,0 +1,96 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockAccountHandler struct{}
+
+func (m *MockAccountHandler) ChangePassword(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+func (m *MockAccountHandler) ChangeEmail(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "changed"})
+}
+
+func (m *MockAccountHandler) ChangeLocale(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "changed"})
+}
+
+func (m *MockAccountHandler) DeleteAccount(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+func TestAccountRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "POST",
+			Path:   "/account/password",
+		},
+		{
+			Method: "POST",
+			Path:   "/account/email",
+		},
+		{
+			Method: "DELETE",
+			Path:   "/account",
+		},
+		{
+			Method: "POST",
+			Path:   "/account/locale",
+		},
+	}
+
+	g := gin.Default()
+	var stepUpOpts middleware.StepUpOptions
+	var requiredScope string
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		Auth: func(c *gin.Context) {},
+		RequireScope: func(scope string) gin.HandlerFunc {
+			requiredScope = scope
+			return func(c *gin.Context) {}
+		},
+		StepUp: func(opts middleware.StepUpOptions) gin.HandlerFunc {
+			stepUpOpts = opts
+			return func(c *gin.Context) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+		},
+	})
+	r.AccountRouting(&MockAccountHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+
+	assert.Equal(t, service.ScopeAccount, requiredScope)
+
+	// ステップアップ認証を通過しない限りハンドラは呼ばれない
+	assert.Equal(t, middleware.DefaultStepUpMaxAge, stepUpOpts.MaxAge)
+	req := httptest.NewRequest(http.MethodDelete, "/account", nil)
+	req.Header.Set("X-CSRF-Token", "token")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// CSRF トークンがなければステップアップ認証より前に拒否する
+	req = httptest.NewRequest(http.MethodDelete, "/account", nil)
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 言語の設定はステップアップ認証を求めない
+	req = httptest.NewRequest(http.MethodPost, "/account/locale", nil)
+	req.Header.Set("X-CSRF-Token", "token")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,24 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+)
+
+func (r *Routing) AdminOauthClientRouting(
+	oauthClientHandler handler.OauthClientHandlerInterface,
+) {
+	// 管理 API は admin グループのファイアウォールと API キーで保護し、発行した secret はキャッシュさせない
+	// Cookie を使わず ADMIN_API_KEY の Bearer で認証するため CSRF の検証は付けない
+	adminGroup := r.gin.Group("/admin/oauth/clients",
+		r.middleware.FirewallGroup("admin"),
+		r.middleware.AdminAuth,
+		r.middleware.SecurityHeadersGroup(middleware.SecurityHeaderOptions{NoStore: true}),
+	)
+	adminGroup.GET("", oauthClientHandler.List)
+	adminGroup.POST("", oauthClientHandler.Create)
+	adminGroup.GET("/:client_id", oauthClientHandler.Get)
+	adminGroup.PUT("/:client_id", oauthClientHandler.Update)
+	adminGroup.DELETE("/:client_id", oauthClientHandler.Delete)
+	adminGroup.POST("/:client_id/secret", oauthClientHandler.RotateSecret)
+}
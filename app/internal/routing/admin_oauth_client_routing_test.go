@@ -0,0 +1,111 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockOauthClientHandler struct{}
+
+func (m *MockOauthClientHandler) List(c *gin.Context) {
+	c.JSON(200, gin.H{"clients": []any{}})
+}
+
+func (m *MockOauthClientHandler) Create(c *gin.Context) {
+	c.JSON(201, gin.H{"client_id": "client"})
+}
+
+func (m *MockOauthClientHandler) Get(c *gin.Context) {
+	c.JSON(200, gin.H{"client_id": "client"})
+}
+
+func (m *MockOauthClientHandler) Update(c *gin.Context) {
+	c.JSON(200, gin.H{"client_id": "client"})
+}
+
+func (m *MockOauthClientHandler) Delete(c *gin.Context) {
+	c.Status(204)
+}
+
+func (m *MockOauthClientHandler) RotateSecret(c *gin.Context) {
+	c.JSON(200, gin.H{"client_secret": "secret"})
+}
+
+func TestAdminOauthClientRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "GET",
+			Path:   "/admin/oauth/clients",
+		},
+		{
+			Method: "POST",
+			Path:   "/admin/oauth/clients",
+		},
+		{
+			Method: "GET",
+			Path:   "/admin/oauth/clients/:client_id",
+		},
+		{
+			Method: "PUT",
+			Path:   "/admin/oauth/clients/:client_id",
+		},
+		{
+			Method: "DELETE",
+			Path:   "/admin/oauth/clients/:client_id",
+		},
+		{
+			Method: "POST",
+			Path:   "/admin/oauth/clients/:client_id/secret",
+		},
+	}
+
+	var firewallGroup string
+	var securityHeaderOpts middleware.SecurityHeaderOptions
+	g := gin.Default()
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		FirewallGroup: func(group string) gin.HandlerFunc {
+			firewallGroup = group
+			return func(c *gin.Context) {}
+		},
+		SecurityHeadersGroup: func(opts middleware.SecurityHeaderOptions) gin.HandlerFunc {
+			securityHeaderOpts = opts
+			return func(c *gin.Context) {}
+		},
+		AdminAuth: func(c *gin.Context) {
+			if c.GetHeader("Authorization") != "Bearer admin-key" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+		},
+	})
+	r.AdminOauthClientRouting(&MockOauthClientHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+
+	assert.Equal(t, "admin", firewallGroup)
+	if !securityHeaderOpts.NoStore {
+		t.Error("expected client secrets not to be cached")
+	}
+
+	// 管理 API は API キーが必要
+	for authorization, status := range map[string]int{"": http.StatusUnauthorized, "Bearer admin-key": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/oauth/clients", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code)
+	}
+
+	// 更新系も CSRF トークンなしで API キーのみで呼べる
+	req := httptest.NewRequest(http.MethodPost, "/admin/oauth/clients", nil)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
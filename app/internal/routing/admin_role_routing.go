@@ -0,0 +1,32 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ロールの管理とユーザーへの割り当ては、OAuth クライアントの管理 API と同じく API キーで保護する
+// 割り当ての変更は、ユーザーが次にトークンをリフレッシュした時点で反映される
+// OAuth クライアントの管理 API と同じく CSRF の検証は付けない
+func (r *Routing) AdminRoleRouting(
+	roleHandler handler.RoleHandlerInterface,
+) {
+	adminMiddleware := []gin.HandlerFunc{
+		r.middleware.FirewallGroup("admin"),
+		r.middleware.AdminAuth,
+		r.middleware.SecurityHeadersGroup(middleware.SecurityHeaderOptions{NoStore: true}),
+	}
+
+	roleGroup := r.gin.Group("/admin/roles", adminMiddleware...)
+	roleGroup.GET("", roleHandler.List)
+	roleGroup.POST("", roleHandler.Create)
+	roleGroup.GET("/:name", roleHandler.Get)
+	roleGroup.PUT("/:name", roleHandler.Update)
+	roleGroup.DELETE("/:name", roleHandler.Delete)
+
+	userRoleGroup := r.gin.Group("/admin/users/:uuid/roles", adminMiddleware...)
+	userRoleGroup.GET("", roleHandler.ListUserRoles)
+	userRoleGroup.PUT("/:name", roleHandler.AssignUserRole)
+	userRoleGroup.DELETE("/:name", roleHandler.UnassignUserRole)
+}
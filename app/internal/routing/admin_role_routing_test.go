@@ -0,0 +1,127 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockRoleHandler struct{}
+
+func (m *MockRoleHandler) List(c *gin.Context) {
+	c.JSON(200, gin.H{"roles": []any{}})
+}
+
+func (m *MockRoleHandler) Create(c *gin.Context) {
+	c.JSON(201, gin.H{"name": "admin"})
+}
+
+func (m *MockRoleHandler) Get(c *gin.Context) {
+	c.JSON(200, gin.H{"name": "admin"})
+}
+
+func (m *MockRoleHandler) Update(c *gin.Context) {
+	c.JSON(200, gin.H{"name": "admin"})
+}
+
+func (m *MockRoleHandler) Delete(c *gin.Context) {
+	c.Status(204)
+}
+
+func (m *MockRoleHandler) ListUserRoles(c *gin.Context) {
+	c.JSON(200, gin.H{"roles": []any{}})
+}
+
+func (m *MockRoleHandler) AssignUserRole(c *gin.Context) {
+	c.Status(204)
+}
+
+func (m *MockRoleHandler) UnassignUserRole(c *gin.Context) {
+	c.Status(204)
+}
+
+func TestAdminRoleRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "GET",
+			Path:   "/admin/roles",
+		},
+		{
+			Method: "POST",
+			Path:   "/admin/roles",
+		},
+		{
+			Method: "GET",
+			Path:   "/admin/roles/:name",
+		},
+		{
+			Method: "PUT",
+			Path:   "/admin/roles/:name",
+		},
+		{
+			Method: "DELETE",
+			Path:   "/admin/roles/:name",
+		},
+		{
+			Method: "GET",
+			Path:   "/admin/users/:uuid/roles",
+		},
+		{
+			Method: "PUT",
+			Path:   "/admin/users/:uuid/roles/:name",
+		},
+		{
+			Method: "DELETE",
+			Path:   "/admin/users/:uuid/roles/:name",
+		},
+	}
+
+	var firewallGroup string
+	var securityHeaderOpts middleware.SecurityHeaderOptions
+	g := gin.Default()
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		FirewallGroup: func(group string) gin.HandlerFunc {
+			firewallGroup = group
+			return func(c *gin.Context) {}
+		},
+		SecurityHeadersGroup: func(opts middleware.SecurityHeaderOptions) gin.HandlerFunc {
+			securityHeaderOpts = opts
+			return func(c *gin.Context) {}
+		},
+		AdminAuth: func(c *gin.Context) {
+			if c.GetHeader("Authorization") != "Bearer admin-key" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+		},
+	})
+	r.AdminRoleRouting(&MockRoleHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+
+	assert.Equal(t, "admin", firewallGroup)
+	assert.True(t, securityHeaderOpts.NoStore)
+
+	// ロールの管理と割り当てはいずれも API キーが必要
+	for _, path := range []string{"/admin/roles", "/admin/users/user-uuid/roles"} {
+		for authorization, status := range map[string]int{"": http.StatusUnauthorized, "Bearer admin-key": http.StatusOK} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Authorization", authorization)
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, req)
+			assert.Equal(t, status, w.Code, path)
+		}
+	}
+
+	// 更新系も CSRF トークンなしで API キーのみで呼べる
+	req := httptest.NewRequest(http.MethodPost, "/admin/roles", nil)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
@@ -1,11 +1,20 @@
 package routing
 
-import "github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+)
 
 func (r *Routing) AuthRouting(
 	authHandler handler.AuthHandlerInterface,
 ) {
-	authGroup := r.gin.Group("/auth")
+	// トークンを返すレスポンスはキャッシュさせない（RFC 6749 5.1）
+	// リクエストボディの資格情報でトークンを払い出すため CSRF の検証は付けない
+	// ネイティブアプリやサーバー間連携が /csrf/get を経由せずに呼べるようにする
+	authGroup := r.gin.Group("/auth", r.middleware.SecurityHeadersGroup(middleware.SecurityHeaderOptions{NoStore: true}))
 	authGroup.POST("/login", authHandler.Login)
 	authGroup.POST("/refresh", authHandler.Refresh)
+	authGroup.POST("/mfa/verify", authHandler.VerifyMfa)
+	authGroup.POST("/webauthn/login/finish", authHandler.LoginWithPasskey)
+	authGroup.POST("/passwordless/complete", authHandler.CompletePasswordless)
 }
@@ -1,10 +1,14 @@
 package routing
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
 )
 
 type MockAuthHandler struct{}
@@ -16,6 +20,19 @@ func (m *MockAuthHandler) Login(c *gin.Context) {
 func (m *MockAuthHandler) Refresh(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "token refreshed"})
 }
+
+func (m *MockAuthHandler) VerifyMfa(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "mfa verified"})
+}
+
+func (m *MockAuthHandler) LoginWithPasskey(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "logged in with passkey"})
+}
+
+func (m *MockAuthHandler) CompletePasswordless(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "logged in without password"})
+}
+
 func TestAuthRouting(t *testing.T) {
 	expected := []funcs.ExpectedRoute{
 		{
@@ -26,11 +43,42 @@ func TestAuthRouting(t *testing.T) {
 			Method: "POST",
 			Path:   "/auth/refresh",
 		},
+		{
+			Method: "POST",
+			Path:   "/auth/mfa/verify",
+		},
+		{
+			Method: "POST",
+			Path:   "/auth/webauthn/login/finish",
+		},
+		{
+			Method: "POST",
+			Path:   "/auth/passwordless/complete",
+		},
 	}
 
+	var securityHeaderOpts middleware.SecurityHeaderOptions
 	g := gin.Default()
-	r := NewRouting(g, nil)
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		SecurityHeadersGroup: func(opts middleware.SecurityHeaderOptions) gin.HandlerFunc {
+			securityHeaderOpts = opts
+			return func(c *gin.Context) {}
+		},
+	})
 	r.AuthRouting(&MockAuthHandler{})
 
 	funcs.EachExepectedRoute(expected, g, t)
+
+	if !securityHeaderOpts.NoStore {
+		t.Error("expected token responses not to be cached")
+	}
+
+	// CSRF トークンなしで呼べる
+	for _, route := range expected {
+		req := httptest.NewRequest(route.Method, route.Path, nil)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, route.Path)
+	}
 }
@@ -0,0 +1,20 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+)
+
+// 他のサービスからの認可判定。authz グループのファイアウォールと API キーで保護し、判定結果はキャッシュさせない
+// AUTHZ_API_KEY の Bearer で呼ぶため CSRF の検証は付けない
+func (r *Routing) AuthzRouting(
+	authzHandler handler.AuthzHandlerInterface,
+) {
+	authzGroup := r.gin.Group("/authz",
+		r.middleware.FirewallGroup("authz"),
+		r.middleware.AuthzAuth,
+		r.middleware.SecurityHeadersGroup(middleware.SecurityHeaderOptions{NoStore: true}),
+	)
+	authzGroup.POST("/check", authzHandler.Check)
+	authzGroup.POST("/check/batch", authzHandler.CheckBatch)
+}
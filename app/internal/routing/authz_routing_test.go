@@ -0,0 +1,69 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockAuthzHandler struct{}
+
+func (m *MockAuthzHandler) Check(c *gin.Context) {
+	c.JSON(200, gin.H{"allowed": true})
+}
+
+func (m *MockAuthzHandler) CheckBatch(c *gin.Context) {
+	c.JSON(200, gin.H{"results": []any{}})
+}
+
+func TestAuthzRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "POST",
+			Path:   "/authz/check",
+		},
+		{
+			Method: "POST",
+			Path:   "/authz/check/batch",
+		},
+	}
+
+	var firewallGroup string
+	var securityHeaderOpts middleware.SecurityHeaderOptions
+	g := gin.Default()
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		FirewallGroup: func(group string) gin.HandlerFunc {
+			firewallGroup = group
+			return func(c *gin.Context) {}
+		},
+		SecurityHeadersGroup: func(opts middleware.SecurityHeaderOptions) gin.HandlerFunc {
+			securityHeaderOpts = opts
+			return func(c *gin.Context) {}
+		},
+		AuthzAuth: func(c *gin.Context) {
+			if c.GetHeader("Authorization") != "Bearer authz-key" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+		},
+	})
+	r.AuthzRouting(&MockAuthzHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+
+	assert.Equal(t, "authz", firewallGroup)
+	assert.True(t, securityHeaderOpts.NoStore)
+
+	for authorization, status := range map[string]int{"": http.StatusUnauthorized, "Bearer authz-key": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/authz/check", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code)
+	}
+}
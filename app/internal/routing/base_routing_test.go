@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
@@ -16,3 +17,10 @@ func TestNewRouting(t *testing.T) {
 	assert.Equal(t, g, r.gin)
 	assert.Equal(t, m, r.middleware)
 }
+
+// CSRF の検証を付けたルートは、トークンがなければ 403 を返す
+func csrfStub(c *gin.Context) {
+	if c.GetHeader("X-CSRF-Token") == "" {
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
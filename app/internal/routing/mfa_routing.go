@@ -0,0 +1,20 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+)
+
+func (r *Routing) MfaRouting(
+	mfaHandler handler.MfaHandlerInterface,
+) {
+	mfaGroup := r.gin.Group("/auth/mfa/totp", r.middleware.Csrf, r.middleware.Auth, r.middleware.RequireScope(service.ScopeMfa))
+	mfaGroup.POST("/enroll", mfaHandler.EnrollTotp)
+	mfaGroup.POST("/confirm", mfaHandler.ConfirmTotp)
+	// 2 要素認証の解除は、直前に 2 要素で認証したセッションのみ許可する
+	mfaGroup.POST("/disable", r.middleware.StepUp(middleware.StepUpOptions{
+		MaxAge:     middleware.DefaultStepUpMaxAge,
+		RequireMfa: true,
+	}), mfaHandler.DisableTotp)
+}
@@ -0,0 +1,99 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockMfaHandler struct{}
+
+func (m *MockMfaHandler) EnrollTotp(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "enrolled"})
+}
+
+func (m *MockMfaHandler) ConfirmTotp(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "confirmed"})
+}
+
+func (m *MockMfaHandler) DisableTotp(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "disabled"})
+}
+
+func TestMfaRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "POST",
+			Path:   "/auth/mfa/totp/enroll",
+		},
+		{
+			Method: "POST",
+			Path:   "/auth/mfa/totp/confirm",
+		},
+		{
+			Method: "POST",
+			Path:   "/auth/mfa/totp/disable",
+		},
+	}
+
+	g := gin.Default()
+	var stepUpOpts middleware.StepUpOptions
+	var requiredScope string
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		RequireScope: func(scope string) gin.HandlerFunc {
+			requiredScope = scope
+			return func(c *gin.Context) {}
+		},
+		Auth: func(c *gin.Context) {
+			if c.GetHeader("Authorization") == "" {
+				c.AbortWithStatus(http.StatusUnauthorized)
+			}
+		},
+		StepUp: func(opts middleware.StepUpOptions) gin.HandlerFunc {
+			stepUpOpts = opts
+			return func(c *gin.Context) {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+		},
+	})
+	r.MfaRouting(&MockMfaHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+	assert.Equal(t, service.ScopeMfa, requiredScope)
+
+	// 認証ミドルウェアを通過しない限りハンドラは呼ばれない
+	req := httptest.NewRequest(http.MethodPost, "/auth/mfa/totp/enroll", nil)
+	req.Header.Set("X-CSRF-Token", "token")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 解除のみステップアップ認証を要求する
+	assert.True(t, stepUpOpts.RequireMfa)
+	req = httptest.NewRequest(http.MethodPost, "/auth/mfa/totp/disable", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-CSRF-Token", "token")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/mfa/totp/enroll", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-CSRF-Token", "token")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/mfa/totp/enroll", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,38 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+)
+
+func (r *Routing) OauthRouting(
+	oauthHandler handler.OauthHandlerInterface,
+) {
+	// 認可コード・トークンを返すレスポンスはキャッシュさせない（RFC 6749 5.1）
+	oauthGroup := r.gin.Group("/oauth", r.middleware.SecurityHeadersGroup(middleware.SecurityHeaderOptions{NoStore: true}))
+	oauthGroup.GET("/authorize", oauthHandler.BeginAuthorize)
+	// クライアント認証（Basic / client_secret_post）で呼ぶエンドポイントは CSRF の検証を付けない
+	oauthGroup.POST("/token", oauthHandler.Token)
+	oauthGroup.POST("/device_authorization", oauthHandler.DeviceAuthorization)
+
+	// 認可コードの発行とデバイスの承認はログイン済みユーザーのみ
+	csrf := r.middleware.Csrf
+	oauthScope := r.middleware.RequireScope(service.ScopeOauth)
+	oauthGroup.POST("/authorize", csrf, r.middleware.Auth, oauthScope, oauthHandler.Authorize)
+	oauthGroup.GET("/device", r.middleware.Auth, oauthScope, oauthHandler.GetDeviceVerification)
+	oauthGroup.POST("/device", csrf, r.middleware.Auth, oauthScope, oauthHandler.DecideDeviceVerification)
+
+	// 同意画面はブラウザで開く HTML のため、同意のチャレンジトークンでユーザーを特定する
+	// 許可後にクライアントへリダイレクトするため form-action は制限しない
+	consentGroup := oauthGroup.Group("/consent", r.middleware.SecurityHeadersGroup(middleware.SecurityHeaderOptions{
+		NoStore:               true,
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'; base-uri 'none'",
+	}))
+	consentGroup.GET("", oauthHandler.ConsentPage)
+	consentGroup.POST("", csrf, oauthHandler.DecideConsent)
+
+	// ユーザーが許可したクライアントの一覧と取り消し
+	oauthGroup.GET("/consents", r.middleware.Auth, oauthScope, oauthHandler.ListConsents)
+	oauthGroup.DELETE("/consents/:client_id", csrf, r.middleware.Auth, oauthScope, oauthHandler.RevokeConsent)
+}
@@ -0,0 +1,169 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockOauthHandler struct{}
+
+func (m *MockOauthHandler) BeginAuthorize(c *gin.Context) {
+	c.Redirect(http.StatusFound, "/login")
+}
+
+func (m *MockOauthHandler) Authorize(c *gin.Context) {
+	c.JSON(200, gin.H{"redirect_to": "/callback"})
+}
+
+func (m *MockOauthHandler) Token(c *gin.Context) {
+	c.JSON(200, gin.H{"access_token": "token"})
+}
+
+func (m *MockOauthHandler) DeviceAuthorization(c *gin.Context) {
+	c.JSON(200, gin.H{"device_code": "code"})
+}
+
+func (m *MockOauthHandler) GetDeviceVerification(c *gin.Context) {
+	c.JSON(200, gin.H{"client_id": "client"})
+}
+
+func (m *MockOauthHandler) DecideDeviceVerification(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+func (m *MockOauthHandler) ConsentPage(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+func (m *MockOauthHandler) DecideConsent(c *gin.Context) {
+	c.Redirect(http.StatusSeeOther, "/callback")
+}
+
+func (m *MockOauthHandler) ListConsents(c *gin.Context) {
+	c.JSON(200, gin.H{"consents": []string{}})
+}
+
+func (m *MockOauthHandler) RevokeConsent(c *gin.Context) {
+	c.Status(http.StatusNoContent)
+}
+
+func TestOauthRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "GET",
+			Path:   "/oauth/authorize",
+		},
+		{
+			Method: "POST",
+			Path:   "/oauth/authorize",
+		},
+		{
+			Method: "POST",
+			Path:   "/oauth/token",
+		},
+		{
+			Method: "POST",
+			Path:   "/oauth/device_authorization",
+		},
+		{
+			Method: "GET",
+			Path:   "/oauth/device",
+		},
+		{
+			Method: "POST",
+			Path:   "/oauth/device",
+		},
+		{
+			Method: "GET",
+			Path:   "/oauth/consent",
+		},
+		{
+			Method: "POST",
+			Path:   "/oauth/consent",
+		},
+		{
+			Method: "GET",
+			Path:   "/oauth/consents",
+		},
+		{
+			Method: "DELETE",
+			Path:   "/oauth/consents/:client_id",
+		},
+	}
+
+	securityHeaderOpts := []middleware.SecurityHeaderOptions{}
+	var requiredScope string
+	g := gin.Default()
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		SecurityHeadersGroup: func(opts middleware.SecurityHeaderOptions) gin.HandlerFunc {
+			securityHeaderOpts = append(securityHeaderOpts, opts)
+			return func(c *gin.Context) {}
+		},
+		Auth: func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		},
+		RequireScope: func(scope string) gin.HandlerFunc {
+			requiredScope = scope
+			return func(c *gin.Context) {}
+		},
+	})
+	r.OauthRouting(&MockOauthHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+	assert.Equal(t, service.ScopeOauth, requiredScope)
+
+	if len(securityHeaderOpts) != 2 || !securityHeaderOpts[0].NoStore {
+		t.Fatalf("expected token responses not to be cached: %+v", securityHeaderOpts)
+	}
+	// 同意画面のみ HTML を返す
+	if !securityHeaderOpts[1].NoStore || !strings.Contains(securityHeaderOpts[1].ContentSecurityPolicy, "frame-ancestors 'none'") {
+		t.Errorf("unexpected consent page headers: %+v", securityHeaderOpts[1])
+	}
+
+	// 認可コードの発行、デバイスの承認と同意の管理はログインが必要
+	// 同意画面はブラウザで開くため、アクセストークンではなくチャレンジトークンで認可する
+	for route, status := range map[string]int{
+		"GET /oauth/authorize":                      http.StatusFound,
+		"POST /oauth/authorize":                     http.StatusUnauthorized,
+		"POST /oauth/token":                         http.StatusOK,
+		"POST /oauth/device_authorization":          http.StatusOK,
+		"GET /oauth/device":                         http.StatusUnauthorized,
+		"POST /oauth/device":                        http.StatusUnauthorized,
+		"GET /oauth/consent":                        http.StatusOK,
+		"POST /oauth/consent":                       http.StatusSeeOther,
+		"GET /oauth/consents":                       http.StatusUnauthorized,
+		"DELETE /oauth/consents/third-party-client": http.StatusUnauthorized,
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-CSRF-Token", "token")
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, route)
+	}
+
+	// クライアント認証で呼ぶエンドポイント以外の更新系は CSRF トークンが必要
+	for route, status := range map[string]int{
+		"POST /oauth/authorize":                     http.StatusForbidden,
+		"POST /oauth/token":                         http.StatusOK,
+		"POST /oauth/device_authorization":          http.StatusOK,
+		"POST /oauth/device":                        http.StatusForbidden,
+		"POST /oauth/consent":                       http.StatusForbidden,
+		"DELETE /oauth/consents/third-party-client": http.StatusForbidden,
+	} {
+		method, path, _ := strings.Cut(route, " ")
+		req := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, route)
+	}
+}
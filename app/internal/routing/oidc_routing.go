@@ -0,0 +1,21 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+)
+
+func (r *Routing) OidcRouting(
+	oidcHandler handler.OidcHandlerInterface,
+) {
+	wellKnownGroup := r.gin.Group("/.well-known")
+	wellKnownGroup.GET("/openid-configuration", oidcHandler.Discovery)
+	wellKnownGroup.GET("/jwks.json", oidcHandler.Jwks)
+
+	// UserInfo は GET・POST の両方を受け付ける（OpenID Connect Core 5.3.1）
+	// OAuth クライアントに発行したトークンで呼ぶため、openid スコープの確認はハンドラーで行う
+	// Bearer トークンで認証するため CSRF の検証は付けない
+	userinfoGroup := r.gin.Group("/userinfo", r.middleware.SecurityHeadersGroup(middleware.SecurityHeaderOptions{NoStore: true}), r.middleware.OauthAuth)
+	userinfoGroup.GET("", oidcHandler.UserInfo)
+	userinfoGroup.POST("", oidcHandler.UserInfo)
+}
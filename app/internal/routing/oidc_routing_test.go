@@ -0,0 +1,81 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockOidcHandler struct{}
+
+func (m *MockOidcHandler) Discovery(c *gin.Context) {
+	c.JSON(200, gin.H{"issuer": "http://localhost:8080"})
+}
+
+func (m *MockOidcHandler) Jwks(c *gin.Context) {
+	c.JSON(200, gin.H{"keys": []any{}})
+}
+
+func (m *MockOidcHandler) UserInfo(c *gin.Context) {
+	c.JSON(200, gin.H{"sub": "usertest-uuid"})
+}
+
+func TestOidcRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "GET",
+			Path:   "/.well-known/openid-configuration",
+		},
+		{
+			Method: "GET",
+			Path:   "/.well-known/jwks.json",
+		},
+		{
+			Method: "GET",
+			Path:   "/userinfo",
+		},
+		{
+			Method: "POST",
+			Path:   "/userinfo",
+		},
+	}
+
+	var securityHeaderOpts middleware.SecurityHeaderOptions
+	g := gin.Default()
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		SecurityHeadersGroup: func(opts middleware.SecurityHeaderOptions) gin.HandlerFunc {
+			securityHeaderOpts = opts
+			return func(c *gin.Context) {}
+		},
+		OauthAuth: func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		},
+	})
+	r.OidcRouting(&MockOidcHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+
+	if !securityHeaderOpts.NoStore {
+		t.Error("expected userinfo responses not to be cached")
+	}
+
+	// UserInfo のみアクセストークンが必要
+	for path, status := range map[string]int{"/.well-known/openid-configuration": http.StatusOK, "/userinfo": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code)
+	}
+
+	// POST も CSRF トークンではなくアクセストークンで認証する
+	req := httptest.NewRequest(http.MethodPost, "/userinfo", nil)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
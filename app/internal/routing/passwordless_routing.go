@@ -0,0 +1,11 @@
+package routing
+
+import "github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+
+func (r *Routing) PasswordlessRouting(
+	passwordlessHandler handler.PasswordlessHandlerInterface,
+) {
+	// ログイン前に呼ぶため CSRF の検証を付けない
+	passwordlessGroup := r.gin.Group("/auth/passwordless")
+	passwordlessGroup.POST("/start", passwordlessHandler.Start)
+}
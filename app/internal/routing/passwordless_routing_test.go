@@ -0,0 +1,29 @@
+package routing
+
+import (
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+)
+
+type MockPasswordlessHandler struct{}
+
+func (m *MockPasswordlessHandler) Start(c *gin.Context) {
+	c.JSON(202, gin.H{"message": "started"})
+}
+
+func TestPasswordlessRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "POST",
+			Path:   "/auth/passwordless/start",
+		},
+	}
+
+	g := gin.Default()
+	r := NewRouting(g, nil)
+	r.PasswordlessRouting(&MockPasswordlessHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+}
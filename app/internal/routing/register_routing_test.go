@@ -1,10 +1,14 @@
 package routing
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
 )
 
 type MockRgisterHandler struct{}
@@ -19,8 +23,16 @@ func TestRegisterRouting(t *testing.T) {
 	}
 
 	g := gin.Default()
-	r := NewRouting(g, nil)
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+	})
 	r.RegisterRouting(&MockRgisterHandler{})
 
 	funcs.EachExepectedRoute(expected, g, t)
+
+	// ブラウザから Cookie 付きで呼ぶため CSRF トークンが必要
+	req := httptest.NewRequest(http.MethodPost, "/register", nil)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
 }
@@ -0,0 +1,14 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+)
+
+// 他のユーザーの情報は、アクセストークンの permissions に users:read を含むユーザーのみ参照できる
+func (r *Routing) UserRouting(
+	roleHandler handler.RoleHandlerInterface,
+) {
+	userGroup := r.gin.Group("/users/:uuid", r.middleware.Auth, r.middleware.RequirePermission(service.PermissionUsersRead))
+	userGroup.GET("/roles", roleHandler.ListUserRoles)
+}
@@ -0,0 +1,44 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "GET",
+			Path:   "/users/:uuid/roles",
+		},
+	}
+
+	var requiredPermission string
+	g := gin.Default()
+	r := NewRouting(g, &middleware.Middleware{
+		Auth: func(c *gin.Context) {},
+		RequirePermission: func(permission string) gin.HandlerFunc {
+			requiredPermission = permission
+			return func(c *gin.Context) {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+		},
+	})
+	r.UserRouting(&MockRoleHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+
+	// パーミッションを持たない場合はハンドラは呼ばれない
+	assert.Equal(t, service.PermissionUsersRead, requiredPermission)
+	req := httptest.NewRequest(http.MethodGet, "/users/user-uuid/roles", nil)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
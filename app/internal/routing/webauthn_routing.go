@@ -0,0 +1,19 @@
+package routing
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/handler"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+)
+
+func (r *Routing) WebauthnRouting(
+	webauthnHandler handler.WebauthnHandlerInterface,
+) {
+	// ログインの開始は Cookie に依存しないため CSRF の検証を付けない
+	webauthnGroup := r.gin.Group("/auth/webauthn")
+	webauthnGroup.POST("/login/begin", webauthnHandler.LoginBegin)
+
+	// パスキーの登録はログイン済みユーザーのみ
+	registerGroup := webauthnGroup.Group("/register", r.middleware.Csrf, r.middleware.Auth, r.middleware.RequireScope(service.ScopeMfa))
+	registerGroup.POST("/begin", webauthnHandler.RegisterBegin)
+	registerGroup.POST("/finish", webauthnHandler.RegisterFinish)
+}
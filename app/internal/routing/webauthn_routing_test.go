@@ -0,0 +1,78 @@
+package routing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/middleware"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockWebauthnHandler struct{}
+
+func (m *MockWebauthnHandler) RegisterBegin(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "register begin"})
+}
+
+func (m *MockWebauthnHandler) RegisterFinish(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "register finish"})
+}
+
+func (m *MockWebauthnHandler) LoginBegin(c *gin.Context) {
+	c.JSON(200, gin.H{"message": "login begin"})
+}
+
+func TestWebauthnRouting(t *testing.T) {
+	expected := []funcs.ExpectedRoute{
+		{
+			Method: "POST",
+			Path:   "/auth/webauthn/login/begin",
+		},
+		{
+			Method: "POST",
+			Path:   "/auth/webauthn/register/begin",
+		},
+		{
+			Method: "POST",
+			Path:   "/auth/webauthn/register/finish",
+		},
+	}
+
+	g := gin.Default()
+	var requiredScope string
+	r := NewRouting(g, &middleware.Middleware{
+		Csrf: csrfStub,
+		Auth: func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		},
+		RequireScope: func(scope string) gin.HandlerFunc {
+			requiredScope = scope
+			return func(c *gin.Context) {}
+		},
+	})
+	r.WebauthnRouting(&MockWebauthnHandler{})
+
+	funcs.EachExepectedRoute(expected, g, t)
+	assert.Equal(t, service.ScopeMfa, requiredScope)
+
+	// 登録は CSRF トークンと認証が必須、ログイン開始はどちらも不要
+	req := httptest.NewRequest(http.MethodPost, "/auth/webauthn/register/begin", nil)
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/webauthn/register/begin", nil)
+	req.Header.Set("X-CSRF-Token", "token")
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/webauthn/login/begin", nil)
+	w = httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabencrypt"
+)
+
+var ErrEmailAlreadyInUse = errors.New("email is already in use")
+
+// パスワード・メールアドレスの変更や退会など、直前の再認証を求める操作と、言語の設定
+type AccountSvcInterface interface {
+	ChangePassword(input ChangePasswordInput) error
+	ChangeEmail(input ChangeEmailInput) (*models.User, error)
+	ChangeLocale(input ChangeLocaleInput) (*models.User, error)
+	DeleteAccount(userUUID string) error
+}
+
+type AccountSvcStruct struct {
+	encryptlib           atylabencrypt.EncryptPkgInterface
+	userRepo             repositories.UserRepoInterface
+	userRefreshTokenRepo repositories.UserRefreshTokenRepoInterface
+	passwordPolicy       PasswordPolicySvcInterface
+}
+
+func NewAccountSvc(
+	encryptlib atylabencrypt.EncryptPkgInterface,
+	userRepo repositories.UserRepoInterface,
+	userRefreshTokenRepo repositories.UserRefreshTokenRepoInterface,
+	passwordPolicy PasswordPolicySvcInterface,
+) *AccountSvcStruct {
+	return &AccountSvcStruct{
+		encryptlib:           encryptlib,
+		userRepo:             userRepo,
+		userRefreshTokenRepo: userRefreshTokenRepo,
+		passwordPolicy:       passwordPolicy,
+	}
+}
+
+type ChangePasswordInput struct {
+	UserUUID    string
+	NewPassword string
+}
+
+// 漏洩したパスワードで発行済みのセッションを使い続けられないよう、リフレッシュトークンを失効させる
+func (s *AccountSvcStruct) ChangePassword(input ChangePasswordInput) error {
+	user, err := s.userRepo.GetByUUID(input.UserUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.passwordPolicy.Validate(PasswordPolicyInput{
+		Password: input.NewPassword,
+		Email:    user.Email,
+		Username: user.Username,
+	}); err != nil {
+		return err
+	}
+
+	hashedPassword, err := s.encryptlib.CreatePasswordHash(NormalizePassword(input.NewPassword))
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(user.ID, hashedPassword); err != nil {
+		return err
+	}
+
+	return s.userRefreshTokenRepo.RevokeAllByUserID(user.ID)
+}
+
+type ChangeEmailInput struct {
+	UserUUID string
+	Email    string
+}
+
+func (s *AccountSvcStruct) ChangeEmail(input ChangeEmailInput) (*models.User, error) {
+	email := strings.TrimSpace(strings.ToLower(input.Email))
+
+	user, err := s.userRepo.GetByUUID(input.UserUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	existing, err := s.userRepo.GetByEmail(email)
+	if err == nil && existing.ID != user.ID {
+		return nil, ErrEmailAlreadyInUse
+	}
+	if err != nil && !errors.Is(err, repositories.ErrUserNotFound) {
+		return nil, err
+	}
+
+	// 確認後に他のリクエストで同じアドレスが登録された場合も一意制約で検出する
+	if err := s.userRepo.UpdateEmail(user.ID, email); err != nil {
+		if errors.Is(err, repositories.ErrDuplicateEmail) {
+			return nil, ErrEmailAlreadyInUse
+		}
+		return nil, err
+	}
+
+	user.Email = email
+	return user, nil
+}
+
+type ChangeLocaleInput struct {
+	UserUUID string
+	// 空の場合は設定を消し、リクエストの Accept-Language に従う
+	Locale string
+}
+
+func (s *AccountSvcStruct) ChangeLocale(input ChangeLocaleInput) (*models.User, error) {
+	user, err := s.userRepo.GetByUUID(input.UserUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	locale := i18n.Normalize(input.Locale)
+	if err := s.userRepo.UpdateLocale(user.ID, locale); err != nil {
+		return nil, err
+	}
+
+	user.Locale = locale
+	return user, nil
+}
+
+func (s *AccountSvcStruct) DeleteAccount(userUUID string) error {
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return s.userRepo.Delete(user.ID)
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabencrypt"
+	"github.com/stretchr/testify/mock"
+)
+
+var testAccountUser = &models.User{
+	ID:       1,
+	UUID:     "test-uuid",
+	Username: "testuser",
+	Email:    "user@example.com",
+}
+
+func newTestAccountSvc() *AccountSvcStruct {
+	breachedRepoMock := new(repo_mock.BreachedPasswordRepoMock)
+	breachedRepoMock.On("IsBreached", mock.Anything).Return(false, nil)
+
+	// ChangeEmail・ChangeLocale で書き換わるためコピーを返す
+	user := *testAccountUser
+	userRepoMock := new(repo_mock.UserRepoMock)
+	userRepoMock.On("GetByUUID", "test-uuid").Return(&user, nil)
+
+	return NewAccountSvc(
+		new(atylabencrypt.EncryptPkgStructMock),
+		userRepoMock,
+		new(repo_mock.UserRefreshTokenRepoMock),
+		NewPasswordPolicySvc(PasswordPolicyConfig{
+			MinLength:   8,
+			MaxLength:   64,
+			MinStrength: 2,
+		}, breachedRepoMock),
+	)
+}
+
+func TestChangePassword(t *testing.T) {
+	svc := newTestAccountSvc()
+	svc.encryptlib.(*atylabencrypt.EncryptPkgStructMock).
+		On("CreatePasswordHash", "correct horse battery").Return("new_hash", nil)
+	userRepoMock := svc.userRepo.(*repo_mock.UserRepoMock)
+	userRepoMock.On("UpdatePassword", uint(1), "new_hash").Return(nil)
+	userRefreshTokenRepoMock := svc.userRefreshTokenRepo.(*repo_mock.UserRefreshTokenRepoMock)
+	userRefreshTokenRepoMock.On("RevokeAllByUserID", uint(1)).Return(nil)
+
+	err := svc.ChangePassword(ChangePasswordInput{
+		UserUUID:    "test-uuid",
+		NewPassword: "correct horse battery",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	userRepoMock.AssertExpectations(t)
+	userRefreshTokenRepoMock.AssertExpectations(t)
+}
+
+func TestChangePasswordPolicyViolation(t *testing.T) {
+	svc := newTestAccountSvc()
+
+	err := svc.ChangePassword(ChangePasswordInput{
+		UserUUID:    "test-uuid",
+		NewPassword: "short",
+	})
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected PasswordPolicyError, got %v", err)
+	}
+	svc.userRepo.(*repo_mock.UserRepoMock).AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+}
+
+func TestChangePasswordFail(t *testing.T) {
+	tests := map[string]func(svc *AccountSvcStruct){
+		"hash error": func(svc *AccountSvcStruct) {
+			svc.encryptlib.(*atylabencrypt.EncryptPkgStructMock).
+				On("CreatePasswordHash", mock.Anything).Return("", fmt.Errorf("hash error"))
+		},
+		"update error": func(svc *AccountSvcStruct) {
+			svc.encryptlib.(*atylabencrypt.EncryptPkgStructMock).
+				On("CreatePasswordHash", mock.Anything).Return("new_hash", nil)
+			svc.userRepo.(*repo_mock.UserRepoMock).On("UpdatePassword", uint(1), "new_hash").Return(fmt.Errorf("db error"))
+		},
+		"revoke error": func(svc *AccountSvcStruct) {
+			svc.encryptlib.(*atylabencrypt.EncryptPkgStructMock).
+				On("CreatePasswordHash", mock.Anything).Return("new_hash", nil)
+			svc.userRepo.(*repo_mock.UserRepoMock).On("UpdatePassword", uint(1), "new_hash").Return(nil)
+			svc.userRefreshTokenRepo.(*repo_mock.UserRefreshTokenRepoMock).
+				On("RevokeAllByUserID", uint(1)).Return(fmt.Errorf("db error"))
+		},
+	}
+
+	for title, setup := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestAccountSvc()
+			setup(svc)
+
+			err := svc.ChangePassword(ChangePasswordInput{
+				UserUUID:    "test-uuid",
+				NewPassword: "correct horse battery",
+			})
+			if err == nil {
+				t.Fatal("expected error, got none")
+			}
+		})
+	}
+}
+
+func TestChangePasswordUserNotFound(t *testing.T) {
+	svc := newTestAccountSvc()
+
+	userRepoMock := new(repo_mock.UserRepoMock)
+	userRepoMock.On("GetByUUID", "unknown").Return((*models.User)(nil), repositories.ErrUserNotFound)
+	svc.userRepo = userRepoMock
+
+	err := svc.ChangePassword(ChangePasswordInput{UserUUID: "unknown", NewPassword: "correct horse battery"})
+	if !errors.Is(err, repositories.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	svc := newTestAccountSvc()
+	userRepoMock := svc.userRepo.(*repo_mock.UserRepoMock)
+	userRepoMock.On("GetByEmail", "new@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+	userRepoMock.On("UpdateEmail", uint(1), "new@example.com").Return(nil)
+
+	user, err := svc.ChangeEmail(ChangeEmailInput{UserUUID: "test-uuid", Email: " New@Example.com "})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if user.Email != "new@example.com" {
+		t.Errorf("expected new@example.com, got %s", user.Email)
+	}
+	userRepoMock.AssertExpectations(t)
+}
+
+func TestChangeEmailFail(t *testing.T) {
+	tests := map[string]struct {
+		setup    func(m *repo_mock.UserRepoMock)
+		expected error
+	}{
+		"already in use": {
+			func(m *repo_mock.UserRepoMock) {
+				m.On("GetByEmail", "new@example.com").Return(&models.User{ID: 2}, nil)
+			},
+			ErrEmailAlreadyInUse,
+		},
+		"lookup error": {
+			func(m *repo_mock.UserRepoMock) {
+				m.On("GetByEmail", "new@example.com").Return((*models.User)(nil), fmt.Errorf("db error"))
+			},
+			nil,
+		},
+		"registered concurrently": {
+			func(m *repo_mock.UserRepoMock) {
+				m.On("GetByEmail", "new@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+				m.On("UpdateEmail", uint(1), "new@example.com").Return(repositories.ErrDuplicateEmail)
+			},
+			ErrEmailAlreadyInUse,
+		},
+		"update error": {
+			func(m *repo_mock.UserRepoMock) {
+				m.On("GetByEmail", "new@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+				m.On("UpdateEmail", uint(1), "new@example.com").Return(fmt.Errorf("db error"))
+			},
+			nil,
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestAccountSvc()
+			tt.setup(svc.userRepo.(*repo_mock.UserRepoMock))
+
+			_, err := svc.ChangeEmail(ChangeEmailInput{UserUUID: "test-uuid", Email: "new@example.com"})
+			if err == nil {
+				t.Fatal("expected error, got none")
+			}
+			if tt.expected != nil && !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestChangeLocale(t *testing.T) {
+	tests := map[string]struct {
+		locale   string
+		expected string
+	}{
+		"supported locale":  {"ja-JP", "ja"},
+		"clear the setting": {"", ""},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestAccountSvc()
+			userRepoMock := svc.userRepo.(*repo_mock.UserRepoMock)
+			userRepoMock.On("UpdateLocale", uint(1), tt.expected).Return(nil)
+
+			user, err := svc.ChangeLocale(ChangeLocaleInput{UserUUID: "test-uuid", Locale: tt.locale})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if user.Locale != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, user.Locale)
+			}
+			userRepoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChangeLocaleFail(t *testing.T) {
+	svc := newTestAccountSvc()
+	userRepoMock := svc.userRepo.(*repo_mock.UserRepoMock)
+	userRepoMock.On("UpdateLocale", uint(1), "ja").Return(fmt.Errorf("db error"))
+
+	if _, err := svc.ChangeLocale(ChangeLocaleInput{UserUUID: "test-uuid", Locale: "ja"}); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	svc := newTestAccountSvc()
+	userRepoMock := svc.userRepo.(*repo_mock.UserRepoMock)
+	userRepoMock.On("Delete", uint(1)).Return(nil)
+
+	if err := svc.DeleteAccount("test-uuid"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	userRepoMock.AssertExpectations(t)
+}
+
+func TestDeleteAccountUserNotFound(t *testing.T) {
+	userRepoMock := new(repo_mock.UserRepoMock)
+	userRepoMock.On("GetByUUID", "unknown").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+	svc := newTestAccountSvc()
+	svc.userRepo = userRepoMock
+
+	if err := svc.DeleteAccount("unknown"); !errors.Is(err, repositories.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
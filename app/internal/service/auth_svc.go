@@ -1,106 +1,676 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
-	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabjwt"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabencrypt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// amr クレームの値（RFC 8176）
+const (
+	AmrPwd = "pwd"
+	AmrOtp = "otp"
+	AmrMfa = "mfa"
+	AmrHwk = "hwk"
+	// RFC 8176 に該当する値がないため、メールによる本人確認は独自の値とする
+	AmrEmail = "email"
+)
+
+var (
+	// メールアドレスの登録有無を推測されないよう、未登録とパスワード誤りを区別しない
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// 許可されていないスコープを要求した場合
+	ErrInvalidScope = errors.New("invalid scope")
+)
+
+// アクセストークンの既定の有効期間（秒）
+const AccessTokenExpiresIn = 3600
+
+// アクセストークンの principal クレームの値。ユーザーとサービス間連携のクライアントを区別する
+const (
+	PrincipalUser   = "user"
+	PrincipalClient = "client"
+)
+
+// ファーストパーティのアクセストークンのスコープ。API のグループごとに分ける
+const (
+	// パスワード・メールアドレスの変更と退会
+	ScopeAccount = "account"
+	// 2 要素認証とパスキーの登録
+	ScopeMfa = "mfa"
+	// OAuth クライアントへの認可と同意の管理
+	ScopeOauth = "oauth"
+)
+
+type AuthConfig struct {
+	// アクセストークンの iss
+	Issuer string
+	// ファーストパーティのアクセストークンの aud
+	Audience string
+	// ログイン時に許可するファーストパーティのスコープ。scope パラメーターで絞り込める
+	Scopes []string
+}
+
+func NewAuthConfigFromEnv() AuthConfig {
+	config := AuthConfig{
+		Issuer:   oauthIssuerFromEnv(),
+		Audience: os.Getenv("AUTH_AUDIENCE"),
+		Scopes:   strings.Fields(os.Getenv("AUTH_SCOPES")),
+	}
+	if config.Audience == "" {
+		config.Audience = config.Issuer
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{ScopeAccount, ScopeMfa, ScopeOauth, OidcScopeOpenID, OidcScopeProfile, OidcScopeEmail}
+	}
+	return config
+}
+
+// acr クレームの値（NIST SP 800-63B の認証器保証レベル）
+const (
+	AcrAal1 = "aal1"
+	AcrAal2 = "aal2"
+)
+
+// いつ・どの方式で認証したか。アクセストークンの auth_time / amr / acr / sid クレームになる
+type AuthContext struct {
+	AuthTime time.Time
+	Amr      []string
+	// リフレッシュトークンのファミリー ID（空の場合はログイン時に採番する）
+	SessionID string
+	// 許可したスコープ。openid を含む場合は ID トークンも発行する
+	Scope string
+	// リフレッシュ時に絞り込んだ、アクセストークンのスコープ（空の場合は Scope と同じ）
+	AccessScope string
+	// 認可リクエストの nonce（認可コードの交換時のみ）
+	Nonce string
+}
+
+func (a AuthContext) Acr() string {
+	for _, method := range a.Amr {
+		if method == AmrMfa {
+			return AcrAal2
+		}
+	}
+	return AcrAal1
+}
+
+func (a AuthContext) accessScope() string {
+	if a.AccessScope != "" {
+		return a.AccessScope
+	}
+	return a.Scope
+}
+
+// アクセストークンのクレームから、トークンを発行したログインの認証時刻と方式を取り出す
+// JSON からパースしたクレームは数値が float64、配列が []any になる
+func AuthContextFromClaims(claims jwt.MapClaims) AuthContext {
+	authContext := AuthContext{}
+	switch v := claims["auth_time"].(type) {
+	case float64:
+		authContext.AuthTime = time.Unix(int64(v), 0)
+	case int64:
+		authContext.AuthTime = time.Unix(v, 0)
+	}
+	switch v := claims["amr"].(type) {
+	case []string:
+		authContext.Amr = v
+	case []any:
+		for _, method := range v {
+			if s, ok := method.(string); ok {
+				authContext.Amr = append(authContext.Amr, s)
+			}
+		}
+	}
+	return authContext
+}
+
 type AuthSvcInterface interface {
 	Login(input LoginInput) (*AuthOutput, error)
 	Refresh(input RefreshInput) (*AuthOutput, error)
+	VerifyMfa(input VerifyMfaInput) (*AuthOutput, error)
+	LoginWithPasskey(input WebauthnLoginInput) (*AuthOutput, error)
+	CompletePasswordless(input PasswordlessCompleteInput) (*AuthOutput, error)
+	ExchangeAuthorizationCode(client *models.OauthClient, input OauthTokenInput) (*AuthOutput, error)
+	ExchangeDeviceCode(client *models.OauthClient, deviceCode string) (*AuthOutput, error)
+	RefreshForClient(client *models.OauthClient, input RefreshInput) (*AuthOutput, error)
+	IssueClientCredentials(client *models.OauthClient, input OauthClientCredentialsInput) (*AuthOutput, error)
+	ExchangeToken(client *models.OauthClient, input OauthTokenExchangeInput) (*AuthOutput, error)
 }
 
 type AuthSvcStruct struct {
+	config               AuthConfig
 	userRepo             repositories.UserRepoInterface
 	userRefreshTokenRepo repositories.UserRefreshTokenRepoInterface
-	jwtlib               atylabjwt.JwtSvcInterface
+	encryptlib           atylabencrypt.EncryptPkgInterface
+	jwttoken             jwttoken.JwtTokenPkgInterface
 	clock                atylabclock.ClockInterface
+	mfa                  MfaSvcInterface
+	webauthn             WebauthnSvcInterface
+	passwordless         PasswordlessSvcInterface
+	oauth                OauthSvcInterface
+	oidc                 OidcSvcInterface
+	roles                RoleSvcInterface
 }
 
 func NewAuthSvc(
+	config AuthConfig,
 	userRepo repositories.UserRepoInterface,
 	userRefreshTokenRepo repositories.UserRefreshTokenRepoInterface,
-	jwtlib atylabjwt.JwtSvcInterface,
+	encryptlib atylabencrypt.EncryptPkgInterface,
+	jwttoken jwttoken.JwtTokenPkgInterface,
 	clock atylabclock.ClockInterface,
+	mfa MfaSvcInterface,
+	webauthn WebauthnSvcInterface,
+	passwordless PasswordlessSvcInterface,
+	oauth OauthSvcInterface,
+	oidc OidcSvcInterface,
+	roles RoleSvcInterface,
 ) *AuthSvcStruct {
 	return &AuthSvcStruct{
+		config:               config,
 		userRepo:             userRepo,
 		userRefreshTokenRepo: userRefreshTokenRepo,
-		jwtlib:               jwtlib,
+		encryptlib:           encryptlib,
+		jwttoken:             jwttoken,
 		clock:                clock,
+		mfa:                  mfa,
+		webauthn:             webauthn,
+		passwordless:         passwordless,
+		oauth:                oauth,
+		oidc:                 oidc,
+		roles:                roles,
 	}
 }
 
 type AuthOutput struct {
 	AccessToken  string
 	RefreshToken string
+	MfaRequired  bool
+	MfaToken     string
+	SessionID    string
+	// アクセストークンの有効期間（秒）
+	ExpiresIn int
+	// OAuth のトークンエンドポイントで返す、許可されたスコープ
+	Scope string
+	// openid スコープを許可した場合の ID トークン
+	IDToken string
+	// トークン交換で発行したトークンの種類（RFC 8693 2.2.1 issued_token_type）
+	IssuedTokenType string
 }
 
 type LoginInput struct {
 	Email    string
 	Password string
+	// 省略した場合は AuthConfig.Scopes のすべて
+	Scope string
 }
 
 func (s *AuthSvcStruct) Login(input LoginInput) (*AuthOutput, error) {
 	email := strings.TrimSpace(strings.ToLower(input.Email))
+	scope, err := narrowScope(input.Scope, s.config.Scopes)
+	if err != nil {
+		return nil, err
+	}
 
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
-		return nil, fmt.Errorf("invalid email: %w", err) // 本来は曖昧にするが、学習目的のため、分ける
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, fmt.Errorf("%w: email not found", ErrInvalidCredentials)
+		}
+		return nil, err
 	}
 
 	// パスワード検証
-	if err := user.VerifyPassword(input.Password); err != nil {
-		return nil, fmt.Errorf("invalid password: %w", err) // 本来は曖昧にするが、学習目的のため、分ける
+	if err := s.verifyPassword(user, input.Password); err != nil {
+		return nil, err
 	}
 
-	return s.createResponseToken(user)
+	return s.createResponseTokenOrMfaChallenge(user, AuthContext{Amr: []string{AmrPwd}, Scope: scope})
 }
 
-func (s *AuthSvcStruct) createResponseToken(user *models.User) (*AuthOutput, error) {
+// NFKC 正規化を導入する前のハッシュは入力そのままから作られているため、一致しない場合は正規化前の入力でも照合する
+// 正規化前の入力で一致した場合は、次回から正規化した入力で照合できるようハッシュを作り直す
+func (s *AuthSvcStruct) verifyPassword(user *models.User, password string) error {
+	normalized := NormalizePassword(password)
+	if err := user.VerifyPassword(normalized); err == nil {
+		return nil
+	}
+	if normalized == password || user.VerifyPassword(password) != nil {
+		return fmt.Errorf("%w: password mismatch", ErrInvalidCredentials)
+	}
+
+	// 作り直しに失敗してもログインは妨げない（次回のログインで再度作り直す）
+	hashedPassword, err := s.encryptlib.CreatePasswordHash(normalized)
+	if err == nil {
+		err = s.userRepo.UpdatePassword(user.ID, hashedPassword)
+	}
+	if err != nil {
+		log.Printf("[auth] failed to rehash normalized password: user=%s: %v", user.UUID, err)
+	}
+	return nil
+}
+
+// 2 要素認証が有効な場合はトークンを発行せず、チャレンジを返す
+func (s *AuthSvcStruct) createResponseTokenOrMfaChallenge(user *models.User, authContext AuthContext) (*AuthOutput, error) {
+	enabled, err := s.mfa.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		mfaToken, err := s.mfa.CreateChallenge(user, authContext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mfa challenge: %w", err)
+		}
+		return &AuthOutput{
+			MfaRequired: true,
+			MfaToken:    mfaToken,
+		}, nil
+	}
+
+	authContext.AuthTime = s.clock.Now()
+	return s.createResponseToken(user, authContext, nil)
+}
+
+// ログイン時に絞り込んだスコープはチャレンジトークンで引き継ぐ
+func (s *AuthSvcStruct) VerifyMfa(input VerifyMfaInput) (*AuthOutput, error) {
+	user, authContext, err := s.mfa.VerifyChallenge(input)
+	if err != nil {
+		return nil, err
+	}
+
+	// スコープを持たない古いチャレンジトークンは、ログイン時の既定のスコープとする
+	if authContext.Scope == "" {
+		authContext.Scope = s.scope()
+	}
+	authContext.AuthTime = s.clock.Now()
+	return s.createResponseToken(user, authContext, nil)
+}
+
+// パスキーはユーザー検証（生体認証・PIN）込みのため、TOTP は要求しない
+func (s *AuthSvcStruct) LoginWithPasskey(input WebauthnLoginInput) (*AuthOutput, error) {
+	user, err := s.webauthn.FinishLogin(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createResponseToken(user, AuthContext{AuthTime: s.clock.Now(), Amr: []string{AmrHwk, AmrMfa}, Scope: s.scope()}, nil)
+}
+
+// メールの受信はパスワードの代わりにすぎないため、TOTP が有効なら 2 要素目を要求する
+func (s *AuthSvcStruct) CompletePasswordless(input PasswordlessCompleteInput) (*AuthOutput, error) {
+	user, err := s.passwordless.Verify(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createResponseTokenOrMfaChallenge(user, AuthContext{Amr: []string{AmrEmail}, Scope: s.scope()})
+}
+
+// 認可コードを発行した時点のログインの認証時刻と方式を引き継ぐ
+func (s *AuthSvcStruct) ExchangeAuthorizationCode(client *models.OauthClient, input OauthTokenInput) (*AuthOutput, error) {
+	code, user, err := s.oauth.ConsumeAuthorizationCode(client, input)
+	if err != nil {
+		return nil, err
+	}
+
+	authContext := AuthContext{Amr: code.AmrList(), Scope: code.Scope, Nonce: code.Nonce}
+	if code.AuthTime != nil {
+		authContext.AuthTime = *code.AuthTime
+	}
+	return s.createResponseToken(user, authContext, client)
+}
+
+// 承認されたデバイスコードと引き換えにトークンを発行する。承認したログインの認証時刻と方式を引き継ぐ
+func (s *AuthSvcStruct) ExchangeDeviceCode(client *models.OauthClient, deviceCode string) (*AuthOutput, error) {
+	code, user, err := s.oauth.ConsumeDeviceCode(client, deviceCode)
+	if err != nil {
+		return nil, err
+	}
+
+	authContext := AuthContext{Amr: code.AmrList(), Scope: code.Scope}
+	if code.AuthTime != nil {
+		authContext.AuthTime = *code.AuthTime
+	}
+	return s.createResponseToken(user, authContext, client)
+}
+
+// OAuth クライアントに発行する場合は client を渡し、クライアントごとの有効期間とグラントを使う
+func (s *AuthSvcStruct) createResponseToken(user *models.User, authContext AuthContext, client *models.OauthClient) (*AuthOutput, error) {
+	if authContext.SessionID == "" {
+		authContext.SessionID = uuid.NewString()
+	}
+
+	now := s.clock.Now()
+	expiresIn := AccessTokenExpiresIn
+	audience := []string{s.config.Audience}
+	refreshToken := &models.UserRefreshToken{
+		UserID:   user.ID,
+		FamilyID: authContext.SessionID,
+		Scope:    authContext.Scope,
+		Amr:      strings.Join(authContext.Amr, " "),
+	}
+	if !authContext.AuthTime.IsZero() {
+		refreshToken.AuthTime = &authContext.AuthTime
+	}
+	if client != nil {
+		refreshToken.ClientID = client.ClientID
+		audience = s.clientAudience(client)
+		if client.AccessTokenLifetime > 0 {
+			expiresIn = client.AccessTokenLifetime
+		}
+		if client.RefreshTokenLifetime > 0 {
+			refreshToken.ExpiresAt = now.Add(time.Duration(client.RefreshTokenLifetime) * time.Second)
+		}
+	}
+
+	claims := s.registeredClaims(now, now.Add(time.Duration(expiresIn)*time.Second), audience)
+	claims["sub"] = "user" + user.UUID
+	claims["email"] = user.Email
+	claims["amr"] = authContext.Amr
+	claims["acr"] = authContext.Acr()
+	claims["sid"] = authContext.SessionID
+	claims["principal"] = PrincipalUser
+	// Accept-Language のないリクエストのエラーメッセージに使う。変更は次のリフレッシュから反映される
+	if user.Locale != "" {
+		claims["locale"] = user.Locale
+	}
+	// 認証時刻が不明なトークン（移行前に発行されたもの）は auth_time を付けず、ステップアップ時に再認証させる
+	if !authContext.AuthTime.IsZero() {
+		claims["auth_time"] = authContext.AuthTime.Unix()
+	}
+	if client != nil {
+		claims["client_id"] = client.ClientID
+	}
+	scope := authContext.accessScope()
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	// ロールはトークンの発行ごとに取得するため、変更は次のリフレッシュから反映される
+	// OAuth クライアントに発行するトークンの権限はスコープで表し、ロールは含めない
+	if client == nil {
+		authorization, err := s.roles.Authorization(user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user roles: %w", err)
+		}
+		if len(authorization.Roles) > 0 {
+			claims["roles"] = authorization.Roles
+			claims["permissions"] = authorization.Permissions
+		}
+	}
+
 	// jwtを発行
-	jwt, err := s.jwtlib.CreateJwt(&atylabjwt.JwtConfig{
-		Key:   []byte(os.Getenv("JWT_SECRET_KEY")),
-		Uuid:  user.UUID,
-		Email: user.Email,
-		Exp:   s.clock.Now().Add(time.Hour * 1),
-	})
+	accessToken, err := s.jwttoken.Sign(claims, []byte(os.Getenv("JWT_SECRET_KEY")))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create jwt: %w", err)
 	}
 
-	refreshToken, err := s.userRefreshTokenRepo.CreateRefreshToken(user.ID)
+	output := &AuthOutput{
+		AccessToken: accessToken,
+		SessionID:   authContext.SessionID,
+		ExpiresIn:   expiresIn,
+		Scope:       scope,
+	}
+	if client != nil && slices.Contains(strings.Fields(scope), OidcScopeOpenID) {
+		if output.IDToken, err = s.oidc.CreateIDToken(user, client, authContext, accessToken); err != nil {
+			return nil, fmt.Errorf("failed to create id token: %w", err)
+		}
+	}
+	// refresh_token グラントを許可していないクライアントにはリフレッシュトークンを発行しない
+	if client != nil && !client.AllowsGrantType(OauthGrantTypeRefreshToken) {
+		return output, nil
+	}
+
+	refreshToken, err = s.userRefreshTokenRepo.CreateRefreshToken(refreshToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create refresh token: %w", err)
 	}
+	output.RefreshToken = refreshToken.RefreshToken
+	return output, nil
+}
+
+type OauthClientCredentialsInput struct {
+	Scope string
+}
+
+// client_credentials グラント（RFC 6749 4.4）。ユーザーを介さないため sub はクライアント ID とし、リフレッシュトークンは発行しない
+func (s *AuthSvcStruct) IssueClientCredentials(client *models.OauthClient, input OauthClientCredentialsInput) (*AuthOutput, error) {
+	if client.IsPublic() || !client.AllowsGrantType(OauthGrantTypeClientCredentials) {
+		return nil, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to use the client credentials grant")
+	}
+
+	// scope を省略した場合はクライアントに許可された全スコープとする（RFC 6749 3.3）
+	scopes := strings.Fields(input.Scope)
+	if len(scopes) == 0 {
+		scopes = client.ScopeList()
+	}
+	if !client.AllowsScopes(scopes) {
+		return nil, newOauthError(OauthErrorInvalidScope, "scope is not allowed for the client")
+	}
+	scope := strings.Join(scopes, " ")
+
+	now := s.clock.Now()
+	expiresIn := AccessTokenExpiresIn
+	if client.AccessTokenLifetime > 0 {
+		expiresIn = client.AccessTokenLifetime
+	}
+	claims := s.registeredClaims(now, now.Add(time.Duration(expiresIn)*time.Second), s.clientAudience(client))
+	claims["sub"] = client.ClientID
+	claims["client_id"] = client.ClientID
+	// ユーザーのトークンと取り違えないよう、クライアント自身のトークンであることを示す
+	claims["principal"] = PrincipalClient
+	if scope != "" {
+		claims["scope"] = scope
+	}
+
+	accessToken, err := s.jwttoken.Sign(claims, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwt: %w", err)
+	}
+	return &AuthOutput{
+		AccessToken: accessToken,
+		ExpiresIn:   expiresIn,
+		Scope:       scope,
+	}, nil
+}
+
+// トークン交換（RFC 8693）。act クレームで委任・成り代わりを示し、リフレッシュトークンは発行しない
+func (s *AuthSvcStruct) ExchangeToken(client *models.OauthClient, input OauthTokenExchangeInput) (*AuthOutput, error) {
+	grant, err := s.oauth.ValidateTokenExchange(client, input)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	expiresIn := AccessTokenExpiresIn
+	if client.AccessTokenLifetime > 0 {
+		expiresIn = client.AccessTokenLifetime
+	}
+	expiresAt := now.Add(time.Duration(expiresIn) * time.Second)
+	// 交換に使ったトークンより長く有効にしない
+	if grant.ExpiresAt.Before(expiresAt) {
+		expiresAt = grant.ExpiresAt
+		expiresIn = int(expiresAt.Sub(now).Seconds())
+	}
+
+	jti := uuid.NewString()
+	claims := s.registeredClaims(now, expiresAt, grant.Audience)
+	claims["sub"] = "user" + grant.Subject.UUID
+	claims["email"] = grant.Subject.Email
+	claims["jti"] = jti
+	claims["principal"] = PrincipalUser
+	claims["client_id"] = client.ClientID
+	claims["act"] = grant.Act
+	// 成り代わりのトークンには認証時刻と方式を付けず、ステップアップが必要な操作をさせない
+	if !grant.Impersonation {
+		claims["amr"] = grant.AuthContext.Amr
+		claims["acr"] = grant.AuthContext.Acr()
+		if grant.AuthContext.SessionID != "" {
+			claims["sid"] = grant.AuthContext.SessionID
+		}
+		if !grant.AuthContext.AuthTime.IsZero() {
+			claims["auth_time"] = grant.AuthContext.AuthTime.Unix()
+		}
+	}
+	if grant.Scope != "" {
+		claims["scope"] = grant.Scope
+	}
+
+	accessToken, err := s.jwttoken.Sign(claims, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwt: %w", err)
+	}
+
+	if err := s.oauth.RecordTokenExchange(&models.OauthTokenExchange{
+		Jti:           jti,
+		ClientID:      client.ClientID,
+		SubjectUserID: grant.Subject.ID,
+		Actor:         grant.Actor,
+		Impersonation: grant.Impersonation,
+		Audience:      strings.Join(grant.Audience, " "),
+		Scope:         grant.Scope,
+		IpAddress:     input.IpAddress,
+		ExpiresAt:     expiresAt,
+	}); err != nil {
+		return nil, err
+	}
+	if grant.Impersonation {
+		log.Printf("[oauth] impersonation: actor=%s subject=user%s client=%s jti=%s", grant.Actor, grant.Subject.UUID, client.ClientID, jti)
+	}
 
 	return &AuthOutput{
-		AccessToken:  jwt,
-		RefreshToken: refreshToken.RefreshToken,
+		AccessToken:     accessToken,
+		ExpiresIn:       expiresIn,
+		Scope:           grant.Scope,
+		IssuedTokenType: OauthTokenTypeAccessToken,
 	}, nil
 }
 
 type RefreshInput struct {
 	RefreshToken string
 	IpAddress    string
+	// 最初に許可したスコープの範囲でのみ絞り込める。省略した場合は最初に許可したスコープ
+	Scope string
 }
 
 func (s *AuthSvcStruct) Refresh(input RefreshInput) (*AuthOutput, error) {
-	refreshTokenRecord, err := s.userRefreshTokenRepo.GetUserByRefreshToken(input.RefreshToken)
+	return s.refresh(input, nil)
+}
+
+// OAuth クライアントの refresh_token グラント。トークンを発行したクライアント以外は使えない
+func (s *AuthSvcStruct) RefreshForClient(client *models.OauthClient, input RefreshInput) (*AuthOutput, error) {
+	if !client.AllowsGrantType(OauthGrantTypeRefreshToken) {
+		return nil, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to use the refresh token grant")
+	}
+	output, err := s.refresh(input, client)
+	switch {
+	case errors.Is(err, ErrInvalidRefreshToken):
+		return nil, newOauthError(OauthErrorInvalidGrant, "refresh token is invalid, expired or already used")
+	case errors.Is(err, ErrInvalidScope):
+		return nil, newOauthError(OauthErrorInvalidScope, "scope exceeds the originally granted scope")
+	}
+	return output, err
+}
+
+func (s *AuthSvcStruct) refresh(input RefreshInput, client *models.OauthClient) (*AuthOutput, error) {
+	user, refreshTokenRecord, err := s.userRefreshTokenRepo.GetUserByRefreshToken(input.RefreshToken)
+	if err != nil {
+		if isInvalidRefreshToken(err) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRefreshToken, err)
+		}
+		return nil, err
+	}
+
+	clientID := ""
+	if client != nil {
+		clientID = client.ClientID
+	}
+	if refreshTokenRecord.ClientID != clientID {
+		return nil, fmt.Errorf("%w: issued to another client", ErrInvalidRefreshToken)
+	}
+
+	// スコープを持たない古いファーストパーティのトークンは、ログイン時の既定のスコープを許可したものとみなす
+	grantedScope := refreshTokenRecord.Scope
+	if grantedScope == "" && client == nil {
+		grantedScope = s.scope()
+	}
+	accessScope, err := narrowScope(input.Scope, strings.Fields(grantedScope))
 	if err != nil {
-		return nil, fmt.Errorf("invalid refresh token: %w", err)
+		return nil, err
 	}
 
 	if err := s.userRefreshTokenRepo.ChangeUsed(input.RefreshToken, input.IpAddress); err != nil {
 		return nil, fmt.Errorf("failed to change used refresh token: %w", err)
 	}
 
-	return s.createResponseToken(refreshTokenRecord)
+	// リフレッシュでは再認証していないため、最初のログイン時の認証時刻と方式を引き継ぐ
+	// 絞り込んだスコープはこのアクセストークンのみに適用し、新しいリフレッシュトークンには最初に許可したスコープを引き継ぐ
+	authContext := AuthContext{Amr: refreshTokenRecord.AmrList(), SessionID: refreshTokenRecord.FamilyID, Scope: grantedScope, AccessScope: accessScope}
+	if refreshTokenRecord.AuthTime != nil {
+		authContext.AuthTime = *refreshTokenRecord.AuthTime
+	}
+	return s.createResponseToken(user, authContext, client)
+}
+
+func isInvalidRefreshToken(err error) bool {
+	return errors.Is(err, repositories.ErrRefreshTokenNotFound) ||
+		errors.Is(err, repositories.ErrRefreshTokenAlreadyUsed) ||
+		errors.Is(err, repositories.ErrRefreshTokenExpired) ||
+		errors.Is(err, repositories.ErrUserNotFound)
+}
+
+// ファーストパーティのログインで許可するスコープ
+func (s *AuthSvcStruct) scope() string {
+	return strings.Join(s.config.Scopes, " ")
+}
+
+// クライアントに登録した受け手がない場合は、このサーバー（UserInfo）を受け手とする
+func (s *AuthSvcStruct) clientAudience(client *models.OauthClient) []string {
+	if audience := client.AudienceList(); len(audience) > 0 {
+		return audience
+	}
+	return []string{s.config.Issuer}
+}
+
+// すべてのアクセストークンに共通の登録済みクレーム（RFC 7519 4.1）
+func (s *AuthSvcStruct) registeredClaims(now time.Time, expiresAt time.Time, audience []string) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss": s.config.Issuer,
+		"aud": audience,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": expiresAt.Unix(),
+	}
+	// 受け手が 1 つの場合は文字列とする（RFC 7519 4.1.3）
+	if len(audience) == 1 {
+		claims["aud"] = audience[0]
+	}
+	return claims
+}
+
+// 要求されたスコープが許可済みの範囲に収まるか確認する。省略した場合は許可済みのすべて
+func narrowScope(requested string, granted []string) (string, error) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return strings.Join(granted, " "), nil
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return "", fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	return strings.Join(scopes, " "), nil
 }
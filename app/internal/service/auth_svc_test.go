@@ -1,18 +1,43 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/lib_mock"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabencrypt"
-	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabjwt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
 )
 
+var testAuthConfig = AuthConfig{
+	Issuer:   "https://auth.example.com",
+	Audience: "https://api.example.com",
+	Scopes:   []string{ScopeAccount, ScopeMfa, ScopeOauth, OidcScopeOpenID},
+}
+
+// CreateRefreshToken に渡すレコードをユーザー・セッション・認証時刻・amr で照合する。familyID が空の場合は問わない
+func matchRefreshToken(userID uint, familyID string, authTime time.Time, amr string) any {
+	return mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+		if familyID != "" && token.FamilyID != familyID {
+			return false
+		}
+		tokenAuthTime := time.Time{}
+		if token.AuthTime != nil {
+			tokenAuthTime = *token.AuthTime
+		}
+		return token.UserID == userID && tokenAuthTime.Equal(authTime) && token.Amr == amr
+	})
+}
+
 func TestLoginSuccess(t *testing.T) {
 	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
 		crypt := atylabencrypt.NewEncryptPkg()
@@ -38,7 +63,7 @@ func TestLoginSuccess(t *testing.T) {
 
 		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
 		userRefreshTokenRepo.On(
-			"CreateRefreshToken", uint(1),
+			"CreateRefreshToken", mock.Anything,
 		).Return(&models.UserRefreshToken{
 			ID:           1,
 			UserID:       1,
@@ -46,22 +71,45 @@ func TestLoginSuccess(t *testing.T) {
 			ExpiresAt:    clock.Now().Add(24 * time.Hour * 30),
 		}, nil)
 
-		jwtlib := &atylabjwt.JwtMock{}
-		jwtlib.On(
-			"CreateJwt",
-			&atylabjwt.JwtConfig{
-				Key:   []byte("testsecretkey"),
-				Uuid:  "test-uuid",
-				Email: "test@example.com",
-				Exp:   clock.Now().Add(time.Hour * 1),
-			},
+		// sid はログインごとに採番されるため、それ以外のクレームを比較する
+		var sid string
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On(
+			"Sign",
+			mock.MatchedBy(func(claims jwt.MapClaims) bool {
+				sid, _ = claims["sid"].(string)
+				others := jwt.MapClaims{}
+				for k, v := range claims {
+					if k != "sid" {
+						others[k] = v
+					}
+				}
+				return sid != "" && reflect.DeepEqual(jwt.MapClaims{
+					"iss":       "https://auth.example.com",
+					"aud":       "https://api.example.com",
+					"sub":       "usertest-uuid",
+					"email":     "test@example.com",
+					"iat":       clock.Now().Unix(),
+					"nbf":       clock.Now().Unix(),
+					"exp":       clock.Now().Add(time.Hour * 1).Unix(),
+					"auth_time": clock.Now().Unix(),
+					"amr":       []string{AmrPwd},
+					"acr":       AcrAal1,
+					"principal": PrincipalUser,
+					"scope":     "account mfa oauth openid",
+				}, others)
+			}),
+			[]byte("testsecretkey"),
 		).Return("test-access-token", nil)
 
 		authSvc := &AuthSvcStruct{
+			config:               testAuthConfig,
 			userRepo:             userRepoMock,
 			userRefreshTokenRepo: userRefreshTokenRepo,
-			jwtlib:               jwtlib,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
 			clock:                clock,
+			mfa:                  newTestMfaSvcWithTotp(nil),
 		}
 
 		input := LoginInput{
@@ -82,8 +130,13 @@ func TestLoginSuccess(t *testing.T) {
 			t.Errorf("expected refresh token %v, but got %v", "test-refresh-token", out.RefreshToken)
 		}
 
+		if out.SessionID != sid {
+			t.Errorf("expected session id %v, but got %v", sid, out.SessionID)
+		}
+		userRefreshTokenRepo.AssertCalled(t, "CreateRefreshToken", matchRefreshToken(1, sid, clock.Now(), "pwd"))
+
 		userRefreshTokenRepo.AssertExpectations(t)
-		jwtlib.AssertExpectations(t)
+		jwtTokenMock.AssertExpectations(t)
 		userRepoMock.AssertExpectations(t)
 	})
 }
@@ -109,7 +162,8 @@ func TestLoginFailInvalidPassword(t *testing.T) {
 	authSvc := &AuthSvcStruct{
 		userRepo:             userRepoMock,
 		userRefreshTokenRepo: nil,
-		jwtlib:               nil,
+		jwttoken:             nil,
+		roles:                newTestRoleSvc(),
 		clock:                nil,
 	}
 
@@ -119,23 +173,73 @@ func TestLoginFailInvalidPassword(t *testing.T) {
 	}
 
 	_, err = authSvc.Login(input)
-	if err == nil {
-		t.Fatalf("expected error, but got none")
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, but got %v", err)
 	}
 
 	userRepoMock.AssertExpectations(t)
 }
 
+// NFKC 正規化を導入する前に全角文字のまま登録したパスワードでもログインでき、正規化した入力でハッシュを作り直す
+func TestLoginVerifyPasswordBeforeNormalization(t *testing.T) {
+	crypt := atylabencrypt.NewEncryptPkg()
+	rawPassword := "ｐａｓｓｗｏｒｄ１２３"
+	legacyHash, err := crypt.CreatePasswordHash(rawPassword)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	normalizedHash, err := crypt.CreatePasswordHash("password123")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	tests := map[string]struct {
+		hash      string
+		password  string
+		updateErr error
+		rehashed  bool
+		expected  error
+	}{
+		"normalized hash":      {normalizedHash, rawPassword, nil, false, nil},
+		"legacy hash":          {legacyHash, rawPassword, nil, true, nil},
+		"legacy hash db error": {legacyHash, rawPassword, fmt.Errorf("db error"), true, nil},
+		"wrong password":       {legacyHash, "ｗｒｏｎｇ", nil, false, ErrInvalidCredentials},
+		"ascii wrong password": {normalizedHash, "wrong", nil, false, ErrInvalidCredentials},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			user := &models.User{ID: 1, UUID: "test-uuid", PasswordHash: tt.hash}
+			userRepoMock := new(repo_mock.UserRepoMock)
+			userRepoMock.On("UpdatePassword", uint(1), "new-hash").Return(tt.updateErr)
+			encryptlibMock := new(atylabencrypt.EncryptPkgStructMock)
+			encryptlibMock.On("CreatePasswordHash", "password123").Return("new-hash", nil)
+
+			authSvc := &AuthSvcStruct{userRepo: userRepoMock, encryptlib: encryptlibMock}
+			err := authSvc.verifyPassword(user, tt.password)
+			if !errors.Is(err, tt.expected) || (tt.expected == nil && err != nil) {
+				t.Fatalf("expected %v, but got %v", tt.expected, err)
+			}
+			if tt.rehashed {
+				userRepoMock.AssertCalled(t, "UpdatePassword", uint(1), "new-hash")
+			} else {
+				userRepoMock.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
 func TestLoginFailUserNotFound(t *testing.T) {
 	userRepoMock := new(repo_mock.UserRepoMock)
 	userRepoMock.On(
 		"GetByEmail", "test@example.com",
-	).Return(&models.User{}, fmt.Errorf("user not found"))
+	).Return(&models.User{}, repositories.ErrUserNotFound)
 
 	authSvc := &AuthSvcStruct{
 		userRepo:             userRepoMock,
 		userRefreshTokenRepo: nil,
-		jwtlib:               nil,
+		jwttoken:             nil,
+		roles:                newTestRoleSvc(),
 		clock:                nil,
 	}
 
@@ -145,13 +249,98 @@ func TestLoginFailUserNotFound(t *testing.T) {
 	}
 
 	_, err := authSvc.Login(input)
-	if err == nil {
-		t.Fatalf("expected error, but got none")
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, but got %v", err)
 	}
 
 	userRepoMock.AssertExpectations(t)
 }
 
+func TestLoginFailGetByEmailDbErr(t *testing.T) {
+	userRepoMock := new(repo_mock.UserRepoMock)
+	userRepoMock.On(
+		"GetByEmail", "test@example.com",
+	).Return(&models.User{}, fmt.Errorf("db error"))
+
+	authSvc := &AuthSvcStruct{userRepo: userRepoMock}
+
+	_, err := authSvc.Login(LoginInput{Email: "test@example.com", Password: "password"})
+	if err == nil || errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected internal error, but got %v", err)
+	}
+}
+
+func TestLoginNarrowScope(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		crypt := atylabencrypt.NewEncryptPkg()
+		passwordHash, err := crypt.CreatePasswordHash("password")
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+
+		userRepoMock := new(repo_mock.UserRepoMock)
+		userRepoMock.On("GetByEmail", "test@example.com").Return(&models.User{
+			ID:           1,
+			UUID:         "test-uuid",
+			Email:        "test@example.com",
+			PasswordHash: passwordHash,
+		}, nil)
+
+		// 絞り込んだスコープはアクセストークンとリフレッシュトークンの両方に適用する
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+			return token.Scope == "account"
+		})).Return(&models.UserRefreshToken{RefreshToken: "test-refresh-token"}, nil)
+
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			return claims["scope"] == "account"
+		}), []byte("testsecretkey")).Return("test-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			config:               testAuthConfig,
+			userRepo:             userRepoMock,
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+			mfa:                  newTestMfaSvcWithTotp(nil),
+		}
+
+		out, err := authSvc.Login(LoginInput{Email: "test@example.com", Password: "password", Scope: "account"})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.Scope != "account" {
+			t.Errorf("expected scope account, but got %v", out.Scope)
+		}
+
+		userRefreshTokenRepo.AssertExpectations(t)
+		jwtTokenMock.AssertExpectations(t)
+	})
+}
+
+func TestLoginFailInvalidScope(t *testing.T) {
+	crypt := atylabencrypt.NewEncryptPkg()
+	passwordHash, err := crypt.CreatePasswordHash("password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	userRepoMock := new(repo_mock.UserRepoMock)
+	userRepoMock.On("GetByEmail", "test@example.com").Return(&models.User{
+		ID:           1,
+		PasswordHash: passwordHash,
+	}, nil)
+
+	authSvc := &AuthSvcStruct{config: testAuthConfig, userRepo: userRepoMock}
+
+	_, err = authSvc.Login(LoginInput{Email: "test@example.com", Password: "password", Scope: "account admin"})
+	if !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("expected ErrInvalidScope, but got %v", err)
+	}
+}
+
 func TestCreateResponseTokenCreateJwtFail(t *testing.T) {
 	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
 		clock := atylabclock.NewClockMock(
@@ -166,30 +355,23 @@ func TestCreateResponseTokenCreateJwtFail(t *testing.T) {
 		}
 
 		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
-		jwtlib := &atylabjwt.JwtMock{}
-		jwtlib.On(
-			"CreateJwt",
-			&atylabjwt.JwtConfig{
-				Key:   []byte("testsecretkey"),
-				Uuid:  "test-uuid",
-				Email: "test@example.com",
-				Exp:   clock.Now().Add(time.Hour * 1),
-			},
-		).Return("", fmt.Errorf("failed to create jwt"))
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("", fmt.Errorf("failed to create jwt"))
 
 		authSvc := &AuthSvcStruct{
 			userRepo:             nil,
 			userRefreshTokenRepo: userRefreshTokenRepo,
-			jwtlib:               jwtlib,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
 			clock:                clock,
 		}
 
-		_, err := authSvc.createResponseToken(user)
+		_, err := authSvc.createResponseToken(user, AuthContext{AuthTime: clock.Now(), Amr: []string{AmrPwd}}, nil)
 		if err == nil {
 			t.Fatalf("expected error, but got none")
 		}
 
-		jwtlib.AssertExpectations(t)
+		jwtTokenMock.AssertExpectations(t)
 	})
 }
 
@@ -208,33 +390,112 @@ func TestCreateResponseTokenCreateRefreshTokenFail(t *testing.T) {
 
 		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
 		userRefreshTokenRepo.On(
-			"CreateRefreshToken", uint(1),
+			"CreateRefreshToken", mock.Anything,
 		).Return(&models.UserRefreshToken{}, fmt.Errorf("failed to create refresh token"))
 
-		jwtlib := &atylabjwt.JwtMock{}
-		jwtlib.On(
-			"CreateJwt",
-			&atylabjwt.JwtConfig{
-				Key:   []byte("testsecretkey"),
-				Uuid:  "test-uuid",
-				Email: "test@example.com",
-				Exp:   clock.Now().Add(time.Hour * 1),
-			},
-		).Return("test-jwt", nil)
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("test-jwt", nil)
 
 		authSvc := &AuthSvcStruct{
 			userRepo:             nil,
 			userRefreshTokenRepo: userRefreshTokenRepo,
-			jwtlib:               jwtlib,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
 			clock:                clock,
 		}
 
-		_, err := authSvc.createResponseToken(user)
+		_, err := authSvc.createResponseToken(user, AuthContext{AuthTime: clock.Now(), Amr: []string{AmrPwd}}, nil)
 		if err == nil {
 			t.Fatalf("expected error, but got none")
 		}
 
-		jwtlib.AssertExpectations(t)
+		jwtTokenMock.AssertExpectations(t)
+	})
+}
+
+func TestCreateResponseTokenRoles(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid", Email: "test@example.com"}
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("CreateRefreshToken", mock.Anything).Return(&models.UserRefreshToken{RefreshToken: "refresh-token"}, nil)
+
+		var claims jwt.MapClaims
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.Anything, []byte("testsecretkey")).Run(func(args mock.Arguments) {
+			claims = args.Get(0).(jwt.MapClaims)
+		}).Return("access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			config:               testAuthConfig,
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(testAdminRole),
+			clock:                atylabclock.NewClockMock(time.Now()),
+			oidc:                 newTestOidcSvc(),
+		}
+
+		if _, err := authSvc.createResponseToken(user, AuthContext{Amr: []string{AmrPwd}}, nil); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if !reflect.DeepEqual(claims["roles"], []string{"admin"}) || !reflect.DeepEqual(claims["permissions"], []string{"users:read", "users:write"}) {
+			t.Errorf("unexpected role claims: %v %v", claims["roles"], claims["permissions"])
+		}
+
+		// OAuth クライアントに発行するトークンにはロールを含めない
+		if _, err := authSvc.createResponseToken(user, AuthContext{Amr: []string{AmrPwd}, Scope: "profile"}, newTestOauthClient()); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if _, ok := claims["roles"]; ok {
+			t.Errorf("expected no roles for client tokens, but got %v", claims["roles"])
+		}
+	})
+}
+
+func TestCreateResponseTokenRolesFail(t *testing.T) {
+	userRoleRepo := new(repo_mock.UserRoleRepoMock)
+	userRoleRepo.On("ListRoles", uint(1)).Return([]models.Role(nil), fmt.Errorf("db error"))
+	userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+
+	authSvc := &AuthSvcStruct{
+		config:               testAuthConfig,
+		userRefreshTokenRepo: userRefreshTokenRepo,
+		roles:                NewRoleSvc(nil, nil, userRoleRepo, nil),
+		clock:                atylabclock.NewClockMock(time.Now()),
+	}
+
+	if _, err := authSvc.createResponseToken(&models.User{ID: 1}, AuthContext{}, nil); err == nil {
+		t.Fatal("expected error, but got none")
+	}
+	userRefreshTokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+}
+
+// ロールの変更は次のリフレッシュで発行するアクセストークンから反映される
+func TestRefreshReflectsRoleChanges(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid", Email: "test@example.com"}
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("GetUserByRefreshToken", "valid-refresh-token").Return(user, &models.UserRefreshToken{FamilyID: "family-id", Scope: "account"}, nil)
+		userRefreshTokenRepo.On("ChangeUsed", "valid-refresh-token", "127.0.0.1").Return(nil)
+		userRefreshTokenRepo.On("CreateRefreshToken", mock.Anything).Return(&models.UserRefreshToken{RefreshToken: "new-refresh-token"}, nil)
+
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			return reflect.DeepEqual(claims["roles"], []string{"support"}) && reflect.DeepEqual(claims["permissions"], []string{"users:read"})
+		}), []byte("testsecretkey")).Return("new-access-token", nil)
+
+		support := models.Role{ID: 3, Name: "support", Permissions: []models.Permission{{Name: "users:read"}}}
+		authSvc := &AuthSvcStruct{
+			config:               testAuthConfig,
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(support),
+			clock:                atylabclock.NewClockMock(time.Now()),
+		}
+
+		if _, err := authSvc.Refresh(RefreshInput{RefreshToken: "valid-refresh-token", IpAddress: "127.0.0.1"}); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		jwtTokenMock.AssertExpectations(t)
 	})
 }
 
@@ -243,19 +504,26 @@ func TestRefresh(t *testing.T) {
 		clock := atylabclock.NewClockMock(
 			time.Now(),
 		)
+		authTime := clock.Now().Add(-2 * time.Hour)
 
 		user := &models.User{
-			ID:    1,
-			UUID:  "test-uuid",
-			Email: "test@example.com",
+			ID:     1,
+			UUID:   "test-uuid",
+			Email:  "test@example.com",
+			Locale: "ja",
 		}
 		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
 		userRefreshTokenRepo.On(
 			"GetUserByRefreshToken", "valid-refresh-token",
-		).Return(user, nil)
+		).Return(user, &models.UserRefreshToken{
+			FamilyID: "family-id",
+			AuthTime: &authTime,
+			Amr:      "pwd otp mfa",
+		}, nil)
 
+		// 最初のログイン時の認証時刻と方式、セッションを引き継ぐ
 		userRefreshTokenRepo.On(
-			"CreateRefreshToken", uint(1),
+			"CreateRefreshToken", matchRefreshToken(1, "family-id", authTime, "pwd otp mfa"),
 		).Return(&models.UserRefreshToken{
 			ID:           1,
 			UserID:       1,
@@ -267,21 +535,17 @@ func TestRefresh(t *testing.T) {
 			"ChangeUsed", "valid-refresh-token", "127.0.0.1",
 		).Return(nil)
 
-		jwtlib := &atylabjwt.JwtMock{}
-		jwtlib.On(
-			"CreateJwt",
-			&atylabjwt.JwtConfig{
-				Key:   []byte("testsecretkey"),
-				Uuid:  "test-uuid",
-				Email: "test@example.com",
-				Exp:   clock.Now().Add(time.Hour * 1),
-			},
-		).Return("new-access-token", nil)
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			// 設定した言語は発行し直すトークンに最新の値を付ける
+			return claims["auth_time"] == authTime.Unix() && claims["acr"] == AcrAal2 && claims["sid"] == "family-id" && claims["locale"] == "ja"
+		}), []byte("testsecretkey")).Return("new-access-token", nil)
 
 		authSvc := &AuthSvcStruct{
 			userRepo:             nil,
 			userRefreshTokenRepo: userRefreshTokenRepo,
-			jwtlib:               jwtlib,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
 			clock:                clock,
 		}
 
@@ -296,28 +560,182 @@ func TestRefresh(t *testing.T) {
 		if out == nil {
 			t.Fatal("expected output, but got nil")
 		}
+
+		userRefreshTokenRepo.AssertExpectations(t)
+		jwtTokenMock.AssertExpectations(t)
+	})
+}
+
+func TestRefreshWithoutAuthTime(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{
+			ID:    1,
+			UUID:  "test-uuid",
+			Email: "test@example.com",
+		}
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On(
+			"GetUserByRefreshToken", "valid-refresh-token",
+		).Return(user, &models.UserRefreshToken{}, nil)
+		userRefreshTokenRepo.On(
+			"CreateRefreshToken", matchRefreshToken(1, "", time.Time{}, ""),
+		).Return(&models.UserRefreshToken{RefreshToken: "new-refresh-token"}, nil)
+		userRefreshTokenRepo.On(
+			"ChangeUsed", "valid-refresh-token", "127.0.0.1",
+		).Return(nil)
+
+		// 認証時刻が不明なトークンには auth_time を付けない
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			_, ok := claims["auth_time"]
+			return !ok && claims["acr"] == AcrAal1
+		}), []byte("testsecretkey")).Return("new-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+		}
+
+		if _, err := authSvc.Refresh(RefreshInput{
+			RefreshToken: "valid-refresh-token",
+			IpAddress:    "127.0.0.1",
+		}); err != nil {
+			t.Fatalf("expected no error, but got: %v", err)
+		}
+
+		jwtTokenMock.AssertExpectations(t)
 	})
 }
 
-func TestRefreshFailGetUserByRefreshToken(t *testing.T) {
+func TestRefreshNarrowScope(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid", Email: "test@example.com"}
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("GetUserByRefreshToken", "valid-refresh-token").Return(user, &models.UserRefreshToken{
+			FamilyID: "family-id",
+			Scope:    "account mfa",
+		}, nil)
+		userRefreshTokenRepo.On("ChangeUsed", "valid-refresh-token", "127.0.0.1").Return(nil)
+		// 新しいリフレッシュトークンには最初に許可したスコープを引き継ぐ
+		userRefreshTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+			return token.Scope == "account mfa"
+		})).Return(&models.UserRefreshToken{RefreshToken: "new-refresh-token"}, nil)
+
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			return claims["scope"] == "mfa" && claims["aud"] == "https://api.example.com"
+		}), []byte("testsecretkey")).Return("new-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			config:               testAuthConfig,
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+		}
+
+		out, err := authSvc.Refresh(RefreshInput{RefreshToken: "valid-refresh-token", IpAddress: "127.0.0.1", Scope: "mfa"})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.Scope != "mfa" {
+			t.Errorf("expected scope mfa, but got %v", out.Scope)
+		}
+
+		userRefreshTokenRepo.AssertExpectations(t)
+		jwtTokenMock.AssertExpectations(t)
+	})
+}
+
+func TestRefreshLegacyTokenScope(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid", Email: "test@example.com"}
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("GetUserByRefreshToken", "valid-refresh-token").Return(user, &models.UserRefreshToken{FamilyID: "family-id"}, nil)
+		userRefreshTokenRepo.On("ChangeUsed", "valid-refresh-token", "127.0.0.1").Return(nil)
+		userRefreshTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+			return token.Scope == "account mfa oauth openid"
+		})).Return(&models.UserRefreshToken{RefreshToken: "new-refresh-token"}, nil)
+
+		// スコープを持たない古いトークンはログイン時の既定のスコープを許可したものとみなす
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			return claims["scope"] == "account mfa oauth openid"
+		}), []byte("testsecretkey")).Return("new-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			config:               testAuthConfig,
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+		}
+
+		if _, err := authSvc.Refresh(RefreshInput{RefreshToken: "valid-refresh-token", IpAddress: "127.0.0.1"}); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		userRefreshTokenRepo.AssertExpectations(t)
+		jwtTokenMock.AssertExpectations(t)
+	})
+}
+
+func TestRefreshFailInvalidScope(t *testing.T) {
 	userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
-	userRefreshTokenRepo.On(
-		"GetUserByRefreshToken", "invalid-refresh-token",
-	).Return(&models.User{}, fmt.Errorf("invalid refresh token"))
+	userRefreshTokenRepo.On("GetUserByRefreshToken", "valid-refresh-token").Return(&models.User{ID: 1}, &models.UserRefreshToken{
+		Scope: "account",
+	}, nil)
 
-	authSvc := &AuthSvcStruct{
-		userRepo:             nil,
-		userRefreshTokenRepo: userRefreshTokenRepo,
-		jwtlib:               nil,
-		clock:                nil,
+	authSvc := &AuthSvcStruct{config: testAuthConfig, userRefreshTokenRepo: userRefreshTokenRepo}
+
+	// 許可済みの範囲を超える場合は、リフレッシュトークンを使用済みにしない
+	_, err := authSvc.Refresh(RefreshInput{RefreshToken: "valid-refresh-token", IpAddress: "127.0.0.1", Scope: "account mfa"})
+	if !errors.Is(err, ErrInvalidScope) {
+		t.Fatalf("expected ErrInvalidScope, but got %v", err)
 	}
+	userRefreshTokenRepo.AssertNotCalled(t, "ChangeUsed", mock.Anything, mock.Anything)
+}
 
-	_, err := authSvc.Refresh(RefreshInput{
-		RefreshToken: "invalid-refresh-token",
-		IpAddress:    "127.0.0.1",
-	})
-	if err == nil {
-		t.Fatalf("expected error, but got none")
+func TestRefreshFailGetUserByRefreshToken(t *testing.T) {
+	tests := map[string]struct {
+		repoErr error
+		invalid bool
+	}{
+		"not found":    {repositories.ErrRefreshTokenNotFound, true},
+		"already used": {repositories.ErrRefreshTokenAlreadyUsed, true},
+		"expired":      {repositories.ErrRefreshTokenExpired, true},
+		"user deleted": {repositories.ErrUserNotFound, true},
+		"db error":     {fmt.Errorf("db error"), false},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+			userRefreshTokenRepo.On(
+				"GetUserByRefreshToken", "invalid-refresh-token",
+			).Return((*models.User)(nil), (*models.UserRefreshToken)(nil), tt.repoErr)
+
+			authSvc := &AuthSvcStruct{
+				userRepo:             nil,
+				userRefreshTokenRepo: userRefreshTokenRepo,
+				jwttoken:             nil,
+				roles:                newTestRoleSvc(),
+				clock:                nil,
+			}
+
+			_, err := authSvc.Refresh(RefreshInput{
+				RefreshToken: "invalid-refresh-token",
+				IpAddress:    "127.0.0.1",
+			})
+			if err == nil {
+				t.Fatalf("expected error, but got none")
+			}
+			if errors.Is(err, ErrInvalidRefreshToken) != tt.invalid {
+				t.Fatalf("expected invalid refresh token %v, but got %v", tt.invalid, err)
+			}
+		})
 	}
 }
 
@@ -330,7 +748,7 @@ func TestRefreshFailChangeUsed(t *testing.T) {
 	userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
 	userRefreshTokenRepo.On(
 		"GetUserByRefreshToken", "valid-refresh-token",
-	).Return(user, nil)
+	).Return(user, &models.UserRefreshToken{}, nil)
 
 	userRefreshTokenRepo.On(
 		"ChangeUsed", "valid-refresh-token", "127.0.0.1",
@@ -339,7 +757,8 @@ func TestRefreshFailChangeUsed(t *testing.T) {
 	authSvc := &AuthSvcStruct{
 		userRepo:             nil,
 		userRefreshTokenRepo: userRefreshTokenRepo,
-		jwtlib:               nil,
+		jwttoken:             nil,
+		roles:                newTestRoleSvc(),
 		clock:                nil,
 	}
 
@@ -352,19 +771,63 @@ func TestRefreshFailChangeUsed(t *testing.T) {
 	}
 }
 
+func TestNewAuthConfigFromEnv(t *testing.T) {
+	funcs.WithEnv("OAUTH_ISSUER", "https://auth.example.com/", t, func() {
+		funcs.WithEnv("AUTH_AUDIENCE", "", t, func() {
+			funcs.WithEnv("AUTH_SCOPES", "", t, func() {
+				config := NewAuthConfigFromEnv()
+				if config.Issuer != "https://auth.example.com" || config.Audience != "https://auth.example.com" {
+					t.Errorf("unexpected default config: %+v", config)
+				}
+				if !reflect.DeepEqual(config.Scopes, []string{ScopeAccount, ScopeMfa, ScopeOauth, OidcScopeOpenID, OidcScopeProfile, OidcScopeEmail}) {
+					t.Errorf("unexpected default scopes: %v", config.Scopes)
+				}
+			})
+
+			funcs.WithEnv("AUTH_AUDIENCE", "https://api.example.com", t, func() {
+				funcs.WithEnv("AUTH_SCOPES", "account  mfa", t, func() {
+					config := NewAuthConfigFromEnv()
+					if config.Audience != "https://api.example.com" || !reflect.DeepEqual(config.Scopes, []string{ScopeAccount, ScopeMfa}) {
+						t.Errorf("unexpected config: %+v", config)
+					}
+				})
+			})
+		})
+	})
+}
+
 func TestNewAuthSvc(t *testing.T) {
 	userRepoMock := new(repo_mock.UserRepoMock)
 	userRefreshTokenRepoMock := new(repo_mock.UserRefreshTokenRepoMock)
-	jwtlibMock := &atylabjwt.JwtMock{}
+	encryptlibMock := new(atylabencrypt.EncryptPkgStructMock)
+	jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
 	clockMock := atylabclock.NewClockMock(time.Now())
+	mfaSvc := newTestMfaSvcWithTotp(nil)
+	webauthnSvc := newTestWebauthnSvc()
+	passwordlessSvc := newTestPasswordlessSvc()
+	oauthSvc := newTestOauthSvc()
+	oidcSvc := newTestOidcSvc()
+	roleSvc := newTestRoleSvc()
 
 	authSvc := NewAuthSvc(
+		testAuthConfig,
 		userRepoMock,
 		userRefreshTokenRepoMock,
-		jwtlibMock,
+		encryptlibMock,
+		jwtTokenMock,
 		clockMock,
+		mfaSvc,
+		webauthnSvc,
+		passwordlessSvc,
+		oauthSvc,
+		oidcSvc,
+		roleSvc,
 	)
 
+	if !reflect.DeepEqual(authSvc.config, testAuthConfig) {
+		t.Errorf("expected config to be set correctly")
+	}
+
 	if authSvc.userRepo != userRepoMock {
 		t.Errorf("expected userRepo to be set correctly")
 	}
@@ -373,11 +836,827 @@ func TestNewAuthSvc(t *testing.T) {
 		t.Errorf("expected userRefreshTokenRepo to be set correctly")
 	}
 
-	if authSvc.jwtlib != jwtlibMock {
-		t.Errorf("expected jwtlib to be set correctly")
+	if authSvc.encryptlib != encryptlibMock {
+		t.Errorf("expected encryptlib to be set correctly")
+	}
+
+	if authSvc.jwttoken != jwtTokenMock {
+		t.Errorf("expected jwttoken to be set correctly")
 	}
 
 	if authSvc.clock != clockMock {
 		t.Errorf("expected clock to be set correctly")
 	}
+
+	if authSvc.mfa != mfaSvc {
+		t.Errorf("expected mfa to be set correctly")
+	}
+
+	if authSvc.webauthn != webauthnSvc {
+		t.Errorf("expected webauthn to be set correctly")
+	}
+
+	if authSvc.passwordless != passwordlessSvc {
+		t.Errorf("expected passwordless to be set correctly")
+	}
+
+	if authSvc.oauth != oauthSvc {
+		t.Errorf("expected oauth to be set correctly")
+	}
+
+	if authSvc.oidc != oidcSvc {
+		t.Errorf("expected oidc to be set correctly")
+	}
+
+	if authSvc.roles != roleSvc {
+		t.Errorf("expected roles to be set correctly")
+	}
+}
+
+func TestLoginMfaRequired(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		crypt := atylabencrypt.NewEncryptPkg()
+
+		passwordHash, err := crypt.CreatePasswordHash("password")
+		if err != nil {
+			t.Fatalf("failed to hash password: %v", err)
+		}
+
+		userRepoMock := new(repo_mock.UserRepoMock)
+		userRepoMock.On(
+			"GetByEmail", "test@example.com",
+		).Return(&models.User{
+			ID:           1,
+			UUID:         "test-uuid",
+			Email:        "test@example.com",
+			PasswordHash: passwordHash,
+		}, nil)
+
+		// トークンは発行されない
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+
+		authSvc := &AuthSvcStruct{
+			userRepo:             userRepoMock,
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+			mfa:                  newTestMfaSvcWithTotp(&models.UserTotp{ID: 1, UserID: 1, Enabled: true}),
+		}
+
+		out, err := authSvc.Login(LoginInput{
+			Email:    "test@example.com",
+			Password: "password",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if !out.MfaRequired {
+			t.Error("expected mfa required")
+		}
+		if out.MfaToken == "" {
+			t.Error("expected mfa token")
+		}
+		if out.AccessToken != "" || out.RefreshToken != "" {
+			t.Error("expected no tokens before mfa verification")
+		}
+
+		userRefreshTokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+		jwtTokenMock.AssertNotCalled(t, "Sign", mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoginFailMfaCheck(t *testing.T) {
+	crypt := atylabencrypt.NewEncryptPkg()
+
+	passwordHash, err := crypt.CreatePasswordHash("password")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	userRepoMock := new(repo_mock.UserRepoMock)
+	userRepoMock.On(
+		"GetByEmail", "test@example.com",
+	).Return(&models.User{
+		ID:           1,
+		PasswordHash: passwordHash,
+	}, nil)
+
+	userTotpRepoMock := new(repo_mock.UserTotpRepoMock)
+	userTotpRepoMock.On("GetByUserID", uint(1)).Return((*models.UserTotp)(nil), fmt.Errorf("db error"))
+
+	authSvc := &AuthSvcStruct{
+		userRepo: userRepoMock,
+		mfa:      &MfaSvcStruct{userTotpRepo: userTotpRepoMock},
+	}
+
+	_, err = authSvc.Login(LoginInput{
+		Email:    "test@example.com",
+		Password: "password",
+	})
+	if err == nil {
+		t.Fatal("expected error, but got none")
+	}
+}
+
+func TestVerifyMfaSuccess(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		clock := atylabclock.NewClockMock(time.Now())
+		user := &models.User{
+			ID:    1,
+			UUID:  "test-uuid",
+			Email: "test@example.com",
+		}
+
+		mfaSvc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 1, UserID: 1, Enabled: true})
+		// ログイン時に絞り込んだスコープを引き継ぐ
+		mfaToken, err := mfaSvc.CreateChallenge(user, AuthContext{Amr: []string{AmrPwd}, Scope: ScopeAccount})
+		if err != nil {
+			t.Fatalf("failed to create challenge: %v", err)
+		}
+		mfaSvc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(user, nil)
+		mfaSvc.userRecoveryCodeRepo.(*repo_mock.UserRecoveryCodeRepoMock).
+			On("Use", uint(1), models.HashRecoveryCode("abcde-fghij")).Return(nil)
+
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On(
+			"CreateRefreshToken", matchRefreshToken(1, "", clock.Now(), "pwd otp mfa"),
+		).Return(&models.UserRefreshToken{
+			RefreshToken: "test-refresh-token",
+		}, nil)
+
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			return claims["acr"] == AcrAal2 && claims["scope"] == ScopeAccount
+		}), mock.Anything).Return("test-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			config:               testAuthConfig,
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                clock,
+			mfa:                  mfaSvc,
+		}
+
+		out, err := authSvc.VerifyMfa(VerifyMfaInput{
+			MfaToken:     mfaToken,
+			RecoveryCode: "abcde-fghij",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if out.AccessToken != "test-access-token" || out.RefreshToken != "test-refresh-token" {
+			t.Errorf("unexpected output: %+v", out)
+		}
+	})
+}
+
+func TestVerifyMfaFail(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		authSvc := &AuthSvcStruct{
+			mfa: newTestMfaSvcWithTotp(nil),
+		}
+
+		_, err := authSvc.VerifyMfa(VerifyMfaInput{
+			MfaToken: "invalid-token",
+			Code:     "123456",
+		})
+		if !errors.Is(err, ErrInvalidMfaToken) {
+			t.Fatalf("expected ErrInvalidMfaToken, but got %v", err)
+		}
+	})
+}
+
+func TestLoginWithPasskeySuccess(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		authenticator, credential := newTestRegisteredAuthenticator(0)
+
+		webauthnSvc := newTestWebauthnSvc()
+		challenge := expectChallenge(webauthnSvc, "webauthn.get", nil)
+		webauthnSvc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testWebauthnUser, nil)
+		credentialRepoMock := webauthnSvc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock)
+		credentialRepoMock.On("GetByCredentialID", credential.CredentialID).Return(credential, nil)
+		credentialRepoMock.On("UpdateSignCount", uint(10), uint32(1)).Return(nil)
+
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On(
+			"CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+				return token.UserID == 1 && token.Amr == "hwk mfa"
+			}),
+		).Return(&models.UserRefreshToken{
+			RefreshToken: "test-refresh-token",
+		}, nil)
+
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("test-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+			webauthn:             webauthnSvc,
+		}
+
+		clientDataJSON, authData, signature := authenticator.GetAssertion(testChallengeBytes(t, challenge))
+		out, err := authSvc.LoginWithPasskey(WebauthnLoginInput{
+			CredentialID:      credential.CredentialID,
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+			UserHandle:        []byte("test-uuid"),
+		})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+
+		if out.AccessToken != "test-access-token" || out.RefreshToken != "test-refresh-token" {
+			t.Errorf("unexpected output: %+v", out)
+		}
+	})
+}
+
+func TestLoginWithPasskeyFail(t *testing.T) {
+	webauthnSvc := newTestWebauthnSvc()
+
+	authSvc := &AuthSvcStruct{
+		webauthn: webauthnSvc,
+	}
+
+	_, err := authSvc.LoginWithPasskey(WebauthnLoginInput{
+		ClientDataJSON: []byte("invalid"),
+	})
+	if !errors.Is(err, ErrInvalidWebauthnResponse) {
+		t.Fatalf("expected ErrInvalidWebauthnResponse, but got %v", err)
+	}
+}
+
+func TestCompletePasswordless(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+		passwordlessSvc := newTestPasswordlessSvc()
+		passwordlessSvc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(testPasswordlessUser, nil)
+		tokenRepoMock := passwordlessSvc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+		tokenRepoMock.On("GetLatestByUserID", uint(1)).Return(newTestPasswordlessCodeToken("123456"), nil)
+		tokenRepoMock.On("RegisterAttempt", uint(5), PasswordlessMaxAttempts).Return(nil)
+		tokenRepoMock.On("Consume", uint(5)).Return(nil)
+
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+			return token.UserID == 1 && token.Amr == "email"
+		})).Return(&models.UserRefreshToken{
+			RefreshToken: "test-refresh-token",
+		}, nil)
+
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("test-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+			mfa:                  newTestMfaSvcWithTotp(nil),
+			passwordless:         passwordlessSvc,
+		}
+
+		out, err := authSvc.CompletePasswordless(PasswordlessCompleteInput{Email: "user@example.com", Code: "123456"})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.AccessToken != "test-access-token" || out.RefreshToken != "test-refresh-token" || out.MfaRequired {
+			t.Errorf("unexpected output: %+v", out)
+		}
+	})
+}
+
+func TestCompletePasswordlessMfaRequired(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+		passwordlessSvc := newTestPasswordlessSvc()
+		passwordlessSvc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock).
+			On("ConsumeByTokenHash", models.HashPasswordlessNonce("nonce"), "link").
+			Return(&models.PasswordlessToken{ID: 1, UserID: 1}, nil)
+		passwordlessSvc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testPasswordlessUser, nil)
+
+		authSvc := &AuthSvcStruct{
+			mfa:          newTestMfaSvcWithTotp(&models.UserTotp{UserID: 1, Enabled: true}),
+			passwordless: passwordlessSvc,
+		}
+
+		out, err := authSvc.CompletePasswordless(PasswordlessCompleteInput{
+			Token: newTestPasswordlessLinkToken(t, validPasswordlessLinkClaims()),
+		})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if !out.MfaRequired || out.MfaToken == "" || out.AccessToken != "" {
+			t.Errorf("unexpected output: %+v", out)
+		}
+	})
+}
+
+func TestCompletePasswordlessFail(t *testing.T) {
+	authSvc := &AuthSvcStruct{
+		passwordless: newTestPasswordlessSvc(),
+	}
+
+	_, err := authSvc.CompletePasswordless(PasswordlessCompleteInput{Token: "invalid"})
+	if !errors.Is(err, ErrInvalidPasswordlessToken) {
+		t.Fatalf("expected ErrInvalidPasswordlessToken, but got %v", err)
+	}
+}
+
+func TestExchangeAuthorizationCode(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		now := time.Now()
+		authTime := now.Add(-5 * time.Minute)
+		oauthSvc := newTestOauthSvc()
+		code := newTestOauthAuthorizationCode()
+		code.AuthTime = &authTime
+		oauthSvc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+			On("ConsumeByCodeHash", models.HashOauthAuthorizationCode("test-code")).Return(code, nil)
+		oauthSvc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testOauthUser, nil)
+
+		// クライアントに設定した有効期間で発行し、リフレッシュトークンをクライアントに紐づける
+		client := newTestOauthClient()
+		client.AccessTokenLifetime = 600
+		client.RefreshTokenLifetime = 3600
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On(
+			"CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+				return token.ClientID == "test-client" && token.Scope == "openid profile" && token.ExpiresAt.Equal(now.Add(time.Hour))
+			}),
+		).Return(&models.UserRefreshToken{
+			RefreshToken: "test-refresh-token",
+		}, nil)
+
+		// ログインした時点の auth_time / acr をトークンに引き継ぐ
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			return claims["auth_time"] == authTime.Unix() && claims["acr"] == AcrAal2 &&
+				claims["exp"] == now.Add(10*time.Minute).Unix() && claims["client_id"] == "test-client" &&
+				claims["scope"] == "openid profile"
+		}), mock.Anything).Return("test-access-token", nil)
+
+		oidcSvc := newTestOidcSvc()
+		oidcSvc.clock = atylabclock.NewClockMock(now)
+		authSvc := &AuthSvcStruct{
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(now),
+			oauth:                oauthSvc,
+			oidc:                 oidcSvc,
+		}
+
+		out, err := authSvc.ExchangeAuthorizationCode(client, newTestOauthTokenInput())
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.AccessToken != "test-access-token" || out.RefreshToken != "test-refresh-token" || out.Scope != "openid profile" || out.ExpiresIn != 600 {
+			t.Errorf("unexpected output: %+v", out)
+		}
+
+		// openid スコープを許可した場合は、認可リクエストの nonce を含む ID トークンも発行する
+		claims, _ := parseTestIDToken(t, out.IDToken)
+		if claims["nonce"] != "n-0S6_WzA2Mj" || claims["aud"] != "test-client" || claims["auth_time"] != float64(authTime.Unix()) ||
+			claims["at_hash"] != oidcHalfHash("test-access-token") {
+			t.Errorf("unexpected id token claims: %v", claims)
+		}
+	})
+}
+
+func TestExchangeAuthorizationCodeWithoutRefreshGrant(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		oauthSvc := newTestOauthSvc()
+		code := newTestOauthAuthorizationCode()
+		code.ClientID = "multi-client"
+		oauthSvc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+			On("ConsumeByCodeHash", mock.Anything).Return(code, nil)
+		oauthSvc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testOauthUser, nil)
+
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("test-access-token", nil)
+
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		authSvc := &AuthSvcStruct{
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+			oauth:                oauthSvc,
+			oidc:                 newTestOidcSvc(),
+		}
+
+		// refresh_token グラントを許可していないクライアントにはリフレッシュトークンを発行しない
+		out, err := authSvc.ExchangeAuthorizationCode(newTestOauthMultiClient(), newTestOauthTokenInput())
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.RefreshToken != "" || out.ExpiresIn != AccessTokenExpiresIn {
+			t.Errorf("unexpected output: %+v", out)
+		}
+		userRefreshTokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+	})
+}
+
+func TestExchangeAuthorizationCodeFail(t *testing.T) {
+	oauthSvc := newTestOauthSvc()
+	oauthSvc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+		On("ConsumeByCodeHash", mock.Anything).Return((*models.OauthAuthorizationCode)(nil), repositories.ErrOauthAuthorizationCodeNotFound)
+
+	authSvc := &AuthSvcStruct{
+		oauth: oauthSvc,
+	}
+
+	_, err := authSvc.ExchangeAuthorizationCode(newTestOauthClient(), newTestOauthTokenInput())
+	var oauthErr *OauthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != OauthErrorInvalidGrant {
+		t.Fatalf("expected invalid_grant, but got %v", err)
+	}
+}
+
+func TestExchangeDeviceCode(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		now := time.Now()
+		authTime := now.Add(-time.Minute)
+		oauthSvc := newTestOauthDeviceSvc()
+		code := newTestOauthDeviceCode()
+		code.AuthTime = &authTime
+		codeRepoMock := oauthSvc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+		codeRepoMock.On("GetByDeviceCodeHash", models.HashOauthDeviceCode("device-code")).Return(code, nil)
+		codeRepoMock.On("Consume", uint(3)).Return(nil)
+		oauthSvc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testOauthUser, nil)
+
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On(
+			"CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+				return token.ClientID == "device-client" && token.Scope == "openid"
+			}),
+		).Return(&models.UserRefreshToken{
+			RefreshToken: "test-refresh-token",
+		}, nil)
+
+		// デバイスを承認したログインの auth_time / amr をトークンに引き継ぐ
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			amr, _ := claims["amr"].([]string)
+			return claims["auth_time"] == authTime.Unix() && len(amr) == 1 && amr[0] == AmrPwd &&
+				claims["client_id"] == "device-client" && claims["scope"] == "openid"
+		}), mock.Anything).Return("test-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(now),
+			oauth:                oauthSvc,
+			oidc:                 newTestOidcSvc(),
+		}
+
+		out, err := authSvc.ExchangeDeviceCode(newTestOauthDeviceClient(), "device-code")
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.AccessToken != "test-access-token" || out.RefreshToken != "test-refresh-token" || out.Scope != "openid" || out.IDToken == "" {
+			t.Errorf("unexpected output: %+v", out)
+		}
+	})
+}
+
+func TestExchangeDeviceCodeFail(t *testing.T) {
+	oauthSvc := newTestOauthDeviceSvc()
+	code := newTestOauthDeviceCode()
+	code.Status = models.OauthDeviceCodeStatusPending
+	codeRepoMock := oauthSvc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+	codeRepoMock.On("GetByDeviceCodeHash", mock.Anything).Return(code, nil)
+	codeRepoMock.On("UpdatePolling", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	authSvc := &AuthSvcStruct{
+		oauth: oauthSvc,
+	}
+
+	_, err := authSvc.ExchangeDeviceCode(newTestOauthDeviceClient(), "device-code")
+	var oauthErr *OauthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != OauthErrorAuthorizationPending {
+		t.Fatalf("expected authorization_pending, but got %v", err)
+	}
+}
+
+func TestRefreshForClient(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("GetUserByRefreshToken", "valid-refresh-token").Return(testOauthUser, &models.UserRefreshToken{
+			FamilyID: "family-id",
+			ClientID: "test-client",
+			Scope:    "profile",
+			Amr:      "pwd",
+		}, nil)
+		userRefreshTokenRepo.On("ChangeUsed", "valid-refresh-token", "127.0.0.1").Return(nil)
+		userRefreshTokenRepo.On("CreateRefreshToken", mock.MatchedBy(func(token *models.UserRefreshToken) bool {
+			return token.ClientID == "test-client" && token.FamilyID == "family-id" && token.Scope == "profile"
+		})).Return(&models.UserRefreshToken{RefreshToken: "new-refresh-token"}, nil)
+
+		// 許可したスコープはリフレッシュ後も引き継ぐ
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			return claims["scope"] == "profile"
+		}), mock.Anything).Return("new-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			userRefreshTokenRepo: userRefreshTokenRepo,
+			jwttoken:             jwtTokenMock,
+			roles:                newTestRoleSvc(),
+			clock:                atylabclock.NewClockMock(time.Now()),
+		}
+
+		out, err := authSvc.RefreshForClient(newTestOauthClient(), RefreshInput{RefreshToken: "valid-refresh-token", IpAddress: "127.0.0.1"})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.AccessToken != "new-access-token" || out.RefreshToken != "new-refresh-token" || out.Scope != "profile" || out.IDToken != "" {
+			t.Errorf("unexpected output: %+v", out)
+		}
+	})
+}
+
+func TestRefreshForClientFail(t *testing.T) {
+	newAuthSvc := func(record *models.UserRefreshToken, err error) *AuthSvcStruct {
+		userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+		userRefreshTokenRepo.On("GetUserByRefreshToken", "refresh-token").Return(testOauthUser, record, err)
+		return &AuthSvcStruct{userRefreshTokenRepo: userRefreshTokenRepo}
+	}
+	input := RefreshInput{RefreshToken: "refresh-token"}
+
+	t.Run("grant not allowed", func(t *testing.T) {
+		_, err := newAuthSvc(&models.UserRefreshToken{ClientID: "multi-client"}, nil).RefreshForClient(newTestOauthMultiClient(), input)
+		assertOauthError(t, err, OauthErrorUnauthorizedClient)
+	})
+
+	t.Run("another client", func(t *testing.T) {
+		_, err := newAuthSvc(&models.UserRefreshToken{ClientID: "other-client"}, nil).RefreshForClient(newTestOauthClient(), input)
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+
+	t.Run("first party session", func(t *testing.T) {
+		_, err := newAuthSvc(&models.UserRefreshToken{}, nil).RefreshForClient(newTestOauthClient(), input)
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+
+	t.Run("used token", func(t *testing.T) {
+		_, err := newAuthSvc(nil, repositories.ErrRefreshTokenAlreadyUsed).RefreshForClient(newTestOauthClient(), input)
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+
+	t.Run("scope not granted", func(t *testing.T) {
+		_, err := newAuthSvc(&models.UserRefreshToken{ClientID: "test-client", Scope: "profile"}, nil).RefreshForClient(newTestOauthClient(), RefreshInput{RefreshToken: "refresh-token", Scope: "profile email"})
+		assertOauthError(t, err, OauthErrorInvalidScope)
+	})
+
+	// クライアントに発行したトークンは自サービスのリフレッシュでは使えない
+	t.Run("client token on first party refresh", func(t *testing.T) {
+		_, err := newAuthSvc(&models.UserRefreshToken{ClientID: "test-client"}, nil).Refresh(input)
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Fatalf("expected ErrInvalidRefreshToken, but got %v", err)
+		}
+	})
+}
+
+func newTestClientCredentialsClient() *models.OauthClient {
+	return &models.OauthClient{
+		ClientID:                "batch-client",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodClientSecretBasic,
+		GrantTypes:              OauthGrantTypeClientCredentials,
+		Scopes:                  "jobs:read jobs:write",
+	}
+}
+
+func TestIssueClientCredentials(t *testing.T) {
+	tests := map[string]struct {
+		scope     string
+		audiences string
+		expected  string
+		aud       any
+	}{
+		"requested scope": {"jobs:read", "", "jobs:read", "https://auth.example.com"},
+		"omitted scope":   {"", "", "jobs:read jobs:write", "https://auth.example.com"},
+		// 受け手を登録したクライアントはそのリソースサーバー向けのトークンになる
+		"registered audience": {"jobs:read", "https://jobs.example.com https://reports.example.com", "jobs:read", []string{"https://jobs.example.com", "https://reports.example.com"}},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+				now := time.Now()
+				client := newTestClientCredentialsClient()
+				client.AccessTokenLifetime = 300
+				client.Audiences = tt.audiences
+
+				// sub はクライアント ID で、ユーザーのトークンと区別できる
+				jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+				jwtTokenMock.On("Sign", jwt.MapClaims{
+					"iss":       "https://auth.example.com",
+					"aud":       tt.aud,
+					"sub":       "batch-client",
+					"client_id": "batch-client",
+					"iat":       now.Unix(),
+					"nbf":       now.Unix(),
+					"exp":       now.Add(5 * time.Minute).Unix(),
+					"principal": PrincipalClient,
+					"scope":     tt.expected,
+				}, []byte("testsecretkey")).Return("client-access-token", nil)
+
+				userRefreshTokenRepo := new(repo_mock.UserRefreshTokenRepoMock)
+				authSvc := &AuthSvcStruct{
+					config:               testAuthConfig,
+					userRefreshTokenRepo: userRefreshTokenRepo,
+					jwttoken:             jwtTokenMock,
+					roles:                newTestRoleSvc(),
+					clock:                atylabclock.NewClockMock(now),
+				}
+
+				out, err := authSvc.IssueClientCredentials(client, OauthClientCredentialsInput{Scope: tt.scope})
+				if err != nil {
+					t.Fatalf("expected no error, but got %v", err)
+				}
+				if out.AccessToken != "client-access-token" || out.RefreshToken != "" || out.Scope != tt.expected || out.ExpiresIn != 300 {
+					t.Errorf("unexpected output: %+v", out)
+				}
+				userRefreshTokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+			})
+		})
+	}
+}
+
+func TestIssueClientCredentialsFail(t *testing.T) {
+	t.Run("grant not allowed", func(t *testing.T) {
+		_, err := (&AuthSvcStruct{}).IssueClientCredentials(newTestOauthClient(), OauthClientCredentialsInput{})
+		assertOauthError(t, err, OauthErrorUnauthorizedClient)
+	})
+
+	t.Run("public client", func(t *testing.T) {
+		client := newTestClientCredentialsClient()
+		client.TokenEndpointAuthMethod = models.OauthClientAuthMethodNone
+		_, err := (&AuthSvcStruct{}).IssueClientCredentials(client, OauthClientCredentialsInput{})
+		assertOauthError(t, err, OauthErrorUnauthorizedClient)
+	})
+
+	t.Run("scope not allowed", func(t *testing.T) {
+		_, err := (&AuthSvcStruct{}).IssueClientCredentials(newTestClientCredentialsClient(), OauthClientCredentialsInput{Scope: "jobs:read admin"})
+		assertOauthError(t, err, OauthErrorInvalidScope)
+	})
+
+	t.Run("sign error", func(t *testing.T) {
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("", fmt.Errorf("sign error"))
+		authSvc := &AuthSvcStruct{jwttoken: jwtTokenMock, clock: atylabclock.NewClockMock(time.Now())}
+		if _, err := authSvc.IssueClientCredentials(newTestClientCredentialsClient(), OauthClientCredentialsInput{}); err == nil {
+			t.Fatal("expected error, but got none")
+		}
+	})
+}
+
+func TestAuthContextAcr(t *testing.T) {
+	tests := map[string]struct {
+		amr      []string
+		expected string
+	}{
+		"password":     {[]string{AmrPwd}, AcrAal1},
+		"passwordless": {[]string{AmrEmail}, AcrAal1},
+		"password+otp": {[]string{AmrPwd, AmrOtp, AmrMfa}, AcrAal2},
+		"passkey":      {[]string{AmrHwk, AmrMfa}, AcrAal2},
+		"unknown":      {nil, AcrAal1},
+	}
+
+	for title, tt := range tests {
+		if acr := (AuthContext{Amr: tt.amr}).Acr(); acr != tt.expected {
+			t.Errorf("%s: expected %s, got %s", title, tt.expected, acr)
+		}
+	}
+}
+
+func TestExchangeToken(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		now := time.Now()
+		oauthSvc := newTestOauthExchangeSvc()
+		oauthSvc.oauthTokenExchangeRepo.(*repo_mock.OauthTokenExchangeRepoMock).On("Create", mock.MatchedBy(func(exchange *models.OauthTokenExchange) bool {
+			return exchange.Jti != "" && exchange.ClientID == "exchange-client" && exchange.SubjectUserID == 1 &&
+				exchange.Actor == "exchange-client" && !exchange.Impersonation && exchange.Audience == "test-client" &&
+				exchange.Scope == "profile" && exchange.IpAddress == "127.0.0.1"
+		})).Return(nil)
+
+		// 委任したクライアントを act に、ログインの認証時刻と方式を引き継ぐ
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			act, _ := claims["act"].(map[string]any)
+			return claims["sub"] == "usertest-uuid" && claims["aud"] == "test-client" && claims["scope"] == "profile" &&
+				claims["iss"] == "https://auth.example.com" && claims["nbf"] == now.Unix() &&
+				claims["client_id"] == "exchange-client" && act["sub"] == "exchange-client" &&
+				claims["acr"] == AcrAal2 && claims["sid"] == "session-id" && claims["auth_time"] != nil && claims["jti"] != ""
+		}), mock.Anything).Return("test-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			config:   testAuthConfig,
+			jwttoken: jwtTokenMock,
+			roles:    newTestRoleSvc(),
+			clock:    atylabclock.NewClockMock(now),
+			oauth:    oauthSvc,
+		}
+
+		out, err := authSvc.ExchangeToken(newTestOauthExchangeClient(), OauthTokenExchangeInput{
+			SubjectToken:     testUserAccessToken(t),
+			SubjectTokenType: OauthTokenTypeAccessToken,
+			Audience:         []string{"test-client"},
+			Scope:            "profile",
+			IpAddress:        "127.0.0.1",
+		})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.AccessToken != "test-access-token" || out.RefreshToken != "" || out.Scope != "profile" ||
+			out.IssuedTokenType != OauthTokenTypeAccessToken || out.ExpiresIn != AccessTokenExpiresIn {
+			t.Errorf("unexpected output: %+v", out)
+		}
+	})
+}
+
+func TestExchangeTokenImpersonation(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		now := time.Now()
+		client := newTestOauthExchangeClient()
+		client.FirstParty = true
+		oauthSvc := newTestOauthExchangeSvc()
+		oauthSvc.oauthTokenExchangeRepo.(*repo_mock.OauthTokenExchangeRepoMock).On("Create", mock.MatchedBy(func(exchange *models.OauthTokenExchange) bool {
+			return exchange.Actor == "userstaff-uuid" && exchange.Impersonation && exchange.SubjectUserID == 1
+		})).Return(nil)
+
+		// 成り代わりのトークンには認証時刻と方式を付けない
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", mock.MatchedBy(func(claims jwt.MapClaims) bool {
+			act, _ := claims["act"].(map[string]any)
+			_, hasAmr := claims["amr"]
+			_, hasAuthTime := claims["auth_time"]
+			return claims["sub"] == "usertest-uuid" && act["sub"] == "userstaff-uuid" && !hasAmr && !hasAuthTime
+		}), mock.Anything).Return("test-access-token", nil)
+
+		authSvc := &AuthSvcStruct{
+			jwttoken: jwtTokenMock,
+			roles:    newTestRoleSvc(),
+			clock:    atylabclock.NewClockMock(now),
+			oauth:    oauthSvc,
+		}
+
+		// スタッフのトークンの残り時間を超えて有効にしない
+		out, err := authSvc.ExchangeToken(client, OauthTokenExchangeInput{
+			RequestedSubject: "test-uuid",
+			ActorToken: signTestExchangeToken(t, jwt.MapClaims{
+				"sub":       "userstaff-uuid",
+				"principal": PrincipalUser,
+				"exp":       now.Add(10 * time.Minute).Unix(),
+			}),
+			ActorTokenType: OauthTokenTypeAccessToken,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		if out.ExpiresIn > 600 || out.ExpiresIn < 590 {
+			t.Errorf("expected the actor token lifetime, got %d", out.ExpiresIn)
+		}
+	})
+}
+
+func TestExchangeTokenFail(t *testing.T) {
+	t.Run("invalid request", func(t *testing.T) {
+		authSvc := &AuthSvcStruct{oauth: newTestOauthExchangeSvc()}
+		_, err := authSvc.ExchangeToken(newTestOauthExchangeClient(), OauthTokenExchangeInput{})
+		assertOauthError(t, err, OauthErrorInvalidRequest)
+	})
+
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		input := OauthTokenExchangeInput{SubjectToken: testUserAccessToken(t), SubjectTokenType: OauthTokenTypeAccessToken}
+
+		t.Run("sign error", func(t *testing.T) {
+			jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+			jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("", fmt.Errorf("sign error"))
+			authSvc := &AuthSvcStruct{jwttoken: jwtTokenMock, clock: atylabclock.NewClockMock(time.Now()), oauth: newTestOauthExchangeSvc()}
+			if _, err := authSvc.ExchangeToken(newTestOauthExchangeClient(), input); err == nil {
+				t.Fatal("expected error, but got none")
+			}
+		})
+
+		// 監査ログに残せない場合はトークンを返さない
+		t.Run("record error", func(t *testing.T) {
+			oauthSvc := newTestOauthExchangeSvc()
+			oauthSvc.oauthTokenExchangeRepo.(*repo_mock.OauthTokenExchangeRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+			jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+			jwtTokenMock.On("Sign", mock.Anything, mock.Anything).Return("test-access-token", nil)
+			authSvc := &AuthSvcStruct{jwttoken: jwtTokenMock, clock: atylabclock.NewClockMock(time.Now()), oauth: oauthSvc}
+			if out, err := authSvc.ExchangeToken(newTestOauthExchangeClient(), input); err == nil || out != nil {
+				t.Fatalf("expected error, but got %+v", out)
+			}
+		})
+	})
 }
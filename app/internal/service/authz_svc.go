@@ -0,0 +1,337 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/google/cel-go/cel"
+)
+
+// ポリシーの effect
+const (
+	AuthzEffectAllow = "allow"
+	AuthzEffectDeny  = "deny"
+)
+
+// 判定の理由。判定ログと API のレスポンスに含める
+const (
+	AuthzReasonAllowed = "allowed"
+	AuthzReasonDenied  = "denied"
+	// 一致する allow ポリシーがない（既定で拒否）
+	AuthzReasonNoMatch = "no_matching_policy"
+	// deny ポリシーの条件を評価できなかったため、安全側に倒して拒否した
+	AuthzReasonConditionError  = "condition_error"
+	AuthzReasonSubjectNotFound = "subject_not_found"
+	// ポリシーファイルを一度も読み込めていない
+	AuthzReasonUnavailable = "policies_unavailable"
+)
+
+type AuthzConfig struct {
+	// ポリシーファイル（JSON）のパス。空の場合はすべて拒否する
+	PoliciesFile string
+	// ポリシーファイルの更新を確認する間隔
+	ReloadInterval time.Duration
+}
+
+func NewAuthzConfigFromEnv() AuthzConfig {
+	return AuthzConfig{
+		PoliciesFile:   os.Getenv("AUTHZ_POLICIES_FILE"),
+		ReloadInterval: envSeconds("AUTHZ_RELOAD_INTERVAL", 10*time.Second),
+	}
+}
+
+// actions は "documents:read" のような操作名。"*" はすべての操作、"documents:*" はリソース単位で一致する
+// condition は CEL の式で、subject / resource / action を参照できる。空の場合は常に一致する
+type AuthzPolicy struct {
+	Name      string
+	Effect    string
+	Actions   []string
+	Condition string
+	program   cel.Program
+}
+
+func (p *AuthzPolicy) matchesAction(action string) bool {
+	for _, pattern := range p.Actions {
+		if pattern == "*" || pattern == action {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, ":*"); ok && strings.HasPrefix(action, prefix+":") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *AuthzPolicy) eval(vars map[string]any) (bool, error) {
+	if p.program == nil {
+		return true, nil
+	}
+	out, _, err := p.program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition returned %T", out.Value())
+	}
+	return matched, nil
+}
+
+// ポリシーファイルの形式
+//
+//	{"policies": [
+//	  {"name": "admin-all", "effect": "allow", "actions": ["*"], "condition": "'admin' in subject.roles"},
+//	  {"name": "owner-edit", "effect": "allow", "actions": ["documents:update"], "condition": "resource.owner == subject.uuid"},
+//	  {"name": "locked", "effect": "deny", "actions": ["documents:*"], "condition": "has(resource.locked) && resource.locked"}
+//	]}
+type authzPolicyFile struct {
+	Policies []struct {
+		Name      string   `json:"name"`
+		Effect    string   `json:"effect"`
+		Actions   []string `json:"actions"`
+		Condition string   `json:"condition"`
+	} `json:"policies"`
+}
+
+func newAuthzEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("subject", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("action", cel.StringType),
+		// JSON の数値は double になるため、int との比較を許可する
+		cel.CrossTypeNumericComparisons(true),
+	)
+}
+
+// 条件式はここでコンパイルし、1 つでも不正なものがあればファイル全体を受け付けない
+func ParseAuthzPolicies(data []byte) ([]AuthzPolicy, error) {
+	var file authzPolicyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse authz policies: %w", err)
+	}
+	env, err := newAuthzEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel env: %w", err)
+	}
+
+	policies := make([]AuthzPolicy, 0, len(file.Policies))
+	for i, entry := range file.Policies {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("policy %d has no name", i)
+		}
+		if entry.Effect != AuthzEffectAllow && entry.Effect != AuthzEffectDeny {
+			return nil, fmt.Errorf("invalid effect in %s: %q", entry.Name, entry.Effect)
+		}
+		if len(entry.Actions) == 0 {
+			return nil, fmt.Errorf("policy %s has no actions", entry.Name)
+		}
+
+		policy := AuthzPolicy{
+			Name:      entry.Name,
+			Effect:    entry.Effect,
+			Actions:   entry.Actions,
+			Condition: entry.Condition,
+		}
+		if strings.TrimSpace(entry.Condition) != "" {
+			ast, issues := env.Compile(entry.Condition)
+			if issues != nil && issues.Err() != nil {
+				return nil, fmt.Errorf("invalid condition in %s: %w", entry.Name, issues.Err())
+			}
+			// resource.locked のように属性をそのまま返す式は dyn になるため、評価時に bool か確認する
+			if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+				return nil, fmt.Errorf("condition in %s must return bool, got %s", entry.Name, ast.OutputType())
+			}
+			program, err := env.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("invalid condition in %s: %w", entry.Name, err)
+			}
+			policy.program = program
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+type AuthzRequest struct {
+	// ユーザーの UUID
+	Subject string
+	Action  string
+	// 呼び出し元のサービスが渡すリソースの属性
+	Resource map[string]any
+}
+
+type AuthzDecision struct {
+	Allowed bool
+	// 判定を決めたポリシーの名前。一致するポリシーがない場合は空
+	Policy string
+	Reason string
+}
+
+type AuthzSvcInterface interface {
+	Check(request AuthzRequest) (*AuthzDecision, error)
+	CheckBatch(requests []AuthzRequest) ([]AuthzDecision, error)
+}
+
+type AuthzSvcStruct struct {
+	config   AuthzConfig
+	userRepo repositories.UserRepoInterface
+	roles    RoleSvcInterface
+	policies *fileReloader[[]AuthzPolicy]
+}
+
+func NewAuthzSvc(
+	config AuthzConfig,
+	userRepo repositories.UserRepoInterface,
+	roles RoleSvcInterface,
+	clock atylabclock.ClockInterface,
+) *AuthzSvcStruct {
+	s := &AuthzSvcStruct{
+		config:   config,
+		userRepo: userRepo,
+		roles:    roles,
+	}
+	if config.PoliciesFile != "" {
+		s.policies = newFileReloader(config.PoliciesFile, config.ReloadInterval, clock, "authz", "policies file", ParseAuthzPolicies)
+	}
+	return s
+}
+
+func (s *AuthzSvcStruct) Check(request AuthzRequest) (*AuthzDecision, error) {
+	decisions, err := s.CheckBatch([]AuthzRequest{request})
+	if err != nil {
+		return nil, err
+	}
+	return &decisions[0], nil
+}
+
+// 同じユーザーの属性は 1 回だけ取得する
+func (s *AuthzSvcStruct) CheckBatch(requests []AuthzRequest) ([]AuthzDecision, error) {
+	policies, loaded := s.currentPolicies()
+
+	subjects := map[string]map[string]any{}
+	decisions := make([]AuthzDecision, 0, len(requests))
+	for _, request := range requests {
+		subject, ok := subjects[request.Subject]
+		if !ok {
+			var err error
+			subject, err = s.subject(request.Subject)
+			if err != nil {
+				return nil, err
+			}
+			subjects[request.Subject] = subject
+		}
+
+		var decision AuthzDecision
+		switch {
+		case !loaded:
+			decision = AuthzDecision{Reason: AuthzReasonUnavailable}
+		case subject == nil:
+			decision = AuthzDecision{Reason: AuthzReasonSubjectNotFound}
+		default:
+			decision = decideAuthz(policies, request, subject)
+		}
+		logAuthzDecision(request, decision)
+		decisions = append(decisions, decision)
+	}
+	return decisions, nil
+}
+
+// deny ポリシーが allow より優先され、どのポリシーにも一致しなければ拒否する
+func decideAuthz(policies []AuthzPolicy, request AuthzRequest, subject map[string]any) AuthzDecision {
+	resource := request.Resource
+	if resource == nil {
+		resource = map[string]any{}
+	}
+	vars := map[string]any{
+		"subject":  subject,
+		"resource": resource,
+		"action":   request.Action,
+	}
+
+	var allowedBy string
+	for i := range policies {
+		policy := &policies[i]
+		if !policy.matchesAction(request.Action) {
+			continue
+		}
+		matched, err := policy.eval(vars)
+		if err != nil {
+			// 属性が足りない等で評価できない deny ポリシーは一致したものとみなす
+			if policy.Effect == AuthzEffectDeny {
+				return AuthzDecision{Policy: policy.Name, Reason: AuthzReasonConditionError}
+			}
+			continue
+		}
+		if !matched {
+			continue
+		}
+		if policy.Effect == AuthzEffectDeny {
+			return AuthzDecision{Policy: policy.Name, Reason: AuthzReasonDenied}
+		}
+		if allowedBy == "" {
+			allowedBy = policy.Name
+		}
+	}
+
+	if allowedBy == "" {
+		return AuthzDecision{Reason: AuthzReasonNoMatch}
+	}
+	return AuthzDecision{Allowed: true, Policy: allowedBy, Reason: AuthzReasonAllowed}
+}
+
+// 条件式から参照できるユーザーの属性。存在しないユーザーの場合は nil を返す
+func (s *AuthzSvcStruct) subject(userUUID string) (map[string]any, error) {
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	authorization, err := s.roles.Authorization(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	return newAuthzSubject(user, authorization), nil
+}
+
+func newAuthzSubject(user *models.User, authorization *UserAuthorization) map[string]any {
+	return map[string]any{
+		"uuid":        user.UUID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"locale":      user.Locale,
+		"created_at":  user.CreatedAt,
+		"roles":       authorization.Roles,
+		"permissions": authorization.Permissions,
+	}
+}
+
+// 判定ログ。リソースの属性は個人情報を含み得るため、id のみ記録する
+func logAuthzDecision(request AuthzRequest, decision AuthzDecision) {
+	result := "deny"
+	if decision.Allowed {
+		result = "allow"
+	}
+	resourceID := ""
+	if id, ok := request.Resource["id"]; ok {
+		resourceID = fmt.Sprint(id)
+	}
+	log.Printf("[authz] decision=%s subject=%s action=%s resource=%s policy=%s reason=%s",
+		result, request.Subject, request.Action, resourceID, decision.Policy, decision.Reason)
+}
+
+func (s *AuthzSvcStruct) currentPolicies() ([]AuthzPolicy, bool) {
+	if s.config.PoliciesFile == "" {
+		return nil, true
+	}
+	return s.policies.current()
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/stretchr/testify/mock"
+)
+
+const testAuthzPolicies = `{"policies": [
+	{"name": "admin-all", "effect": "allow", "actions": ["*"], "condition": "'admin' in subject.roles"},
+	{"name": "owner-edit", "effect": "allow", "actions": ["documents:update"], "condition": "resource.owner == subject.uuid"},
+	{"name": "read-documents", "effect": "allow", "actions": ["documents:read"], "condition": "'users:read' in subject.permissions && resource.size < 100"},
+	{"name": "locked", "effect": "deny", "actions": ["documents:*"], "condition": "has(resource.locked) && resource.locked"},
+	{"name": "archived", "effect": "deny", "actions": ["documents:delete"], "condition": "resource.archived"}
+]}`
+
+func writeAuthzPolicies(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write policies: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mod time: %v", err)
+	}
+}
+
+func newTestAuthzSvc(t *testing.T, policies string, roles ...models.Role) *AuthzSvcStruct {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policies.json")
+	writeAuthzPolicies(t, path, policies, time.Now())
+
+	roleSvc := newTestRoleSvc(roles...)
+	return NewAuthzSvc(
+		AuthzConfig{PoliciesFile: path, ReloadInterval: time.Minute},
+		roleSvc.userRepo,
+		roleSvc,
+		atylabclock.NewClockMock(time.Now()),
+	)
+}
+
+func TestNewAuthzConfigFromEnv(t *testing.T) {
+	config := NewAuthzConfigFromEnv()
+	if config.PoliciesFile != "" || config.ReloadInterval != 10*time.Second {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+
+	funcs.WithEnv("AUTHZ_POLICIES_FILE", "/etc/auth/policies.json", t, func() {
+		funcs.WithEnv("AUTHZ_RELOAD_INTERVAL", "30", t, func() {
+			config := NewAuthzConfigFromEnv()
+			if config.PoliciesFile != "/etc/auth/policies.json" || config.ReloadInterval != 30*time.Second {
+				t.Errorf("unexpected config: %+v", config)
+			}
+		})
+	})
+}
+
+func TestParseAuthzPoliciesInvalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"policies": [{"effect": "allow", "actions": ["*"]}]}`,
+		`{"policies": [{"name": "p", "effect": "permit", "actions": ["*"]}]}`,
+		`{"policies": [{"name": "p", "effect": "allow"}]}`,
+		`{"policies": [{"name": "p", "effect": "allow", "actions": ["*"], "condition": "subject.uuid =="}]}`,
+		`{"policies": [{"name": "p", "effect": "allow", "actions": ["*"], "condition": "size(subject.roles)"}]}`,
+		`{"policies": [{"name": "p", "effect": "allow", "actions": ["*"], "condition": "user.uuid == ''"}]}`,
+	} {
+		if _, err := ParseAuthzPolicies([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestAuthzCheck(t *testing.T) {
+	reader := models.Role{ID: 3, Name: "reader", Permissions: []models.Permission{{Name: "users:read"}}}
+
+	tests := map[string]struct {
+		roles    []models.Role
+		request  AuthzRequest
+		expected AuthzDecision
+	}{
+		"admin": {
+			[]models.Role{testAdminRole},
+			AuthzRequest{Subject: "test-uuid", Action: "billing:refund"},
+			AuthzDecision{Allowed: true, Policy: "admin-all", Reason: AuthzReasonAllowed},
+		},
+		"owner": {
+			nil,
+			AuthzRequest{Subject: "test-uuid", Action: "documents:update", Resource: map[string]any{"owner": "test-uuid"}},
+			AuthzDecision{Allowed: true, Policy: "owner-edit", Reason: AuthzReasonAllowed},
+		},
+		"not owner": {
+			nil,
+			AuthzRequest{Subject: "test-uuid", Action: "documents:update", Resource: map[string]any{"owner": "other-uuid"}},
+			AuthzDecision{Reason: AuthzReasonNoMatch},
+		},
+		"permission and json number": {
+			[]models.Role{reader},
+			AuthzRequest{Subject: "test-uuid", Action: "documents:read", Resource: map[string]any{"size": float64(10)}},
+			AuthzDecision{Allowed: true, Policy: "read-documents", Reason: AuthzReasonAllowed},
+		},
+		"missing attribute in allow": {
+			[]models.Role{reader},
+			AuthzRequest{Subject: "test-uuid", Action: "documents:read"},
+			AuthzDecision{Reason: AuthzReasonNoMatch},
+		},
+		"deny overrides allow": {
+			[]models.Role{testAdminRole},
+			AuthzRequest{Subject: "test-uuid", Action: "documents:update", Resource: map[string]any{"locked": true}},
+			AuthzDecision{Policy: "locked", Reason: AuthzReasonDenied},
+		},
+		"missing attribute in deny": {
+			[]models.Role{testAdminRole},
+			AuthzRequest{Subject: "test-uuid", Action: "documents:delete"},
+			AuthzDecision{Policy: "archived", Reason: AuthzReasonConditionError},
+		},
+		"non-bool attribute in deny": {
+			[]models.Role{testAdminRole},
+			AuthzRequest{Subject: "test-uuid", Action: "documents:read", Resource: map[string]any{"locked": "yes"}},
+			AuthzDecision{Policy: "locked", Reason: AuthzReasonConditionError},
+		},
+		"subject not found": {
+			[]models.Role{testAdminRole},
+			AuthzRequest{Subject: "unknown-uuid", Action: "documents:read"},
+			AuthzDecision{Reason: AuthzReasonSubjectNotFound},
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestAuthzSvc(t, testAuthzPolicies, tt.roles...)
+			decision, err := svc.Check(tt.request)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if *decision != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, *decision)
+			}
+		})
+	}
+}
+
+func TestAuthzCheckBatch(t *testing.T) {
+	svc := newTestAuthzSvc(t, testAuthzPolicies)
+	decisions, err := svc.CheckBatch([]AuthzRequest{
+		{Subject: "test-uuid", Action: "documents:update", Resource: map[string]any{"owner": "test-uuid"}},
+		{Subject: "test-uuid", Action: "documents:update", Resource: map[string]any{"owner": "other-uuid"}},
+		{Subject: "unknown-uuid", Action: "documents:update"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(decisions) != 3 || !decisions[0].Allowed || decisions[1].Allowed || decisions[2].Reason != AuthzReasonSubjectNotFound {
+		t.Errorf("unexpected decisions: %+v", decisions)
+	}
+
+	// 同じユーザーの属性は 1 回だけ取得する
+	svc.userRepo.(*repo_mock.UserRepoMock).AssertNumberOfCalls(t, "GetByUUID", 2)
+}
+
+func TestAuthzCheckFail(t *testing.T) {
+	t.Run("user repo error", func(t *testing.T) {
+		userRepo := new(repo_mock.UserRepoMock)
+		userRepo.On("GetByUUID", "test-uuid").Return((*models.User)(nil), errors.New("db error"))
+
+		svc := newTestAuthzSvc(t, testAuthzPolicies)
+		svc.userRepo = userRepo
+		if _, err := svc.Check(AuthzRequest{Subject: "test-uuid", Action: "documents:read"}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+
+	t.Run("roles error", func(t *testing.T) {
+		userRoleRepo := new(repo_mock.UserRoleRepoMock)
+		userRoleRepo.On("ListRoles", mock.Anything).Return([]models.Role(nil), errors.New("db error"))
+
+		svc := newTestAuthzSvc(t, testAuthzPolicies)
+		svc.roles.(*RoleSvcStruct).userRoleRepo = userRoleRepo
+		if _, err := svc.Check(AuthzRequest{Subject: "test-uuid", Action: "documents:read"}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestAuthzCheckWithoutPoliciesFile(t *testing.T) {
+	roleSvc := newTestRoleSvc(testAdminRole)
+	svc := NewAuthzSvc(AuthzConfig{}, roleSvc.userRepo, roleSvc, atylabclock.NewClockMock(time.Now()))
+
+	decision, err := svc.Check(AuthzRequest{Subject: "test-uuid", Action: "documents:read"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decision.Allowed || decision.Reason != AuthzReasonNoMatch {
+		t.Errorf("expected deny without policies, got %+v", decision)
+	}
+}
+
+func TestAuthzDeniesWhenPoliciesNeverLoaded(t *testing.T) {
+	svc := newTestAuthzSvc(t, `invalid`, testAdminRole)
+
+	decision, err := svc.Check(AuthzRequest{Subject: "test-uuid", Action: "documents:read"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decision.Allowed || decision.Reason != AuthzReasonUnavailable {
+		t.Errorf("expected deny when policies could not be loaded, got %+v", decision)
+	}
+}
+
+func TestAuthzReload(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "policies.json")
+	writeAuthzPolicies(t, path, `{"policies": [{"name": "v1", "effect": "allow", "actions": ["documents:read"]}]}`, now.Add(-time.Hour))
+
+	roleSvc := newTestRoleSvc()
+	svc := NewAuthzSvc(AuthzConfig{PoliciesFile: path, ReloadInterval: time.Minute}, roleSvc.userRepo, roleSvc, atylabclock.NewClockMock(now))
+	check := func() *AuthzDecision {
+		t.Helper()
+		decision, err := svc.Check(AuthzRequest{Subject: "test-uuid", Action: "documents:read"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return decision
+	}
+	if decision := check(); decision.Policy != "v1" {
+		t.Fatalf("expected initial policy, got %+v", decision)
+	}
+
+	writeAuthzPolicies(t, path, `{"policies": [{"name": "v2", "effect": "allow", "actions": ["documents:read"]}]}`, now)
+
+	// 確認間隔内は読み直さない
+	if decision := check(); decision.Policy != "v1" {
+		t.Errorf("expected policies not to be reloaded within interval, got %+v", decision)
+	}
+
+	svc.policies.clock = atylabclock.NewClockMock(now.Add(2 * time.Minute))
+	if decision := check(); decision.Policy != "v2" {
+		t.Errorf("expected updated policies to be applied, got %+v", decision)
+	}
+
+	// 壊れたファイルに更新された場合は直前のポリシーを使い続ける
+	writeAuthzPolicies(t, path, `{"policies": [{"name": "v3", "effect": "allow", "actions": ["*"], "condition": "("}]}`, now.Add(time.Minute))
+	svc.policies.clock = atylabclock.NewClockMock(now.Add(4 * time.Minute))
+	if decision := check(); decision.Policy != "v2" {
+		t.Errorf("expected previous policies to be kept, got %+v", decision)
+	}
+}
+
+func TestNewAuthzSubject(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	subject := newAuthzSubject(
+		&models.User{UUID: "test-uuid", Username: "alice", Email: "alice@example.com", CreatedAt: createdAt},
+		&UserAuthorization{Roles: []string{"admin"}, Permissions: []string{"users:read"}},
+	)
+	if subject["uuid"] != "test-uuid" || subject["email"] != "alice@example.com" || subject["created_at"] != createdAt {
+		t.Errorf("unexpected subject: %+v", subject)
+	}
+
+	// 日時の属性は CEL の timestamp として比較できる
+	policies, err := ParseAuthzPolicies([]byte(`{"policies": [{"name": "old-users", "effect": "allow", "actions": ["*"], "condition": "subject.created_at < timestamp('2025-01-01T00:00:00Z')"}]}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	decision := decideAuthz(policies, AuthzRequest{Action: "documents:read"}, subject)
+	if !decision.Allowed {
+		t.Errorf("expected timestamp comparison to match, got %+v", decision)
+	}
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// 0 以上の整数を読む。未設定や不正な値の場合は defaultValue
+func envInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// 秒数で指定する期間を読む。未設定や不正な値の場合は defaultValue
+func envSeconds(key string, defaultValue time.Duration) time.Duration {
+	return time.Duration(envInt(key, int(defaultValue/time.Second))) * time.Second
+}
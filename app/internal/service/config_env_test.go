@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+)
+
+func TestEnvInt(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expected int
+	}{
+		"set":      {"30", 30},
+		"zero":     {"0", 0},
+		"unset":    {"", 10},
+		"negative": {"-1", 10},
+		"invalid":  {"abc", 10},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			funcs.WithEnv("TEST_ENV_INT", tt.value, t, func() {
+				if got := envInt("TEST_ENV_INT", 10); got != tt.expected {
+					t.Errorf("expected %d, got %d", tt.expected, got)
+				}
+				if got := envSeconds("TEST_ENV_INT", 10*time.Second); got != time.Duration(tt.expected)*time.Second {
+					t.Errorf("expected %ds, got %v", tt.expected, got)
+				}
+			})
+		})
+	}
+}
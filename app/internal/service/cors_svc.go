@@ -0,0 +1,87 @@
+package service
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// クロスオリジンのリクエストを許可するオリジンと、レスポンスに付ける CORS ヘッダーの設定
+type CorsConfig struct {
+	// 許可するオリジン（scheme://host[:port]）。"https://*.example.com" でサブドメインを、"*" ですべてを許可する
+	AllowedOrigins []string
+	// Cookie や Authorization ヘッダーの送信を許可するオリジン（書式は AllowedOrigins と同じ。"*" は指定できない）
+	CredentialOrigins []string
+	AllowedMethods    []string
+	AllowedHeaders    []string
+	ExposedHeaders    []string
+	MaxAge            time.Duration
+}
+
+func NewCorsConfigFromEnv() CorsConfig {
+	config := CorsConfig{
+		AllowedOrigins:    splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		CredentialOrigins: splitList(os.Getenv("CORS_CREDENTIAL_ORIGINS")),
+		AllowedMethods:    splitList(os.Getenv("CORS_ALLOWED_METHODS")),
+		AllowedHeaders:    splitList(os.Getenv("CORS_ALLOWED_HEADERS")),
+		ExposedHeaders:    splitList(os.Getenv("CORS_EXPOSED_HEADERS")),
+		MaxAge:            envSeconds("CORS_MAX_AGE", 10*time.Minute),
+	}
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	}
+	if len(config.AllowedHeaders) == 0 {
+		config.AllowedHeaders = []string{"Authorization", "Content-Type", "X-CSRF-Token"}
+	}
+	if len(config.ExposedHeaders) == 0 {
+		// 使い捨ての CSRF トークンやステップアップ認証の要求をクライアントが読めるようにする
+		config.ExposedHeaders = []string{"X-CSRF-Token", "WWW-Authenticate"}
+	}
+	return config
+}
+
+func splitList(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, strings.TrimRight(v, "/"))
+		}
+	}
+	return values
+}
+
+// オリジンを許可するか、許可する場合に資格情報の送信も許可するかを返す
+func (c CorsConfig) Match(origin string) (allowed bool, credentials bool) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+		return false, false
+	}
+
+	for _, pattern := range c.CredentialOrigins {
+		if pattern != "*" && matchOrigin(pattern, u) {
+			return true, true
+		}
+	}
+	for _, pattern := range c.AllowedOrigins {
+		if pattern == "*" || matchOrigin(pattern, u) {
+			return true, false
+		}
+	}
+	return false, false
+}
+
+func matchOrigin(pattern string, origin *url.URL) bool {
+	p, err := url.Parse(pattern)
+	if err != nil || !strings.EqualFold(p.Scheme, origin.Scheme) {
+		return false
+	}
+
+	host := strings.ToLower(origin.Host)
+	patternHost := strings.ToLower(p.Host)
+	// "*.example.com" は example.com 自体を含まず、任意の深さのサブドメインにのみ一致する
+	if suffix, ok := strings.CutPrefix(patternHost, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix) && len(host) > len(suffix)+1
+	}
+	return host == patternHost
+}
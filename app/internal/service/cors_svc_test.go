@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+)
+
+func TestNewCorsConfigFromEnv(t *testing.T) {
+	config := NewCorsConfigFromEnv()
+	if len(config.AllowedOrigins) != 0 || len(config.CredentialOrigins) != 0 || config.MaxAge != 10*time.Minute {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+	if len(config.AllowedMethods) == 0 || len(config.AllowedHeaders) == 0 || len(config.ExposedHeaders) == 0 {
+		t.Errorf("expected default methods and headers: %+v", config)
+	}
+
+	funcs.WithEnv("CORS_ALLOWED_ORIGINS", "https://app.example.com/, https://*.example.net", t, func() {
+		funcs.WithEnv("CORS_CREDENTIAL_ORIGINS", "https://app.example.com", t, func() {
+			funcs.WithEnv("CORS_ALLOWED_HEADERS", "Content-Type", t, func() {
+				funcs.WithEnv("CORS_MAX_AGE", "3600", t, func() {
+					config := NewCorsConfigFromEnv()
+					if len(config.AllowedOrigins) != 2 || config.AllowedOrigins[0] != "https://app.example.com" {
+						t.Errorf("unexpected allowed origins: %v", config.AllowedOrigins)
+					}
+					if len(config.CredentialOrigins) != 1 || len(config.AllowedHeaders) != 1 || config.MaxAge != time.Hour {
+						t.Errorf("unexpected config: %+v", config)
+					}
+				})
+			})
+		})
+	})
+}
+
+func TestCorsConfigMatch(t *testing.T) {
+	config := CorsConfig{
+		AllowedOrigins:    []string{"https://*.example.com", "http://localhost:3000"},
+		CredentialOrigins: []string{"https://app.example.com", "https://*.admin.example.com", "*"},
+	}
+
+	tests := []struct {
+		origin      string
+		allowed     bool
+		credentials bool
+	}{
+		{"https://app.example.com", true, true},
+		{"https://APP.example.com", true, true},
+		{"https://console.admin.example.com", true, true},
+		{"https://docs.example.com", true, false},
+		{"https://a.b.example.com", true, false},
+		{"http://localhost:3000", true, false},
+		{"http://localhost:3001", false, false},
+		{"https://example.com", false, false},
+		{"https://evilexample.com", false, false},
+		{"https://example.com.evil.com", false, false},
+		{"http://app.example.com", false, false},
+		{"null", false, false},
+		{"https://app.example.com/path", false, false},
+	}
+
+	for _, tt := range tests {
+		allowed, credentials := config.Match(tt.origin)
+		if allowed != tt.allowed || credentials != tt.credentials {
+			t.Errorf("%s: expected (%v, %v), got (%v, %v)", tt.origin, tt.allowed, tt.credentials, allowed, credentials)
+		}
+	}
+}
+
+func TestCorsConfigMatchWildcard(t *testing.T) {
+	config := CorsConfig{AllowedOrigins: []string{"*"}}
+
+	allowed, credentials := config.Match("https://any.example.org")
+	if !allowed || credentials {
+		t.Errorf("expected any origin without credentials, got (%v, %v)", allowed, credentials)
+	}
+}
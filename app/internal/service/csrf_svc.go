@@ -1,36 +1,233 @@
 package service
 
-import "github.com/AtsuyaOotsuka/portfolio-go-lib/atylabcsrf"
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabcsrf"
+)
+
+// CSRF トークンを載せる Cookie と、状態を変更するリクエストを受け付ける Origin の設定
+type CsrfConfig struct {
+	CookieName     string
+	CookieDomain   string
+	CookieSecure   bool
+	CookieSameSite http.SameSite
+	// 自オリジン以外に許可するオリジン（scheme://host[:port]）
+	TrustedOrigins []string
+	// Bearer の Authorization ヘッダーで認証する API クライアントは検証しない
+	ExemptAPIClients bool
+	// 検証しないルート（"METHOD /path" の形式。パスは gin のルート定義と同じ表記）
+	ExemptRoutes []string
+	// トークンをリフレッシュトークンのファミリー（セッション）に紐付け、ログイン後に発行し直す
+	// セッションは Bearer のアクセストークンの sid、なければログイン時に発行するセッション Cookie から取る
+	// Bearer のリクエストも紐付けて検証するため、有効にすると ExemptAPIClients の既定値は無効になる
+	BindSession bool
+	// ログイン時に発行する、セッション ID を署名付きで載せる Cookie の名前
+	SessionCookieName string
+	// トークンを一度の検証で使い捨てにし、検証のたびに新しいトークンを発行する
+	SingleUse bool
+}
+
+func NewCsrfConfigFromEnv() CsrfConfig {
+	config := CsrfConfig{
+		CookieName:        os.Getenv("CSRF_COOKIE_NAME"),
+		CookieDomain:      os.Getenv("CSRF_COOKIE_DOMAIN"),
+		CookieSecure:      os.Getenv("CSRF_COOKIE_SECURE") != "false",
+		CookieSameSite:    parseSameSite(os.Getenv("CSRF_COOKIE_SAMESITE")),
+		TrustedOrigins:    []string{},
+		ExemptRoutes:      []string{},
+		BindSession:       os.Getenv("CSRF_BIND_SESSION") == "true",
+		SessionCookieName: os.Getenv("CSRF_SESSION_COOKIE_NAME"),
+		SingleUse:         os.Getenv("CSRF_SINGLE_USE") == "true",
+	}
+	switch os.Getenv("CSRF_EXEMPT_API_CLIENTS") {
+	case "true":
+		config.ExemptAPIClients = true
+	case "false":
+		config.ExemptAPIClients = false
+	default:
+		config.ExemptAPIClients = !config.BindSession
+	}
+	if config.CookieName == "" {
+		config.CookieName = "csrf_token"
+	}
+	if config.SessionCookieName == "" {
+		config.SessionCookieName = "csrf_session"
+	}
+	for _, origin := range strings.Split(os.Getenv("CSRF_TRUSTED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.TrustedOrigins = append(config.TrustedOrigins, strings.TrimRight(origin, "/"))
+		}
+	}
+	for _, route := range strings.Split(os.Getenv("CSRF_EXEMPT_ROUTES"), ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			config.ExemptRoutes = append(config.ExemptRoutes, route)
+		}
+	}
+	return config
+}
+
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// Origin（なければ Referer）が自オリジンまたは許可リストに含まれるか
+// どちらのヘッダーもない場合はブラウザ以外からのリクエストとみなして許可する
+func (c CsrfConfig) IsAllowedOrigin(origin string, referer string, host string) bool {
+	if origin == "" && referer == "" {
+		return true
+	}
+	if origin == "" {
+		origin = referer
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+
+	normalized := strings.ToLower(u.Scheme + "://" + u.Host)
+	for _, trusted := range c.TrustedOrigins {
+		if strings.ToLower(trusted) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// 発行したトークンの有効期間（atylabcsrf の検証期限と合わせる）
+const CsrfTokenExpiresIn = 600
+
+var ErrCsrfTokenAlreadyUsed = errors.New("csrf token already used")
 
 type CsrfSvcInterface interface {
-	CreateCSRFToken(timestamp int64, secret string) string
-	Verify(token string, secret string, timestamp int64) error
+	CreateCSRFToken(timestamp int64, secret string, sessionID string) (string, error)
+	Verify(token string, secret string, timestamp int64, sessionID string) error
+	SessionID(accessToken string, sessionCookie string) string
+	SessionCookie(sessionID string) string
 }
 
 type CsrfSvcStruct struct {
-	csrf atylabcsrf.CsrfPkgInterface
+	csrf          atylabcsrf.CsrfPkgInterface
+	config        CsrfConfig
+	csrfTokenRepo repositories.CsrfTokenRepoInterface
+	jwttoken      jwttoken.JwtTokenPkgInterface
 }
 
 func NewCsrfSvcStruct(
 	csrf atylabcsrf.CsrfPkgInterface,
+	config CsrfConfig,
+	csrfTokenRepo repositories.CsrfTokenRepoInterface,
+	jwttoken jwttoken.JwtTokenPkgInterface,
 ) *CsrfSvcStruct {
 	return &CsrfSvcStruct{
-		csrf: csrf,
+		csrf:          csrf,
+		config:        config,
+		csrfTokenRepo: csrfTokenRepo,
+		jwttoken:      jwttoken,
 	}
 }
 
 func (s *CsrfSvcStruct) CreateCSRFToken(
 	timestamp int64,
 	secret string,
-) string {
+	sessionID string,
+) (string, error) {
 	nonceStr := s.csrf.GenerateNonceString()
-	return s.csrf.GenerateCSRFCookieToken(secret, timestamp, nonceStr)
+	token := s.csrf.GenerateCSRFCookieToken(s.sessionSecret(secret, sessionID), timestamp, nonceStr)
+
+	if s.config.SingleUse {
+		if err := s.csrfTokenRepo.Create(&models.CsrfToken{
+			TokenHash: models.HashCsrfToken(token),
+			SessionID: sessionID,
+			ExpiresAt: time.Unix(timestamp+CsrfTokenExpiresIn, 0),
+		}); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
 }
 
 func (s *CsrfSvcStruct) Verify(
 	token string,
 	secret string,
 	timestamp int64,
+	sessionID string,
 ) error {
-	return s.csrf.ValidateCSRFCookieToken(token, secret, timestamp)
+	if err := s.csrf.ValidateCSRFCookieToken(token, s.sessionSecret(secret, sessionID), timestamp); err != nil {
+		return err
+	}
+
+	if s.config.SingleUse {
+		if err := s.csrfTokenRepo.Consume(models.HashCsrfToken(token)); err != nil {
+			if errors.Is(err, repositories.ErrCsrfTokenNotFound) {
+				return ErrCsrfTokenAlreadyUsed
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// 署名鍵にセッション ID を混ぜ、別のセッション（未ログイン時を含む）で発行したトークンを通さない
+func (s *CsrfSvcStruct) sessionSecret(secret string, sessionID string) string {
+	if !s.config.BindSession || sessionID == "" {
+		return secret
+	}
+	return secret + ":" + sessionID
+}
+
+// 有効なアクセストークンの sid クレーム（リフレッシュトークンのファミリー ID）を返す
+// Bearer がない Cookie だけのリクエストでは、署名を検証したセッション Cookie の値を使う
+// どちらも検証できない場合は未ログインとして空文字を返す
+func (s *CsrfSvcStruct) SessionID(accessToken string, sessionCookie string) string {
+	if !s.config.BindSession {
+		return ""
+	}
+	if accessToken != "" {
+		claims, err := s.jwttoken.Parse(accessToken, []byte(os.Getenv("JWT_SECRET_KEY")))
+		if err != nil {
+			return ""
+		}
+		sid, _ := claims["sid"].(string)
+		return sid
+	}
+
+	sid, signature, ok := strings.Cut(sessionCookie, ".")
+	if !ok || sid == "" || !hmac.Equal([]byte(signature), []byte(sessionCookieSignature(sid))) {
+		return ""
+	}
+	return sid
+}
+
+// セッション Cookie に載せる値（セッション ID と CSRF_TOKEN による署名）
+func (s *CsrfSvcStruct) SessionCookie(sessionID string) string {
+	return sessionID + "." + sessionCookieSignature(sessionID)
+}
+
+func sessionCookieSignature(sessionID string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("CSRF_TOKEN")))
+	mac.Write([]byte("csrf-session:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
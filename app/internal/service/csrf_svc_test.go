@@ -1,13 +1,29 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/lib_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabcsrf"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestNewCsrfSvcStruct(t *testing.T) {
-	svc := NewCsrfSvcStruct(&atylabcsrf.CsrfPkgMockStruct{})
+	svc := NewCsrfSvcStruct(
+		&atylabcsrf.CsrfPkgMockStruct{},
+		CsrfConfig{},
+		new(repo_mock.CsrfTokenRepoMock),
+		new(lib_mock.JwtTokenPkgMock),
+	)
 	if svc == nil {
 		t.Error("expected non-nil CsrfSvcStruct")
 	}
@@ -20,7 +36,10 @@ func TestCreateCSRFToken(t *testing.T) {
 		csrf: csrfMock,
 	}
 
-	token := cvs.CreateCSRFToken(1234567890, "test_secret")
+	token, err := cvs.CreateCSRFToken(1234567890, "test_secret", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
 
 	if token != "mocked_csrf_token" {
 		t.Errorf("expected 'mocked_csrf_token', got '%s'", token)
@@ -34,8 +53,193 @@ func TestVerify(t *testing.T) {
 		csrf: csrfMock,
 	}
 
-	err := cvs.Verify("test_token", "test_secret", 1234567890)
+	err := cvs.Verify("test_token", "test_secret", 1234567890, "")
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 }
+
+func TestCsrfTokenBindSession(t *testing.T) {
+	now := time.Now().Unix()
+	bound := CsrfSvcStruct{csrf: atylabcsrf.NewCsrfPkgStruct(), config: CsrfConfig{BindSession: true}}
+	unbound := CsrfSvcStruct{csrf: atylabcsrf.NewCsrfPkgStruct()}
+
+	token, err := bound.CreateCSRFToken(now, "test_secret", "session-a")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := bound.Verify(token, "test_secret", now, "session-a"); err != nil {
+		t.Errorf("expected token to be valid for the same session, got %v", err)
+	}
+	if err := bound.Verify(token, "test_secret", now, "session-b"); err == nil {
+		t.Error("expected token to be rejected for another session")
+	}
+
+	// ログイン前に発行したトークンはログイン後のセッションでは使えない
+	anonymous, _ := bound.CreateCSRFToken(now, "test_secret", "")
+	if err := bound.Verify(anonymous, "test_secret", now, "session-a"); err == nil {
+		t.Error("expected anonymous token to be rejected after login")
+	}
+
+	// 紐付けが無効な場合はセッションを問わない
+	token, _ = unbound.CreateCSRFToken(now, "test_secret", "session-a")
+	if err := unbound.Verify(token, "test_secret", now, "session-b"); err != nil {
+		t.Errorf("expected unbound token to be valid, got %v", err)
+	}
+}
+
+func TestCsrfTokenSingleUse(t *testing.T) {
+	csrfTokenRepoMock := new(repo_mock.CsrfTokenRepoMock)
+	csrfTokenRepoMock.On("Create", mock.MatchedBy(func(token *models.CsrfToken) bool {
+		return token.TokenHash == models.HashCsrfToken("mocked_csrf_token") &&
+			token.SessionID == "session-a" &&
+			token.ExpiresAt.Equal(time.Unix(1234567890+CsrfTokenExpiresIn, 0))
+	})).Return(nil)
+	csrfTokenRepoMock.On("Consume", models.HashCsrfToken("mocked_csrf_token")).Return(nil).Once()
+	csrfTokenRepoMock.On("Consume", models.HashCsrfToken("mocked_csrf_token")).Return(repositories.ErrCsrfTokenNotFound)
+
+	svc := NewCsrfSvcStruct(&atylabcsrf.CsrfPkgMockStruct{}, CsrfConfig{SingleUse: true}, csrfTokenRepoMock, nil)
+
+	token, err := svc.CreateCSRFToken(1234567890, "test_secret", "session-a")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.Verify(token, "test_secret", 1234567890, "session-a"); err != nil {
+		t.Fatalf("expected first use to succeed, got %v", err)
+	}
+	if err := svc.Verify(token, "test_secret", 1234567890, "session-a"); !errors.Is(err, ErrCsrfTokenAlreadyUsed) {
+		t.Fatalf("expected ErrCsrfTokenAlreadyUsed, got %v", err)
+	}
+	csrfTokenRepoMock.AssertExpectations(t)
+}
+
+func TestCsrfTokenSingleUseFail(t *testing.T) {
+	csrfTokenRepoMock := new(repo_mock.CsrfTokenRepoMock)
+	csrfTokenRepoMock.On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+	csrfTokenRepoMock.On("Consume", mock.Anything).Return(fmt.Errorf("db error"))
+
+	svc := NewCsrfSvcStruct(&atylabcsrf.CsrfPkgMockStruct{}, CsrfConfig{SingleUse: true}, csrfTokenRepoMock, nil)
+
+	if _, err := svc.CreateCSRFToken(1234567890, "test_secret", ""); err == nil {
+		t.Error("expected create error, got none")
+	}
+	if err := svc.Verify("mocked_csrf_token", "test_secret", 1234567890, ""); err == nil || errors.Is(err, ErrCsrfTokenAlreadyUsed) {
+		t.Errorf("expected db error, got %v", err)
+	}
+}
+
+func TestCsrfSessionID(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Parse", "valid-token", []byte("testsecretkey")).Return(jwt.MapClaims{"sid": "session-a"}, nil)
+		jwtTokenMock.On("Parse", "invalid-token", []byte("testsecretkey")).Return(nil, fmt.Errorf("invalid token"))
+
+		svc := NewCsrfSvcStruct(nil, CsrfConfig{BindSession: true}, nil, jwtTokenMock)
+		sessionB := svc.SessionCookie("session-b")
+		if sid := svc.SessionID("valid-token", ""); sid != "session-a" {
+			t.Errorf("expected session-a, got %q", sid)
+		}
+		// Bearer がある場合はセッション Cookie より優先する
+		if sid := svc.SessionID("valid-token", sessionB); sid != "session-a" {
+			t.Errorf("expected session-a, got %q", sid)
+		}
+		if sid := svc.SessionID("invalid-token", sessionB); sid != "" {
+			t.Errorf("expected empty session id, got %q", sid)
+		}
+		if sid := svc.SessionID("", ""); sid != "" {
+			t.Errorf("expected empty session id, got %q", sid)
+		}
+		if sid := svc.SessionID("", sessionB); sid != "session-b" {
+			t.Errorf("expected session-b, got %q", sid)
+		}
+		for _, cookie := range []string{"session-b", "session-b.forged", "." + sessionB} {
+			if sid := svc.SessionID("", cookie); sid != "" {
+				t.Errorf("expected empty session id for %q, got %q", cookie, sid)
+			}
+		}
+
+		// 紐付けが無効な場合はトークンを検証しない
+		unbound := NewCsrfSvcStruct(nil, CsrfConfig{}, nil, jwtTokenMock)
+		if sid := unbound.SessionID("valid-token", sessionB); sid != "" {
+			t.Errorf("expected empty session id, got %q", sid)
+		}
+		jwtTokenMock.AssertNumberOfCalls(t, "Parse", 3)
+	})
+}
+
+func TestNewCsrfConfigFromEnv(t *testing.T) {
+	config := NewCsrfConfigFromEnv()
+	if config.CookieName != "csrf_token" || config.SessionCookieName != "csrf_session" || !config.CookieSecure || config.CookieSameSite != http.SameSiteLaxMode || !config.ExemptAPIClients || config.BindSession || config.SingleUse {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+
+	funcs.WithEnv("CSRF_COOKIE_SECURE", "false", t, func() {
+		funcs.WithEnv("CSRF_COOKIE_SAMESITE", "Strict", t, func() {
+			funcs.WithEnv("CSRF_TRUSTED_ORIGINS", "https://app.example.com/, https://admin.example.com", t, func() {
+				config := NewCsrfConfigFromEnv()
+				if config.CookieSecure || config.CookieSameSite != http.SameSiteStrictMode {
+					t.Errorf("unexpected cookie config: %+v", config)
+				}
+				if len(config.TrustedOrigins) != 2 || config.TrustedOrigins[0] != "https://app.example.com" {
+					t.Errorf("unexpected trusted origins: %v", config.TrustedOrigins)
+				}
+			})
+		})
+	})
+
+	funcs.WithEnv("CSRF_BIND_SESSION", "true", t, func() {
+		funcs.WithEnv("CSRF_SINGLE_USE", "true", t, func() {
+			config := NewCsrfConfigFromEnv()
+			if !config.BindSession || !config.SingleUse {
+				t.Errorf("unexpected token config: %+v", config)
+			}
+			// セッションへの紐付けが効くよう、Bearer のリクエストも検証する
+			if config.ExemptAPIClients {
+				t.Error("expected api clients not to be exempt when binding sessions")
+			}
+		})
+
+		funcs.WithEnv("CSRF_EXEMPT_API_CLIENTS", "true", t, func() {
+			if config := NewCsrfConfigFromEnv(); !config.ExemptAPIClients {
+				t.Error("expected explicit CSRF_EXEMPT_API_CLIENTS to be kept")
+			}
+		})
+	})
+
+	funcs.WithEnv("CSRF_EXEMPT_API_CLIENTS", "false", t, func() {
+		funcs.WithEnv("CSRF_EXEMPT_ROUTES", "POST /webhooks/:id, POST /auth/mfa/verify", t, func() {
+			config := NewCsrfConfigFromEnv()
+			if config.ExemptAPIClients {
+				t.Error("expected api clients not to be exempt")
+			}
+			if len(config.ExemptRoutes) != 2 || config.ExemptRoutes[1] != "POST /auth/mfa/verify" {
+				t.Errorf("unexpected exempt routes: %v", config.ExemptRoutes)
+			}
+		})
+	})
+}
+
+func TestCsrfConfigIsAllowedOrigin(t *testing.T) {
+	config := CsrfConfig{TrustedOrigins: []string{"https://app.example.com"}}
+
+	tests := []struct {
+		origin   string
+		referer  string
+		expected bool
+	}{
+		{"", "", true},
+		{"http://api.example.com", "", true},
+		{"HTTPS://APP.EXAMPLE.COM", "", true},
+		{"", "https://app.example.com/path?q=1", true},
+		{"https://app.example.com:8443", "", false},
+		{"http://app.example.com", "", false},
+		{"null", "", false},
+		{"", "not a url", false},
+	}
+
+	for _, tt := range tests {
+		if got := config.IsAllowedOrigin(tt.origin, tt.referer, "api.example.com"); got != tt.expected {
+			t.Errorf("origin=%q referer=%q: expected %v, got %v", tt.origin, tt.referer, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+)
+
+// 設定ファイルを確認間隔ごとに見張り、更新日時が変わっていれば読み直す
+// 読み込みに失敗した場合は直前の値を使い続ける
+type fileReloader[T any] struct {
+	path     string
+	interval time.Duration
+	clock    atylabclock.ClockInterface
+	parse    func(data []byte) (T, error)
+	// ログのタグとファイルの種類（例: "authz", "policies file"）
+	tag  string
+	kind string
+
+	mu        sync.RWMutex
+	value     T
+	loaded    bool
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newFileReloader[T any](
+	path string,
+	interval time.Duration,
+	clock atylabclock.ClockInterface,
+	tag string,
+	kind string,
+	parse func(data []byte) (T, error),
+) *fileReloader[T] {
+	r := &fileReloader[T]{
+		path:     path,
+		interval: interval,
+		clock:    clock,
+		parse:    parse,
+		tag:      tag,
+		kind:     kind,
+	}
+	r.reload()
+	return r
+}
+
+// 現在の値と、一度でも読み込めたかを返す
+func (r *fileReloader[T]) current() (T, bool) {
+	r.reloadIfChanged()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.value, r.loaded
+}
+
+func (r *fileReloader[T]) reloadIfChanged() {
+	now := r.clock.Now()
+	r.mu.RLock()
+	due := now.Sub(r.checkedAt) >= r.interval
+	r.mu.RUnlock()
+	if !due {
+		return
+	}
+	r.reload()
+}
+
+func (r *fileReloader[T]) reload() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkedAt = r.clock.Now()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		log.Printf("[%s] failed to stat %s: %v", r.tag, r.kind, err)
+		return
+	}
+	if r.loaded && info.ModTime().Equal(r.modTime) {
+		return
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		log.Printf("[%s] failed to read %s: %v", r.tag, r.kind, err)
+		return
+	}
+	value, err := r.parse(data)
+	if err != nil {
+		log.Printf("[%s] %v", r.tag, err)
+		return
+	}
+
+	r.value = value
+	r.loaded = true
+	r.modTime = info.ModTime()
+	log.Printf("[%s] loaded %s", r.tag, r.kind)
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+)
+
+func TestFileReloaderLoadsFileCreatedLater(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "values.txt")
+	parse := func(data []byte) (string, error) {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	reloader := newFileReloader(path, time.Minute, atylabclock.NewClockMock(now), "test", "values file", parse)
+	if _, loaded := reloader.current(); loaded {
+		t.Fatal("expected missing file not to be loaded")
+	}
+
+	if err := os.WriteFile(path, []byte("v1\n"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	reloader.clock = atylabclock.NewClockMock(now.Add(2 * time.Minute))
+	value, loaded := reloader.current()
+	if !loaded || value != "v1" {
+		t.Errorf("expected file to be loaded after it was created, got %q (loaded=%v)", value, loaded)
+	}
+}
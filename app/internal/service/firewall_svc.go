@@ -0,0 +1,156 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+)
+
+// すべてのリクエストに適用するルールのグループ名
+const FirewallDefaultGroup = "default"
+
+type FirewallConfig struct {
+	// ルールファイル（JSON）のパス。空の場合はすべて許可する
+	RulesFile string
+	// ルールファイルの更新を確認する間隔
+	ReloadInterval time.Duration
+}
+
+func NewFirewallConfigFromEnv() FirewallConfig {
+	return FirewallConfig{
+		RulesFile:      os.Getenv("FIREWALL_RULES_FILE"),
+		ReloadInterval: envSeconds("FIREWALL_RELOAD_INTERVAL", 10*time.Second),
+	}
+}
+
+// グループ単位の許可・拒否リスト
+// 拒否リストが優先され、許可リストが空でなければ許可リストに含まれる IP のみ通す
+type FirewallRule struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+func (r FirewallRule) Allows(addr netip.Addr) bool {
+	for _, prefix := range r.Deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(r.Allow) == 0 {
+		return true
+	}
+	for _, prefix := range r.Allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ルールファイルの形式
+//
+//	{"default": {"deny": ["203.0.113.0/24"]}, "admin": {"allow": ["10.0.0.0/8", "::1"]}}
+type firewallRuleFile map[string]struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+func ParseFirewallRules(data []byte) (map[string]FirewallRule, error) {
+	var file firewallRuleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse firewall rules: %w", err)
+	}
+
+	rules := map[string]FirewallRule{}
+	for group, entry := range file {
+		allow, err := parsePrefixes(entry.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow rule in %s: %w", group, err)
+		}
+		deny, err := parsePrefixes(entry.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny rule in %s: %w", group, err)
+		}
+		rules[group] = FirewallRule{Allow: allow, Deny: deny}
+	}
+	return rules, nil
+}
+
+// CIDR 表記のほか単一の IP アドレスも受け付ける
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, err
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+type FirewallSvcInterface interface {
+	Allowed(group string, clientIP string) bool
+}
+
+type FirewallSvcStruct struct {
+	config FirewallConfig
+	rules  *fileReloader[map[string]FirewallRule]
+}
+
+func NewFirewallSvc(
+	config FirewallConfig,
+	clock atylabclock.ClockInterface,
+) *FirewallSvcStruct {
+	s := &FirewallSvcStruct{
+		config: config,
+	}
+	if config.RulesFile != "" {
+		s.rules = newFileReloader(config.RulesFile, config.ReloadInterval, clock, "firewall", "rules file", ParseFirewallRules)
+	}
+	return s
+}
+
+// default グループと指定グループの両方のルールを満たす場合のみ許可する
+func (s *FirewallSvcStruct) Allowed(group string, clientIP string) bool {
+	if s.config.RulesFile == "" {
+		return true
+	}
+	rules, loaded := s.rules.current()
+
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	// 一度も読み込めていない場合は安全側に倒してすべて拒否する
+	if !loaded {
+		return false
+	}
+	if rule, ok := rules[FirewallDefaultGroup]; ok && !rule.Allows(addr) {
+		return false
+	}
+	if rule, ok := rules[group]; ok && group != FirewallDefaultGroup && !rule.Allows(addr) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+)
+
+func writeFirewallRules(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write rules: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mod time: %v", err)
+	}
+}
+
+func TestNewFirewallConfigFromEnv(t *testing.T) {
+	config := NewFirewallConfigFromEnv()
+	if config.RulesFile != "" || config.ReloadInterval != 10*time.Second {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+
+	funcs.WithEnv("FIREWALL_RULES_FILE", "/etc/auth/firewall.json", t, func() {
+		funcs.WithEnv("FIREWALL_RELOAD_INTERVAL", "30", t, func() {
+			config := NewFirewallConfigFromEnv()
+			if config.RulesFile != "/etc/auth/firewall.json" || config.ReloadInterval != 30*time.Second {
+				t.Errorf("unexpected config: %+v", config)
+			}
+		})
+	})
+}
+
+func TestParseFirewallRules(t *testing.T) {
+	rules, err := ParseFirewallRules([]byte(`{
+		"default": {"deny": ["203.0.113.0/24", "2001:db8:bad::/48"]},
+		"admin": {"allow": ["10.0.0.0/8", "192.168.1.10", "::1", "::ffff:172.16.0.0/108"]}
+	}`))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		group    string
+		ip       string
+		expected bool
+	}{
+		{"default", "203.0.113.5", false},
+		{"default", "198.51.100.1", true},
+		{"default", "2001:db8:bad::1", false},
+		{"default", "2001:db8:1::1", true},
+		{"admin", "10.1.2.3", true},
+		{"admin", "192.168.1.10", true},
+		{"admin", "192.168.1.11", false},
+		{"admin", "::1", true},
+		{"admin", "172.16.5.5", true},
+		{"admin", "8.8.8.8", false},
+	}
+
+	for _, tt := range tests {
+		addr := mustParseAddr(t, tt.ip)
+		if got := rules[tt.group].Allows(addr); got != tt.expected {
+			t.Errorf("%s %s: expected %v, got %v", tt.group, tt.ip, tt.expected, got)
+		}
+	}
+}
+
+func TestParseFirewallRulesInvalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"admin": {"allow": ["10.0.0.0/33"]}}`,
+		`{"admin": {"deny": ["example.com"]}}`,
+	} {
+		if _, err := ParseFirewallRules([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestFirewallAllowed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "firewall.json")
+	writeFirewallRules(t, path, `{
+		"default": {"deny": ["203.0.113.0/24"]},
+		"admin": {"allow": ["10.0.0.0/8"]}
+	}`, time.Now())
+
+	svc := NewFirewallSvc(FirewallConfig{RulesFile: path, ReloadInterval: time.Minute}, atylabclock.NewClockMock(time.Now()))
+
+	tests := []struct {
+		group    string
+		ip       string
+		expected bool
+	}{
+		{FirewallDefaultGroup, "198.51.100.1", true},
+		{FirewallDefaultGroup, "203.0.113.1", false},
+		{"admin", "10.0.0.1", true},
+		{"admin", "::ffff:10.0.0.1", true},
+		{"admin", "198.51.100.1", false},
+		{"unknown", "198.51.100.1", true},
+		{FirewallDefaultGroup, "not-an-ip", false},
+	}
+	for _, tt := range tests {
+		if got := svc.Allowed(tt.group, tt.ip); got != tt.expected {
+			t.Errorf("%s %s: expected %v, got %v", tt.group, tt.ip, tt.expected, got)
+		}
+	}
+}
+
+func TestFirewallAllowedWithoutRulesFile(t *testing.T) {
+	svc := NewFirewallSvc(FirewallConfig{}, atylabclock.NewClockMock(time.Now()))
+	if !svc.Allowed("admin", "198.51.100.1") {
+		t.Error("expected all requests to be allowed without rules file")
+	}
+}
+
+func TestFirewallDeniesWhenRulesNeverLoaded(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "firewall.json")
+	writeFirewallRules(t, path, `invalid`, time.Now())
+
+	svc := NewFirewallSvc(FirewallConfig{RulesFile: path}, atylabclock.NewClockMock(time.Now()))
+	if svc.Allowed(FirewallDefaultGroup, "198.51.100.1") {
+		t.Error("expected requests to be denied when rules could not be loaded")
+	}
+}
+
+func TestFirewallReload(t *testing.T) {
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), "firewall.json")
+	writeFirewallRules(t, path, `{"default": {"deny": ["198.51.100.1"]}}`, now.Add(-time.Hour))
+
+	svc := NewFirewallSvc(FirewallConfig{RulesFile: path, ReloadInterval: time.Minute}, atylabclock.NewClockMock(now))
+	if svc.Allowed(FirewallDefaultGroup, "198.51.100.1") {
+		t.Fatal("expected initial rules to deny")
+	}
+
+	writeFirewallRules(t, path, `{"default": {"deny": ["198.51.100.2"]}}`, now)
+
+	// 確認間隔内は読み直さない
+	if svc.Allowed(FirewallDefaultGroup, "198.51.100.1") {
+		t.Error("expected rules not to be reloaded within interval")
+	}
+
+	svc.rules.clock = atylabclock.NewClockMock(now.Add(2 * time.Minute))
+	if !svc.Allowed(FirewallDefaultGroup, "198.51.100.1") || svc.Allowed(FirewallDefaultGroup, "198.51.100.2") {
+		t.Error("expected updated rules to be applied")
+	}
+
+	// 壊れたファイルに更新された場合は直前のルールを使い続ける
+	writeFirewallRules(t, path, `invalid`, now.Add(time.Minute))
+	svc.rules.clock = atylabclock.NewClockMock(now.Add(4 * time.Minute))
+	if svc.Allowed(FirewallDefaultGroup, "198.51.100.2") {
+		t.Error("expected previous rules to be kept")
+	}
+}
+
+func mustParseAddr(t *testing.T, ip string) netip.Addr {
+	t.Helper()
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		t.Fatalf("invalid ip %s: %v", ip, err)
+	}
+	return addr.Unmap()
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// 信頼するプロキシ（TRUSTED_PROXIES、カンマ区切りの IP / CIDR）
+// gin のクライアント IP の解決と Forwarded ミドルウェアで同じ値を使うため、起動時に一度だけ読み込む
+type TrustedProxies []netip.Prefix
+
+func NewTrustedProxiesFromEnv() TrustedProxies {
+	values := []string{}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			values = append(values, proxy)
+		}
+	}
+	prefixes, err := parsePrefixes(values)
+	if err != nil {
+		// 信頼するプロキシが不明な場合はどの接続元も信頼しない
+		log.Printf("[proxy] invalid TRUSTED_PROXIES, no proxies are trusted: %v", err)
+		return TrustedProxies{}
+	}
+	return TrustedProxies(prefixes)
+}
+
+func (p TrustedProxies) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// gin.Engine.SetTrustedProxies に渡す CIDR 表記
+func (p TrustedProxies) Strings() []string {
+	values := make([]string, 0, len(p))
+	for _, prefix := range p {
+		values = append(values, prefix.String())
+	}
+	return values
+}
+
+type ForwardedConfig struct {
+	// RFC 7239 の Forwarded ヘッダーを使うか。プロキシが Forwarded を付け直す構成でのみ有効にする
+	Enabled bool
+	// Forwarded を受け付ける接続元
+	TrustedProxies TrustedProxies
+}
+
+func NewForwardedConfigFromEnv(trustedProxies TrustedProxies) ForwardedConfig {
+	return ForwardedConfig{
+		Enabled:        os.Getenv("TRUST_FORWARDED_HEADER") == "true",
+		TrustedProxies: trustedProxies,
+	}
+}
+
+func (c ForwardedConfig) IsTrustedProxy(addr netip.Addr) bool {
+	return c.TrustedProxies.Contains(addr)
+}
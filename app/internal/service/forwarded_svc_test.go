@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+)
+
+func TestNewTrustedProxiesFromEnv(t *testing.T) {
+	if proxies := NewTrustedProxiesFromEnv(); len(proxies) != 0 {
+		t.Errorf("expected no trusted proxies by default: %v", proxies)
+	}
+
+	funcs.WithEnv("TRUSTED_PROXIES", "192.0.2.0/24, 10.0.0.1, ::ffff:172.16.0.1", t, func() {
+		proxies := NewTrustedProxiesFromEnv()
+		if !proxies.Contains(mustParseAddr(t, "192.0.2.10")) || !proxies.Contains(mustParseAddr(t, "::ffff:10.0.0.1")) || proxies.Contains(mustParseAddr(t, "198.51.100.1")) {
+			t.Errorf("unexpected trusted proxies: %v", proxies)
+		}
+		expected := []string{"192.0.2.0/24", "10.0.0.1/32", "172.16.0.1/32"}
+		if got := proxies.Strings(); len(got) != len(expected) || got[0] != expected[0] || got[1] != expected[1] || got[2] != expected[2] {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	})
+
+	funcs.WithEnv("TRUSTED_PROXIES", "192.0.2.0/24, not-a-proxy", t, func() {
+		if proxies := NewTrustedProxiesFromEnv(); len(proxies) != 0 {
+			t.Errorf("expected no trusted proxies with invalid values: %v", proxies)
+		}
+	})
+}
+
+func TestNewForwardedConfigFromEnv(t *testing.T) {
+	trustedProxies := TrustedProxies{netip.MustParsePrefix("192.0.2.0/24")}
+	if config := NewForwardedConfigFromEnv(trustedProxies); config.Enabled {
+		t.Errorf("expected Forwarded header to be ignored by default: %+v", config)
+	}
+
+	funcs.WithEnv("TRUST_FORWARDED_HEADER", "true", t, func() {
+		config := NewForwardedConfigFromEnv(trustedProxies)
+		if !config.Enabled || !config.IsTrustedProxy(mustParseAddr(t, "192.0.2.10")) || config.IsTrustedProxy(mustParseAddr(t, "198.51.100.1")) {
+			t.Errorf("unexpected config: %+v", config)
+		}
+
+		// TRUSTED_PROXIES が空（または不正）の場合はどの接続元の Forwarded も使わない
+		if config := NewForwardedConfigFromEnv(TrustedProxies{}); config.IsTrustedProxy(mustParseAddr(t, "192.0.2.10")) {
+			t.Errorf("expected no trusted proxies: %+v", config)
+		}
+	})
+}
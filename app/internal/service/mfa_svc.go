@@ -0,0 +1,320 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/qrcode"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/totp"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// MFA チャレンジトークンの typ クレーム
+	MfaTokenType = "mfa"
+	// MFA チャレンジトークンの有効期限（秒）
+	MfaTokenExpiresIn = 300
+	// 発行するリカバリーコードの数
+	RecoveryCodeCount = 10
+	// 前後 1 ステップ（±30秒）までの時刻ずれを許容する
+	totpSkew = 1
+)
+
+var (
+	ErrTotpAlreadyEnabled = errors.New("totp is already enabled")
+	ErrTotpNotEnabled     = errors.New("totp is not enabled")
+	ErrInvalidMfaToken    = errors.New("invalid mfa token")
+	ErrInvalidMfaCode     = errors.New("invalid mfa code")
+)
+
+type MfaSvcInterface interface {
+	IsEnabled(userId uint) (bool, error)
+	EnrollTotp(userUUID string) (*TotpEnrollOutput, error)
+	ConfirmTotp(input TotpCodeInput) ([]string, error)
+	DisableTotp(input TotpCodeInput) error
+	CreateChallenge(user *models.User, authContext AuthContext) (string, error)
+	VerifyChallenge(input VerifyMfaInput) (*models.User, AuthContext, error)
+}
+
+type MfaSvcStruct struct {
+	userRepo             repositories.UserRepoInterface
+	userTotpRepo         repositories.UserTotpRepoInterface
+	userRecoveryCodeRepo repositories.UserRecoveryCodeRepoInterface
+	totp                 totp.TotpPkgInterface
+	qrcode               qrcode.QrcodePkgInterface
+	jwttoken             jwttoken.JwtTokenPkgInterface
+	clock                atylabclock.ClockInterface
+}
+
+func NewMfaSvc(
+	userRepo repositories.UserRepoInterface,
+	userTotpRepo repositories.UserTotpRepoInterface,
+	userRecoveryCodeRepo repositories.UserRecoveryCodeRepoInterface,
+	totp totp.TotpPkgInterface,
+	qrcode qrcode.QrcodePkgInterface,
+	jwttoken jwttoken.JwtTokenPkgInterface,
+	clock atylabclock.ClockInterface,
+) *MfaSvcStruct {
+	return &MfaSvcStruct{
+		userRepo:             userRepo,
+		userTotpRepo:         userTotpRepo,
+		userRecoveryCodeRepo: userRecoveryCodeRepo,
+		totp:                 totp,
+		qrcode:               qrcode,
+		jwttoken:             jwttoken,
+		clock:                clock,
+	}
+}
+
+type TotpEnrollOutput struct {
+	Secret string
+	URI    string
+	QRCode string
+}
+
+type TotpCodeInput struct {
+	UserUUID string
+	Code     string
+}
+
+type VerifyMfaInput struct {
+	MfaToken     string
+	Code         string
+	RecoveryCode string
+}
+
+func (s *MfaSvcStruct) IsEnabled(userId uint) (bool, error) {
+	userTotp, err := s.userTotpRepo.GetByUserID(userId)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTotpNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get totp: %w", err)
+	}
+	return userTotp.Enabled, nil
+}
+
+// シークレットを発行し、認証アプリで読み取るための URI と QR コードを返す
+// 確認コードが検証されるまでは有効化しない
+func (s *MfaSvcStruct) EnrollTotp(userUUID string) (*TotpEnrollOutput, error) {
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	userTotp, err := s.userTotpRepo.GetByUserID(user.ID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrTotpNotFound) {
+			return nil, fmt.Errorf("failed to get totp: %w", err)
+		}
+		userTotp = &models.UserTotp{UserID: user.ID}
+	}
+	if userTotp.Enabled {
+		return nil, ErrTotpAlreadyEnabled
+	}
+
+	secret, err := s.totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	userTotp.Secret = secret
+	if err := s.userTotpRepo.Save(userTotp); err != nil {
+		return nil, err
+	}
+
+	uri, err := s.totp.URI(totpIssuer(), user.Email, secret)
+	if err != nil {
+		return nil, err
+	}
+	png, err := s.qrcode.EncodePNG(uri, 4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create qr code: %w", err)
+	}
+
+	return &TotpEnrollOutput{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// 確認コードを検証して有効化し、リカバリーコードを発行する
+// リカバリーコードの平文はこのレスポンスでしか返さない
+func (s *MfaSvcStruct) ConfirmTotp(input TotpCodeInput) ([]string, error) {
+	user, err := s.userRepo.GetByUUID(input.UserUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	userTotp, err := s.userTotpRepo.GetByUserID(user.ID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTotpNotFound) {
+			return nil, ErrTotpNotEnabled
+		}
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+	if userTotp.Enabled {
+		return nil, ErrTotpAlreadyEnabled
+	}
+
+	step, ok := s.totp.Validate(userTotp.Secret, input.Code, s.clock.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMfaCode
+	}
+	if err := s.userTotpRepo.Enable(userTotp.ID, step); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, RecoveryCodeCount)
+	hashes := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		code := models.CreateRecoveryCode()
+		codes = append(codes, code)
+		hashes = append(hashes, models.HashRecoveryCode(code))
+	}
+	if err := s.userRecoveryCodeRepo.ReplaceAll(user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *MfaSvcStruct) DisableTotp(input TotpCodeInput) error {
+	user, err := s.userRepo.GetByUUID(input.UserUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	userTotp, err := s.getEnabledTotp(user.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyTotpCode(userTotp, input.Code); err != nil {
+		return err
+	}
+
+	if err := s.userTotpRepo.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+	return s.userRecoveryCodeRepo.DeleteByUserID(user.ID)
+}
+
+// パスワード認証後、2 要素目の入力を待つ間だけ使える短命のトークンを発行する
+// 1 要素目の認証方式とログイン時に要求されたスコープを入れ、2 要素目の検証後に引き継ぐ
+func (s *MfaSvcStruct) CreateChallenge(user *models.User, authContext AuthContext) (string, error) {
+	now := s.clock.Now()
+	claims := jwt.MapClaims{
+		"sub": user.UUID,
+		"typ": MfaTokenType,
+		"amr": authContext.Amr,
+		"iat": now.Unix(),
+		"exp": now.Add(MfaTokenExpiresIn * time.Second).Unix(),
+	}
+	if authContext.Scope != "" {
+		claims["scope"] = authContext.Scope
+	}
+	return s.jwttoken.Sign(claims, []byte(os.Getenv("JWT_SECRET_KEY")))
+}
+
+// チャレンジトークンと TOTP コードまたはリカバリーコードを検証し、ユーザーと認証方式・スコープを返す
+func (s *MfaSvcStruct) VerifyChallenge(input VerifyMfaInput) (*models.User, AuthContext, error) {
+	claims, err := s.jwttoken.Parse(input.MfaToken, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return nil, AuthContext{}, fmt.Errorf("%w: %v", ErrInvalidMfaToken, err)
+	}
+	if typ, _ := claims["typ"].(string); typ != MfaTokenType {
+		return nil, AuthContext{}, ErrInvalidMfaToken
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, AuthContext{}, ErrInvalidMfaToken
+	}
+
+	user, err := s.userRepo.GetByUUID(sub)
+	if err != nil {
+		return nil, AuthContext{}, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	userTotp, err := s.getEnabledTotp(user.ID)
+	if err != nil {
+		return nil, AuthContext{}, err
+	}
+
+	// リカバリーコードも使い捨てのパスワードなので otp として扱う
+	scope, _ := claims["scope"].(string)
+	authContext := AuthContext{Amr: append(challengeAmr(claims), AmrOtp, AmrMfa), Scope: scope}
+
+	if strings.TrimSpace(input.RecoveryCode) != "" {
+		if err := s.userRecoveryCodeRepo.Use(user.ID, models.HashRecoveryCode(input.RecoveryCode)); err != nil {
+			if errors.Is(err, repositories.ErrRecoveryCodeNotFound) {
+				return nil, AuthContext{}, ErrInvalidMfaCode
+			}
+			return nil, AuthContext{}, err
+		}
+		return user, authContext, nil
+	}
+
+	if err := s.verifyTotpCode(userTotp, input.Code); err != nil {
+		return nil, AuthContext{}, err
+	}
+	return user, authContext, nil
+}
+
+// amr を持たない古いチャレンジトークンはパスワード認証とみなす
+func challengeAmr(claims jwt.MapClaims) []string {
+	values, _ := claims["amr"].([]interface{})
+	amr := make([]string, 0, len(values)+2)
+	for _, v := range values {
+		if method, ok := v.(string); ok {
+			amr = append(amr, method)
+		}
+	}
+	if len(amr) == 0 {
+		amr = append(amr, AmrPwd)
+	}
+	return amr
+}
+
+func (s *MfaSvcStruct) getEnabledTotp(userId uint) (*models.UserTotp, error) {
+	userTotp, err := s.userTotpRepo.GetByUserID(userId)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTotpNotFound) {
+			return nil, ErrTotpNotEnabled
+		}
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+	if !userTotp.Enabled {
+		return nil, ErrTotpNotEnabled
+	}
+	return userTotp, nil
+}
+
+// 一度使った time step 以前のコードは受け付けない（リプレイ対策）
+func (s *MfaSvcStruct) verifyTotpCode(userTotp *models.UserTotp, code string) error {
+	step, ok := s.totp.Validate(userTotp.Secret, code, s.clock.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidMfaCode
+	}
+	if err := s.userTotpRepo.MarkStepUsed(userTotp.ID, step); err != nil {
+		if errors.Is(err, repositories.ErrTotpStepAlreadyUsed) {
+			return ErrInvalidMfaCode
+		}
+		return err
+	}
+	return nil
+}
+
+func totpIssuer() string {
+	if issuer := os.Getenv("TOTP_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "portfolio-go-auth"
+}
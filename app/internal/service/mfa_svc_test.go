@@ -0,0 +1,467 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/qrcode"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/totp"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/lib_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+const testTotpSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+var testMfaNow = time.Now()
+
+func newTestMfaSvc() *MfaSvcStruct {
+	return NewMfaSvc(
+		new(repo_mock.UserRepoMock),
+		new(repo_mock.UserTotpRepoMock),
+		new(repo_mock.UserRecoveryCodeRepoMock),
+		totp.NewTotpPkg(),
+		qrcode.NewQrcodePkg(),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClockMock(testMfaNow),
+	)
+}
+
+// userTotp が nil の場合は 2 要素認証が未登録のユーザーとして扱う
+func newTestMfaSvcWithTotp(userTotp *models.UserTotp) *MfaSvcStruct {
+	svc := newTestMfaSvc()
+	if userTotp == nil {
+		svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+			On("GetByUserID", mock.Anything).Return((*models.UserTotp)(nil), repositories.ErrTotpNotFound)
+	} else {
+		svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+			On("GetByUserID", userTotp.UserID).Return(userTotp, nil)
+	}
+	return svc
+}
+
+func testTotpCode(t *testing.T, now time.Time) string {
+	t.Helper()
+	p := totp.NewTotpPkg()
+	code, err := p.Code(testTotpSecret, p.Step(now))
+	if err != nil {
+		t.Fatalf("failed to create totp code: %v", err)
+	}
+	return code
+}
+
+func TestMfaIsEnabled(t *testing.T) {
+	tests := []struct {
+		title    string
+		userTotp *models.UserTotp
+		expected bool
+	}{
+		{"not registered", nil, false},
+		{"pending", &models.UserTotp{ID: 1, UserID: 1, Enabled: false}, false},
+		{"enabled", &models.UserTotp{ID: 1, UserID: 1, Enabled: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			svc := newTestMfaSvcWithTotp(tt.userTotp)
+
+			enabled, err := svc.IsEnabled(1)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if enabled != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, enabled)
+			}
+		})
+	}
+}
+
+func TestMfaIsEnabledFailDbErr(t *testing.T) {
+	svc := newTestMfaSvc()
+	svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+		On("GetByUserID", uint(1)).Return((*models.UserTotp)(nil), fmt.Errorf("db error"))
+
+	if _, err := svc.IsEnabled(1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestMfaEnrollTotp(t *testing.T) {
+	funcs.WithEnv("TOTP_ISSUER", "Example", t, func() {
+		svc := newTestMfaSvcWithTotp(nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1, UUID: "test-uuid", Email: "user@example.com"}, nil)
+		userTotpRepoMock := svc.userTotpRepo.(*repo_mock.UserTotpRepoMock)
+		userTotpRepoMock.On("Save", mock.MatchedBy(func(u *models.UserTotp) bool {
+			return u.UserID == 1 && u.Secret != "" && !u.Enabled
+		})).Return(nil)
+
+		out, err := svc.EnrollTotp("test-uuid")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(out.Secret) != 32 {
+			t.Errorf("expected 32 chars secret, got %q", out.Secret)
+		}
+		if !strings.HasPrefix(out.URI, "otpauth://totp/Example:user@example.com?") || !strings.Contains(out.URI, "secret="+out.Secret) {
+			t.Errorf("unexpected uri: %s", out.URI)
+		}
+		if !strings.HasPrefix(out.QRCode, "data:image/png;base64,") {
+			t.Errorf("unexpected qr code: %s", out.QRCode)
+		}
+		userTotpRepoMock.AssertExpectations(t)
+	})
+}
+
+func TestMfaEnrollTotpRegenerateSecret(t *testing.T) {
+	svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: "OLDSECRET"})
+	svc.userRepo.(*repo_mock.UserRepoMock).
+		On("GetByUUID", "test-uuid").Return(&models.User{ID: 1, UUID: "test-uuid", Email: "user@example.com"}, nil)
+	svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+		On("Save", mock.MatchedBy(func(u *models.UserTotp) bool {
+			return u.ID == 5 && u.Secret != "OLDSECRET"
+		})).Return(nil)
+
+	if _, err := svc.EnrollTotp("test-uuid"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestMfaEnrollTotpFail(t *testing.T) {
+	t.Run("user not found", func(t *testing.T) {
+		svc := newTestMfaSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return((*models.User)(nil), fmt.Errorf("not found"))
+
+		if _, err := svc.EnrollTotp("test-uuid"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 1, UserID: 1, Enabled: true})
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+
+		if _, err := svc.EnrollTotp("test-uuid"); !errors.Is(err, ErrTotpAlreadyEnabled) {
+			t.Fatalf("expected ErrTotpAlreadyEnabled, got %v", err)
+		}
+	})
+
+	t.Run("save error", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+		svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+			On("Save", mock.Anything).Return(fmt.Errorf("db error"))
+
+		if _, err := svc.EnrollTotp("test-uuid"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+
+	t.Run("qr code error", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+		svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+			On("Save", mock.Anything).Return(nil)
+		qrcodeMock := new(lib_mock.QrcodePkgMock)
+		qrcodeMock.On("EncodePNG", mock.Anything, 4).Return(nil, fmt.Errorf("too long"))
+		svc.qrcode = qrcodeMock
+
+		if _, err := svc.EnrollTotp("test-uuid"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestMfaConfirmTotp(t *testing.T) {
+	svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret})
+	svc.userRepo.(*repo_mock.UserRepoMock).
+		On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+	userTotpRepoMock := svc.userTotpRepo.(*repo_mock.UserTotpRepoMock)
+	userTotpRepoMock.On("Enable", uint(5), totp.NewTotpPkg().Step(testMfaNow)).Return(nil)
+	userRecoveryCodeRepoMock := svc.userRecoveryCodeRepo.(*repo_mock.UserRecoveryCodeRepoMock)
+	userRecoveryCodeRepoMock.On("ReplaceAll", uint(1), mock.MatchedBy(func(hashes []string) bool {
+		return len(hashes) == RecoveryCodeCount
+	})).Return(nil)
+
+	codes, err := svc.ConfirmTotp(TotpCodeInput{
+		UserUUID: "test-uuid",
+		Code:     testTotpCode(t, testMfaNow),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", RecoveryCodeCount, len(codes))
+	}
+
+	// 保存されるのは平文ではなくハッシュ
+	hashes := userRecoveryCodeRepoMock.Calls[0].Arguments.Get(1).([]string)
+	for i, code := range codes {
+		if hashes[i] != models.HashRecoveryCode(code) {
+			t.Errorf("expected hashed recovery code at %d", i)
+		}
+	}
+	userTotpRepoMock.AssertExpectations(t)
+}
+
+func TestMfaConfirmTotpFail(t *testing.T) {
+	t.Run("invalid code", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret})
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+
+		_, err := svc.ConfirmTotp(TotpCodeInput{UserUUID: "test-uuid", Code: "000000"})
+		if !errors.Is(err, ErrInvalidMfaCode) {
+			t.Fatalf("expected ErrInvalidMfaCode, got %v", err)
+		}
+	})
+
+	t.Run("not enrolled", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+
+		_, err := svc.ConfirmTotp(TotpCodeInput{UserUUID: "test-uuid", Code: "000000"})
+		if !errors.Is(err, ErrTotpNotEnabled) {
+			t.Fatalf("expected ErrTotpNotEnabled, got %v", err)
+		}
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Enabled: true})
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+
+		_, err := svc.ConfirmTotp(TotpCodeInput{UserUUID: "test-uuid", Code: "000000"})
+		if !errors.Is(err, ErrTotpAlreadyEnabled) {
+			t.Fatalf("expected ErrTotpAlreadyEnabled, got %v", err)
+		}
+	})
+
+	t.Run("replace recovery codes error", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret})
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+		svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+			On("Enable", uint(5), mock.Anything).Return(nil)
+		svc.userRecoveryCodeRepo.(*repo_mock.UserRecoveryCodeRepoMock).
+			On("ReplaceAll", uint(1), mock.Anything).Return(fmt.Errorf("db error"))
+
+		if _, err := svc.ConfirmTotp(TotpCodeInput{UserUUID: "test-uuid", Code: testTotpCode(t, testMfaNow)}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestMfaDisableTotp(t *testing.T) {
+	svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret, Enabled: true})
+	svc.userRepo.(*repo_mock.UserRepoMock).
+		On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+	userTotpRepoMock := svc.userTotpRepo.(*repo_mock.UserTotpRepoMock)
+	userTotpRepoMock.On("MarkStepUsed", uint(5), mock.Anything).Return(nil)
+	userTotpRepoMock.On("DeleteByUserID", uint(1)).Return(nil)
+	userRecoveryCodeRepoMock := svc.userRecoveryCodeRepo.(*repo_mock.UserRecoveryCodeRepoMock)
+	userRecoveryCodeRepoMock.On("DeleteByUserID", uint(1)).Return(nil)
+
+	err := svc.DisableTotp(TotpCodeInput{
+		UserUUID: "test-uuid",
+		Code:     testTotpCode(t, testMfaNow),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	userTotpRepoMock.AssertExpectations(t)
+	userRecoveryCodeRepoMock.AssertExpectations(t)
+}
+
+func TestMfaDisableTotpFail(t *testing.T) {
+	t.Run("not enabled", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret})
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+
+		err := svc.DisableTotp(TotpCodeInput{UserUUID: "test-uuid", Code: testTotpCode(t, testMfaNow)})
+		if !errors.Is(err, ErrTotpNotEnabled) {
+			t.Fatalf("expected ErrTotpNotEnabled, got %v", err)
+		}
+	})
+
+	t.Run("code already used", func(t *testing.T) {
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret, Enabled: true})
+		svc.userRepo.(*repo_mock.UserRepoMock).
+			On("GetByUUID", "test-uuid").Return(&models.User{ID: 1}, nil)
+		userTotpRepoMock := svc.userTotpRepo.(*repo_mock.UserTotpRepoMock)
+		userTotpRepoMock.On("MarkStepUsed", uint(5), mock.Anything).Return(repositories.ErrTotpStepAlreadyUsed)
+
+		err := svc.DisableTotp(TotpCodeInput{UserUUID: "test-uuid", Code: testTotpCode(t, testMfaNow)})
+		if !errors.Is(err, ErrInvalidMfaCode) {
+			t.Fatalf("expected ErrInvalidMfaCode, got %v", err)
+		}
+		userTotpRepoMock.AssertNotCalled(t, "DeleteByUserID", mock.Anything)
+	})
+}
+
+func TestMfaCreateChallenge(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+		jwtTokenMock.On("Sign", jwt.MapClaims{
+			"sub":   "test-uuid",
+			"typ":   MfaTokenType,
+			"amr":   []string{AmrPwd},
+			"scope": "account mfa",
+			"iat":   testMfaNow.Unix(),
+			"exp":   testMfaNow.Add(5 * time.Minute).Unix(),
+		}, []byte("testsecretkey")).Return("mfa-token", nil)
+
+		svc := newTestMfaSvc()
+		svc.jwttoken = jwtTokenMock
+
+		token, err := svc.CreateChallenge(&models.User{UUID: "test-uuid"}, AuthContext{Amr: []string{AmrPwd}, Scope: "account mfa"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if token != "mfa-token" {
+			t.Errorf("expected mfa-token, got %s", token)
+		}
+	})
+}
+
+func TestMfaVerifyChallengeWithTotp(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid"}
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret, Enabled: true})
+		svc.clock = atylabclock.NewClockMock(time.Now())
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(user, nil)
+		svc.userTotpRepo.(*repo_mock.UserTotpRepoMock).
+			On("MarkStepUsed", uint(5), totp.NewTotpPkg().Step(svc.clock.Now())).Return(nil)
+
+		token, err := svc.CreateChallenge(user, AuthContext{Amr: []string{AmrEmail}, Scope: "account mfa"})
+		if err != nil {
+			t.Fatalf("failed to create challenge: %v", err)
+		}
+
+		result, authContext, err := svc.VerifyChallenge(VerifyMfaInput{
+			MfaToken: token,
+			Code:     testTotpCode(t, svc.clock.Now()),
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result != user {
+			t.Errorf("expected user %v, got %v", user, result)
+		}
+		if strings.Join(authContext.Amr, " ") != "email otp mfa" {
+			t.Errorf("expected amr [email otp mfa], got %v", authContext.Amr)
+		}
+		if authContext.Scope != "account mfa" {
+			t.Errorf("expected scope to be carried over, got %q", authContext.Scope)
+		}
+	})
+}
+
+func TestMfaVerifyChallengeFail(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid"}
+		key := []byte("testsecretkey")
+		p := jwttoken.NewJwtTokenPkg()
+
+		accessToken, _ := p.Sign(jwt.MapClaims{
+			"sub": "test-uuid",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}, key)
+		expiredToken, _ := p.Sign(jwt.MapClaims{
+			"sub": "test-uuid",
+			"typ": MfaTokenType,
+			"exp": time.Now().Add(-time.Minute).Unix(),
+		}, key)
+		validToken, _ := p.Sign(jwt.MapClaims{
+			"sub": "test-uuid",
+			"typ": MfaTokenType,
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}, key)
+
+		tests := []struct {
+			title    string
+			input    VerifyMfaInput
+			expected error
+		}{
+			{"access token", VerifyMfaInput{MfaToken: accessToken, Code: "123456"}, ErrInvalidMfaToken},
+			{"expired token", VerifyMfaInput{MfaToken: expiredToken, Code: "123456"}, ErrInvalidMfaToken},
+			{"wrong code", VerifyMfaInput{MfaToken: validToken, Code: "000000"}, ErrInvalidMfaCode},
+			{"unknown recovery code", VerifyMfaInput{MfaToken: validToken, RecoveryCode: "aaaaa-bbbbb"}, ErrInvalidMfaCode},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.title, func(t *testing.T) {
+				svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret, Enabled: true})
+				svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(user, nil)
+				svc.userRecoveryCodeRepo.(*repo_mock.UserRecoveryCodeRepoMock).
+					On("Use", uint(1), mock.Anything).Return(repositories.ErrRecoveryCodeNotFound)
+
+				_, _, err := svc.VerifyChallenge(tt.input)
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+			})
+		}
+	})
+}
+
+func TestMfaVerifyChallengeTotpDisabled(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid"}
+		svc := newTestMfaSvcWithTotp(nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(user, nil)
+
+		token, err := svc.CreateChallenge(user, AuthContext{Amr: []string{AmrPwd}})
+		if err != nil {
+			t.Fatalf("failed to create challenge: %v", err)
+		}
+
+		_, _, err = svc.VerifyChallenge(VerifyMfaInput{MfaToken: token, Code: "123456"})
+		if !errors.Is(err, ErrTotpNotEnabled) {
+			t.Fatalf("expected ErrTotpNotEnabled, got %v", err)
+		}
+	})
+}
+
+func TestMfaVerifyChallengeWithRecoveryCode(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "testsecretkey", t, func() {
+		user := &models.User{ID: 1, UUID: "test-uuid"}
+		svc := newTestMfaSvcWithTotp(&models.UserTotp{ID: 5, UserID: 1, Secret: testTotpSecret, Enabled: true})
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(user, nil)
+		svc.userRecoveryCodeRepo.(*repo_mock.UserRecoveryCodeRepoMock).
+			On("Use", uint(1), models.HashRecoveryCode("aaaaa-bbbbb")).Return(nil)
+
+		// amr を持たないチャレンジトークンはパスワード認証として扱う
+		token, _ := jwttoken.NewJwtTokenPkg().Sign(jwt.MapClaims{
+			"sub": "test-uuid",
+			"typ": MfaTokenType,
+			"exp": time.Now().Add(time.Minute).Unix(),
+		}, []byte("testsecretkey"))
+
+		_, authContext, err := svc.VerifyChallenge(VerifyMfaInput{MfaToken: token, RecoveryCode: "aaaaa-bbbbb"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if strings.Join(authContext.Amr, " ") != "pwd otp mfa" || authContext.Scope != "" {
+			t.Errorf("expected amr [pwd otp mfa] without scope, got %+v", authContext)
+		}
+	})
+}
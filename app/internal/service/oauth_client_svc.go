@@ -0,0 +1,228 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOauthClientNotFound = errors.New("oauth client not found")
+	// 登録内容の誤り（RFC 7591 invalid_client_metadata 相当）
+	ErrInvalidOauthClientMetadata = errors.New("invalid oauth client metadata")
+)
+
+// クライアントに許可できるグラント
+var OauthSupportedGrantTypes = []string{
+	OauthGrantTypeAuthorizationCode,
+	OauthGrantTypeRefreshToken,
+	OauthGrantTypeClientCredentials,
+	OauthGrantTypeDeviceCode,
+	OauthGrantTypeTokenExchange,
+}
+
+// RFC 6749 3.3 の scope-token
+var oauthScopeTokenPattern = regexp.MustCompile(`^[\x21\x23-\x5B\x5D-\x7E]+$`)
+
+type OauthClientSvcInterface interface {
+	Create(input OauthClientInput) (*OauthClientOutput, error)
+	List() ([]models.OauthClient, error)
+	Get(clientID string) (*models.OauthClient, error)
+	Update(clientID string, input OauthClientInput) (*OauthClientOutput, error)
+	Delete(clientID string) error
+	RotateSecret(clientID string) (*OauthClientOutput, error)
+}
+
+type OauthClientSvcStruct struct {
+	oauthClientRepo repositories.OauthClientRepoInterface
+}
+
+func NewOauthClientSvc(
+	oauthClientRepo repositories.OauthClientRepoInterface,
+) *OauthClientSvcStruct {
+	return &OauthClientSvcStruct{
+		oauthClientRepo: oauthClientRepo,
+	}
+}
+
+type OauthClientInput struct {
+	Name                          string
+	TokenEndpointAuthMethod       string
+	PublicKey                     string
+	RedirectURIs                  []string
+	GrantTypes                    []string
+	Scopes                        []string
+	Audiences                     []string
+	TokenExchangeSubjectAudiences []string
+	TokenExchangeAudiences        []string
+	AccessTokenLifetime           int
+	RefreshTokenLifetime          int
+	FirstParty                    bool
+}
+
+type OauthClientOutput struct {
+	Client *models.OauthClient
+	// 新たに発行した場合のみ平文の client_secret を返す（再表示はできない）
+	ClientSecret string
+}
+
+func (s *OauthClientSvcStruct) Create(input OauthClientInput) (*OauthClientOutput, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	client := &models.OauthClient{ClientID: uuid.NewString()}
+	input.apply(client)
+	output := &OauthClientOutput{Client: client}
+	if client.UsesClientSecret() {
+		output.ClientSecret = models.CreateOauthClientSecret()
+		client.ClientSecretHash = models.HashOauthClientSecret(output.ClientSecret)
+	}
+
+	if err := s.oauthClientRepo.Create(client); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func (s *OauthClientSvcStruct) List() ([]models.OauthClient, error) {
+	return s.oauthClientRepo.List()
+}
+
+func (s *OauthClientSvcStruct) Get(clientID string) (*models.OauthClient, error) {
+	client, err := s.oauthClientRepo.GetByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOauthClientNotFound) {
+			return nil, ErrOauthClientNotFound
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// 認証方式を client_secret に変更した場合は secret を発行し、それ以外に変更した場合は破棄する
+func (s *OauthClientSvcStruct) Update(clientID string, input OauthClientInput) (*OauthClientOutput, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+	client, err := s.Get(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	input.apply(client)
+	output := &OauthClientOutput{Client: client}
+	switch {
+	case !client.UsesClientSecret():
+		client.ClientSecretHash = ""
+	case client.ClientSecretHash == "":
+		output.ClientSecret = models.CreateOauthClientSecret()
+		client.ClientSecretHash = models.HashOauthClientSecret(output.ClientSecret)
+	}
+
+	if err := s.oauthClientRepo.Update(client); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// クライアントの削除と同時に、発行済みの認可コードとリフレッシュトークンも無効にする
+func (s *OauthClientSvcStruct) Delete(clientID string) error {
+	if err := s.oauthClientRepo.Delete(clientID); err != nil {
+		if errors.Is(err, repositories.ErrOauthClientNotFound) {
+			return ErrOauthClientNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// 新しい client_secret を発行する。以前の secret は直ちに使えなくなる
+func (s *OauthClientSvcStruct) RotateSecret(clientID string) (*OauthClientOutput, error) {
+	client, err := s.Get(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !client.UsesClientSecret() {
+		return nil, fmt.Errorf("%w: client does not use client_secret", ErrInvalidOauthClientMetadata)
+	}
+
+	output := &OauthClientOutput{Client: client, ClientSecret: models.CreateOauthClientSecret()}
+	client.ClientSecretHash = models.HashOauthClientSecret(output.ClientSecret)
+	if err := s.oauthClientRepo.Update(client); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// 一覧はスペース区切りで保存するため、空白を含む値は受け付けない
+func (i OauthClientInput) validate() error {
+	for _, grantType := range i.GrantTypes {
+		if !slices.Contains(OauthSupportedGrantTypes, grantType) {
+			return fmt.Errorf("%w: unsupported grant_type %q", ErrInvalidOauthClientMetadata, grantType)
+		}
+	}
+	if slices.Contains(i.GrantTypes, OauthGrantTypeAuthorizationCode) && len(i.RedirectURIs) == 0 {
+		return fmt.Errorf("%w: redirect_uris is required for the authorization code grant", ErrInvalidOauthClientMetadata)
+	}
+	// client_credentials はクライアント自身の資格情報のみで発行するため、公開クライアントには許可しない（RFC 6749 4.4）
+	if slices.Contains(i.GrantTypes, OauthGrantTypeClientCredentials) && i.TokenEndpointAuthMethod == models.OauthClientAuthMethodNone {
+		return fmt.Errorf("%w: client_credentials requires client authentication", ErrInvalidOauthClientMetadata)
+	}
+	// トークン交換はクライアントの認証を前提とするため、公開クライアントには許可しない（RFC 8693 2.1）
+	if slices.Contains(i.GrantTypes, OauthGrantTypeTokenExchange) && i.TokenEndpointAuthMethod == models.OauthClientAuthMethodNone {
+		return fmt.Errorf("%w: token exchange requires client authentication", ErrInvalidOauthClientMetadata)
+	}
+
+	// リダイレクト URI は完全一致で照合するため、絶対 URI でフラグメントを含まないもののみ（RFC 6749 3.1.2）
+	for _, redirectURI := range i.RedirectURIs {
+		u, err := url.Parse(redirectURI)
+		if err != nil || !u.IsAbs() || u.Fragment != "" || strings.ContainsAny(redirectURI, " \t\r\n#") {
+			return fmt.Errorf("%w: invalid redirect_uri %q", ErrInvalidOauthClientMetadata, redirectURI)
+		}
+	}
+	for _, scope := range i.Scopes {
+		if !oauthScopeTokenPattern.MatchString(scope) {
+			return fmt.Errorf("%w: invalid scope %q", ErrInvalidOauthClientMetadata, scope)
+		}
+	}
+	for _, audience := range slices.Concat(i.Audiences, i.TokenExchangeSubjectAudiences, i.TokenExchangeAudiences) {
+		if audience == "" || strings.ContainsAny(audience, " \t\r\n") {
+			return fmt.Errorf("%w: invalid audience %q", ErrInvalidOauthClientMetadata, audience)
+		}
+	}
+
+	if i.TokenEndpointAuthMethod == models.OauthClientAuthMethodPrivateKeyJwt {
+		if _, err := jwttoken.ParsePublicKey(i.PublicKey); err != nil {
+			return fmt.Errorf("%w: invalid public_key", ErrInvalidOauthClientMetadata)
+		}
+	}
+	return nil
+}
+
+func (i OauthClientInput) apply(client *models.OauthClient) {
+	client.Name = i.Name
+	client.TokenEndpointAuthMethod = i.TokenEndpointAuthMethod
+	client.PublicKey = ""
+	// 公開鍵は private_key_jwt の場合のみ保持する
+	if i.TokenEndpointAuthMethod == models.OauthClientAuthMethodPrivateKeyJwt {
+		client.PublicKey = i.PublicKey
+	}
+	client.RedirectURIs = strings.Join(i.RedirectURIs, " ")
+	client.GrantTypes = strings.Join(i.GrantTypes, " ")
+	client.Scopes = strings.Join(i.Scopes, " ")
+	client.Audiences = strings.Join(i.Audiences, " ")
+	client.TokenExchangeSubjectAudiences = strings.Join(i.TokenExchangeSubjectAudiences, " ")
+	client.TokenExchangeAudiences = strings.Join(i.TokenExchangeAudiences, " ")
+	client.AccessTokenLifetime = i.AccessTokenLifetime
+	client.RefreshTokenLifetime = i.RefreshTokenLifetime
+	client.FirstParty = i.FirstParty
+}
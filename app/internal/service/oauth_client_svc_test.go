@@ -0,0 +1,314 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestOauthClientSvc() *OauthClientSvcStruct {
+	return NewOauthClientSvc(new(repo_mock.OauthClientRepoMock))
+}
+
+func validOauthClientInput() OauthClientInput {
+	return OauthClientInput{
+		Name:                          "Client",
+		TokenEndpointAuthMethod:       models.OauthClientAuthMethodClientSecretBasic,
+		RedirectURIs:                  []string{"https://client.example.com/cb", "com.example.app:/cb"},
+		GrantTypes:                    []string{OauthGrantTypeAuthorizationCode, OauthGrantTypeRefreshToken},
+		Scopes:                        []string{"openid", "profile"},
+		Audiences:                     []string{"https://api.example.com"},
+		TokenExchangeSubjectAudiences: []string{"https://api.example.com"},
+		TokenExchangeAudiences:        []string{"orders-client"},
+		AccessTokenLifetime:           600,
+	}
+}
+
+func testPublicKeyPEM(t *testing.T, key *ecdsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestOauthClientSvcCreate(t *testing.T) {
+	svc := newTestOauthClientSvc()
+	clientRepoMock := svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock)
+	clientRepoMock.On("Create", mock.Anything).Return(nil)
+
+	output, err := svc.Create(validOauthClientInput())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	client := output.Client
+	if client.ClientID == "" || client.RedirectURIs != "https://client.example.com/cb com.example.app:/cb" ||
+		client.GrantTypes != "authorization_code refresh_token" || client.Scopes != "openid profile" || client.Audiences != "https://api.example.com" || client.AccessTokenLifetime != 600 ||
+		client.TokenExchangeSubjectAudiences != "https://api.example.com" || client.TokenExchangeAudiences != "orders-client" {
+		t.Errorf("unexpected client: %+v", client)
+	}
+	// 平文の secret は返すのみで、保存するのはハッシュ
+	if output.ClientSecret == "" || !client.VerifyClientSecret(output.ClientSecret) {
+		t.Errorf("unexpected secret: %+v", output)
+	}
+}
+
+func TestOauthClientSvcCreateWithoutSecret(t *testing.T) {
+	tests := map[string]func(input *OauthClientInput){
+		"public client": func(input *OauthClientInput) {
+			input.TokenEndpointAuthMethod = models.OauthClientAuthMethodNone
+			input.PublicKey = "ignored"
+		},
+		"private_key_jwt": func(input *OauthClientInput) {
+			input.TokenEndpointAuthMethod = models.OauthClientAuthMethodPrivateKeyJwt
+			key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			input.PublicKey = testPublicKeyPEM(t, key)
+		},
+	}
+
+	for title, modify := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthClientSvc()
+			svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock).On("Create", mock.Anything).Return(nil)
+
+			input := validOauthClientInput()
+			modify(&input)
+			output, err := svc.Create(input)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if output.ClientSecret != "" || output.Client.ClientSecretHash != "" {
+				t.Errorf("expected no secret: %+v", output)
+			}
+			if (input.TokenEndpointAuthMethod == models.OauthClientAuthMethodPrivateKeyJwt) != (output.Client.PublicKey != "") {
+				t.Errorf("unexpected public key: %q", output.Client.PublicKey)
+			}
+		})
+	}
+}
+
+func TestOauthClientSvcCreateFail(t *testing.T) {
+	tests := map[string]func(input *OauthClientInput){
+		"unsupported grant":       func(input *OauthClientInput) { input.GrantTypes = []string{"password"} },
+		"code without redirect":   func(input *OauthClientInput) { input.RedirectURIs = nil },
+		"relative redirect_uri":   func(input *OauthClientInput) { input.RedirectURIs = []string{"/cb"} },
+		"redirect_uri fragment":   func(input *OauthClientInput) { input.RedirectURIs = []string{"https://client.example.com/cb#frag"} },
+		"redirect_uri empty frag": func(input *OauthClientInput) { input.RedirectURIs = []string{"https://client.example.com/cb#"} },
+		"redirect_uri with space": func(input *OauthClientInput) { input.RedirectURIs = []string{"https://client.example.com/a b"} },
+		"scope with space":        func(input *OauthClientInput) { input.Scopes = []string{"openid profile"} },
+		"scope with quote":        func(input *OauthClientInput) { input.Scopes = []string{`a"b`} },
+		"empty scope":             func(input *OauthClientInput) { input.Scopes = []string{""} },
+		"audience with space":     func(input *OauthClientInput) { input.Audiences = []string{"api other"} },
+		"empty audience":          func(input *OauthClientInput) { input.Audiences = []string{""} },
+		"token exchange audience with space": func(input *OauthClientInput) {
+			input.TokenExchangeAudiences = []string{"orders other"}
+		},
+		"empty token exchange subject audience": func(input *OauthClientInput) {
+			input.TokenExchangeSubjectAudiences = []string{""}
+		},
+		"public client_credentials": func(input *OauthClientInput) {
+			input.TokenEndpointAuthMethod = models.OauthClientAuthMethodNone
+			input.GrantTypes = []string{OauthGrantTypeClientCredentials}
+		},
+		"public token exchange": func(input *OauthClientInput) {
+			input.TokenEndpointAuthMethod = models.OauthClientAuthMethodNone
+			input.GrantTypes = []string{OauthGrantTypeTokenExchange}
+		},
+		"private_key_jwt no key": func(input *OauthClientInput) {
+			input.TokenEndpointAuthMethod = models.OauthClientAuthMethodPrivateKeyJwt
+		},
+		"private_key_jwt bad key": func(input *OauthClientInput) {
+			input.TokenEndpointAuthMethod = models.OauthClientAuthMethodPrivateKeyJwt
+			input.PublicKey = "invalid"
+		},
+	}
+
+	for title, modify := range tests {
+		t.Run(title, func(t *testing.T) {
+			input := validOauthClientInput()
+			modify(&input)
+			if _, err := newTestOauthClientSvc().Create(input); !errors.Is(err, ErrInvalidOauthClientMetadata) {
+				t.Fatalf("expected ErrInvalidOauthClientMetadata, got %v", err)
+			}
+		})
+	}
+
+	t.Run("db error", func(t *testing.T) {
+		svc := newTestOauthClientSvc()
+		svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+		if _, err := svc.Create(validOauthClientInput()); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestOauthClientSvcGet(t *testing.T) {
+	svc := newTestOauthClientSvc()
+	clientRepoMock := svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock)
+	clientRepoMock.On("GetByClientID", "client").Return(&models.OauthClient{ClientID: "client"}, nil)
+	clientRepoMock.On("GetByClientID", "unknown").Return((*models.OauthClient)(nil), repositories.ErrOauthClientNotFound)
+	clientRepoMock.On("List").Return([]models.OauthClient{{ClientID: "client"}}, nil)
+
+	if client, err := svc.Get("client"); err != nil || client.ClientID != "client" {
+		t.Errorf("unexpected result: %+v %v", client, err)
+	}
+	if _, err := svc.Get("unknown"); !errors.Is(err, ErrOauthClientNotFound) {
+		t.Errorf("expected ErrOauthClientNotFound, got %v", err)
+	}
+	if clients, err := svc.List(); err != nil || len(clients) != 1 {
+		t.Errorf("unexpected result: %+v %v", clients, err)
+	}
+}
+
+func TestOauthClientSvcUpdate(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		hash       string
+		expectNew  bool
+		expectHash bool
+	}{
+		"keep secret":            {models.OauthClientAuthMethodClientSecretPost, "existing", false, true},
+		"issue secret on change": {models.OauthClientAuthMethodClientSecretBasic, "", true, true},
+		"clear secret on change": {models.OauthClientAuthMethodNone, "existing", false, false},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthClientSvc()
+			clientRepoMock := svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock)
+			clientRepoMock.On("GetByClientID", "client").Return(&models.OauthClient{ClientID: "client", ClientSecretHash: tt.hash}, nil)
+			clientRepoMock.On("Update", mock.Anything).Return(nil)
+
+			input := validOauthClientInput()
+			input.TokenEndpointAuthMethod = tt.method
+			input.Name = "Renamed"
+			output, err := svc.Update("client", input)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if output.Client.ClientID != "client" || output.Client.Name != "Renamed" {
+				t.Errorf("unexpected client: %+v", output.Client)
+			}
+			if (output.ClientSecret != "") != tt.expectNew || (output.Client.ClientSecretHash != "") != tt.expectHash {
+				t.Errorf("unexpected secret: %+v", output)
+			}
+			if tt.expectNew && !output.Client.VerifyClientSecret(output.ClientSecret) {
+				t.Error("expected the issued secret to match")
+			}
+		})
+	}
+}
+
+func TestOauthClientSvcUpdateFail(t *testing.T) {
+	t.Run("invalid metadata", func(t *testing.T) {
+		input := validOauthClientInput()
+		input.GrantTypes = []string{"implicit"}
+		if _, err := newTestOauthClientSvc().Update("client", input); !errors.Is(err, ErrInvalidOauthClientMetadata) {
+			t.Fatalf("expected ErrInvalidOauthClientMetadata, got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc := newTestOauthClientSvc()
+		svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock).
+			On("GetByClientID", "client").Return((*models.OauthClient)(nil), repositories.ErrOauthClientNotFound)
+		if _, err := svc.Update("client", validOauthClientInput()); !errors.Is(err, ErrOauthClientNotFound) {
+			t.Fatalf("expected ErrOauthClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		svc := newTestOauthClientSvc()
+		clientRepoMock := svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock)
+		clientRepoMock.On("GetByClientID", "client").Return(&models.OauthClient{ClientID: "client"}, nil)
+		clientRepoMock.On("Update", mock.Anything).Return(fmt.Errorf("db error"))
+		if _, err := svc.Update("client", validOauthClientInput()); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestOauthClientSvcDelete(t *testing.T) {
+	svc := newTestOauthClientSvc()
+	clientRepoMock := svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock)
+	clientRepoMock.On("Delete", "client").Return(nil)
+	clientRepoMock.On("Delete", "unknown").Return(repositories.ErrOauthClientNotFound)
+	clientRepoMock.On("Delete", "broken").Return(fmt.Errorf("db error"))
+
+	if err := svc.Delete("client"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := svc.Delete("unknown"); !errors.Is(err, ErrOauthClientNotFound) {
+		t.Errorf("expected ErrOauthClientNotFound, got %v", err)
+	}
+	if err := svc.Delete("broken"); err == nil || errors.Is(err, ErrOauthClientNotFound) {
+		t.Errorf("expected db error, got %v", err)
+	}
+}
+
+func TestOauthClientSvcRotateSecret(t *testing.T) {
+	oldSecret := models.CreateOauthClientSecret()
+	svc := newTestOauthClientSvc()
+	clientRepoMock := svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock)
+	clientRepoMock.On("GetByClientID", "client").Return(&models.OauthClient{
+		ClientID:                "client",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodClientSecretBasic,
+		ClientSecretHash:        models.HashOauthClientSecret(oldSecret),
+	}, nil)
+	clientRepoMock.On("Update", mock.Anything).Return(nil)
+
+	output, err := svc.RotateSecret("client")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !output.Client.VerifyClientSecret(output.ClientSecret) || output.Client.VerifyClientSecret(oldSecret) {
+		t.Errorf("expected only the new secret to match: %+v", output)
+	}
+}
+
+func TestOauthClientSvcRotateSecretFail(t *testing.T) {
+	t.Run("public client", func(t *testing.T) {
+		svc := newTestOauthClientSvc()
+		svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock).On("GetByClientID", "client").Return(&models.OauthClient{
+			ClientID:                "client",
+			TokenEndpointAuthMethod: models.OauthClientAuthMethodNone,
+		}, nil)
+		if _, err := svc.RotateSecret("client"); !errors.Is(err, ErrInvalidOauthClientMetadata) {
+			t.Fatalf("expected ErrInvalidOauthClientMetadata, got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc := newTestOauthClientSvc()
+		svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock).
+			On("GetByClientID", "client").Return((*models.OauthClient)(nil), repositories.ErrOauthClientNotFound)
+		if _, err := svc.RotateSecret("client"); !errors.Is(err, ErrOauthClientNotFound) {
+			t.Fatalf("expected ErrOauthClientNotFound, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		svc := newTestOauthClientSvc()
+		clientRepoMock := svc.oauthClientRepo.(*repo_mock.OauthClientRepoMock)
+		clientRepoMock.On("GetByClientID", "client").Return(&models.OauthClient{
+			ClientID:                "client",
+			TokenEndpointAuthMethod: models.OauthClientAuthMethodClientSecretPost,
+		}, nil)
+		clientRepoMock.On("Update", mock.Anything).Return(fmt.Errorf("db error"))
+		if _, err := svc.RotateSecret("client"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
@@ -0,0 +1,1245 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	OauthResponseTypeCode           = "code"
+	OauthGrantTypeAuthorizationCode = "authorization_code"
+	OauthGrantTypeRefreshToken      = "refresh_token"
+	OauthGrantTypeClientCredentials = "client_credentials"
+	OauthGrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	OauthGrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// 認可コードの有効期限（秒）。RFC 6749 4.1.2 では最大 10 分を推奨
+	OauthAuthorizationCodeExpiresIn = 60
+	// private_key_jwt のアサーション（RFC 7523）
+	OauthClientAssertionTypeJwtBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	// アサーションの exp として受け付ける最大の残り時間（秒）
+	OauthClientAssertionMaxLifetime = 600
+	// 認可コードに保存できる nonce の長さ
+	OauthNonceMaxLength = 255
+	// デバイスコード・ユーザーコードの有効期限（秒）
+	OauthDeviceCodeExpiresIn = 600
+	// トークンエンドポイントへのポーリング間隔と、slow_down を返すたびに延ばす秒数（RFC 8628 3.5）
+	OauthDevicePollInterval     = 5
+	OauthDeviceSlowDownInterval = 5
+	// 同意画面に渡すチャレンジトークンの typ クレームと有効期限（秒）
+	OauthConsentChallengeType      = "oauth_consent"
+	OauthConsentChallengeExpiresIn = 600
+)
+
+// トークン交換で扱うトークンの種類（RFC 8693 3）。発行するトークンは JWT のアクセストークンのみ
+const (
+	OauthTokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	OauthTokenTypeJwt         = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// RFC 6749 4.1.2.1 / 5.2 のエラーコード
+const (
+	OauthErrorInvalidRequest          = "invalid_request"
+	OauthErrorInvalidClient           = "invalid_client"
+	OauthErrorInvalidGrant            = "invalid_grant"
+	OauthErrorUnsupportedGrantType    = "unsupported_grant_type"
+	OauthErrorUnsupportedResponseType = "unsupported_response_type"
+	OauthErrorUnauthorizedClient      = "unauthorized_client"
+	OauthErrorInvalidScope            = "invalid_scope"
+	// RFC 8628 3.5 のデバイスコードのポーリングのエラーコード
+	OauthErrorAuthorizationPending = "authorization_pending"
+	OauthErrorSlowDown             = "slow_down"
+	OauthErrorAccessDenied         = "access_denied"
+	OauthErrorExpiredToken         = "expired_token"
+	// RFC 8693 2.2.2 の audience が不正な場合のエラーコード
+	OauthErrorInvalidTarget = "invalid_target"
+)
+
+var (
+	// 検証画面で入力されたユーザーコードが存在しない、期限切れまたは承認・拒否済み
+	ErrInvalidOauthUserCode = errors.New("invalid oauth user code")
+	// 同意画面のチャレンジトークンが不正、期限切れ、またはクライアントが削除された
+	ErrInvalidOauthConsentChallenge = errors.New("invalid oauth consent challenge")
+	ErrOauthConsentNotFound         = errors.New("oauth consent not found")
+)
+
+// S256 の code_challenge は SHA-256 の base64url（パディングなし）で 43 文字
+var oauthCodeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9\-_]{43}$`)
+
+// クライアントに返す OAuth のエラー。Code はレスポンスの error にそのまま使う
+type OauthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOauthError(code string, description string) *OauthError {
+	return &OauthError{Code: code, Description: description}
+}
+
+type OauthConfig struct {
+	// 認可サーバーの識別子。private_key_jwt のアサーションの aud と ID トークンの iss に使う
+	Issuer string
+	// 未ログインのユーザーを送るログイン画面。認可リクエストのパラメーターをクエリで引き継ぐ
+	LoginURL string
+	// デバイスの利用者がユーザーコードを入力する検証画面（RFC 8628 3.2 verification_uri）
+	DeviceVerificationURL string
+	// ID トークンの署名鍵（RS256）。公開鍵は JWKS で配布する
+	SigningKey *rsa.PrivateKey
+	// トークン交換でユーザーに成り代われるスタッフのユーザー UUID
+	Impersonators []string
+}
+
+func NewOauthConfigFromEnv() OauthConfig {
+	config := OauthConfig{
+		Issuer:                oauthIssuerFromEnv(),
+		LoginURL:              os.Getenv("OAUTH_LOGIN_URL"),
+		DeviceVerificationURL: os.Getenv("OAUTH_DEVICE_VERIFICATION_URL"),
+		SigningKey:            loadOidcSigningKey(os.Getenv("OIDC_SIGNING_KEY_FILE")),
+		Impersonators:         strings.FieldsFunc(os.Getenv("OAUTH_IMPERSONATORS"), func(r rune) bool { return r == ',' || r == ' ' }),
+	}
+	if config.LoginURL == "" {
+		config.LoginURL = "http://localhost:8080/oauth/login"
+	}
+	if config.DeviceVerificationURL == "" {
+		config.DeviceVerificationURL = "http://localhost:8080/oauth/device"
+	}
+	return config
+}
+
+// アクセストークンと ID トークンで同じ iss を使う
+func oauthIssuerFromEnv() string {
+	issuer := strings.TrimSuffix(os.Getenv("OAUTH_ISSUER"), "/")
+	if issuer == "" {
+		return "http://localhost:8080"
+	}
+	return issuer
+}
+
+// 署名鍵を設定しない場合はプロセスごとに生成した鍵を使う
+// 再起動や複数台構成では発行済みの ID トークンを検証できなくなるため、本番では OIDC_SIGNING_KEY_FILE を設定する
+var ephemeralOidcSigningKey = sync.OnceValue(func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate signing key: %v", err))
+	}
+	return key
+})
+
+func loadOidcSigningKey(path string) *rsa.PrivateKey {
+	if path == "" {
+		log.Printf("[oauth] OIDC_SIGNING_KEY_FILE is not set, using an ephemeral signing key")
+		return ephemeralOidcSigningKey()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("[oauth] failed to read signing key, using an ephemeral signing key: %v", err)
+		return ephemeralOidcSigningKey()
+	}
+	key, err := jwttoken.ParseRSAPrivateKey(string(data))
+	if err != nil {
+		log.Printf("[oauth] %v, using an ephemeral signing key", err)
+		return ephemeralOidcSigningKey()
+	}
+	return key
+}
+
+func (c OauthConfig) AuthorizationEndpoint() string {
+	return c.Issuer + "/oauth/authorize"
+}
+
+func (c OauthConfig) TokenEndpoint() string {
+	return c.Issuer + "/oauth/token"
+}
+
+func (c OauthConfig) ConsentURL() string {
+	return c.Issuer + "/oauth/consent"
+}
+
+func (c OauthConfig) DeviceAuthorizationEndpoint() string {
+	return c.Issuer + "/oauth/device_authorization"
+}
+
+func (c OauthConfig) UserinfoEndpoint() string {
+	return c.Issuer + "/userinfo"
+}
+
+func (c OauthConfig) JwksURI() string {
+	return c.Issuer + "/.well-known/jwks.json"
+}
+
+type OauthSvcInterface interface {
+	BeginAuthorize(input OauthAuthorizeInput) (string, error)
+	Authorize(userUUID string, authContext AuthContext, input OauthAuthorizeInput) (string, error)
+	AuthenticateClient(input OauthClientAuthInput) (*models.OauthClient, error)
+	ConsumeAuthorizationCode(client *models.OauthClient, input OauthTokenInput) (*models.OauthAuthorizationCode, *models.User, error)
+	AuthorizeDevice(client *models.OauthClient, input OauthDeviceAuthorizationInput) (*OauthDeviceAuthorizationOutput, error)
+	GetDeviceAuthorization(userCode string) (*OauthDeviceVerification, error)
+	DecideDeviceAuthorization(userUUID string, authContext AuthContext, input OauthDeviceDecisionInput) error
+	ConsumeDeviceCode(client *models.OauthClient, deviceCode string) (*models.OauthDeviceCode, *models.User, error)
+	ValidateTokenExchange(client *models.OauthClient, input OauthTokenExchangeInput) (*OauthTokenExchangeGrant, error)
+	RecordTokenExchange(exchange *models.OauthTokenExchange) error
+	GetConsent(challenge string) (*OauthConsentRequest, error)
+	DecideConsent(challenge string, approve bool) (string, error)
+	ListConsents(userUUID string) ([]OauthConsent, error)
+	RevokeConsent(userUUID string, clientID string) error
+}
+
+type OauthSvcStruct struct {
+	config                     OauthConfig
+	userRepo                   repositories.UserRepoInterface
+	oauthClientRepo            repositories.OauthClientRepoInterface
+	oauthAuthorizationCodeRepo repositories.OauthAuthorizationCodeRepoInterface
+	oauthDeviceCodeRepo        repositories.OauthDeviceCodeRepoInterface
+	oauthClientAssertionRepo   repositories.OauthClientAssertionRepoInterface
+	oauthTokenExchangeRepo     repositories.OauthTokenExchangeRepoInterface
+	userConsentRepo            repositories.UserConsentRepoInterface
+	jwttoken                   jwttoken.JwtTokenPkgInterface
+	clock                      atylabclock.ClockInterface
+}
+
+func NewOauthSvc(
+	config OauthConfig,
+	userRepo repositories.UserRepoInterface,
+	oauthClientRepo repositories.OauthClientRepoInterface,
+	oauthAuthorizationCodeRepo repositories.OauthAuthorizationCodeRepoInterface,
+	oauthDeviceCodeRepo repositories.OauthDeviceCodeRepoInterface,
+	oauthClientAssertionRepo repositories.OauthClientAssertionRepoInterface,
+	oauthTokenExchangeRepo repositories.OauthTokenExchangeRepoInterface,
+	userConsentRepo repositories.UserConsentRepoInterface,
+	jwttoken jwttoken.JwtTokenPkgInterface,
+	clock atylabclock.ClockInterface,
+) *OauthSvcStruct {
+	return &OauthSvcStruct{
+		config:                     config,
+		userRepo:                   userRepo,
+		oauthClientRepo:            oauthClientRepo,
+		oauthAuthorizationCodeRepo: oauthAuthorizationCodeRepo,
+		oauthDeviceCodeRepo:        oauthDeviceCodeRepo,
+		oauthClientAssertionRepo:   oauthClientAssertionRepo,
+		oauthTokenExchangeRepo:     oauthTokenExchangeRepo,
+		userConsentRepo:            userConsentRepo,
+		jwttoken:                   jwttoken,
+		clock:                      clock,
+	}
+}
+
+type OauthAuthorizeInput struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// OpenID Connect のリプレイ対策（ID トークンの nonce クレームになる）
+	Nonce string
+}
+
+type OauthTokenInput struct {
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+}
+
+// トークンエンドポイントで受け取ったクライアントの認証情報
+type OauthClientAuthInput struct {
+	// Authorization: Basic（client_secret_basic）。URL デコード済みの値
+	BasicClientID     string
+	BasicClientSecret string
+	// フォームの client_id / client_secret（client_secret_post、公開クライアントは client_id のみ）
+	ClientID     string
+	ClientSecret string
+	// private_key_jwt（RFC 7523 2.2）
+	ClientAssertionType string
+	ClientAssertion     string
+}
+
+// 認可リクエストを検証し、ログイン画面の URL を返す
+// リクエストに誤りがある場合はエラーを付けたクライアントのリダイレクト先を返す
+func (s *OauthSvcStruct) BeginAuthorize(input OauthAuthorizeInput) (string, error) {
+	_, redirectURI, err := s.validateAuthorizeRequest(input)
+	if err != nil {
+		return s.authorizeErrorRedirect(redirectURI, input.State, err)
+	}
+
+	query := url.Values{}
+	query.Set("response_type", input.ResponseType)
+	query.Set("client_id", input.ClientID)
+	query.Set("code_challenge", input.CodeChallenge)
+	query.Set("code_challenge_method", input.CodeChallengeMethod)
+	for key, value := range map[string]string{
+		"redirect_uri": input.RedirectURI,
+		"scope":        input.Scope,
+		"state":        input.State,
+		"nonce":        input.Nonce,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return appendQuery(s.config.LoginURL, query)
+}
+
+// ログイン済みのユーザーに認可コードを発行し、クライアントのリダイレクト先を返す
+// サードパーティのクライアントが未許可のスコープを要求した場合は、同意画面の URL を返す
+func (s *OauthSvcStruct) Authorize(userUUID string, authContext AuthContext, input OauthAuthorizeInput) (string, error) {
+	client, redirectURI, err := s.validateAuthorizeRequest(input)
+	if err != nil {
+		return s.authorizeErrorRedirect(redirectURI, input.State, err)
+	}
+
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !client.FirstParty {
+		consent, err := s.userConsentRepo.Get(user.ID, client.ClientID)
+		if err != nil && !errors.Is(err, repositories.ErrUserConsentNotFound) {
+			return "", err
+		}
+		if consent == nil || !consent.Covers(strings.Fields(input.Scope)) {
+			return s.consentURL(user, authContext, input)
+		}
+	}
+	return s.issueAuthorizationCode(user, authContext, input, redirectURI)
+}
+
+func (s *OauthSvcStruct) issueAuthorizationCode(user *models.User, authContext AuthContext, input OauthAuthorizeInput, redirectURI string) (string, error) {
+	code := models.CreateOauthAuthorizationCode()
+	record := &models.OauthAuthorizationCode{
+		CodeHash: models.HashOauthAuthorizationCode(code),
+		ClientID: input.ClientID,
+		UserID:   user.ID,
+		// 認可リクエストで指定された場合のみ、トークンリクエストでも同じ値を要求する（RFC 6749 4.1.3）
+		RedirectURI:         input.RedirectURI,
+		Scope:               strings.Join(strings.Fields(input.Scope), " "),
+		CodeChallenge:       input.CodeChallenge,
+		CodeChallengeMethod: input.CodeChallengeMethod,
+		Nonce:               input.Nonce,
+		Amr:                 strings.Join(authContext.Amr, " "),
+		ExpiresAt:           s.clock.Now().Add(OauthAuthorizationCodeExpiresIn * time.Second),
+	}
+	if !authContext.AuthTime.IsZero() {
+		record.AuthTime = &authContext.AuthTime
+	}
+	if err := s.oauthAuthorizationCodeRepo.Create(record); err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("code", code)
+	if input.State != "" {
+		query.Set("state", input.State)
+	}
+	return appendQuery(redirectURI, query)
+}
+
+// 同意画面に表示する内容
+type OauthConsentRequest struct {
+	ClientID   string
+	ClientName string
+	Scopes     []string
+}
+
+// ユーザーが許可したクライアントとスコープ
+type OauthConsent struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
+
+// 認可リクエストとログインの認証時刻・方式を短命のトークンに載せ、同意画面に引き継ぐ
+// 同意画面はブラウザで直接開くため、アクセストークンの代わりにこのトークンでユーザーを特定する
+func (s *OauthSvcStruct) consentURL(user *models.User, authContext AuthContext, input OauthAuthorizeInput) (string, error) {
+	now := s.clock.Now()
+	claims := jwt.MapClaims{
+		"sub": user.UUID,
+		"typ": OauthConsentChallengeType,
+		"amr": authContext.Amr,
+		"iat": now.Unix(),
+		"exp": now.Add(OauthConsentChallengeExpiresIn * time.Second).Unix(),
+	}
+	if !authContext.AuthTime.IsZero() {
+		claims["auth_time"] = authContext.AuthTime.Unix()
+	}
+	for key, value := range input.values() {
+		claims[key] = value
+	}
+	challenge, err := s.jwttoken.Sign(claims, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return "", fmt.Errorf("failed to create consent challenge: %w", err)
+	}
+	return appendQuery(s.config.ConsentURL(), url.Values{"consent_challenge": {challenge}})
+}
+
+// 同意画面に表示するクライアントとスコープを返す
+func (s *OauthSvcStruct) GetConsent(challenge string) (*OauthConsentRequest, error) {
+	consent, err := s.parseConsentChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return &OauthConsentRequest{
+		ClientID:   consent.client.ClientID,
+		ClientName: consent.client.Name,
+		Scopes:     strings.Fields(consent.input.Scope),
+	}, nil
+}
+
+// 同意画面での許可・拒否を受け付け、クライアントのリダイレクト先を返す
+// 許可した場合はスコープを保存し、次回以降の同意画面を省略する
+func (s *OauthSvcStruct) DecideConsent(challenge string, approve bool) (string, error) {
+	request, err := s.parseConsentChallenge(challenge)
+	if err != nil {
+		return "", err
+	}
+	if !approve {
+		return s.authorizeErrorRedirect(request.redirectURI, request.input.State, newOauthError(OauthErrorAccessDenied, "the user denied the authorization request"))
+	}
+
+	consent, err := s.userConsentRepo.Get(request.user.ID, request.client.ClientID)
+	if err != nil {
+		if !errors.Is(err, repositories.ErrUserConsentNotFound) {
+			return "", err
+		}
+		consent = &models.UserConsent{UserID: request.user.ID, ClientID: request.client.ClientID}
+	}
+	consent.Merge(strings.Fields(request.input.Scope))
+	if err := s.userConsentRepo.Save(consent); err != nil {
+		return "", err
+	}
+	return s.issueAuthorizationCode(request.user, request.authContext, request.input, request.redirectURI)
+}
+
+// 同意画面のトークンから復元した認可リクエスト
+type oauthConsentChallenge struct {
+	user        *models.User
+	authContext AuthContext
+	input       OauthAuthorizeInput
+	client      *models.OauthClient
+	redirectURI string
+}
+
+func (s *OauthSvcStruct) parseConsentChallenge(challenge string) (*oauthConsentChallenge, error) {
+	claims, err := s.jwttoken.Parse(challenge, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOauthConsentChallenge, err)
+	}
+	if typ, _ := claims["typ"].(string); typ != OauthConsentChallengeType {
+		return nil, ErrInvalidOauthConsentChallenge
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, ErrInvalidOauthConsentChallenge
+	}
+
+	// 発行後にクライアントの登録内容が変わった場合に備え、認可リクエストを検証し直す
+	input := oauthAuthorizeInputFromClaims(claims)
+	client, redirectURI, err := s.validateAuthorizeRequest(input)
+	if err != nil {
+		var oauthErr *OauthError
+		if errors.As(err, &oauthErr) {
+			return nil, ErrInvalidOauthConsentChallenge
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByUUID(sub)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrInvalidOauthConsentChallenge
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &oauthConsentChallenge{
+		user:        user,
+		authContext: AuthContextFromClaims(claims),
+		input:       input,
+		client:      client,
+		redirectURI: redirectURI,
+	}, nil
+}
+
+// 同意画面のトークンに載せる認可リクエストのパラメーター
+func (i OauthAuthorizeInput) values() map[string]string {
+	return map[string]string{
+		"response_type":         i.ResponseType,
+		"client_id":             i.ClientID,
+		"redirect_uri":          i.RedirectURI,
+		"scope":                 i.Scope,
+		"state":                 i.State,
+		"code_challenge":        i.CodeChallenge,
+		"code_challenge_method": i.CodeChallengeMethod,
+		"nonce":                 i.Nonce,
+	}
+}
+
+func oauthAuthorizeInputFromClaims(claims jwt.MapClaims) OauthAuthorizeInput {
+	value := func(key string) string {
+		v, _ := claims[key].(string)
+		return v
+	}
+	return OauthAuthorizeInput{
+		ResponseType:        value("response_type"),
+		ClientID:            value("client_id"),
+		RedirectURI:         value("redirect_uri"),
+		Scope:               value("scope"),
+		State:               value("state"),
+		CodeChallenge:       value("code_challenge"),
+		CodeChallengeMethod: value("code_challenge_method"),
+		Nonce:               value("nonce"),
+	}
+}
+
+// ユーザーがサードパーティのクライアントに許可した内容の一覧
+func (s *OauthSvcStruct) ListConsents(userUUID string) ([]OauthConsent, error) {
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	consents, err := s.userConsentRepo.ListByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []OauthConsent{}
+	for _, consent := range consents {
+		client, err := s.oauthClientRepo.GetByClientID(consent.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, OauthConsent{
+			ClientID:   consent.ClientID,
+			ClientName: client.Name,
+			Scopes:     consent.ScopeList(),
+			GrantedAt:  consent.UpdatedAt,
+		})
+	}
+	return result, nil
+}
+
+// 同意を取り消す。クライアントに発行済みのリフレッシュトークンも失効させ、次回の認可では同意画面を表示する
+func (s *OauthSvcStruct) RevokeConsent(userUUID string, clientID string) error {
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := s.userConsentRepo.Delete(user.ID, clientID); err != nil {
+		if errors.Is(err, repositories.ErrUserConsentNotFound) {
+			return ErrOauthConsentNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// クライアントと redirect_uri が正しい場合はリダイレクト先を返す
+// リダイレクト先を確定できない誤りはクライアントに返さず、そのままエラーにする（RFC 6749 4.1.2.1）
+func (s *OauthSvcStruct) validateAuthorizeRequest(input OauthAuthorizeInput) (*models.OauthClient, string, error) {
+	if input.ClientID == "" {
+		return nil, "", newOauthError(OauthErrorInvalidRequest, "client_id is required")
+	}
+	client, err := s.oauthClientRepo.GetByClientID(input.ClientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOauthClientNotFound) {
+			return nil, "", newOauthError(OauthErrorInvalidRequest, "unknown client_id")
+		}
+		return nil, "", err
+	}
+
+	// 省略できるのは 1 件だけ登録されている場合のみ
+	redirectURI := input.RedirectURI
+	if redirectURIs := client.RedirectURIList(); redirectURI == "" && len(redirectURIs) == 1 {
+		redirectURI = redirectURIs[0]
+	}
+	if redirectURI == "" || !client.AllowsRedirectURI(redirectURI) {
+		return nil, "", newOauthError(OauthErrorInvalidRequest, "redirect_uri is not registered")
+	}
+
+	if input.ResponseType != OauthResponseTypeCode {
+		return nil, redirectURI, newOauthError(OauthErrorUnsupportedResponseType, "response_type must be code")
+	}
+	if !client.AllowsGrantType(OauthGrantTypeAuthorizationCode) {
+		return nil, redirectURI, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to use the authorization code grant")
+	}
+	if !client.AllowsScopes(strings.Fields(input.Scope)) {
+		return nil, redirectURI, newOauthError(OauthErrorInvalidScope, "scope is not allowed for the client")
+	}
+	if input.CodeChallenge == "" {
+		return nil, redirectURI, newOauthError(OauthErrorInvalidRequest, "code_challenge is required")
+	}
+	if input.CodeChallengeMethod != models.OauthCodeChallengeMethodS256 {
+		return nil, redirectURI, newOauthError(OauthErrorInvalidRequest, "code_challenge_method must be S256")
+	}
+	if !oauthCodeChallengePattern.MatchString(input.CodeChallenge) {
+		return nil, redirectURI, newOauthError(OauthErrorInvalidRequest, "code_challenge is invalid")
+	}
+	if len(input.Nonce) > OauthNonceMaxLength {
+		return nil, redirectURI, newOauthError(OauthErrorInvalidRequest, "nonce is too long")
+	}
+	return client, redirectURI, nil
+}
+
+func (s *OauthSvcStruct) authorizeErrorRedirect(redirectURI string, state string, err error) (string, error) {
+	var oauthErr *OauthError
+	if redirectURI == "" || !errors.As(err, &oauthErr) {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("error", oauthErr.Code)
+	query.Set("error_description", oauthErr.Description)
+	if state != "" {
+		query.Set("state", state)
+	}
+	return appendQuery(redirectURI, query)
+}
+
+// トークンエンドポイントでクライアントを認証する（RFC 6749 2.3）
+// 登録された token_endpoint_auth_method 以外の方式は受け付けない
+func (s *OauthSvcStruct) AuthenticateClient(input OauthClientAuthInput) (*models.OauthClient, error) {
+	method, clientID, err := input.method()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.oauthClientRepo.GetByClientID(clientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOauthClientNotFound) {
+			return nil, newOauthError(OauthErrorInvalidClient, "client authentication failed")
+		}
+		return nil, err
+	}
+	if client.TokenEndpointAuthMethod != method {
+		return nil, newOauthError(OauthErrorInvalidClient, "client authentication method is not allowed")
+	}
+
+	switch method {
+	case models.OauthClientAuthMethodClientSecretBasic:
+		if !client.VerifyClientSecret(input.BasicClientSecret) {
+			return nil, newOauthError(OauthErrorInvalidClient, "client authentication failed")
+		}
+	case models.OauthClientAuthMethodClientSecretPost:
+		if !client.VerifyClientSecret(input.ClientSecret) {
+			return nil, newOauthError(OauthErrorInvalidClient, "client authentication failed")
+		}
+	case models.OauthClientAuthMethodPrivateKeyJwt:
+		if err := s.verifyClientAssertion(client, input.ClientAssertion); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// 送られた認証情報から認証方式とクライアント ID を判定する。複数の方式を同時に使うことはできない
+func (i OauthClientAuthInput) method() (string, string, error) {
+	methods := []string{}
+	if i.BasicClientID != "" || i.BasicClientSecret != "" {
+		methods = append(methods, models.OauthClientAuthMethodClientSecretBasic)
+	}
+	if i.ClientSecret != "" {
+		methods = append(methods, models.OauthClientAuthMethodClientSecretPost)
+	}
+	if i.ClientAssertionType != "" || i.ClientAssertion != "" {
+		methods = append(methods, models.OauthClientAuthMethodPrivateKeyJwt)
+	}
+	if len(methods) > 1 {
+		return "", "", newOauthError(OauthErrorInvalidRequest, "multiple client authentication methods are used")
+	}
+
+	method := models.OauthClientAuthMethodNone
+	if len(methods) == 1 {
+		method = methods[0]
+	}
+
+	clientID := i.ClientID
+	switch method {
+	case models.OauthClientAuthMethodClientSecretBasic:
+		if clientID != "" && clientID != i.BasicClientID {
+			return "", "", newOauthError(OauthErrorInvalidClient, "client_id does not match")
+		}
+		clientID = i.BasicClientID
+	case models.OauthClientAuthMethodPrivateKeyJwt:
+		if i.ClientAssertionType != OauthClientAssertionTypeJwtBearer {
+			return "", "", newOauthError(OauthErrorInvalidRequest, "client_assertion_type is not supported")
+		}
+		// client_id は省略でき、その場合はアサーションの sub を使う。署名はクライアントを特定した後に検証する
+		if clientID == "" {
+			clientID = unverifiedSubject(i.ClientAssertion)
+		}
+	}
+	if clientID == "" {
+		return "", "", newOauthError(OauthErrorInvalidClient, "client authentication failed")
+	}
+	return method, clientID, nil
+}
+
+// private_key_jwt のアサーションを検証する（RFC 7523 3）
+// 同じ jti は有効期限まで再利用できない
+func (s *OauthSvcStruct) verifyClientAssertion(client *models.OauthClient, assertion string) error {
+	claims, err := s.jwttoken.ParseWithPublicKey(assertion, client.PublicKey)
+	if err != nil {
+		return newOauthError(OauthErrorInvalidClient, "client assertion is invalid")
+	}
+
+	issuer, _ := claims.GetIssuer()
+	subject, _ := claims.GetSubject()
+	if issuer != client.ClientID || subject != client.ClientID {
+		return newOauthError(OauthErrorInvalidClient, "client assertion iss and sub must be the client_id")
+	}
+	audience, _ := claims.GetAudience()
+	if !slices.Contains(audience, s.config.TokenEndpoint()) && !slices.Contains(audience, s.config.Issuer) {
+		return newOauthError(OauthErrorInvalidClient, "client assertion aud is invalid")
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return newOauthError(OauthErrorInvalidClient, "client assertion exp is required")
+	}
+	// 有効期限が長すぎるアサーションは jti を長期間保持することになるため拒否する
+	if expiresAt.After(s.clock.Now().Add(OauthClientAssertionMaxLifetime * time.Second)) {
+		return newOauthError(OauthErrorInvalidClient, "client assertion exp is too far in the future")
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return newOauthError(OauthErrorInvalidClient, "client assertion jti is required")
+	}
+
+	if err := s.oauthClientAssertionRepo.Register(client.ClientID, jti, expiresAt.Time); err != nil {
+		if errors.Is(err, repositories.ErrOauthClientAssertionReplayed) {
+			return newOauthError(OauthErrorInvalidClient, "client assertion has already been used")
+		}
+		return err
+	}
+	return nil
+}
+
+func unverifiedSubject(token string) string {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+	subject, _ := claims.GetSubject()
+	return subject
+}
+
+// 認可コードを使用済みにし、コードを発行したユーザーを返す
+// PKCE の検証に失敗した場合もコードは使用済みのままにし、総当たりできないようにする
+func (s *OauthSvcStruct) ConsumeAuthorizationCode(client *models.OauthClient, input OauthTokenInput) (*models.OauthAuthorizationCode, *models.User, error) {
+	if !client.AllowsGrantType(OauthGrantTypeAuthorizationCode) {
+		return nil, nil, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to use the authorization code grant")
+	}
+	if input.Code == "" || input.CodeVerifier == "" {
+		return nil, nil, newOauthError(OauthErrorInvalidRequest, "code and code_verifier are required")
+	}
+
+	code, err := s.oauthAuthorizationCodeRepo.ConsumeByCodeHash(models.HashOauthAuthorizationCode(input.Code))
+	if err != nil {
+		if errors.Is(err, repositories.ErrOauthAuthorizationCodeNotFound) {
+			return nil, nil, newOauthError(OauthErrorInvalidGrant, "authorization code is invalid, expired or already used")
+		}
+		return nil, nil, err
+	}
+
+	if code.ClientID != client.ClientID {
+		return nil, nil, newOauthError(OauthErrorInvalidGrant, "authorization code was issued to another client")
+	}
+	if code.RedirectURI != input.RedirectURI {
+		return nil, nil, newOauthError(OauthErrorInvalidGrant, "redirect_uri does not match")
+	}
+	if !code.VerifyCodeVerifier(input.CodeVerifier) {
+		return nil, nil, newOauthError(OauthErrorInvalidGrant, "code_verifier does not match")
+	}
+
+	user, err := s.userRepo.GetByID(code.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, nil, newOauthError(OauthErrorInvalidGrant, "user not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return code, user, nil
+}
+
+type OauthDeviceAuthorizationInput struct {
+	Scope string
+}
+
+// RFC 8628 3.2 のデバイス認可レスポンス
+type OauthDeviceAuthorizationOutput struct {
+	DeviceCode              string
+	UserCode                string
+	VerificationURI         string
+	VerificationURIComplete string
+	ExpiresIn               int
+	Interval                int
+}
+
+// 検証画面でユーザーに確認する承認の内容
+type OauthDeviceVerification struct {
+	ClientID   string   `json:"client_id"`
+	ClientName string   `json:"client_name"`
+	Scopes     []string `json:"scopes"`
+}
+
+type OauthDeviceDecisionInput struct {
+	UserCode string
+	Approve  bool
+}
+
+// ブラウザを使えないデバイスにデバイスコードとユーザーコードを発行する（RFC 8628 3.1）
+// クライアントの認証はトークンエンドポイントと同じ方式で行う
+func (s *OauthSvcStruct) AuthorizeDevice(client *models.OauthClient, input OauthDeviceAuthorizationInput) (*OauthDeviceAuthorizationOutput, error) {
+	if !client.AllowsGrantType(OauthGrantTypeDeviceCode) {
+		return nil, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to use the device authorization grant")
+	}
+	scopes := strings.Fields(input.Scope)
+	if !client.AllowsScopes(scopes) {
+		return nil, newOauthError(OauthErrorInvalidScope, "scope is not allowed for the client")
+	}
+
+	deviceCode := models.CreateOauthDeviceCode()
+	userCode := models.CreateOauthUserCode()
+	if err := s.oauthDeviceCodeRepo.Create(&models.OauthDeviceCode{
+		DeviceCodeHash: models.HashOauthDeviceCode(deviceCode),
+		UserCodeHash:   models.HashOauthUserCode(userCode),
+		ClientID:       client.ClientID,
+		Scope:          strings.Join(scopes, " "),
+		Status:         models.OauthDeviceCodeStatusPending,
+		PollInterval:   OauthDevicePollInterval,
+		ExpiresAt:      s.clock.Now().Add(OauthDeviceCodeExpiresIn * time.Second),
+	}); err != nil {
+		return nil, err
+	}
+
+	// QR コード等で開く場合に入力を省略できる URL（RFC 8628 3.3.1）
+	verificationURIComplete, err := appendQuery(s.config.DeviceVerificationURL, url.Values{"user_code": {userCode}})
+	if err != nil {
+		return nil, err
+	}
+	return &OauthDeviceAuthorizationOutput{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         s.config.DeviceVerificationURL,
+		VerificationURIComplete: verificationURIComplete,
+		ExpiresIn:               OauthDeviceCodeExpiresIn,
+		Interval:                OauthDevicePollInterval,
+	}, nil
+}
+
+// 検証画面でユーザーコードを入力したユーザーに、承認するクライアントとスコープを返す
+func (s *OauthSvcStruct) GetDeviceAuthorization(userCode string) (*OauthDeviceVerification, error) {
+	code, client, err := s.getPendingDeviceCode(userCode)
+	if err != nil {
+		return nil, err
+	}
+	return &OauthDeviceVerification{
+		ClientID:   client.ClientID,
+		ClientName: client.Name,
+		Scopes:     strings.Fields(code.Scope),
+	}, nil
+}
+
+// ログイン済みのユーザーがデバイスを承認・拒否する
+// 承認した場合は、ログインの認証時刻と方式をデバイスに発行するトークンに引き継ぐ
+func (s *OauthSvcStruct) DecideDeviceAuthorization(userUUID string, authContext AuthContext, input OauthDeviceDecisionInput) error {
+	code, _, err := s.getPendingDeviceCode(input.UserCode)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	code.UserID = &user.ID
+	code.Status = models.OauthDeviceCodeStatusDenied
+	if input.Approve {
+		code.Status = models.OauthDeviceCodeStatusApproved
+		code.Amr = strings.Join(authContext.Amr, " ")
+		if !authContext.AuthTime.IsZero() {
+			code.AuthTime = &authContext.AuthTime
+		}
+	}
+	if err := s.oauthDeviceCodeRepo.Decide(code); err != nil {
+		if errors.Is(err, repositories.ErrOauthDeviceCodeNotFound) {
+			return ErrInvalidOauthUserCode
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *OauthSvcStruct) getPendingDeviceCode(userCode string) (*models.OauthDeviceCode, *models.OauthClient, error) {
+	if models.NormalizeOauthUserCode(userCode) == "" {
+		return nil, nil, ErrInvalidOauthUserCode
+	}
+	code, err := s.oauthDeviceCodeRepo.GetPendingByUserCodeHash(models.HashOauthUserCode(userCode))
+	if err != nil {
+		if errors.Is(err, repositories.ErrOauthDeviceCodeNotFound) {
+			return nil, nil, ErrInvalidOauthUserCode
+		}
+		return nil, nil, err
+	}
+	// 発行後にクライアントが削除された場合
+	client, err := s.oauthClientRepo.GetByClientID(code.ClientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOauthClientNotFound) {
+			return nil, nil, ErrInvalidOauthUserCode
+		}
+		return nil, nil, err
+	}
+	return code, client, nil
+}
+
+// トークンエンドポイントへのポーリング（RFC 8628 3.4 / 3.5）
+// 承認されるまでは authorization_pending を返し、間隔を守らないクライアントには slow_down を返して間隔を延ばす
+func (s *OauthSvcStruct) ConsumeDeviceCode(client *models.OauthClient, deviceCode string) (*models.OauthDeviceCode, *models.User, error) {
+	if !client.AllowsGrantType(OauthGrantTypeDeviceCode) {
+		return nil, nil, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to use the device authorization grant")
+	}
+	if deviceCode == "" {
+		return nil, nil, newOauthError(OauthErrorInvalidRequest, "device_code is required")
+	}
+
+	code, err := s.oauthDeviceCodeRepo.GetByDeviceCodeHash(models.HashOauthDeviceCode(deviceCode))
+	if err != nil {
+		if errors.Is(err, repositories.ErrOauthDeviceCodeNotFound) {
+			return nil, nil, newOauthError(OauthErrorInvalidGrant, "device_code is invalid")
+		}
+		return nil, nil, err
+	}
+	if code.ClientID != client.ClientID {
+		return nil, nil, newOauthError(OauthErrorInvalidGrant, "device_code was issued to another client")
+	}
+	if code.UsedAt != nil {
+		return nil, nil, newOauthError(OauthErrorInvalidGrant, "device_code has already been used")
+	}
+
+	now := s.clock.Now()
+	if !now.Before(code.ExpiresAt) {
+		return nil, nil, newOauthError(OauthErrorExpiredToken, "device_code has expired")
+	}
+
+	switch code.Status {
+	case models.OauthDeviceCodeStatusDenied:
+		return nil, nil, newOauthError(OauthErrorAccessDenied, "the user denied the authorization request")
+	case models.OauthDeviceCodeStatusPending:
+		return nil, nil, s.pollPendingDeviceCode(code, now)
+	}
+
+	if err := s.oauthDeviceCodeRepo.Consume(code.ID); err != nil {
+		if errors.Is(err, repositories.ErrOauthDeviceCodeNotFound) {
+			return nil, nil, newOauthError(OauthErrorInvalidGrant, "device_code has already been used")
+		}
+		return nil, nil, err
+	}
+
+	if code.UserID == nil {
+		return nil, nil, newOauthError(OauthErrorInvalidGrant, "user not found")
+	}
+	user, err := s.userRepo.GetByID(*code.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, nil, newOauthError(OauthErrorInvalidGrant, "user not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return code, user, nil
+}
+
+// 前回のポーリングから間隔が空いていない場合は slow_down を返し、以降の間隔を延ばす
+func (s *OauthSvcStruct) pollPendingDeviceCode(code *models.OauthDeviceCode, now time.Time) error {
+	pollInterval := code.PollInterval
+	oauthErr := newOauthError(OauthErrorAuthorizationPending, "the user has not yet completed the authorization")
+	if code.LastPolledAt != nil && now.Before(code.LastPolledAt.Add(time.Duration(pollInterval)*time.Second)) {
+		pollInterval += OauthDeviceSlowDownInterval
+		oauthErr = newOauthError(OauthErrorSlowDown, "polling too frequently")
+	}
+
+	if err := s.oauthDeviceCodeRepo.UpdatePolling(code.ID, now, pollInterval); err != nil {
+		return err
+	}
+	return oauthErr
+}
+
+// トークン交換のリクエスト（RFC 8693 2.1）
+// subject_token を渡すと委任、requested_subject を渡すと成り代わりとして扱う
+type OauthTokenExchangeInput struct {
+	SubjectToken       string
+	SubjectTokenType   string
+	ActorToken         string
+	ActorTokenType     string
+	RequestedTokenType string
+	// 成り代わるユーザーの UUID。actor_token にはスタッフのアクセストークンを渡す
+	RequestedSubject string
+	Audience         []string
+	Scope            string
+	IpAddress        string
+}
+
+// 検証済みのトークン交換。AuthSvc がこの内容でアクセストークンを発行する
+type OauthTokenExchangeGrant struct {
+	Subject *models.User
+	// act クレーム（RFC 8693 4.1）。交換前のトークンの act は入れ子にして残す
+	Act map[string]any
+	// 監査ログに残す act の sub
+	Actor         string
+	Impersonation bool
+	Audience      []string
+	Scope         string
+	// 成り代わりの場合は空にし、ステップアップが必要な操作をさせない
+	AuthContext AuthContext
+	// 交換に使ったトークンの有効期限。発行するトークンをこれより長く有効にしない
+	ExpiresAt time.Time
+}
+
+// トークン交換（RFC 8693）を検証する。トークンを交換できるのはこのグラントを許可した機密クライアントのみ
+func (s *OauthSvcStruct) ValidateTokenExchange(client *models.OauthClient, input OauthTokenExchangeInput) (*OauthTokenExchangeGrant, error) {
+	if client.IsPublic() || !client.AllowsGrantType(OauthGrantTypeTokenExchange) {
+		return nil, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to use the token exchange grant")
+	}
+	if input.RequestedTokenType != "" && input.RequestedTokenType != OauthTokenTypeAccessToken {
+		return nil, newOauthError(OauthErrorInvalidRequest, "requested_token_type is not supported")
+	}
+	if input.ActorToken == "" && input.ActorTokenType != "" {
+		return nil, newOauthError(OauthErrorInvalidRequest, "actor_token is required when actor_token_type is present")
+	}
+
+	var grant *OauthTokenExchangeGrant
+	var availableScopes []string
+	var err error
+	if input.RequestedSubject != "" {
+		grant, err = s.impersonationGrant(client, input)
+		availableScopes = client.ScopeList()
+	} else {
+		grant, availableScopes, err = s.delegationGrant(client, input)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if grant.Audience, err = s.tokenExchangeAudience(client, input.Audience); err != nil {
+		return nil, err
+	}
+
+	// scope を省略した場合は交換前のトークンの範囲をそのまま引き継ぐ。広げることはできない
+	scopes := strings.Fields(input.Scope)
+	if len(scopes) == 0 {
+		scopes = availableScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(availableScopes, scope) {
+			return nil, newOauthError(OauthErrorInvalidScope, "scope exceeds the subject token")
+		}
+	}
+	grant.Scope = strings.Join(scopes, " ")
+	return grant, nil
+}
+
+// ユーザーのトークンを、クライアント（またはクライアントの actor_token）が代理で使うトークンに交換する
+func (s *OauthSvcStruct) delegationGrant(client *models.OauthClient, input OauthTokenExchangeInput) (*OauthTokenExchangeGrant, []string, error) {
+	if input.SubjectToken == "" || input.SubjectTokenType == "" {
+		return nil, nil, newOauthError(OauthErrorInvalidRequest, "subject_token and subject_token_type are required")
+	}
+	subjectClaims, err := s.parseExchangeToken(input.SubjectToken, input.SubjectTokenType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkExchangeTokenAudience(client, subjectClaims, "subject_token"); err != nil {
+		return nil, nil, err
+	}
+	user, err := s.exchangeTokenUser(subjectClaims)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, nil, newOauthError(OauthErrorInvalidRequest, "subject_token is invalid")
+		}
+		return nil, nil, err
+	}
+
+	expiresAt, _ := subjectClaims.GetExpirationTime()
+	grant := &OauthTokenExchangeGrant{
+		Subject:     user,
+		Actor:       client.ClientID,
+		AuthContext: AuthContextFromClaims(subjectClaims),
+		ExpiresAt:   expiresAt.Time,
+	}
+	if sid, ok := subjectClaims["sid"].(string); ok {
+		grant.AuthContext.SessionID = sid
+	}
+
+	// actor_token はクライアント自身の client_credentials のトークンのみ受け付ける
+	if input.ActorToken != "" {
+		actorClaims, err := s.parseExchangeToken(input.ActorToken, input.ActorTokenType)
+		if err != nil {
+			return nil, nil, err
+		}
+		if actorClaims["principal"] != PrincipalClient || actorClaims["client_id"] != client.ClientID {
+			return nil, nil, newOauthError(OauthErrorInvalidRequest, "actor_token must be issued to the client")
+		}
+		if actorExpiresAt, _ := actorClaims.GetExpirationTime(); actorExpiresAt.Before(grant.ExpiresAt) {
+			grant.ExpiresAt = actorExpiresAt.Time
+		}
+	}
+
+	grant.Act = map[string]any{"sub": grant.Actor}
+	if prior, ok := subjectClaims["act"].(map[string]any); ok {
+		grant.Act["act"] = prior
+	}
+
+	// 交換前のトークンとクライアントの両方に許可されたスコープのみ引き継ぐ
+	// scope クレームのないトークンはファーストパーティのログインのものも含め、スコープなしとして扱う
+	scope, _ := subjectClaims["scope"].(string)
+	availableScopes := slices.DeleteFunc(strings.Fields(scope), func(scope string) bool {
+		return !slices.Contains(client.ScopeList(), scope)
+	})
+	return grant, availableScopes, nil
+}
+
+// スタッフのトークンを、requested_subject のユーザーに成り代わるトークンに交換する
+// ファーストパーティのクライアントから、OAUTH_IMPERSONATORS に登録したスタッフのみ利用できる
+func (s *OauthSvcStruct) impersonationGrant(client *models.OauthClient, input OauthTokenExchangeInput) (*OauthTokenExchangeGrant, error) {
+	if !client.FirstParty {
+		return nil, newOauthError(OauthErrorUnauthorizedClient, "client is not allowed to impersonate users")
+	}
+	if input.SubjectToken != "" {
+		return nil, newOauthError(OauthErrorInvalidRequest, "subject_token cannot be used with requested_subject")
+	}
+	if input.ActorToken == "" {
+		return nil, newOauthError(OauthErrorInvalidRequest, "actor_token is required to impersonate users")
+	}
+	actorClaims, err := s.parseExchangeToken(input.ActorToken, input.ActorTokenType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkExchangeTokenAudience(client, actorClaims, "actor_token"); err != nil {
+		return nil, err
+	}
+	staff, err := s.exchangeTokenUser(actorClaims)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, newOauthError(OauthErrorInvalidRequest, "actor_token is invalid")
+		}
+		return nil, err
+	}
+	if !slices.Contains(s.config.Impersonators, staff.UUID) {
+		return nil, newOauthError(OauthErrorInvalidRequest, "actor is not allowed to impersonate users")
+	}
+
+	user, err := s.userRepo.GetByUUID(input.RequestedSubject)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, newOauthError(OauthErrorInvalidRequest, "requested_subject is not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	expiresAt, _ := actorClaims.GetExpirationTime()
+	actor := "user" + staff.UUID
+	return &OauthTokenExchangeGrant{
+		Subject:       user,
+		Act:           map[string]any{"sub": actor},
+		Actor:         actor,
+		Impersonation: true,
+		ExpiresAt:     expiresAt.Time,
+	}, nil
+}
+
+// 交換に使うトークンはこのサーバーが発行したアクセストークンのみ
+// MFA トークン等の typ を持つトークンは受け付けない
+func (s *OauthSvcStruct) parseExchangeToken(token string, tokenType string) (jwt.MapClaims, error) {
+	if tokenType != OauthTokenTypeAccessToken && tokenType != OauthTokenTypeJwt {
+		return nil, newOauthError(OauthErrorInvalidRequest, "token type is not supported")
+	}
+	claims, err := s.jwttoken.Parse(token, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return nil, newOauthError(OauthErrorInvalidRequest, "token is invalid")
+	}
+	if _, ok := claims["typ"]; ok {
+		return nil, newOauthError(OauthErrorInvalidRequest, "token is not an access token")
+	}
+	if issuer, _ := claims.GetIssuer(); issuer != s.config.Issuer {
+		return nil, newOauthError(OauthErrorInvalidRequest, "token is not issued by this server")
+	}
+	return claims, nil
+}
+
+// ユーザーのアクセストークンの sub から、削除されていないユーザーを取得する
+func (s *OauthSvcStruct) exchangeTokenUser(claims jwt.MapClaims) (*models.User, error) {
+	sub, _ := claims["sub"].(string)
+	userUUID, ok := strings.CutPrefix(sub, "user")
+	if !ok || claims["principal"] == PrincipalClient {
+		return nil, repositories.ErrUserNotFound
+	}
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// 別の受け手に発行されたトークンを持ち込んで交換できないよう、aud がクライアント自身か交換を許可した受け手のトークンのみ受け付ける
+func (s *OauthSvcStruct) checkExchangeTokenAudience(client *models.OauthClient, claims jwt.MapClaims, param string) error {
+	audience, _ := claims.GetAudience()
+	if !client.AcceptsTokenExchangeSubject(audience) {
+		return newOauthError(OauthErrorInvalidRequest, param+" is not intended for the client")
+	}
+	return nil
+}
+
+// audience はクライアントごとに許可したもののみ指定できる。省略した場合は交換したクライアント自身とする
+func (s *OauthSvcStruct) tokenExchangeAudience(client *models.OauthClient, audience []string) ([]string, error) {
+	if len(audience) == 0 {
+		return []string{client.ClientID}, nil
+	}
+	audiences := []string{}
+	for _, aud := range audience {
+		if slices.Contains(audiences, aud) {
+			continue
+		}
+		if !client.AllowsTokenExchangeAudience(aud) {
+			return nil, newOauthError(OauthErrorInvalidTarget, "audience is not allowed for the client")
+		}
+		audiences = append(audiences, aud)
+	}
+	return audiences, nil
+}
+
+// 発行したトークンを監査ログに残す
+func (s *OauthSvcStruct) RecordTokenExchange(exchange *models.OauthTokenExchange) error {
+	return s.oauthTokenExchangeRepo.Create(exchange)
+}
+
+// 登録済みの redirect_uri が持つクエリは残したままパラメーターを追加する（RFC 6749 3.1.2）
+func appendQuery(rawURL string, params url.Values) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,1696 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+// RFC 7636 Appendix B
+const (
+	testOauthCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testOauthCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	testOauthRedirectURI   = "https://client.example.com/callback"
+)
+
+var testOauthUser = &models.User{
+	ID:    1,
+	UUID:  "test-uuid",
+	Email: "user@example.com",
+}
+
+func newTestOauthConfig() OauthConfig {
+	return OauthConfig{
+		Issuer:                "https://auth.example.com",
+		LoginURL:              "https://auth.example.com/login",
+		DeviceVerificationURL: "https://auth.example.com/device",
+		SigningKey:            testOidcSigningKey,
+	}
+}
+
+// 認可コードのテストでは同意画面を省略するファーストパーティのクライアントを使う
+func newTestOauthClient() *models.OauthClient {
+	return &models.OauthClient{
+		ClientID:                "test-client",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodNone,
+		FirstParty:              true,
+		RedirectURIs:            testOauthRedirectURI,
+		GrantTypes:              "authorization_code refresh_token",
+		Scopes:                  "openid profile email",
+	}
+}
+
+func newTestOauthMultiClient() *models.OauthClient {
+	return &models.OauthClient{
+		ClientID:                "multi-client",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodNone,
+		FirstParty:              true,
+		RedirectURIs:            "https://a.example.com/cb https://b.example.com/cb?tenant=1",
+		GrantTypes:              "authorization_code",
+		Scopes:                  "openid profile",
+	}
+}
+
+func newTestOauthSvc() *OauthSvcStruct {
+	return newTestOauthSvcWithClients(newTestOauthClient(), newTestOauthMultiClient())
+}
+
+// 登録済みのクライアント以外は見つからないものとして扱う
+func newTestOauthSvcWithClients(clients ...*models.OauthClient) *OauthSvcStruct {
+	return NewOauthSvc(
+		newTestOauthConfig(),
+		new(repo_mock.UserRepoMock),
+		new(repo_mock.OauthClientRepoMock).OnGetByClientID(clients...),
+		new(repo_mock.OauthAuthorizationCodeRepoMock),
+		new(repo_mock.OauthDeviceCodeRepoMock),
+		new(repo_mock.OauthClientAssertionRepoMock),
+		new(repo_mock.OauthTokenExchangeRepoMock),
+		new(repo_mock.UserConsentRepoMock),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClockMock(time.Now()),
+	)
+}
+
+func validOauthAuthorizeInput() OauthAuthorizeInput {
+	return OauthAuthorizeInput{
+		ResponseType:        OauthResponseTypeCode,
+		ClientID:            "test-client",
+		RedirectURI:         testOauthRedirectURI,
+		Scope:               "openid profile",
+		State:               "xyz",
+		CodeChallenge:       testOauthCodeChallenge,
+		CodeChallengeMethod: models.OauthCodeChallengeMethodS256,
+		Nonce:               "n-0S6_WzA2Mj",
+	}
+}
+
+func newTestOauthAuthorizationCode() *models.OauthAuthorizationCode {
+	return &models.OauthAuthorizationCode{
+		ID:                  1,
+		ClientID:            "test-client",
+		UserID:              1,
+		RedirectURI:         testOauthRedirectURI,
+		Scope:               "openid profile",
+		CodeChallenge:       testOauthCodeChallenge,
+		CodeChallengeMethod: models.OauthCodeChallengeMethodS256,
+		Nonce:               "n-0S6_WzA2Mj",
+		Amr:                 "pwd otp mfa",
+	}
+}
+
+func newTestOauthTokenInput() OauthTokenInput {
+	return OauthTokenInput{
+		Code:         "test-code",
+		RedirectURI:  testOauthRedirectURI,
+		CodeVerifier: testOauthCodeVerifier,
+	}
+}
+
+func assertOauthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *OauthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
+
+func TestNewOauthConfigFromEnv(t *testing.T) {
+	config := NewOauthConfigFromEnv()
+	if config.Issuer != "http://localhost:8080" || config.LoginURL != "http://localhost:8080/oauth/login" ||
+		config.DeviceVerificationURL != "http://localhost:8080/oauth/device" {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+
+	funcs.WithEnvMap(map[string]string{
+		"OAUTH_ISSUER":                  "https://auth.example.com/",
+		"OAUTH_LOGIN_URL":               "https://auth.example.com/login",
+		"OAUTH_DEVICE_VERIFICATION_URL": "https://auth.example.com/device",
+		"OAUTH_IMPERSONATORS":           "staff-uuid, admin-uuid",
+	}, t, func() {
+		config := NewOauthConfigFromEnv()
+		if config.Issuer != "https://auth.example.com" || config.TokenEndpoint() != "https://auth.example.com/oauth/token" {
+			t.Errorf("unexpected issuer: %+v", config)
+		}
+		if config.AuthorizationEndpoint() != "https://auth.example.com/oauth/authorize" ||
+			config.UserinfoEndpoint() != "https://auth.example.com/userinfo" ||
+			config.JwksURI() != "https://auth.example.com/.well-known/jwks.json" {
+			t.Errorf("unexpected endpoints: %+v", config)
+		}
+		if config.LoginURL != "https://auth.example.com/login" || config.DeviceVerificationURL != "https://auth.example.com/device" {
+			t.Errorf("unexpected urls: %+v", config)
+		}
+		if config.DeviceAuthorizationEndpoint() != "https://auth.example.com/oauth/device_authorization" {
+			t.Errorf("unexpected device authorization endpoint: %s", config.DeviceAuthorizationEndpoint())
+		}
+		if !slices.Equal(config.Impersonators, []string{"staff-uuid", "admin-uuid"}) {
+			t.Errorf("unexpected impersonators: %v", config.Impersonators)
+		}
+	})
+}
+
+func TestNewOauthConfigFromEnvSigningKey(t *testing.T) {
+	// 未設定の場合はプロセス内で同じ鍵を使い回す
+	ephemeral := NewOauthConfigFromEnv().SigningKey
+	if ephemeral == nil || !ephemeral.Equal(NewOauthConfigFromEnv().SigningKey) {
+		t.Fatal("expected the same ephemeral signing key")
+	}
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "signing_key.pem")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testOidcSigningKey)})
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalidFile := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		path     string
+		expected *rsa.PrivateKey
+	}{
+		"key file":     {keyFile, testOidcSigningKey},
+		"missing file": {filepath.Join(dir, "missing.pem"), ephemeral},
+		"invalid file": {invalidFile, ephemeral},
+	}
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			funcs.WithEnv("OIDC_SIGNING_KEY_FILE", tt.path, t, func() {
+				if !NewOauthConfigFromEnv().SigningKey.Equal(tt.expected) {
+					t.Error("unexpected signing key")
+				}
+			})
+		})
+	}
+}
+
+func TestOauthBeginAuthorize(t *testing.T) {
+	svc := newTestOauthSvc()
+
+	redirectTo, err := svc.BeginAuthorize(validOauthAuthorizeInput())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	u, _ := url.Parse(redirectTo)
+	if u.Host != "auth.example.com" || u.Path != "/login" {
+		t.Errorf("unexpected redirect: %s", redirectTo)
+	}
+	query := u.Query()
+	for key, expected := range map[string]string{
+		"response_type":         "code",
+		"client_id":             "test-client",
+		"redirect_uri":          testOauthRedirectURI,
+		"scope":                 "openid profile",
+		"state":                 "xyz",
+		"code_challenge":        testOauthCodeChallenge,
+		"code_challenge_method": "S256",
+		"nonce":                 "n-0S6_WzA2Mj",
+	} {
+		if query.Get(key) != expected {
+			t.Errorf("%s: expected %s, got %s", key, expected, query.Get(key))
+		}
+	}
+}
+
+func TestOauthBeginAuthorizeRedirectError(t *testing.T) {
+	tests := map[string]struct {
+		modify   func(input *OauthAuthorizeInput)
+		expected string
+	}{
+		"unsupported response_type": {func(input *OauthAuthorizeInput) { input.ResponseType = "token" }, OauthErrorUnsupportedResponseType},
+		"missing code_challenge":    {func(input *OauthAuthorizeInput) { input.CodeChallenge = "" }, OauthErrorInvalidRequest},
+		"plain method":              {func(input *OauthAuthorizeInput) { input.CodeChallengeMethod = "plain" }, OauthErrorInvalidRequest},
+		"missing method":            {func(input *OauthAuthorizeInput) { input.CodeChallengeMethod = "" }, OauthErrorInvalidRequest},
+		"invalid code_challenge":    {func(input *OauthAuthorizeInput) { input.CodeChallenge = "short" }, OauthErrorInvalidRequest},
+		"scope not allowed":         {func(input *OauthAuthorizeInput) { input.Scope = "openid admin" }, OauthErrorInvalidScope},
+		"grant not allowed":         {func(input *OauthAuthorizeInput) { input.ClientID = "code-disabled-client" }, OauthErrorUnauthorizedClient},
+		"nonce too long":            {func(input *OauthAuthorizeInput) { input.Nonce = strings.Repeat("n", OauthNonceMaxLength+1) }, OauthErrorInvalidRequest},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			input := validOauthAuthorizeInput()
+			tt.modify(&input)
+
+			codeDisabledClient := newTestOauthClient()
+			codeDisabledClient.ClientID = "code-disabled-client"
+			codeDisabledClient.GrantTypes = "refresh_token"
+			redirectTo, err := newTestOauthSvcWithClients(newTestOauthClient(), codeDisabledClient).BeginAuthorize(input)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			// エラーはクライアントのリダイレクト先に state を付けて返す
+			u, _ := url.Parse(redirectTo)
+			if u.Scheme+"://"+u.Host+u.Path != testOauthRedirectURI {
+				t.Errorf("unexpected redirect: %s", redirectTo)
+			}
+			if u.Query().Get("error") != tt.expected || u.Query().Get("state") != "xyz" || u.Query().Get("error_description") == "" {
+				t.Errorf("unexpected query: %s", u.RawQuery)
+			}
+		})
+	}
+}
+
+func TestOauthBeginAuthorizeFail(t *testing.T) {
+	tests := map[string]func(input *OauthAuthorizeInput){
+		"unknown client":             func(input *OauthAuthorizeInput) { input.ClientID = "unknown" },
+		"empty client":               func(input *OauthAuthorizeInput) { input.ClientID = "" },
+		"unregistered redirect_uri":  func(input *OauthAuthorizeInput) { input.RedirectURI = "https://evil.example.com/callback" },
+		"redirect_uri prefix":        func(input *OauthAuthorizeInput) { input.RedirectURI = testOauthRedirectURI + "/evil" },
+		"redirect_uri with query":    func(input *OauthAuthorizeInput) { input.RedirectURI = testOauthRedirectURI + "?next=evil" },
+		"omitted with multiple uris": func(input *OauthAuthorizeInput) { input.ClientID = "multi-client"; input.RedirectURI = "" },
+	}
+
+	for title, modify := range tests {
+		t.Run(title, func(t *testing.T) {
+			input := validOauthAuthorizeInput()
+			// クライアントを確認できない場合は、他の誤りがあってもリダイレクトしない
+			input.ResponseType = "token"
+			modify(&input)
+
+			redirectTo, err := newTestOauthSvc().BeginAuthorize(input)
+			assertOauthError(t, err, OauthErrorInvalidRequest)
+			if redirectTo != "" {
+				t.Errorf("expected no redirect, got %s", redirectTo)
+			}
+		})
+	}
+
+	t.Run("client db error", func(t *testing.T) {
+		svc := newTestOauthSvcWithClients()
+		clientRepoMock := new(repo_mock.OauthClientRepoMock)
+		clientRepoMock.On("GetByClientID", "test-client").Return((*models.OauthClient)(nil), fmt.Errorf("db error"))
+		svc.oauthClientRepo = clientRepoMock
+
+		redirectTo, err := svc.BeginAuthorize(validOauthAuthorizeInput())
+		var oauthErr *OauthError
+		if err == nil || errors.As(err, &oauthErr) || redirectTo != "" {
+			t.Fatalf("expected db error, got %v %s", err, redirectTo)
+		}
+	})
+}
+
+func TestOauthAuthorize(t *testing.T) {
+	now := time.Now()
+	authTime := now.Add(-time.Minute)
+	svc := newTestOauthSvc()
+	svc.clock = atylabclock.NewClockMock(now)
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+	codeRepoMock := svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock)
+	codeRepoMock.On("Create", mock.Anything).Return(nil)
+
+	input := validOauthAuthorizeInput()
+	input.Scope = " openid  profile "
+	redirectTo, err := svc.Authorize("test-uuid", AuthContext{AuthTime: authTime, Amr: []string{AmrPwd}}, input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	u, _ := url.Parse(redirectTo)
+	code := u.Query().Get("code")
+	if u.Scheme+"://"+u.Host+u.Path != testOauthRedirectURI || code == "" || u.Query().Get("state") != "xyz" {
+		t.Fatalf("unexpected redirect: %s", redirectTo)
+	}
+
+	// 平文のコードは保存しない
+	record := codeRepoMock.Calls[0].Arguments.Get(0).(*models.OauthAuthorizationCode)
+	if record.CodeHash != models.HashOauthAuthorizationCode(code) {
+		t.Errorf("unexpected code hash: %s", record.CodeHash)
+	}
+	if record.ClientID != "test-client" || record.UserID != 1 || record.RedirectURI != testOauthRedirectURI {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if record.Scope != "openid profile" || record.CodeChallenge != testOauthCodeChallenge || record.Amr != "pwd" || record.Nonce != "n-0S6_WzA2Mj" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if !record.AuthTime.Equal(authTime) || !record.ExpiresAt.Equal(now.Add(OauthAuthorizationCodeExpiresIn*time.Second)) {
+		t.Errorf("unexpected times: %+v", record)
+	}
+}
+
+func TestOauthAuthorizeDefaultRedirectURI(t *testing.T) {
+	svc := newTestOauthSvc()
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+	codeRepoMock := svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock)
+	codeRepoMock.On("Create", mock.Anything).Return(nil)
+
+	input := validOauthAuthorizeInput()
+	input.RedirectURI = ""
+	input.State = ""
+	redirectTo, err := svc.Authorize("test-uuid", AuthContext{}, input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	u, _ := url.Parse(redirectTo)
+	if u.Scheme+"://"+u.Host+u.Path != testOauthRedirectURI || u.Query().Has("state") {
+		t.Errorf("unexpected redirect: %s", redirectTo)
+	}
+	// 省略された場合はトークンリクエストでも省略させる
+	record := codeRepoMock.Calls[0].Arguments.Get(0).(*models.OauthAuthorizationCode)
+	if record.RedirectURI != "" || record.AuthTime != nil {
+		t.Errorf("unexpected record: %+v", record)
+	}
+}
+
+func TestOauthAuthorizeKeepsRegisteredQuery(t *testing.T) {
+	svc := newTestOauthSvc()
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+	svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).On("Create", mock.Anything).Return(nil)
+
+	input := validOauthAuthorizeInput()
+	input.ClientID = "multi-client"
+	input.RedirectURI = "https://b.example.com/cb?tenant=1"
+	redirectTo, err := svc.Authorize("test-uuid", AuthContext{}, input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	u, _ := url.Parse(redirectTo)
+	if u.Query().Get("tenant") != "1" || u.Query().Get("code") == "" {
+		t.Errorf("unexpected redirect: %s", redirectTo)
+	}
+}
+
+func TestOauthAuthorizeFail(t *testing.T) {
+	t.Run("invalid client", func(t *testing.T) {
+		input := validOauthAuthorizeInput()
+		input.ClientID = "unknown"
+		_, err := newTestOauthSvc().Authorize("test-uuid", AuthContext{}, input)
+		assertOauthError(t, err, OauthErrorInvalidRequest)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		svc := newTestOauthSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return((*models.User)(nil), repositories.ErrUserNotFound)
+		if _, err := svc.Authorize("test-uuid", AuthContext{}, validOauthAuthorizeInput()); !errors.Is(err, repositories.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+
+	t.Run("create error", func(t *testing.T) {
+		svc := newTestOauthSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+		svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+		if _, err := svc.Authorize("test-uuid", AuthContext{}, validOauthAuthorizeInput()); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestOauthConsumeAuthorizationCode(t *testing.T) {
+	svc := newTestOauthSvc()
+	svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+		On("ConsumeByCodeHash", models.HashOauthAuthorizationCode("test-code")).Return(newTestOauthAuthorizationCode(), nil)
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testOauthUser, nil)
+
+	code, user, err := svc.ConsumeAuthorizationCode(newTestOauthClient(), newTestOauthTokenInput())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if code.ID != 1 || user.UUID != "test-uuid" {
+		t.Errorf("unexpected result: %+v %+v", code, user)
+	}
+}
+
+func TestOauthConsumeAuthorizationCodeFail(t *testing.T) {
+	tests := map[string]struct {
+		client   *models.OauthClient
+		modify   func(input *OauthTokenInput)
+		expected string
+	}{
+		"missing code":           {newTestOauthClient(), func(input *OauthTokenInput) { input.Code = "" }, OauthErrorInvalidRequest},
+		"missing code_verifier":  {newTestOauthClient(), func(input *OauthTokenInput) { input.CodeVerifier = "" }, OauthErrorInvalidRequest},
+		"another client":         {newTestOauthMultiClient(), func(input *OauthTokenInput) {}, OauthErrorInvalidGrant},
+		"redirect_uri mismatch":  {newTestOauthClient(), func(input *OauthTokenInput) { input.RedirectURI = "https://a.example.com/cb" }, OauthErrorInvalidGrant},
+		"redirect_uri omitted":   {newTestOauthClient(), func(input *OauthTokenInput) { input.RedirectURI = "" }, OauthErrorInvalidGrant},
+		"code_verifier mismatch": {newTestOauthClient(), func(input *OauthTokenInput) { input.CodeVerifier = testOauthCodeVerifier[:42] + "a" }, OauthErrorInvalidGrant},
+		"grant not allowed":      {&models.OauthClient{ClientID: "test-client", GrantTypes: "refresh_token"}, func(input *OauthTokenInput) {}, OauthErrorUnauthorizedClient},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthSvc()
+			svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+				On("ConsumeByCodeHash", mock.Anything).Return(newTestOauthAuthorizationCode(), nil)
+
+			input := newTestOauthTokenInput()
+			tt.modify(&input)
+			_, _, err := svc.ConsumeAuthorizationCode(tt.client, input)
+			assertOauthError(t, err, tt.expected)
+		})
+	}
+
+	t.Run("used or expired code", func(t *testing.T) {
+		svc := newTestOauthSvc()
+		svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+			On("ConsumeByCodeHash", mock.Anything).Return((*models.OauthAuthorizationCode)(nil), repositories.ErrOauthAuthorizationCodeNotFound)
+
+		_, _, err := svc.ConsumeAuthorizationCode(newTestOauthClient(), newTestOauthTokenInput())
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+
+	t.Run("consume db error", func(t *testing.T) {
+		svc := newTestOauthSvc()
+		svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+			On("ConsumeByCodeHash", mock.Anything).Return((*models.OauthAuthorizationCode)(nil), fmt.Errorf("db error"))
+
+		_, _, err := svc.ConsumeAuthorizationCode(newTestOauthClient(), newTestOauthTokenInput())
+		var oauthErr *OauthError
+		if err == nil || errors.As(err, &oauthErr) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+
+	t.Run("user deleted", func(t *testing.T) {
+		svc := newTestOauthSvc()
+		svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).
+			On("ConsumeByCodeHash", mock.Anything).Return(newTestOauthAuthorizationCode(), nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+		_, _, err := svc.ConsumeAuthorizationCode(newTestOauthClient(), newTestOauthTokenInput())
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+}
+
+func newTestConfidentialOauthClient(clientID string, method string) (*models.OauthClient, string) {
+	secret := models.CreateOauthClientSecret()
+	client := newTestOauthClient()
+	client.ClientID = clientID
+	client.TokenEndpointAuthMethod = method
+	client.ClientSecretHash = models.HashOauthClientSecret(secret)
+	return client, secret
+}
+
+func newTestPrivateKeyJwtClient(t *testing.T) (*models.OauthClient, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	client := newTestOauthClient()
+	client.ClientID = "jwt-client"
+	client.TokenEndpointAuthMethod = models.OauthClientAuthMethodPrivateKeyJwt
+	client.PublicKey = testPublicKeyPEM(t, key)
+	return client, key
+}
+
+func validClientAssertionClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss": "jwt-client",
+		"sub": "jwt-client",
+		"aud": "https://auth.example.com/oauth/token",
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"jti": "assertion-id",
+	}
+}
+
+func signTestClientAssertion(t *testing.T, key *ecdsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign assertion: %v", err)
+	}
+	return token
+}
+
+func TestOauthAuthenticateClient(t *testing.T) {
+	basicClient, basicSecret := newTestConfidentialOauthClient("basic-client", models.OauthClientAuthMethodClientSecretBasic)
+	postClient, postSecret := newTestConfidentialOauthClient("post-client", models.OauthClientAuthMethodClientSecretPost)
+	svc := newTestOauthSvcWithClients(newTestOauthClient(), basicClient, postClient)
+
+	tests := map[string]struct {
+		input    OauthClientAuthInput
+		expected string
+	}{
+		"none":                {OauthClientAuthInput{ClientID: "test-client"}, "test-client"},
+		"client_secret_basic": {OauthClientAuthInput{BasicClientID: "basic-client", BasicClientSecret: basicSecret}, "basic-client"},
+		"basic with same form client_id": {
+			OauthClientAuthInput{BasicClientID: "basic-client", BasicClientSecret: basicSecret, ClientID: "basic-client"},
+			"basic-client",
+		},
+		"client_secret_post": {OauthClientAuthInput{ClientID: "post-client", ClientSecret: postSecret}, "post-client"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			client, err := svc.AuthenticateClient(tt.input)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if client.ClientID != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, client.ClientID)
+			}
+		})
+	}
+}
+
+func TestOauthAuthenticateClientFail(t *testing.T) {
+	basicClient, basicSecret := newTestConfidentialOauthClient("basic-client", models.OauthClientAuthMethodClientSecretBasic)
+	postClient, postSecret := newTestConfidentialOauthClient("post-client", models.OauthClientAuthMethodClientSecretPost)
+	svc := newTestOauthSvcWithClients(newTestOauthClient(), basicClient, postClient)
+
+	tests := map[string]struct {
+		input    OauthClientAuthInput
+		expected string
+	}{
+		"no credentials":  {OauthClientAuthInput{}, OauthErrorInvalidClient},
+		"unknown client":  {OauthClientAuthInput{ClientID: "unknown"}, OauthErrorInvalidClient},
+		"wrong secret":    {OauthClientAuthInput{BasicClientID: "basic-client", BasicClientSecret: "wrong"}, OauthErrorInvalidClient},
+		"missing secret":  {OauthClientAuthInput{ClientID: "post-client"}, OauthErrorInvalidClient},
+		"method mismatch": {OauthClientAuthInput{ClientID: "basic-client", ClientSecret: basicSecret}, OauthErrorInvalidClient},
+		// 公開クライアントに secret を送っても認証方式が一致しない
+		"secret for public client": {OauthClientAuthInput{BasicClientID: "test-client", BasicClientSecret: "secret"}, OauthErrorInvalidClient},
+		"client_id mismatch":       {OauthClientAuthInput{BasicClientID: "basic-client", BasicClientSecret: basicSecret, ClientID: "post-client"}, OauthErrorInvalidClient},
+		"multiple methods": {
+			OauthClientAuthInput{BasicClientID: "basic-client", BasicClientSecret: basicSecret, ClientID: "post-client", ClientSecret: postSecret},
+			OauthErrorInvalidRequest,
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			_, err := svc.AuthenticateClient(tt.input)
+			assertOauthError(t, err, tt.expected)
+		})
+	}
+
+	t.Run("client db error", func(t *testing.T) {
+		svc := newTestOauthSvcWithClients()
+		clientRepoMock := new(repo_mock.OauthClientRepoMock)
+		clientRepoMock.On("GetByClientID", "test-client").Return((*models.OauthClient)(nil), fmt.Errorf("db error"))
+		svc.oauthClientRepo = clientRepoMock
+
+		_, err := svc.AuthenticateClient(OauthClientAuthInput{ClientID: "test-client"})
+		var oauthErr *OauthError
+		if err == nil || errors.As(err, &oauthErr) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
+
+func TestOauthAuthenticateClientPrivateKeyJwt(t *testing.T) {
+	client, key := newTestPrivateKeyJwtClient(t)
+	svc := newTestOauthSvcWithClients(client)
+	claims := validClientAssertionClaims()
+	svc.oauthClientAssertionRepo.(*repo_mock.OauthClientAssertionRepoMock).
+		On("Register", "jwt-client", "assertion-id", time.Unix(claims["exp"].(int64), 0)).Return(nil)
+
+	// client_id を省略した場合はアサーションの sub でクライアントを特定する
+	authenticated, err := svc.AuthenticateClient(OauthClientAuthInput{
+		ClientAssertionType: OauthClientAssertionTypeJwtBearer,
+		ClientAssertion:     signTestClientAssertion(t, key, claims),
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if authenticated.ClientID != "jwt-client" {
+		t.Errorf("unexpected client: %+v", authenticated)
+	}
+
+	// aud は認可サーバーの issuer でもよい
+	claims["aud"] = "https://auth.example.com"
+	claims["jti"] = "issuer-assertion-id"
+	svc.oauthClientAssertionRepo.(*repo_mock.OauthClientAssertionRepoMock).
+		On("Register", "jwt-client", "issuer-assertion-id", mock.Anything).Return(nil)
+	if _, err := svc.AuthenticateClient(OauthClientAuthInput{
+		ClientID:            "jwt-client",
+		ClientAssertionType: OauthClientAssertionTypeJwtBearer,
+		ClientAssertion:     signTestClientAssertion(t, key, claims),
+	}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestOauthAuthenticateClientPrivateKeyJwtFail(t *testing.T) {
+	client, key := newTestPrivateKeyJwtClient(t)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := map[string]struct {
+		key    *ecdsa.PrivateKey
+		modify func(claims jwt.MapClaims)
+	}{
+		"other key":      {otherKey, func(claims jwt.MapClaims) {}},
+		"expired":        {key, func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		"missing exp":    {key, func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		"exp too far":    {key, func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(24 * time.Hour).Unix() }},
+		"iss mismatch":   {key, func(claims jwt.MapClaims) { claims["iss"] = "other" }},
+		"sub mismatch":   {key, func(claims jwt.MapClaims) { claims["sub"] = "other" }},
+		"aud mismatch":   {key, func(claims jwt.MapClaims) { claims["aud"] = "https://other.example.com/oauth/token" }},
+		"missing jti":    {key, func(claims jwt.MapClaims) { delete(claims, "jti") }},
+		"other endpoint": {key, func(claims jwt.MapClaims) { claims["aud"] = "https://auth.example.com/other" }},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			claims := validClientAssertionClaims()
+			tt.modify(claims)
+
+			svc := newTestOauthSvcWithClients(client)
+			_, err := svc.AuthenticateClient(OauthClientAuthInput{
+				ClientID:            "jwt-client",
+				ClientAssertionType: OauthClientAssertionTypeJwtBearer,
+				ClientAssertion:     signTestClientAssertion(t, tt.key, claims),
+			})
+			assertOauthError(t, err, OauthErrorInvalidClient)
+		})
+	}
+
+	t.Run("replayed", func(t *testing.T) {
+		svc := newTestOauthSvcWithClients(client)
+		svc.oauthClientAssertionRepo.(*repo_mock.OauthClientAssertionRepoMock).
+			On("Register", "jwt-client", "assertion-id", mock.Anything).Return(repositories.ErrOauthClientAssertionReplayed)
+
+		_, err := svc.AuthenticateClient(OauthClientAuthInput{
+			ClientAssertionType: OauthClientAssertionTypeJwtBearer,
+			ClientAssertion:     signTestClientAssertion(t, key, validClientAssertionClaims()),
+		})
+		assertOauthError(t, err, OauthErrorInvalidClient)
+	})
+
+	t.Run("unsupported assertion type", func(t *testing.T) {
+		_, err := newTestOauthSvcWithClients(client).AuthenticateClient(OauthClientAuthInput{
+			ClientAssertionType: "urn:example:other",
+			ClientAssertion:     signTestClientAssertion(t, key, validClientAssertionClaims()),
+		})
+		assertOauthError(t, err, OauthErrorInvalidRequest)
+	})
+
+	// 公開鍵を登録していない secret のクライアントは private_key_jwt で認証できない
+	t.Run("method mismatch", func(t *testing.T) {
+		basicClient, _ := newTestConfidentialOauthClient("jwt-client", models.OauthClientAuthMethodClientSecretBasic)
+		_, err := newTestOauthSvcWithClients(basicClient).AuthenticateClient(OauthClientAuthInput{
+			ClientAssertionType: OauthClientAssertionTypeJwtBearer,
+			ClientAssertion:     signTestClientAssertion(t, key, validClientAssertionClaims()),
+		})
+		assertOauthError(t, err, OauthErrorInvalidClient)
+	})
+}
+
+func newTestOauthDeviceClient() *models.OauthClient {
+	return &models.OauthClient{
+		ClientID:                "device-client",
+		Name:                    "TV App",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodNone,
+		GrantTypes:              "urn:ietf:params:oauth:grant-type:device_code refresh_token",
+		Scopes:                  "openid profile",
+	}
+}
+
+func newTestOauthDeviceSvc() *OauthSvcStruct {
+	return newTestOauthSvcWithClients(newTestOauthDeviceClient(), newTestOauthClient())
+}
+
+func newTestOauthDeviceCode() *models.OauthDeviceCode {
+	userID := uint(1)
+	return &models.OauthDeviceCode{
+		ID:           3,
+		ClientID:     "device-client",
+		Scope:        "openid",
+		Status:       models.OauthDeviceCodeStatusApproved,
+		UserID:       &userID,
+		Amr:          "pwd",
+		PollInterval: OauthDevicePollInterval,
+		ExpiresAt:    time.Now().Add(time.Minute),
+	}
+}
+
+func TestOauthAuthorizeDevice(t *testing.T) {
+	now := time.Now()
+	svc := newTestOauthDeviceSvc()
+	svc.clock = atylabclock.NewClockMock(now)
+	codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+	codeRepoMock.On("Create", mock.Anything).Return(nil)
+
+	output, err := svc.AuthorizeDevice(newTestOauthDeviceClient(), OauthDeviceAuthorizationInput{Scope: " openid  profile "})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if output.DeviceCode == "" || output.UserCode == "" || output.ExpiresIn != OauthDeviceCodeExpiresIn || output.Interval != OauthDevicePollInterval {
+		t.Errorf("unexpected output: %+v", output)
+	}
+	if output.VerificationURI != "https://auth.example.com/device" ||
+		output.VerificationURIComplete != "https://auth.example.com/device?user_code="+output.UserCode {
+		t.Errorf("unexpected verification uri: %+v", output)
+	}
+
+	// 平文のコードは保存しない
+	record := codeRepoMock.Calls[0].Arguments.Get(0).(*models.OauthDeviceCode)
+	if record.DeviceCodeHash != models.HashOauthDeviceCode(output.DeviceCode) || record.UserCodeHash != models.HashOauthUserCode(output.UserCode) {
+		t.Errorf("unexpected hashes: %+v", record)
+	}
+	if record.ClientID != "device-client" || record.Scope != "openid profile" || record.Status != models.OauthDeviceCodeStatusPending {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if record.PollInterval != OauthDevicePollInterval || !record.ExpiresAt.Equal(now.Add(OauthDeviceCodeExpiresIn*time.Second)) {
+		t.Errorf("unexpected record: %+v", record)
+	}
+}
+
+func TestOauthAuthorizeDeviceFail(t *testing.T) {
+	tests := map[string]struct {
+		client   *models.OauthClient
+		scope    string
+		expected string
+	}{
+		"grant not allowed": {newTestOauthClient(), "openid", OauthErrorUnauthorizedClient},
+		"scope not allowed": {newTestOauthDeviceClient(), "openid email", OauthErrorInvalidScope},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			_, err := newTestOauthDeviceSvc().AuthorizeDevice(tt.client, OauthDeviceAuthorizationInput{Scope: tt.scope})
+			assertOauthError(t, err, tt.expected)
+		})
+	}
+
+	t.Run("create error", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+		if _, err := svc.AuthorizeDevice(newTestOauthDeviceClient(), OauthDeviceAuthorizationInput{}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestOauthGetDeviceAuthorization(t *testing.T) {
+	svc := newTestOauthDeviceSvc()
+	code := newTestOauthDeviceCode()
+	code.Scope = "openid profile"
+	svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock).
+		On("GetPendingByUserCodeHash", models.HashOauthUserCode("WDJB-MJHT")).Return(code, nil)
+
+	// 入力されたコードは大文字・小文字とハイフンを区別しない
+	verification, err := svc.GetDeviceAuthorization("wdjbmjht")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if verification.ClientID != "device-client" || verification.ClientName != "TV App" ||
+		strings.Join(verification.Scopes, " ") != "openid profile" {
+		t.Errorf("unexpected verification: %+v", verification)
+	}
+}
+
+func TestOauthGetDeviceAuthorizationFail(t *testing.T) {
+	deletedClientCode := newTestOauthDeviceCode()
+	deletedClientCode.ClientID = "deleted-client"
+
+	tests := map[string]struct {
+		userCode string
+		code     *models.OauthDeviceCode
+		err      error
+		expected error
+	}{
+		"empty code":     {"-", nil, nil, ErrInvalidOauthUserCode},
+		"unknown code":   {"WDJB-MJHT", nil, repositories.ErrOauthDeviceCodeNotFound, ErrInvalidOauthUserCode},
+		"deleted client": {"WDJB-MJHT", deletedClientCode, nil, ErrInvalidOauthUserCode},
+		"db error":       {"WDJB-MJHT", nil, fmt.Errorf("db error"), nil},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthDeviceSvc()
+			svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock).
+				On("GetPendingByUserCodeHash", mock.Anything).Return(tt.code, tt.err)
+
+			_, err := svc.GetDeviceAuthorization(tt.userCode)
+			if err == nil || (tt.expected != nil && !errors.Is(err, tt.expected)) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestOauthDecideDeviceAuthorization(t *testing.T) {
+	authTime := time.Now().Add(-time.Minute)
+
+	tests := map[string]struct {
+		approve  bool
+		expected string
+	}{
+		"approve": {true, models.OauthDeviceCodeStatusApproved},
+		"deny":    {false, models.OauthDeviceCodeStatusDenied},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthDeviceSvc()
+			code := newTestOauthDeviceCode()
+			code.Status = models.OauthDeviceCodeStatusPending
+			code.UserID = nil
+			code.Amr = ""
+			codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+			codeRepoMock.On("GetPendingByUserCodeHash", models.HashOauthUserCode("WDJB-MJHT")).Return(code, nil)
+			codeRepoMock.On("Decide", code).Return(nil)
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+
+			err := svc.DecideDeviceAuthorization("test-uuid", AuthContext{AuthTime: authTime, Amr: []string{AmrPwd, AmrOtp}}, OauthDeviceDecisionInput{
+				UserCode: "WDJB-MJHT",
+				Approve:  tt.approve,
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if code.Status != tt.expected || code.UserID == nil || *code.UserID != 1 {
+				t.Errorf("unexpected code: %+v", code)
+			}
+			// 拒否した場合はトークンを発行しないため、認証時刻と方式は保存しない
+			if tt.approve != (code.Amr == "pwd otp" && code.AuthTime != nil && code.AuthTime.Equal(authTime)) {
+				t.Errorf("unexpected auth context: %+v", code)
+			}
+		})
+	}
+}
+
+func TestOauthDecideDeviceAuthorizationFail(t *testing.T) {
+	tests := map[string]struct {
+		userErr   error
+		decideErr error
+		expected  error
+	}{
+		"already decided": {nil, repositories.ErrOauthDeviceCodeNotFound, ErrInvalidOauthUserCode},
+		"decide db error": {nil, fmt.Errorf("db error"), nil},
+		"user not found":  {repositories.ErrUserNotFound, nil, repositories.ErrUserNotFound},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthDeviceSvc()
+			code := newTestOauthDeviceCode()
+			code.Status = models.OauthDeviceCodeStatusPending
+			codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+			codeRepoMock.On("GetPendingByUserCodeHash", mock.Anything).Return(code, nil)
+			codeRepoMock.On("Decide", mock.Anything).Return(tt.decideErr)
+			user := testOauthUser
+			if tt.userErr != nil {
+				user = nil
+			}
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(user, tt.userErr)
+
+			err := svc.DecideDeviceAuthorization("test-uuid", AuthContext{}, OauthDeviceDecisionInput{UserCode: "WDJB-MJHT", Approve: true})
+			if err == nil || (tt.expected != nil && !errors.Is(err, tt.expected)) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	t.Run("invalid user code", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock).
+			On("GetPendingByUserCodeHash", mock.Anything).Return((*models.OauthDeviceCode)(nil), repositories.ErrOauthDeviceCodeNotFound)
+
+		err := svc.DecideDeviceAuthorization("test-uuid", AuthContext{}, OauthDeviceDecisionInput{UserCode: "WDJB-MJHT", Approve: true})
+		if !errors.Is(err, ErrInvalidOauthUserCode) {
+			t.Fatalf("expected ErrInvalidOauthUserCode, got %v", err)
+		}
+	})
+}
+
+func TestOauthConsumeDeviceCode(t *testing.T) {
+	svc := newTestOauthDeviceSvc()
+	codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+	codeRepoMock.On("GetByDeviceCodeHash", models.HashOauthDeviceCode("device-code")).Return(newTestOauthDeviceCode(), nil)
+	codeRepoMock.On("Consume", uint(3)).Return(nil)
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testOauthUser, nil)
+
+	code, user, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if code.ID != 3 || user.UUID != "test-uuid" {
+		t.Errorf("unexpected result: %+v %+v", code, user)
+	}
+}
+
+func TestOauthConsumeDeviceCodePolling(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		lastPolledAt *time.Time
+		expected     string
+		interval     int
+	}{
+		"first poll": {nil, OauthErrorAuthorizationPending, OauthDevicePollInterval},
+		"after interval": {
+			func() *time.Time { v := now.Add(-OauthDevicePollInterval * time.Second); return &v }(),
+			OauthErrorAuthorizationPending, OauthDevicePollInterval,
+		},
+		// 間隔を守らない場合は以降の間隔を 5 秒延ばす（RFC 8628 3.5）
+		"too frequent": {
+			func() *time.Time { v := now.Add(-time.Second); return &v }(),
+			OauthErrorSlowDown, OauthDevicePollInterval + OauthDeviceSlowDownInterval,
+		},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthDeviceSvc()
+			svc.clock = atylabclock.NewClockMock(now)
+			code := newTestOauthDeviceCode()
+			code.Status = models.OauthDeviceCodeStatusPending
+			code.LastPolledAt = tt.lastPolledAt
+			codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+			codeRepoMock.On("GetByDeviceCodeHash", mock.Anything).Return(code, nil)
+			codeRepoMock.On("UpdatePolling", uint(3), now, tt.interval).Return(nil)
+
+			_, _, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+			assertOauthError(t, err, tt.expected)
+			codeRepoMock.AssertCalled(t, "UpdatePolling", uint(3), now, tt.interval)
+		})
+	}
+
+	t.Run("update error", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		code := newTestOauthDeviceCode()
+		code.Status = models.OauthDeviceCodeStatusPending
+		codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+		codeRepoMock.On("GetByDeviceCodeHash", mock.Anything).Return(code, nil)
+		codeRepoMock.On("UpdatePolling", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("db error"))
+
+		_, _, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+		var oauthErr *OauthError
+		if err == nil || errors.As(err, &oauthErr) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
+
+func TestOauthConsumeDeviceCodeFail(t *testing.T) {
+	tests := map[string]struct {
+		client     *models.OauthClient
+		deviceCode string
+		modify     func(code *models.OauthDeviceCode)
+		expected   string
+	}{
+		"grant not allowed": {newTestOauthClient(), "device-code", func(code *models.OauthDeviceCode) {}, OauthErrorUnauthorizedClient},
+		"missing code":      {newTestOauthDeviceClient(), "", func(code *models.OauthDeviceCode) {}, OauthErrorInvalidRequest},
+		"another client":    {newTestOauthDeviceClient(), "device-code", func(code *models.OauthDeviceCode) { code.ClientID = "test-client" }, OauthErrorInvalidGrant},
+		"already used":      {newTestOauthDeviceClient(), "device-code", func(code *models.OauthDeviceCode) { now := time.Now(); code.UsedAt = &now }, OauthErrorInvalidGrant},
+		"expired":           {newTestOauthDeviceClient(), "device-code", func(code *models.OauthDeviceCode) { code.ExpiresAt = time.Now().Add(-time.Second) }, OauthErrorExpiredToken},
+		"denied":            {newTestOauthDeviceClient(), "device-code", func(code *models.OauthDeviceCode) { code.Status = models.OauthDeviceCodeStatusDenied }, OauthErrorAccessDenied},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthDeviceSvc()
+			code := newTestOauthDeviceCode()
+			tt.modify(code)
+			svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock).On("GetByDeviceCodeHash", mock.Anything).Return(code, nil)
+
+			_, _, err := svc.ConsumeDeviceCode(tt.client, tt.deviceCode)
+			assertOauthError(t, err, tt.expected)
+		})
+	}
+
+	t.Run("unknown code", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock).
+			On("GetByDeviceCodeHash", mock.Anything).Return((*models.OauthDeviceCode)(nil), repositories.ErrOauthDeviceCodeNotFound)
+
+		_, _, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+
+	// 同時にポーリングされ、先にトークンを発行済みの場合
+	t.Run("consumed concurrently", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+		codeRepoMock.On("GetByDeviceCodeHash", mock.Anything).Return(newTestOauthDeviceCode(), nil)
+		codeRepoMock.On("Consume", uint(3)).Return(repositories.ErrOauthDeviceCodeNotFound)
+
+		_, _, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+
+	t.Run("user deleted", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+		codeRepoMock.On("GetByDeviceCodeHash", mock.Anything).Return(newTestOauthDeviceCode(), nil)
+		codeRepoMock.On("Consume", uint(3)).Return(nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+		_, _, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+		assertOauthError(t, err, OauthErrorInvalidGrant)
+	})
+
+	t.Run("get db error", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock).
+			On("GetByDeviceCodeHash", mock.Anything).Return((*models.OauthDeviceCode)(nil), fmt.Errorf("db error"))
+
+		_, _, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+		var oauthErr *OauthError
+		if err == nil || errors.As(err, &oauthErr) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+
+	t.Run("consume db error", func(t *testing.T) {
+		svc := newTestOauthDeviceSvc()
+		codeRepoMock := svc.oauthDeviceCodeRepo.(*repo_mock.OauthDeviceCodeRepoMock)
+		codeRepoMock.On("GetByDeviceCodeHash", mock.Anything).Return(newTestOauthDeviceCode(), nil)
+		codeRepoMock.On("Consume", uint(3)).Return(fmt.Errorf("db error"))
+
+		_, _, err := svc.ConsumeDeviceCode(newTestOauthDeviceClient(), "device-code")
+		var oauthErr *OauthError
+		if err == nil || errors.As(err, &oauthErr) {
+			t.Fatalf("expected db error, got %v", err)
+		}
+	})
+}
+
+var testOauthStaff = &models.User{
+	ID:    2,
+	UUID:  "staff-uuid",
+	Email: "staff@example.com",
+}
+
+func newTestOauthExchangeClient() *models.OauthClient {
+	return &models.OauthClient{
+		ClientID:                "exchange-client",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodClientSecretBasic,
+		GrantTypes:              "urn:ietf:params:oauth:grant-type:token-exchange client_credentials",
+		Scopes:                  "openid profile orders",
+		// ファーストパーティのログインで発行したトークンの交換と、test-client 向けのトークンの要求を許可する
+		TokenExchangeSubjectAudiences: "https://api.example.com",
+		TokenExchangeAudiences:        "test-client",
+	}
+}
+
+func newTestOauthExchangeSvc() *OauthSvcStruct {
+	svc := newTestOauthSvcWithClients(newTestOauthExchangeClient(), newTestOauthClient())
+	svc.config.Impersonators = []string{"staff-uuid"}
+	svc.userRepo.(*repo_mock.UserRepoMock).OnGetByUUID(testOauthUser, testOauthStaff)
+	return svc
+}
+
+func signTestExchangeToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(24 * time.Hour).Unix()
+	}
+	if _, ok := claims["iss"]; !ok {
+		claims["iss"] = "https://auth.example.com"
+	}
+	if _, ok := claims["aud"]; !ok {
+		claims["aud"] = "exchange-client"
+	}
+	token, err := jwttoken.NewJwtTokenPkg().Sign(claims, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func testUserAccessToken(t *testing.T) string {
+	return signTestExchangeToken(t, jwt.MapClaims{
+		"sub":       "usertest-uuid",
+		"principal": PrincipalUser,
+		"amr":       []string{"pwd", "mfa"},
+		"auth_time": time.Now().Add(-time.Minute).Unix(),
+		"sid":       "session-id",
+		"scope":     "openid profile orders",
+	})
+}
+
+func TestOauthValidateTokenExchangeDelegation(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		svc := newTestOauthExchangeSvc()
+		subjectExp := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+		subjectToken := signTestExchangeToken(t, jwt.MapClaims{
+			"sub":       "usertest-uuid",
+			"principal": PrincipalUser,
+			"amr":       []string{"pwd"},
+			"sid":       "session-id",
+			"client_id": "gateway-client",
+			"scope":     "openid profile admin",
+			"exp":       subjectExp.Unix(),
+			"act":       map[string]any{"sub": "gateway"},
+		})
+
+		grant, err := svc.ValidateTokenExchange(newTestOauthExchangeClient(), OauthTokenExchangeInput{
+			SubjectToken:     subjectToken,
+			SubjectTokenType: OauthTokenTypeAccessToken,
+			Audience:         []string{"test-client", "test-client"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if grant.Subject != testOauthUser || grant.Impersonation || grant.Actor != "exchange-client" {
+			t.Errorf("unexpected grant: %+v", grant)
+		}
+		// 交換前の act は入れ子にして残す
+		prior, _ := grant.Act["act"].(map[string]any)
+		if grant.Act["sub"] != "exchange-client" || prior["sub"] != "gateway" {
+			t.Errorf("unexpected act: %v", grant.Act)
+		}
+		if !slices.Equal(grant.Audience, []string{"test-client"}) {
+			t.Errorf("unexpected audience: %v", grant.Audience)
+		}
+		// クライアントに許可されていないスコープは引き継がない
+		if grant.Scope != "openid profile" {
+			t.Errorf("unexpected scope: %s", grant.Scope)
+		}
+		if !slices.Equal(grant.AuthContext.Amr, []string{"pwd"}) || grant.AuthContext.SessionID != "session-id" {
+			t.Errorf("unexpected auth context: %+v", grant.AuthContext)
+		}
+		if !grant.ExpiresAt.Equal(subjectExp) {
+			t.Errorf("expected subject token expiry, got %v", grant.ExpiresAt)
+		}
+	})
+}
+
+// scope クレームとクライアントのスコープの両方に含まれるものだけを引き継ぐ
+// ファーストパーティのトークンでも、scope クレームがなければクライアントのスコープで補わない
+func TestOauthValidateTokenExchangeSubjectScope(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		tests := map[string]struct {
+			claims   jwt.MapClaims
+			scope    string
+			expected string
+			err      string
+		}{
+			"first party":                        {jwt.MapClaims{"aud": "https://api.example.com", "scope": "openid admin"}, "", "openid", ""},
+			"first party without scope":          {jwt.MapClaims{"aud": "https://api.example.com"}, "", "", ""},
+			"first party without scope requests": {jwt.MapClaims{"aud": "https://api.example.com"}, "openid", "", OauthErrorInvalidScope},
+			"client issued without scope":        {jwt.MapClaims{"client_id": "gateway-client"}, "", "", ""},
+			"client issued without scope requests": {
+				jwt.MapClaims{"client_id": "gateway-client"}, "orders", "", OauthErrorInvalidScope,
+			},
+		}
+
+		for title, tt := range tests {
+			t.Run(title, func(t *testing.T) {
+				tt.claims["sub"] = "usertest-uuid"
+				tt.claims["principal"] = PrincipalUser
+
+				grant, err := newTestOauthExchangeSvc().ValidateTokenExchange(newTestOauthExchangeClient(), OauthTokenExchangeInput{
+					SubjectToken:     signTestExchangeToken(t, tt.claims),
+					SubjectTokenType: OauthTokenTypeAccessToken,
+					Scope:            tt.scope,
+				})
+				if tt.err != "" {
+					assertOauthError(t, err, tt.err)
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if grant.Scope != tt.expected {
+					t.Errorf("expected scope %q, got %q", tt.expected, grant.Scope)
+				}
+			})
+		}
+	})
+}
+
+func TestOauthValidateTokenExchangeActorToken(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		svc := newTestOauthExchangeSvc()
+		actorExp := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+		actorToken := signTestExchangeToken(t, jwt.MapClaims{
+			"sub":       "exchange-client",
+			"client_id": "exchange-client",
+			"principal": PrincipalClient,
+			"exp":       actorExp.Unix(),
+		})
+
+		grant, err := svc.ValidateTokenExchange(newTestOauthExchangeClient(), OauthTokenExchangeInput{
+			SubjectToken:     testUserAccessToken(t),
+			SubjectTokenType: OauthTokenTypeJwt,
+			ActorToken:       actorToken,
+			ActorTokenType:   OauthTokenTypeAccessToken,
+			Scope:            "orders",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if grant.Scope != "orders" || !slices.Equal(grant.Audience, []string{"exchange-client"}) {
+			t.Errorf("unexpected grant: %+v", grant)
+		}
+		// actor_token の方が先に切れる場合はそちらに合わせる
+		if !grant.ExpiresAt.Equal(actorExp) {
+			t.Errorf("expected actor token expiry, got %v", grant.ExpiresAt)
+		}
+	})
+}
+
+func TestOauthValidateTokenExchangeImpersonation(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		svc := newTestOauthExchangeSvc()
+		client := newTestOauthExchangeClient()
+		client.FirstParty = true
+
+		grant, err := svc.ValidateTokenExchange(client, OauthTokenExchangeInput{
+			RequestedSubject: "test-uuid",
+			ActorToken: signTestExchangeToken(t, jwt.MapClaims{
+				"sub":       "userstaff-uuid",
+				"principal": PrincipalUser,
+				"amr":       []string{"pwd", "mfa"},
+				"auth_time": time.Now().Unix(),
+			}),
+			ActorTokenType: OauthTokenTypeAccessToken,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if grant.Subject != testOauthUser || !grant.Impersonation || grant.Actor != "userstaff-uuid" || grant.Act["sub"] != "userstaff-uuid" {
+			t.Errorf("unexpected grant: %+v", grant)
+		}
+		// スタッフの認証時刻と方式は引き継がない
+		if !grant.AuthContext.AuthTime.IsZero() || len(grant.AuthContext.Amr) != 0 {
+			t.Errorf("expected empty auth context, got %+v", grant.AuthContext)
+		}
+		if grant.Scope != "openid profile orders" {
+			t.Errorf("unexpected scope: %s", grant.Scope)
+		}
+	})
+}
+
+func TestOauthValidateTokenExchangeFail(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		firstParty := newTestOauthExchangeClient()
+		firstParty.FirstParty = true
+		publicClient := newTestOauthExchangeClient()
+		publicClient.TokenEndpointAuthMethod = models.OauthClientAuthMethodNone
+
+		userToken := testUserAccessToken(t)
+		staffToken := signTestExchangeToken(t, jwt.MapClaims{"sub": "userstaff-uuid", "principal": PrincipalUser})
+
+		tests := map[string]struct {
+			client   *models.OauthClient
+			input    OauthTokenExchangeInput
+			expected string
+		}{
+			"grant not allowed": {newTestOauthClient(), OauthTokenExchangeInput{SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken}, OauthErrorUnauthorizedClient},
+			"public client":     {publicClient, OauthTokenExchangeInput{SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken}, OauthErrorUnauthorizedClient},
+			"unsupported requested_token_type": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken, RequestedTokenType: "urn:ietf:params:oauth:token-type:refresh_token",
+			}, OauthErrorInvalidRequest},
+			"actor_token_type without actor_token": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken, ActorTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"missing subject_token":      {newTestOauthExchangeClient(), OauthTokenExchangeInput{SubjectTokenType: OauthTokenTypeAccessToken}, OauthErrorInvalidRequest},
+			"missing subject_token_type": {newTestOauthExchangeClient(), OauthTokenExchangeInput{SubjectToken: userToken}, OauthErrorInvalidRequest},
+			"unsupported subject_token_type": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: userToken, SubjectTokenType: "urn:ietf:params:oauth:token-type:id_token",
+			}, OauthErrorInvalidRequest},
+			"invalid subject_token": {newTestOauthExchangeClient(), OauthTokenExchangeInput{SubjectToken: "invalid", SubjectTokenType: OauthTokenTypeAccessToken}, OauthErrorInvalidRequest},
+			"mfa token": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: signTestExchangeToken(t, jwt.MapClaims{"sub": "usertest-uuid", "typ": "mfa"}), SubjectTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"client token as subject": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: signTestExchangeToken(t, jwt.MapClaims{"sub": "exchange-client", "principal": PrincipalClient}), SubjectTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"deleted user": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: signTestExchangeToken(t, jwt.MapClaims{"sub": "userdeleted-uuid", "principal": PrincipalUser}), SubjectTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"actor_token of another client": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken,
+				ActorToken:     signTestExchangeToken(t, jwt.MapClaims{"sub": "test-client", "client_id": "test-client", "principal": PrincipalClient}),
+				ActorTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"user token as actor": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken, ActorToken: staffToken, ActorTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"audience not allowed for the client": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken, Audience: []string{"gateway-client"},
+			}, OauthErrorInvalidTarget},
+			"subject_token for another audience": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: signTestExchangeToken(t, jwt.MapClaims{"aud": "test-client", "sub": "usertest-uuid", "principal": PrincipalUser}), SubjectTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"subject_token without audience": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: signTestExchangeToken(t, jwt.MapClaims{"aud": []string{}, "sub": "usertest-uuid", "principal": PrincipalUser}), SubjectTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"impersonation actor_token for another audience": {firstParty, OauthTokenExchangeInput{
+				RequestedSubject: "test-uuid", ActorTokenType: OauthTokenTypeAccessToken,
+				ActorToken: signTestExchangeToken(t, jwt.MapClaims{"aud": "test-client", "sub": "userstaff-uuid", "principal": PrincipalUser}),
+			}, OauthErrorInvalidRequest},
+			"other issuer": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: signTestExchangeToken(t, jwt.MapClaims{"iss": "https://other.example.com", "sub": "usertest-uuid", "principal": PrincipalUser}), SubjectTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"scope exceeds subject token": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken: signTestExchangeToken(t, jwt.MapClaims{"sub": "usertest-uuid", "principal": PrincipalUser, "client_id": "gateway-client", "scope": "profile"}), SubjectTokenType: OauthTokenTypeAccessToken,
+				Scope: "profile orders",
+			}, OauthErrorInvalidScope},
+			"impersonation from third party": {newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				RequestedSubject: "test-uuid", ActorToken: staffToken, ActorTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorUnauthorizedClient},
+			"impersonation with subject_token": {firstParty, OauthTokenExchangeInput{
+				RequestedSubject: "test-uuid", SubjectToken: userToken, SubjectTokenType: OauthTokenTypeAccessToken, ActorToken: staffToken, ActorTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"impersonation without actor_token": {firstParty, OauthTokenExchangeInput{RequestedSubject: "test-uuid"}, OauthErrorInvalidRequest},
+			"actor is not an impersonator": {firstParty, OauthTokenExchangeInput{
+				RequestedSubject: "test-uuid", ActorToken: userToken, ActorTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+			"unknown requested_subject": {firstParty, OauthTokenExchangeInput{
+				RequestedSubject: "unknown-uuid", ActorToken: staffToken, ActorTokenType: OauthTokenTypeAccessToken,
+			}, OauthErrorInvalidRequest},
+		}
+
+		for title, tt := range tests {
+			t.Run(title, func(t *testing.T) {
+				_, err := newTestOauthExchangeSvc().ValidateTokenExchange(tt.client, tt.input)
+				assertOauthError(t, err, tt.expected)
+			})
+		}
+
+		t.Run("db error", func(t *testing.T) {
+			svc := newTestOauthSvcWithClients(newTestOauthExchangeClient())
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return((*models.User)(nil), fmt.Errorf("db error"))
+
+			_, err := svc.ValidateTokenExchange(newTestOauthExchangeClient(), OauthTokenExchangeInput{
+				SubjectToken:     userToken,
+				SubjectTokenType: OauthTokenTypeAccessToken,
+			})
+			var oauthErr *OauthError
+			if err == nil || errors.As(err, &oauthErr) {
+				t.Fatalf("expected db error, got %v", err)
+			}
+		})
+	})
+}
+
+func TestOauthRecordTokenExchange(t *testing.T) {
+	svc := newTestOauthSvc()
+	exchange := &models.OauthTokenExchange{Jti: "jti", ClientID: "exchange-client", SubjectUserID: 1}
+	svc.oauthTokenExchangeRepo.(*repo_mock.OauthTokenExchangeRepoMock).On("Create", exchange).Return(nil)
+
+	if err := svc.RecordTokenExchange(exchange); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func newTestOauthThirdPartyClient() *models.OauthClient {
+	return &models.OauthClient{
+		ClientID:                "third-party-client",
+		Name:                    "Third Party App",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodNone,
+		RedirectURIs:            testOauthRedirectURI,
+		GrantTypes:              "authorization_code refresh_token",
+		Scopes:                  "openid profile email",
+	}
+}
+
+func newTestOauthConsentSvc() *OauthSvcStruct {
+	svc := newTestOauthSvcWithClients(newTestOauthThirdPartyClient())
+	svc.userRepo.(*repo_mock.UserRepoMock).OnGetByUUID(testOauthUser)
+	svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).On("Create", mock.Anything).Return(nil).Maybe()
+	return svc
+}
+
+func validOauthConsentInput() OauthAuthorizeInput {
+	input := validOauthAuthorizeInput()
+	input.ClientID = "third-party-client"
+	return input
+}
+
+// サードパーティのクライアントに同意画面の URL を発行させ、チャレンジトークンを取り出す
+func createTestConsentChallenge(t *testing.T, svc *OauthSvcStruct, authContext AuthContext) string {
+	t.Helper()
+	consentRepoMock := svc.userConsentRepo.(*repo_mock.UserConsentRepoMock)
+	consentRepoMock.On("Get", uint(1), "third-party-client").Return((*models.UserConsent)(nil), repositories.ErrUserConsentNotFound).Once()
+
+	redirectTo, err := svc.Authorize("test-uuid", authContext, validOauthConsentInput())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	u, _ := url.Parse(redirectTo)
+	if u.Scheme+"://"+u.Host+u.Path != "https://auth.example.com/oauth/consent" {
+		t.Fatalf("expected consent url, got %s", redirectTo)
+	}
+	return u.Query().Get("consent_challenge")
+}
+
+func TestOauthAuthorizeRequiresConsent(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		svc := newTestOauthConsentSvc()
+		challenge := createTestConsentChallenge(t, svc, AuthContext{})
+		if challenge == "" {
+			t.Fatal("expected consent challenge")
+		}
+		// 同意するまで認可コードは発行しない
+		svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).AssertNotCalled(t, "Create", mock.Anything)
+
+		// 要求したスコープの一部しか許可していない場合も同意画面を表示する
+		svc.userConsentRepo.(*repo_mock.UserConsentRepoMock).On("Get", uint(1), "third-party-client").
+			Return(&models.UserConsent{Scope: "openid"}, nil).Once()
+		redirectTo, err := svc.Authorize("test-uuid", AuthContext{}, validOauthConsentInput())
+		if err != nil || !strings.HasPrefix(redirectTo, "https://auth.example.com/oauth/consent?") {
+			t.Fatalf("expected consent url, got %s, %v", redirectTo, err)
+		}
+	})
+}
+
+func TestOauthAuthorizeWithConsent(t *testing.T) {
+	svc := newTestOauthConsentSvc()
+	svc.userConsentRepo.(*repo_mock.UserConsentRepoMock).On("Get", uint(1), "third-party-client").
+		Return(&models.UserConsent{Scope: "openid profile email"}, nil)
+
+	redirectTo, err := svc.Authorize("test-uuid", AuthContext{}, validOauthConsentInput())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	u, _ := url.Parse(redirectTo)
+	if u.Scheme+"://"+u.Host+u.Path != testOauthRedirectURI || u.Query().Get("code") == "" {
+		t.Errorf("unexpected redirect: %s", redirectTo)
+	}
+}
+
+func TestOauthAuthorizeConsentDbError(t *testing.T) {
+	svc := newTestOauthConsentSvc()
+	svc.userConsentRepo.(*repo_mock.UserConsentRepoMock).On("Get", uint(1), "third-party-client").
+		Return((*models.UserConsent)(nil), fmt.Errorf("db error"))
+
+	if _, err := svc.Authorize("test-uuid", AuthContext{}, validOauthConsentInput()); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestOauthGetConsent(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		svc := newTestOauthConsentSvc()
+		challenge := createTestConsentChallenge(t, svc, AuthContext{})
+
+		consent, err := svc.GetConsent(challenge)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if consent.ClientID != "third-party-client" || consent.ClientName != "Third Party App" ||
+			!slices.Equal(consent.Scopes, []string{"openid", "profile"}) {
+			t.Errorf("unexpected consent: %+v", consent)
+		}
+	})
+}
+
+func TestOauthDecideConsent(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+		svc := newTestOauthConsentSvc()
+		challenge := createTestConsentChallenge(t, svc, AuthContext{AuthTime: authTime, Amr: []string{AmrPwd}})
+
+		// 許可済みのスコープに追加する
+		consentRepoMock := svc.userConsentRepo.(*repo_mock.UserConsentRepoMock)
+		consentRepoMock.On("Get", uint(1), "third-party-client").Return(&models.UserConsent{ID: 5, UserID: 1, ClientID: "third-party-client", Scope: "email"}, nil)
+		consentRepoMock.On("Save", mock.MatchedBy(func(consent *models.UserConsent) bool {
+			return consent.ID == 5 && consent.Scope == "email openid profile"
+		})).Return(nil)
+
+		redirectTo, err := svc.DecideConsent(challenge, true)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		u, _ := url.Parse(redirectTo)
+		if u.Scheme+"://"+u.Host+u.Path != testOauthRedirectURI || u.Query().Get("code") == "" || u.Query().Get("state") != "xyz" {
+			t.Fatalf("unexpected redirect: %s", redirectTo)
+		}
+
+		// ログインの認証時刻と方式を認可コードに引き継ぐ
+		record := svc.oauthAuthorizationCodeRepo.(*repo_mock.OauthAuthorizationCodeRepoMock).Calls[0].Arguments.Get(0).(*models.OauthAuthorizationCode)
+		if record.ClientID != "third-party-client" || record.Amr != "pwd" || !record.AuthTime.Equal(authTime) ||
+			record.CodeChallenge != testOauthCodeChallenge || record.Nonce != "n-0S6_WzA2Mj" {
+			t.Errorf("unexpected record: %+v", record)
+		}
+	})
+}
+
+func TestOauthDecideConsentFirstTime(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		svc := newTestOauthConsentSvc()
+		challenge := createTestConsentChallenge(t, svc, AuthContext{})
+
+		consentRepoMock := svc.userConsentRepo.(*repo_mock.UserConsentRepoMock)
+		consentRepoMock.On("Get", uint(1), "third-party-client").Return((*models.UserConsent)(nil), repositories.ErrUserConsentNotFound)
+		consentRepoMock.On("Save", mock.MatchedBy(func(consent *models.UserConsent) bool {
+			return consent.UserID == 1 && consent.ClientID == "third-party-client" && consent.Scope == "openid profile"
+		})).Return(nil)
+
+		if _, err := svc.DecideConsent(challenge, true); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+}
+
+func TestOauthDecideConsentDeny(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		svc := newTestOauthConsentSvc()
+		challenge := createTestConsentChallenge(t, svc, AuthContext{})
+
+		redirectTo, err := svc.DecideConsent(challenge, false)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		u, _ := url.Parse(redirectTo)
+		if u.Query().Get("error") != OauthErrorAccessDenied || u.Query().Get("state") != "xyz" || u.Query().Has("code") {
+			t.Errorf("unexpected redirect: %s", redirectTo)
+		}
+		svc.userConsentRepo.(*repo_mock.UserConsentRepoMock).AssertNotCalled(t, "Save", mock.Anything)
+	})
+}
+
+func TestOauthDecideConsentFail(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", "test-secret", t, func() {
+		sign := func(claims jwt.MapClaims) string {
+			claims["exp"] = time.Now().Add(time.Minute).Unix()
+			token, _ := jwttoken.NewJwtTokenPkg().Sign(claims, []byte("test-secret"))
+			return token
+		}
+		validClaims := func() jwt.MapClaims {
+			claims := jwt.MapClaims{"sub": "test-uuid", "typ": OauthConsentChallengeType}
+			for key, value := range validOauthConsentInput().values() {
+				claims[key] = value
+			}
+			return claims
+		}
+
+		tests := map[string]string{
+			"invalid token": "invalid",
+			"mfa token":     sign(jwt.MapClaims{"sub": "test-uuid", "typ": MfaTokenType}),
+			"missing sub": sign(func() jwt.MapClaims {
+				claims := validClaims()
+				delete(claims, "sub")
+				return claims
+			}()),
+			// 発行後にクライアントが削除された場合
+			"unknown client": sign(func() jwt.MapClaims {
+				claims := validClaims()
+				claims["client_id"] = "deleted-client"
+				return claims
+			}()),
+			"deleted user": sign(func() jwt.MapClaims {
+				claims := validClaims()
+				claims["sub"] = "deleted-uuid"
+				return claims
+			}()),
+		}
+
+		for title, challenge := range tests {
+			t.Run(title, func(t *testing.T) {
+				svc := newTestOauthSvcWithClients(newTestOauthThirdPartyClient())
+				svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "deleted-uuid").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+				if _, err := svc.DecideConsent(challenge, true); !errors.Is(err, ErrInvalidOauthConsentChallenge) {
+					t.Fatalf("expected ErrInvalidOauthConsentChallenge, got %v", err)
+				}
+				if _, err := svc.GetConsent(challenge); !errors.Is(err, ErrInvalidOauthConsentChallenge) {
+					t.Fatalf("expected ErrInvalidOauthConsentChallenge, got %v", err)
+				}
+			})
+		}
+
+		t.Run("save error", func(t *testing.T) {
+			svc := newTestOauthConsentSvc()
+			consentRepoMock := svc.userConsentRepo.(*repo_mock.UserConsentRepoMock)
+			consentRepoMock.On("Get", uint(1), "third-party-client").Return((*models.UserConsent)(nil), repositories.ErrUserConsentNotFound)
+			consentRepoMock.On("Save", mock.Anything).Return(fmt.Errorf("db error"))
+
+			_, err := svc.DecideConsent(sign(validClaims()), true)
+			if err == nil || errors.Is(err, ErrInvalidOauthConsentChallenge) {
+				t.Fatalf("expected db error, got %v", err)
+			}
+		})
+	})
+}
+
+func TestOauthListConsents(t *testing.T) {
+	grantedAt := time.Now()
+	svc := newTestOauthSvcWithClients(newTestOauthThirdPartyClient())
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+	svc.userConsentRepo.(*repo_mock.UserConsentRepoMock).On("ListByUserID", uint(1)).Return([]models.UserConsent{
+		{ClientID: "third-party-client", Scope: "openid profile", UpdatedAt: grantedAt},
+	}, nil)
+
+	consents, err := svc.ListConsents("test-uuid")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(consents) != 1 || consents[0].ClientID != "third-party-client" || consents[0].ClientName != "Third Party App" ||
+		!slices.Equal(consents[0].Scopes, []string{"openid", "profile"}) || !consents[0].GrantedAt.Equal(grantedAt) {
+		t.Errorf("unexpected consents: %+v", consents)
+	}
+}
+
+func TestOauthListConsentsFail(t *testing.T) {
+	t.Run("list error", func(t *testing.T) {
+		svc := newTestOauthSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+		svc.userConsentRepo.(*repo_mock.UserConsentRepoMock).On("ListByUserID", uint(1)).Return([]models.UserConsent(nil), fmt.Errorf("db error"))
+
+		if _, err := svc.ListConsents("test-uuid"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+
+	t.Run("user error", func(t *testing.T) {
+		svc := newTestOauthSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+		if _, err := svc.ListConsents("test-uuid"); !errors.Is(err, repositories.ErrUserNotFound) {
+			t.Fatalf("expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
+
+func TestOauthRevokeConsent(t *testing.T) {
+	tests := map[string]struct {
+		repoErr  error
+		expected error
+	}{
+		"success":   {nil, nil},
+		"not found": {repositories.ErrUserConsentNotFound, ErrOauthConsentNotFound},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOauthSvc()
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testOauthUser, nil)
+			svc.userConsentRepo.(*repo_mock.UserConsentRepoMock).On("Delete", uint(1), "third-party-client").Return(tt.repoErr)
+
+			if err := svc.RevokeConsent("test-uuid", "third-party-client"); !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OpenID Connect のスコープ（OpenID Connect Core 5.4）
+const (
+	OidcScopeOpenID  = "openid"
+	OidcScopeProfile = "profile"
+	OidcScopeEmail   = "email"
+)
+
+// ID トークンの有効期間（秒）
+const OidcIDTokenExpiresIn = 3600
+
+var (
+	// openid スコープを許可されていないアクセストークン（RFC 6750 3.1 insufficient_scope）
+	ErrInsufficientScope = errors.New("insufficient scope")
+	// アクセストークンのユーザーが削除されている
+	ErrOidcUserNotFound = errors.New("oidc user not found")
+)
+
+type OidcSvcInterface interface {
+	Discovery() OidcDiscovery
+	Jwks() OidcJwks
+	CreateIDToken(user *models.User, client *models.OauthClient, authContext AuthContext, accessToken string) (string, error)
+	UserInfo(userUUID string, scope string) (*OidcUserInfo, error)
+}
+
+type OidcSvcStruct struct {
+	config   OauthConfig
+	userRepo repositories.UserRepoInterface
+	jwttoken jwttoken.JwtTokenPkgInterface
+	clock    atylabclock.ClockInterface
+}
+
+func NewOidcSvc(
+	config OauthConfig,
+	userRepo repositories.UserRepoInterface,
+	jwttoken jwttoken.JwtTokenPkgInterface,
+	clock atylabclock.ClockInterface,
+) *OidcSvcStruct {
+	return &OidcSvcStruct{
+		config:   config,
+		userRepo: userRepo,
+		jwttoken: jwttoken,
+		clock:    clock,
+	}
+}
+
+// /.well-known/openid-configuration（OpenID Connect Discovery 1.0 3）
+type OidcDiscovery struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint"`
+	UserinfoEndpoint                           string   `json:"userinfo_endpoint"`
+	JwksURI                                    string   `json:"jwks_uri"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	ResponseModesSupported                     []string `json:"response_modes_supported"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                            []string `json:"claims_supported"`
+}
+
+type OidcJwks struct {
+	Keys []jwttoken.JWK `json:"keys"`
+}
+
+// スコープで許可された項目のみを返す（OpenID Connect Core 5.3.2）
+type OidcUserInfo struct {
+	Sub               string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Locale            string `json:"locale,omitempty"`
+	UpdatedAt         int64  `json:"updated_at,omitempty"`
+	Email             string `json:"email,omitempty"`
+}
+
+func (s *OidcSvcStruct) Discovery() OidcDiscovery {
+	return OidcDiscovery{
+		Issuer:                           s.config.Issuer,
+		AuthorizationEndpoint:            s.config.AuthorizationEndpoint(),
+		TokenEndpoint:                    s.config.TokenEndpoint(),
+		DeviceAuthorizationEndpoint:      s.config.DeviceAuthorizationEndpoint(),
+		UserinfoEndpoint:                 s.config.UserinfoEndpoint(),
+		JwksURI:                          s.config.JwksURI(),
+		ScopesSupported:                  []string{OidcScopeOpenID, OidcScopeProfile, OidcScopeEmail},
+		ResponseTypesSupported:           []string{OauthResponseTypeCode},
+		ResponseModesSupported:           []string{"query"},
+		GrantTypesSupported:              OauthSupportedGrantTypes,
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{jwt.SigningMethodRS256.Alg()},
+		TokenEndpointAuthMethodsSupported: []string{
+			models.OauthClientAuthMethodNone,
+			models.OauthClientAuthMethodClientSecretBasic,
+			models.OauthClientAuthMethodClientSecretPost,
+			models.OauthClientAuthMethodPrivateKeyJwt,
+		},
+		TokenEndpointAuthSigningAlgValuesSupported: jwttoken.PublicKeyMethods,
+		CodeChallengeMethodsSupported:              []string{models.OauthCodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "amr", "acr", "sid",
+			"preferred_username", "locale", "updated_at", "email",
+		},
+	}
+}
+
+func (s *OidcSvcStruct) Jwks() OidcJwks {
+	return OidcJwks{Keys: []jwttoken.JWK{jwttoken.NewRSAPublicJWK(&s.config.SigningKey.PublicKey)}}
+}
+
+// 認可コード・リフレッシュトークンと引き換えに発行する ID トークン（OpenID Connect Core 2）
+// sub はアクセストークン・UserInfo と同じ値にする
+func (s *OidcSvcStruct) CreateIDToken(user *models.User, client *models.OauthClient, authContext AuthContext, accessToken string) (string, error) {
+	now := s.clock.Now()
+	claims := jwt.MapClaims{
+		"iss":     s.config.Issuer,
+		"sub":     "user" + user.UUID,
+		"aud":     client.ClientID,
+		"iat":     now.Unix(),
+		"exp":     now.Add(OidcIDTokenExpiresIn * time.Second).Unix(),
+		"amr":     authContext.Amr,
+		"acr":     authContext.Acr(),
+		"sid":     authContext.SessionID,
+		"at_hash": oidcHalfHash(accessToken),
+	}
+	if !authContext.AuthTime.IsZero() {
+		claims["auth_time"] = authContext.AuthTime.Unix()
+	}
+	if authContext.Nonce != "" {
+		claims["nonce"] = authContext.Nonce
+	}
+
+	key := s.config.SigningKey
+	return s.jwttoken.SignRS256(claims, key, jwttoken.NewRSAPublicJWK(&key.PublicKey).Kid)
+}
+
+// scope はアクセストークンの scope クレーム
+func (s *OidcSvcStruct) UserInfo(userUUID string, scope string) (*OidcUserInfo, error) {
+	scopes := strings.Fields(scope)
+	if !slices.Contains(scopes, OidcScopeOpenID) {
+		return nil, ErrInsufficientScope
+	}
+
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrOidcUserNotFound
+		}
+		return nil, err
+	}
+
+	info := &OidcUserInfo{Sub: "user" + user.UUID}
+	if slices.Contains(scopes, OidcScopeProfile) {
+		info.PreferredUsername = user.Username
+		info.Locale = user.Locale
+		info.UpdatedAt = user.UpdatedAt.Unix()
+	}
+	if slices.Contains(scopes, OidcScopeEmail) {
+		info.Email = user.Email
+	}
+	return info, nil
+}
+
+// at_hash: アクセストークンの SHA-256 の左半分の base64url（OpenID Connect Core 3.3.2.11）
+func oidcHalfHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/lib_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+var testOidcSigningKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+func newTestOidcSvc() *OidcSvcStruct {
+	return NewOidcSvc(
+		newTestOauthConfig(),
+		new(repo_mock.UserRepoMock),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClockMock(time.Now()),
+	)
+}
+
+// ID トークンを JWKS の公開鍵で検証する
+func parseTestIDToken(t *testing.T, idToken string) (jwt.MapClaims, map[string]any) {
+	t.Helper()
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, func(t *jwt.Token) (interface{}, error) {
+		return &testOidcSigningKey.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil {
+		t.Fatalf("failed to verify id token: %v", err)
+	}
+	return claims, token.Header
+}
+
+func TestOidcDiscovery(t *testing.T) {
+	discovery := newTestOidcSvc().Discovery()
+
+	if discovery.Issuer != "https://auth.example.com" ||
+		discovery.AuthorizationEndpoint != "https://auth.example.com/oauth/authorize" ||
+		discovery.TokenEndpoint != "https://auth.example.com/oauth/token" ||
+		discovery.DeviceAuthorizationEndpoint != "https://auth.example.com/oauth/device_authorization" ||
+		discovery.UserinfoEndpoint != "https://auth.example.com/userinfo" ||
+		discovery.JwksURI != "https://auth.example.com/.well-known/jwks.json" {
+		t.Errorf("unexpected endpoints: %+v", discovery)
+	}
+	// OpenID Connect Discovery 3 で必須の項目
+	if len(discovery.ResponseTypesSupported) == 0 || len(discovery.SubjectTypesSupported) == 0 ||
+		len(discovery.IDTokenSigningAlgValuesSupported) == 0 || discovery.IDTokenSigningAlgValuesSupported[0] != "RS256" {
+		t.Errorf("missing required metadata: %+v", discovery)
+	}
+}
+
+func TestOidcJwks(t *testing.T) {
+	jwks := newTestOidcSvc().Jwks()
+
+	if len(jwks.Keys) != 1 || jwks.Keys[0] != jwttoken.NewRSAPublicJWK(&testOidcSigningKey.PublicKey) {
+		t.Errorf("unexpected jwks: %+v", jwks)
+	}
+}
+
+func TestOidcCreateIDToken(t *testing.T) {
+	now := time.Now()
+	authTime := now.Add(-time.Minute)
+	svc := newTestOidcSvc()
+	svc.clock = atylabclock.NewClockMock(now)
+
+	idToken, err := svc.CreateIDToken(testOauthUser, newTestOauthClient(), AuthContext{
+		AuthTime:  authTime,
+		Amr:       []string{AmrPwd},
+		SessionID: "family-id",
+		Nonce:     "n-0S6_WzA2Mj",
+	}, "access-token")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	claims, header := parseTestIDToken(t, idToken)
+	if header["kid"] != svc.Jwks().Keys[0].Kid {
+		t.Errorf("unexpected kid: %v", header["kid"])
+	}
+	for key, expected := range map[string]any{
+		"iss":       "https://auth.example.com",
+		"sub":       "usertest-uuid",
+		"aud":       "test-client",
+		"iat":       float64(now.Unix()),
+		"exp":       float64(now.Add(OidcIDTokenExpiresIn * time.Second).Unix()),
+		"auth_time": float64(authTime.Unix()),
+		"nonce":     "n-0S6_WzA2Mj",
+		"acr":       AcrAal1,
+		"sid":       "family-id",
+		// base64url(SHA-256("access-token") の左 128 ビット)
+		"at_hash": oidcHalfHash("access-token"),
+	} {
+		if claims[key] != expected {
+			t.Errorf("%s: expected %v, got %v", key, expected, claims[key])
+		}
+	}
+	if len(oidcHalfHash("access-token")) != 22 {
+		t.Errorf("unexpected at_hash length: %s", oidcHalfHash("access-token"))
+	}
+}
+
+func TestOidcCreateIDTokenWithoutNonce(t *testing.T) {
+	idToken, err := newTestOidcSvc().CreateIDToken(testOauthUser, newTestOauthClient(), AuthContext{}, "access-token")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// リフレッシュ時は nonce を含めず、認証時刻が不明な場合は auth_time も含めない
+	claims, _ := parseTestIDToken(t, idToken)
+	if _, ok := claims["nonce"]; ok {
+		t.Error("expected no nonce")
+	}
+	if _, ok := claims["auth_time"]; ok {
+		t.Error("expected no auth_time")
+	}
+}
+
+func TestOidcCreateIDTokenFail(t *testing.T) {
+	jwtTokenMock := new(lib_mock.JwtTokenPkgMock)
+	jwtTokenMock.On("SignRS256", mock.Anything, testOidcSigningKey, mock.Anything).Return("", fmt.Errorf("sign error"))
+	svc := newTestOidcSvc()
+	svc.jwttoken = jwtTokenMock
+
+	if _, err := svc.CreateIDToken(testOauthUser, newTestOauthClient(), AuthContext{}, "access-token"); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func TestOidcUserInfo(t *testing.T) {
+	updatedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := &models.User{UUID: "test-uuid", Username: "test-user", Email: "user@example.com", Locale: "ja", UpdatedAt: updatedAt}
+
+	tests := map[string]struct {
+		scope    string
+		expected OidcUserInfo
+	}{
+		"openid only": {"openid", OidcUserInfo{Sub: "usertest-uuid"}},
+		"profile":     {"openid profile", OidcUserInfo{Sub: "usertest-uuid", PreferredUsername: "test-user", Locale: "ja", UpdatedAt: updatedAt.Unix()}},
+		"email":       {"openid email", OidcUserInfo{Sub: "usertest-uuid", Email: "user@example.com"}},
+		"all": {"openid profile email", OidcUserInfo{
+			Sub: "usertest-uuid", PreferredUsername: "test-user", Locale: "ja", UpdatedAt: updatedAt.Unix(), Email: "user@example.com",
+		}},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOidcSvc()
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(user, nil)
+
+			info, err := svc.UserInfo("test-uuid", tt.scope)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if *info != tt.expected {
+				t.Errorf("unexpected userinfo: %+v", info)
+			}
+		})
+	}
+}
+
+func TestOidcUserInfoFail(t *testing.T) {
+	tests := map[string]struct {
+		scope    string
+		err      error
+		expected error
+	}{
+		// 自サービスのログインで発行したトークン
+		"without scope":  {"", nil, ErrInsufficientScope},
+		"without openid": {"profile email", nil, ErrInsufficientScope},
+		"deleted user":   {"openid", repositories.ErrUserNotFound, ErrOidcUserNotFound},
+		"db error":       {"openid", fmt.Errorf("db error"), nil},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			svc := newTestOidcSvc()
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return((*models.User)(nil), tt.err)
+
+			_, err := svc.UserInfo("test-uuid", tt.scope)
+			if err == nil || (tt.expected != nil && !errors.Is(err, tt.expected)) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	PasswordViolationTooShort         = "password_too_short"
+	PasswordViolationTooLong          = "password_too_long"
+	PasswordViolationTooManyBytes     = "password_too_many_bytes"
+	PasswordViolationContainsEmail    = "password_contains_email"
+	PasswordViolationContainsUsername = "password_contains_username"
+	PasswordViolationTooWeak          = "password_too_weak"
+	PasswordViolationBreached         = "password_breached"
+)
+
+// bcrypt が扱える最大バイト数
+const passwordMaxBytes = 72
+
+type PasswordPolicySvcInterface interface {
+	Validate(input PasswordPolicyInput) error
+}
+
+type PasswordPolicyConfig struct {
+	MinLength   int
+	MaxLength   int
+	MinStrength int
+}
+
+func NewPasswordPolicyConfigFromEnv() PasswordPolicyConfig {
+	return PasswordPolicyConfig{
+		MinLength:   envInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:   envInt("PASSWORD_MAX_LENGTH", 64),
+		MinStrength: envInt("PASSWORD_MIN_STRENGTH", 2),
+	}
+}
+
+type PasswordPolicySvcStruct struct {
+	config       PasswordPolicyConfig
+	breachedRepo repositories.BreachedPasswordRepoInterface
+}
+
+func NewPasswordPolicySvc(
+	config PasswordPolicyConfig,
+	breachedRepo repositories.BreachedPasswordRepoInterface,
+) *PasswordPolicySvcStruct {
+	return &PasswordPolicySvcStruct{
+		config:       config,
+		breachedRepo: breachedRepo,
+	}
+}
+
+type PasswordPolicyInput struct {
+	Password string
+	Email    string
+	Username string
+}
+
+type PasswordPolicyViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// メッセージの翻訳に使う値（最小・最大の文字数）
+	Param string `json:"param,omitempty"`
+}
+
+type PasswordPolicyError struct {
+	Violations []PasswordPolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	codes := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		codes = append(codes, v.Code)
+	}
+	return fmt.Sprintf("password policy violation: %s", strings.Join(codes, ", "))
+}
+
+// 登録・変更・リセットで同じ表現になるよう、パスワードは NFKC で正規化してから扱う
+func NormalizePassword(password string) string {
+	return norm.NFKC.String(password)
+}
+
+func (s *PasswordPolicySvcStruct) Validate(input PasswordPolicyInput) error {
+	password := NormalizePassword(input.Password)
+	violations := []PasswordPolicyViolation{}
+
+	length := len([]rune(password))
+	if length < s.config.MinLength {
+		violations = append(violations, PasswordPolicyViolation{
+			Code:    PasswordViolationTooShort,
+			Message: fmt.Sprintf("password must be at least %d characters", s.config.MinLength),
+			Param:   strconv.Itoa(s.config.MinLength),
+		})
+	}
+	if length > s.config.MaxLength {
+		violations = append(violations, PasswordPolicyViolation{
+			Code:    PasswordViolationTooLong,
+			Message: fmt.Sprintf("password must be at most %d characters", s.config.MaxLength),
+			Param:   strconv.Itoa(s.config.MaxLength),
+		})
+	} else if len(password) > passwordMaxBytes {
+		// 文字数の上限内でもマルチバイト文字では bcrypt の上限を超えることがあるため、バイト数として伝える
+		violations = append(violations, PasswordPolicyViolation{
+			Code:    PasswordViolationTooManyBytes,
+			Message: fmt.Sprintf("password must be at most %d bytes", passwordMaxBytes),
+			Param:   strconv.Itoa(passwordMaxBytes),
+		})
+	}
+
+	lower := strings.ToLower(password)
+	if s.containsEmail(lower, input.Email) {
+		violations = append(violations, PasswordPolicyViolation{
+			Code:    PasswordViolationContainsEmail,
+			Message: "password must not contain the email address",
+		})
+	}
+	if username := strings.ToLower(NormalizePassword(strings.TrimSpace(input.Username))); len([]rune(username)) >= 3 && strings.Contains(lower, username) {
+		violations = append(violations, PasswordPolicyViolation{
+			Code:    PasswordViolationContainsUsername,
+			Message: "password must not contain the username",
+		})
+	}
+
+	if EstimatePasswordStrength(password) < s.config.MinStrength {
+		violations = append(violations, PasswordPolicyViolation{
+			Code:    PasswordViolationTooWeak,
+			Message: "password is too weak",
+		})
+	}
+
+	breached, err := s.breachedRepo.IsBreached(password)
+	if err != nil {
+		return fmt.Errorf("failed to check breached password: %w", err)
+	}
+	if breached {
+		violations = append(violations, PasswordPolicyViolation{
+			Code:    PasswordViolationBreached,
+			Message: "password has appeared in a data breach",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (s *PasswordPolicySvcStruct) containsEmail(password string, email string) bool {
+	email = strings.TrimSpace(strings.ToLower(email))
+	if email == "" {
+		return false
+	}
+	if strings.Contains(password, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return len([]rune(local)) >= 3 && strings.Contains(password, local)
+}
+
+// パスワード強度を 0〜4 のスコアで推定する
+// 文字種から求めた 1 文字あたりのエントロピーを積み上げ、直前と同じ文字や連番は 1bit として扱う
+func EstimatePasswordStrength(password string) int {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < unicode.MaxASCII:
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+
+	pool := 0
+	if hasLower {
+		pool += 26
+	}
+	if hasUpper {
+		pool += 26
+	}
+	if hasDigit {
+		pool += 10
+	}
+	if hasSymbol {
+		pool += 33
+	}
+	if hasOther {
+		pool += 100
+	}
+	perChar := math.Log2(float64(pool))
+
+	bits := perChar
+	for i := 1; i < len(runes); i++ {
+		diff := runes[i] - runes[i-1]
+		if diff >= -1 && diff <= 1 {
+			bits += 1
+			continue
+		}
+		bits += perChar
+	}
+
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 80:
+		return 3
+	default:
+		return 4
+	}
+}
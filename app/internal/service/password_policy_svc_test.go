@@ -0,0 +1,208 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestPasswordPolicySvc(breached bool) *PasswordPolicySvcStruct {
+	breachedRepoMock := new(repo_mock.BreachedPasswordRepoMock)
+	breachedRepoMock.On("IsBreached", mock.Anything).Return(breached, nil)
+
+	return NewPasswordPolicySvc(PasswordPolicyConfig{
+		MinLength:   8,
+		MaxLength:   20,
+		MinStrength: 2,
+	}, breachedRepoMock)
+}
+
+func TestPasswordPolicyValidateSuccess(t *testing.T) {
+	svc := newTestPasswordPolicySvc(false)
+
+	err := svc.Validate(PasswordPolicyInput{
+		Password: "Tr0ub4dor&3",
+		Email:    "user@example.com",
+		Username: "Test User",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestPasswordPolicyValidateViolations(t *testing.T) {
+	tests := []struct {
+		title    string
+		password string
+		breached bool
+		expected string
+	}{
+		{"too short", "Ab1!", false, PasswordViolationTooShort},
+		{"too long", strings.Repeat("Ab1!x", 5), false, PasswordViolationTooLong},
+		{"too many bytes", strings.Repeat("😀", 19), false, PasswordViolationTooManyBytes},
+		{"contains email", "xUser@Example.com1", false, PasswordViolationContainsEmail},
+		{"contains email local part", "myuser-pass!", false, PasswordViolationContainsEmail},
+		{"contains username", "hanako-secret", false, PasswordViolationContainsUsername},
+		{"too weak", "aaaaaaaaaa", false, PasswordViolationTooWeak},
+		{"breached", "Tr0ub4dor&3", true, PasswordViolationBreached},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			svc := newTestPasswordPolicySvc(tt.breached)
+
+			err := svc.Validate(PasswordPolicyInput{
+				Password: tt.password,
+				Email:    "user@example.com",
+				Username: "Hanako",
+			})
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("expected password policy error, got %v", err)
+			}
+
+			found := false
+			for _, v := range policyErr.Violations {
+				if v.Code == tt.expected {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected violation %v, got %v", tt.expected, policyErr.Violations)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyValidateMultibytePassword(t *testing.T) {
+	breachedRepoMock := new(repo_mock.BreachedPasswordRepoMock)
+	breachedRepoMock.On("IsBreached", mock.Anything).Return(false, nil)
+	svc := NewPasswordPolicySvc(PasswordPolicyConfig{MinLength: 8, MaxLength: 64}, breachedRepoMock)
+
+	// 30 文字（90 バイト）は文字数の上限内だが、bcrypt の 72 バイトを超える
+	err := svc.Validate(PasswordPolicyInput{Password: strings.Repeat("あいうえおか", 5)})
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) || len(policyErr.Violations) != 1 {
+		t.Fatalf("expected a single violation, got %v", err)
+	}
+	violation := policyErr.Violations[0]
+	if violation.Code != PasswordViolationTooManyBytes || violation.Param != "72" || !strings.Contains(violation.Message, "72 bytes") {
+		t.Errorf("unexpected violation: %+v", violation)
+	}
+
+	// 24 文字（72 バイト）までは受け付ける
+	if err := svc.Validate(PasswordPolicyInput{Password: strings.Repeat("あいうえおか", 4)}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestPasswordPolicyValidateNormalizesPassword(t *testing.T) {
+	breachedRepoMock := new(repo_mock.BreachedPasswordRepoMock)
+	breachedRepoMock.On("IsBreached", "Password123").Return(true, nil)
+
+	svc := NewPasswordPolicySvc(PasswordPolicyConfig{
+		MinLength:   8,
+		MaxLength:   64,
+		MinStrength: 0,
+	}, breachedRepoMock)
+
+	// 全角英数字は NFKC で半角に正規化される
+	err := svc.Validate(PasswordPolicyInput{
+		Password: "Ｐａｓｓｗｏｒｄ１２３",
+	})
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected password policy error, got %v", err)
+	}
+	if policyErr.Violations[0].Code != PasswordViolationBreached {
+		t.Errorf("expected %v, got %v", PasswordViolationBreached, policyErr.Violations[0].Code)
+	}
+	breachedRepoMock.AssertExpectations(t)
+}
+
+func TestPasswordPolicyValidateBreachedRepoError(t *testing.T) {
+	breachedRepoMock := new(repo_mock.BreachedPasswordRepoMock)
+	breachedRepoMock.On("IsBreached", mock.Anything).Return(false, fmt.Errorf("read error"))
+
+	svc := NewPasswordPolicySvc(PasswordPolicyConfig{
+		MinLength:   8,
+		MaxLength:   64,
+		MinStrength: 2,
+	}, breachedRepoMock)
+
+	err := svc.Validate(PasswordPolicyInput{
+		Password: "Tr0ub4dor&3",
+	})
+	if err == nil {
+		t.Fatal("expected error, got none")
+	}
+
+	var policyErr *PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		t.Error("expected non policy error")
+	}
+}
+
+func TestPasswordPolicyErrorMessage(t *testing.T) {
+	err := &PasswordPolicyError{
+		Violations: []PasswordPolicyViolation{
+			{Code: PasswordViolationTooShort},
+			{Code: PasswordViolationTooWeak},
+		},
+	}
+
+	expected := "password policy violation: password_too_short, password_too_weak"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestEstimatePasswordStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		expected int
+	}{
+		{"", 0},
+		{"aaaaaaaa", 0},
+		{"12345678", 0},
+		{"abcdefgh", 0},
+		{"password", 1},
+		{"password123", 2},
+		{"securepassword", 3},
+		{"Tr0ub4dor&3xyzW", 4},
+	}
+
+	for _, tt := range tests {
+		if got := EstimatePasswordStrength(tt.password); got != tt.expected {
+			t.Errorf("EstimatePasswordStrength(%q): expected %d, got %d", tt.password, tt.expected, got)
+		}
+	}
+}
+
+func TestNewPasswordPolicyConfigFromEnv(t *testing.T) {
+	funcs.WithEnvMap(funcs.Envs{
+		"PASSWORD_MIN_LENGTH":   "10",
+		"PASSWORD_MAX_LENGTH":   "",
+		"PASSWORD_MIN_STRENGTH": "invalid",
+	}, t, func() {
+		config := NewPasswordPolicyConfigFromEnv()
+
+		if config.MinLength != 10 {
+			t.Errorf("expected MinLength 10, got %d", config.MinLength)
+		}
+		if config.MaxLength != 64 {
+			t.Errorf("expected MaxLength 64, got %d", config.MaxLength)
+		}
+		if config.MinStrength != 2 {
+			t.Errorf("expected MinStrength 2, got %d", config.MinStrength)
+		}
+	})
+}
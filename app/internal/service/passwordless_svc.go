@@ -0,0 +1,260 @@
+package service
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/mailer"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// マジックリンクトークンの typ クレーム
+	PasswordlessTokenType = "passwordless"
+	// マジックリンク・ワンタイムコードの有効期限（秒）
+	PasswordlessExpiresIn = 600
+	// ワンタイムコードの試行回数の上限
+	PasswordlessMaxAttempts = 5
+	// 同じユーザーへの再送を受け付けない間隔（秒）
+	PasswordlessResendInterval = 60
+)
+
+var (
+	ErrInvalidPasswordlessToken = errors.New("invalid passwordless token")
+	ErrInvalidPasswordlessCode  = errors.New("invalid passwordless code")
+)
+
+type PasswordlessSvcInterface interface {
+	Start(input PasswordlessStartInput) error
+	Verify(input PasswordlessCompleteInput) (*models.User, error)
+}
+
+type PasswordlessSvcStruct struct {
+	userRepo              repositories.UserRepoInterface
+	passwordlessTokenRepo repositories.PasswordlessTokenRepoInterface
+	mailer                mailer.MailerPkgInterface
+	jwttoken              jwttoken.JwtTokenPkgInterface
+	clock                 atylabclock.ClockInterface
+}
+
+func NewPasswordlessSvc(
+	userRepo repositories.UserRepoInterface,
+	passwordlessTokenRepo repositories.PasswordlessTokenRepoInterface,
+	mailer mailer.MailerPkgInterface,
+	jwttoken jwttoken.JwtTokenPkgInterface,
+	clock atylabclock.ClockInterface,
+) *PasswordlessSvcStruct {
+	return &PasswordlessSvcStruct{
+		userRepo:              userRepo,
+		passwordlessTokenRepo: passwordlessTokenRepo,
+		mailer:                mailer,
+		jwttoken:              jwttoken,
+		clock:                 clock,
+	}
+}
+
+type PasswordlessStartInput struct {
+	Email  string
+	Method string
+	// ユーザーが言語を設定していない場合のメールの言語（Accept-Language から決めたもの）
+	Locale string
+}
+
+type PasswordlessCompleteInput struct {
+	Email string
+	Code  string
+	Token string
+}
+
+// メールアドレスの登録有無を推測されないよう、未登録や再送間隔内でもエラーにしない
+func (s *PasswordlessSvcStruct) Start(input PasswordlessStartInput) error {
+	email := strings.TrimSpace(strings.ToLower(input.Email))
+
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	now := s.clock.Now()
+	latest, err := s.passwordlessTokenRepo.GetLatestByUserID(user.ID)
+	if err != nil && !errors.Is(err, repositories.ErrPasswordlessTokenNotFound) {
+		return err
+	}
+	if latest != nil && latest.IsActive(now) && now.Sub(latest.CreatedAt) < PasswordlessResendInterval*time.Second {
+		return nil
+	}
+
+	// 有効なリンク・コードは常に最新の 1 件のみ
+	if err := s.passwordlessTokenRepo.InvalidateByUserID(user.ID); err != nil {
+		return err
+	}
+
+	locale := user.Locale
+	if locale == "" {
+		locale = i18n.Normalize(input.Locale)
+	}
+
+	var message mailer.Message
+	switch input.Method {
+	case models.PasswordlessMethodLink:
+		message, err = s.createLink(user, locale, now)
+	case models.PasswordlessMethodCode:
+		message, err = s.createCode(user, locale, now)
+	default:
+		return fmt.Errorf("unsupported passwordless method: %s", input.Method)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(message)
+}
+
+func (s *PasswordlessSvcStruct) createLink(user *models.User, locale string, now time.Time) (mailer.Message, error) {
+	nonce := models.CreatePasswordlessNonce()
+	if err := s.passwordlessTokenRepo.Create(&models.PasswordlessToken{
+		UserID:    user.ID,
+		Method:    models.PasswordlessMethodLink,
+		TokenHash: models.HashPasswordlessNonce(nonce),
+		ExpiresAt: now.Add(PasswordlessExpiresIn * time.Second),
+	}); err != nil {
+		return mailer.Message{}, err
+	}
+
+	token, err := s.jwttoken.Sign(jwt.MapClaims{
+		"sub": user.UUID,
+		"typ": PasswordlessTokenType,
+		"jti": nonce,
+		"iat": now.Unix(),
+		"exp": now.Add(PasswordlessExpiresIn * time.Second).Unix(),
+	}, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	return mailer.NewTemplateMessage(user.Email, locale, "passwordless_link", map[string]any{
+		"Link":             passwordlessLinkURL() + "?token=" + url.QueryEscape(token),
+		"ExpiresInMinutes": PasswordlessExpiresIn / 60,
+	})
+}
+
+func (s *PasswordlessSvcStruct) createCode(user *models.User, locale string, now time.Time) (mailer.Message, error) {
+	code := models.CreatePasswordlessCode()
+	if err := s.passwordlessTokenRepo.Create(&models.PasswordlessToken{
+		UserID:    user.ID,
+		Method:    models.PasswordlessMethodCode,
+		TokenHash: models.HashPasswordlessCode(user.ID, code, []byte(os.Getenv("JWT_SECRET_KEY"))),
+		ExpiresAt: now.Add(PasswordlessExpiresIn * time.Second),
+	}); err != nil {
+		return mailer.Message{}, err
+	}
+
+	return mailer.NewTemplateMessage(user.Email, locale, "passwordless_code", map[string]any{
+		"Code":             code,
+		"ExpiresInMinutes": PasswordlessExpiresIn / 60,
+	})
+}
+
+// マジックリンクのトークン、またはメールアドレスとワンタイムコードを検証し、ユーザーを返す
+func (s *PasswordlessSvcStruct) Verify(input PasswordlessCompleteInput) (*models.User, error) {
+	if input.Token != "" {
+		return s.verifyLink(input.Token)
+	}
+	return s.verifyCode(input.Email, input.Code)
+}
+
+func (s *PasswordlessSvcStruct) verifyLink(token string) (*models.User, error) {
+	claims, err := s.jwttoken.Parse(token, []byte(os.Getenv("JWT_SECRET_KEY")))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPasswordlessToken, err)
+	}
+	if typ, _ := claims["typ"].(string); typ != PasswordlessTokenType {
+		return nil, ErrInvalidPasswordlessToken
+	}
+	sub, _ := claims["sub"].(string)
+	nonce, _ := claims["jti"].(string)
+	if sub == "" || nonce == "" {
+		return nil, ErrInvalidPasswordlessToken
+	}
+
+	// 署名が正しくても、使用済み・無効化済みのリンクは受け付けない
+	passwordlessToken, err := s.passwordlessTokenRepo.ConsumeByTokenHash(models.HashPasswordlessNonce(nonce), models.PasswordlessMethodLink)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPasswordlessTokenNotFound) {
+			return nil, ErrInvalidPasswordlessToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(passwordlessToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.UUID != sub {
+		return nil, ErrInvalidPasswordlessToken
+	}
+	return user, nil
+}
+
+func (s *PasswordlessSvcStruct) verifyCode(email string, code string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(strings.TrimSpace(strings.ToLower(email)))
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrInvalidPasswordlessCode
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	passwordlessToken, err := s.passwordlessTokenRepo.GetLatestByUserID(user.ID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrPasswordlessTokenNotFound) {
+			return nil, ErrInvalidPasswordlessCode
+		}
+		return nil, err
+	}
+	if passwordlessToken.Method != models.PasswordlessMethodCode || !passwordlessToken.IsActive(s.clock.Now()) {
+		return nil, ErrInvalidPasswordlessCode
+	}
+
+	// 照合前に試行回数を加算し、上限を超えたコードは正しくても受け付けない
+	if err := s.passwordlessTokenRepo.RegisterAttempt(passwordlessToken.ID, PasswordlessMaxAttempts); err != nil {
+		if errors.Is(err, repositories.ErrPasswordlessTokenNotFound) {
+			return nil, ErrInvalidPasswordlessCode
+		}
+		return nil, err
+	}
+
+	hash := models.HashPasswordlessCode(user.ID, strings.TrimSpace(code), []byte(os.Getenv("JWT_SECRET_KEY")))
+	if !hmac.Equal([]byte(hash), []byte(passwordlessToken.TokenHash)) {
+		return nil, ErrInvalidPasswordlessCode
+	}
+
+	if err := s.passwordlessTokenRepo.Consume(passwordlessToken.ID); err != nil {
+		if errors.Is(err, repositories.ErrPasswordlessTokenNotFound) {
+			return nil, ErrInvalidPasswordlessCode
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// メール内のリンク先（フロントエンドの画面）。トークンをクエリで受け取り complete を呼ぶ想定
+func passwordlessLinkURL() string {
+	if link := os.Getenv("PASSWORDLESS_LINK_URL"); link != "" {
+		return link
+	}
+	return "http://localhost:8080/passwordless/complete"
+}
@@ -0,0 +1,499 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/jwttoken"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/mailer"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/lib_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+const testPasswordlessKey = "testsecretkey"
+
+var testPasswordlessUser = &models.User{
+	ID:    1,
+	UUID:  "test-uuid",
+	Email: "user@example.com",
+}
+
+func newTestPasswordlessSvc() *PasswordlessSvcStruct {
+	return NewPasswordlessSvc(
+		new(repo_mock.UserRepoMock),
+		new(repo_mock.PasswordlessTokenRepoMock),
+		new(lib_mock.MailerPkgMock),
+		jwttoken.NewJwtTokenPkg(),
+		atylabclock.NewClockMock(time.Now()),
+	)
+}
+
+func TestPasswordlessStartCode(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+		svc := newTestPasswordlessSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(testPasswordlessUser, nil)
+		tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+		tokenRepoMock.On("GetLatestByUserID", uint(1)).Return((*models.PasswordlessToken)(nil), repositories.ErrPasswordlessTokenNotFound)
+		tokenRepoMock.On("InvalidateByUserID", uint(1)).Return(nil)
+		tokenRepoMock.On("Create", mock.Anything).Return(nil)
+		mailerMock := svc.mailer.(*lib_mock.MailerPkgMock)
+		mailerMock.On("Send", mock.Anything).Return(nil)
+
+		err := svc.Start(PasswordlessStartInput{Email: " User@Example.com ", Method: "code"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		message := mailerMock.Calls[0].Arguments.Get(0).(mailer.Message)
+		if message.To != "user@example.com" {
+			t.Errorf("unexpected recipient: %s", message.To)
+		}
+		code := regexp.MustCompile(`[0-9]{6}`).FindString(message.Body)
+		if code == "" {
+			t.Fatalf("expected code in body: %s", message.Body)
+		}
+
+		// コードの平文は保存しない
+		created := tokenRepoMock.Calls[2].Arguments.Get(0).(*models.PasswordlessToken)
+		if created.Method != "code" || created.UserID != 1 {
+			t.Errorf("unexpected token: %+v", created)
+		}
+		if created.TokenHash != models.HashPasswordlessCode(1, code, []byte(testPasswordlessKey)) {
+			t.Error("expected hashed code to be stored")
+		}
+		if created.ExpiresAt.Sub(svc.clock.Now()) != PasswordlessExpiresIn*time.Second {
+			t.Errorf("unexpected expires at: %v", created.ExpiresAt)
+		}
+	})
+}
+
+func TestPasswordlessStartLink(t *testing.T) {
+	funcs.WithEnvMap(funcs.Envs{
+		"JWT_SECRET_KEY":        testPasswordlessKey,
+		"PASSWORDLESS_LINK_URL": "https://example.com/login/link",
+	}, t, func() {
+		svc := newTestPasswordlessSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(testPasswordlessUser, nil)
+		tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+		tokenRepoMock.On("GetLatestByUserID", uint(1)).Return((*models.PasswordlessToken)(nil), repositories.ErrPasswordlessTokenNotFound)
+		tokenRepoMock.On("InvalidateByUserID", uint(1)).Return(nil)
+		tokenRepoMock.On("Create", mock.Anything).Return(nil)
+		mailerMock := svc.mailer.(*lib_mock.MailerPkgMock)
+		mailerMock.On("Send", mock.Anything).Return(nil)
+
+		err := svc.Start(PasswordlessStartInput{Email: "user@example.com", Method: "link"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		message := mailerMock.Calls[0].Arguments.Get(0).(mailer.Message)
+		link := regexp.MustCompile(`https://example\.com/login/link\?token=\S+`).FindString(message.Body)
+		if link == "" {
+			t.Fatalf("expected link in body: %s", message.Body)
+		}
+		parsed, _ := url.Parse(link)
+		claims, err := jwttoken.NewJwtTokenPkg().Parse(parsed.Query().Get("token"), []byte(testPasswordlessKey))
+		if err != nil {
+			t.Fatalf("expected signed token, got %v", err)
+		}
+		if claims["typ"] != PasswordlessTokenType || claims["sub"] != "test-uuid" {
+			t.Errorf("unexpected claims: %v", claims)
+		}
+
+		created := tokenRepoMock.Calls[2].Arguments.Get(0).(*models.PasswordlessToken)
+		if created.Method != "link" || created.TokenHash != models.HashPasswordlessNonce(claims["jti"].(string)) {
+			t.Errorf("unexpected token: %+v", created)
+		}
+	})
+}
+
+func TestPasswordlessStartLocale(t *testing.T) {
+	tests := map[string]struct {
+		userLocale    string
+		requestLocale string
+		expected      string
+		subject       string
+	}{
+		"request locale":         {"", "ja", "ja", "ログイン用コードのお知らせ"},
+		"user preference":        {"ja", "en", "ja", "ログイン用コードのお知らせ"},
+		"default":                {"", "", "en", "Your sign-in code"},
+		"unsupported to default": {"", "fr", "en", "Your sign-in code"},
+	}
+
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+				user := *testPasswordlessUser
+				user.Locale = tt.userLocale
+
+				svc := newTestPasswordlessSvc()
+				svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(&user, nil)
+				tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+				tokenRepoMock.On("GetLatestByUserID", uint(1)).Return((*models.PasswordlessToken)(nil), repositories.ErrPasswordlessTokenNotFound)
+				tokenRepoMock.On("InvalidateByUserID", uint(1)).Return(nil)
+				tokenRepoMock.On("Create", mock.Anything).Return(nil)
+				mailerMock := svc.mailer.(*lib_mock.MailerPkgMock)
+				mailerMock.On("Send", mock.Anything).Return(nil)
+
+				if err := svc.Start(PasswordlessStartInput{Email: "user@example.com", Method: "code", Locale: tt.requestLocale}); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+
+				message := mailerMock.Calls[0].Arguments.Get(0).(mailer.Message)
+				if message.Language != tt.expected || message.Subject != tt.subject {
+					t.Errorf("unexpected message: %+v", message)
+				}
+			})
+		})
+	}
+}
+
+func TestPasswordlessStartSkip(t *testing.T) {
+	t.Run("unknown email", func(t *testing.T) {
+		svc := newTestPasswordlessSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "unknown@example.com").Return((*models.User)(nil), repositories.ErrUserNotFound)
+
+		if err := svc.Start(PasswordlessStartInput{Email: "unknown@example.com", Method: "code"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		svc.mailer.(*lib_mock.MailerPkgMock).AssertNotCalled(t, "Send", mock.Anything)
+	})
+
+	t.Run("resend interval", func(t *testing.T) {
+		svc := newTestPasswordlessSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(testPasswordlessUser, nil)
+		tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+		tokenRepoMock.On("GetLatestByUserID", uint(1)).Return(&models.PasswordlessToken{
+			CreatedAt: svc.clock.Now().Add(-30 * time.Second),
+			ExpiresAt: svc.clock.Now().Add(time.Minute),
+		}, nil)
+
+		if err := svc.Start(PasswordlessStartInput{Email: "user@example.com", Method: "code"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		tokenRepoMock.AssertNotCalled(t, "Create", mock.Anything)
+		svc.mailer.(*lib_mock.MailerPkgMock).AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
+
+func TestPasswordlessStartAfterResendInterval(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+		svc := newTestPasswordlessSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(testPasswordlessUser, nil)
+		tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+		tokenRepoMock.On("GetLatestByUserID", uint(1)).Return(&models.PasswordlessToken{
+			CreatedAt: svc.clock.Now().Add(-2 * time.Minute),
+			ExpiresAt: svc.clock.Now().Add(time.Minute),
+		}, nil)
+		tokenRepoMock.On("InvalidateByUserID", uint(1)).Return(nil)
+		tokenRepoMock.On("Create", mock.Anything).Return(nil)
+		svc.mailer.(*lib_mock.MailerPkgMock).On("Send", mock.Anything).Return(nil)
+
+		if err := svc.Start(PasswordlessStartInput{Email: "user@example.com", Method: "code"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		tokenRepoMock.AssertCalled(t, "InvalidateByUserID", uint(1))
+	})
+}
+
+func TestPasswordlessStartFail(t *testing.T) {
+	tests := []struct {
+		title   string
+		method  string
+		prepare func(svc *PasswordlessSvcStruct)
+	}{
+		{
+			title: "user repo error",
+			prepare: func(svc *PasswordlessSvcStruct) {
+				svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", mock.Anything).Return((*models.User)(nil), fmt.Errorf("db error"))
+			},
+		},
+		{
+			title: "latest token error",
+			prepare: func(svc *PasswordlessSvcStruct) {
+				svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock).
+					On("GetLatestByUserID", uint(1)).Return((*models.PasswordlessToken)(nil), fmt.Errorf("db error"))
+			},
+		},
+		{
+			title: "invalidate error",
+			prepare: func(svc *PasswordlessSvcStruct) {
+				svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock).On("InvalidateByUserID", uint(1)).Return(fmt.Errorf("db error"))
+			},
+		},
+		{
+			title:  "unsupported method",
+			method: "sms",
+		},
+		{
+			title: "create error",
+			prepare: func(svc *PasswordlessSvcStruct) {
+				svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+			},
+		},
+		{
+			title:  "create link error",
+			method: "link",
+			prepare: func(svc *PasswordlessSvcStruct) {
+				svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+			},
+		},
+		{
+			title: "mail error",
+			prepare: func(svc *PasswordlessSvcStruct) {
+				svc.mailer.(*lib_mock.MailerPkgMock).On("Send", mock.Anything).Return(fmt.Errorf("smtp error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			svc := newTestPasswordlessSvc()
+			if tt.prepare != nil {
+				tt.prepare(svc)
+			}
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", mock.Anything).Return(testPasswordlessUser, nil)
+			tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+			tokenRepoMock.On("GetLatestByUserID", uint(1)).Return((*models.PasswordlessToken)(nil), repositories.ErrPasswordlessTokenNotFound)
+			tokenRepoMock.On("InvalidateByUserID", uint(1)).Return(nil)
+			tokenRepoMock.On("Create", mock.Anything).Return(nil)
+			svc.mailer.(*lib_mock.MailerPkgMock).On("Send", mock.Anything).Return(nil)
+
+			method := tt.method
+			if method == "" {
+				method = "code"
+			}
+			if err := svc.Start(PasswordlessStartInput{Email: "user@example.com", Method: method}); err == nil {
+				t.Fatal("expected error, got none")
+			}
+		})
+	}
+}
+
+func newTestPasswordlessLinkToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwttoken.NewJwtTokenPkg().Sign(claims, []byte(testPasswordlessKey))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func validPasswordlessLinkClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "test-uuid",
+		"typ": PasswordlessTokenType,
+		"jti": "nonce",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func TestPasswordlessVerifyLink(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+		svc := newTestPasswordlessSvc()
+		svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock).
+			On("ConsumeByTokenHash", models.HashPasswordlessNonce("nonce"), "link").
+			Return(&models.PasswordlessToken{ID: 1, UserID: 1}, nil)
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testPasswordlessUser, nil)
+
+		user, err := svc.Verify(PasswordlessCompleteInput{Token: newTestPasswordlessLinkToken(t, validPasswordlessLinkClaims())})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user != testPasswordlessUser {
+			t.Errorf("expected user %v, got %v", testPasswordlessUser, user)
+		}
+	})
+}
+
+func TestPasswordlessVerifyLinkFail(t *testing.T) {
+	tests := []struct {
+		title    string
+		token    func(t *testing.T) string
+		consume  error
+		userUUID string
+		expected error
+	}{
+		{
+			title:    "not jwt",
+			token:    func(t *testing.T) string { return "invalid" },
+			expected: ErrInvalidPasswordlessToken,
+		},
+		{
+			title: "mfa token",
+			token: func(t *testing.T) string {
+				claims := validPasswordlessLinkClaims()
+				claims["typ"] = MfaTokenType
+				return newTestPasswordlessLinkToken(t, claims)
+			},
+			expected: ErrInvalidPasswordlessToken,
+		},
+		{
+			title: "no jti",
+			token: func(t *testing.T) string {
+				claims := validPasswordlessLinkClaims()
+				delete(claims, "jti")
+				return newTestPasswordlessLinkToken(t, claims)
+			},
+			expected: ErrInvalidPasswordlessToken,
+		},
+		{
+			title:    "already used",
+			token:    func(t *testing.T) string { return newTestPasswordlessLinkToken(t, validPasswordlessLinkClaims()) },
+			consume:  repositories.ErrPasswordlessTokenNotFound,
+			expected: ErrInvalidPasswordlessToken,
+		},
+		{
+			title:    "other user",
+			token:    func(t *testing.T) string { return newTestPasswordlessLinkToken(t, validPasswordlessLinkClaims()) },
+			userUUID: "other-uuid",
+			expected: ErrInvalidPasswordlessToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+				svc := newTestPasswordlessSvc()
+				svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock).
+					On("ConsumeByTokenHash", mock.Anything, "link").
+					Return(&models.PasswordlessToken{ID: 1, UserID: 1}, tt.consume)
+				userUUID := tt.userUUID
+				if userUUID == "" {
+					userUUID = "test-uuid"
+				}
+				svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(&models.User{ID: 1, UUID: userUUID}, nil)
+
+				_, err := svc.Verify(PasswordlessCompleteInput{Token: tt.token(t)})
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+			})
+		})
+	}
+}
+
+func newTestPasswordlessCodeToken(code string) *models.PasswordlessToken {
+	return &models.PasswordlessToken{
+		ID:        5,
+		UserID:    1,
+		Method:    models.PasswordlessMethodCode,
+		TokenHash: models.HashPasswordlessCode(1, code, []byte(testPasswordlessKey)),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+}
+
+func TestPasswordlessVerifyCode(t *testing.T) {
+	funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+		svc := newTestPasswordlessSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(testPasswordlessUser, nil)
+		tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+		tokenRepoMock.On("GetLatestByUserID", uint(1)).Return(newTestPasswordlessCodeToken("123456"), nil)
+		tokenRepoMock.On("RegisterAttempt", uint(5), PasswordlessMaxAttempts).Return(nil)
+		tokenRepoMock.On("Consume", uint(5)).Return(nil)
+
+		user, err := svc.Verify(PasswordlessCompleteInput{Email: "User@example.com", Code: "123456"})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if user != testPasswordlessUser {
+			t.Errorf("expected user %v, got %v", testPasswordlessUser, user)
+		}
+		tokenRepoMock.AssertExpectations(t)
+	})
+}
+
+func TestPasswordlessVerifyCodeFail(t *testing.T) {
+	used := time.Now()
+
+	tests := []struct {
+		title    string
+		code     string
+		user     error
+		token    func() *models.PasswordlessToken
+		latest   error
+		attempt  error
+		consume  error
+		expected error
+	}{
+		{title: "unknown email", user: repositories.ErrUserNotFound, expected: ErrInvalidPasswordlessCode},
+		{title: "no token", latest: repositories.ErrPasswordlessTokenNotFound, expected: ErrInvalidPasswordlessCode},
+		{title: "wrong code", code: "654321", expected: ErrInvalidPasswordlessCode},
+		{title: "attempts exceeded", attempt: repositories.ErrPasswordlessTokenNotFound, expected: ErrInvalidPasswordlessCode},
+		{title: "already used", consume: repositories.ErrPasswordlessTokenNotFound, expected: ErrInvalidPasswordlessCode},
+		{title: "link token", token: func() *models.PasswordlessToken {
+			token := newTestPasswordlessCodeToken("123456")
+			token.Method = models.PasswordlessMethodLink
+			return token
+		}, expected: ErrInvalidPasswordlessCode},
+		{title: "expired", token: func() *models.PasswordlessToken {
+			token := newTestPasswordlessCodeToken("123456")
+			token.ExpiresAt = time.Now().Add(-time.Second)
+			return token
+		}, expected: ErrInvalidPasswordlessCode},
+		{title: "invalidated", token: func() *models.PasswordlessToken {
+			token := newTestPasswordlessCodeToken("123456")
+			token.UsedAt = &used
+			return token
+		}, expected: ErrInvalidPasswordlessCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			funcs.WithEnv("JWT_SECRET_KEY", testPasswordlessKey, t, func() {
+				svc := newTestPasswordlessSvc()
+				svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return(testPasswordlessUser, tt.user)
+				token := newTestPasswordlessCodeToken("123456")
+				if tt.token != nil {
+					token = tt.token()
+				}
+				tokenRepoMock := svc.passwordlessTokenRepo.(*repo_mock.PasswordlessTokenRepoMock)
+				tokenRepoMock.On("GetLatestByUserID", uint(1)).Return(token, tt.latest)
+				tokenRepoMock.On("RegisterAttempt", uint(5), PasswordlessMaxAttempts).Return(tt.attempt)
+				tokenRepoMock.On("Consume", uint(5)).Return(tt.consume)
+
+				code := tt.code
+				if code == "" {
+					code = "123456"
+				}
+				_, err := svc.Verify(PasswordlessCompleteInput{Email: "user@example.com", Code: code})
+				if !errors.Is(err, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+				if tt.code != "" {
+					tokenRepoMock.AssertCalled(t, "RegisterAttempt", uint(5), PasswordlessMaxAttempts)
+					tokenRepoMock.AssertNotCalled(t, "Consume", mock.Anything)
+				}
+			})
+		})
+	}
+}
+
+func TestPasswordlessVerifyCodeDbError(t *testing.T) {
+	svc := newTestPasswordlessSvc()
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByEmail", "user@example.com").Return((*models.User)(nil), fmt.Errorf("db error"))
+
+	_, err := svc.Verify(PasswordlessCompleteInput{Email: "user@example.com", Code: "123456"})
+	if err == nil || errors.Is(err, ErrInvalidPasswordlessCode) {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestPasswordlessLinkURL(t *testing.T) {
+	funcs.WithEnv("PASSWORDLESS_LINK_URL", "", t, func() {
+		if !strings.HasPrefix(passwordlessLinkURL(), "http://localhost") {
+			t.Errorf("unexpected default link url: %s", passwordlessLinkURL())
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleAlreadyExists = errors.New("role already exists")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserRoleNotFound  = errors.New("role is not assigned to the user")
+)
+
+// ユーザーのロールを参照するためのパーミッション
+const PermissionUsersRead = "users:read"
+
+// アクセストークンのクレームに含めるため、空白や区切り文字を含まない名前のみ受け付ける
+var (
+	roleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+	// "users:read" のように、リソースと操作をコロンで区切る
+	permissionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+:[A-Za-z0-9_.-]+$`)
+)
+
+type RoleSvcInterface interface {
+	List() ([]models.Role, error)
+	Get(name string) (*models.Role, error)
+	Create(name string, input RoleInput) (*models.Role, error)
+	Update(name string, input RoleInput) (*models.Role, error)
+	Delete(name string) error
+	ListUserRoles(userUUID string) ([]models.Role, error)
+	AssignUserRole(userUUID string, roleName string) error
+	UnassignUserRole(userUUID string, roleName string) error
+	Authorization(userID uint) (*UserAuthorization, error)
+}
+
+type RoleSvcStruct struct {
+	roleRepo       repositories.RoleRepoInterface
+	permissionRepo repositories.PermissionRepoInterface
+	userRoleRepo   repositories.UserRoleRepoInterface
+	userRepo       repositories.UserRepoInterface
+}
+
+func NewRoleSvc(
+	roleRepo repositories.RoleRepoInterface,
+	permissionRepo repositories.PermissionRepoInterface,
+	userRoleRepo repositories.UserRoleRepoInterface,
+	userRepo repositories.UserRepoInterface,
+) *RoleSvcStruct {
+	return &RoleSvcStruct{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		userRoleRepo:   userRoleRepo,
+		userRepo:       userRepo,
+	}
+}
+
+type RoleInput struct {
+	Description string
+	// 未登録のパーミッションは登録する
+	Permissions []string
+}
+
+// アクセストークンの roles / permissions クレーム
+type UserAuthorization struct {
+	Roles       []string
+	Permissions []string
+}
+
+func (s *RoleSvcStruct) List() ([]models.Role, error) {
+	return s.roleRepo.List()
+}
+
+func (s *RoleSvcStruct) Get(name string) (*models.Role, error) {
+	role, err := s.roleRepo.GetByName(name)
+	if err != nil {
+		if errors.Is(err, repositories.ErrRoleNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *RoleSvcStruct) Create(name string, input RoleInput) (*models.Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: invalid name %q", ErrInvalidRole, name)
+	}
+	permissions, err := s.permissions(input)
+	if err != nil {
+		return nil, err
+	}
+
+	role := &models.Role{Name: name, Description: input.Description, Permissions: permissions}
+	if err := s.roleRepo.Create(role); err != nil {
+		if errors.Is(err, repositories.ErrDuplicateRole) {
+			return nil, ErrRoleAlreadyExists
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// ロールを割り当て済みのユーザーには、次のリフレッシュで発行するアクセストークンから反映される
+func (s *RoleSvcStruct) Update(name string, input RoleInput) (*models.Role, error) {
+	role, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.permissions(input)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = input.Description
+	role.Permissions = permissions
+	if err := s.roleRepo.Update(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *RoleSvcStruct) Delete(name string) error {
+	if err := s.roleRepo.Delete(name); err != nil {
+		if errors.Is(err, repositories.ErrRoleNotFound) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *RoleSvcStruct) ListUserRoles(userUUID string) ([]models.Role, error) {
+	user, err := s.user(userUUID)
+	if err != nil {
+		return nil, err
+	}
+	return s.userRoleRepo.ListRoles(user.ID)
+}
+
+func (s *RoleSvcStruct) AssignUserRole(userUUID string, roleName string) error {
+	user, role, err := s.userAndRole(userUUID, roleName)
+	if err != nil {
+		return err
+	}
+	return s.userRoleRepo.Assign(user.ID, role.ID)
+}
+
+func (s *RoleSvcStruct) UnassignUserRole(userUUID string, roleName string) error {
+	user, role, err := s.userAndRole(userUUID, roleName)
+	if err != nil {
+		return err
+	}
+	if err := s.userRoleRepo.Unassign(user.ID, role.ID); err != nil {
+		if errors.Is(err, repositories.ErrUserRoleNotFound) {
+			return ErrUserRoleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// 割り当てたロールの名前と、それらのロールが持つパーミッションの和集合
+func (s *RoleSvcStruct) Authorization(userID uint) (*UserAuthorization, error) {
+	roles, err := s.userRoleRepo.ListRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorization := &UserAuthorization{Roles: []string{}, Permissions: []string{}}
+	for _, role := range roles {
+		authorization.Roles = append(authorization.Roles, role.Name)
+		authorization.Permissions = append(authorization.Permissions, role.PermissionNames()...)
+	}
+	slices.Sort(authorization.Permissions)
+	authorization.Permissions = slices.Compact(authorization.Permissions)
+	return authorization, nil
+}
+
+func (s *RoleSvcStruct) permissions(input RoleInput) ([]models.Permission, error) {
+	for _, name := range input.Permissions {
+		if !permissionNamePattern.MatchString(name) || len(name) > 100 {
+			return nil, fmt.Errorf("%w: invalid permission %q", ErrInvalidRole, name)
+		}
+	}
+	return s.permissionRepo.FindOrCreate(input.Permissions)
+}
+
+func (s *RoleSvcStruct) user(userUUID string) (*models.User, error) {
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *RoleSvcStruct) userAndRole(userUUID string, roleName string) (*models.User, *models.Role, error) {
+	user, err := s.user(userUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	role, err := s.Get(roleName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, role, nil
+}
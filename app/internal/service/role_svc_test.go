@@ -0,0 +1,256 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/stretchr/testify/mock"
+)
+
+// どのユーザーにも roles を割り当てたロールサービス
+func newTestRoleSvc(roles ...models.Role) *RoleSvcStruct {
+	userRoleRepo := new(repo_mock.UserRoleRepoMock)
+	userRoleRepo.On("ListRoles", mock.Anything).Return(roles, nil)
+
+	return NewRoleSvc(
+		new(repo_mock.RoleRepoMock),
+		new(repo_mock.PermissionRepoMock),
+		userRoleRepo,
+		new(repo_mock.UserRepoMock).OnGetByUUID(&models.User{ID: 1, UUID: "test-uuid"}),
+	)
+}
+
+var testAdminRole = models.Role{
+	ID:          2,
+	Name:        "admin",
+	Permissions: []models.Permission{{ID: 10, Name: "users:read"}, {ID: 11, Name: "users:write"}},
+}
+
+func TestRoleSvcCreate(t *testing.T) {
+	svc := newTestRoleSvc()
+	permissions := []models.Permission{{ID: 10, Name: "users:read"}}
+	svc.permissionRepo.(*repo_mock.PermissionRepoMock).On("FindOrCreate", []string{"users:read"}).Return(permissions, nil)
+	roleRepoMock := svc.roleRepo.(*repo_mock.RoleRepoMock)
+	roleRepoMock.On("Create", mock.MatchedBy(func(role *models.Role) bool {
+		return role.Name == "support" && role.Description == "Support staff" && reflect.DeepEqual(role.Permissions, permissions)
+	})).Return(nil)
+
+	role, err := svc.Create("support", RoleInput{Description: "Support staff", Permissions: []string{"users:read"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if role.Name != "support" {
+		t.Errorf("unexpected role: %+v", role)
+	}
+	roleRepoMock.AssertExpectations(t)
+}
+
+func TestRoleSvcCreateFail(t *testing.T) {
+	tests := map[string]struct {
+		name        string
+		permissions []string
+	}{
+		"empty name":            {"", nil},
+		"name with space":       {"super admin", nil},
+		"permission no action":  {"admin", []string{"users"}},
+		"permission with space": {"admin", []string{"users: read"}},
+	}
+	for title, tt := range tests {
+		t.Run(title, func(t *testing.T) {
+			if _, err := newTestRoleSvc().Create(tt.name, RoleInput{Permissions: tt.permissions}); !errors.Is(err, ErrInvalidRole) {
+				t.Fatalf("expected ErrInvalidRole, got %v", err)
+			}
+		})
+	}
+
+	t.Run("duplicate", func(t *testing.T) {
+		svc := newTestRoleSvc()
+		svc.permissionRepo.(*repo_mock.PermissionRepoMock).On("FindOrCreate", mock.Anything).Return([]models.Permission{}, nil)
+		svc.roleRepo.(*repo_mock.RoleRepoMock).On("Create", mock.Anything).Return(repositories.ErrDuplicateRole)
+		if _, err := svc.Create("admin", RoleInput{}); !errors.Is(err, ErrRoleAlreadyExists) {
+			t.Fatalf("expected ErrRoleAlreadyExists, got %v", err)
+		}
+	})
+
+	t.Run("permission error", func(t *testing.T) {
+		svc := newTestRoleSvc()
+		svc.permissionRepo.(*repo_mock.PermissionRepoMock).On("FindOrCreate", mock.Anything).Return([]models.Permission(nil), fmt.Errorf("db error"))
+		if _, err := svc.Create("admin", RoleInput{Permissions: []string{"users:read"}}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestRoleSvcGet(t *testing.T) {
+	svc := newTestRoleSvc()
+	roleRepoMock := svc.roleRepo.(*repo_mock.RoleRepoMock)
+	roleRepoMock.On("GetByName", "admin").Return(&testAdminRole, nil)
+	roleRepoMock.On("GetByName", "unknown").Return((*models.Role)(nil), repositories.ErrRoleNotFound)
+	roleRepoMock.On("List").Return([]models.Role{testAdminRole}, nil)
+
+	if role, err := svc.Get("admin"); err != nil || role.Name != "admin" {
+		t.Errorf("unexpected result: %+v %v", role, err)
+	}
+	if _, err := svc.Get("unknown"); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("expected ErrRoleNotFound, got %v", err)
+	}
+	if roles, err := svc.List(); err != nil || len(roles) != 1 {
+		t.Errorf("unexpected result: %+v %v", roles, err)
+	}
+}
+
+func TestRoleSvcUpdate(t *testing.T) {
+	svc := newTestRoleSvc()
+	role := testAdminRole
+	roleRepoMock := svc.roleRepo.(*repo_mock.RoleRepoMock)
+	roleRepoMock.On("GetByName", "admin").Return(&role, nil)
+	roleRepoMock.On("Update", mock.MatchedBy(func(role *models.Role) bool {
+		return role.ID == 2 && role.Description == "Read only" && len(role.Permissions) == 1
+	})).Return(nil)
+	svc.permissionRepo.(*repo_mock.PermissionRepoMock).On("FindOrCreate", []string{"users:read"}).
+		Return([]models.Permission{{ID: 10, Name: "users:read"}}, nil)
+
+	updated, err := svc.Update("admin", RoleInput{Description: "Read only", Permissions: []string{"users:read"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if updated.Name != "admin" || !reflect.DeepEqual(updated.PermissionNames(), []string{"users:read"}) {
+		t.Errorf("unexpected role: %+v", updated)
+	}
+	roleRepoMock.AssertExpectations(t)
+}
+
+func TestRoleSvcUpdateFail(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		svc := newTestRoleSvc()
+		svc.roleRepo.(*repo_mock.RoleRepoMock).On("GetByName", "admin").Return((*models.Role)(nil), repositories.ErrRoleNotFound)
+		if _, err := svc.Update("admin", RoleInput{}); !errors.Is(err, ErrRoleNotFound) {
+			t.Fatalf("expected ErrRoleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("invalid permission", func(t *testing.T) {
+		svc := newTestRoleSvc()
+		role := testAdminRole
+		svc.roleRepo.(*repo_mock.RoleRepoMock).On("GetByName", "admin").Return(&role, nil)
+		if _, err := svc.Update("admin", RoleInput{Permissions: []string{"*"}}); !errors.Is(err, ErrInvalidRole) {
+			t.Fatalf("expected ErrInvalidRole, got %v", err)
+		}
+	})
+
+	t.Run("db error", func(t *testing.T) {
+		svc := newTestRoleSvc()
+		role := testAdminRole
+		roleRepoMock := svc.roleRepo.(*repo_mock.RoleRepoMock)
+		roleRepoMock.On("GetByName", "admin").Return(&role, nil)
+		roleRepoMock.On("Update", mock.Anything).Return(fmt.Errorf("db error"))
+		svc.permissionRepo.(*repo_mock.PermissionRepoMock).On("FindOrCreate", mock.Anything).Return([]models.Permission{}, nil)
+		if _, err := svc.Update("admin", RoleInput{}); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestRoleSvcDelete(t *testing.T) {
+	svc := newTestRoleSvc()
+	roleRepoMock := svc.roleRepo.(*repo_mock.RoleRepoMock)
+	roleRepoMock.On("Delete", "admin").Return(nil)
+	roleRepoMock.On("Delete", "unknown").Return(repositories.ErrRoleNotFound)
+	roleRepoMock.On("Delete", "broken").Return(fmt.Errorf("db error"))
+
+	if err := svc.Delete("admin"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := svc.Delete("unknown"); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("expected ErrRoleNotFound, got %v", err)
+	}
+	if err := svc.Delete("broken"); err == nil || errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("expected db error, got %v", err)
+	}
+}
+
+func TestRoleSvcListUserRoles(t *testing.T) {
+	svc := newTestRoleSvc(testAdminRole)
+
+	roles, err := svc.ListUserRoles("test-uuid")
+	if err != nil || len(roles) != 1 || roles[0].Name != "admin" {
+		t.Fatalf("unexpected result: %+v %v", roles, err)
+	}
+	if _, err := svc.ListUserRoles("unknown"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestRoleSvcAssignUserRole(t *testing.T) {
+	svc := newTestRoleSvc()
+	role := testAdminRole
+	svc.roleRepo.(*repo_mock.RoleRepoMock).On("GetByName", "admin").Return(&role, nil)
+	svc.roleRepo.(*repo_mock.RoleRepoMock).On("GetByName", "unknown").Return((*models.Role)(nil), repositories.ErrRoleNotFound)
+	userRoleRepoMock := svc.userRoleRepo.(*repo_mock.UserRoleRepoMock)
+	userRoleRepoMock.On("Assign", uint(1), uint(2)).Return(nil)
+
+	if err := svc.AssignUserRole("test-uuid", "admin"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.AssignUserRole("unknown", "admin"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+	if err := svc.AssignUserRole("test-uuid", "unknown"); !errors.Is(err, ErrRoleNotFound) {
+		t.Errorf("expected ErrRoleNotFound, got %v", err)
+	}
+	userRoleRepoMock.AssertNumberOfCalls(t, "Assign", 1)
+}
+
+func TestRoleSvcUnassignUserRole(t *testing.T) {
+	svc := newTestRoleSvc()
+	role := testAdminRole
+	svc.roleRepo.(*repo_mock.RoleRepoMock).On("GetByName", "admin").Return(&role, nil)
+	userRoleRepoMock := svc.userRoleRepo.(*repo_mock.UserRoleRepoMock)
+	userRoleRepoMock.On("Unassign", uint(1), uint(2)).Return(nil).Once()
+	userRoleRepoMock.On("Unassign", uint(1), uint(2)).Return(repositories.ErrUserRoleNotFound)
+
+	if err := svc.UnassignUserRole("test-uuid", "admin"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.UnassignUserRole("test-uuid", "admin"); !errors.Is(err, ErrUserRoleNotFound) {
+		t.Errorf("expected ErrUserRoleNotFound, got %v", err)
+	}
+	if err := svc.UnassignUserRole("unknown", "admin"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestRoleSvcAuthorization(t *testing.T) {
+	// 複数のロールに含まれるパーミッションは 1 つにまとめる
+	support := models.Role{ID: 3, Name: "support", Permissions: []models.Permission{{Name: "users:read"}, {Name: "tickets:write"}}}
+	authorization, err := newTestRoleSvc(testAdminRole, support).Authorization(1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(authorization.Roles, []string{"admin", "support"}) {
+		t.Errorf("unexpected roles: %v", authorization.Roles)
+	}
+	if !reflect.DeepEqual(authorization.Permissions, []string{"tickets:write", "users:read", "users:write"}) {
+		t.Errorf("unexpected permissions: %v", authorization.Permissions)
+	}
+
+	authorization, err = newTestRoleSvc().Authorization(1)
+	if err != nil || len(authorization.Roles) != 0 || len(authorization.Permissions) != 0 {
+		t.Errorf("unexpected result: %+v %v", authorization, err)
+	}
+}
+
+func TestRoleSvcAuthorizationFailDbErr(t *testing.T) {
+	userRoleRepo := new(repo_mock.UserRoleRepoMock)
+	userRoleRepo.On("ListRoles", uint(1)).Return([]models.Role(nil), fmt.Errorf("db error"))
+	svc := NewRoleSvc(nil, nil, userRoleRepo, nil)
+
+	if _, err := svc.Authorization(1); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
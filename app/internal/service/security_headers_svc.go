@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"os"
+)
+
+// すべてのレスポンスに付けるセキュリティ関連ヘッダーの設定
+type SecurityHeadersConfig struct {
+	// Strict-Transport-Security の max-age（0 の場合は付けない）
+	HstsMaxAge            int
+	HstsIncludeSubdomains bool
+	HstsPreload           bool
+	ReferrerPolicy        string
+	// API は HTML を返さないため既定ではすべて禁止し、HTML を返すルートグループで上書きする
+	ContentSecurityPolicy string
+}
+
+func NewSecurityHeadersConfigFromEnv() SecurityHeadersConfig {
+	config := SecurityHeadersConfig{
+		HstsMaxAge:            envInt("SECURITY_HSTS_MAX_AGE", 31536000),
+		HstsIncludeSubdomains: os.Getenv("SECURITY_HSTS_INCLUDE_SUBDOMAINS") != "false",
+		HstsPreload:           os.Getenv("SECURITY_HSTS_PRELOAD") == "true",
+		ReferrerPolicy:        os.Getenv("SECURITY_REFERRER_POLICY"),
+		ContentSecurityPolicy: os.Getenv("SECURITY_CONTENT_SECURITY_POLICY"),
+	}
+	if config.ReferrerPolicy == "" {
+		config.ReferrerPolicy = "no-referrer"
+	}
+	if config.ContentSecurityPolicy == "" {
+		config.ContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+	}
+	return config
+}
+
+func (c SecurityHeadersConfig) StrictTransportSecurity() string {
+	if c.HstsMaxAge <= 0 {
+		return ""
+	}
+	value := fmt.Sprintf("max-age=%d", c.HstsMaxAge)
+	if c.HstsIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if c.HstsPreload {
+		value += "; preload"
+	}
+	return value
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+)
+
+func TestNewSecurityHeadersConfigFromEnv(t *testing.T) {
+	config := NewSecurityHeadersConfigFromEnv()
+	if config.StrictTransportSecurity() != "max-age=31536000; includeSubDomains" {
+		t.Errorf("unexpected hsts: %s", config.StrictTransportSecurity())
+	}
+	if config.ReferrerPolicy != "no-referrer" || config.ContentSecurityPolicy != "default-src 'none'; frame-ancestors 'none'" {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+
+	funcs.WithEnv("SECURITY_HSTS_MAX_AGE", "63072000", t, func() {
+		funcs.WithEnv("SECURITY_HSTS_INCLUDE_SUBDOMAINS", "false", t, func() {
+			funcs.WithEnv("SECURITY_HSTS_PRELOAD", "true", t, func() {
+				funcs.WithEnv("SECURITY_REFERRER_POLICY", "strict-origin-when-cross-origin", t, func() {
+					config := NewSecurityHeadersConfigFromEnv()
+					if config.StrictTransportSecurity() != "max-age=63072000; preload" {
+						t.Errorf("unexpected hsts: %s", config.StrictTransportSecurity())
+					}
+					if config.ReferrerPolicy != "strict-origin-when-cross-origin" {
+						t.Errorf("unexpected referrer policy: %s", config.ReferrerPolicy)
+					}
+				})
+			})
+		})
+	})
+
+	// ローカル開発などで HSTS を無効にする
+	funcs.WithEnv("SECURITY_HSTS_MAX_AGE", "0", t, func() {
+		if hsts := NewSecurityHeadersConfigFromEnv().StrictTransportSecurity(); hsts != "" {
+			t.Errorf("expected hsts to be disabled, got %s", hsts)
+		}
+	})
+}
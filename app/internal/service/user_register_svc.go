@@ -1,8 +1,10 @@
 package service
 
 import (
+	"errors"
 	"strings"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/i18n"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabencrypt"
@@ -13,17 +15,20 @@ type UserRegisterSvcInterface interface {
 }
 
 type UserRegisterSvcStruct struct {
-	encryptlib atylabencrypt.EncryptPkgInterface
-	userRepo   repositories.UserRepoInterface
+	encryptlib     atylabencrypt.EncryptPkgInterface
+	userRepo       repositories.UserRepoInterface
+	passwordPolicy PasswordPolicySvcInterface
 }
 
 func NewUserRegisterSvc(
 	encryptlib atylabencrypt.EncryptPkgInterface,
 	userRepo repositories.UserRepoInterface,
+	passwordPolicy PasswordPolicySvcInterface,
 ) *UserRegisterSvcStruct {
 	return &UserRegisterSvcStruct{
-		encryptlib: encryptlib,
-		userRepo:   userRepo,
+		encryptlib:     encryptlib,
+		userRepo:       userRepo,
+		passwordPolicy: passwordPolicy,
 	}
 }
 
@@ -31,13 +36,23 @@ type RegisterUserInput struct {
 	Name     string
 	Email    string
 	Password string
+	// メールの言語。対応していない言語の場合は保存しない
+	Locale string
 }
 
 func (s *UserRegisterSvcStruct) RegisterUser(
 	input RegisterUserInput,
 ) (models.User, error) {
 	email := strings.TrimSpace(strings.ToLower(input.Email))
-	hashedPassword, err := s.encryptlib.CreatePasswordHash(input.Password)
+	if err := s.passwordPolicy.Validate(PasswordPolicyInput{
+		Password: input.Password,
+		Email:    email,
+		Username: input.Name,
+	}); err != nil {
+		return models.User{}, err
+	}
+
+	hashedPassword, err := s.encryptlib.CreatePasswordHash(NormalizePassword(input.Password))
 	if err != nil {
 		return models.User{}, err
 	}
@@ -46,9 +61,13 @@ func (s *UserRegisterSvcStruct) RegisterUser(
 		Username:     input.Name,
 		Email:        email,
 		PasswordHash: hashedPassword,
+		Locale:       i18n.Normalize(input.Locale),
 	}
 
 	if err := s.userRepo.Create(&user); err != nil {
+		if errors.Is(err, repositories.ErrDuplicateEmail) {
+			return models.User{}, ErrEmailAlreadyInUse
+		}
 		return models.User{}, err
 	}
 
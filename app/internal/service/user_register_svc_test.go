@@ -1,33 +1,50 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabencrypt"
+	"github.com/stretchr/testify/mock"
 )
 
+func newPasswordPolicy(input RegisterUserInput) (*PasswordPolicySvcStruct, *repo_mock.BreachedPasswordRepoMock) {
+	breachedRepoMock := new(repo_mock.BreachedPasswordRepoMock)
+	breachedRepoMock.On("IsBreached", input.Password).Return(false, nil)
+	return NewPasswordPolicySvc(PasswordPolicyConfig{
+		MinLength:   8,
+		MaxLength:   64,
+		MinStrength: 2,
+	}, breachedRepoMock), breachedRepoMock
+}
+
 func TestRegisterUserSuccess(t *testing.T) {
 	input := RegisterUserInput{
 		Name:     "testuser",
 		Email:    "testuser@example.com",
 		Password: "password123",
+		Locale:   "ja-JP",
 	}
 
 	encryptlibMock := new(atylabencrypt.EncryptPkgStructMock)
 	encryptlibMock.On("CreatePasswordHash", input.Password).
 		Return("hashedpassword123", nil)
 
+	passwordPolicy, _ := newPasswordPolicy(input)
+
 	userRepoMock := new(repo_mock.UserRepoMock)
 	userRepoMock.On("Create", &models.User{
 		Username:     input.Name,
 		Email:        input.Email,
 		PasswordHash: "hashedpassword123",
+		Locale:       "ja",
 	}).Return(nil)
 
-	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock)
+	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock, passwordPolicy)
 
 	user, err := svc.RegisterUser(input)
 	if err != nil {
@@ -57,9 +74,11 @@ func TestRegisterUserCreatePasswordHashError(t *testing.T) {
 	encryptlibMock.On("CreatePasswordHash", input.Password).
 		Return("", fmt.Errorf("hash error"))
 
+	passwordPolicy, _ := newPasswordPolicy(input)
+
 	userRepoMock := new(repo_mock.UserRepoMock)
 
-	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock)
+	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock, passwordPolicy)
 
 	user, err := svc.RegisterUser(input)
 	if err == nil {
@@ -84,6 +103,8 @@ func TestRegisterUserDBCreateError(t *testing.T) {
 	encryptlibMock.On("CreatePasswordHash", input.Password).
 		Return("hashedpassword123", nil)
 
+	passwordPolicy, _ := newPasswordPolicy(input)
+
 	userRepoMock := new(repo_mock.UserRepoMock)
 	userRepoMock.On("Create", &models.User{
 		Username:     input.Name,
@@ -91,7 +112,7 @@ func TestRegisterUserDBCreateError(t *testing.T) {
 		PasswordHash: "hashedpassword123",
 	}).Return(fmt.Errorf("db create error"))
 
-	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock)
+	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock, passwordPolicy)
 
 	user, err := svc.RegisterUser(input)
 	if err == nil {
@@ -104,3 +125,57 @@ func TestRegisterUserDBCreateError(t *testing.T) {
 
 	encryptlibMock.AssertExpectations(t)
 }
+
+func TestRegisterUserDuplicateEmail(t *testing.T) {
+	input := RegisterUserInput{
+		Name:     "testuser",
+		Email:    "testuser@example.com",
+		Password: "password123",
+	}
+
+	encryptlibMock := new(atylabencrypt.EncryptPkgStructMock)
+	encryptlibMock.On("CreatePasswordHash", input.Password).
+		Return("hashedpassword123", nil)
+
+	passwordPolicy, _ := newPasswordPolicy(input)
+
+	userRepoMock := new(repo_mock.UserRepoMock)
+	userRepoMock.On("Create", &models.User{
+		Username:     input.Name,
+		Email:        input.Email,
+		PasswordHash: "hashedpassword123",
+	}).Return(repositories.ErrDuplicateEmail)
+
+	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock, passwordPolicy)
+
+	if _, err := svc.RegisterUser(input); !errors.Is(err, ErrEmailAlreadyInUse) {
+		t.Fatalf("expected ErrEmailAlreadyInUse, got %v", err)
+	}
+}
+
+func TestRegisterUserPasswordPolicyError(t *testing.T) {
+	input := RegisterUserInput{
+		Name:     "hanako",
+		Email:    "testuser@example.com",
+		Password: "hanako-secret",
+	}
+
+	encryptlibMock := new(atylabencrypt.EncryptPkgStructMock)
+	userRepoMock := new(repo_mock.UserRepoMock)
+	passwordPolicy, breachedRepoMock := newPasswordPolicy(input)
+
+	svc := NewUserRegisterSvc(encryptlibMock, userRepoMock, passwordPolicy)
+
+	_, err := svc.RegisterUser(input)
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected password policy error, got %v", err)
+	}
+	if policyErr.Violations[0].Code != PasswordViolationContainsUsername {
+		t.Errorf("expected %v, got %v", PasswordViolationContainsUsername, policyErr.Violations[0].Code)
+	}
+
+	encryptlibMock.AssertNotCalled(t, "CreatePasswordHash", mock.Anything)
+	userRepoMock.AssertNotCalled(t, "Create", mock.Anything)
+	breachedRepoMock.AssertExpectations(t)
+}
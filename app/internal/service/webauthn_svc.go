@@ -0,0 +1,402 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// チャレンジの有効期限（秒）
+const WebauthnTimeout = 300
+
+var (
+	ErrInvalidWebauthnResponse  = errors.New("invalid webauthn response")
+	ErrWebauthnCredentialExists = errors.New("webauthn credential already registered")
+)
+
+type WebauthnSvcInterface interface {
+	BeginRegistration(userUUID string) (*WebauthnCreationOptions, error)
+	FinishRegistration(input WebauthnRegistrationInput) (*models.UserCredential, error)
+	BeginLogin() (*WebauthnRequestOptions, error)
+	FinishLogin(input WebauthnLoginInput) (*models.User, error)
+}
+
+type WebauthnConfig struct {
+	RPID    string
+	RPName  string
+	Origins []string
+}
+
+func NewWebauthnConfigFromEnv() WebauthnConfig {
+	config := WebauthnConfig{
+		RPID:    os.Getenv("WEBAUTHN_RP_ID"),
+		RPName:  os.Getenv("WEBAUTHN_RP_NAME"),
+		Origins: []string{},
+	}
+	if config.RPID == "" {
+		config.RPID = "localhost"
+	}
+	if config.RPName == "" {
+		config.RPName = "portfolio-go-auth"
+	}
+	for _, origin := range strings.Split(os.Getenv("WEBAUTHN_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.Origins = append(config.Origins, origin)
+		}
+	}
+	if len(config.Origins) == 0 {
+		config.Origins = []string{"http://localhost:8080"}
+	}
+	return config
+}
+
+type WebauthnSvcStruct struct {
+	config                WebauthnConfig
+	userRepo              repositories.UserRepoInterface
+	userCredentialRepo    repositories.UserCredentialRepoInterface
+	webauthnChallengeRepo repositories.WebauthnChallengeRepoInterface
+	clock                 atylabclock.ClockInterface
+}
+
+func NewWebauthnSvc(
+	config WebauthnConfig,
+	userRepo repositories.UserRepoInterface,
+	userCredentialRepo repositories.UserCredentialRepoInterface,
+	webauthnChallengeRepo repositories.WebauthnChallengeRepoInterface,
+	clock atylabclock.ClockInterface,
+) *WebauthnSvcStruct {
+	return &WebauthnSvcStruct{
+		config:                config,
+		userRepo:              userRepo,
+		userCredentialRepo:    userCredentialRepo,
+		webauthnChallengeRepo: webauthnChallengeRepo,
+		clock:                 clock,
+	}
+}
+
+// navigator.credentials.create() に渡す PublicKeyCredentialCreationOptions
+type WebauthnCreationOptions = protocol.PublicKeyCredentialCreationOptions
+
+// navigator.credentials.get() に渡す PublicKeyCredentialRequestOptions
+type WebauthnRequestOptions = protocol.PublicKeyCredentialRequestOptions
+
+type WebauthnRegistrationInput struct {
+	UserUUID          string
+	CredentialID      string
+	ClientDataJSON    []byte
+	AttestationObject []byte
+	Name              string
+}
+
+type WebauthnLoginInput struct {
+	CredentialID      string
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+var webauthnCredentialParameters = []protocol.CredentialParameter{
+	{Type: protocol.PublicKeyCredentialType, Algorithm: webauthncose.AlgES256},
+	{Type: protocol.PublicKeyCredentialType, Algorithm: webauthncose.AlgRS256},
+}
+
+// webauthn.User の実装
+// ユーザーハンドルには UUID を使う（メールアドレス等の個人情報は含めない）
+type webauthnUser struct {
+	user        *models.User
+	credentials []models.UserCredential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	return []byte(u.user.UUID)
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, credential := range u.credentials {
+		id, err := base64.RawURLEncoding.DecodeString(credential.CredentialID)
+		if err != nil {
+			continue
+		}
+		aaguid, _ := hex.DecodeString(credential.AAGUID)
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationFormat,
+			Flags:           webauthn.CredentialFlags{BackupEligible: credential.BackupEligible},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    aaguid,
+				SignCount: credential.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// 署名・attestation の検証は go-webauthn に任せる
+// packed の証明書チェーンを信頼アンカー（MDS）で検証することはしない
+func (s *WebauthnSvcStruct) relyingParty() (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{
+		Timeout:    WebauthnTimeout * time.Second,
+		TimeoutUVD: WebauthnTimeout * time.Second,
+	}
+	rp, err := webauthn.New(&webauthn.Config{
+		RPID:                  s.config.RPID,
+		RPDisplayName:         s.config.RPName,
+		RPOrigins:             s.config.Origins,
+		AttestationPreference: protocol.PreferDirectAttestation,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationPreferred,
+		},
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid webauthn config: %w", err)
+	}
+	return rp, nil
+}
+
+func (s *WebauthnSvcStruct) BeginRegistration(userUUID string) (*WebauthnCreationOptions, error) {
+	user, err := s.userRepo.GetByUUID(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	credentials, err := s.userCredentialRepo.ListByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	owner := &webauthnUser{user: user, credentials: credentials}
+
+	rp, err := s.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	creation, session, err := rp.BeginRegistration(
+		owner,
+		webauthn.WithExclusions(webauthn.Credentials(owner.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithCredentialParameters(webauthnCredentialParameters),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveChallenge(session.Challenge, protocol.CreateCeremony, &user.ID); err != nil {
+		return nil, err
+	}
+	return &creation.Response, nil
+}
+
+func (s *WebauthnSvcStruct) FinishRegistration(input WebauthnRegistrationInput) (*models.UserCredential, error) {
+	user, err := s.userRepo.GetByUUID(input.UserUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	rawID, err := base64.RawURLEncoding.DecodeString(input.CredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebauthnResponse, err)
+	}
+	parsed, err := protocol.CredentialCreationResponse{
+		PublicKeyCredential: protocol.PublicKeyCredential{
+			Credential: protocol.Credential{ID: input.CredentialID, Type: string(protocol.PublicKeyCredentialType)},
+			RawID:      rawID,
+		},
+		AttestationResponse: protocol.AuthenticatorAttestationResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: input.ClientDataJSON},
+			AttestationObject:     input.AttestationObject,
+		},
+	}.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebauthnResponse, err)
+	}
+
+	challenge, err := s.consumeChallenge(parsed.Response.CollectedClientData, protocol.CreateCeremony)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID == nil || *challenge.UserID != user.ID {
+		return nil, fmt.Errorf("%w: challenge was issued for another user", ErrInvalidWebauthnResponse)
+	}
+
+	rp, err := s.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	created, err := rp.CreateCredential(&webauthnUser{user: user}, webauthn.SessionData{
+		Challenge:        parsed.Response.CollectedClientData.Challenge,
+		UserID:           []byte(user.UUID),
+		UserVerification: protocol.VerificationPreferred,
+		CredParams:       webauthnCredentialParameters,
+	}, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebauthnResponse, err)
+	}
+	if !bytes.Equal(created.ID, rawID) {
+		return nil, fmt.Errorf("%w: credential id mismatch", ErrInvalidWebauthnResponse)
+	}
+
+	var key webauthncose.PublicKeyData
+	if err := webauthncbor.Unmarshal(created.PublicKey, &key); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebauthnResponse, err)
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(created.ID)
+	if _, err := s.userCredentialRepo.GetByCredentialID(credentialID); err == nil {
+		return nil, ErrWebauthnCredentialExists
+	} else if !errors.Is(err, repositories.ErrCredentialNotFound) {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		name = "passkey"
+	}
+
+	credential := &models.UserCredential{
+		UserID:            user.ID,
+		CredentialID:      credentialID,
+		PublicKey:         created.PublicKey,
+		Algorithm:         key.Algorithm,
+		SignCount:         created.Authenticator.SignCount,
+		BackupEligible:    created.Flags.BackupEligible,
+		AttestationFormat: created.AttestationType,
+		AAGUID:            hex.EncodeToString(created.Authenticator.AAGUID),
+		Name:              name,
+	}
+	if err := s.userCredentialRepo.Create(credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+// discoverable credential を前提とし、allowCredentials は空で返す
+func (s *WebauthnSvcStruct) BeginLogin() (*WebauthnRequestOptions, error) {
+	rp, err := s.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	assertion, session, err := rp.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveChallenge(session.Challenge, protocol.AssertCeremony, nil); err != nil {
+		return nil, err
+	}
+	return &assertion.Response, nil
+}
+
+func (s *WebauthnSvcStruct) FinishLogin(input WebauthnLoginInput) (*models.User, error) {
+	rawID, err := base64.RawURLEncoding.DecodeString(input.CredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebauthnResponse, err)
+	}
+	parsed, err := protocol.CredentialAssertionResponse{
+		PublicKeyCredential: protocol.PublicKeyCredential{
+			Credential: protocol.Credential{ID: input.CredentialID, Type: string(protocol.PublicKeyCredentialType)},
+			RawID:      rawID,
+		},
+		AssertionResponse: protocol.AuthenticatorAssertionResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: input.ClientDataJSON},
+			AuthenticatorData:     input.AuthenticatorData,
+			Signature:             input.Signature,
+			UserHandle:            input.UserHandle,
+		},
+	}.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebauthnResponse, err)
+	}
+
+	if _, err := s.consumeChallenge(parsed.Response.CollectedClientData, protocol.AssertCeremony); err != nil {
+		return nil, err
+	}
+
+	credential, err := s.userCredentialRepo.GetByCredentialID(input.CredentialID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCredentialNotFound) {
+			return nil, fmt.Errorf("%w: unknown credential", ErrInvalidWebauthnResponse)
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(credential.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	rp, err := s.relyingParty()
+	if err != nil {
+		return nil, err
+	}
+	// userHandle とクレデンシャルの所有者の一致はライブラリ側で確認する
+	owner := func(rawID, userHandle []byte) (webauthn.User, error) {
+		return &webauthnUser{user: user, credentials: []models.UserCredential{*credential}}, nil
+	}
+	validated, err := rp.ValidateDiscoverableLogin(owner, webauthn.SessionData{
+		Challenge:        parsed.Response.CollectedClientData.Challenge,
+		UserVerification: protocol.VerificationRequired,
+	}, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebauthnResponse, err)
+	}
+
+	// カウンタが増えていない場合はクローンされた認証器の可能性がある
+	if validated.Authenticator.CloneWarning {
+		return nil, fmt.Errorf("%w: sign count did not increase", ErrInvalidWebauthnResponse)
+	}
+	if err := s.userCredentialRepo.UpdateSignCount(credential.ID, validated.Authenticator.SignCount); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *WebauthnSvcStruct) saveChallenge(challenge string, ceremony protocol.CeremonyType, userId *uint) error {
+	return s.webauthnChallengeRepo.Create(&models.WebauthnChallenge{
+		ChallengeHash: models.HashWebauthnChallenge(challenge),
+		Ceremony:      string(ceremony),
+		UserID:        userId,
+		ExpiresAt:     s.clock.Now().Add(WebauthnTimeout * time.Second),
+	})
+}
+
+// clientDataJSON のチャレンジを使用済みにする
+// 種別・オリジンの検証はライブラリ側で行う
+func (s *WebauthnSvcStruct) consumeChallenge(clientData protocol.CollectedClientData, ceremony protocol.CeremonyType) (*models.WebauthnChallenge, error) {
+	challenge, err := s.webauthnChallengeRepo.Consume(models.HashWebauthnChallenge(clientData.Challenge), string(ceremony))
+	if err != nil {
+		if errors.Is(err, repositories.ErrWebauthnChallengeNotFound) {
+			return nil, fmt.Errorf("%w: unknown or expired challenge", ErrInvalidWebauthnResponse)
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
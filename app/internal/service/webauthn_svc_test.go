@@ -0,0 +1,457 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/mocks/repo_mock"
+	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabclock"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "http://localhost:8080"
+)
+
+var testWebauthnUser = &models.User{
+	ID:       1,
+	UUID:     "test-uuid",
+	Email:    "user@example.com",
+	Username: "Test User",
+}
+
+func newTestWebauthnSvc() *WebauthnSvcStruct {
+	return NewWebauthnSvc(
+		WebauthnConfig{
+			RPID:    testRPID,
+			RPName:  "Test",
+			Origins: []string{testOrigin},
+		},
+		new(repo_mock.UserRepoMock),
+		new(repo_mock.UserCredentialRepoMock),
+		new(repo_mock.WebauthnChallengeRepoMock),
+		atylabclock.NewClockMock(time.Now()),
+	)
+}
+
+func testChallengeBytes(t *testing.T, challenge string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(challenge)
+	if err != nil {
+		t.Fatalf("invalid challenge: %v", err)
+	}
+	return b
+}
+
+// チャレンジの発行から検証までを通す
+func expectChallenge(svc *WebauthnSvcStruct, ceremony string, userId *uint) string {
+	bytes, _ := protocol.CreateChallenge()
+	challenge := bytes.String()
+	svc.webauthnChallengeRepo.(*repo_mock.WebauthnChallengeRepoMock).
+		On("Consume", models.HashWebauthnChallenge(challenge), ceremony).
+		Return(&models.WebauthnChallenge{Ceremony: ceremony, UserID: userId}, nil)
+	return challenge
+}
+
+func TestNewWebauthnConfigFromEnv(t *testing.T) {
+	funcs.WithEnvMap(funcs.Envs{
+		"WEBAUTHN_RP_ID":   "",
+		"WEBAUTHN_RP_NAME": "",
+		"WEBAUTHN_ORIGINS": "",
+	}, t, func() {
+		config := NewWebauthnConfigFromEnv()
+		if config.RPID != "localhost" || config.RPName != "portfolio-go-auth" {
+			t.Errorf("unexpected default config: %+v", config)
+		}
+		if len(config.Origins) != 1 || config.Origins[0] != "http://localhost:8080" {
+			t.Errorf("unexpected default origins: %v", config.Origins)
+		}
+	})
+
+	funcs.WithEnvMap(funcs.Envs{
+		"WEBAUTHN_RP_ID":   "example.com",
+		"WEBAUTHN_RP_NAME": "Example",
+		"WEBAUTHN_ORIGINS": "https://example.com, https://login.example.com,",
+	}, t, func() {
+		config := NewWebauthnConfigFromEnv()
+		if config.RPID != "example.com" || config.RPName != "Example" {
+			t.Errorf("unexpected config: %+v", config)
+		}
+		if len(config.Origins) != 2 || config.Origins[1] != "https://login.example.com" {
+			t.Errorf("unexpected origins: %v", config.Origins)
+		}
+	})
+}
+
+func TestWebauthnBeginRegistration(t *testing.T) {
+	svc := newTestWebauthnSvc()
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testWebauthnUser, nil)
+	svc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock).
+		On("ListByUserID", uint(1)).Return([]models.UserCredential{{CredentialID: "existing"}}, nil)
+	challengeRepoMock := svc.webauthnChallengeRepo.(*repo_mock.WebauthnChallengeRepoMock)
+	challengeRepoMock.On("Create", mock.MatchedBy(func(c *models.WebauthnChallenge) bool {
+		return c.Ceremony == string(protocol.CreateCeremony) && c.UserID != nil && *c.UserID == 1 && c.ExpiresAt.After(time.Now())
+	})).Return(nil)
+
+	options, err := svc.BeginRegistration("test-uuid")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if options.RelyingParty.ID != testRPID || string(options.User.ID.(protocol.URLEncodedBase64)) != "test-uuid" {
+		t.Errorf("unexpected options: %+v", options)
+	}
+	if options.User.Name != "user@example.com" || options.User.DisplayName != "Test User" {
+		t.Errorf("unexpected user entity: %+v", options.User)
+	}
+	if len(options.CredentialExcludeList) != 1 || options.CredentialExcludeList[0].CredentialID.String() != "existing" {
+		t.Errorf("unexpected exclude credentials: %+v", options.CredentialExcludeList)
+	}
+	if options.AuthenticatorSelection.ResidentKey != protocol.ResidentKeyRequirementRequired {
+		t.Errorf("expected discoverable credential to be required")
+	}
+
+	created := challengeRepoMock.Calls[0].Arguments.Get(0).(*models.WebauthnChallenge)
+	if created.ChallengeHash != models.HashWebauthnChallenge(options.Challenge.String()) {
+		t.Error("expected challenge hash to be stored")
+	}
+}
+
+func TestWebauthnBeginRegistrationFail(t *testing.T) {
+	t.Run("user not found", func(t *testing.T) {
+		svc := newTestWebauthnSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return((*models.User)(nil), fmt.Errorf("not found"))
+
+		if _, err := svc.BeginRegistration("test-uuid"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+
+	t.Run("challenge error", func(t *testing.T) {
+		svc := newTestWebauthnSvc()
+		svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testWebauthnUser, nil)
+		svc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock).On("ListByUserID", uint(1)).Return(nil, nil)
+		svc.webauthnChallengeRepo.(*repo_mock.WebauthnChallengeRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+
+		if _, err := svc.BeginRegistration("test-uuid"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+	})
+}
+
+func TestWebauthnFinishRegistration(t *testing.T) {
+	tests := []struct {
+		title         string
+		authenticator func() *funcs.SoftwareAuthenticator
+		format        string
+		alg           int64
+	}{
+		{"none", func() *funcs.SoftwareAuthenticator { return funcs.NewSoftwareAuthenticator(testRPID, testOrigin) }, "none", int64(webauthncose.AlgES256)},
+		{"packed self", func() *funcs.SoftwareAuthenticator { return funcs.NewSoftwareAuthenticator(testRPID, testOrigin) }, "packed", int64(webauthncose.AlgES256)},
+		{"packed rs256", func() *funcs.SoftwareAuthenticator {
+			return funcs.NewSoftwareAuthenticatorRS256(testRPID, testOrigin)
+		}, "packed", int64(webauthncose.AlgRS256)},
+		{"packed x5c", func() *funcs.SoftwareAuthenticator {
+			a := funcs.NewSoftwareAuthenticator(testRPID, testOrigin)
+			a.UseAttestationCertificate()
+			return a
+		}, "packed", int64(webauthncose.AlgES256)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			authenticator := tt.authenticator()
+			credentialID := base64.RawURLEncoding.EncodeToString(authenticator.CredentialID)
+
+			svc := newTestWebauthnSvc()
+			userId := uint(1)
+			challenge := expectChallenge(svc, string(protocol.CreateCeremony), &userId)
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testWebauthnUser, nil)
+			credentialRepoMock := svc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock)
+			credentialRepoMock.On("GetByCredentialID", credentialID).Return((*models.UserCredential)(nil), repositories.ErrCredentialNotFound)
+			credentialRepoMock.On("Create", mock.Anything).Return(nil)
+
+			clientDataJSON, attestationObject := authenticator.CreateCredential(testChallengeBytes(t, challenge), []byte("test-uuid"), tt.format)
+
+			credential, err := svc.FinishRegistration(WebauthnRegistrationInput{
+				UserUUID:          "test-uuid",
+				CredentialID:      credentialID,
+				ClientDataJSON:    clientDataJSON,
+				AttestationObject: attestationObject,
+				Name:              "My Key",
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if credential.UserID != 1 || credential.CredentialID != credentialID || credential.Name != "My Key" {
+				t.Errorf("unexpected credential: %+v", credential)
+			}
+			if credential.Algorithm != tt.alg || credential.AttestationFormat != tt.format {
+				t.Errorf("unexpected credential: %+v", credential)
+			}
+			if string(credential.PublicKey) != string(authenticator.COSEPublicKey()) {
+				t.Error("expected cose public key to be stored")
+			}
+			credentialRepoMock.AssertCalled(t, "Create", credential)
+		})
+	}
+}
+
+func TestWebauthnFinishRegistrationFail(t *testing.T) {
+	otherUserId := uint(2)
+	userId := uint(1)
+
+	tests := []struct {
+		title    string
+		prepare  func(a *funcs.SoftwareAuthenticator)
+		userId   *uint
+		modify   func(input *WebauthnRegistrationInput)
+		existing bool
+		expected error
+	}{
+		{title: "wrong origin", prepare: func(a *funcs.SoftwareAuthenticator) { a.Origin = "https://evil.example.com" }, userId: &userId, expected: ErrInvalidWebauthnResponse},
+		{title: "wrong rp id", prepare: func(a *funcs.SoftwareAuthenticator) { a.RPID = "evil.example.com" }, userId: &userId, expected: ErrInvalidWebauthnResponse},
+		{title: "challenge for other user", userId: &otherUserId, expected: ErrInvalidWebauthnResponse},
+		{title: "credential id mismatch", userId: &userId, modify: func(input *WebauthnRegistrationInput) {
+			input.CredentialID = base64.RawURLEncoding.EncodeToString([]byte("other"))
+		}, expected: ErrInvalidWebauthnResponse},
+		{title: "broken attestation", userId: &userId, modify: func(input *WebauthnRegistrationInput) { input.AttestationObject = []byte{0xa0} }, expected: ErrInvalidWebauthnResponse},
+		{title: "already registered", userId: &userId, existing: true, expected: ErrWebauthnCredentialExists},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			authenticator := funcs.NewSoftwareAuthenticator(testRPID, testOrigin)
+			if tt.prepare != nil {
+				tt.prepare(authenticator)
+			}
+			credentialID := base64.RawURLEncoding.EncodeToString(authenticator.CredentialID)
+
+			svc := newTestWebauthnSvc()
+			challenge := expectChallenge(svc, string(protocol.CreateCeremony), tt.userId)
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testWebauthnUser, nil)
+			credentialRepoMock := svc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock)
+			if tt.existing {
+				credentialRepoMock.On("GetByCredentialID", credentialID).Return(&models.UserCredential{ID: 1}, nil)
+			} else {
+				credentialRepoMock.On("GetByCredentialID", credentialID).Return((*models.UserCredential)(nil), repositories.ErrCredentialNotFound)
+			}
+
+			clientDataJSON, attestationObject := authenticator.CreateCredential(testChallengeBytes(t, challenge), []byte("test-uuid"), "packed")
+			input := WebauthnRegistrationInput{
+				UserUUID:          "test-uuid",
+				CredentialID:      credentialID,
+				ClientDataJSON:    clientDataJSON,
+				AttestationObject: attestationObject,
+			}
+			if tt.modify != nil {
+				tt.modify(&input)
+			}
+
+			_, err := svc.FinishRegistration(input)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			credentialRepoMock.AssertNotCalled(t, "Create", mock.Anything)
+		})
+	}
+}
+
+func TestWebauthnFinishRegistrationChallengeNotFound(t *testing.T) {
+	authenticator := funcs.NewSoftwareAuthenticator(testRPID, testOrigin)
+
+	svc := newTestWebauthnSvc()
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testWebauthnUser, nil)
+	svc.webauthnChallengeRepo.(*repo_mock.WebauthnChallengeRepoMock).
+		On("Consume", mock.Anything, string(protocol.CreateCeremony)).
+		Return((*models.WebauthnChallenge)(nil), repositories.ErrWebauthnChallengeNotFound)
+
+	clientDataJSON, attestationObject := authenticator.CreateCredential([]byte("unknown"), []byte("test-uuid"), "none")
+
+	_, err := svc.FinishRegistration(WebauthnRegistrationInput{
+		UserUUID:          "test-uuid",
+		ClientDataJSON:    clientDataJSON,
+		AttestationObject: attestationObject,
+	})
+	if !errors.Is(err, ErrInvalidWebauthnResponse) {
+		t.Fatalf("expected ErrInvalidWebauthnResponse, got %v", err)
+	}
+}
+
+func TestWebauthnFinishRegistrationWrongCeremony(t *testing.T) {
+	authenticator := funcs.NewSoftwareAuthenticator(testRPID, testOrigin)
+
+	svc := newTestWebauthnSvc()
+	svc.userRepo.(*repo_mock.UserRepoMock).On("GetByUUID", "test-uuid").Return(testWebauthnUser, nil)
+
+	// 認証レスポンスの clientDataJSON を登録に流用することはできない
+	clientDataJSON, _, _ := authenticator.GetAssertion([]byte("challenge"))
+
+	_, err := svc.FinishRegistration(WebauthnRegistrationInput{
+		UserUUID:       "test-uuid",
+		ClientDataJSON: clientDataJSON,
+	})
+	if !errors.Is(err, ErrInvalidWebauthnResponse) {
+		t.Fatalf("expected ErrInvalidWebauthnResponse, got %v", err)
+	}
+}
+
+func TestWebauthnBeginLogin(t *testing.T) {
+	svc := newTestWebauthnSvc()
+	svc.webauthnChallengeRepo.(*repo_mock.WebauthnChallengeRepoMock).
+		On("Create", mock.MatchedBy(func(c *models.WebauthnChallenge) bool {
+			return c.Ceremony == string(protocol.AssertCeremony) && c.UserID == nil
+		})).Return(nil)
+
+	options, err := svc.BeginLogin()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if options.RelyingPartyID != testRPID || options.UserVerification != protocol.VerificationRequired || len(options.AllowedCredentials) != 0 {
+		t.Errorf("unexpected options: %+v", options)
+	}
+	if len(options.Challenge) != 32 {
+		t.Errorf("unexpected challenge: %s", options.Challenge)
+	}
+}
+
+func TestWebauthnBeginLoginFail(t *testing.T) {
+	svc := newTestWebauthnSvc()
+	svc.webauthnChallengeRepo.(*repo_mock.WebauthnChallengeRepoMock).On("Create", mock.Anything).Return(fmt.Errorf("db error"))
+
+	if _, err := svc.BeginLogin(); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+func newTestRegisteredAuthenticator(signCount uint32) (*funcs.SoftwareAuthenticator, *models.UserCredential) {
+	authenticator := funcs.NewSoftwareAuthenticator(testRPID, testOrigin)
+	authenticator.SignCount = signCount
+	return authenticator, &models.UserCredential{
+		ID:           10,
+		UserID:       1,
+		CredentialID: base64.RawURLEncoding.EncodeToString(authenticator.CredentialID),
+		PublicKey:    authenticator.COSEPublicKey(),
+		Algorithm:    int64(webauthncose.AlgES256),
+		SignCount:    signCount,
+	}
+}
+
+func TestWebauthnFinishLogin(t *testing.T) {
+	for _, signCount := range []uint32{0, 5} {
+		t.Run(fmt.Sprintf("sign count %d", signCount), func(t *testing.T) {
+			authenticator, credential := newTestRegisteredAuthenticator(signCount)
+
+			svc := newTestWebauthnSvc()
+			challenge := expectChallenge(svc, string(protocol.AssertCeremony), nil)
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testWebauthnUser, nil)
+			credentialRepoMock := svc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock)
+			credentialRepoMock.On("GetByCredentialID", credential.CredentialID).Return(credential, nil)
+			credentialRepoMock.On("UpdateSignCount", uint(10), signCount+1).Return(nil)
+
+			clientDataJSON, authData, signature := authenticator.GetAssertion(testChallengeBytes(t, challenge))
+
+			user, err := svc.FinishLogin(WebauthnLoginInput{
+				CredentialID:      credential.CredentialID,
+				ClientDataJSON:    clientDataJSON,
+				AuthenticatorData: authData,
+				Signature:         signature,
+				UserHandle:        []byte("test-uuid"),
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			if user != testWebauthnUser {
+				t.Errorf("expected user %v, got %v", testWebauthnUser, user)
+			}
+			credentialRepoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWebauthnFinishLoginFail(t *testing.T) {
+	tests := []struct {
+		title   string
+		prepare func(a *funcs.SoftwareAuthenticator, c *models.UserCredential)
+		modify  func(input *WebauthnLoginInput)
+	}{
+		{title: "wrong origin", prepare: func(a *funcs.SoftwareAuthenticator, c *models.UserCredential) { a.Origin = "https://evil.example.com" }},
+		{title: "wrong rp id", prepare: func(a *funcs.SoftwareAuthenticator, c *models.UserCredential) { a.RPID = "evil.example.com" }},
+		{title: "user not verified", prepare: func(a *funcs.SoftwareAuthenticator, c *models.UserCredential) { a.NoUserVerification = true }},
+		{title: "sign count not increased", prepare: func(a *funcs.SoftwareAuthenticator, c *models.UserCredential) { c.SignCount = 100 }},
+		{title: "other public key", prepare: func(a *funcs.SoftwareAuthenticator, c *models.UserCredential) {
+			c.PublicKey = funcs.NewSoftwareAuthenticator(testRPID, testOrigin).COSEPublicKey()
+		}},
+		{title: "user handle mismatch", modify: func(input *WebauthnLoginInput) { input.UserHandle = []byte("other-uuid") }},
+		{title: "no user handle", modify: func(input *WebauthnLoginInput) { input.UserHandle = nil }},
+		{title: "broken signature", modify: func(input *WebauthnLoginInput) { input.Signature = []byte{0x30, 0x00} }},
+		{title: "broken authenticator data", modify: func(input *WebauthnLoginInput) { input.AuthenticatorData = input.AuthenticatorData[:10] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			authenticator, credential := newTestRegisteredAuthenticator(1)
+			if tt.prepare != nil {
+				tt.prepare(authenticator, credential)
+			}
+
+			svc := newTestWebauthnSvc()
+			challenge := expectChallenge(svc, string(protocol.AssertCeremony), nil)
+			svc.userRepo.(*repo_mock.UserRepoMock).On("GetByID", uint(1)).Return(testWebauthnUser, nil)
+			credentialRepoMock := svc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock)
+			credentialRepoMock.On("GetByCredentialID", credential.CredentialID).Return(credential, nil)
+
+			clientDataJSON, authData, signature := authenticator.GetAssertion(testChallengeBytes(t, challenge))
+			input := WebauthnLoginInput{
+				CredentialID:      credential.CredentialID,
+				ClientDataJSON:    clientDataJSON,
+				AuthenticatorData: authData,
+				Signature:         signature,
+				UserHandle:        []byte("test-uuid"),
+			}
+			if tt.modify != nil {
+				tt.modify(&input)
+			}
+
+			_, err := svc.FinishLogin(input)
+			if !errors.Is(err, ErrInvalidWebauthnResponse) {
+				t.Fatalf("expected ErrInvalidWebauthnResponse, got %v", err)
+			}
+			credentialRepoMock.AssertNotCalled(t, "UpdateSignCount", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestWebauthnFinishLoginUnknownCredential(t *testing.T) {
+	authenticator := funcs.NewSoftwareAuthenticator(testRPID, testOrigin)
+
+	svc := newTestWebauthnSvc()
+	challenge := expectChallenge(svc, string(protocol.AssertCeremony), nil)
+	svc.userCredentialRepo.(*repo_mock.UserCredentialRepoMock).
+		On("GetByCredentialID", "unknown").Return((*models.UserCredential)(nil), repositories.ErrCredentialNotFound)
+
+	clientDataJSON, authData, signature := authenticator.GetAssertion(testChallengeBytes(t, challenge))
+
+	_, err := svc.FinishLogin(WebauthnLoginInput{
+		CredentialID:      "unknown",
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        []byte("test-uuid"),
+	})
+	if !errors.Is(err, ErrInvalidWebauthnResponse) {
+		t.Fatalf("expected ErrInvalidWebauthnResponse, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/app"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/proxyproto"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabdatabase"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -62,5 +63,11 @@ func main() {
 	if port == "" {
 		port = "8080"
 	}
-	r.Run(":" + port)
+
+	// PROXY_PROTOCOL=true の場合はロードバランサーが付けたヘッダーから接続元を復元する
+	listener, err := proxyproto.Listen(":"+port, proxyproto.NewConfigFromEnv())
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.RunListener(listener)
 }
@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/url"
 	"os"
@@ -13,10 +17,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/test_helper/funcs"
 	"github.com/AtsuyaOotsuka/portfolio-go-lib/atylabcsrf"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -79,11 +85,11 @@ func createCsrf() string {
 	return nonce
 }
 
-func request(method string, url string, body io.Reader, t *testing.T) (*http.Response, func() error) {
+func request(method string, path string, body io.Reader, t *testing.T) (*http.Response, func() error) {
 	csrf := createCsrf()
 
 	client := &http.Client{}
-	requestUrl := baseURL + url
+	requestUrl := baseURL + path
 	fmt.Println("Request URL:", requestUrl)
 	req, err := http.NewRequest(method, requestUrl, body)
 	if method != "GET" && err == nil {
@@ -91,7 +97,9 @@ func request(method string, url string, body io.Reader, t *testing.T) (*http.Res
 	}
 	assert.NoError(t, err)
 	if method != "GET" {
+		// double-submit のため、ヘッダーと Cookie に同じトークンを載せる（Cookie は SetCookie と同じく URL エンコードする）
 		req.Header.Set("X-CSRF-Token", csrf)
+		req.AddCookie(&http.Cookie{Name: "csrf_token", Value: url.QueryEscape(csrf)})
 	}
 	resp, err := client.Do(req)
 	assert.NoError(t, err)
@@ -275,3 +283,141 @@ func TestCsrfGet(t *testing.T) {
 	)
 	assert.NoError(t, validErr)
 }
+
+func oidcRequest(method string, path string, body io.Reader, header http.Header, t *testing.T) map[string]interface{} {
+	req, err := http.NewRequest(method, baseURL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var respData map[string]interface{}
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(bodyBytes, &respData)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: unexpected status %d: %s", method, path, resp.StatusCode, bodyBytes)
+	}
+	return respData
+}
+
+// OpenID Connect の Basic OP（認可コードフロー）の主要な確認項目を再現する
+// Discovery → 認可 → トークン → ID トークンの検証 → UserInfo → リフレッシュ
+func TestOidcConformance(t *testing.T) {
+	usersData := funcs.FilterRecordsByTableName(dbRecords, "users")
+	email := usersData[0].Data[0]["email"].(string)
+	password := usersData[0].Data[0]["password"].(string)
+
+	client := &models.OauthClient{
+		ClientID:                "e2e-oidc-client",
+		ClientSecretHash:        models.HashOauthClientSecret("e2e-secret"),
+		Name:                    "e2e",
+		TokenEndpointAuthMethod: models.OauthClientAuthMethodClientSecretBasic,
+		RedirectURIs:            "https://client.example.com/callback",
+		GrantTypes:              "authorization_code refresh_token",
+		Scopes:                  "openid profile email",
+		// 同意画面を経由せずに認可コードを発行する
+		FirstParty: true,
+	}
+	assert.NoError(t, db.Create(client).Error)
+	t.Cleanup(func() { db.Delete(client) })
+
+	// Discovery: 必須の項目と、発行する ID トークンの iss と一致する issuer
+	discovery := oidcRequest("GET", "/.well-known/openid-configuration", nil, nil, t)
+	issuer := discovery["issuer"].(string)
+	for _, key := range []string{"authorization_endpoint", "token_endpoint", "userinfo_endpoint", "jwks_uri", "response_types_supported", "subject_types_supported", "id_token_signing_alg_values_supported"} {
+		assert.NotEmpty(t, discovery[key], key)
+	}
+	assert.True(t, strings.HasSuffix(discovery["jwks_uri"].(string), "/.well-known/jwks.json"))
+	jwks := oidcRequest("GET", "/.well-known/jwks.json", nil, nil, t)
+
+	// ログインしたユーザーで認可コードを発行する
+	loginBody, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	login := oidcRequest("POST", "/auth/login", strings.NewReader(string(loginBody)), http.Header{"Content-Type": {"application/json"}}, t)
+	loginToken := login["access_token"].(string)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	authorizeBody, _ := json.Marshal(map[string]string{
+		"response_type":         "code",
+		"client_id":             client.ClientID,
+		"redirect_uri":          "https://client.example.com/callback",
+		"scope":                 "openid profile email",
+		"state":                 "e2e-state",
+		"nonce":                 "e2e-nonce",
+		"code_challenge":        models.CreateOauthCodeChallenge(verifier),
+		"code_challenge_method": "S256",
+	})
+	authorize := oidcRequest("POST", "/oauth/authorize", strings.NewReader(string(authorizeBody)), http.Header{
+		"Content-Type":  {"application/json"},
+		"Authorization": {"Bearer " + loginToken},
+	}, t)
+	redirectTo, err := url.Parse(authorize["redirect_to"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, "e2e-state", redirectTo.Query().Get("state"))
+
+	tokenRequest := func(form url.Values) map[string]interface{} {
+		return oidcRequest("POST", "/oauth/token", strings.NewReader(form.Encode()), http.Header{
+			"Content-Type":  {"application/x-www-form-urlencoded"},
+			"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(client.ClientID+":e2e-secret"))},
+		}, t)
+	}
+	token := tokenRequest(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {redirectTo.Query().Get("code")},
+		"redirect_uri":  {"https://client.example.com/callback"},
+		"code_verifier": {verifier},
+	})
+	accessToken := token["access_token"].(string)
+	assert.Equal(t, "Bearer", token["token_type"])
+	assert.NotEmpty(t, token["refresh_token"])
+
+	// ID トークン: JWKS の鍵による RS256 の署名、iss / aud / nonce / at_hash / auth_time
+	idTokenClaims := verifyOidcIDToken(t, token["id_token"].(string), jwks)
+	assert.Equal(t, issuer, idTokenClaims["iss"])
+	assert.Equal(t, client.ClientID, idTokenClaims["aud"])
+	assert.Equal(t, "e2e-nonce", idTokenClaims["nonce"])
+	atHash := sha256.Sum256([]byte(accessToken))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(atHash[:16]), idTokenClaims["at_hash"])
+	assert.NotEmpty(t, idTokenClaims["auth_time"])
+
+	// UserInfo: ID トークンと同じ sub と、許可したスコープの項目
+	userinfo := oidcRequest("GET", "/userinfo", nil, http.Header{"Authorization": {"Bearer " + accessToken}}, t)
+	assert.Equal(t, idTokenClaims["sub"], userinfo["sub"])
+	assert.Equal(t, email, userinfo["email"])
+	assert.NotEmpty(t, userinfo["preferred_username"])
+
+	// リフレッシュ後も同じ sub の ID トークンを発行し、nonce は含めない
+	refreshed := tokenRequest(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token["refresh_token"].(string)},
+	})
+	refreshedClaims := verifyOidcIDToken(t, refreshed["id_token"].(string), jwks)
+	assert.Equal(t, idTokenClaims["sub"], refreshedClaims["sub"])
+	assert.NotContains(t, refreshedClaims, "nonce")
+}
+
+func verifyOidcIDToken(t *testing.T, idToken string, jwks map[string]interface{}) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		for _, key := range jwks["keys"].([]interface{}) {
+			jwk := key.(map[string]interface{})
+			if jwk["kid"] != token.Header["kid"] {
+				continue
+			}
+			n, _ := base64.RawURLEncoding.DecodeString(jwk["n"].(string))
+			e, _ := base64.RawURLEncoding.DecodeString(jwk["e"].(string))
+			return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+		}
+		return nil, fmt.Errorf("unknown kid: %v", token.Header["kid"])
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	if err != nil {
+		t.Fatalf("failed to verify id token: %v", err)
+	}
+	return claims
+}
@@ -19,6 +19,11 @@ func truncateTable(db *sql.DB, tableName string) error {
 func DbCleanup(db *sql.DB) ([]DbRecords, error) {
 	truncateTable(db, "users")
 	truncateTable(db, "user_refresh_tokens")
+	truncateTable(db, "user_totps")
+	truncateTable(db, "user_recovery_codes")
+	truncateTable(db, "user_credentials")
+	truncateTable(db, "webauthn_challenges")
+	truncateTable(db, "passwordless_tokens")
 	dbRecords, err := CreateSeeders(db)
 	if err != nil {
 		return nil, err
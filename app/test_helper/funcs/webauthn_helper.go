@@ -0,0 +1,204 @@
+package funcs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// 同じ鍵から常に同じバイト列になるよう、キーを並べ替えてエンコードする
+var cborEncMode, _ = cbor.CTAP2EncOptions().EncMode()
+
+// テスト用のソフトウェア認証器
+// 実機の代わりに登録（attestation）・認証（assertion）レスポンスを生成する
+type SoftwareAuthenticator struct {
+	RPID         string
+	Origin       string
+	CredentialID []byte
+	UserHandle   []byte
+	SignCount    uint32
+	// true の場合 UV フラグを立てない（PIN・生体認証なし）
+	NoUserVerification bool
+	// 設定すると packed attestation で x5c を付与する
+	AttestationKey  *ecdsa.PrivateKey
+	AttestationCert []byte
+
+	ecKey  *ecdsa.PrivateKey
+	rsaKey *rsa.PrivateKey
+}
+
+func NewSoftwareAuthenticator(rpID string, origin string) *SoftwareAuthenticator {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return &SoftwareAuthenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: randomBytes(32),
+		ecKey:        key,
+	}
+}
+
+func NewSoftwareAuthenticatorRS256(rpID string, origin string) *SoftwareAuthenticator {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	return &SoftwareAuthenticator{
+		RPID:         rpID,
+		Origin:       origin,
+		CredentialID: randomBytes(32),
+		rsaKey:       key,
+	}
+}
+
+// packed attestation 用の自己発行証明書を設定する
+func (a *SoftwareAuthenticator) UseAttestationCertificate() {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"JP"},
+			Organization:       []string{"Test Authenticator"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Test Authenticator",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  false,
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	a.AttestationKey = key
+	a.AttestationCert = der
+}
+
+func (a *SoftwareAuthenticator) COSEPublicKey() []byte {
+	var key map[any]any
+	if a.rsaKey != nil {
+		key = map[any]any{
+			1:  3,
+			3:  -257,
+			-1: a.rsaKey.N.Bytes(),
+			-2: big.NewInt(int64(a.rsaKey.E)).Bytes(),
+		}
+	} else {
+		key = map[any]any{
+			1:  2,
+			3:  -7,
+			-1: 1,
+			-2: a.ecKey.X.FillBytes(make([]byte, 32)),
+			-3: a.ecKey.Y.FillBytes(make([]byte, 32)),
+		}
+	}
+	b, _ := cborEncMode.Marshal(key)
+	return b
+}
+
+func (a *SoftwareAuthenticator) alg() int64 {
+	if a.rsaKey != nil {
+		return -257
+	}
+	return -7
+}
+
+func (a *SoftwareAuthenticator) sign(data []byte) []byte {
+	digest := sha256.Sum256(data)
+	if a.rsaKey != nil {
+		sig, _ := rsa.SignPKCS1v15(rand.Reader, a.rsaKey, crypto.SHA256, digest[:])
+		return sig
+	}
+	sig, _ := ecdsa.SignASN1(rand.Reader, a.ecKey, digest[:])
+	return sig
+}
+
+func (a *SoftwareAuthenticator) clientDataJSON(ceremony string, challenge []byte) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return b
+}
+
+func (a *SoftwareAuthenticator) authenticatorData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := byte(0x01)
+	if !a.NoUserVerification {
+		flags |= 0x04
+	}
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.CredentialID)))
+		data = append(data, a.CredentialID...)
+		data = append(data, a.COSEPublicKey()...)
+	}
+	return data
+}
+
+// 登録レスポンス（clientDataJSON, attestationObject）を生成する
+// format は "none" または "packed"
+func (a *SoftwareAuthenticator) CreateCredential(challenge []byte, userHandle []byte, format string) ([]byte, []byte) {
+	a.UserHandle = userHandle
+	clientDataJSON := a.clientDataJSON("webauthn.create", challenge)
+	authData := a.authenticatorData(true)
+
+	attStmt := map[any]any{}
+	if format == "packed" {
+		clientDataHash := sha256.Sum256(clientDataJSON)
+		signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+		if a.AttestationKey != nil {
+			digest := sha256.Sum256(signed)
+			sig, _ := ecdsa.SignASN1(rand.Reader, a.AttestationKey, digest[:])
+			attStmt = map[any]any{
+				"alg": -7,
+				"sig": sig,
+				"x5c": []any{a.AttestationCert},
+			}
+		} else {
+			attStmt = map[any]any{
+				"alg": a.alg(),
+				"sig": a.sign(signed),
+			}
+		}
+	}
+
+	attestationObject, _ := cborEncMode.Marshal(map[any]any{
+		"fmt":      format,
+		"attStmt":  attStmt,
+		"authData": authData,
+	})
+	return clientDataJSON, attestationObject
+}
+
+// 認証レスポンス（clientDataJSON, authenticatorData, signature）を生成する
+func (a *SoftwareAuthenticator) GetAssertion(challenge []byte) ([]byte, []byte, []byte) {
+	a.SignCount++
+	clientDataJSON := a.clientDataJSON("webauthn.get", challenge)
+	authData := a.authenticatorData(false)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	return clientDataJSON, authData, a.sign(signed)
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.Read(b)
+	return b
+}
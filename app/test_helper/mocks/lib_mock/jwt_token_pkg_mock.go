@@ -0,0 +1,34 @@
+package lib_mock
+
+import (
+	"crypto/rsa"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/mock"
+)
+
+type JwtTokenPkgMock struct {
+	mock.Mock
+}
+
+func (m *JwtTokenPkgMock) Sign(claims jwt.MapClaims, key []byte) (string, error) {
+	args := m.Called(claims, key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *JwtTokenPkgMock) SignRS256(claims jwt.MapClaims, key *rsa.PrivateKey, kid string) (string, error) {
+	args := m.Called(claims, key, kid)
+	return args.String(0), args.Error(1)
+}
+
+func (m *JwtTokenPkgMock) Parse(token string, key []byte) (jwt.MapClaims, error) {
+	args := m.Called(token, key)
+	claims, _ := args.Get(0).(jwt.MapClaims)
+	return claims, args.Error(1)
+}
+
+func (m *JwtTokenPkgMock) ParseWithPublicKey(token string, publicKeyPEM string) (jwt.MapClaims, error) {
+	args := m.Called(token, publicKeyPEM)
+	claims, _ := args.Get(0).(jwt.MapClaims)
+	return claims, args.Error(1)
+}
@@ -0,0 +1,15 @@
+package lib_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/lib/mailer"
+	"github.com/stretchr/testify/mock"
+)
+
+type MailerPkgMock struct {
+	mock.Mock
+}
+
+func (m *MailerPkgMock) Send(message mailer.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
@@ -0,0 +1,15 @@
+package lib_mock
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type QrcodePkgMock struct {
+	mock.Mock
+}
+
+func (m *QrcodePkgMock) EncodePNG(content string, scale int) ([]byte, error) {
+	args := m.Called(content, scale)
+	b, _ := args.Get(0).([]byte)
+	return b, args.Error(1)
+}
@@ -0,0 +1,36 @@
+package lib_mock
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type TotpPkgMock struct {
+	mock.Mock
+}
+
+func (m *TotpPkgMock) GenerateSecret() (string, error) {
+	args := m.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (m *TotpPkgMock) Code(secret string, step int64) (string, error) {
+	args := m.Called(secret, step)
+	return args.String(0), args.Error(1)
+}
+
+func (m *TotpPkgMock) Validate(secret string, code string, now time.Time, skew int64) (int64, bool) {
+	args := m.Called(secret, code, now, skew)
+	return args.Get(0).(int64), args.Bool(1)
+}
+
+func (m *TotpPkgMock) Step(now time.Time) int64 {
+	args := m.Called(now)
+	return args.Get(0).(int64)
+}
+
+func (m *TotpPkgMock) URI(issuer string, account string, secret string) (string, error) {
+	args := m.Called(issuer, account, secret)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,14 @@
+package repo_mock
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type BreachedPasswordRepoMock struct {
+	mock.Mock
+}
+
+func (m *BreachedPasswordRepoMock) IsBreached(password string) (bool, error) {
+	args := m.Called(password)
+	return args.Bool(0), args.Error(1)
+}
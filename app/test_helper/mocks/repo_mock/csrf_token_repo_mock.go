@@ -0,0 +1,20 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type CsrfTokenRepoMock struct {
+	mock.Mock
+}
+
+func (m *CsrfTokenRepoMock) Create(token *models.CsrfToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *CsrfTokenRepoMock) Consume(tokenHash string) error {
+	args := m.Called(tokenHash)
+	return args.Error(0)
+}
@@ -0,0 +1,25 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/repositories"
+	"github.com/stretchr/testify/mock"
+)
+
+// 渡したユーザーを UUID で引けるようにし、それ以外は見つからないものとして扱う
+func (r *UserRepoMock) OnGetByUUID(users ...*models.User) *UserRepoMock {
+	for _, user := range users {
+		r.On("GetByUUID", user.UUID).Return(user, nil).Maybe()
+	}
+	r.On("GetByUUID", mock.Anything).Return((*models.User)(nil), repositories.ErrUserNotFound).Maybe()
+	return r
+}
+
+// 渡したクライアントを client_id で引けるようにし、それ以外は見つからないものとして扱う
+func (m *OauthClientRepoMock) OnGetByClientID(clients ...*models.OauthClient) *OauthClientRepoMock {
+	for _, client := range clients {
+		m.On("GetByClientID", client.ClientID).Return(client, nil).Maybe()
+	}
+	m.On("GetByClientID", mock.Anything).Return((*models.OauthClient)(nil), repositories.ErrOauthClientNotFound).Maybe()
+	return m
+}
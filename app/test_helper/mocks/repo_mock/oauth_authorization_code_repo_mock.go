@@ -0,0 +1,20 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type OauthAuthorizationCodeRepoMock struct {
+	mock.Mock
+}
+
+func (m *OauthAuthorizationCodeRepoMock) Create(code *models.OauthAuthorizationCode) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *OauthAuthorizationCodeRepoMock) ConsumeByCodeHash(codeHash string) (*models.OauthAuthorizationCode, error) {
+	args := m.Called(codeHash)
+	return args.Get(0).(*models.OauthAuthorizationCode), args.Error(1)
+}
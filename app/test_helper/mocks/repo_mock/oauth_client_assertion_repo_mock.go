@@ -0,0 +1,16 @@
+package repo_mock
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type OauthClientAssertionRepoMock struct {
+	mock.Mock
+}
+
+func (m *OauthClientAssertionRepoMock) Register(clientId string, jti string, expiresAt time.Time) error {
+	args := m.Called(clientId, jti, expiresAt)
+	return args.Error(0)
+}
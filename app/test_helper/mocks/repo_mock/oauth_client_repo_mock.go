@@ -0,0 +1,35 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type OauthClientRepoMock struct {
+	mock.Mock
+}
+
+func (m *OauthClientRepoMock) Create(client *models.OauthClient) error {
+	args := m.Called(client)
+	return args.Error(0)
+}
+
+func (m *OauthClientRepoMock) GetByClientID(clientId string) (*models.OauthClient, error) {
+	args := m.Called(clientId)
+	return args.Get(0).(*models.OauthClient), args.Error(1)
+}
+
+func (m *OauthClientRepoMock) List() ([]models.OauthClient, error) {
+	args := m.Called()
+	return args.Get(0).([]models.OauthClient), args.Error(1)
+}
+
+func (m *OauthClientRepoMock) Update(client *models.OauthClient) error {
+	args := m.Called(client)
+	return args.Error(0)
+}
+
+func (m *OauthClientRepoMock) Delete(clientId string) error {
+	args := m.Called(clientId)
+	return args.Error(0)
+}
@@ -0,0 +1,42 @@
+package repo_mock
+
+import (
+	"time"
+
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type OauthDeviceCodeRepoMock struct {
+	mock.Mock
+}
+
+func (m *OauthDeviceCodeRepoMock) Create(code *models.OauthDeviceCode) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *OauthDeviceCodeRepoMock) GetPendingByUserCodeHash(userCodeHash string) (*models.OauthDeviceCode, error) {
+	args := m.Called(userCodeHash)
+	return args.Get(0).(*models.OauthDeviceCode), args.Error(1)
+}
+
+func (m *OauthDeviceCodeRepoMock) GetByDeviceCodeHash(deviceCodeHash string) (*models.OauthDeviceCode, error) {
+	args := m.Called(deviceCodeHash)
+	return args.Get(0).(*models.OauthDeviceCode), args.Error(1)
+}
+
+func (m *OauthDeviceCodeRepoMock) Decide(code *models.OauthDeviceCode) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *OauthDeviceCodeRepoMock) UpdatePolling(id uint, lastPolledAt time.Time, pollInterval int) error {
+	args := m.Called(id, lastPolledAt, pollInterval)
+	return args.Error(0)
+}
+
+func (m *OauthDeviceCodeRepoMock) Consume(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,15 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type OauthTokenExchangeRepoMock struct {
+	mock.Mock
+}
+
+func (m *OauthTokenExchangeRepoMock) Create(exchange *models.OauthTokenExchange) error {
+	args := m.Called(exchange)
+	return args.Error(0)
+}
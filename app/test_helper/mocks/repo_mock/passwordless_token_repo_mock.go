@@ -0,0 +1,40 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordlessTokenRepoMock struct {
+	mock.Mock
+}
+
+func (m *PasswordlessTokenRepoMock) Create(token *models.PasswordlessToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *PasswordlessTokenRepoMock) GetLatestByUserID(userId uint) (*models.PasswordlessToken, error) {
+	args := m.Called(userId)
+	return args.Get(0).(*models.PasswordlessToken), args.Error(1)
+}
+
+func (m *PasswordlessTokenRepoMock) InvalidateByUserID(userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
+
+func (m *PasswordlessTokenRepoMock) RegisterAttempt(id uint, maxAttempts int) error {
+	args := m.Called(id, maxAttempts)
+	return args.Error(0)
+}
+
+func (m *PasswordlessTokenRepoMock) Consume(id uint) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *PasswordlessTokenRepoMock) ConsumeByTokenHash(tokenHash string, method string) (*models.PasswordlessToken, error) {
+	args := m.Called(tokenHash, method)
+	return args.Get(0).(*models.PasswordlessToken), args.Error(1)
+}
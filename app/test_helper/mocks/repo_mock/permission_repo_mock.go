@@ -0,0 +1,20 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type PermissionRepoMock struct {
+	mock.Mock
+}
+
+func (m *PermissionRepoMock) List() ([]models.Permission, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
+
+func (m *PermissionRepoMock) FindOrCreate(names []string) ([]models.Permission, error) {
+	args := m.Called(names)
+	return args.Get(0).([]models.Permission), args.Error(1)
+}
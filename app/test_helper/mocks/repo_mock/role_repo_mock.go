@@ -0,0 +1,35 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type RoleRepoMock struct {
+	mock.Mock
+}
+
+func (m *RoleRepoMock) List() ([]models.Role, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *RoleRepoMock) GetByName(name string) (*models.Role, error) {
+	args := m.Called(name)
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *RoleRepoMock) Create(role *models.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *RoleRepoMock) Update(role *models.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *RoleRepoMock) Delete(name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
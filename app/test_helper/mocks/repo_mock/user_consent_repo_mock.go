@@ -0,0 +1,30 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type UserConsentRepoMock struct {
+	mock.Mock
+}
+
+func (m *UserConsentRepoMock) Get(userID uint, clientID string) (*models.UserConsent, error) {
+	args := m.Called(userID, clientID)
+	return args.Get(0).(*models.UserConsent), args.Error(1)
+}
+
+func (m *UserConsentRepoMock) ListByUserID(userID uint) ([]models.UserConsent, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.UserConsent), args.Error(1)
+}
+
+func (m *UserConsentRepoMock) Save(consent *models.UserConsent) error {
+	args := m.Called(consent)
+	return args.Error(0)
+}
+
+func (m *UserConsentRepoMock) Delete(userID uint, clientID string) error {
+	args := m.Called(userID, clientID)
+	return args.Error(0)
+}
@@ -0,0 +1,31 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type UserCredentialRepoMock struct {
+	mock.Mock
+}
+
+func (m *UserCredentialRepoMock) Create(credential *models.UserCredential) error {
+	args := m.Called(credential)
+	return args.Error(0)
+}
+
+func (m *UserCredentialRepoMock) GetByCredentialID(credentialId string) (*models.UserCredential, error) {
+	args := m.Called(credentialId)
+	return args.Get(0).(*models.UserCredential), args.Error(1)
+}
+
+func (m *UserCredentialRepoMock) ListByUserID(userId uint) ([]models.UserCredential, error) {
+	args := m.Called(userId)
+	credentials, _ := args.Get(0).([]models.UserCredential)
+	return credentials, args.Error(1)
+}
+
+func (m *UserCredentialRepoMock) UpdateSignCount(id uint, signCount uint32) error {
+	args := m.Called(id, signCount)
+	return args.Error(0)
+}
@@ -0,0 +1,24 @@
+package repo_mock
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type UserRecoveryCodeRepoMock struct {
+	mock.Mock
+}
+
+func (m *UserRecoveryCodeRepoMock) ReplaceAll(userId uint, codeHashes []string) error {
+	args := m.Called(userId, codeHashes)
+	return args.Error(0)
+}
+
+func (m *UserRecoveryCodeRepoMock) Use(userId uint, codeHash string) error {
+	args := m.Called(userId, codeHash)
+	return args.Error(0)
+}
+
+func (m *UserRecoveryCodeRepoMock) DeleteByUserID(userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
@@ -9,17 +9,22 @@ type UserRefreshTokenRepoMock struct {
 	mock.Mock
 }
 
-func (m *UserRefreshTokenRepoMock) CreateRefreshToken(userId uint) (*models.UserRefreshToken, error) {
-	args := m.Called(userId)
+func (m *UserRefreshTokenRepoMock) CreateRefreshToken(token *models.UserRefreshToken) (*models.UserRefreshToken, error) {
+	args := m.Called(token)
 	return args.Get(0).(*models.UserRefreshToken), args.Error(1)
 }
 
-func (m *UserRefreshTokenRepoMock) GetUserByRefreshToken(refreshToken string) (*models.User, error) {
+func (m *UserRefreshTokenRepoMock) GetUserByRefreshToken(refreshToken string) (*models.User, *models.UserRefreshToken, error) {
 	args := m.Called(refreshToken)
-	return args.Get(0).(*models.User), args.Error(1)
+	return args.Get(0).(*models.User), args.Get(1).(*models.UserRefreshToken), args.Error(2)
 }
 
 func (m *UserRefreshTokenRepoMock) ChangeUsed(refreshToken string, ipAddress string) error {
 	args := m.Called(refreshToken, ipAddress)
 	return args.Error(0)
 }
+
+func (m *UserRefreshTokenRepoMock) RevokeAllByUserID(userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
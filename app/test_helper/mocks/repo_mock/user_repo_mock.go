@@ -18,3 +18,33 @@ func (r *UserRepoMock) Create(user *models.User) error {
 	args := r.Called(user)
 	return args.Error(0)
 }
+
+func (r *UserRepoMock) GetByUUID(uuid string) (*models.User, error) {
+	args := r.Called(uuid)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (r *UserRepoMock) GetByID(id uint) (*models.User, error) {
+	args := r.Called(id)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (r *UserRepoMock) UpdatePassword(id uint, passwordHash string) error {
+	args := r.Called(id, passwordHash)
+	return args.Error(0)
+}
+
+func (r *UserRepoMock) UpdateEmail(id uint, email string) error {
+	args := r.Called(id, email)
+	return args.Error(0)
+}
+
+func (r *UserRepoMock) UpdateLocale(id uint, locale string) error {
+	args := r.Called(id, locale)
+	return args.Error(0)
+}
+
+func (r *UserRepoMock) Delete(id uint) error {
+	args := r.Called(id)
+	return args.Error(0)
+}
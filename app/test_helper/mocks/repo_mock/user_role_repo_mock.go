@@ -0,0 +1,25 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type UserRoleRepoMock struct {
+	mock.Mock
+}
+
+func (m *UserRoleRepoMock) ListRoles(userID uint) ([]models.Role, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *UserRoleRepoMock) Assign(userID uint, roleID uint) error {
+	args := m.Called(userID, roleID)
+	return args.Error(0)
+}
+
+func (m *UserRoleRepoMock) Unassign(userID uint, roleID uint) error {
+	args := m.Called(userID, roleID)
+	return args.Error(0)
+}
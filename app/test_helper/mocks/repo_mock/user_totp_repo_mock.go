@@ -0,0 +1,35 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type UserTotpRepoMock struct {
+	mock.Mock
+}
+
+func (m *UserTotpRepoMock) GetByUserID(userId uint) (*models.UserTotp, error) {
+	args := m.Called(userId)
+	return args.Get(0).(*models.UserTotp), args.Error(1)
+}
+
+func (m *UserTotpRepoMock) Save(totp *models.UserTotp) error {
+	args := m.Called(totp)
+	return args.Error(0)
+}
+
+func (m *UserTotpRepoMock) Enable(id uint, step int64) error {
+	args := m.Called(id, step)
+	return args.Error(0)
+}
+
+func (m *UserTotpRepoMock) MarkStepUsed(id uint, step int64) error {
+	args := m.Called(id, step)
+	return args.Error(0)
+}
+
+func (m *UserTotpRepoMock) DeleteByUserID(userId uint) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
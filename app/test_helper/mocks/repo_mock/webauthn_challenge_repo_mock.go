@@ -0,0 +1,20 @@
+package repo_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/stretchr/testify/mock"
+)
+
+type WebauthnChallengeRepoMock struct {
+	mock.Mock
+}
+
+func (m *WebauthnChallengeRepoMock) Create(challenge *models.WebauthnChallenge) error {
+	args := m.Called(challenge)
+	return args.Error(0)
+}
+
+func (m *WebauthnChallengeRepoMock) Consume(challengeHash string, ceremony string) (*models.WebauthnChallenge, error) {
+	args := m.Called(challengeHash, ceremony)
+	return args.Get(0).(*models.WebauthnChallenge), args.Error(1)
+}
@@ -0,0 +1,31 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type AccountSvcMock struct {
+	mock.Mock
+}
+
+func (m *AccountSvcMock) ChangePassword(input service.ChangePasswordInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *AccountSvcMock) ChangeEmail(input service.ChangeEmailInput) (*models.User, error) {
+	args := m.Called(input)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *AccountSvcMock) ChangeLocale(input service.ChangeLocaleInput) (*models.User, error) {
+	args := m.Called(input)
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *AccountSvcMock) DeleteAccount(userUUID string) error {
+	args := m.Called(userUUID)
+	return args.Error(0)
+}
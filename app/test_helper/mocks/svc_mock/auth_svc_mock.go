@@ -1,6 +1,7 @@
 package svc_mock
 
 import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
 	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,3 +19,43 @@ func (m *AuthSvcMock) Refresh(input service.RefreshInput) (*service.AuthOutput,
 	args := m.Called(input)
 	return args.Get(0).(*service.AuthOutput), args.Error(1)
 }
+
+func (m *AuthSvcMock) VerifyMfa(input service.VerifyMfaInput) (*service.AuthOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
+
+func (m *AuthSvcMock) LoginWithPasskey(input service.WebauthnLoginInput) (*service.AuthOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
+
+func (m *AuthSvcMock) CompletePasswordless(input service.PasswordlessCompleteInput) (*service.AuthOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
+
+func (m *AuthSvcMock) ExchangeAuthorizationCode(client *models.OauthClient, input service.OauthTokenInput) (*service.AuthOutput, error) {
+	args := m.Called(client, input)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
+
+func (m *AuthSvcMock) RefreshForClient(client *models.OauthClient, input service.RefreshInput) (*service.AuthOutput, error) {
+	args := m.Called(client, input)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
+
+func (m *AuthSvcMock) ExchangeDeviceCode(client *models.OauthClient, deviceCode string) (*service.AuthOutput, error) {
+	args := m.Called(client, deviceCode)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
+
+func (m *AuthSvcMock) ExchangeToken(client *models.OauthClient, input service.OauthTokenExchangeInput) (*service.AuthOutput, error) {
+	args := m.Called(client, input)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
+
+func (m *AuthSvcMock) IssueClientCredentials(client *models.OauthClient, input service.OauthClientCredentialsInput) (*service.AuthOutput, error) {
+	args := m.Called(client, input)
+	return args.Get(0).(*service.AuthOutput), args.Error(1)
+}
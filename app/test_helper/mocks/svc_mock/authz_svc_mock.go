@@ -0,0 +1,20 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type AuthzSvcMock struct {
+	mock.Mock
+}
+
+func (m *AuthzSvcMock) Check(request service.AuthzRequest) (*service.AuthzDecision, error) {
+	args := m.Called(request)
+	return args.Get(0).(*service.AuthzDecision), args.Error(1)
+}
+
+func (m *AuthzSvcMock) CheckBatch(requests []service.AuthzRequest) ([]service.AuthzDecision, error) {
+	args := m.Called(requests)
+	return args.Get(0).([]service.AuthzDecision), args.Error(1)
+}
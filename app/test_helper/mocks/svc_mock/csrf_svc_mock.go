@@ -11,16 +11,28 @@ type CsrfSvcMockStruct struct {
 func (m *CsrfSvcMockStruct) CreateCSRFToken(
 	timestamp int64,
 	secret string,
-) string {
-	args := m.Called(timestamp, secret)
-	return args.String(0)
+	sessionID string,
+) (string, error) {
+	args := m.Called(timestamp, secret, sessionID)
+	return args.String(0), args.Error(1)
 }
 
 func (m *CsrfSvcMockStruct) Verify(
 	token string,
 	secret string,
 	timestamp int64,
+	sessionID string,
 ) error {
-	args := m.Called(token, secret, timestamp)
+	args := m.Called(token, secret, timestamp, sessionID)
 	return args.Error(0)
 }
+
+func (m *CsrfSvcMockStruct) SessionID(accessToken string, sessionCookie string) string {
+	args := m.Called(accessToken, sessionCookie)
+	return args.String(0)
+}
+
+func (m *CsrfSvcMockStruct) SessionCookie(sessionID string) string {
+	args := m.Called(sessionID)
+	return args.String(0)
+}
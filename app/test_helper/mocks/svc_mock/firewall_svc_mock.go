@@ -0,0 +1,14 @@
+package svc_mock
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type FirewallSvcMock struct {
+	mock.Mock
+}
+
+func (m *FirewallSvcMock) Allowed(group string, clientIP string) bool {
+	args := m.Called(group, clientIP)
+	return args.Bool(0)
+}
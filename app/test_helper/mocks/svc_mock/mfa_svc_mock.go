@@ -0,0 +1,43 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type MfaSvcMock struct {
+	mock.Mock
+}
+
+func (m *MfaSvcMock) IsEnabled(userId uint) (bool, error) {
+	args := m.Called(userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MfaSvcMock) EnrollTotp(userUUID string) (*service.TotpEnrollOutput, error) {
+	args := m.Called(userUUID)
+	return args.Get(0).(*service.TotpEnrollOutput), args.Error(1)
+}
+
+func (m *MfaSvcMock) ConfirmTotp(input service.TotpCodeInput) ([]string, error) {
+	args := m.Called(input)
+	codes, _ := args.Get(0).([]string)
+	return codes, args.Error(1)
+}
+
+func (m *MfaSvcMock) DisableTotp(input service.TotpCodeInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MfaSvcMock) CreateChallenge(user *models.User, authContext service.AuthContext) (string, error) {
+	args := m.Called(user, authContext)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MfaSvcMock) VerifyChallenge(input service.VerifyMfaInput) (*models.User, service.AuthContext, error) {
+	args := m.Called(input)
+	authContext, _ := args.Get(1).(service.AuthContext)
+	return args.Get(0).(*models.User), authContext, args.Error(2)
+}
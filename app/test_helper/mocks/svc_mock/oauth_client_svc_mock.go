@@ -0,0 +1,41 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type OauthClientSvcMock struct {
+	mock.Mock
+}
+
+func (m *OauthClientSvcMock) Create(input service.OauthClientInput) (*service.OauthClientOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*service.OauthClientOutput), args.Error(1)
+}
+
+func (m *OauthClientSvcMock) List() ([]models.OauthClient, error) {
+	args := m.Called()
+	return args.Get(0).([]models.OauthClient), args.Error(1)
+}
+
+func (m *OauthClientSvcMock) Get(clientID string) (*models.OauthClient, error) {
+	args := m.Called(clientID)
+	return args.Get(0).(*models.OauthClient), args.Error(1)
+}
+
+func (m *OauthClientSvcMock) Update(clientID string, input service.OauthClientInput) (*service.OauthClientOutput, error) {
+	args := m.Called(clientID, input)
+	return args.Get(0).(*service.OauthClientOutput), args.Error(1)
+}
+
+func (m *OauthClientSvcMock) Delete(clientID string) error {
+	args := m.Called(clientID)
+	return args.Error(0)
+}
+
+func (m *OauthClientSvcMock) RotateSecret(clientID string) (*service.OauthClientOutput, error) {
+	args := m.Called(clientID)
+	return args.Get(0).(*service.OauthClientOutput), args.Error(1)
+}
@@ -0,0 +1,81 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type OauthSvcMock struct {
+	mock.Mock
+}
+
+func (m *OauthSvcMock) BeginAuthorize(input service.OauthAuthorizeInput) (string, error) {
+	args := m.Called(input)
+	return args.String(0), args.Error(1)
+}
+
+func (m *OauthSvcMock) Authorize(userUUID string, authContext service.AuthContext, input service.OauthAuthorizeInput) (string, error) {
+	args := m.Called(userUUID, authContext, input)
+	return args.String(0), args.Error(1)
+}
+
+func (m *OauthSvcMock) AuthenticateClient(input service.OauthClientAuthInput) (*models.OauthClient, error) {
+	args := m.Called(input)
+	return args.Get(0).(*models.OauthClient), args.Error(1)
+}
+
+func (m *OauthSvcMock) ConsumeAuthorizationCode(client *models.OauthClient, input service.OauthTokenInput) (*models.OauthAuthorizationCode, *models.User, error) {
+	args := m.Called(client, input)
+	return args.Get(0).(*models.OauthAuthorizationCode), args.Get(1).(*models.User), args.Error(2)
+}
+
+func (m *OauthSvcMock) AuthorizeDevice(client *models.OauthClient, input service.OauthDeviceAuthorizationInput) (*service.OauthDeviceAuthorizationOutput, error) {
+	args := m.Called(client, input)
+	return args.Get(0).(*service.OauthDeviceAuthorizationOutput), args.Error(1)
+}
+
+func (m *OauthSvcMock) GetDeviceAuthorization(userCode string) (*service.OauthDeviceVerification, error) {
+	args := m.Called(userCode)
+	return args.Get(0).(*service.OauthDeviceVerification), args.Error(1)
+}
+
+func (m *OauthSvcMock) DecideDeviceAuthorization(userUUID string, authContext service.AuthContext, input service.OauthDeviceDecisionInput) error {
+	args := m.Called(userUUID, authContext, input)
+	return args.Error(0)
+}
+
+func (m *OauthSvcMock) ConsumeDeviceCode(client *models.OauthClient, deviceCode string) (*models.OauthDeviceCode, *models.User, error) {
+	args := m.Called(client, deviceCode)
+	return args.Get(0).(*models.OauthDeviceCode), args.Get(1).(*models.User), args.Error(2)
+}
+
+func (m *OauthSvcMock) ValidateTokenExchange(client *models.OauthClient, input service.OauthTokenExchangeInput) (*service.OauthTokenExchangeGrant, error) {
+	args := m.Called(client, input)
+	return args.Get(0).(*service.OauthTokenExchangeGrant), args.Error(1)
+}
+
+func (m *OauthSvcMock) RecordTokenExchange(exchange *models.OauthTokenExchange) error {
+	args := m.Called(exchange)
+	return args.Error(0)
+}
+
+func (m *OauthSvcMock) GetConsent(challenge string) (*service.OauthConsentRequest, error) {
+	args := m.Called(challenge)
+	return args.Get(0).(*service.OauthConsentRequest), args.Error(1)
+}
+
+func (m *OauthSvcMock) DecideConsent(challenge string, approve bool) (string, error) {
+	args := m.Called(challenge, approve)
+	return args.String(0), args.Error(1)
+}
+
+func (m *OauthSvcMock) ListConsents(userUUID string) ([]service.OauthConsent, error) {
+	args := m.Called(userUUID)
+	return args.Get(0).([]service.OauthConsent), args.Error(1)
+}
+
+func (m *OauthSvcMock) RevokeConsent(userUUID string, clientID string) error {
+	args := m.Called(userUUID, clientID)
+	return args.Error(0)
+}
@@ -0,0 +1,31 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type OidcSvcMock struct {
+	mock.Mock
+}
+
+func (m *OidcSvcMock) Discovery() service.OidcDiscovery {
+	args := m.Called()
+	return args.Get(0).(service.OidcDiscovery)
+}
+
+func (m *OidcSvcMock) Jwks() service.OidcJwks {
+	args := m.Called()
+	return args.Get(0).(service.OidcJwks)
+}
+
+func (m *OidcSvcMock) CreateIDToken(user *models.User, client *models.OauthClient, authContext service.AuthContext, accessToken string) (string, error) {
+	args := m.Called(user, client, authContext, accessToken)
+	return args.String(0), args.Error(1)
+}
+
+func (m *OidcSvcMock) UserInfo(userUUID string, scope string) (*service.OidcUserInfo, error) {
+	args := m.Called(userUUID, scope)
+	return args.Get(0).(*service.OidcUserInfo), args.Error(1)
+}
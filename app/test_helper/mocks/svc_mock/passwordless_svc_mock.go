@@ -0,0 +1,21 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type PasswordlessSvcMock struct {
+	mock.Mock
+}
+
+func (m *PasswordlessSvcMock) Start(input service.PasswordlessStartInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *PasswordlessSvcMock) Verify(input service.PasswordlessCompleteInput) (*models.User, error) {
+	args := m.Called(input)
+	return args.Get(0).(*models.User), args.Error(1)
+}
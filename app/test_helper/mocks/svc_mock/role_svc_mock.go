@@ -0,0 +1,56 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type RoleSvcMock struct {
+	mock.Mock
+}
+
+func (m *RoleSvcMock) List() ([]models.Role, error) {
+	args := m.Called()
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *RoleSvcMock) Get(name string) (*models.Role, error) {
+	args := m.Called(name)
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *RoleSvcMock) Create(name string, input service.RoleInput) (*models.Role, error) {
+	args := m.Called(name, input)
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *RoleSvcMock) Update(name string, input service.RoleInput) (*models.Role, error) {
+	args := m.Called(name, input)
+	return args.Get(0).(*models.Role), args.Error(1)
+}
+
+func (m *RoleSvcMock) Delete(name string) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+func (m *RoleSvcMock) ListUserRoles(userUUID string) ([]models.Role, error) {
+	args := m.Called(userUUID)
+	return args.Get(0).([]models.Role), args.Error(1)
+}
+
+func (m *RoleSvcMock) AssignUserRole(userUUID string, roleName string) error {
+	args := m.Called(userUUID, roleName)
+	return args.Error(0)
+}
+
+func (m *RoleSvcMock) UnassignUserRole(userUUID string, roleName string) error {
+	args := m.Called(userUUID, roleName)
+	return args.Error(0)
+}
+
+func (m *RoleSvcMock) Authorization(userID uint) (*service.UserAuthorization, error) {
+	args := m.Called(userID)
+	return args.Get(0).(*service.UserAuthorization), args.Error(1)
+}
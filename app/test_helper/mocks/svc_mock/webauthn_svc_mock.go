@@ -0,0 +1,31 @@
+package svc_mock
+
+import (
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/models"
+	"github.com/AtsuyaOotsuka/portfolio-go-auth/internal/service"
+	"github.com/stretchr/testify/mock"
+)
+
+type WebauthnSvcMock struct {
+	mock.Mock
+}
+
+func (m *WebauthnSvcMock) BeginRegistration(userUUID string) (*service.WebauthnCreationOptions, error) {
+	args := m.Called(userUUID)
+	return args.Get(0).(*service.WebauthnCreationOptions), args.Error(1)
+}
+
+func (m *WebauthnSvcMock) FinishRegistration(input service.WebauthnRegistrationInput) (*models.UserCredential, error) {
+	args := m.Called(input)
+	return args.Get(0).(*models.UserCredential), args.Error(1)
+}
+
+func (m *WebauthnSvcMock) BeginLogin() (*service.WebauthnRequestOptions, error) {
+	args := m.Called()
+	return args.Get(0).(*service.WebauthnRequestOptions), args.Error(1)
+}
+
+func (m *WebauthnSvcMock) FinishLogin(input service.WebauthnLoginInput) (*models.User, error) {
+	args := m.Called(input)
+	return args.Get(0).(*models.User), args.Error(1)
+}
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS user_credentials;
+CREATE TABLE user_credentials (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    credential_id VARCHAR(255) NOT NULL UNIQUE,
+    public_key BLOB NOT NULL,
+    algorithm INT NOT NULL,
+    sign_count INT UNSIGNED NOT NULL DEFAULT 0,
+    attestation_format VARCHAR(32) NOT NULL,
+    aaguid CHAR(32) NOT NULL,
+    name VARCHAR(255) NULL,
+    last_used_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_user_credentials_user_id (user_id)
+);
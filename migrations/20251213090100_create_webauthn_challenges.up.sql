@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS webauthn_challenges;
+CREATE TABLE webauthn_challenges (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    challenge_hash CHAR(64) NOT NULL UNIQUE,
+    ceremony VARCHAR(32) NOT NULL,
+    user_id BIGINT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_webauthn_challenges_user_id (user_id)
+);
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS passwordless_tokens;
+CREATE TABLE passwordless_tokens (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    method VARCHAR(16) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_passwordless_tokens_user_id (user_id),
+    INDEX idx_passwordless_tokens_token_hash (token_hash)
+);
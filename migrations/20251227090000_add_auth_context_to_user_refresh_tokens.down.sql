@@ -0,0 +1,3 @@
+ALTER TABLE user_refresh_tokens
+    DROP COLUMN amr,
+    DROP COLUMN auth_time;
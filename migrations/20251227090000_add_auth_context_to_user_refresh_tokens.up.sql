@@ -0,0 +1,3 @@
+ALTER TABLE user_refresh_tokens
+    ADD COLUMN auth_time DATETIME NULL AFTER use_ip,
+    ADD COLUMN amr VARCHAR(64) NULL AFTER auth_time;
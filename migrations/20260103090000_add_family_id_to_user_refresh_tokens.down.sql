@@ -0,0 +1,3 @@
+ALTER TABLE user_refresh_tokens
+    DROP INDEX idx_user_refresh_tokens_family_id,
+    DROP COLUMN family_id;
@@ -0,0 +1,3 @@
+ALTER TABLE user_refresh_tokens
+    ADD COLUMN family_id CHAR(36) NULL AFTER user_id,
+    ADD INDEX idx_user_refresh_tokens_family_id (family_id);
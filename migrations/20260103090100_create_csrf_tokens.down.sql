@@ -0,0 +1 @@
+DROP TABLE IF EXISTS csrf_tokens;
@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS oauth_authorization_codes;
+CREATE TABLE oauth_authorization_codes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    client_id VARCHAR(255) NOT NULL,
+    user_id BIGINT NOT NULL,
+    redirect_uri VARCHAR(2048) NOT NULL,
+    scope VARCHAR(1024) NOT NULL DEFAULT '',
+    code_challenge VARCHAR(128) NOT NULL,
+    code_challenge_method VARCHAR(16) NOT NULL,
+    auth_time DATETIME NULL,
+    amr VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_oauth_authorization_codes_user_id (user_id)
+);
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS oauth_clients;
+CREATE TABLE oauth_clients (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    client_secret_hash CHAR(64) NOT NULL DEFAULT '',
+    name VARCHAR(255) NOT NULL,
+    token_endpoint_auth_method VARCHAR(32) NOT NULL,
+    public_key TEXT NULL,
+    redirect_uris TEXT NULL,
+    grant_types VARCHAR(255) NOT NULL DEFAULT '',
+    scopes VARCHAR(1024) NOT NULL DEFAULT '',
+    access_token_lifetime INT NOT NULL DEFAULT 0,
+    refresh_token_lifetime INT NOT NULL DEFAULT 0,
+    first_party BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
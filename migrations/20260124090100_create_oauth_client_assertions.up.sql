@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS oauth_client_assertions;
+CREATE TABLE oauth_client_assertions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL,
+    jti VARCHAR(255) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_oauth_client_assertions_client_id_jti (client_id, jti),
+    INDEX idx_oauth_client_assertions_expires_at (expires_at)
+);
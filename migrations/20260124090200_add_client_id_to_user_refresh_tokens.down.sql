@@ -0,0 +1,2 @@
+ALTER TABLE user_refresh_tokens
+    DROP COLUMN client_id;
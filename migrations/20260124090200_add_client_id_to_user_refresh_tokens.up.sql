@@ -0,0 +1,2 @@
+ALTER TABLE user_refresh_tokens
+    ADD COLUMN client_id VARCHAR(64) NOT NULL DEFAULT '' AFTER family_id;
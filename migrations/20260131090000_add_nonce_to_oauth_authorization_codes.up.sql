@@ -0,0 +1,2 @@
+ALTER TABLE oauth_authorization_codes
+    ADD COLUMN nonce VARCHAR(255) NOT NULL DEFAULT '' AFTER code_challenge_method;
@@ -0,0 +1,2 @@
+ALTER TABLE user_refresh_tokens
+    ADD COLUMN scope VARCHAR(1024) NOT NULL DEFAULT '' AFTER client_id;
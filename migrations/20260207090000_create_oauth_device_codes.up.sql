@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS oauth_device_codes;
+CREATE TABLE oauth_device_codes (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    device_code_hash CHAR(64) NOT NULL UNIQUE,
+    user_code_hash CHAR(64) NOT NULL UNIQUE,
+    client_id VARCHAR(255) NOT NULL,
+    scope VARCHAR(1024) NOT NULL DEFAULT '',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    user_id BIGINT NULL,
+    auth_time DATETIME NULL,
+    amr VARCHAR(64) NOT NULL DEFAULT '',
+    poll_interval INT NOT NULL,
+    last_polled_at DATETIME NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_oauth_device_codes_user_id (user_id)
+);
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS oauth_token_exchanges;
+CREATE TABLE oauth_token_exchanges (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    jti CHAR(36) NOT NULL UNIQUE,
+    client_id VARCHAR(64) NOT NULL,
+    subject_user_id BIGINT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    impersonation TINYINT(1) NOT NULL DEFAULT 0,
+    audience VARCHAR(1024) NOT NULL DEFAULT '',
+    scope VARCHAR(1024) NOT NULL DEFAULT '',
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_oauth_token_exchanges_subject_user_id (subject_user_id),
+    INDEX idx_oauth_token_exchanges_actor (actor)
+);
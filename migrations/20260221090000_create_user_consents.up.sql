@@ -0,0 +1,10 @@
+CREATE TABLE user_consents (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    scope VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_user_consents_user_id_client_id (user_id, client_id),
+    INDEX idx_user_consents_client_id (client_id)
+);
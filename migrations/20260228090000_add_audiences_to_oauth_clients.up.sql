@@ -0,0 +1,2 @@
+ALTER TABLE oauth_clients
+    ADD COLUMN audiences VARCHAR(1024) NOT NULL DEFAULT '' AFTER scopes;
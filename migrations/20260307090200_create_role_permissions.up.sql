@@ -0,0 +1,6 @@
+CREATE TABLE role_permissions (
+    role_id BIGINT NOT NULL,
+    permission_id BIGINT NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    INDEX idx_role_permissions_permission_id (permission_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user_credentials
+    DROP COLUMN backup_eligible;
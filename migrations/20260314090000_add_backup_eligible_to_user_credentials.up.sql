@@ -0,0 +1,2 @@
+ALTER TABLE user_credentials
+    ADD COLUMN backup_eligible TINYINT(1) NOT NULL DEFAULT 0 AFTER sign_count;
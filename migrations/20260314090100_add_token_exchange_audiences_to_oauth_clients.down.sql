@@ -0,0 +1,3 @@
+ALTER TABLE oauth_clients
+    DROP COLUMN token_exchange_audiences,
+    DROP COLUMN token_exchange_subject_audiences;
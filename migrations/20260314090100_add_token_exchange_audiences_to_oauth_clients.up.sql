@@ -0,0 +1,3 @@
+ALTER TABLE oauth_clients
+    ADD COLUMN token_exchange_subject_audiences VARCHAR(1024) NOT NULL DEFAULT '' AFTER audiences,
+    ADD COLUMN token_exchange_audiences VARCHAR(1024) NOT NULL DEFAULT '' AFTER token_exchange_subject_audiences;